@@ -144,7 +144,7 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	return core.GetReceipt(b.database, txHash), nil
+	return core.GetDerivedReceipt(b.database, txHash, b.config), nil
 }
 
 // PendingCodeAt returns the code associated with an account in the pending state.
@@ -167,8 +167,11 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call networkchain.C
 	if err != nil {
 		return nil, err
 	}
-	rval, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
-	return rval, err
+	result, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	if err != nil {
+		return nil, err
+	}
+	return result.ReturnData, nil
 }
 
 // PendingCallContract executes a contract call on the pending state.
@@ -177,8 +180,11 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call network
 	defer b.mu.Unlock()
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
-	rval, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
-	return rval, err
+	result, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	if err != nil {
+		return nil, err
+	}
+	return result.ReturnData, nil
 }
 
 // PendingNonceAt implements PendingStateReader.PendingNonceAt, retrieving
@@ -202,36 +208,55 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call networkchain.Ca
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// Binary search the gas requirement, as it may be higher than the amount used
-	var lo, hi uint64
-	if call.Gas != nil {
+	// Determine the highest gas limit can be used during the estimation
+	var (
+		lo     = params.TxGas - 1
+		hi     uint64
+		gasCap uint64
+	)
+	if call.Gas != nil && call.Gas.Uint64() >= params.TxGas {
 		hi = call.Gas.Uint64()
 	} else {
 		hi = b.pendingBlock.GasLimit().Uint64()
 	}
-	for lo+1 < hi {
-		// Take a guess at the gas, and check transaction validity
-		mid := (hi + lo) / 2
-		call.Gas = new(big.Int).SetUint64(mid)
+	gasCap = hi
+
+	// Create a helper to check if a gas allowance results in a successful execution
+	execute := func(gas uint64) (*core.ExecutionResult, error) {
+		call.Gas = new(big.Int).SetUint64(gas)
 
 		snapshot := b.pendingState.Snapshot()
-		_, gas, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+		result, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
 		b.pendingState.RevertToSnapshot(snapshot)
 
-		// If the transaction became invalid or used all the gas (failed), raise the gas limit
-		if err != nil || gas.Cmp(call.Gas) == 0 {
+		return result, err
+	}
+	// Binary search the lowest gas allowance that still executes successfully
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		if result, err := execute(mid); err != nil || result.Failed() {
 			lo = mid
-			continue
+		} else {
+			hi = mid
+		}
+	}
+	// Reject the transaction as invalid if it still fails at the highest allowance
+	if hi == gasCap {
+		result, err := execute(hi)
+		if err != nil {
+			return nil, err
+		}
+		if result.Failed() {
+			return nil, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction: %v", gasCap, result.Err)
 		}
-		// Otherwise assume the transaction succeeded, lower the gas limit
-		hi = mid
 	}
 	return new(big.Int).SetUint64(hi), nil
 }
 
 // callContract implemens common code between normal and pending contract calls.
-// state is modified during execution, make sure to copy it if necessary.
-func (b *SimulatedBackend) callContract(ctx context.Context, call networkchain.CallMsg, block *types.Block, statedb *state.StateDB) ([]byte, *big.Int, error) {
+// state is modified during execution, make sure to copy it if necessary. The error
+// the EVM aborted the execution with is reported in the result.
+func (b *SimulatedBackend) callContract(ctx context.Context, call networkchain.CallMsg, block *types.Block, statedb *state.StateDB) (*core.ExecutionResult, error) {
 	// Ensure message is initialized properly.
 	if call.GasPrice == nil {
 		call.GasPrice = big.NewInt(1)
@@ -253,8 +278,7 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call networkchain.C
 	// about the transaction and calling mechanisms.
 	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{})
 	gaspool := new(core.GasPool).AddGas(math.MaxBig256)
-	ret, gasUsed, _, err := core.NewStateTransition(vmenv, msg, gaspool).TransitionDb()
-	return ret, gasUsed, err
+	return core.ExecuteMessage(vmenv, msg, gaspool)
 }
 
 // SendTransaction updates the pending block to include the given transaction.
@@ -0,0 +1,74 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/networkchain/networkchain"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+)
+
+// Tests that gas estimation finds the lowest allowance a call succeeds with, even
+// if refunds make it use less, and reports calls failing with any allowance.
+func TestSimulatedBackendEstimateGas(t *testing.T) {
+	var (
+		refunder = common.Address{0x01} // Clears a storage slot, earning a refund
+		failer   = common.Address{0x02} // Executes an invalid opcode
+	)
+	sim := NewSimulatedBackend(core.GenesisAlloc{
+		refunder: {
+			Code:    []byte{0x60, 0x00, 0x60, 0x00, 0x55, 0x00}, // PUSH1 0, PUSH1 0, SSTORE, STOP
+			Storage: map[common.Hash]common.Hash{{}: {0x01}},
+			Balance: new(big.Int),
+		},
+		failer: {
+			Code:    []byte{0xfe},
+			Balance: new(big.Int),
+		},
+	})
+	tests := []struct {
+		to   common.Address
+		gas  uint64
+		fail bool
+	}{
+		{to: common.Address{0xff}, gas: 21000},
+		{to: refunder, gas: 26006},
+		{to: failer, fail: true},
+	}
+	for i, tt := range tests {
+		to := tt.to
+		gas, err := sim.EstimateGas(context.Background(), networkchain.CallMsg{From: common.Address{0xaa}, To: &to})
+		if tt.fail {
+			if err == nil || !strings.Contains(err.Error(), "always failing transaction") {
+				t.Errorf("test %d: error mismatch: have %v, want always failing transaction", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to estimate gas: %v", i, err)
+			continue
+		}
+		if gas.Uint64() != tt.gas {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, gas, tt.gas)
+		}
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -71,6 +70,8 @@ type accountCache struct {
 	byAddr   map[common.Address][]accounts.Account
 	throttle *time.Timer
 	notify   chan struct{}
+	fileC    fileCache
+	index    *accountIndex
 }
 
 func newAccountCache(keydir string) (*accountCache, chan struct{}) {
@@ -103,6 +104,12 @@ func (ac *accountCache) add(newAccount accounts.Account) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
 
+	ac.insert(newAccount)
+}
+
+// insert adds an account to the cache if not yet present.
+// Callers must hold ac.mu.
+func (ac *accountCache) insert(newAccount accounts.Account) {
 	i := sort.Search(len(ac.all), func(i int) bool { return ac.all[i].URL.Cmp(newAccount.URL) >= 0 })
 	if i < len(ac.all) && ac.all[i] == newAccount {
 		return
@@ -119,6 +126,12 @@ func (ac *accountCache) delete(removed accounts.Account) {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
 
+	ac.remove(removed)
+}
+
+// remove drops an account from the cache.
+// Callers must hold ac.mu.
+func (ac *accountCache) remove(removed accounts.Account) {
 	ac.all = removeAccount(ac.all, removed)
 	if ba := removeAccount(ac.byAddr[removed.Address], removed); len(ba) == 0 {
 		delete(ac.byAddr, removed.Address)
@@ -127,6 +140,15 @@ func (ac *accountCache) delete(removed accounts.Account) {
 	}
 }
 
+// removeByPath drops the account backed by the given key file from the cache.
+// Callers must hold ac.mu.
+func (ac *accountCache) removeByPath(path string) {
+	i := sort.Search(len(ac.all), func(i int) bool { return ac.all[i].URL.Path >= path })
+	if i < len(ac.all) && ac.all[i].URL.Path == path {
+		ac.remove(ac.all[i])
+	}
+}
+
 func removeAccount(slice []accounts.Account, elem accounts.Account) []accounts.Account {
 	for i := range slice {
 		if slice[i] == elem {
@@ -205,70 +227,88 @@ func (ac *accountCache) close() {
 	ac.mu.Unlock()
 }
 
-// reload caches addresses of existing accounts.
+// reload updates the cache with the key files changed since the last scan.
 // Callers must hold ac.mu.
 func (ac *accountCache) reload() {
-	accounts, err := ac.scan()
+	creates, deletes, updates, err := ac.fileC.scan(ac.keydir)
 	if err != nil {
 		log.Debug("Failed to reload keystore contents", "err", err)
+		return
+	}
+	if len(creates) == 0 && len(deletes) == 0 && len(updates) == 0 {
+		return
 	}
-	ac.all = accounts
-	sort.Sort(ac.all)
-	for k := range ac.byAddr {
-		delete(ac.byAddr, k)
+	if ac.index == nil {
+		ac.index = loadAccountIndex(ac.keydir)
+		ac.index.prune(ac.fileC.files)
 	}
-	for _, a := range accounts {
-		ac.byAddr[a.Address] = append(ac.byAddr[a.Address], a)
+	for _, path := range deletes {
+		ac.removeByPath(path)
+		ac.index.remove(path)
+	}
+	for _, path := range updates {
+		ac.removeByPath(path)
+		if a := ac.indexedAccount(path); a != nil {
+			ac.insert(*a)
+		}
+	}
+	for _, path := range creates {
+		if a := ac.indexedAccount(path); a != nil {
+			ac.insert(*a)
+		}
 	}
+	ac.index.save()
+
 	select {
 	case ac.notify <- struct{}{}:
 	default:
 	}
-	log.Debug("Reloaded keystore contents", "accounts", len(ac.all))
+	log.Debug("Reloaded keystore contents", "accounts", len(ac.all), "created", len(creates), "deleted", len(deletes), "updated", len(updates))
 }
 
-func (ac *accountCache) scan() ([]accounts.Account, error) {
-	files, err := ioutil.ReadDir(ac.keydir)
-	if err != nil {
-		return nil, err
+// indexedAccount retrieves the account backed by a key file, using the address
+// from the account index if it's up to date and parsing the key file otherwise.
+// Callers must hold ac.mu.
+func (ac *accountCache) indexedAccount(path string) *accounts.Account {
+	stat := ac.fileC.files[path]
+	if addr, ok := ac.index.lookup(path, stat); ok {
+		return &accounts.Account{Address: addr, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}}
+	}
+	a := readAccount(path)
+	if a != nil {
+		ac.index.update(path, stat, a.Address)
+	} else {
+		ac.index.remove(path)
 	}
+	return a
+}
 
-	var (
-		buf     = new(bufio.Reader)
-		addrs   []accounts.Account
-		keyJSON struct {
-			Address string `json:"address"`
-		}
-	)
-	for _, fi := range files {
-		path := filepath.Join(ac.keydir, fi.Name())
-		if skipKeyFile(fi) {
-			log.Trace("Ignoring file on account scan", "path", path)
-			continue
-		}
-		logger := log.New("path", path)
+// readAccount parses the address out of a key file, returning nil if the file
+// cannot be read or does not contain a valid key.
+func readAccount(path string) *accounts.Account {
+	logger := log.New("path", path)
 
-		fd, err := os.Open(path)
-		if err != nil {
-			logger.Trace("Failed to open keystore file", "err", err)
-			continue
-		}
-		buf.Reset(fd)
-		// Parse the address.
-		keyJSON.Address = ""
-		err = json.NewDecoder(buf).Decode(&keyJSON)
-		addr := common.HexToAddress(keyJSON.Address)
-		switch {
-		case err != nil:
-			logger.Debug("Failed to decode keystore key", "err", err)
-		case (addr == common.Address{}):
-			logger.Debug("Failed to decode keystore key", "err", "missing or zero address")
-		default:
-			addrs = append(addrs, accounts.Account{Address: addr, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}})
-		}
-		fd.Close()
+	fd, err := os.Open(path)
+	if err != nil {
+		logger.Trace("Failed to open keystore file", "err", err)
+		return nil
+	}
+	defer fd.Close()
+
+	var keyJSON struct {
+		Address string `json:"address"`
+	}
+	err = json.NewDecoder(bufio.NewReader(fd)).Decode(&keyJSON)
+	addr := common.HexToAddress(keyJSON.Address)
+	switch {
+	case err != nil:
+		logger.Debug("Failed to decode keystore key", "err", err)
+	case (addr == common.Address{}):
+		logger.Debug("Failed to decode keystore key", "err", "missing or zero address")
+	default:
+		return &accounts.Account{Address: addr, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}}
 	}
-	return addrs, err
+	return nil
 }
 
 func skipKeyFile(fi os.FileInfo) bool {
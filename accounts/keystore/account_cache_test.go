@@ -18,6 +18,7 @@ package keystore
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -295,3 +296,119 @@ func TestCacheFind(t *testing.T) {
 		}
 	}
 }
+
+// Tests that reloading the cache only picks up the key files changed since the
+// last scan, including keys atomically replaced by renaming over existing files.
+func TestCacheIncrementalReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eth-keystore-reload-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, notify := newAccountCache(dir)
+	cache.watcher.running = true // prevent unexpected reloads
+
+	// Copy in two keys and ensure both are picked up
+	for _, name := range []string{"aaa", "zzz"} {
+		if err := cp.CopyFile(filepath.Join(dir, name), filepath.Join(cachetestDir, name)); err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(-time.Hour)
+		os.Chtimes(filepath.Join(dir, name), past, past)
+	}
+	cache.mu.Lock()
+	cache.reload()
+	cache.mu.Unlock()
+
+	want := []accounts.Account{
+		{Address: cachetestAccounts[1].Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "aaa")}},
+		{Address: cachetestAccounts[2].Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "zzz")}},
+	}
+	if list := cache.accounts(); !reflect.DeepEqual(list, want) {
+		t.Fatalf("accounts mismatch after create:\ngot  %v\nwant %v", list, want)
+	}
+	select {
+	case <-notify:
+	default:
+		t.Fatalf("wasn't notified of new accounts")
+	}
+	// Reloading without any changes should not report anything
+	cache.mu.Lock()
+	cache.reload()
+	cache.mu.Unlock()
+
+	select {
+	case <-notify:
+		t.Fatalf("notified without keystore changes")
+	default:
+	}
+	// Atomically replace one key with another and delete the other one
+	content, err := ioutil.ReadFile(cachetestAccounts[0].URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeKeyFile(filepath.Join(dir, "aaa"), content); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(dir, "zzz"))
+
+	cache.mu.Lock()
+	cache.reload()
+	cache.mu.Unlock()
+
+	want = []accounts.Account{
+		{Address: cachetestAccounts[0].Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: filepath.Join(dir, "aaa")}},
+	}
+	if list := cache.accounts(); !reflect.DeepEqual(list, want) {
+		t.Fatalf("accounts mismatch after update:\ngot  %v\nwant %v", list, want)
+	}
+}
+
+// Tests that large keystores persist an account index which is used on later
+// startups instead of parsing every key file.
+func TestCacheAccountIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eth-keystore-index-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < minIndexedKeys; i++ {
+		if err := cp.CopyFile(filepath.Join(dir, fmt.Sprintf("key-%03d", i)), cachetestAccounts[0].URL.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache, _ := newAccountCache(dir)
+	cache.watcher.running = true // prevent unexpected reloads
+
+	cache.mu.Lock()
+	cache.reload()
+	cache.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, accountIndexFile)); err != nil {
+		t.Fatalf("account index not written: %v", err)
+	}
+	// Tamper with the index and ensure a new cache trusts it over the key files
+	idx := loadAccountIndex(dir)
+	fake := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	entry := idx.entries["key-000"]
+	entry.Address = fake
+	idx.entries["key-000"] = entry
+	idx.dirty = true
+	idx.save()
+
+	cache, _ = newAccountCache(dir)
+	cache.watcher.running = true // prevent unexpected reloads
+
+	cache.mu.Lock()
+	cache.reload()
+	cache.mu.Unlock()
+
+	if !cache.hasAddress(fake) {
+		t.Errorf("indexed address not used")
+	}
+	if len(cache.accounts()) != minIndexedKeys {
+		t.Errorf("account count mismatch: have %d, want %d", len(cache.accounts()), minIndexedKeys)
+	}
+}
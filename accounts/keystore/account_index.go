@@ -0,0 +1,139 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/log"
+)
+
+// accountIndexFile is the name of the file within the keystore directory that
+// persists the address of each key file across restarts. Being hidden, it is
+// ignored by the keystore scans.
+const accountIndexFile = ".accountindex"
+
+// minIndexedKeys is the number of key files below which the account index is not
+// persisted, as parsing a handful of keys on startup is cheaper than maintaining
+// an extra file in the keystore directory.
+const minIndexedKeys = 128
+
+// indexEntry is the persisted record of a single key file. The address is only
+// trusted as long as the file's metadata is unchanged, and is validated lazily
+// against the key contents when the key is eventually decrypted.
+type indexEntry struct {
+	Address common.Address `json:"address"`
+	ModTime time.Time      `json:"modtime"`
+	Size    int64          `json:"size"`
+}
+
+// accountIndex maps the names of the key files in the keystore directory to the
+// addresses they contain, saving a JSON decode of every key file on startup.
+type accountIndex struct {
+	path    string                // Location of the index file
+	entries map[string]indexEntry // Indexed key files, keyed by base name
+	dirty   bool                  // Whether the index changed since it was last saved
+}
+
+// loadAccountIndex reads the account index of a keystore directory, starting
+// with an empty one if it doesn't exist yet or is corrupted.
+func loadAccountIndex(keydir string) *accountIndex {
+	idx := &accountIndex{
+		path:    filepath.Join(keydir, accountIndexFile),
+		entries: make(map[string]indexEntry),
+	}
+	blob, err := ioutil.ReadFile(idx.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug("Failed to read keystore index", "path", idx.path, "err", err)
+		}
+		return idx
+	}
+	if err := json.Unmarshal(blob, &idx.entries); err != nil {
+		log.Warn("Discarding corrupted keystore index", "path", idx.path, "err", err)
+		idx.entries = make(map[string]indexEntry)
+		idx.dirty = true
+	}
+	return idx
+}
+
+// lookup returns the indexed address of a key file if the index entry is still
+// up to date with the given file metadata.
+func (idx *accountIndex) lookup(path string, stat fileStat) (common.Address, bool) {
+	entry, ok := idx.entries[filepath.Base(path)]
+	if !ok || !entry.ModTime.Equal(stat.modTime) || entry.Size != stat.size {
+		return common.Address{}, false
+	}
+	return entry.Address, true
+}
+
+// update records the address contained in a key file.
+func (idx *accountIndex) update(path string, stat fileStat, addr common.Address) {
+	idx.entries[filepath.Base(path)] = indexEntry{Address: addr, ModTime: stat.modTime, Size: stat.size}
+	idx.dirty = true
+}
+
+// remove drops a key file from the index.
+func (idx *accountIndex) remove(path string) {
+	name := filepath.Base(path)
+	if _, ok := idx.entries[name]; ok {
+		delete(idx.entries, name)
+		idx.dirty = true
+	}
+}
+
+// prune drops all entries of key files not present in the keystore directory
+// anymore, e.g. the ones deleted while the node was offline.
+func (idx *accountIndex) prune(files map[string]fileStat) {
+	present := make(map[string]struct{}, len(files))
+	for path := range files {
+		present[filepath.Base(path)] = struct{}{}
+	}
+	for name := range idx.entries {
+		if _, ok := present[name]; !ok {
+			delete(idx.entries, name)
+			idx.dirty = true
+		}
+	}
+}
+
+// save persists the index if it changed since it was last loaded or saved. The
+// index is not written for small keystores or if the keystore directory does
+// not exist.
+func (idx *accountIndex) save() {
+	if !idx.dirty || len(idx.entries) < minIndexedKeys {
+		return
+	}
+	if _, err := os.Stat(filepath.Dir(idx.path)); err != nil {
+		return
+	}
+	blob, err := json.Marshal(idx.entries)
+	if err != nil {
+		log.Warn("Failed to encode keystore index", "err", err)
+		return
+	}
+	if err := writeKeyFile(idx.path, blob); err != nil {
+		log.Warn("Failed to write keystore index", "path", idx.path, "err", err)
+		return
+	}
+	idx.dirty = false
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+)
+
+// fileStat is the subset of file metadata used to detect keystore file changes.
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileCache tracks the files of the keystore directory seen during the last
+// scan, allowing subsequent scans to only process the files that changed.
+type fileCache struct {
+	files map[string]fileStat // Metadata of the key files seen during the last scan
+}
+
+// scan lists the keystore directory and diffs it against the previous scan,
+// returning the paths of the created, deleted and updated key files.
+//
+// Key files are saved by writing a hidden temporary file and renaming it into
+// place, which surfaces as a create of a previously unseen path or as an update
+// of an existing one, as the renamed file carries the metadata of the temporary.
+func (fc *fileCache) scan(keyDir string) (creates, deletes, updates []string, err error) {
+	t0 := time.Now()
+
+	// A missing keystore directory is treated as empty, dropping all known keys
+	files, err := ioutil.ReadDir(keyDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+	t1 := time.Now()
+
+	seen := make(map[string]fileStat, len(files))
+	for _, fi := range files {
+		path := filepath.Join(keyDir, fi.Name())
+		if skipKeyFile(fi) {
+			log.Trace("Ignoring file on account scan", "path", path)
+			continue
+		}
+		stat := fileStat{modTime: fi.ModTime(), size: fi.Size()}
+		seen[path] = stat
+
+		prev, ok := fc.files[path]
+		switch {
+		case !ok:
+			creates = append(creates, path)
+		case !prev.modTime.Equal(stat.modTime) || prev.size != stat.size:
+			updates = append(updates, path)
+		}
+	}
+	for path := range fc.files {
+		if _, ok := seen[path]; !ok {
+			deletes = append(deletes, path)
+		}
+	}
+	fc.files = seen
+	t2 := time.Now()
+
+	log.Trace("Handled keystore changes", "time", t2.Sub(t1), "list", t1.Sub(t0))
+	return creates, deletes, updates, nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package policy implements per-account rules restricting what the RPC signing
+// endpoints of a node may sign, for deployments holding keys on behalf of users.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/math"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+)
+
+// Operations which may be allowed to an account.
+const (
+	OpTransaction = "transaction" // Signing transactions
+	OpMessage     = "message"     // Signing arbitrary messages
+)
+
+// window is the period the daily value allowance of an account applies to.
+const window = 24 * time.Hour
+
+// Rule restricts the signing operations of an account. The zero rule permits
+// everything.
+type Rule struct {
+	Deny         bool                  `json:"deny,omitempty"`         // Deny all operations
+	Operations   []string              `json:"operations,omitempty"`   // Allowed operations, all if empty
+	Destinations []common.Address      `json:"destinations,omitempty"` // Allowed recipients, any if empty; contract creation is denied otherwise
+	MaxValue     *math.HexOrDecimal256 `json:"maxValue,omitempty"`     // Maximum value of a single transaction
+	DailyValue   *math.HexOrDecimal256 `json:"dailyValue,omitempty"`   // Maximum value of the transactions signed in 24 hours
+}
+
+// Rules is the content of a policy file: the rules of the listed accounts, and
+// the default rule of all others.
+type Rules struct {
+	Default  *Rule                    `json:"default,omitempty"`
+	Accounts map[common.Address]*Rule `json:"accounts"`
+}
+
+// spend is the value of a transaction signed at a given time.
+type spend struct {
+	Time  time.Time `json:"time"`
+	Value *big.Int  `json:"value"`
+}
+
+// Policy enforces signing rules on the accounts of a node. The values of the
+// signed transactions are tracked in a journal, so that the daily allowances
+// persist across restarts. A nil policy permits everything.
+type Policy struct {
+	rules   Rules
+	journal string // File tracking the signed values ("" = not persisted)
+
+	lock  sync.Mutex
+	spent map[common.Address][]*spend // Values signed within the allowance window
+}
+
+// Load reads the rules of a policy from the given file, and the values signed
+// in the last 24 hours from the journal, if any.
+func Load(file, journal string) (*Policy, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{journal: journal, spent: make(map[common.Address][]*spend)}
+	if err := json.Unmarshal(blob, &p.rules); err != nil {
+		return nil, fmt.Errorf("invalid signing policy %s: %v", file, err)
+	}
+	for addr, rule := range p.rules.Accounts {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid signing policy of %x: %v", addr, err)
+		}
+	}
+	if p.rules.Default != nil {
+		if err := p.rules.Default.validate(); err != nil {
+			return nil, fmt.Errorf("invalid default signing policy: %v", err)
+		}
+	}
+	if journal != "" {
+		blob, err := ioutil.ReadFile(journal)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(blob, &p.spent); err != nil {
+				return nil, fmt.Errorf("invalid signing policy journal %s: %v", journal, err)
+			}
+		}
+	}
+	log.Info("Loaded signing policy", "file", file, "accounts", len(p.rules.Accounts), "default", p.rules.Default != nil)
+	return p, nil
+}
+
+// validate checks that a rule only contains known operations.
+func (r *Rule) validate() error {
+	for _, op := range r.Operations {
+		if op != OpTransaction && op != OpMessage {
+			return fmt.Errorf("unknown operation %q", op)
+		}
+	}
+	return nil
+}
+
+// rule returns the rule of an account, nil if unrestricted.
+func (p *Policy) rule(addr common.Address) *Rule {
+	if rule, ok := p.rules.Accounts[addr]; ok {
+		return rule
+	}
+	return p.rules.Default
+}
+
+// allows checks whether the rule permits an operation.
+func (r *Rule) allows(op string) bool {
+	if r.Deny {
+		return false
+	}
+	if len(r.Operations) == 0 {
+		return true
+	}
+	for _, allowed := range r.Operations {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeMessage checks whether the account may sign arbitrary messages.
+func (p *Policy) AuthorizeMessage(addr common.Address) error {
+	if p == nil {
+		return nil
+	}
+	if rule := p.rule(addr); rule != nil && !rule.allows(OpMessage) {
+		return fmt.Errorf("signing policy: account %x may not sign messages", addr)
+	}
+	return nil
+}
+
+// AuthorizeTx checks whether the account may sign the given transaction, and
+// reserves its value from the daily allowance of the account. If the transaction
+// ends up not being signed, the returned cancel function must be called to give
+// the reservation back.
+func (p *Policy) AuthorizeTx(from common.Address, tx *types.Transaction) (cancel func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	rule := p.rule(from)
+	if rule == nil {
+		return func() {}, nil
+	}
+	if !rule.allows(OpTransaction) {
+		return nil, fmt.Errorf("signing policy: account %x may not sign transactions", from)
+	}
+	if len(rule.Destinations) > 0 {
+		if tx.To() == nil {
+			return nil, fmt.Errorf("signing policy: account %x may not create contracts", from)
+		}
+		allowed := false
+		for _, dest := range rule.Destinations {
+			if dest == *tx.To() {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("signing policy: account %x may not send to %x", from, *tx.To())
+		}
+	}
+	if rule.MaxValue != nil && tx.Value().Cmp((*big.Int)(rule.MaxValue)) > 0 {
+		return nil, fmt.Errorf("signing policy: value %v exceeds the limit %v of account %x", tx.Value(), (*big.Int)(rule.MaxValue), from)
+	}
+	if rule.DailyValue == nil || tx.Value().Sign() == 0 {
+		return func() {}, nil
+	}
+	// Reserve the value from the allowance of the account
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	total := new(big.Int).Set(tx.Value())
+	for _, s := range p.expire(from, now) {
+		total.Add(total, s.Value)
+	}
+	if total.Cmp((*big.Int)(rule.DailyValue)) > 0 {
+		return nil, fmt.Errorf("signing policy: value %v exceeds the remaining daily allowance of account %x", tx.Value(), from)
+	}
+	reserved := &spend{Time: now, Value: new(big.Int).Set(tx.Value())}
+	p.spent[from] = append(p.spent[from], reserved)
+	p.persist()
+
+	return func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		for i, s := range p.spent[from] {
+			if s == reserved {
+				p.spent[from] = append(p.spent[from][:i], p.spent[from][i+1:]...)
+				p.persist()
+				break
+			}
+		}
+	}, nil
+}
+
+// expire drops the spends of an account older than the allowance window and
+// returns the remaining ones. The caller must hold the lock.
+func (p *Policy) expire(addr common.Address, now time.Time) []*spend {
+	spends := p.spent[addr]
+	for len(spends) > 0 && now.Sub(spends[0].Time) >= window {
+		spends = spends[1:]
+	}
+	if len(spends) == 0 {
+		delete(p.spent, addr)
+		return nil
+	}
+	p.spent[addr] = spends
+	return spends
+}
+
+// persist writes the signed values to the journal. The caller must hold the lock.
+func (p *Policy) persist() {
+	if p.journal == "" {
+		return
+	}
+	blob, err := json.Marshal(p.spent)
+	if err != nil {
+		log.Error("Failed to encode signing policy journal", "err", err)
+		return
+	}
+	tmp := p.journal + ".new"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		log.Error("Failed to write signing policy journal", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, p.journal); err != nil {
+		log.Error("Failed to replace signing policy journal", "err", err)
+	}
+}
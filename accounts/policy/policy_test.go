@@ -0,0 +1,166 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+var (
+	restricted = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	denied     = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	other      = common.HexToAddress("0x3000000000000000000000000000000000000003")
+	whitelist  = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+)
+
+const testPolicy = `{
+	"default": {"operations": ["message"]},
+	"accounts": {
+		"0x1000000000000000000000000000000000000001": {
+			"operations":   ["transaction"],
+			"destinations": ["0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"],
+			"maxValue":     "100",
+			"dailyValue":   "0x96"
+		},
+		"0x2000000000000000000000000000000000000002": {"deny": true}
+	}
+}`
+
+func newTestPolicy(t *testing.T) (*Policy, string, func()) {
+	dir, err := ioutil.TempDir("", "policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "policy.json")
+	if err := ioutil.WriteFile(file, []byte(testPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	journal := filepath.Join(dir, "journal")
+	p, err := Load(file, journal)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	return p, file, func() { os.RemoveAll(dir) }
+}
+
+func transfer(to common.Address, value int64) *types.Transaction {
+	return types.NewTransaction(0, to, big.NewInt(value), big.NewInt(21000), big.NewInt(1), nil)
+}
+
+// Tests that the operations, destinations and value limits of accounts are enforced.
+func TestRules(t *testing.T) {
+	p, _, cleanup := newTestPolicy(t)
+	defer cleanup()
+
+	tests := []struct {
+		from common.Address
+		tx   *types.Transaction
+		ok   bool
+	}{
+		{restricted, transfer(whitelist, 100), true},
+		{restricted, transfer(whitelist, 101), false}, // Above the maximum value
+		{restricted, transfer(other, 1), false},       // Destination not whitelisted
+		{restricted, types.NewContractCreation(0, big.NewInt(0), big.NewInt(21000), big.NewInt(1), nil), false},
+		{denied, transfer(whitelist, 0), false},
+		{other, transfer(whitelist, 0), false}, // Default only allows messages
+	}
+	for i, tt := range tests {
+		_, err := p.AuthorizeTx(tt.from, tt.tx)
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: authorization mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+	if err := p.AuthorizeMessage(restricted); err == nil {
+		t.Errorf("restricted account allowed to sign messages")
+	}
+	if err := p.AuthorizeMessage(denied); err == nil {
+		t.Errorf("denied account allowed to sign messages")
+	}
+	if err := p.AuthorizeMessage(other); err != nil {
+		t.Errorf("default account denied signing messages: %v", err)
+	}
+	// A nil policy must permit everything
+	var none *Policy
+	if _, err := none.AuthorizeTx(denied, transfer(other, 1)); err != nil {
+		t.Errorf("nil policy denied transaction: %v", err)
+	}
+	if err := none.AuthorizeMessage(denied); err != nil {
+		t.Errorf("nil policy denied message: %v", err)
+	}
+}
+
+// Tests that the daily allowance is tracked across cancellations and restarts.
+func TestDailyAllowance(t *testing.T) {
+	p, file, cleanup := newTestPolicy(t)
+	defer cleanup()
+
+	if _, err := p.AuthorizeTx(restricted, transfer(whitelist, 100)); err != nil {
+		t.Fatalf("first transfer denied: %v", err)
+	}
+	if _, err := p.AuthorizeTx(restricted, transfer(whitelist, 51)); err == nil {
+		t.Fatalf("transfer above daily allowance permitted")
+	}
+	cancel, err := p.AuthorizeTx(restricted, transfer(whitelist, 50))
+	if err != nil {
+		t.Fatalf("transfer within daily allowance denied: %v", err)
+	}
+	// Cancelling a reservation should give the value back
+	cancel()
+	if _, err := p.AuthorizeTx(restricted, transfer(whitelist, 40)); err != nil {
+		t.Fatalf("transfer after cancellation denied: %v", err)
+	}
+	// Reloading the policy should retain the spent values
+	reloaded, err := Load(file, p.journal)
+	if err != nil {
+		t.Fatalf("failed to reload policy: %v", err)
+	}
+	if _, err := reloaded.AuthorizeTx(restricted, transfer(whitelist, 11)); err == nil {
+		t.Fatalf("reloaded policy forgot spent values")
+	}
+	if _, err := reloaded.AuthorizeTx(restricted, transfer(whitelist, 10)); err != nil {
+		t.Fatalf("transfer within reloaded allowance denied: %v", err)
+	}
+	// Spends older than the allowance window should expire
+	for _, s := range reloaded.spent[restricted] {
+		s.Time = s.Time.Add(-window)
+	}
+	if _, err := reloaded.AuthorizeTx(restricted, transfer(whitelist, 100)); err != nil {
+		t.Fatalf("transfer after allowance window denied: %v", err)
+	}
+}
+
+// Tests that policies with unknown operations are rejected.
+func TestInvalidOperation(t *testing.T) {
+	f, err := ioutil.TempFile("", "policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"accounts": {"0x1000000000000000000000000000000000000001": {"operations": ["deploy"]}}}`)
+	f.Close()
+
+	if _, err := Load(f.Name(), ""); err == nil {
+		t.Fatalf("policy with unknown operation loaded")
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the implementation for interacting with the Trezor hardware
+// wallets. The wire protocol spec can be found on the SatoshiLabs website:
+// https://doc.satoshilabs.com/trezor-tech/api-protobuf.html
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+)
+
+// ErrTrezorPINNeeded is returned if opening the trezor requires a PIN code. In
+// this case, the calling application should display a pinpad and send back the
+// encoded passphrase.
+var ErrTrezorPINNeeded = errors.New("trezor: pin needed")
+
+// errTrezorReplyInvalidHeader is the error message returned by a Trezor data exchange
+// if the device replies with a mismatching header. This usually means the device
+// is in browser mode.
+var errTrezorReplyInvalidHeader = errors.New("trezor: invalid reply header")
+
+// trezorDataChunk is the maximum size of the transaction payload sent to the
+// Trezor in a single signing request or acknowledgement.
+const trezorDataChunk = 1024
+
+// trezorDriver implements the communication with a Trezor hardware wallet.
+type trezorDriver struct {
+	device  io.ReadWriter // USB device connection to communicate through
+	version [3]uint32     // Current version of the Trezor firmware
+	label   string        // Current textual label of the Trezor device
+	pinwait bool          // Flags whether the device is waiting for PIN entry
+	failure error         // Any failure that would make the device unusable
+	log     log.Logger    // Contextual logger to tag the trezor with its id
+}
+
+// newTrezorDriver creates a new instance of a Trezor USB protocol driver.
+func newTrezorDriver(logger log.Logger) driver {
+	return &trezorDriver{
+		log: logger,
+	}
+}
+
+// Status implements usbwallet.driver, returning whether the Trezor is waiting
+// for the PIN to be entered or is online.
+func (w *trezorDriver) Status() string {
+	if w.failure != nil {
+		return fmt.Sprintf("Failed: %v", w.failure)
+	}
+	if w.pinwait {
+		return fmt.Sprintf("Trezor v%d.%d.%d '%s' waiting for PIN", w.version[0], w.version[1], w.version[2], w.label)
+	}
+	return fmt.Sprintf("Trezor v%d.%d.%d '%s' online", w.version[0], w.version[1], w.version[2], w.label)
+}
+
+// Open implements usbwallet.driver, attempting to initialize the connection to
+// the Trezor hardware wallet. Initializing the Trezor is a two phase operation:
+//  * The first phase is to initialize the connection and read the wallet's
+//    features. This phase is invoked if the provided passphrase is empty. The
+//    device will display the pinpad as a result and will return an appropriate
+//    error to notify the user that a second open phase is needed.
+//  * The second phase is to unlock access to the Trezor, which is done by the
+//    user actually providing a passphrase mapping a keyboard keypad to the pin
+//    number of the user (shuffled according to the pinpad displayed).
+func (w *trezorDriver) Open(device io.ReadWriter, passphrase string) error {
+	w.device, w.failure = device, nil
+
+	// If phase 1 is requested, init the connection and wait for user callback
+	if passphrase == "" {
+		// If we're already waiting for a PIN entry, insta-return
+		if w.pinwait {
+			return ErrTrezorPINNeeded
+		}
+		// Initialize a connection to the device
+		reply, _, err := w.trezorExchange(trezorInitialize, nil, trezorFeatures)
+		if err != nil {
+			return err
+		}
+		features, err := decodeTrezorFeatures(reply)
+		if err != nil {
+			return err
+		}
+		if features.BootloaderMode {
+			return errors.New("trezor: device in bootloader mode")
+		}
+		if !features.Initialized {
+			return errors.New("trezor: device not initialized")
+		}
+		w.version, w.label = features.Version, features.Label
+
+		// Do a manual ping, forcing the device to ask for its PIN
+		_, kind, err := w.trezorExchange(trezorPing, encodeTrezorPing(true), trezorPinMatrixRequest, trezorSuccess)
+		if err != nil {
+			return err
+		}
+		// Only return the PIN request if the device wasn't unlocked until now
+		if kind == trezorSuccess {
+			return nil // Device responded with trezor.Success
+		}
+		w.pinwait = true
+		return ErrTrezorPINNeeded
+	}
+	// Phase 2 requested with actual PIN entry
+	w.pinwait = false
+
+	if _, _, err := w.trezorExchange(trezorPinMatrixAck, encodeTrezorPinMatrixAck(passphrase), trezorSuccess); err != nil {
+		w.failure = err
+		return err
+	}
+	return nil
+}
+
+// Close implements usbwallet.driver, cleaning up and metadata maintained within
+// the Trezor driver.
+func (w *trezorDriver) Close() error {
+	w.version, w.label, w.pinwait = [3]uint32{}, "", false
+	return nil
+}
+
+// Heartbeat implements usbwallet.driver, performing a sanity check against the
+// Trezor to see if it's still online.
+func (w *trezorDriver) Heartbeat() error {
+	if _, _, err := w.trezorExchange(trezorPing, encodeTrezorPing(false), trezorSuccess); err != nil {
+		w.failure = err
+		return err
+	}
+	return nil
+}
+
+// Offline implements usbwallet.driver, returning whether the Trezor is still
+// waiting for the PIN to be entered, so no accounts can be derived yet.
+func (w *trezorDriver) Offline() bool {
+	return w.pinwait
+}
+
+// Derive implements usbwallet.driver, sending a derivation request to the Trezor
+// and returning the NetworkChain address located on that derivation path.
+func (w *trezorDriver) Derive(path accounts.DerivationPath) (common.Address, error) {
+	return w.trezorDerive(path)
+}
+
+// SignTx implements usbwallet.driver, sending the transaction to the Trezor and
+// waiting for the user to confirm or deny the transaction.
+func (w *trezorDriver) SignTx(path accounts.DerivationPath, address common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if w.device == nil || w.pinwait {
+		return nil, accounts.ErrWalletClosed
+	}
+	return w.trezorSign(path, address, tx, chainID)
+}
+
+// trezorDerive sends a derivation request to the Trezor device and returns the
+// NetworkChain address located on that path.
+func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
+	reply, _, err := w.trezorExchange(trezorEthereumGetAddress, encodeTrezorEthereumGetAddress(derivationPath), trezorEthereumAddress)
+	if err != nil {
+		return common.Address{}, err
+	}
+	raw, hexstr, err := decodeTrezorEthereumAddress(reply)
+	if err != nil {
+		return common.Address{}, err
+	}
+	switch {
+	case len(raw) == common.AddressLength:
+		return common.BytesToAddress(raw), nil
+	case hexstr != "":
+		return common.HexToAddress(hexstr), nil
+	default:
+		return common.Address{}, errors.New("trezor: reply lacks address entry")
+	}
+}
+
+// trezorSign sends the transaction to the Trezor wallet, and waits for the user
+// to confirm or deny the transaction.
+func (w *trezorDriver) trezorSign(derivationPath []uint32, address common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	// Create the transaction initiation message
+	data := tx.Data()
+	request := &trezorSignTxRequest{
+		AddressN:   derivationPath,
+		Nonce:      new(big.Int).SetUint64(tx.Nonce()).Bytes(),
+		GasPrice:   tx.GasPrice().Bytes(),
+		GasLimit:   tx.Gas().Bytes(),
+		Value:      tx.Value().Bytes(),
+		DataLength: uint32(len(data)),
+	}
+	if to := tx.To(); to != nil {
+		request.To = (*to)[:] // Non contract deploy, set recipient explicitly
+	}
+	if length := len(data); length > trezorDataChunk {
+		request.DataChunk, data = data[:trezorDataChunk], data[trezorDataChunk:]
+	} else {
+		request.DataChunk, data = data, nil
+	}
+	if chainID != nil { // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported!?)
+		request.ChainID = uint32(chainID.Uint64())
+	}
+	// Send the initiation message and stream content until a signature is returned
+	reply, _, err := w.trezorExchange(trezorEthereumSignTx, request.encode(), trezorEthereumTxRequest)
+	if err != nil {
+		return nil, err
+	}
+	response, err := decodeTrezorTxRequest(reply)
+	if err != nil {
+		return nil, err
+	}
+	for response.DataLength > 0 {
+		if int(response.DataLength) > len(data) {
+			return nil, fmt.Errorf("trezor: requested %d bytes of data, only %d left", response.DataLength, len(data))
+		}
+		chunk := data[:response.DataLength]
+		data = data[response.DataLength:]
+
+		if reply, _, err = w.trezorExchange(trezorEthereumTxAck, encodeTrezorEthereumTxAck(chunk), trezorEthereumTxRequest); err != nil {
+			return nil, err
+		}
+		if response, err = decodeTrezorTxRequest(reply); err != nil {
+			return nil, err
+		}
+	}
+	// Extract the NetworkChain signature and do a sanity validation
+	if len(response.R) == 0 || len(response.R) > 32 || len(response.S) == 0 || len(response.S) > 32 || response.V == 0 {
+		return nil, errors.New("reply lacks signature")
+	}
+	signature := make([]byte, 65)
+	copy(signature[32-len(response.R):32], response.R)
+	copy(signature[64-len(response.S):64], response.S)
+
+	// Create the correct signer and signature transform based on the chain ID
+	var signer types.Signer
+	if chainID == nil {
+		signer = new(types.HomesteadSigner)
+		signature[64] = byte(response.V - 27)
+	} else {
+		signer = types.NewEIP155Signer(chainID)
+		signature[64] = byte(uint64(response.V) - (chainID.Uint64()*2 + 35))
+	}
+	// Inject the final signature into the transaction and sanity check the sender
+	signed, err := tx.WithSignature(signer, signature)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", address.Hex(), sender.Hex())
+	}
+	return signed, nil
+}
+
+// trezorExchange performs a data exchange with the Trezor wallet, sending it a
+// message and retrieving the response. If multiple responses are possible, the
+// method will also return the kind of the reply that arrived. Button requests
+// are acknowledged transparently, failures are converted into errors.
+//
+// The wire framing splits every message into 64 byte HID reports, the first of
+// which is laid out as follows (later ones carrying only the report ID and the
+// continuation of the payload):
+//
+//  Description                           | Length
+//  --------------------------------------+----------
+//  Report ID (0x3f)                      | 1 byte
+//  Magic header "##"                     | 2 bytes
+//  Message type (big endian)             | 2 bytes
+//  Payload length (big endian)           | 4 bytes
+//  Protocol buffer encoded payload       | arbitrary
+func (w *trezorDriver) trezorExchange(kind trezorMessageType, data []byte, results ...trezorMessageType) ([]byte, trezorMessageType, error) {
+	for {
+		reply, rkind, err := w.trezorRoundTrip(kind, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Try to parse the reply into the requested reply message
+		switch rkind {
+		case trezorButtonRequest:
+			// Trezor is waiting for user confirmation, ack and wait for the next message
+			kind, data = trezorButtonAck, nil
+			continue
+
+		case trezorFailure:
+			// Trezor returned a failure, extract and return the message
+			return nil, 0, decodeTrezorFailure(reply)
+
+		case trezorPassphraseRequest:
+			// Passphrase protected wallets would derive different accounts for an empty
+			// passphrase, refuse to guess on the user's behalf
+			return nil, 0, errors.New("trezor: passphrase protection not supported")
+		}
+		for _, result := range results {
+			if rkind == result {
+				return reply, rkind, nil
+			}
+		}
+		return nil, 0, fmt.Errorf("trezor: expected reply types %v, got %v", results, rkind)
+	}
+}
+
+// trezorRoundTrip streams a single message to the Trezor and reads back the
+// next message the device sends, without interpreting it.
+func (w *trezorDriver) trezorRoundTrip(kind trezorMessageType, data []byte) ([]byte, trezorMessageType, error) {
+	// Construct the original message payload to chunk up
+	payload := make([]byte, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], uint16(kind))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	// Stream all the chunks to the device
+	chunk := make([]byte, 64)
+	chunk[0] = 0x3f // Report ID magic number
+
+	for len(payload) > 0 {
+		// Construct the new message to stream, padding with zeroes if needed
+		if len(payload) > 63 {
+			copy(chunk[1:], payload[:63])
+			payload = payload[63:]
+		} else {
+			copy(chunk[1:], make([]byte, 63))
+			copy(chunk[1:], payload)
+			payload = nil
+		}
+		// Send over to the device
+		w.log.Trace("Data chunk sent to the Trezor", "chunk", hexutil.Bytes(chunk))
+		if _, err := w.device.Write(chunk); err != nil {
+			return nil, 0, err
+		}
+	}
+	// Stream the reply back from the wallet in 64 byte chunks
+	var (
+		rkind trezorMessageType
+		reply []byte
+	)
+	for i := 0; ; i++ {
+		// Read the next chunk from the Trezor wallet
+		if _, err := io.ReadFull(w.device, chunk); err != nil {
+			return nil, 0, err
+		}
+		w.log.Trace("Data chunk received from the Trezor", "chunk", hexutil.Bytes(chunk))
+
+		// Make sure the transport header matches
+		if chunk[0] != 0x3f || (i == 0 && (chunk[1] != 0x23 || chunk[2] != 0x23)) {
+			return nil, 0, errTrezorReplyInvalidHeader
+		}
+		// If it's the first chunk, retrieve the reply message type and total message length
+		var payload []byte
+
+		if i == 0 {
+			rkind = trezorMessageType(binary.BigEndian.Uint16(chunk[3:5]))
+			reply = make([]byte, 0, int(binary.BigEndian.Uint32(chunk[5:9])))
+			payload = chunk[9:]
+		} else {
+			payload = chunk[1:]
+		}
+		// Append to the reply and stop when filled up
+		if left := cap(reply) - len(reply); left > len(payload) {
+			reply = append(reply, payload...)
+		} else {
+			reply = append(reply, payload[:left]...)
+			break
+		}
+	}
+	return reply, rkind, nil
+}
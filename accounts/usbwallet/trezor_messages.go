@@ -0,0 +1,330 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains a minimal protocol buffer codec for the subset of the
+// Trezor wire messages needed to derive accounts and sign transactions. The
+// full message definitions can be found in the Trezor GitHub repo:
+// https://github.com/trezor/trezor-common/blob/master/protob/messages.proto
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// trezorMessageType is an enumeration of the Trezor wire message types.
+type trezorMessageType uint16
+
+const (
+	trezorInitialize         trezorMessageType = 0  // Reset the device and request its features
+	trezorPing               trezorMessageType = 1  // Test the device connectivity, optionally requesting a PIN
+	trezorSuccess            trezorMessageType = 2  // Generic success reply
+	trezorFailure            trezorMessageType = 3  // Generic failure reply
+	trezorFeatures           trezorMessageType = 17 // Device features and metadata
+	trezorPinMatrixRequest   trezorMessageType = 18 // Device requests the user to enter the PIN
+	trezorPinMatrixAck       trezorMessageType = 19 // PIN entered by the user (matrix positions)
+	trezorButtonRequest      trezorMessageType = 26 // Device waits for the user to press a button
+	trezorButtonAck          trezorMessageType = 27 // Acknowledgement of a button request
+	trezorPassphraseRequest  trezorMessageType = 41 // Device requests the user to enter a passphrase
+	trezorEthereumGetAddress trezorMessageType = 56 // Request an address for a derivation path
+	trezorEthereumAddress    trezorMessageType = 57 // Address derived for a derivation path
+	trezorEthereumSignTx     trezorMessageType = 58 // Request a transaction signature
+	trezorEthereumTxRequest  trezorMessageType = 59 // Request for more data or the final signature
+	trezorEthereumTxAck      trezorMessageType = 60 // Next chunk of transaction data
+)
+
+// String implements fmt.Stringer, returning a human readable message type name.
+func (t trezorMessageType) String() string {
+	switch t {
+	case trezorInitialize:
+		return "Initialize"
+	case trezorPing:
+		return "Ping"
+	case trezorSuccess:
+		return "Success"
+	case trezorFailure:
+		return "Failure"
+	case trezorFeatures:
+		return "Features"
+	case trezorPinMatrixRequest:
+		return "PinMatrixRequest"
+	case trezorPinMatrixAck:
+		return "PinMatrixAck"
+	case trezorButtonRequest:
+		return "ButtonRequest"
+	case trezorButtonAck:
+		return "ButtonAck"
+	case trezorPassphraseRequest:
+		return "PassphraseRequest"
+	case trezorEthereumGetAddress:
+		return "EthereumGetAddress"
+	case trezorEthereumAddress:
+		return "EthereumAddress"
+	case trezorEthereumSignTx:
+		return "EthereumSignTx"
+	case trezorEthereumTxRequest:
+		return "EthereumTxRequest"
+	case trezorEthereumTxAck:
+		return "EthereumTxAck"
+	default:
+		return fmt.Sprintf("MessageType(%d)", uint16(t))
+	}
+}
+
+// Protocol buffer wire types used by the Trezor messages.
+const (
+	protoVarint = 0 // int32, uint32, bool, enum
+	protoBytes  = 2 // string, bytes, embedded messages, packed repeated fields
+)
+
+// errProtoTruncated is returned if a protocol buffer message ends mid-field.
+var errProtoTruncated = errors.New("truncated protobuf message")
+
+// protoEncoder accumulates protocol buffer encoded fields into a message.
+type protoEncoder []byte
+
+// uint appends a varint encoded numeric field to the message.
+func (enc *protoEncoder) uint(field int, value uint64) {
+	*enc = appendVarint(*enc, uint64(field)<<3|protoVarint)
+	*enc = appendVarint(*enc, value)
+}
+
+// bool appends a boolean field to the message.
+func (enc *protoEncoder) bool(field int, value bool) {
+	if value {
+		enc.uint(field, 1)
+	} else {
+		enc.uint(field, 0)
+	}
+}
+
+// bytes appends a length prefixed binary field to the message.
+func (enc *protoEncoder) bytes(field int, value []byte) {
+	*enc = appendVarint(*enc, uint64(field)<<3|protoBytes)
+	*enc = appendVarint(*enc, uint64(len(value)))
+	*enc = append(*enc, value...)
+}
+
+// appendVarint appends the base 128 varint encoding of value to buf.
+func appendVarint(buf []byte, value uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(buf, scratch[:binary.PutUvarint(scratch[:], value)]...)
+}
+
+// protoDecode iterates over all the fields of a protocol buffer message and
+// invokes the callback with either the numeric value (varint fields) or the
+// binary content (length prefixed fields). Unknown wire types are rejected.
+func protoDecode(msg []byte, fn func(field int, value uint64, data []byte)) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		msg = msg[n:]
+
+		switch field := int(key >> 3); key & 0x07 {
+		case protoVarint:
+			value, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			msg = msg[n:]
+			fn(field, value, nil)
+
+		case protoBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < size {
+				return errProtoTruncated
+			}
+			fn(field, 0, msg[n:n+int(size)])
+			msg = msg[n+int(size):]
+
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", key&0x07)
+		}
+	}
+	return nil
+}
+
+// trezorFeaturesReply contains the fields of a Trezor Features message that are
+// of interest to the wallet.
+type trezorFeaturesReply struct {
+	Version        [3]uint32 // Firmware version (major, minor, patch)
+	Label          string    // User assigned label of the device
+	PinProtection  bool      // Whether the device is protected with a PIN
+	PinCached      bool      // Whether the PIN is cached (no need to enter it again)
+	Initialized    bool      // Whether the device has a seed loaded
+	BootloaderMode bool      // Whether the device is running in bootloader mode
+}
+
+// decodeTrezorFeatures parses a Features message.
+func decodeTrezorFeatures(msg []byte) (*trezorFeaturesReply, error) {
+	features := new(trezorFeaturesReply)
+	err := protoDecode(msg, func(field int, value uint64, data []byte) {
+		switch field {
+		case 2, 3, 4:
+			features.Version[field-2] = uint32(value)
+		case 5:
+			features.BootloaderMode = value != 0
+		case 7:
+			features.PinProtection = value != 0
+		case 10:
+			features.Label = string(data)
+		case 12:
+			features.Initialized = value != 0
+		case 16:
+			features.PinCached = value != 0
+		}
+	})
+	return features, err
+}
+
+// decodeTrezorFailure parses a Failure message into a Go error.
+func decodeTrezorFailure(msg []byte) error {
+	var (
+		code    uint64
+		message string
+	)
+	if err := protoDecode(msg, func(field int, value uint64, data []byte) {
+		switch field {
+		case 1:
+			code = value
+		case 2:
+			message = string(data)
+		}
+	}); err != nil {
+		return err
+	}
+	if message == "" {
+		return fmt.Errorf("trezor: failure code %d", code)
+	}
+	return fmt.Errorf("trezor: %s", message)
+}
+
+// encodeTrezorPing creates a Ping message, optionally demanding the PIN to be
+// entered before the device replies.
+func encodeTrezorPing(pinProtection bool) []byte {
+	var enc protoEncoder
+	if pinProtection {
+		enc.bool(3, true)
+	}
+	return enc
+}
+
+// encodeTrezorPinMatrixAck creates a PinMatrixAck message carrying the PIN as
+// entered by the user (positions of the digits on the scrambled matrix).
+func encodeTrezorPinMatrixAck(pin string) []byte {
+	var enc protoEncoder
+	enc.bytes(1, []byte(pin))
+	return enc
+}
+
+// encodeTrezorEthereumGetAddress creates an EthereumGetAddress message for the
+// given derivation path.
+func encodeTrezorEthereumGetAddress(path []uint32) []byte {
+	var enc protoEncoder
+	for _, component := range path {
+		enc.uint(1, uint64(component))
+	}
+	return enc
+}
+
+// decodeTrezorEthereumAddress parses an EthereumAddress message, returning the
+// raw 20 byte address. Both the legacy binary and the newer hex encoded address
+// fields are supported.
+func decodeTrezorEthereumAddress(msg []byte) (address []byte, hexstr string, err error) {
+	err = protoDecode(msg, func(field int, value uint64, data []byte) {
+		switch field {
+		case 1:
+			address = data
+		case 2:
+			hexstr = string(data)
+		}
+	})
+	return address, hexstr, err
+}
+
+// trezorSignTxRequest contains the fields of an EthereumSignTx message.
+type trezorSignTxRequest struct {
+	AddressN   []uint32 // BIP-32 derivation path of the signing account
+	Nonce      []byte   // Big endian account nonce
+	GasPrice   []byte   // Big endian gas price
+	GasLimit   []byte   // Big endian gas limit
+	To         []byte   // Recipient address (empty for contract creation)
+	Value      []byte   // Big endian value to transfer
+	DataChunk  []byte   // First chunk of the transaction payload (max 1024 bytes)
+	DataLength uint32   // Total length of the transaction payload
+	ChainID    uint32   // EIP-155 chain identifier (zero for Homestead signing)
+}
+
+// encode serializes the signing request into an EthereumSignTx message.
+func (req *trezorSignTxRequest) encode() []byte {
+	var enc protoEncoder
+	for _, component := range req.AddressN {
+		enc.uint(1, uint64(component))
+	}
+	enc.bytes(2, req.Nonce)
+	enc.bytes(3, req.GasPrice)
+	enc.bytes(4, req.GasLimit)
+	if len(req.To) > 0 {
+		enc.bytes(5, req.To)
+	}
+	enc.bytes(6, req.Value)
+	if req.DataLength > 0 {
+		enc.bytes(7, req.DataChunk)
+		enc.uint(8, uint64(req.DataLength))
+	}
+	if req.ChainID != 0 {
+		enc.uint(9, uint64(req.ChainID))
+	}
+	return enc
+}
+
+// trezorTxRequestReply contains the fields of an EthereumTxRequest message.
+type trezorTxRequestReply struct {
+	DataLength uint32 // Number of payload bytes the device expects next (zero if done)
+	V          uint32 // Signature recovery identifier (with chain ID if EIP-155)
+	R          []byte // Signature R component
+	S          []byte // Signature S component
+}
+
+// decodeTrezorTxRequest parses an EthereumTxRequest message.
+func decodeTrezorTxRequest(msg []byte) (*trezorTxRequestReply, error) {
+	reply := new(trezorTxRequestReply)
+	err := protoDecode(msg, func(field int, value uint64, data []byte) {
+		switch field {
+		case 1:
+			reply.DataLength = uint32(value)
+		case 2:
+			reply.V = uint32(value)
+		case 3:
+			reply.R = data
+		case 4:
+			reply.S = data
+		}
+	})
+	return reply, err
+}
+
+// encodeTrezorEthereumTxAck creates an EthereumTxAck message with the next
+// chunk of transaction payload.
+func encodeTrezorEthereumTxAck(chunk []byte) []byte {
+	var enc protoEncoder
+	enc.bytes(1, chunk)
+	return enc
+}
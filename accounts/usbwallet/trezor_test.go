@@ -0,0 +1,138 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/log"
+)
+
+// fakeTrezor is a scripted Trezor device, recording the messages sent to it and
+// replying with a predefined message sequence.
+type fakeTrezor struct {
+	replies [][]byte     // Framed HID reports to return, in order
+	sent    bytes.Buffer // Raw HID reports written by the driver
+}
+
+// queue frames a reply message into HID reports and queues it for reading.
+func (dev *fakeTrezor) queue(kind trezorMessageType, data []byte) {
+	payload := make([]byte, 8+len(data))
+	copy(payload, "##")
+	binary.BigEndian.PutUint16(payload[2:], uint16(kind))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	for len(payload) > 0 {
+		chunk := make([]byte, 64)
+		chunk[0] = 0x3f
+		payload = payload[copy(chunk[1:], payload):]
+		dev.replies = append(dev.replies, chunk)
+	}
+}
+
+func (dev *fakeTrezor) Write(p []byte) (int, error) { return dev.sent.Write(p) }
+
+func (dev *fakeTrezor) Read(p []byte) (int, error) {
+	n := copy(p, dev.replies[0])
+	dev.replies = dev.replies[1:]
+	return n, nil
+}
+
+// Tests that the Trezor driver frames its requests correctly, transparently
+// acknowledges button requests and decodes the derived addresses.
+func TestTrezorDerive(t *testing.T) {
+	address := common.HexToAddress("0x1a642f0e3c3af545e7acbd38b07251b3990914f1")
+
+	var reply protoEncoder
+	reply.bytes(1, address[:])
+
+	dev := new(fakeTrezor)
+	dev.queue(trezorButtonRequest, nil)
+	dev.queue(trezorEthereumAddress, reply)
+
+	drv := &trezorDriver{device: dev, log: log.New()}
+	derived, err := drv.Derive(accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+	if derived != address {
+		t.Errorf("derived address mismatch: have %x, want %x", derived, address)
+	}
+	// Two requests should've been sent: EthereumGetAddress and ButtonAck
+	sent := dev.sent.Bytes()
+	if len(sent) != 128 {
+		t.Fatalf("sent data length mismatch: have %d, want %d", len(sent), 128)
+	}
+	if kind := trezorMessageType(binary.BigEndian.Uint16(sent[3:5])); kind != trezorEthereumGetAddress {
+		t.Errorf("first request type mismatch: have %v, want %v", kind, trezorEthereumGetAddress)
+	}
+	var path []uint32
+	size := binary.BigEndian.Uint32(sent[5:9])
+	if err := protoDecode(sent[9:9+size], func(field int, value uint64, data []byte) {
+		if field == 1 {
+			path = append(path, uint32(value))
+		}
+	}); err != nil {
+		t.Fatalf("failed to decode derivation request: %v", err)
+	}
+	if !reflect.DeepEqual(path, []uint32(accounts.DefaultBaseDerivationPath)) {
+		t.Errorf("derivation path mismatch: have %v, want %v", path, accounts.DefaultBaseDerivationPath)
+	}
+	if kind := trezorMessageType(binary.BigEndian.Uint16(sent[64+3 : 64+5])); kind != trezorButtonAck {
+		t.Errorf("second request type mismatch: have %v, want %v", kind, trezorButtonAck)
+	}
+}
+
+// Tests that the Trezor PIN challenge is surfaced during opening and that the
+// failure replies of the device are converted into errors.
+func TestTrezorOpenPIN(t *testing.T) {
+	var features protoEncoder
+	features.uint(2, 1)
+	features.uint(3, 5)
+	features.uint(4, 2)
+	features.bytes(10, []byte("My Trezor"))
+	features.bool(12, true)
+
+	var failure protoEncoder
+	failure.uint(1, 7)
+	failure.bytes(2, []byte("PIN invalid"))
+
+	dev := new(fakeTrezor)
+	dev.queue(trezorFeatures, features)
+	dev.queue(trezorPinMatrixRequest, nil)
+	dev.queue(trezorFailure, failure)
+
+	drv := newTrezorDriver(log.New())
+	if err := drv.Open(dev, ""); err != ErrTrezorPINNeeded {
+		t.Fatalf("open error mismatch: have %v, want %v", err, ErrTrezorPINNeeded)
+	}
+	if !drv.Offline() {
+		t.Errorf("driver online while waiting for PIN")
+	}
+	if status, want := drv.Status(), "Trezor v1.5.2 'My Trezor' waiting for PIN"; status != want {
+		t.Errorf("status mismatch: have %q, want %q", status, want)
+	}
+	if err := drv.Open(dev, "1234"); err == nil || err.Error() != "trezor: PIN invalid" {
+		t.Errorf("PIN error mismatch: have %v, want %v", err, "trezor: PIN invalid")
+	}
+}
@@ -33,6 +33,7 @@ Available commands are:
    xcode      [ -local ] [ -sign key-id ] [-deploy repo] [ -upload dest ]                      -- creates an iOS XCode framework
    xgo        [ -alltools ] [ options ]                                                        -- cross builds according to options
    purge      [ -store blobstore ] [ -days threshold ]                                         -- purges old archives from the blobstore
+   fuzz       [ -time duration ] [ packages... ]                                               -- runs the go-fuzz fuzzers
 
 For all commands, -n prevents execution of external programs (dry run mode).
 
@@ -61,6 +62,16 @@ import (
 )
 
 var (
+	// Packages containing go-fuzz fuzzers, seeded from their testdata/fuzz folder.
+	fuzzPackages = []string{
+		"./common/bitutil",
+		"./core/vm/runtime",
+		"./crypto/bls",
+		"./les",
+		"./nkc",
+		"./p2p",
+	}
+
 	// Files that end up in the netk*.zip archive.
 	netkArchiveFiles = []string{
 		"COPYING",
@@ -159,6 +170,8 @@ func main() {
 		doXgo(os.Args[2:])
 	case "purge":
 		doPurge(os.Args[2:])
+	case "fuzz":
+		doFuzz(os.Args[2:])
 	default:
 		log.Fatal("unknown command ", os.Args[1])
 	}
@@ -229,10 +242,23 @@ func doInstall(cmdline []string) {
 	}
 }
 
+// versionPkg is the package receiving the version control metadata at link time.
+const versionPkg = "github.com/networkchain/networkchain/internal/version"
+
 func buildFlags(env build.Environment) (flags []string) {
 	var ld []string
 	if env.Commit != "" {
-		ld = append(ld, "-X", "main.gitCommit="+env.Commit)
+		ld = append(ld, "-X", versionPkg+".gitCommit="+env.Commit)
+		ld = append(ld, "-X", versionPkg+".gitDate="+env.Date)
+	}
+	if env.Dirty {
+		ld = append(ld, "-X", versionPkg+".gitDirty=true")
+	}
+	// Strip the build ID and the local file system paths from the binaries, so
+	// that release builds can be reproduced bit for bit on any machine.
+	ld = append(ld, "-buildid=")
+	if hasTrimpath() {
+		flags = append(flags, "-trimpath")
 	}
 	if runtime.GOOS == "darwin" {
 		ld = append(ld, "-s")
@@ -244,6 +270,16 @@ func buildFlags(env build.Environment) (flags []string) {
 	return flags
 }
 
+// hasTrimpath reports whether the Go toolchain supports the -trimpath flag,
+// which was added in Go 1.13.
+func hasTrimpath() bool {
+	var minor int
+	if _, err := fmt.Sscanf(runtime.Version(), "go1.%d", &minor); err != nil {
+		return true // development toolchain
+	}
+	return minor >= 13
+}
+
 func goTool(subcmd string, args ...string) *exec.Cmd {
 	return goToolArch(runtime.GOARCH, subcmd, args...)
 }
@@ -296,6 +332,9 @@ func doTest(cmdline []string) {
 
 	// Run analysis tools before the tests.
 	build.MustRun(goTool("vet", packages...))
+
+	// Ensure the fuzzers keep compiling, they are excluded from regular builds.
+	build.MustRun(goTool("vet", append([]string{"-tags", "gofuzz"}, fuzzPackages...)...))
 	if *misspell {
 		// TODO(karalabe): Reenable after false detection is fixed: https://github.com/client9/misspell/issues/105
 		// spellcheck(packages)
@@ -340,6 +379,61 @@ func spellcheck(packages []string) {
 	}
 }
 
+// Fuzzing
+//
+// Every fuzzer runs in its own work directory under build/_workspace/fuzz, which
+// retains the corpus across runs. The build fails if any crashers are found.
+
+func doFuzz(cmdline []string) {
+	var (
+		duration = flag.Duration("time", 5*time.Minute, "Time to run each fuzzer for")
+	)
+	flag.CommandLine.Parse(cmdline)
+
+	packages := fuzzPackages
+	if len(flag.CommandLine.Args()) > 0 {
+		packages = flag.CommandLine.Args()
+	}
+	// Ensure the fuzzing tools are available
+	build.MustRun(goTool("get", "github.com/dvyukov/go-fuzz/go-fuzz", "github.com/dvyukov/go-fuzz/go-fuzz-build"))
+
+	var failed []string
+	for _, pkg := range packages {
+		pkg = filepath.ToSlash(filepath.Clean(pkg))
+		workdir := filepath.Join("build", "_workspace", "fuzz", strings.Replace(pkg, "/", "-", -1))
+
+		// Seed the corpus with the inputs shipped with the package
+		seeds, _ := filepath.Glob(filepath.Join(pkg, "testdata", "fuzz", "*"))
+		for _, seed := range seeds {
+			build.CopyFile(filepath.Join(workdir, "corpus", filepath.Base(seed)), seed, 0644)
+		}
+		// Instrument the package and fuzz it for the requested time
+		archive := filepath.Join(workdir, "fuzz.zip")
+		build.MustRunCommand(filepath.Join(GOBIN, "go-fuzz-build"), "-o", archive, "github.com/networkchain/networkchain/"+pkg)
+
+		fuzz := exec.Command(filepath.Join(GOBIN, "go-fuzz"), "-bin", archive, "-workdir", workdir)
+		fmt.Println(">>>", strings.Join(fuzz.Args, " "))
+		if *build.DryRunFlag {
+			continue
+		}
+		fuzz.Stdout, fuzz.Stderr = os.Stdout, os.Stderr
+		if err := fuzz.Start(); err != nil {
+			log.Fatal(err)
+		}
+		timer := time.AfterFunc(*duration, func() { fuzz.Process.Signal(os.Interrupt) })
+		fuzz.Wait()
+		timer.Stop()
+
+		if crashers, _ := filepath.Glob(filepath.Join(workdir, "crashers", "*.quoted")); len(crashers) > 0 {
+			log.Printf("%s: %d crashers found in %s", pkg, len(crashers), filepath.Join(workdir, "crashers"))
+			failed = append(failed, pkg)
+		}
+	}
+	if len(failed) > 0 {
+		log.Fatalf("fuzzing found crashers in %s", strings.Join(failed, ", "))
+	}
+}
+
 // Release Packaging
 
 func doArchive(cmdline []string) {
@@ -26,10 +26,8 @@ import (
 	"gopkg.in/urfave/cli.v1"
 )
 
-var gitCommit = "" // Git SHA1 commit hash of the release (set via linker flags)
-
 var (
-	app = utils.NewApp(gitCommit, "the evm command line interface")
+	app = utils.NewApp("the evm command line interface")
 
 	DebugFlag = cli.BoolFlag{
 		Name:  "debug",
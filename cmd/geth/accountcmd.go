@@ -19,10 +19,13 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
 
 	"github.com/networkchain/networkchain/accounts"
 	"github.com/networkchain/networkchain/accounts/keystore"
 	"github.com/networkchain/networkchain/cmd/utils"
+	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/console"
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/log"
@@ -54,6 +57,7 @@ passwordfile as argument containing the wallet password in plaintext.`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.OutputFormatFlag,
 				},
 				Description: `
 	netk wallet [options] /path/to/my/presale.wallet
@@ -72,7 +76,8 @@ passwordfile as argument containing the wallet password in plaintext.`,
 		Description: `
 
 Manage accounts, list all existing accounts, import a private key into a new
-account, create a new account or update an existing account.
+account, create a new account, update an existing account or export it into a
+portable key file.
 
 It supports interactive mode, when you are prompted for password as well as
 non-interactive mode where passwords are supplied via a given password file.
@@ -97,6 +102,7 @@ Make sure you backup your keys regularly.`,
 				Flags: []cli.Flag{
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
+					utils.OutputFormatFlag,
 				},
 				Description: `
 Print a short summary of all accounts`,
@@ -110,6 +116,7 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.OutputFormatFlag,
 				},
 				Description: `
     netk account new
@@ -135,6 +142,7 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.OutputFormatFlag,
 				},
 				Description: `
     netk account update <address>
@@ -153,6 +161,37 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export an account into a portable key file",
+				Action:    utils.MigrateFlags(accountExport),
+				ArgsUsage: "<address> [<keyFile>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.OutputFormatFlag,
+				},
+				Description: `
+    netk account export <address> [<keyfile>]
+
+Exports an existing account into an encrypted key file, which can be imported
+by any wallet supporting the keystore format. The key file is written to
+<keyfile>, or to <address>.json in the current directory if omitted.
+
+You are prompted for the passphrase unlocking the account and for a new one
+protecting the exported key file.
+
+For non-interactive use the passphrases can be specified with the --password
+flag, the first line of the file unlocking the account and the second one
+protecting the exported key file:
+
+    netk account export [options] <address> [<keyfile>]
+
+Note, exporting your key in unencrypted format is NOT supported.
 `,
 			},
 			{
@@ -164,6 +203,7 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.OutputFormatFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -192,15 +232,43 @@ nodes.
 	}
 )
 
+// accountJSON is the machine readable representation of an account printed by
+// the account commands in JSON output mode.
+type accountJSON struct {
+	Address common.Address `json:"address"`
+	URL     string         `json:"url"`
+}
+
+// printAccount prints the address of a created or imported account.
+func printAccount(ctx *cli.Context, account accounts.Account) {
+	if utils.OutputJSON(ctx) {
+		utils.PrintJSON(accountJSON{Address: account.Address, URL: account.URL.String()})
+		return
+	}
+	fmt.Printf("Address: {%s}\n", account.Address.Hex()[2:])
+}
+
 func accountList(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
-	var index int
+	output := utils.OutputJSON(ctx)
+
+	var (
+		index int
+		list  = []accountJSON{}
+	)
 	for _, wallet := range stack.AccountManager().Wallets() {
 		for _, account := range wallet.Accounts() {
-			fmt.Printf("Account #%d: {%x} %s\n", index, account.Address, &account.URL)
+			if output {
+				list = append(list, accountJSON{Address: account.Address, URL: account.URL.String()})
+			} else {
+				fmt.Printf("Account #%d: {%s} %s\n", index, account.Address.Hex()[2:], &account.URL)
+			}
 			index++
 		}
 	}
+	if output {
+		utils.PrintJSON(list)
+	}
 	return nil
 }
 
@@ -299,7 +367,7 @@ func accountCreate(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
 	}
-	fmt.Printf("Address: {%x}\n", account.Address)
+	printAccount(ctx, account)
 	return nil
 }
 
@@ -312,13 +380,53 @@ func accountUpdate(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 
+	updated := []accountJSON{}
 	for _, addr := range ctx.Args() {
 		account, oldPassword := unlockAccount(ctx, ks, addr, 0, nil)
 		newPassword := getPassPhrase("Please give a new password. Do not forget this password.", true, 0, nil)
 		if err := ks.Update(account, oldPassword, newPassword); err != nil {
 			utils.Fatalf("Could not update the account: %v", err)
 		}
+		updated = append(updated, accountJSON{Address: account.Address, URL: account.URL.String()})
+	}
+	if utils.OutputJSON(ctx) {
+		utils.PrintJSON(updated)
+	}
+	return nil
+}
+
+// accountExport writes an account into a standalone key file, re-encrypted with
+// a new passphrase.
+func accountExport(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("No account specified to export")
+	}
+	address, keyfile := ctx.Args().First(), ctx.Args().Get(1)
+	if keyfile == "" {
+		keyfile = strings.TrimPrefix(strings.ToLower(address), "0x") + ".json"
+	}
+	if _, err := os.Stat(keyfile); err == nil {
+		utils.Fatalf("Key file %s already exists", keyfile)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	passwords := utils.MakePasswordList(ctx)
+	account, password := unlockAccount(ctx, ks, address, 0, passwords)
+	newPassword := getPassPhrase("Please give a password for the exported key file. Do not forget this password.", true, 1, passwords)
+
+	keyJSON, err := ks.Export(account, password, newPassword)
+	if err != nil {
+		utils.Fatalf("Could not export the account: %v", err)
+	}
+	if err := ioutil.WriteFile(keyfile, keyJSON, 0600); err != nil {
+		utils.Fatalf("Could not write the key file: %v", err)
+	}
+	if utils.OutputJSON(ctx) {
+		utils.PrintJSON(accountJSON{Address: account.Address, URL: keyfile})
+		return nil
 	}
+	fmt.Printf("Exported account {%s} to %s\n", account.Address.Hex()[2:], keyfile)
 	return nil
 }
 
@@ -340,7 +448,7 @@ func importWallet(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("%v", err)
 	}
-	fmt.Printf("Address: {%x}\n", acct.Address)
+	printAccount(ctx, acct)
 	return nil
 }
 
@@ -361,6 +469,6 @@ func accountImport(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Could not create the account: %v", err)
 	}
-	fmt.Printf("Address: {%x}\n", acct.Address)
+	printAccount(ctx, acct)
 	return nil
 }
@@ -24,6 +24,8 @@ import (
 	"testing"
 
 	"github.com/cespare/cp"
+	"github.com/networkchain/networkchain/accounts/keystore"
+	"github.com/networkchain/networkchain/common"
 )
 
 // These tests are 'smoke tests' for the account related
@@ -53,19 +55,44 @@ func TestAccountList(t *testing.T) {
 	defer netk.ExpectExit()
 	if runtime.GOOS == "windows" {
 		netk.Expect(`
-Account #0: {7ef5a6135f1fd6a02593eedc869c6d41d934aef8} keystore://{{.Datadir}}\keystore\UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
-Account #1: {f466859ead1932d743d622cb74fc058882e8648a} keystore://{{.Datadir}}\keystore\aaa
-Account #2: {289d485d9771714cce91d3393d764e1311907acc} keystore://{{.Datadir}}\keystore\zzz
+Account #0: {7EF5A6135f1FD6a02593eEdC869c6D41D934aef8} keystore://{{.Datadir}}\keystore\UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
+Account #1: {f466859eAD1932D743d622CB74FC058882E8648A} keystore://{{.Datadir}}\keystore\aaa
+Account #2: {289d485D9771714CCe91D3393D764E1311907ACc} keystore://{{.Datadir}}\keystore\zzz
 `)
 	} else {
 		netk.Expect(`
-Account #0: {7ef5a6135f1fd6a02593eedc869c6d41d934aef8} keystore://{{.Datadir}}/keystore/UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
-Account #1: {f466859ead1932d743d622cb74fc058882e8648a} keystore://{{.Datadir}}/keystore/aaa
-Account #2: {289d485d9771714cce91d3393d764e1311907acc} keystore://{{.Datadir}}/keystore/zzz
+Account #0: {7EF5A6135f1FD6a02593eEdC869c6D41D934aef8} keystore://{{.Datadir}}/keystore/UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
+Account #1: {f466859eAD1932D743d622CB74FC058882E8648A} keystore://{{.Datadir}}/keystore/aaa
+Account #2: {289d485D9771714CCe91D3393D764E1311907ACc} keystore://{{.Datadir}}/keystore/zzz
 `)
 	}
 }
 
+func TestAccountListJSON(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("JSON escaped paths differ on windows")
+	}
+	datadir := tmpDatadirWithKeystore(t)
+	netk := runNetk(t, "account", "list", "--datadir", datadir, "--output", "json")
+	defer netk.ExpectExit()
+	netk.Expect(`
+[
+  {
+    "address": "0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8",
+    "url": "keystore://{{.Datadir}}/keystore/UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8"
+  },
+  {
+    "address": "0xf466859eAD1932D743d622CB74FC058882E8648A",
+    "url": "keystore://{{.Datadir}}/keystore/aaa"
+  },
+  {
+    "address": "0x289d485D9771714CCe91D3393D764E1311907ACc",
+    "url": "keystore://{{.Datadir}}/keystore/zzz"
+  }
+]
+`)
+}
+
 func TestAccountNew(t *testing.T) {
 	netk := runNetk(t, "account", "new", "--lightkdf")
 	defer netk.ExpectExit()
@@ -75,7 +102,7 @@ Your new account is locked with a password. Please give a password. Do not forge
 Passphrase: {{.InputLine "foobar"}}
 Repeat passphrase: {{.InputLine "foobar"}}
 `)
-	netk.ExpectRegexp(`Address: \{[0-9a-f]{40}\}\n`)
+	netk.ExpectRegexp(`Address: \{[0-9a-fA-F]{40}\}\n`)
 }
 
 func TestAccountNewBadRepeat(t *testing.T) {
@@ -106,13 +133,43 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountExport(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	keyfile := filepath.Join(datadir, "exported.json")
+	netk := runNetk(t, "account", "export",
+		"--datadir", datadir, "--lightkdf",
+		"f466859ead1932d743d622cb74fc058882e8648a", keyfile)
+	netk.Expect(`
+Unlocking account f466859ead1932d743d622cb74fc058882e8648a | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Please give a password for the exported key file. Do not forget this password.
+Passphrase: {{.InputLine "foobar2"}}
+Repeat passphrase: {{.InputLine "foobar2"}}
+Exported account {f466859eAD1932D743d622CB74FC058882E8648A} to ` + keyfile + `
+`)
+	netk.ExpectExit()
+
+	keyJSON, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		t.Fatalf("failed to read exported key file: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, "foobar2")
+	if err != nil {
+		t.Fatalf("failed to decrypt exported key file: %v", err)
+	}
+	if want := common.HexToAddress("f466859ead1932d743d622cb74fc058882e8648a"); key.Address != want {
+		t.Errorf("exported address mismatch: have %x, want %x", key.Address, want)
+	}
+}
+
 func TestWalletImport(t *testing.T) {
 	netk := runNetk(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
 	defer netk.ExpectExit()
 	netk.Expect(`
 !! Unsupported terminal, password will be echoed.
 Passphrase: {{.InputLine "foo"}}
-Address: {d4584b5f6229b7be90727b0fc8c6b91bb427821f}
+Address: {d4584b5F6229b7BE90727b0FC8C6b91BB427821f}
 `)
 
 	files, err := ioutil.ReadDir(filepath.Join(netk.Datadir, "keystore"))
@@ -146,7 +203,7 @@ Passphrase: {{.InputLine "foobar"}}
 
 	wantMessages := []string{
 		"Unlocked account",
-		"=0xf466859ead1932d743d622cb74fc058882e8648a",
+		"=0xf466859eAD1932D743d622CB74FC058882E8648A",
 	}
 	for _, m := range wantMessages {
 		if !strings.Contains(netk.StderrText(), m) {
@@ -191,8 +248,8 @@ Passphrase: {{.InputLine "foobar"}}
 
 	wantMessages := []string{
 		"Unlocked account",
-		"=0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8",
-		"=0x289d485d9771714cce91d3393d764e1311907acc",
+		"=0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8",
+		"=0x289d485D9771714CCe91D3393D764E1311907ACc",
 	}
 	for _, m := range wantMessages {
 		if !strings.Contains(netk.StderrText(), m) {
@@ -211,8 +268,8 @@ func TestUnlockFlagPasswordFile(t *testing.T) {
 
 	wantMessages := []string{
 		"Unlocked account",
-		"=0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8",
-		"=0x289d485d9771714cce91d3393d764e1311907acc",
+		"=0x7EF5A6135f1FD6a02593eEdC869c6D41D934aef8",
+		"=0x289d485D9771714CCe91D3393D764E1311907ACc",
 	}
 	for _, m := range wantMessages {
 		if !strings.Contains(netk.StderrText(), m) {
@@ -261,7 +318,7 @@ In order to avoid this warning, you need to remove the following duplicate key f
 
 	wantMessages := []string{
 		"Unlocked account",
-		"=0xf466859ead1932d743d622cb74fc058882e8648a",
+		"=0xf466859eAD1932D743d622CB74FC058882E8648A",
 	}
 	for _, m := range wantMessages {
 		if !strings.Contains(netk.StderrText(), m) {
@@ -0,0 +1,583 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/cmd/utils"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	vmruntime "github.com/networkchain/networkchain/core/vm/runtime"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/internal/ethapi"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	benchEndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "HTTP-RPC endpoint to measure instead of an in-process server",
+	}
+	benchCallsFlag = cli.IntFlag{
+		Name:  "calls",
+		Value: 1000,
+		Usage: "Number of RPC calls to measure per method",
+	}
+	benchCommand = cli.Command{
+		Action:    utils.MigrateFlags(bench),
+		Name:      "bench",
+		Usage:     "Run a deterministic performance benchmark",
+		ArgsUsage: "[<blockNumFirst> <blockNumLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+			utils.ReadOnlyFlag,
+			utils.OutputFormatFlag,
+			benchEndpointFlag,
+			benchCallsFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The bench command measures the performance of the node on the local machine:
+
+ - block import: the given range of stored blocks (by default the last 100) is
+   re-executed on top of the state of its parent and validated. Nothing is
+   written to the chain database. Seal verification is skipped.
+ - EVM: synthetic arithmetic, hashing and storage loops are run.
+ - database: a fixed set of keys is written to and read from a temporary
+   LevelDB instance.
+ - RPC: eth_blockNumber and eth_getBlockByNumber latency is measured against
+   an in-process HTTP server, or against --endpoint if given.
+
+All workloads are seeded, so reports of different releases run on the same
+data and machine are comparable. Use --output json for a machine readable
+report.`,
+	}
+)
+
+// benchReport is the result of a bench run.
+type benchReport struct {
+	Version   string             `json:"version"`
+	GoVersion string             `json:"goVersion"`
+	Platform  string             `json:"platform"`
+	CPUs      int                `json:"cpus"`
+	Import    *benchImportResult `json:"import,omitempty"`
+	EVM       []*benchEVMResult  `json:"evm"`
+	Database  *benchDBResult     `json:"database"`
+	RPC       []*benchRPCResult  `json:"rpc"`
+}
+
+// benchImportResult is the result of replaying a block range.
+type benchImportResult struct {
+	First     uint64  `json:"first"`
+	Last      uint64  `json:"last"`
+	Txs       int     `json:"txs"`
+	Gas       uint64  `json:"gas"`
+	Seconds   float64 `json:"seconds"`
+	BlocksSec float64 `json:"blocksPerSecond"`
+	TxsSec    float64 `json:"txsPerSecond"`
+	MgasSec   float64 `json:"mgasPerSecond"`
+}
+
+// benchEVMResult is the result of a synthetic EVM workload.
+type benchEVMResult struct {
+	Name    string  `json:"name"`
+	Loops   int     `json:"loops"`
+	Gas     uint64  `json:"gas"`
+	Seconds float64 `json:"seconds"`
+	MgasSec float64 `json:"mgasPerSecond"`
+}
+
+// benchDBResult is the result of the database workload.
+type benchDBResult struct {
+	Entries    int     `json:"entries"`
+	WriteOps   float64 `json:"writesPerSecond"`
+	WriteMBSec float64 `json:"writeMBPerSecond"`
+	ReadOps    float64 `json:"readsPerSecond"`
+}
+
+// benchRPCResult is the call latency of a single RPC method.
+type benchRPCResult struct {
+	Method string  `json:"method"`
+	Calls  int     `json:"calls"`
+	Mean   float64 `json:"meanMs"`
+	P50    float64 `json:"p50Ms"`
+	P99    float64 `json:"p99Ms"`
+}
+
+const (
+	benchSeed      = 1   // Seed of all randomized workloads
+	benchBlocks    = 100 // Number of blocks replayed by default
+	benchEVMRuns   = 3   // Number of runs of an EVM workload, the median is reported
+	benchDBEntries = 100000
+	benchDBValue   = 100 // Size of the values written by the database workload
+	benchDBBatch   = 1000
+)
+
+func bench(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 && len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires zero or two arguments.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	report := &benchReport{
+		Version:   params.Version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+	}
+	// Replay the requested block range, or the last blocks of the chain
+	head := chain.CurrentBlock().NumberU64()
+	first, last := uint64(1), head
+	if head > benchBlocks {
+		first = head - benchBlocks + 1
+	}
+	if len(ctx.Args()) == 2 {
+		var err error
+		if first, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			utils.Fatalf("Invalid first block number: %v", err)
+		}
+		if last, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			utils.Fatalf("Invalid last block number: %v", err)
+		}
+	}
+	if first <= last && last <= head {
+		result, err := benchImport(chain, chainDb, first, last)
+		if err != nil {
+			utils.Fatalf("Block import benchmark failed: %v", err)
+		}
+		report.Import = result
+	} else if len(ctx.Args()) == 2 {
+		utils.Fatalf("Invalid block range %d-%d, head is %d", first, last, head)
+	}
+	// Run the synthetic workloads
+	for _, workload := range benchWorkloads {
+		result, err := benchEVM(workload)
+		if err != nil {
+			utils.Fatalf("EVM benchmark %s failed: %v", workload.name, err)
+		}
+		report.EVM = append(report.EVM, result)
+	}
+	result, err := benchDatabase()
+	if err != nil {
+		utils.Fatalf("Database benchmark failed: %v", err)
+	}
+	report.Database = result
+
+	endpoint := ctx.String(benchEndpointFlag.Name)
+	if endpoint == "" {
+		srv, err := startBenchServer(chain)
+		if err != nil {
+			utils.Fatalf("Failed to start RPC server: %v", err)
+		}
+		defer srv.Close()
+		endpoint = "http://" + srv.Addr().String()
+	}
+	if report.RPC, err = benchRPC(endpoint, ctx.Int(benchCallsFlag.Name)); err != nil {
+		utils.Fatalf("RPC benchmark failed: %v", err)
+	}
+	if utils.OutputJSON(ctx) {
+		utils.PrintJSON(report)
+	} else {
+		printBenchReport(report)
+	}
+	return nil
+}
+
+// benchImport re-executes the blocks first..last on top of the state of the
+// parent of first and validates the results. State changes are kept in memory.
+func benchImport(chain *core.BlockChain, db ethdb.Database, first, last uint64) (*benchImportResult, error) {
+	if first == 0 {
+		return nil, errors.New("cannot replay the genesis block")
+	}
+	parent := chain.GetBlockByNumber(first - 1)
+	if parent == nil {
+		return nil, fmt.Errorf("block %d not found", first-1)
+	}
+	blocks := make([]*types.Block, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		blocks = append(blocks, block)
+	}
+	overlay := newOverlayDatabase(db)
+	cache := state.NewDatabase(overlay)
+	if _, err := state.New(parent.Root(), cache); err != nil {
+		return nil, fmt.Errorf("state of block %d not available: %v", first-1, err)
+	}
+	result := &benchImportResult{First: first, Last: last}
+
+	start := time.Now()
+	for _, block := range blocks {
+		if err := chain.Engine().VerifyHeader(chain, block.Header(), false); err != nil {
+			return nil, fmt.Errorf("block %d: %v", block.NumberU64(), err)
+		}
+		statedb, err := state.New(parent.Root(), cache)
+		if err != nil {
+			return nil, err
+		}
+		receipts, _, usedGas, err := chain.Processor().Process(block, statedb, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %v", block.NumberU64(), err)
+		}
+		if err := chain.Validator().ValidateState(block, parent, statedb, receipts, usedGas); err != nil {
+			return nil, fmt.Errorf("block %d: %v", block.NumberU64(), err)
+		}
+		if _, err := statedb.CommitTo(overlay, chain.Config().IsEIP158(block.Number())); err != nil {
+			return nil, err
+		}
+		result.Txs += len(block.Transactions())
+		result.Gas += usedGas.Uint64()
+		parent = block
+	}
+	result.Seconds = time.Since(start).Seconds()
+	result.BlocksSec = float64(len(blocks)) / result.Seconds
+	result.TxsSec = float64(result.Txs) / result.Seconds
+	result.MgasSec = float64(result.Gas) / 1e6 / result.Seconds
+	return result, nil
+}
+
+// overlayDatabase is a database reading through to another database, but
+// keeping all its modifications in memory.
+type overlayDatabase struct {
+	source  ethdb.Database
+	lock    sync.RWMutex
+	writes  map[string][]byte
+	deleted map[string]struct{}
+}
+
+func newOverlayDatabase(source ethdb.Database) *overlayDatabase {
+	return &overlayDatabase{
+		source:  source,
+		writes:  make(map[string][]byte),
+		deleted: make(map[string]struct{}),
+	}
+}
+
+func (db *overlayDatabase) Put(key []byte, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.writes[string(key)] = common.CopyBytes(value)
+	delete(db.deleted, string(key))
+	return nil
+}
+
+func (db *overlayDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if value, ok := db.writes[string(key)]; ok {
+		return common.CopyBytes(value), nil
+	}
+	if _, ok := db.deleted[string(key)]; ok {
+		return nil, errors.New("not found")
+	}
+	return db.source.Get(key)
+}
+
+func (db *overlayDatabase) Delete(key []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	delete(db.writes, string(key))
+	db.deleted[string(key)] = struct{}{}
+	return nil
+}
+
+// Close does nothing, the source database is owned by the caller.
+func (db *overlayDatabase) Close() {}
+
+func (db *overlayDatabase) NewBatch() ethdb.Batch {
+	return &overlayBatch{db: db}
+}
+
+type overlayBatch struct {
+	db     *overlayDatabase
+	writes []kv
+}
+
+type kv struct{ k, v []byte }
+
+func (b *overlayBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	return nil
+}
+
+func (b *overlayBatch) Write() error {
+	for _, kv := range b.writes {
+		b.db.Put(kv.k, kv.v)
+	}
+	return nil
+}
+
+// benchWorkload is a synthetic EVM workload, running body the given number of
+// times. The body must leave the stack as it found it, with the remaining loop
+// count on top.
+type benchWorkload struct {
+	name  string
+	loops int
+	body  []vm.OpCode
+}
+
+var benchWorkloads = []benchWorkload{
+	{"arithmetic", 1000000, []vm.OpCode{vm.DUP1, vm.DUP1, vm.MUL, vm.DUP2, vm.ADD, vm.PUSH1, 7, vm.SWAP1, vm.MOD, vm.POP}},
+	{"sha3", 200000, []vm.OpCode{vm.DUP1, vm.PUSH1, 0, vm.MSTORE, vm.PUSH1, 32, vm.PUSH1, 0, vm.SHA3, vm.POP}},
+	{"sstore", 10000, []vm.OpCode{vm.DUP1, vm.DUP1, vm.SSTORE}},
+}
+
+// code assembles the loop running the workload.
+func (w benchWorkload) code() []byte {
+	code := []byte{byte(vm.PUSH4), byte(w.loops >> 24), byte(w.loops >> 16), byte(w.loops >> 8), byte(w.loops), byte(vm.JUMPDEST)}
+	for _, op := range w.body {
+		code = append(code, byte(op))
+	}
+	return append(code,
+		byte(vm.PUSH1), 1, byte(vm.SWAP1), byte(vm.SUB),
+		byte(vm.DUP1), byte(vm.PUSH1), 5, byte(vm.JUMPI),
+		byte(vm.STOP),
+	)
+}
+
+// run executes the workload on a fresh state, returning the gas used.
+func (w benchWorkload) run() (uint64, error) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	address := common.BytesToAddress([]byte("bench"))
+	statedb.SetCode(address, w.code())
+
+	cfg := &vmruntime.Config{State: statedb, GasLimit: 1 << 62, BlockNumber: new(big.Int)}
+	_, leftOverGas, err := vmruntime.Call(address, nil, cfg)
+	return cfg.GasLimit - leftOverGas, err
+}
+
+// benchEVM runs a workload a few times and reports the median run.
+func benchEVM(w benchWorkload) (*benchEVMResult, error) {
+	var (
+		gas  uint64
+		runs []float64
+	)
+	for i := 0; i < benchEVMRuns; i++ {
+		start := time.Now()
+		used, err := w.run()
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, time.Since(start).Seconds())
+		gas = used
+	}
+	sort.Float64s(runs)
+	median := runs[len(runs)/2]
+	return &benchEVMResult{
+		Name:    w.name,
+		Loops:   w.loops,
+		Gas:     gas,
+		Seconds: median,
+		MgasSec: float64(gas) / 1e6 / median,
+	}, nil
+}
+
+// benchDatabase writes a fixed set of random entries to a temporary database
+// in batches and reads them back in random order.
+func benchDatabase() (*benchDBResult, error) {
+	dir, err := ioutil.TempDir("", "netk-bench")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rnd := rand.New(rand.NewSource(benchSeed))
+	keys := make([][]byte, benchDBEntries)
+	for i := range keys {
+		keys[i] = make([]byte, common.HashLength)
+		rnd.Read(keys[i])
+	}
+	value := make([]byte, benchDBValue)
+
+	start := time.Now()
+	batch := db.NewBatch()
+	for i, key := range keys {
+		rnd.Read(value)
+		batch.Put(key, value)
+		if (i+1)%benchDBBatch == 0 {
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			batch = db.NewBatch()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	writing := time.Since(start).Seconds()
+
+	start = time.Now()
+	for _, i := range rnd.Perm(len(keys)) {
+		if _, err := db.Get(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	reading := time.Since(start).Seconds()
+
+	return &benchDBResult{
+		Entries:    benchDBEntries,
+		WriteOps:   float64(benchDBEntries) / writing,
+		WriteMBSec: float64(benchDBEntries*(common.HashLength+benchDBValue)) / (1024 * 1024) / writing,
+		ReadOps:    float64(benchDBEntries) / reading,
+	}, nil
+}
+
+// BenchAPI serves the subset of the eth namespace measured by the RPC benchmark
+// directly from the chain. It is exported as required by the RPC server.
+type BenchAPI struct {
+	chain *core.BlockChain
+}
+
+func (api *BenchAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.chain.CurrentBlock().NumberU64())
+}
+
+func (api *BenchAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	block := api.chain.CurrentBlock()
+	if number >= 0 {
+		block = api.chain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return ethapi.RPCMarshalBlock(block, true, fullTx)
+}
+
+// startBenchServer serves the bench API over HTTP on a random local port.
+func startBenchServer(chain *core.BlockChain) (net.Listener, error) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", &BenchAPI{chain}); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go rpc.NewHTTPServer(nil, srv).Serve(listener)
+	return listener, nil
+}
+
+// benchRPC measures the latency of the benchmarked methods over HTTP.
+func benchRPC(endpoint string, calls int) ([]*benchRPCResult, error) {
+	client, err := rpc.DialHTTP(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var head hexutil.Uint64
+	if err := client.Call(&head, "eth_blockNumber"); err != nil {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(benchSeed))
+
+	methods := []struct {
+		name string
+		args func() []interface{}
+	}{
+		{"eth_blockNumber", func() []interface{} { return nil }},
+		{"eth_getBlockByNumber", func() []interface{} {
+			return []interface{}{hexutil.Uint64(rnd.Int63n(int64(head) + 1)), true}
+		}},
+	}
+	var results []*benchRPCResult
+	for _, method := range methods {
+		latencies := make([]float64, calls)
+		for i := range latencies {
+			var result interface{}
+			start := time.Now()
+			if err := client.Call(&result, method.name, method.args()...); err != nil {
+				return nil, fmt.Errorf("%s: %v", method.name, err)
+			}
+			latencies[i] = float64(time.Since(start)) / float64(time.Millisecond)
+		}
+		results = append(results, latencyResult(method.name, latencies))
+	}
+	return results, nil
+}
+
+// latencyResult summarizes the latencies of a method, in milliseconds.
+func latencyResult(method string, latencies []float64) *benchRPCResult {
+	result := &benchRPCResult{Method: method, Calls: len(latencies)}
+	if len(latencies) == 0 {
+		return result
+	}
+	sort.Float64s(latencies)
+	for _, latency := range latencies {
+		result.Mean += latency
+	}
+	result.Mean /= float64(len(latencies))
+	result.P50 = latencies[len(latencies)/2]
+	result.P99 = latencies[len(latencies)*99/100]
+	return result
+}
+
+func printBenchReport(report *benchReport) {
+	fmt.Printf("Version:  %s (%s, %s, %d CPUs)\n", report.Version, report.GoVersion, report.Platform, report.CPUs)
+	if imp := report.Import; imp != nil {
+		fmt.Printf("\nBlock import #%d-#%d: %d txs, %d gas in %.3fs\n", imp.First, imp.Last, imp.Txs, imp.Gas, imp.Seconds)
+		fmt.Printf("  %.2f blocks/s, %.2f txs/s, %.2f Mgas/s\n", imp.BlocksSec, imp.TxsSec, imp.MgasSec)
+	}
+	fmt.Println("\nEVM:")
+	for _, evm := range report.EVM {
+		fmt.Printf("  %-12s %8d loops  %12d gas  %8.3fs  %10.2f Mgas/s\n", evm.Name, evm.Loops, evm.Gas, evm.Seconds, evm.MgasSec)
+	}
+	db := report.Database
+	fmt.Printf("\nDatabase (%d entries):\n", db.Entries)
+	fmt.Printf("  writes  %12.0f ops/s  %8.2f MB/s\n", db.WriteOps, db.WriteMBSec)
+	fmt.Printf("  reads   %12.0f ops/s\n", db.ReadOps)
+
+	fmt.Println("\nRPC:")
+	for _, rpc := range report.RPC {
+		fmt.Printf("  %-22s %6d calls  mean %7.3fms  p50 %7.3fms  p99 %7.3fms\n", rpc.Method, rpc.Calls, rpc.Mean, rpc.P50, rpc.P99)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that a block range is replayed and validated without modifying the
+// chain database.
+func TestBenchImport(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0x01}, big.NewInt(1000), big.NewInt(21000), nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	keys := len(db.Keys())
+
+	result, err := benchImport(chain, db, 3, 10)
+	if err != nil {
+		t.Fatalf("failed to replay blocks: %v", err)
+	}
+	if result.Txs != 8 || result.Gas != 8*21000 {
+		t.Errorf("replay mismatch: have %d txs and %d gas, want 8 txs and %d gas", result.Txs, result.Gas, 8*21000)
+	}
+	if len(db.Keys()) != keys {
+		t.Errorf("chain database modified: have %d keys, want %d", len(db.Keys()), keys)
+	}
+	if _, err := benchImport(chain, db, 0, 10); err == nil {
+		t.Errorf("replaying the genesis block succeeded")
+	}
+}
+
+// Tests that the synthetic EVM workloads run their loops to completion.
+func TestBenchWorkloads(t *testing.T) {
+	for _, workload := range benchWorkloads {
+		short := workload
+		short.loops = 10
+		gas, err := short.run()
+		if err != nil {
+			t.Fatalf("%s: failed to run: %v", workload.name, err)
+		}
+		// Doubling the loop count must roughly double the gas spent
+		short.loops = 20
+		double, err := short.run()
+		if err != nil {
+			t.Fatalf("%s: failed to run: %v", workload.name, err)
+		}
+		if perLoop := (double - gas) / 10; perLoop == 0 || gas < 10*perLoop {
+			t.Errorf("%s: gas mismatch: %d gas for 10 loops, %d for 20", workload.name, gas, double)
+		}
+	}
+}
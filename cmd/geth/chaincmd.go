@@ -32,6 +32,7 @@ import (
 	"github.com/networkchain/networkchain/core/state"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/les"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -54,7 +55,7 @@ The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
 participating.
 
-It expects the genesis file as argument.`,
+It expects the genesis file as argument, or "-" to read it from standard input.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -65,6 +66,7 @@ It expects the genesis file as argument.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.RPCEnabledFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -72,7 +74,11 @@ The import command imports blocks from an RLP-encoded form. The form can be one
 with several RLP-encoded blocks, or several files can be used.
 
 If only one file is used, import error will result in failure. If several files are used, 
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+With --rpc, the argument is the endpoint (IPC, HTTP or WebSocket) of a trusted remote
+node instead, whose canonical chain is retrieved over RPC from the local head block
+onwards and executed locally.`,
 	}
 	exportCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportChain),
@@ -83,13 +89,40 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.ReadOnlyFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing.
+
+With --readonly, the chain of a running node can be exported from a snapshot.`,
+	}
+	exportCSVCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportChainCSV),
+		Name:      "export-csv",
+		Usage:     "Export blockchain data into CSV files for analytics",
+		ArgsUsage: "<directory> [<blockNumFirst> <blockNumLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+			utils.ReadOnlyFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-csv command flattens the blocks, transactions, receipts and logs of
+the local chain into blocks.csv, transactions.csv, receipts.csv and logs.csv in
+the given directory, overwriting any previous export. The layout is described by
+an accompanying schema.json, carrying a version number that is bumped on every
+incompatible column change.
+
+Optional second and third arguments control the first and last block to write,
+otherwise the whole chain up to the current head is exported.
+
+With --readonly, the chain of a running node can be exported from a snapshot.`,
 	}
 	removedbCommand = cli.Command{
 		Action:    utils.MigrateFlags(removeDB),
@@ -99,10 +132,43 @@ if already existing.`,
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
 			utils.LightModeFlag,
+			utils.OutputFormatFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-Remove blockchain and state databases`,
+Remove blockchain and state databases. With --output json the outcome for each
+database (removed, aborted or missing) is reported as a JSON list.`,
+	}
+	pruneStatePinFlag = cli.StringFlag{
+		Name:  "pin",
+		Usage: "Comma separated block numbers and ranges whose state to retain (e.g. 1000-2000,4370000)",
+	}
+	pruneStateRecentFlag = cli.Uint64Flag{
+		Name:  "recent",
+		Usage: "Number of recent blocks whose state to retain",
+		Value: 128,
+	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Prune the historical state outside of pinned block ranges",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			pruneStatePinFlag,
+			pruneStateRecentFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command deletes the state of all historical blocks from the chain
+database, except for the most recent ones (--recent) and those within the pinned
+block ranges (--pin). This keeps deep history available for the periods of
+interest without the cost of a full archive node.
+
+Pruning can only be done while the node is stopped. The state of blocks pruned
+once can't be retained anymore by pinning them in subsequent runs, so the list of
+pinned ranges should always be complete.`,
 	}
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
@@ -113,11 +179,39 @@ Remove blockchain and state databases`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.ReadOnlyFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The arguments are interpreted as block numbers or hashes.
-Use "networkchain dump 0" to dump the genesis block.`,
+Use "networkchain dump 0" to dump the genesis block.
+
+With --readonly, the state of a running node can be dumped from a snapshot.`,
+	}
+	dumpGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpGenesis),
+		Name:      "dumpgenesis",
+		Usage:     "Dump the genesis block JSON configuration to stdout",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.LightModeFlag,
+			utils.ReadOnlyFlag,
+			utils.TestnetFlag,
+			utils.RinkebyFlag,
+			utils.DevModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The dumpgenesis command prints the genesis definition of the network selected by
+the command line flags, including its chain configuration and fork schedule. If
+no network is selected and the data directory was already initialised, the
+genesis is reconstructed from the database instead, so private networks can be
+reproduced with:
+
+    netk --datadir <old> dumpgenesis | netk --datadir <new> init -
+
+Accounts and storage are sorted, making the output stable across runs.`,
 	}
 )
 
@@ -129,11 +223,14 @@ func initGenesis(ctx *cli.Context) error {
 	if len(genesisPath) == 0 {
 		utils.Fatalf("Must supply path to genesis JSON file")
 	}
-	file, err := os.Open(genesisPath)
-	if err != nil {
-		utils.Fatalf("Failed to read genesis file: %v", err)
+	file := os.Stdin
+	if genesisPath != "-" {
+		var err error
+		if file, err = os.Open(genesisPath); err != nil {
+			utils.Fatalf("Failed to read genesis file: %v", err)
+		}
+		defer file.Close()
 	}
-	defer file.Close()
 
 	genesis := new(core.Genesis)
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
@@ -155,6 +252,24 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+// dumpGenesis prints the genesis definition of the selected network, or the one
+// the data directory was initialised with, as JSON.
+func dumpGenesis(ctx *cli.Context) error {
+	genesis := utils.MakeGenesis(ctx)
+	if genesis == nil {
+		genesis = readGenesis(ctx)
+	}
+	if genesis == nil {
+		genesis = core.DefaultGenesisBlock()
+	}
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -181,7 +296,14 @@ func importChain(ctx *cli.Context) error {
 	// Import the chain
 	start := time.Now()
 
-	if len(ctx.Args()) == 1 {
+	if ctx.GlobalBool(utils.RPCEnabledFlag.Name) {
+		if len(ctx.Args()) != 1 {
+			utils.Fatalf("Importing over RPC requires a single endpoint argument.")
+		}
+		if err := utils.ImportChainRPC(chain, ctx.Args().First()); err != nil {
+			utils.Fatalf("Import error: %v", err)
+		}
+	} else if len(ctx.Args()) == 1 {
 		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
 			utils.Fatalf("Import error: %v", err)
 		}
@@ -241,7 +363,9 @@ func exportChain(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	chain, _ := utils.MakeChain(ctx, stack)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
 	start := time.Now()
 
 	var err error
@@ -268,9 +392,42 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportChainCSV(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 && len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires a directory and an optional block range.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	first, last := uint64(0), chain.CurrentBlock().NumberU64()
+	if len(ctx.Args()) == 3 {
+		var ferr, lerr error
+		first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		last, lerr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not a non-negative integer\n")
+		}
+	}
+	start := time.Now()
+	if err := utils.ExportCSV(chain, ctx.Args().First(), first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+// removedDB is the machine readable outcome of removing a single database.
+type removedDB struct {
+	Database string `json:"database"`
+	Path     string `json:"path"`
+	Status   string `json:"status"` // One of "removed", "aborted" or "missing"
+}
+
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
+	results := []removedDB{}
 	for _, name := range []string{"chaindata", "lightchaindata"} {
 		// Ensure the database exists in the first place
 		logger := log.New("database", name)
@@ -278,6 +435,7 @@ func removeDB(ctx *cli.Context) error {
 		dbdir := stack.ResolvePath(name)
 		if !common.FileExist(dbdir) {
 			logger.Info("Database doesn't exist, skipping", "path", dbdir)
+			results = append(results, removedDB{name, dbdir, "missing"})
 			continue
 		}
 		// Confirm removal and execute
@@ -288,12 +446,48 @@ func removeDB(ctx *cli.Context) error {
 			utils.Fatalf("%v", err)
 		case !confirm:
 			logger.Warn("Database deletion aborted")
+			results = append(results, removedDB{name, dbdir, "aborted"})
 		default:
 			start := time.Now()
 			os.RemoveAll(dbdir)
 			logger.Info("Database successfully deleted", "elapsed", common.PrettyDuration(time.Since(start)))
+			results = append(results, removedDB{name, dbdir, "removed"})
 		}
 	}
+	if utils.OutputJSON(ctx) {
+		utils.PrintJSON(results)
+	}
+	return nil
+}
+
+func pruneState(ctx *cli.Context) error {
+	if ctx.GlobalBool(utils.LightModeFlag.Name) {
+		utils.Fatalf("Light clients don't store state to prune.")
+	}
+	pinned, err := core.ParseBlockRanges(ctx.String(pruneStatePinFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid pinned ranges: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := core.PruneState(chainDb, pinned, ctx.Uint64(pruneStateRecentFlag.Name), append(les.ChtRoots(chainDb), les.BloomTrieRoots(chainDb)...))
+	if err != nil {
+		utils.Fatalf("Prune error: %v", err)
+	}
+	fmt.Printf("Pruned %d trie nodes and codes (%v), retained state of %d blocks in %v\n", stats.Deleted, stats.Freed, stats.Retained, time.Since(start))
+	if stats.Missing > 0 {
+		fmt.Printf("State of %d pinned blocks was already pruned before\n", stats.Missing)
+	}
+	// Compact the database to actually release the freed up space
+	start = time.Now()
+	fmt.Println("Compacting entire database...")
+	if err := chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n", time.Since(start))
 	return nil
 }
 
@@ -323,6 +517,31 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// readGenesis reconstructs the genesis the data directory was initialised with,
+// returning nil if there is no chain database yet.
+func readGenesis(ctx *cli.Context) *core.Genesis {
+	stack, _ := makeConfigNode(ctx)
+
+	name := "chaindata"
+	if ctx.GlobalBool(utils.LightModeFlag.Name) {
+		name = "lightchaindata"
+	}
+	if !common.FileExist(stack.ResolvePath(name)) {
+		return nil
+	}
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	if core.GetCanonicalHash(chainDb, 0) == (common.Hash{}) {
+		return nil
+	}
+	genesis, err := core.ReadGenesis(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to read stored genesis: %v", err)
+	}
+	return genesis
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -24,6 +24,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"unicode"
 
 	cli "gopkg.in/urfave/cli.v1"
@@ -31,8 +32,14 @@ import (
 	"github.com/networkchain/networkchain/cmd/utils"
 	"github.com/networkchain/networkchain/contracts/release"
 	"github.com/networkchain/networkchain/eth"
+	"github.com/networkchain/networkchain/eth/eventsink"
+	"github.com/networkchain/networkchain/eth/webhooks"
+	"github.com/networkchain/networkchain/internal/debug"
+	"github.com/networkchain/networkchain/internal/governor"
+	"github.com/networkchain/networkchain/internal/version"
 	"github.com/networkchain/networkchain/node"
 	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/tracing/otlp"
 	whisper "github.com/networkchain/networkchain/whisper/whisperv5"
 	"github.com/naoina/toml"
 )
@@ -52,6 +59,10 @@ var (
 		Name:  "config",
 		Usage: "TOML configuration file",
 	}
+	networksFlag = cli.StringFlag{
+		Name:  "networks",
+		Usage: "Comma separated additional networks to host in-process (<name>=<TOML configuration file>)",
+	}
 )
 
 // These settings ensure that TOML keys use the same names as Go struct fields.
@@ -72,14 +83,26 @@ var tomlSettings = toml.Config{
 }
 
 type ethstatsConfig struct {
-	URL string `toml:",omitempty"`
+	URL    string `toml:",omitempty"`
+	File   string `toml:",omitempty"`
+	Socket string `toml:",omitempty"`
+}
+
+type logConfig struct {
+	Level   string `toml:",omitempty"`
+	Vmodule string `toml:",omitempty"`
 }
 
 type netkConfig struct {
-	Eth      eth.Config
-	Shh      whisper.Config
-	Node     node.Config
-	Ethstats ethstatsConfig
+	Log       logConfig
+	Eth       eth.Config
+	Shh       whisper.Config
+	Node      node.Config
+	Ethstats  ethstatsConfig
+	Governor  governor.Config
+	Webhooks  webhooks.Config
+	EventSink eventsink.Config
+	Tracing   otlp.Config
 }
 
 func loadConfig(file string, cfg *netkConfig) error {
@@ -100,20 +123,28 @@ func loadConfig(file string, cfg *netkConfig) error {
 func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
-	cfg.Version = params.VersionWithCommit(gitCommit)
+	cfg.Version = version.Get().String()
 	cfg.HTTPModules = append(cfg.HTTPModules, "eth", "shh")
 	cfg.WSModules = append(cfg.WSModules, "eth", "shh")
 	cfg.IPCPath = "netk.ipc"
 	return cfg
 }
 
+func defaultConfig() netkConfig {
+	return netkConfig{
+		Eth:       eth.DefaultConfig,
+		Shh:       whisper.DefaultConfig,
+		Node:      defaultNodeConfig(),
+		Governor:  governor.DefaultConfig,
+		Webhooks:  webhooks.DefaultConfig,
+		EventSink: eventsink.DefaultConfig,
+		Tracing:   otlp.DefaultConfig,
+	}
+}
+
 func makeConfigNode(ctx *cli.Context) (*node.Node, netkConfig) {
 	// Load defaults.
-	cfg := netkConfig{
-		Eth:  eth.DefaultConfig,
-		Shh:  whisper.DefaultConfig,
-		Node: defaultNodeConfig(),
-	}
+	cfg := defaultConfig()
 
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
@@ -123,6 +154,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, netkConfig) {
 	}
 
 	// Apply flags.
+	if err := debug.SetLogLevels(ctx, cfg.Log.Level, cfg.Log.Vmodule); err != nil {
+		utils.Fatalf("Invalid log settings: %v", err)
+	}
 	utils.SetNodeConfig(ctx, &cfg.Node)
 	stack, err := node.New(&cfg.Node)
 	if err != nil {
@@ -132,8 +166,18 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, netkConfig) {
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(utils.EthStatsFileFlag.Name) {
+		cfg.Ethstats.File = ctx.GlobalString(utils.EthStatsFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.EthStatsSocketFlag.Name) {
+		cfg.Ethstats.Socket = ctx.GlobalString(utils.EthStatsSocketFlag.Name)
+	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
+	utils.SetGovernorConfig(ctx, &cfg.Governor)
+	utils.SetWebhooksConfig(ctx, &cfg.Webhooks)
+	utils.SetEventSinkConfig(ctx, &cfg.EventSink)
+	utils.SetTracingConfig(ctx, &cfg.Tracing)
 
 	return stack, cfg
 }
@@ -152,6 +196,7 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
 
 	utils.RegisterEthService(stack, &cfg.Eth)
+	registerNetworks(ctx, stack)
 
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag or in dev mode
 	shhEnabled := enableWhisper(ctx)
@@ -167,8 +212,28 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	}
 
 	// Add the NetworkChain Stats daemon if requested.
-	if cfg.Ethstats.URL != "" {
-		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
+	if cfg.Ethstats.URL != "" || cfg.Ethstats.File != "" || cfg.Ethstats.Socket != "" {
+		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL, cfg.Ethstats.File, cfg.Ethstats.Socket)
+	}
+
+	// Add the web-hooks service if requested.
+	if cfg.Webhooks.Enabled {
+		utils.RegisterWebhooksService(stack, &cfg.Webhooks)
+	}
+
+	// Add the event sink if a message broker is configured.
+	if cfg.EventSink.URL != "" {
+		utils.RegisterEventSinkService(stack, &cfg.EventSink)
+	}
+
+	// Add the tracing span exporter if a collector is configured.
+	if cfg.Tracing.Endpoint != "" {
+		utils.RegisterTracingService(stack, &cfg.Tracing)
+	}
+
+	// Add the memory governor if any watermark is configured.
+	if cfg.Governor.Enabled() {
+		utils.RegisterGovernorService(stack, &cfg.Governor)
 	}
 
 	// Add the release oracle service so it boots along with node.
@@ -179,7 +244,7 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 			Minor:  uint32(params.VersionMinor),
 			Patch:  uint32(params.VersionPatch),
 		}
-		commit, _ := hex.DecodeString(gitCommit)
+		commit, _ := hex.DecodeString(version.Commit())
 		copy(config.Commit[:], commit)
 		return release.NewReleaseService(ctx, config)
 	}); err != nil {
@@ -188,6 +253,34 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	return stack
 }
 
+// registerNetworks adds the chain services of the additional networks requested
+// to be hosted in-process to the stack. Each network is configured by its own
+// TOML file, which must specify a separate data directory and listening port.
+func registerNetworks(ctx *cli.Context, stack *node.Node) {
+	spec := ctx.GlobalString(networksFlag.Name)
+	if spec == "" {
+		return
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			utils.Fatalf("Invalid network %q, expected <name>=<config file>", entry)
+		}
+		cfg := netkConfig{
+			Eth:  eth.DefaultConfig,
+			Node: defaultNodeConfig(),
+		}
+		if err := loadConfig(parts[1], &cfg); err != nil {
+			utils.Fatalf("Failed to load network %s: %v", parts[0], err)
+		}
+		network, err := stack.AddNetwork(parts[0], &cfg.Node)
+		if err != nil {
+			utils.Fatalf("Failed to add network %s: %v", parts[0], err)
+		}
+		utils.RegisterEthService(network, &cfg.Eth)
+	}
+}
+
 // dumpConfig is the dumpconfig command.
 func dumpConfig(ctx *cli.Context) error {
 	_, cfg := makeConfigNode(ctx)
@@ -0,0 +1,44 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that disabling address checksums applies to the addresses of the config
+// file too, which is loaded before the node related flags are applied.
+func TestConfigAddressChecksums(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	// Configure an etherbase with an invalid mixed-case checksum
+	config := filepath.Join(datadir, "config.toml")
+	if err := ioutil.WriteFile(config, []byte("[Eth]\nEtherbase = \"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD\"\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	netk := runNetk(t, "--datadir", datadir, "--config", config, "dumpconfig")
+	netk.ExpectRegexp(`Fatal: .*invalid address checksum`)
+	netk.WaitExit()
+
+	netk = runNetk(t, "--datadir", datadir, "--noaddresschecksum", "--config", config, "dumpconfig")
+	netk.ExpectRegexp(`(?s).*Etherbase = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"`)
+	netk.WaitExit()
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/networkchain/networkchain/console"
 	"github.com/networkchain/networkchain/node"
 	"github.com/networkchain/networkchain/rpc"
+	"github.com/robertkrimen/otto"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -61,12 +62,17 @@ This command allows to open a console on a running netk node.`,
 		Action:    utils.MigrateFlags(ephemeralConsole),
 		Name:      "js",
 		Usage:     "Execute the specified JavaScript files",
-		ArgsUsage: "<jsfile> [jsfile...]",
+		ArgsUsage: "<jsfile> [jsfile...] [-- args...]",
 		Flags:     append(nodeFlags, consoleFlags...),
 		Category:  "CONSOLE COMMANDS",
 		Description: `
 The JavaScript VM exposes a node admin interface as well as the Ðapp
-JavaScript API. See https://github.com/networkchain/networkchain/wiki/Javascipt-Console`,
+JavaScript API. See https://github.com/networkchain/networkchain/wiki/Javascipt-Console
+
+The files are executed in order. Any arguments after -- are exposed to them as
+process.argv, local modules can be loaded with require("./module.js"). A script
+may terminate with a specific exit code by calling process.exit(code), whereas
+uncaught errors terminate the command with exit code 1.`,
 	}
 )
 
@@ -169,22 +175,32 @@ func ephemeralConsole(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to attach to the inproc netk: %v", err)
 	}
+	files, argv := splitScriptArgs(ctx.Args(), os.Args)
 	config := console.Config{
 		DataDir: utils.MakeDataDir(ctx),
 		DocRoot: ctx.GlobalString(utils.JSpathFlag.Name),
 		Client:  client,
 		Preload: utils.MakeConsolePreloads(ctx),
+		Argv:    argv,
 	}
 
-	console, err := console.New(config)
+	jsconsole, err := console.New(config)
 	if err != nil {
 		utils.Fatalf("Failed to start the JavaScript console: %v", err)
 	}
-	defer console.Stop(false)
+	defer jsconsole.Stop(false)
 
 	// Evaluate each of the specified JavaScript files
-	for _, file := range ctx.Args() {
-		if err = console.Execute(file); err != nil {
+	for _, file := range files {
+		if err = jsconsole.Execute(file); err != nil {
+			if exit, ok := err.(*console.ExitError); ok {
+				jsconsole.Stop(false)
+				node.Stop()
+				os.Exit(exit.Code)
+			}
+			if ottoErr, ok := err.(*otto.Error); ok {
+				utils.Fatalf("Failed to execute %s: %s", file, ottoErr.String())
+			}
 			utils.Fatalf("Failed to execute %s: %v", file, err)
 		}
 	}
@@ -196,7 +212,22 @@ func ephemeralConsole(ctx *cli.Context) error {
 		<-abort
 		os.Exit(0)
 	}()
-	console.Stop(true)
+	jsconsole.Stop(true)
 
 	return nil
 }
+
+// splitScriptArgs separates the JavaScript files to execute from the arguments
+// to pass to them. The CLI parser drops the -- separator, so its position is
+// looked up in the raw process arguments instead.
+func splitScriptArgs(args []string, raw []string) (files []string, argv []string) {
+	for i, arg := range raw {
+		if arg == "--" {
+			if n := len(raw) - i - 1; n <= len(args) {
+				return args[:len(args)-n], args[len(args)-n:]
+			}
+			break
+		}
+	}
+	return args, nil
+}
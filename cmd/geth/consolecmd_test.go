@@ -38,7 +38,7 @@ const (
 // Tests that a node embedded within a console can be started up properly and
 // then terminated by closing the input stream.
 func TestConsoleWelcome(t *testing.T) {
-	coinbase := "0x8605cdbbdb6d264aa742e77020dcbc58fcdce182"
+	coinbase := "0x8605CdbbDb6D264Aa742e77020dCbc58FcDCe182"
 
 	// Start a netk console, make sure it's cleaned up and terminate the console
 	netk := runNetk(t,
@@ -72,7 +72,7 @@ at block: 0 ({{niltime}})
 // Tests that a console can be attached to a running node via various means.
 func TestIPCAttachWelcome(t *testing.T) {
 	// Configure the instance for IPC attachement
-	coinbase := "0x8605cdbbdb6d264aa742e77020dcbc58fcdce182"
+	coinbase := "0x8605CdbbDb6D264Aa742e77020dCbc58FcDCe182"
 	var ipc string
 	if runtime.GOOS == "windows" {
 		ipc = `\\.\pipe\netk` + strconv.Itoa(trulyRandInt(100000, 999999))
@@ -95,7 +95,7 @@ func TestIPCAttachWelcome(t *testing.T) {
 }
 
 func TestHTTPAttachWelcome(t *testing.T) {
-	coinbase := "0x8605cdbbdb6d264aa742e77020dcbc58fcdce182"
+	coinbase := "0x8605CdbbDb6D264Aa742e77020dCbc58FcDCe182"
 	port := strconv.Itoa(trulyRandInt(1024, 65536)) // Yeah, sometimes this will fail, sorry :P
 	netk := runNetk(t,
 		"--port", "0", "--maxpeers", "0", "--nodiscover", "--nat", "none",
@@ -109,7 +109,7 @@ func TestHTTPAttachWelcome(t *testing.T) {
 }
 
 func TestWSAttachWelcome(t *testing.T) {
-	coinbase := "0x8605cdbbdb6d264aa742e77020dcbc58fcdce182"
+	coinbase := "0x8605CdbbDb6D264Aa742e77020dCbc58FcDCe182"
 	port := strconv.Itoa(trulyRandInt(1024, 65536)) // Yeah, sometimes this will fail, sorry :P
 
 	netk := runNetk(t,
@@ -17,10 +17,15 @@
 package main
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/networkchain/networkchain/core"
 )
 
 var customGenesisTests = []struct {
@@ -82,6 +87,27 @@ var customGenesisTests = []struct {
 		query:  "eth.getBlock(0).nonce",
 		result: "0x0000000000000042",
 	},
+	// Genesis file with a chain configuration reported by the admin API
+	{
+		genesis: `{
+			"alloc"      : {},
+			"coinbase"   : "0x0000000000000000000000000000000000000000",
+			"difficulty" : "0x20000",
+			"extraData"  : "",
+			"gasLimit"   : "0x2fefd8",
+			"nonce"      : "0x0000000000000042",
+			"mixhash"    : "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"parentHash" : "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"timestamp"  : "0x00",
+			"config"     : {
+				"chainId"        : 1337,
+				"homesteadBlock" : 314,
+				"eip150Block"    : 628
+			}
+		}`,
+		query:  "[admin.chainConfig.chainId, admin.chainConfig.homesteadBlock, admin.chainConfig.eip150Block].join()",
+		result: "1337,314,628",
+	},
 }
 
 // Tests that initializing Netk with a custom genesis block and chain definitions
@@ -108,3 +134,92 @@ func TestCustomGenesis(t *testing.T) {
 		netk.ExpectExit()
 	}
 }
+
+// Tests that the genesis definitions dumped for the built-in networks are the
+// ones the networks are initialised with.
+func TestDumpGenesis(t *testing.T) {
+	tests := []struct {
+		flags   []string
+		genesis *core.Genesis
+	}{
+		{nil, core.DefaultGenesisBlock()},
+		{[]string{"--testnet"}, core.DefaultTestnetGenesisBlock()},
+		{[]string{"--rinkeby"}, core.DefaultRinkebyGenesisBlock()},
+	}
+	for i, tt := range tests {
+		netk := runNetk(t, append(tt.flags, "dumpgenesis")...)
+		_, matches := netk.ExpectRegexp(`(?s)^\{.*\}\n$`)
+		netk.ExpectExit()
+		if len(matches) == 0 {
+			continue
+		}
+		dumped := new(core.Genesis)
+		if err := json.Unmarshal([]byte(matches[0]), dumped); err != nil {
+			t.Errorf("test %d: failed to parse dumped genesis: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(dumped.Config, tt.genesis.Config) {
+			t.Errorf("test %d: chain config mismatch: have %v, want %v", i, dumped.Config, tt.genesis.Config)
+		}
+		have, _ := dumped.ToBlock()
+		want, _ := tt.genesis.ToBlock()
+		if have.Hash() != want.Hash() {
+			t.Errorf("test %d: genesis hash mismatch: have %x, want %x", i, have.Hash(), want.Hash())
+		}
+	}
+}
+
+// Tests that the genesis a data directory was initialised with can be dumped and
+// used to initialise another data directory with the very same genesis.
+func TestDumpGenesisRoundTrip(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	json := filepath.Join(datadir, "genesis.json")
+	genesis := `{
+		"alloc"      : {
+			"0x0000000000000000000000000000000000000aaa": {
+				"balance": "0x10",
+				"code"   : "0x600035600055",
+				"storage": {"0x01": "0x02"}
+			}
+		},
+		"difficulty" : "0x20000",
+		"extraData"  : "0x1234",
+		"gasLimit"   : "0x2fefd8",
+		"nonce"      : "0x0000000000000042",
+		"timestamp"  : "0x00",
+		"config"     : {"chainId": 1337, "homesteadBlock": 5}
+	}`
+	if err := ioutil.WriteFile(json, []byte(genesis), 0600); err != nil {
+		t.Fatalf("failed to write genesis file: %v", err)
+	}
+	original := filepath.Join(datadir, "original")
+	runNetk(t, "--datadir", original, "init", json).WaitExit()
+
+	dumpgenesis := func(datadir string) string {
+		netk := runNetk(t, "--datadir", datadir, "dumpgenesis")
+		_, matches := netk.ExpectRegexp(`(?s)^\{.*\}\n$`)
+		netk.ExpectExit()
+		if len(matches) == 0 {
+			return ""
+		}
+		return matches[0]
+	}
+	dump := dumpgenesis(original)
+	for _, want := range []string{`"chainId": 1337`, `"extraData": "0x1234"`, `"code": "0x600035600055"`} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dumped genesis missing %s:\n%s", want, dump)
+		}
+	}
+	// Feed the dump into a fresh data directory and ensure it reproduces the genesis
+	copied := filepath.Join(datadir, "copied")
+	netk := runNetk(t, "--datadir", copied, "init", "-")
+	netk.InputLine(dump)
+	netk.CloseStdin()
+	netk.WaitExit()
+
+	if copy := dumpgenesis(copied); copy != dump {
+		t.Errorf("round-tripped genesis mismatch:\n%s\nwant:\n%s", copy, dump)
+	}
+}
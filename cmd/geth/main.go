@@ -26,12 +26,14 @@ import (
 
 	"github.com/networkchain/networkchain/accounts"
 	"github.com/networkchain/networkchain/accounts/keystore"
+	"github.com/networkchain/networkchain/accounts/usbwallet"
 	"github.com/networkchain/networkchain/cmd/utils"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/console"
 	"github.com/networkchain/networkchain/eth"
 	"github.com/networkchain/networkchain/ethclient"
 	"github.com/networkchain/networkchain/internal/debug"
+	"github.com/networkchain/networkchain/internal/supervisor"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/metrics"
 	"github.com/networkchain/networkchain/node"
@@ -43,21 +45,21 @@ const (
 )
 
 var (
-	// Git SHA1 commit hash of the release (set via linker flags)
-	gitCommit = ""
 	// NetworkChain address of the Netk release oracle.
 	relOracle = common.HexToAddress("0xfa7b9770ca4cb04296cac84f37736d4041251cdf")
 	// The app that holds all commands and flags.
-	app = utils.NewApp(gitCommit, "the networkchain command line interface")
+	app = utils.NewApp("the networkchain command line interface")
 	// flags that configure the node
 	nodeFlags = []cli.Flag{
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
+		utils.SigningPolicyFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.ReadOnlyFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
 		utils.EthashCacheDirFlag,
@@ -66,7 +68,11 @@ var (
 		utils.EthashDatasetDirFlag,
 		utils.EthashDatasetsInMemoryFlag,
 		utils.EthashDatasetsOnDiskFlag,
+		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
+		utils.TxPoolRebroadcastFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -74,14 +80,29 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxRescueFlag,
+		utils.TxRescueWindowFlag,
+		utils.TxRescuePriceBumpFlag,
+		utils.TxRescuePriceCapFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.GCModeFlag,
+		utils.StaticSyncFlag,
+		utils.SyncAnchorFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightRetentionFlag,
+		utils.LightProbeFlag,
+		utils.LightPreferNetsFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.TrieCacheGenFlag,
+		utils.HeaderCacheFlag,
+		utils.LogIndexFlag,
+		utils.SnapshotFlag,
+		utils.GovernorHighFlag,
+		utils.GovernorCriticalFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -94,22 +115,42 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.ProxyFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
+		utils.NodeKeyPasswordFlag,
+		utils.NodeKeyMachineFlag,
 		utils.DevModeFlag,
 		utils.TestnetFlag,
 		utils.RinkebyFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMTrackAccessFlag,
+		utils.VMParallelFlag,
+		utils.VMWitnessFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.EthStatsURLFlag,
+		utils.EthStatsFileFlag,
+		utils.EthStatsSocketFlag,
+		utils.WebhooksFlag,
+		utils.WebhooksRetriesFlag,
+		utils.EventSinkURLFlag,
+		utils.EventSinkEncodingFlag,
+		utils.TracingEndpointFlag,
+		utils.TracingServiceFlag,
+		utils.TracingSampleFlag,
+		utils.TracingHeadersFlag,
 		utils.MetricsEnabledFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
 		utils.ExtraDataFlag,
+		utils.MinerTxOrderFlag,
+		utils.MinerNotifyFlag,
+		utils.RemoteSealerFlag,
 		configFileFlag,
+		networksFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -124,6 +165,7 @@ var (
 		utils.WSAllowedOriginsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.NoAddressChecksumFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -143,8 +185,13 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		exportCSVCommand,
 		removedbCommand,
+		pruneStateCommand,
 		dumpCommand,
+		dumpGenesisCommand,
+		// See benchcmd.go:
+		benchCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -161,6 +208,8 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See servicecmd.go:
+		serviceCommand,
 	}
 
 	app.Flags = append(app.Flags, nodeFlags...)
@@ -168,6 +217,7 @@ func init() {
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, whisperFlags...)
+	app.Flags = utils.BindEnvVars(app.Flags)
 
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -178,6 +228,7 @@ func init() {
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
 		utils.SetupNetwork(ctx)
+		utils.SetupAddressChecksums(ctx)
 		return nil
 	}
 
@@ -199,8 +250,27 @@ func main() {
 // It creates a default node based on the command line arguments and runs it in
 // blocking mode, waiting for it to be shut down.
 func netk(ctx *cli.Context) error {
+	err := supervisor.RunService(func(stop <-chan struct{}) error {
+		return runNode(ctx, stop)
+	})
+	if err != supervisor.ErrNotService {
+		return err
+	}
+	return runNode(ctx, nil)
+}
+
+// runNode runs the node until it is shut down, either by an interrupt or by
+// closing the stop channel.
+func runNode(ctx *cli.Context, stop <-chan struct{}) error {
 	node := makeFullNode(ctx)
 	startNode(ctx, node)
+	if stop != nil {
+		go func() {
+			<-stop
+			log.Info("Got service stop request, shutting down...")
+			node.Stop()
+		}()
+	}
 	node.Wait()
 	return nil
 }
@@ -211,6 +281,7 @@ func netk(ctx *cli.Context) error {
 func startNode(ctx *cli.Context, stack *node.Node) {
 	// Start up the node itself
 	utils.StartNode(stack)
+	watchConfig(ctx, stack)
 
 	// Unlock any account specifically requested
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
@@ -236,20 +307,28 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 
 		// Open and self derive any wallets already attached
 		for _, wallet := range stack.AccountManager().Wallets() {
-			if err := wallet.Open(""); err != nil {
-				log.Warn("Failed to open wallet", "url", wallet.URL(), "err", err)
-			} else {
+			switch err := wallet.Open(""); err {
+			case nil:
 				wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
+			case usbwallet.ErrTrezorPINNeeded:
+				log.Info("Wallet waiting for PIN, unlock with personal.openWallet", "url", wallet.URL())
+				wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
+			default:
+				log.Warn("Failed to open wallet", "url", wallet.URL(), "err", err)
 			}
 		}
 		// Listen for wallet event till termination
 		for event := range events {
 			if event.Arrive {
-				if err := event.Wallet.Open(""); err != nil {
-					log.Warn("New wallet appeared, failed to open", "url", event.Wallet.URL(), "err", err)
-				} else {
+				switch err := event.Wallet.Open(""); err {
+				case nil:
 					log.Info("New wallet appeared", "url", event.Wallet.URL(), "status", event.Wallet.Status())
 					event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
+				case usbwallet.ErrTrezorPINNeeded:
+					log.Info("New wallet appeared, waiting for PIN", "url", event.Wallet.URL())
+					event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
+				default:
+					log.Warn("New wallet appeared, failed to open", "url", event.Wallet.URL(), "err", err)
 				}
 			} else {
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
@@ -279,4 +358,6 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			utils.Fatalf("Failed to start mining: %v", err)
 		}
 	}
+	// Report the started node to the process supervisor, if any
+	notifySupervisor(stack)
 }
@@ -28,7 +28,7 @@ import (
 	"github.com/networkchain/networkchain/cmd/utils"
 	"github.com/networkchain/networkchain/consensus/ethash"
 	"github.com/networkchain/networkchain/eth"
-	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/internal/version"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -47,7 +47,7 @@ Regular users do not need to execute it.
 `,
 	}
 	versionCommand = cli.Command{
-		Action:    utils.MigrateFlags(version),
+		Action:    utils.MigrateFlags(printVersion),
 		Name:      "version",
 		Usage:     "Print version numbers",
 		ArgsUsage: " ",
@@ -95,12 +95,9 @@ func makedag(ctx *cli.Context) error {
 	return nil
 }
 
-func version(ctx *cli.Context) error {
+func printVersion(ctx *cli.Context) error {
 	fmt.Println(strings.Title(clientIdentifier))
-	fmt.Println("Version:", params.Version)
-	if gitCommit != "" {
-		fmt.Println("Git Commit:", gitCommit)
-	}
+	version.Get().Print()
 	fmt.Println("Architecture:", runtime.GOARCH)
 	fmt.Println("Protocol Versions:", eth.ProtocolVersions)
 	fmt.Println("Network Id:", eth.DefaultConfig.NetworkId)
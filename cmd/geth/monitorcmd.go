@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/networkchain/networkchain/cmd/utils"
+	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/node"
 	"github.com/networkchain/networkchain/rpc"
 	"github.com/gizak/termui"
@@ -58,6 +59,11 @@ var (
 The Netk monitor is a tool to collect and visualize various internal metrics
 gathered by the node, supporting different chart types as well as the capacity
 to display multiple metrics simultaneously.
+
+Besides the metrics collected with --metrics, the block height, peer count and
+transaction pool status of the node are always available under node/, e.g.
+
+    netk monitor node/chain/height node/p2p/peers node/txpool/pending,queued
 `,
 		Flags: []cli.Flag{
 			monitorCommandAttachFlag,
@@ -159,11 +165,48 @@ func monitor(ctx *cli.Context) error {
 }
 
 // retrieveMetrics contacts the attached netk node and retrieves the entire set
-// of collected system metrics.
+// of collected system metrics, extended with the live status of the node.
 func retrieveMetrics(client *rpc.Client) (map[string]interface{}, error) {
 	var metrics map[string]interface{}
-	err := client.Call(&metrics, "debug_metrics", true)
-	return metrics, err
+	if err := client.Call(&metrics, "debug_metrics", true); err != nil {
+		return nil, err
+	}
+	status, err := retrieveNodeStatus(client)
+	if err != nil {
+		return nil, err
+	}
+	metrics["node"] = status
+	return metrics, nil
+}
+
+// retrieveNodeStatus queries the chain height, peer count and transaction pool
+// status of the attached node, which are tracked independently of the metrics
+// system. Values of the APIs not exposed by the node are omitted.
+func retrieveNodeStatus(client *rpc.Client) (map[string]interface{}, error) {
+	var (
+		height hexutil.Uint64
+		peers  hexutil.Uint
+		pool   map[string]hexutil.Uint
+	)
+	batch := []rpc.BatchElem{
+		{Method: "eth_blockNumber", Result: &height},
+		{Method: "net_peerCount", Result: &peers},
+		{Method: "txpool_status", Result: &pool},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		return nil, err
+	}
+	status := make(map[string]interface{})
+	if batch[0].Error == nil {
+		status["chain"] = map[string]interface{}{"height": float64(height)}
+	}
+	if batch[1].Error == nil {
+		status["p2p"] = map[string]interface{}{"peers": float64(peers)}
+	}
+	if batch[2].Error == nil {
+		status["txpool"] = map[string]interface{}{"pending": float64(pool["pending"]), "queued": float64(pool["queued"])}
+	}
+	return status, nil
 }
 
 // resolveMetrics takes a list of input metric patterns, and resolves each to one
@@ -255,7 +298,7 @@ func fetchMetric(metrics map[string]interface{}, metric string) float64 {
 func refreshCharts(client *rpc.Client, metrics []string, data [][]float64, units []int, charts []*termui.LineChart, ctx *cli.Context, footer *termui.Par) (realign bool) {
 	values, err := retrieveMetrics(client)
 	for i, metric := range metrics {
-		if len(data) < 512 {
+		if len(data[i]) < 512 {
 			data[i] = append([]float64{fetchMetric(values, metric)}, data[i]...)
 		} else {
 			data[i] = append([]float64{fetchMetric(values, metric)}, data[i][:len(data[i])-1]...)
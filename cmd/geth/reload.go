@@ -0,0 +1,111 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	cli "gopkg.in/urfave/cli.v1"
+
+	"github.com/networkchain/networkchain/cmd/utils"
+	"github.com/networkchain/networkchain/internal/debug"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/node"
+)
+
+// watchConfig reloads the TOML config file whenever the process receives SIGHUP,
+// applying the log levels, peer limits and RPC modules to the running node. Any
+// other changed setting is reported as requiring a restart.
+func watchConfig(ctx *cli.Context, stack *node.Node) {
+	file := ctx.GlobalString(configFileFlag.Name)
+	if file == "" {
+		return
+	}
+	// Track the file contents separately, the running config has flags applied
+	loaded := defaultConfig()
+	if err := loadConfig(file, &loaded); err != nil {
+		log.Error("Failed to load config file for reloading", "file", file, "err", err)
+		return
+	}
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigc)
+		for range sigc {
+			cfg := defaultConfig()
+			if err := loadConfig(file, &cfg); err != nil {
+				log.Error("Failed to reload config file", "file", file, "err", err)
+				continue
+			}
+			log.Info("Reloading config file", "file", file)
+			if err := debug.SetLogLevels(ctx, cfg.Log.Level, cfg.Log.Vmodule); err != nil {
+				log.Error("Failed to reload log settings", "err", err)
+			}
+			skipped := changedSettings(loaded, cfg)
+			loaded = cfg
+
+			// Apply the flags on top of the file without reading the node key and
+			// its password again, their sources may have been consumed on startup
+			utils.OverlayNodeConfig(ctx, &cfg.Node)
+			running := stack.Config()
+			cfg.Node.P2P.PrivateKey = running.P2P.PrivateKey
+			cfg.Node.NodeKeyPassphrase = running.NodeKeyPassphrase
+
+			nodeSkipped, err := stack.Reload(&cfg.Node)
+			if err == node.ErrNodeStopped {
+				return
+			}
+			if err != nil {
+				log.Error("Failed to reload node settings", "err", err)
+			}
+			for _, setting := range nodeSkipped {
+				skipped = append(skipped, "Node."+setting)
+			}
+			if len(skipped) > 0 {
+				log.Warn("Changed settings require a restart", "settings", skipped)
+			}
+		}
+	}()
+}
+
+// changedSettings returns the names of the settings outside the node and log
+// sections which differ between two configs.
+func changedSettings(old, new netkConfig) []string {
+	var changed []string
+
+	oldv, newv := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldv.NumField(); i++ {
+		section := oldv.Type().Field(i)
+		if section.Name == "Node" || section.Name == "Log" {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			if field.PkgPath != "" || field.Tag.Get("toml") == "-" {
+				continue
+			}
+			if !reflect.DeepEqual(oldv.Field(i).Field(j).Interface(), newv.Field(i).Field(j).Interface()) {
+				changed = append(changed, section.Name+"."+field.Name)
+			}
+		}
+	}
+	return changed
+}
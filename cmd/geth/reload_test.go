@@ -0,0 +1,72 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Tests that a SIGHUP reloads the config file of a running node whose node key
+// password was read from a consumable source, without reading it again.
+func TestReloadNodeKeyPasswordEnv(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	config := filepath.Join(datadir, "config.toml")
+	if err := ioutil.WriteFile(config, []byte("[Node.P2P]\nMaxPeers = 5\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	// The environment variable is unset once read on startup
+	os.Setenv("NETK_TEST_NODEKEY_PASSWORD", "secret")
+	defer os.Unsetenv("NETK_TEST_NODEKEY_PASSWORD")
+
+	netk := runNetk(t,
+		"--datadir", datadir, "--config", config, "--nodekeypassword", "env:NETK_TEST_NODEKEY_PASSWORD",
+		"--port", "0", "--nodiscover", "--nat", "none", "--ipcdisable")
+	defer netk.Kill()
+
+	waitLog := func(text string) {
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(100 * time.Millisecond) {
+			if strings.Contains(netk.StderrText(), text) {
+				return
+			}
+		}
+		t.Fatalf("log %q missing", text)
+	}
+	waitLog("RLPx listener up")
+	time.Sleep(time.Second) // Simple way to wait for the signal handler to be installed
+
+	if err := ioutil.WriteFile(config, []byte("[Node.P2P]\nMaxPeers = 7\n"), 0600); err != nil {
+		t.Fatalf("failed to update config file: %v", err)
+	}
+	netk.Signal(syscall.SIGHUP)
+	waitLog("Reloaded peer limit")
+
+	netk.Interrupt()
+	netk.ExpectExit()
+	if strings.Contains(netk.StderrText(), "Fatal") {
+		t.Errorf("node failed on reload")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/networkchain/networkchain/cmd/utils"
+	"github.com/networkchain/networkchain/ethclient"
+	"github.com/networkchain/networkchain/internal/supervisor"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	serviceNameFlag = cli.StringFlag{
+		Name:  "servicename",
+		Usage: "Name of the Windows service",
+		Value: clientIdentifier,
+	}
+
+	serviceCommand = cli.Command{
+		Name:     "service",
+		Usage:    "Manage the Windows service running netk",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+Manage a Windows service running netk in the background, started automatically
+with the system. The node flags of the service are given after a "--":
+
+    netk service install -- --datadir C:\netk --cache 1024
+
+On Linux, run netk as a systemd service of type "notify" instead, which is told
+when the node is ready and may enable a watchdog with WatchdogSec.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "install",
+				Usage:     "Install netk as a Windows service",
+				ArgsUsage: "[-- <node flags>]",
+				Action:    installService,
+				Flags:     []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:   "remove",
+				Usage:  "Remove the Windows service",
+				Action: removeService,
+				Flags:  []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:   "start",
+				Usage:  "Start the Windows service",
+				Action: startService,
+				Flags:  []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:   "stop",
+				Usage:  "Stop the Windows service",
+				Action: stopService,
+				Flags:  []cli.Flag{serviceNameFlag},
+			},
+		},
+	}
+)
+
+func installService(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := supervisor.InstallService(name, "NetworkChain node ("+name+")", ctx.Args()); err != nil {
+		utils.Fatalf("Failed to install service: %v", err)
+	}
+	fmt.Printf("Installed service %s\n", name)
+	return nil
+}
+
+func removeService(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := supervisor.RemoveService(name); err != nil {
+		utils.Fatalf("Failed to remove service: %v", err)
+	}
+	fmt.Printf("Removed service %s\n", name)
+	return nil
+}
+
+func startService(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := supervisor.StartService(name); err != nil {
+		utils.Fatalf("Failed to start service: %v", err)
+	}
+	fmt.Printf("Started service %s\n", name)
+	return nil
+}
+
+func stopService(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := supervisor.StopService(name); err != nil {
+		utils.Fatalf("Failed to stop service: %v", err)
+	}
+	fmt.Printf("Stopping service %s\n", name)
+	return nil
+}
+
+// notifySupervisor reports the readiness and sync progress of a started node to
+// the process supervisor, and keeps its watchdog (if any) fed as long as the node
+// keeps serving requests.
+func notifySupervisor(stack *node.Node) {
+	rpcClient, err := stack.Attach()
+	if err != nil {
+		utils.Fatalf("Failed to attach to self: %v", err)
+	}
+	quit := make(chan struct{})
+	go func() {
+		stack.Wait()
+		close(quit)
+	}()
+	supervisor.StartWatchdog(func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var version string
+		return rpcClient.CallContext(ctx, &version, "web3_clientVersion")
+	}, quit)
+
+	supervisor.Ready()
+	log.Debug("Notified supervisor of readiness")
+
+	go func() {
+		client := ethclient.NewClient(rpcClient)
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if progress, err := client.SyncProgress(ctx); err == nil {
+				if progress != nil {
+					supervisor.Status("Syncing block %d of %d", progress.CurrentBlock, progress.HighestBlock)
+				} else if head, err := client.HeaderByNumber(ctx, nil); err == nil {
+					supervisor.Status("Synchronised at block %d", head.Number)
+				}
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
@@ -66,7 +66,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "ETHEREUM",
 		Flags: []cli.Flag{
 			configFileFlag,
+			networksFlag,
 			utils.DataDirFlag,
+			utils.ReadOnlyFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
@@ -74,10 +76,26 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RinkebyFlag,
 			utils.DevModeFlag,
 			utils.SyncModeFlag,
+			utils.GCModeFlag,
+			utils.StaticSyncFlag,
+			utils.SyncAnchorFlag,
 			utils.EthStatsURLFlag,
+			utils.EthStatsFileFlag,
+			utils.EthStatsSocketFlag,
+			utils.WebhooksFlag,
+			utils.WebhooksRetriesFlag,
+			utils.EventSinkURLFlag,
+			utils.EventSinkEncodingFlag,
+			utils.TracingEndpointFlag,
+			utils.TracingServiceFlag,
+			utils.TracingSampleFlag,
+			utils.TracingHeadersFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightRetentionFlag,
+			utils.LightProbeFlag,
+			utils.LightPreferNetsFlag,
 			utils.LightKDFFlag,
 		},
 	},
@@ -95,7 +113,11 @@ var AppHelpFlagGroups = []flagGroup{
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
+			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
+			utils.TxPoolJournalFlag,
+			utils.TxPoolRejournalFlag,
+			utils.TxPoolRebroadcastFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -105,11 +127,25 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolLifetimeFlag,
 		},
 	},
+	{
+		Name: "TRANSACTION RESCUE",
+		Flags: []cli.Flag{
+			utils.TxRescueFlag,
+			utils.TxRescueWindowFlag,
+			utils.TxRescuePriceBumpFlag,
+			utils.TxRescuePriceCapFlag,
+		},
+	},
 	{
 		Name: "PERFORMANCE TUNING",
 		Flags: []cli.Flag{
 			utils.CacheFlag,
 			utils.TrieCacheGenFlag,
+			utils.HeaderCacheFlag,
+			utils.LogIndexFlag,
+			utils.SnapshotFlag,
+			utils.GovernorHighFlag,
+			utils.GovernorCriticalFlag,
 		},
 	},
 	{
@@ -117,6 +153,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.SigningPolicyFlag,
 		},
 	},
 	{
@@ -133,6 +170,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSAllowedOriginsFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
+			utils.NoAddressChecksumFlag,
 			utils.RPCCORSDomainFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -152,8 +190,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.ProxyFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
+			utils.NodeKeyPasswordFlag,
+			utils.NodeKeyMachineFlag,
 		},
 	},
 	{
@@ -165,6 +206,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.MinerTxOrderFlag,
+			utils.MinerNotifyFlag,
+			utils.RemoteSealerFlag,
 		},
 	},
 	{
@@ -178,6 +222,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMTrackAccessFlag,
+			utils.VMParallelFlag,
+			utils.VMWitnessFlag,
 		},
 	},
 	{
@@ -241,6 +288,11 @@ func init() {
 	// Override the default app help template
 	cli.AppHelpTemplate = AppHelpTemplate
 
+	// Show the environment variables configuring the flags, as bound by main
+	for i := range AppHelpFlagGroups {
+		AppHelpFlagGroups[i].Flags = utils.BindEnvVars(AppHelpFlagGroups[i].Flags)
+	}
+
 	// Define a one shot struct to pass to the usage template
 	type helpData struct {
 		App        interface{}
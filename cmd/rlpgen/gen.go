@@ -0,0 +1,385 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// rlpPackage is the import path of the rlp package used by the generated code.
+const rlpPackage = "github.com/networkchain/networkchain/rlp"
+
+// generator accumulates the generated code of a single output file, keeping
+// track of the packages it references.
+type generator struct {
+	pkg     *types.Package    // Package the code is generated into
+	imports map[string]string // Imported packages, path to name
+	buf     bytes.Buffer      // Code of the method currently being generated
+	temps   int               // Counter for unique temporary variables
+}
+
+// generate creates the source of a file containing the EncodeRLP and DecodeRLP
+// methods of the given named struct type. If names is set, only the listed fields
+// are encoded, in the given order.
+func generate(pkg *types.Package, typ *types.Named, names []string) ([]byte, error) {
+	styp, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct type", typ.Obj().Name())
+	}
+	fields, err := selectFields(styp, names)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{pkg: pkg, imports: map[string]string{"io": "io", rlpPackage: "rlp"}}
+
+	// Nil pointers encode as empty lists, just like with reflection
+	name := typ.Obj().Name()
+	fmt.Fprintf(&g.buf, "// EncodeRLP implements rlp.Encoder.\n")
+	fmt.Fprintf(&g.buf, "func (obj *%s) EncodeRLP(_w io.Writer) error {\n", name)
+	fmt.Fprintf(&g.buf, "w := rlp.NewEncoderBuffer(_w)\n")
+	fmt.Fprintf(&g.buf, "if obj == nil {\nw.ListEnd(w.List())\nreturn w.Flush()\n}\n")
+	if err := g.encodeFields("obj", fields); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&g.buf, "return w.Flush()\n}\n\n")
+	encoder := g.buf.String()
+
+	g.buf.Reset()
+	fmt.Fprintf(&g.buf, "// DecodeRLP implements rlp.Decoder.\n")
+	fmt.Fprintf(&g.buf, "func (obj *%s) DecodeRLP(dec *rlp.Stream) error {\n", name)
+	if err := g.decodeFields("obj", fields); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&g.buf, "return nil\n}\n")
+	decoder := g.buf.String()
+
+	// Assemble the file and format it
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by rlpgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg.Name())
+	fmt.Fprintf(&out, "import (\n")
+	var std, ext []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			ext = append(ext, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(ext)
+	for _, path := range std {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	fmt.Fprintf(&out, "\n")
+	for _, path := range ext {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.WriteString(encoder)
+	out.WriteString(decoder)
+
+	return format.Source(out.Bytes())
+}
+
+// temp returns a new unique temporary variable name.
+func (g *generator) temp() string {
+	g.temps++
+	return fmt.Sprintf("_tmp%d", g.temps)
+}
+
+// typeString returns the source representation of typ within the generated file,
+// recording any packages that need importing.
+func (g *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == g.pkg {
+			return ""
+		}
+		path := pkg.Path()
+		if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+			path = path[i+len("/vendor/"):]
+		}
+		g.imports[path] = pkg.Name()
+		return pkg.Name()
+	})
+}
+
+// rlpFields returns the struct fields taking part in the RLP encoding, which are
+// all exported fields not tagged with `rlp:"-"`.
+func rlpFields(typ *types.Struct) ([]*types.Var, error) {
+	var fields []*types.Var
+	for i := 0; i < typ.NumFields(); i++ {
+		field := typ.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		switch tag := reflect.StructTag(typ.Tag(i)).Get("rlp"); tag {
+		case "":
+			fields = append(fields, field)
+		case "-":
+		default:
+			return nil, fmt.Errorf("unsupported struct tag %q on field %s", tag, field.Name())
+		}
+	}
+	return fields, nil
+}
+
+// selectFields returns the named fields of the struct type in the given order, or
+// all RLP fields if no names are given.
+func selectFields(typ *types.Struct, names []string) ([]*types.Var, error) {
+	if len(names) == 0 {
+		return rlpFields(typ)
+	}
+	fields := make([]*types.Var, 0, len(names))
+	for _, name := range names {
+		var field *types.Var
+		for i := 0; i < typ.NumFields(); i++ {
+			if typ.Field(i).Name() == name {
+				field = typ.Field(i)
+				break
+			}
+		}
+		if field == nil || !field.Exported() {
+			return nil, fmt.Errorf("no exported field %s", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// hasMethod reports whether the method set of typ contains the named method.
+func hasMethod(typ types.Type, name string) bool {
+	set := types.NewMethodSet(typ)
+	for i := 0; i < set.Len(); i++ {
+		if set.At(i).Obj().Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isEncoder reports whether values of typ implement rlp.Encoder, assuming they
+// are addressable.
+func isEncoder(typ types.Type) bool {
+	if _, ok := typ.(*types.Pointer); ok {
+		return hasMethod(typ, "EncodeRLP")
+	}
+	return hasMethod(types.NewPointer(typ), "EncodeRLP")
+}
+
+// isDecoder reports whether values of typ implement rlp.Decoder, assuming they
+// are addressable.
+func isDecoder(typ types.Type) bool {
+	if _, ok := typ.(*types.Pointer); ok {
+		return hasMethod(typ, "DecodeRLP")
+	}
+	return hasMethod(types.NewPointer(typ), "DecodeRLP")
+}
+
+// isBigInt reports whether typ is *big.Int.
+func isBigInt(typ types.Type) bool {
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "math/big" && named.Obj().Name() == "Int"
+}
+
+// isByte reports whether typ is a byte type without custom encoding rules.
+func isByte(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Uint8 && !isEncoder(typ)
+}
+
+// isUint reports whether typ is an unsigned integer type of 64 bits, the only
+// integer width the stream decoder exposes directly.
+func isUint(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && (basic.Kind() == types.Uint64 || basic.Kind() == types.Uint)
+}
+
+// encodeStruct generates the encoder of the struct value expr as an RLP list.
+func (g *generator) encodeStruct(expr string, typ *types.Struct) error {
+	fields, err := rlpFields(typ)
+	if err != nil {
+		return err
+	}
+	return g.encodeFields(expr, fields)
+}
+
+// encodeFields generates the encoder of the given fields of the struct value
+// expr as an RLP list.
+func (g *generator) encodeFields(expr string, fields []*types.Var) error {
+	list := g.temp()
+	fmt.Fprintf(&g.buf, "%s := w.List()\n", list)
+	for _, field := range fields {
+		if err := g.encode(expr+"."+field.Name(), field.Type()); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name(), err)
+		}
+	}
+	fmt.Fprintf(&g.buf, "w.ListEnd(%s)\n", list)
+	return nil
+}
+
+// encode generates the encoder of the addressable value expr of type typ.
+func (g *generator) encode(expr string, typ types.Type) error {
+	switch {
+	case isEncoder(typ):
+		fmt.Fprintf(&g.buf, "if err := %s.EncodeRLP(w); err != nil {\nreturn err\n}\n", expr)
+		return nil
+	case isBigInt(typ):
+		fmt.Fprintf(&g.buf, "if err := w.WriteBigInt(%s); err != nil {\nreturn err\n}\n", expr)
+		return nil
+	}
+	switch utyp := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case utyp.Kind() == types.Bool:
+			fmt.Fprintf(&g.buf, "w.WriteBool(bool(%s))\n", expr)
+		case utyp.Kind() == types.String:
+			fmt.Fprintf(&g.buf, "w.WriteString(string(%s))\n", expr)
+		case isUint(typ):
+			fmt.Fprintf(&g.buf, "w.WriteUint64(uint64(%s))\n", expr)
+		default:
+			return fmt.Errorf("unsupported type %s", g.typeString(typ))
+		}
+	case *types.Array:
+		if !isByte(utyp.Elem()) {
+			return fmt.Errorf("unsupported type %s", g.typeString(typ))
+		}
+		fmt.Fprintf(&g.buf, "w.WriteBytes(%s[:])\n", expr)
+	case *types.Slice:
+		if isByte(utyp.Elem()) {
+			fmt.Fprintf(&g.buf, "w.WriteBytes(%s)\n", expr)
+			return nil
+		}
+		list, elem := g.temp(), g.temp()
+		fmt.Fprintf(&g.buf, "%s := w.List()\n", list)
+		fmt.Fprintf(&g.buf, "for _, %s := range %s {\n", elem, expr)
+		if err := g.encode(elem, utyp.Elem()); err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.buf, "}\nw.ListEnd(%s)\n", list)
+	case *types.Struct:
+		return g.encodeStruct(expr, utyp)
+	default:
+		return fmt.Errorf("unsupported type %s", g.typeString(typ))
+	}
+	return nil
+}
+
+// decodeStruct generates the decoder of an RLP list into the struct value expr.
+func (g *generator) decodeStruct(expr string, typ *types.Struct) error {
+	fields, err := rlpFields(typ)
+	if err != nil {
+		return err
+	}
+	return g.decodeFields(expr, fields)
+}
+
+// decodeFields generates the decoder of an RLP list into the given fields of the
+// struct value expr.
+func (g *generator) decodeFields(expr string, fields []*types.Var) error {
+	fmt.Fprintf(&g.buf, "if _, err := dec.List(); err != nil {\nreturn err\n}\n")
+	for _, field := range fields {
+		fmt.Fprintf(&g.buf, "// %s:\n", field.Name())
+		if err := g.decode(expr+"."+field.Name(), field.Type()); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name(), err)
+		}
+	}
+	fmt.Fprintf(&g.buf, "if err := dec.ListEnd(); err != nil {\nreturn err\n}\n")
+	return nil
+}
+
+// decode generates the decoder of the next value into the addressable expr of
+// type typ.
+func (g *generator) decode(expr string, typ types.Type) error {
+	if isDecoder(typ) {
+		if ptr, ok := typ.(*types.Pointer); ok {
+			fmt.Fprintf(&g.buf, "%s = new(%s)\n", expr, g.typeString(ptr.Elem()))
+		}
+		fmt.Fprintf(&g.buf, "if err := %s.DecodeRLP(dec); err != nil {\nreturn err\n}\n", expr)
+		return nil
+	}
+	if isBigInt(typ) {
+		g.decodeValue(expr, "dec.BigInt()", "")
+		return nil
+	}
+	switch utyp := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case utyp.Kind() == types.Bool:
+			g.decodeValue(expr, "dec.Bool()", g.typeString(typ))
+		case utyp.Kind() == types.String:
+			g.decodeValue(expr, "dec.Bytes()", g.typeString(typ))
+		case isUint(typ):
+			g.decodeValue(expr, "dec.Uint()", g.typeString(typ))
+		default:
+			return fmt.Errorf("unsupported type %s", g.typeString(typ))
+		}
+	case *types.Array:
+		if !isByte(utyp.Elem()) {
+			return fmt.Errorf("unsupported type %s", g.typeString(typ))
+		}
+		fmt.Fprintf(&g.buf, "if err := dec.ReadBytes(%s[:]); err != nil {\nreturn err\n}\n", expr)
+	case *types.Slice:
+		if isByte(utyp.Elem()) {
+			g.decodeValue(expr, "dec.Bytes()", "")
+			return nil
+		}
+		// Empty lists decode into empty, non-nil slices just like with reflection
+		list, elem := g.temp(), g.temp()
+		fmt.Fprintf(&g.buf, "%s := %s{}\n", list, g.typeString(typ))
+		fmt.Fprintf(&g.buf, "if _, err := dec.List(); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(&g.buf, "for dec.MoreDataInList() {\n")
+		if ptr, ok := utyp.Elem().(*types.Pointer); ok && isDecoder(ptr) {
+			fmt.Fprintf(&g.buf, "%s := new(%s)\n", elem, g.typeString(ptr.Elem()))
+			fmt.Fprintf(&g.buf, "if err := %s.DecodeRLP(dec); err != nil {\nreturn err\n}\n", elem)
+		} else {
+			fmt.Fprintf(&g.buf, "var %s %s\n", elem, g.typeString(utyp.Elem()))
+			if err := g.decode(elem, utyp.Elem()); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(&g.buf, "%s = append(%s, %s)\n}\n", list, list, elem)
+		fmt.Fprintf(&g.buf, "if err := dec.ListEnd(); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(&g.buf, "%s = %s\n", expr, list)
+	case *types.Struct:
+		return g.decodeStruct(expr, utyp)
+	default:
+		return fmt.Errorf("unsupported type %s", g.typeString(typ))
+	}
+	return nil
+}
+
+// decodeValue generates a call to a value returning stream method, assigning the
+// result to expr. If conv is set, the result is converted to that type first.
+func (g *generator) decodeValue(expr string, call string, conv string) {
+	val := g.temp()
+	fmt.Fprintf(&g.buf, "%s, err := %s\nif err != nil {\nreturn err\n}\n", val, call)
+	if conv != "" {
+		val = fmt.Sprintf("%s(%s)", conv, val)
+	}
+	fmt.Fprintf(&g.buf, "%s = %s\n", expr, val)
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+// rlpgen generates reflection free RLP encoders and decoders for struct types.
+//
+// It is meant to be run through go:generate from the package declaring the type:
+//
+//	//go:generate rlpgen -type Header -out gen_header_rlp.go
+//
+// By default all exported fields of the type are encoded, except for the ones
+// tagged with `rlp:"-"`. Types mixing consensus and derived fields can list the
+// encoded ones instead:
+//
+//	//go:generate rlpgen -type Log -fields Address,Topics,Data -out gen_log_rlp.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typFlag    = flag.String("type", "", "Struct type to generate the RLP methods for")
+	fieldsFlag = flag.String("fields", "", "Comma separated fields to encode, in order (default = all exported fields)")
+	dirFlag    = flag.String("dir", ".", "Directory of the package declaring the type")
+	outFlag    = flag.String("out", "", "Output file for the generated methods (default = stdout)")
+)
+
+func main() {
+	flag.Parse()
+
+	if *typFlag == "" {
+		fmt.Printf("No struct type specified (--type)\n")
+		os.Exit(-1)
+	}
+	pkg, err := loadPackage(*dirFlag, *outFlag)
+	if err != nil {
+		fmt.Printf("Failed to load package: %v\n", err)
+		os.Exit(-1)
+	}
+	obj, ok := pkg.Scope().Lookup(*typFlag).(*types.TypeName)
+	if !ok {
+		fmt.Printf("Type %s not found in package %s\n", *typFlag, pkg.Name())
+		os.Exit(-1)
+	}
+	var fields []string
+	if *fieldsFlag != "" {
+		fields = strings.Split(*fieldsFlag, ",")
+	}
+	code, err := generate(pkg, obj.Type().(*types.Named), fields)
+	if err != nil {
+		fmt.Printf("Failed to generate RLP methods: %v\n", err)
+		os.Exit(-1)
+	}
+	if *outFlag == "" {
+		fmt.Printf("%s", code)
+		return
+	}
+	if err := ioutil.WriteFile(*outFlag, code, 0644); err != nil {
+		fmt.Printf("Failed to write generated methods: %v\n", err)
+		os.Exit(-1)
+	}
+}
+
+// loadPackage parses and type checks the package in dir. The output file is left
+// out, so a stale or broken previous generation doesn't prevent regenerating it.
+// Type errors elsewhere in the package are tolerated for the same reason, as the
+// generated methods of one type may be used by the generated code of another.
+func loadPackage(dir, out string) (*types.Package, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	bpkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	skip := ""
+	if out != "" {
+		if skip, err = filepath.Abs(out); err != nil {
+			return nil, err
+		}
+	}
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	for _, name := range append(bpkg.GoFiles, bpkg.CgoFiles...) {
+		path := filepath.Join(dir, name)
+		if path == skip {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{
+		Importer: importer.For("source", nil),
+		Error:    func(error) {},
+	}
+	pkg, err := conf.Check(bpkg.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, err
+	}
+	return pkg, nil
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/ethclient"
 	"github.com/networkchain/networkchain/internal/debug"
+	"github.com/networkchain/networkchain/internal/version"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/node"
 	"github.com/networkchain/networkchain/p2p"
@@ -53,7 +54,6 @@ import (
 const clientIdentifier = "swarm"
 
 var (
-	gitCommit        string // Git SHA1 commit hash of the release (set via linker flags)
 	testbetBootNodes = []string{
 		"enode://ec8ae764f7cb0417bdfb009b9d0f18ab3818a3a4e8e7c67dd5f18971a93510a2e6f43cd0b69a27e439a9629457ea804104f37c85e41eed057d3faabbf7744cdf@13.74.157.139:30429",
 		"enode://c2e1fceb3bf3be19dff71eec6cccf19f2dbf7567ee017d130240c670be8594bc9163353ca55dd8df7a4f161dd94b36d0615c17418b5a3cdcbb4e9d99dfa4de37@13.74.157.139:30430",
@@ -151,14 +151,14 @@ var defaultNodeConfig = node.DefaultConfig
 // This init function sets defaults so cmd/swarm can run alongside netk.
 func init() {
 	defaultNodeConfig.Name = clientIdentifier
-	defaultNodeConfig.Version = params.VersionWithCommit(gitCommit)
+	defaultNodeConfig.Version = version.Get().String()
 	defaultNodeConfig.P2P.ListenAddr = ":30399"
 	defaultNodeConfig.IPCPath = "bzzd.ipc"
 	// Set flag defaults for --help display.
 	utils.ListenPortFlag.Value = 30399
 }
 
-var app = utils.NewApp(gitCommit, "NetworkChain Swarm")
+var app = utils.NewApp("NetworkChain Swarm")
 
 // This init function creates the cli.App.
 func init() {
@@ -167,7 +167,7 @@ func init() {
 	app.Copyright = "Copyright 2013-2016 The networkchain Authors"
 	app.Commands = []cli.Command{
 		{
-			Action:    version,
+			Action:    printVersion,
 			Name:      "version",
 			Usage:     "Print version numbers",
 			ArgsUsage: " ",
@@ -307,12 +307,9 @@ func main() {
 	}
 }
 
-func version(ctx *cli.Context) error {
+func printVersion(ctx *cli.Context) error {
 	fmt.Println(strings.Title(clientIdentifier))
-	fmt.Println("Version:", params.Version)
-	if gitCommit != "" {
-		fmt.Println("Git Commit:", gitCommit)
-	}
+	version.Get().Print()
 	fmt.Println("Network Id:", ctx.GlobalInt(utils.NetworkIdFlag.Name))
 	fmt.Println("Go Version:", runtime.Version())
 	fmt.Println("OS:", runtime.GOOS)
@@ -19,23 +19,33 @@ package utils
 
 import (
 	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 
+	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/ethclient"
 	"github.com/networkchain/networkchain/internal/debug"
+	"github.com/networkchain/networkchain/internal/supervisor"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/node"
 	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/rpc"
 )
 
 const (
-	importBatchSize = 2500
+	importBatchSize  = 2500
+	rpcImportWorkers = 16 // Number of concurrent block requests when importing over RPC
 )
 
 // Fatalf formats a message to standard error and exits the program.
@@ -58,16 +68,26 @@ func Fatalf(format string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// PrintJSON writes the JSON encoding of a command result to standard output.
+func PrintJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		Fatalf("Failed to encode output: %v", err)
+	}
+	fmt.Println(string(out))
+}
+
 func StartNode(stack *node.Node) {
 	if err := stack.Start(); err != nil {
 		Fatalf("Error starting protocol stack: %v", err)
 	}
 	go func() {
 		sigc := make(chan os.Signal, 1)
-		signal.Notify(sigc, os.Interrupt)
+		signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
 		defer signal.Stop(sigc)
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
+		supervisor.Stopping()
 		go stack.Stop()
 		for i := 10; i > 0; i-- {
 			<-sigc
@@ -81,27 +101,8 @@ func StartNode(stack *node.Node) {
 }
 
 func ImportChain(chain *core.BlockChain, fn string) error {
-	// Watch for Ctrl-C while the import is running.
-	// If a signal is received, the import will stop at the next batch.
-	interrupt := make(chan os.Signal, 1)
-	stop := make(chan struct{})
-	signal.Notify(interrupt, os.Interrupt)
-	defer signal.Stop(interrupt)
-	defer close(interrupt)
-	go func() {
-		if _, ok := <-interrupt; ok {
-			log.Info("Interrupted during import, stopping at next batch")
-		}
-		close(stop)
-	}()
-	checkInterrupt := func() bool {
-		select {
-		case <-stop:
-			return true
-		default:
-			return false
-		}
-	}
+	checkInterrupt, unwatch := watchInterrupt()
+	defer unwatch()
 
 	log.Info("Importing blockchain", "file", fn)
 	fh, err := os.Open(fn)
@@ -162,6 +163,158 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 	return nil
 }
 
+// ImportChainRPC imports the canonical chain of a trusted remote node over RPC,
+// starting after the local head block and ending at the remote head block as of
+// the start of the import. The blocks are executed locally, while the next batch
+// is already being retrieved.
+func ImportChainRPC(chain *core.BlockChain, url string) error {
+	checkInterrupt, unwatch := watchInterrupt()
+	defer unwatch()
+
+	log.Info("Importing blockchain", "rpc", url)
+	rpcClient, err := rpc.Dial(url)
+	if err != nil {
+		return err
+	}
+	defer rpcClient.Close()
+
+	client := ethclient.NewClient(rpcClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Make sure the remote node is on the same network and find the import range
+	genesis, err := client.HeaderByNumber(ctx, common.Big0)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve remote genesis: %v", err)
+	}
+	if genesis.Hash() != chain.Genesis().Hash() {
+		return fmt.Errorf("genesis mismatch: have %x, remote %x", chain.Genesis().Hash(), genesis.Hash())
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve remote head: %v", err)
+	}
+	first, last := chain.CurrentBlock().NumberU64()+1, head.Number.Uint64()
+	if first > last {
+		log.Info("Local chain already at remote head", "number", last)
+		return nil
+	}
+	log.Info("Retrieving remote blocks", "first", first, "last", last)
+
+	// Retrieve the blocks in the background, one batch ahead of the import
+	var (
+		batches = make(chan types.Blocks, 1)
+		errc    = make(chan error, 1)
+	)
+	go func() {
+		defer close(batches)
+		for from := first; from <= last; from += importBatchSize {
+			to := from + importBatchSize - 1
+			if to > last {
+				to = last
+			}
+			blocks, err := fetchBlocksRPC(ctx, client, from, to)
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case batches <- blocks:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for blocks := range batches {
+		if checkInterrupt() {
+			return fmt.Errorf("interrupted")
+		}
+		if index, err := chain.InsertChain(blocks); err != nil {
+			return fmt.Errorf("invalid block %d: %v", blocks[index].NumberU64(), err)
+		}
+		log.Info("Imported remote blocks", "number", blocks[len(blocks)-1].Number(), "hash", blocks[len(blocks)-1].Hash())
+	}
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// fetchBlocksRPC retrieves the canonical blocks numbered from first to last
+// (inclusive) from a remote node, using a pool of concurrent requests.
+func fetchBlocksRPC(ctx context.Context, client *ethclient.Client, first, last uint64) (types.Blocks, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		blocks = make(types.Blocks, last-first+1)
+		tasks  = make(chan uint64, len(blocks))
+		errc   = make(chan error, rpcImportWorkers)
+		pend   sync.WaitGroup
+	)
+	for number := first; number <= last; number++ {
+		tasks <- number
+	}
+	close(tasks)
+
+	for i := 0; i < rpcImportWorkers; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for number := range tasks {
+				block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+				if err == nil && block.NumberU64() != number {
+					err = fmt.Errorf("remote returned block %d", block.NumberU64())
+				}
+				if err != nil {
+					errc <- fmt.Errorf("failed to retrieve block %d: %v", number, err)
+					cancel()
+					return
+				}
+				blocks[number-first] = block
+			}
+		}()
+	}
+	pend.Wait()
+
+	select {
+	case err := <-errc:
+		return nil, err
+	default:
+		return blocks, nil
+	}
+}
+
+// watchInterrupt watches for Ctrl-C while an import is running, returning a
+// function reporting whether the import should stop at the next batch, and a
+// function to stop watching.
+func watchInterrupt() (func() bool, func()) {
+	interrupt := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during import, stopping at next batch")
+		}
+		close(stop)
+	}()
+	checkInterrupt := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+	unwatch := func() {
+		signal.Stop(interrupt)
+		close(interrupt)
+	}
+	return checkInterrupt, unwatch
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash()) {
@@ -0,0 +1,123 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// RemoteChainAPI serves the blocks of a local chain over RPC, standing in for the
+// eth namespace of a trusted remote node.
+type RemoteChainAPI struct {
+	chain *core.BlockChain
+}
+
+func (r *RemoteChainAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	block := r.chain.CurrentBlock()
+	if number != rpc.LatestBlockNumber {
+		block = r.chain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return ethapi.RPCMarshalBlock(block, true, fullTx)
+}
+
+// newImportChain creates a blockchain on top of a fresh database seeded with the
+// given genesis specification, importing the given blocks into it.
+func newImportChain(t *testing.T, gspec *core.Genesis, blocks types.Blocks) *core.BlockChain {
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain
+}
+
+// Tests that the canonical chain of a remote node is imported over RPC, continuing
+// from the local head, and that nodes of other networks are rejected.
+func TestImportChainRPC(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dest    = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		db, _   = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	// Create a remote chain with value transfers in every other block
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 64, func(i int, gen *core.BlockGen) {
+		if i%2 == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), dest, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	remote := newImportChain(t, gspec, blocks)
+	defer remote.Stop()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", &RemoteChainAPI{remote}); err != nil {
+		t.Fatalf("failed to register remote chain: %v", err)
+	}
+	endpoint := httptest.NewServer(server)
+	defer endpoint.Close()
+
+	// Import the remote chain into local chains starting at various heights
+	for _, height := range []int{0, 10, 63, 64} {
+		local := newImportChain(t, gspec, blocks[:height])
+		if err := ImportChainRPC(local, endpoint.URL); err != nil {
+			t.Errorf("height %d: failed to import chain: %v", height, err)
+		} else if head := local.CurrentBlock(); head.Hash() != remote.CurrentBlock().Hash() {
+			t.Errorf("height %d: head mismatch: have #%d [%x], want #%d [%x]", height, head.NumberU64(), head.Hash(), remote.CurrentBlock().NumberU64(), remote.CurrentBlock().Hash())
+		} else if statedb, _ := local.State(); statedb.GetBalance(dest).Cmp(big.NewInt(32*1000)) != 0 {
+			t.Errorf("height %d: transferred balance mismatch: have %v, want %v", height, statedb.GetBalance(dest), 32*1000)
+		}
+		local.Stop()
+	}
+	// Ensure a node on a different network is refused
+	other := newImportChain(t, &core.Genesis{Config: params.TestChainConfig, ExtraData: []byte("other")}, nil)
+	defer other.Stop()
+
+	if err := ImportChainRPC(other, endpoint.URL); err == nil || !strings.HasPrefix(err.Error(), "genesis mismatch") {
+		t.Errorf("error mismatch: have %v, want genesis mismatch", err)
+	}
+	if head := other.CurrentBlock().NumberU64(); head != 0 {
+		t.Errorf("mismatching chain imported up to #%d", head)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+)
+
+// CSVSchemaVersion is the version of the column layout produced by ExportCSV.
+// It must be bumped whenever a column is added, removed or changes meaning, so
+// that loaders can reject dumps they don't understand.
+const CSVSchemaVersion = 1
+
+// csvSchemaFile is the name of the metadata file describing an export. It is
+// written last, so its presence marks the dump as complete.
+const csvSchemaFile = "schema.json"
+
+// csvTables is the ordered list of flat files written by ExportCSV along with
+// their column headers.
+var csvTables = []struct {
+	Name    string
+	Columns []string
+}{
+	{"blocks", []string{"number", "hash", "parent_hash", "timestamp", "miner", "difficulty", "gas_limit", "gas_used", "transaction_count", "uncle_count", "size", "extra_data"}},
+	{"transactions", []string{"block_number", "block_hash", "transaction_index", "hash", "from", "to", "nonce", "value", "gas", "gas_price", "input"}},
+	{"receipts", []string{"block_number", "transaction_hash", "transaction_index", "post_state", "cumulative_gas_used", "gas_used", "contract_address", "log_count"}},
+	{"logs", []string{"block_number", "transaction_hash", "transaction_index", "log_index", "address", "topic0", "topic1", "topic2", "topic3", "data"}},
+}
+
+// csvSchema is the content of the metadata file accompanying a CSV export.
+type csvSchema struct {
+	Version int                 `json:"version"`
+	ChainId *big.Int            `json:"chainId"`
+	First   uint64              `json:"first"`
+	Last    uint64              `json:"last"`
+	Tables  map[string][]string `json:"tables"`
+}
+
+// ExportCSV writes the blocks, transactions, receipts and logs in the given
+// (inclusive) block range as comma separated flat files into dir, one file per
+// table, together with a schema file recording the layout version. Quantities
+// are written in decimal, binary data as 0x prefixed hex.
+func ExportCSV(blockchain *core.BlockChain, dir string, first, last uint64) error {
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting blockchain to CSV", "dir", dir, "first", first, "last", last)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Create all the tables and write their headers
+	writers := make(map[string]*csv.Writer)
+	for _, table := range csvTables {
+		fh, err := os.Create(filepath.Join(dir, table.Name+".csv"))
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+
+		writers[table.Name] = csv.NewWriter(fh)
+		writers[table.Name].Write(table.Columns)
+	}
+	// Iterate over the requested range, flattening each block into the tables
+	var (
+		start  = time.Now()
+		report = time.Now()
+	)
+	for nr := first; ; nr++ {
+		block := blockchain.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		if err := exportCSVBlock(blockchain, block, writers); err != nil {
+			return err
+		}
+		if time.Since(report) > 8*time.Second {
+			log.Info("Exporting blockchain to CSV", "number", nr, "elapsed", common.PrettyDuration(time.Since(start)))
+			report = time.Now()
+		}
+		if nr == last {
+			break
+		}
+	}
+	for _, table := range csvTables {
+		writers[table.Name].Flush()
+		if err := writers[table.Name].Error(); err != nil {
+			return err
+		}
+	}
+	// All tables written, seal the export with the schema description
+	schema := &csvSchema{
+		Version: CSVSchemaVersion,
+		ChainId: blockchain.Config().ChainId,
+		First:   first,
+		Last:    last,
+		Tables:  make(map[string][]string),
+	}
+	for _, table := range csvTables {
+		schema.Tables[table.Name] = table.Columns
+	}
+	blob, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, csvSchemaFile), blob, 0644); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain to CSV", "dir", dir, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportCSVBlock writes a single block along with its transactions, receipts
+// and logs into the table writers.
+func exportCSVBlock(blockchain *core.BlockChain, block *types.Block, writers map[string]*csv.Writer) error {
+	var (
+		number   = strconv.FormatUint(block.NumberU64(), 10)
+		hash     = block.Hash()
+		txs      = block.Transactions()
+		receipts = blockchain.GetReceiptsByHash(hash)
+		signer   = types.MakeSigner(blockchain.Config(), block.Number())
+	)
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("export failed on #%d: have %d receipts for %d transactions", block.NumberU64(), len(receipts), len(txs))
+	}
+	writers["blocks"].Write([]string{
+		number,
+		hash.Hex(),
+		block.ParentHash().Hex(),
+		block.Time().String(),
+		block.Coinbase().Hex(),
+		block.Difficulty().String(),
+		block.GasLimit().String(),
+		block.GasUsed().String(),
+		strconv.Itoa(len(txs)),
+		strconv.Itoa(len(block.Uncles())),
+		strconv.FormatFloat(float64(block.Size()), 'f', 0, 64),
+		hexutil.Encode(block.Extra()),
+	})
+	logIndex := 0
+	for i, tx := range txs {
+		index := strconv.Itoa(i)
+
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return fmt.Errorf("export failed on #%d: invalid transaction %x: %v", block.NumberU64(), tx.Hash(), err)
+		}
+		to := ""
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		writers["transactions"].Write([]string{
+			number,
+			hash.Hex(),
+			index,
+			tx.Hash().Hex(),
+			from.Hex(),
+			to,
+			strconv.FormatUint(tx.Nonce(), 10),
+			tx.Value().String(),
+			tx.Gas().String(),
+			tx.GasPrice().String(),
+			hexutil.Encode(tx.Data()),
+		})
+		receipt := receipts[i]
+
+		contract := ""
+		if tx.To() == nil {
+			contract = receipt.ContractAddress.Hex()
+		}
+		writers["receipts"].Write([]string{
+			number,
+			tx.Hash().Hex(),
+			index,
+			hexutil.Encode(receipt.PostState),
+			receipt.CumulativeGasUsed.String(),
+			receipt.GasUsed.String(),
+			contract,
+			strconv.Itoa(len(receipt.Logs)),
+		})
+		for _, l := range receipt.Logs {
+			topics := make([]string, 4)
+			for j := 0; j < len(l.Topics) && j < len(topics); j++ {
+				topics[j] = l.Topics[j].Hex()
+			}
+			writers["logs"].Write([]string{
+				number,
+				tx.Hash().Hex(),
+				index,
+				strconv.Itoa(logIndex),
+				l.Address.Hex(),
+				topics[0], topics[1], topics[2], topics[3],
+				hexutil.Encode(l.Data),
+			})
+			logIndex++
+		}
+	}
+	return nil
+}
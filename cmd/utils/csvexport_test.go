@@ -0,0 +1,134 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that a range of blocks is flattened into the expected CSV tables.
+func TestExportCSV(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		dest    = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		db, _   = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	// Block 1 transfers some value, block 2 deploys a contract emitting a log
+	// with a single 0xff topic from its constructor
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 3, func(i int, gen *core.BlockGen) {
+		var tx *types.Transaction
+		switch i {
+		case 0:
+			tx = types.NewTransaction(gen.TxNonce(addr), dest, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil)
+		case 1:
+			tx = types.NewContractCreation(gen.TxNonce(addr), new(big.Int), big.NewInt(100000), big.NewInt(1), common.FromHex("60ff60006000a1"))
+		default:
+			return
+		}
+		tx, _ = types.SignTx(tx, signer, key)
+		gen.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "csvexport-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ExportCSV(chain, dir, 1, 3); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	// Verify the header and row counts of all the tables
+	tables := make(map[string][][]string)
+	for _, table := range csvTables {
+		fh, err := os.Open(filepath.Join(dir, table.Name+".csv"))
+		if err != nil {
+			t.Fatalf("failed to open %s table: %v", table.Name, err)
+		}
+		records, err := csv.NewReader(fh).ReadAll()
+		fh.Close()
+		if err != nil {
+			t.Fatalf("failed to parse %s table: %v", table.Name, err)
+		}
+		if !reflect.DeepEqual(records[0], table.Columns) {
+			t.Errorf("%s header mismatch: have %v, want %v", table.Name, records[0], table.Columns)
+		}
+		tables[table.Name] = records[1:]
+	}
+	if len(tables["blocks"]) != 3 || len(tables["transactions"]) != 2 || len(tables["receipts"]) != 2 || len(tables["logs"]) != 1 {
+		t.Fatalf("row count mismatch: blocks %d, transactions %d, receipts %d, logs %d",
+			len(tables["blocks"]), len(tables["transactions"]), len(tables["receipts"]), len(tables["logs"]))
+	}
+	// Spot check the flattened contents
+	if row := tables["blocks"][0]; row[0] != "1" || row[1] != blocks[0].Hash().Hex() || row[8] != "1" {
+		t.Errorf("block row mismatch: %v", row)
+	}
+	if row := tables["transactions"][0]; row[4] != addr.Hex() || row[5] != dest.Hex() || row[7] != "1000" {
+		t.Errorf("transfer row mismatch: %v", row)
+	}
+	contract := crypto.CreateAddress(addr, 1)
+	if row := tables["transactions"][1]; row[0] != "2" || row[5] != "" || row[10] != "0x60ff60006000a1" {
+		t.Errorf("creation row mismatch: %v", row)
+	}
+	if row := tables["receipts"][1]; row[6] != contract.Hex() || row[7] != "1" {
+		t.Errorf("creation receipt row mismatch: %v", row)
+	}
+	if row := tables["logs"][0]; row[4] != contract.Hex() || row[5] != common.BigToHash(big.NewInt(0xff)).Hex() || row[6] != "" || row[9] != hexutil.Encode(nil) {
+		t.Errorf("log row mismatch: %v", row)
+	}
+	// Verify that the schema was sealed into the export
+	blob, err := ioutil.ReadFile(filepath.Join(dir, csvSchemaFile))
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+	var schema csvSchema
+	if err := json.Unmarshal(blob, &schema); err != nil {
+		t.Fatalf("failed to decode schema: %v", err)
+	}
+	if schema.Version != CSVSchemaVersion || schema.First != 1 || schema.Last != 3 || len(schema.Tables) != len(csvTables) {
+		t.Errorf("schema mismatch: %+v", schema)
+	}
+	// Exporting beyond the head must fail
+	if err := ExportCSV(chain, dir, 2, 4); err == nil {
+		t.Errorf("export beyond head succeeded")
+	}
+}
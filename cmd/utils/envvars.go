@@ -0,0 +1,88 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// EnvVarPrefix is prepended to the flag names to derive the environment variables
+// configuring them.
+const EnvVarPrefix = "NETK_"
+
+// EnvVarName returns the environment variable configuring the flag with the given
+// name, e.g. NETK_DATADIR for --datadir and NETK_TXPOOL_JOURNAL for --txpool.journal.
+func EnvVarName(name string) string {
+	name = strings.TrimSpace(strings.Split(name, ",")[0])
+	return EnvVarPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// BindEnvVars returns copies of the given flags which also take their values
+// from the environment variables named by EnvVarName. Values given on the command
+// line take precedence over the environment, which in turn takes precedence over
+// the config file. Flags already bound to other variables are left untouched.
+func BindEnvVars(flags []cli.Flag) []cli.Flag {
+	bound := make([]cli.Flag, len(flags))
+	for i, flag := range flags {
+		bound[i] = flag
+
+		value := reflect.ValueOf(flag)
+		if value.Kind() != reflect.Struct {
+			continue
+		}
+		copy := reflect.New(value.Type()).Elem()
+		copy.Set(value)
+		if envVar := copy.FieldByName("EnvVar"); envVar.IsValid() && envVar.Kind() == reflect.String && envVar.String() == "" {
+			envVar.SetString(EnvVarName(flag.GetName()))
+			bound[i] = copy.Interface().(cli.Flag)
+		}
+	}
+	return bound
+}
+
+// lookupEnv returns the value of the first set environment variable out of a
+// comma separated list.
+func lookupEnv(envVars string) (string, bool) {
+	if envVars == "" {
+		return "", false
+	}
+	for _, envVar := range strings.Split(envVars, ",") {
+		if value, ok := os.LookupEnv(strings.TrimSpace(envVar)); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// withEnvHint appends the environment variables configuring a flag to its usage,
+// the same way the cli package does for its own flags.
+func withEnvHint(envVars, usage string) string {
+	if envVars == "" {
+		return usage
+	}
+	prefix, suffix, sep := "$", "", ", $"
+	if runtime.GOOS == "windows" {
+		prefix, suffix, sep = "%", "%", "%, %"
+	}
+	return fmt.Sprintf("%s [%s%s%s]", usage, prefix, strings.Join(strings.Split(envVars, ","), sep), suffix)
+}
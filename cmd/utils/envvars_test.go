@@ -0,0 +1,77 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Tests that flags bound to environment variables pick up their values, unless
+// overridden on the command line.
+func TestBindEnvVars(t *testing.T) {
+	if name := EnvVarName("txpool.journal"); name != "NETK_TXPOOL_JOURNAL" {
+		t.Errorf("environment variable name mismatch: have %s, want %s", name, "NETK_TXPOOL_JOURNAL")
+	}
+	flags := BindEnvVars([]cli.Flag{
+		cli.IntFlag{Name: "testpeers", Value: 25},
+		cli.StringFlag{Name: "testident"},
+		cli.BoolFlag{Name: "testbool", EnvVar: "NETK_TEST_OTHER"},
+		DirectoryFlag{Name: "testdir"},
+		BigFlag{Name: "testprice", Value: big.NewInt(1)},
+	})
+	env := map[string]string{
+		"NETK_TESTPEERS": "10",
+		"NETK_TESTIDENT": "env",
+		"NETK_TESTBOOL":  "true",
+		"NETK_TESTDIR":   "/tmp/netk",
+		"NETK_TESTPRICE": "0x10",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+	app := cli.NewApp()
+	app.Flags = flags
+	app.Action = func(ctx *cli.Context) error {
+		if peers := ctx.GlobalInt("testpeers"); peers != 10 {
+			t.Errorf("int flag mismatch: have %d, want %d", peers, 10)
+		}
+		if !ctx.GlobalIsSet("testpeers") {
+			t.Errorf("int flag set from environment not reported as set")
+		}
+		if ident := ctx.GlobalString("testident"); ident != "cli" {
+			t.Errorf("string flag mismatch: have %s, want %s", ident, "cli")
+		}
+		if ctx.GlobalBool("testbool") {
+			t.Errorf("flag bound to other variable picked up default variable")
+		}
+		if dir := ctx.GlobalString("testdir"); dir != "/tmp/netk" {
+			t.Errorf("directory flag mismatch: have %s, want %s", dir, "/tmp/netk")
+		}
+		if price := GlobalBig(ctx, "testprice"); price.Cmp(big.NewInt(16)) != 0 {
+			t.Errorf("big flag mismatch: have %v, want %v", price, 16)
+		}
+		return nil
+	}
+	if err := app.Run([]string{"netk", "--testident", "cli"}); err != nil {
+		t.Fatalf("failed to run app: %v", err)
+	}
+}
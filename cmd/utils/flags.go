@@ -18,11 +18,14 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -38,10 +41,14 @@ import (
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/eth"
 	"github.com/networkchain/networkchain/eth/downloader"
+	"github.com/networkchain/networkchain/eth/eventsink"
 	"github.com/networkchain/networkchain/eth/gasprice"
+	"github.com/networkchain/networkchain/eth/webhooks"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/ethstats"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/governor"
+	"github.com/networkchain/networkchain/internal/version"
 	"github.com/networkchain/networkchain/les"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/metrics"
@@ -52,6 +59,7 @@ import (
 	"github.com/networkchain/networkchain/p2p/nat"
 	"github.com/networkchain/networkchain/p2p/netutil"
 	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/tracing/otlp"
 	whisper "github.com/networkchain/networkchain/whisper/whisperv5"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -86,17 +94,15 @@ GLOBAL OPTIONS:
 	cli.CommandHelpTemplate = CommandHelpTemplate
 }
 
-// NewApp creates an app with sane defaults.
-func NewApp(gitCommit, usage string) *cli.App {
+// NewApp creates an app with sane defaults, versioned with the metadata stamped
+// into the binary at build time.
+func NewApp(usage string) *cli.App {
 	app := cli.NewApp()
 	app.Name = filepath.Base(os.Args[0])
 	app.Author = ""
 	//app.Authors = nil
 	app.Email = ""
-	app.Version = params.Version
-	if gitCommit != "" {
-		app.Version += "-" + gitCommit[:8]
-	}
+	app.Version = version.Get().String()
 	app.Usage = usage
 	return app
 }
@@ -163,6 +169,19 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	GCModeFlag = cli.StringFlag{
+		Name:  "gcmode",
+		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Value: "archive",
+	}
+	StaticSyncFlag = cli.BoolFlag{
+		Name:  "staticsync",
+		Usage: "Synchronise blocks exclusively from static nodes (disables peer discovery)",
+	}
+	SyncAnchorFlag = cli.StringFlag{
+		Name:  "syncanchor",
+		Usage: "Trusted block hash the synchronised chain must contain",
+	}
 
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
@@ -174,6 +193,18 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: 20,
 	}
+	LightRetentionFlag = cli.Uint64Flag{
+		Name:  "lightretention",
+		Usage: "Number of recent headers a light client keeps on disk (0 = keep all)",
+	}
+	LightProbeFlag = cli.BoolFlag{
+		Name:  "lightprobe",
+		Usage: "Probe the latency of discovered LES servers and prefer nearby ones",
+	}
+	LightPreferNetsFlag = cli.StringFlag{
+		Name:  "lightprefer",
+		Usage: "Prefers LES servers in the given IP networks (CIDR masks)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -209,10 +240,29 @@ var (
 		Value: eth.DefaultConfig.EthashDatasetsOnDisk,
 	}
 	// Transaction pool settings
+	TxPoolLocalsFlag = cli.StringFlag{
+		Name:  "txpool.locals",
+		Usage: "Comma separated accounts to treat as locals (no flush, priority inclusion)",
+	}
 	TxPoolNoLocalsFlag = cli.BoolFlag{
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for local transactions to survive node restarts",
+		Value: eth.DefaultConfig.TxPool.Journal,
+	}
+	TxPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the local transaction journal",
+		Value: eth.DefaultConfig.TxPool.Rejournal,
+	}
+	TxPoolRebroadcastFlag = cli.DurationFlag{
+		Name:  "txpool.rebroadcast",
+		Usage: "Time interval to re-announce pending local transactions (0 = disabled)",
+		Value: eth.DefaultConfig.TxPool.Rebroadcast,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -248,16 +298,60 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	// Stuck transaction rescue settings
+	TxRescueFlag = cli.BoolFlag{
+		Name:  "txrescue",
+		Usage: "Enable rebroadcasting (and re-pricing) stuck local transactions",
+	}
+	TxRescueWindowFlag = cli.DurationFlag{
+		Name:  "txrescue.window",
+		Usage: "Time a local transaction may stay pending before being rescued",
+		Value: eth.DefaultConfig.TxRescue.Window,
+	}
+	TxRescuePriceBumpFlag = cli.Uint64Flag{
+		Name:  "txrescue.pricebump",
+		Usage: "Gas price bump percentage for stuck transactions of unlocked accounts (0 = rebroadcast only)",
+	}
+	TxRescuePriceCapFlag = BigFlag{
+		Name:  "txrescue.pricecap",
+		Usage: "Maximum gas price a stuck transaction may be bumped to (0 = uncapped)",
+		Value: new(big.Int),
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
-		Usage: "Megabytes of memory allocated to internal caching (min 16MB / database forced)",
+		Usage: "Megabytes of memory allocated to internal caching, split across database, trie and GC (min 16MB / database forced)",
 		Value: 128,
 	}
+	ReadOnlyFlag = cli.BoolFlag{
+		Name:  "readonly",
+		Usage: "Open the chain database read-only in chain commands, snapshotting it if in use by a running node",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an index of contract events by address and signature for fast log queries",
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat snapshot of the state to serve account and storage reads without trie lookups",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
-		Value: int(state.MaxTrieCacheGen),
+		Value: int(state.MaxTrieCacheGen()),
+	}
+	HeaderCacheFlag = cli.IntFlag{
+		Name:  "header-cache",
+		Usage: "Number of recent block headers to keep in memory (total difficulties are cached twice as deep)",
+		Value: core.HeaderCacheLimit,
+	}
+	GovernorHighFlag = cli.Uint64Flag{
+		Name:  "governor.high",
+		Usage: "Megabytes of resident memory above which caches are dropped (0 = disabled)",
+	}
+	GovernorCriticalFlag = cli.Uint64Flag{
+		Name:  "governor.critical",
+		Usage: "Megabytes of resident memory above which chain sync and background work are paused too (0 = disabled)",
 	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
@@ -288,6 +382,19 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerTxOrderFlag = cli.StringFlag{
+		Name:  "miner.txorder",
+		Usage: "Transaction ordering strategy used by the miner (price, pricenonce, fifo)",
+		Value: "price",
+	}
+	MinerNotifyFlag = cli.StringFlag{
+		Name:  "miner.notify",
+		Usage: "Comma separated HTTP URL list to notify of new work packages",
+	}
+	RemoteSealerFlag = cli.StringFlag{
+		Name:  "sealer.remote",
+		Usage: "External consensus engine endpoint (IPC, HTTP or WebSocket) to delegate header verification and sealing to",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -296,18 +403,83 @@ var (
 	}
 	PasswordFileFlag = cli.StringFlag{
 		Name:  "password",
-		Usage: "Password file to use for non-inteactive password input",
+		Usage: "Password source for non-interactive password input (file path, env:<VAR>, fd:<N> or cmd:<command>)",
 		Value: "",
 	}
+	SigningPolicyFlag = cli.StringFlag{
+		Name:  "signingpolicy",
+		Usage: "JSON file restricting the operations, recipients and values the RPC endpoints may sign per account",
+	}
+	OutputFormatFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: `Format of the command results ("text" or "json")`,
+		Value: "text",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMTrackAccessFlag = cli.BoolFlag{
+		Name:  "vmtrackaccess",
+		Usage: "Record the state accessed by each transaction and measure their conflicts",
+	}
+	VMParallelFlag = cli.BoolFlag{
+		Name:  "vmparallel",
+		Usage: "Speculatively execute the transactions of imported blocks in parallel",
+	}
+	VMWitnessFlag = cli.BoolFlag{
+		Name:  "vmwitness",
+		Usage: "Record the execution witness (state trie nodes and codes touched) of imported blocks",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
-		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
+		Usage: "Comma separated reporting URLs of ethstats services (nodename:secret@host:port)",
+	}
+	EthStatsFileFlag = cli.StringFlag{
+		Name:  "ethstats.file",
+		Usage: "Local file to write the ethstats snapshots into as JSON",
+	}
+	EthStatsSocketFlag = cli.StringFlag{
+		Name:  "ethstats.socket",
+		Usage: "Local unix socket to serve the latest ethstats snapshot on as JSON",
+	}
+	WebhooksFlag = cli.BoolFlag{
+		Name:  "webhooks",
+		Usage: "Enable the web-hooks service notifying HTTP callbacks of address activity (managed via the webhooks RPC API)",
+	}
+	WebhooksRetriesFlag = cli.IntFlag{
+		Name:  "webhooks.retries",
+		Usage: "Number of redelivery attempts of a failed web-hook notification",
+		Value: webhooks.DefaultConfig.Retries,
+	}
+	EventSinkURLFlag = cli.StringFlag{
+		Name:  "eventsink",
+		Usage: "Message broker to stream chain events into (nats://host:port or mqtt://host:port)",
+	}
+	EventSinkEncodingFlag = cli.StringFlag{
+		Name:  "eventsink.encoding",
+		Usage: `Serialization format of the streamed chain events ("json" or "rlp")`,
+		Value: eventsink.DefaultConfig.Encoding,
+	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "OTLP/HTTP endpoint to export tracing spans to (e.g. http://localhost:4318/v1/traces)",
+	}
+	TracingServiceFlag = cli.StringFlag{
+		Name:  "tracing.service",
+		Usage: "Service name the exported tracing spans are reported under",
+		Value: otlp.DefaultConfig.Service,
+	}
+	TracingSampleFlag = cli.Float64Flag{
+		Name:  "tracing.sample",
+		Usage: "Ratio of the traces to export, between 0 and 1",
+		Value: otlp.DefaultConfig.SampleRatio,
+	}
+	TracingHeadersFlag = cli.StringFlag{
+		Name:  "tracing.headers",
+		Usage: "Comma separated key=value HTTP headers sent to the tracing endpoint",
 	}
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
@@ -354,6 +526,10 @@ var (
 		Name:  "ipcpath",
 		Usage: "Filename for IPC socket/pipe within the datadir (explicit paths escape it)",
 	}
+	NoAddressChecksumFlag = cli.BoolFlag{
+		Name:  "noaddresschecksum",
+		Usage: "Output lowercase addresses and skip checksum validation of inputs (compatibility mode)",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -426,6 +602,14 @@ var (
 		Name:  "nodekeyhex",
 		Usage: "P2P node key as hex (for testing)",
 	}
+	NodeKeyPasswordFlag = cli.StringFlag{
+		Name:  "nodekeypassword",
+		Usage: "Password source to encrypt the persisted node key with (file path, env:<VAR>, fd:<N> or cmd:<command>)",
+	}
+	NodeKeyMachineFlag = cli.BoolFlag{
+		Name:  "nodekeymachine",
+		Usage: "Encrypt the persisted node key with the identity of the host machine if no password is given",
+	}
 	NATFlag = cli.StringFlag{
 		Name:  "nat",
 		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
@@ -443,6 +627,10 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	ProxyFlag = cli.StringFlag{
+		Name:  "proxy",
+		Usage: "SOCKS5 proxy for outbound peer connections (socks5://[user:pass@]host:port)",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -696,6 +884,9 @@ func makeDatabaseHandles() int {
 func MakeAddress(ks *keystore.KeyStore, account string) (accounts.Account, error) {
 	// If the specified account is a valid address, return it
 	if common.IsHexAddress(account) {
+		if err := common.VerifyAddressChecksum(account); err != nil {
+			return accounts.Account{}, err
+		}
 		return accounts.Account{Address: common.HexToAddress(account)}, nil
 	}
 	// Otherwise try to interpret the account as a keystore index
@@ -731,15 +922,16 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 }
 
-// MakePasswordList reads password lines from the file specified by the global --password flag.
+// MakePasswordList reads password lines from the source specified by the global
+// --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
-	path := ctx.GlobalString(PasswordFileFlag.Name)
-	if path == "" {
+	source := ctx.GlobalString(PasswordFileFlag.Name)
+	if source == "" {
 		return nil
 	}
-	text, err := ioutil.ReadFile(path)
+	text, err := readPasswords(source)
 	if err != nil {
-		Fatalf("Failed to read password file: %v", err)
+		Fatalf("Failed to read passwords: %v", err)
 	}
 	lines := strings.Split(string(text), "\n")
 	// Sanitise DOS line endings.
@@ -749,8 +941,83 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
+// OutputJSON reports whether the results of a command were requested in machine
+// readable JSON format via the --output flag, instead of human readable text.
+func OutputJSON(ctx *cli.Context) bool {
+	switch format := ctx.String(OutputFormatFlag.Name); format {
+	case "", "text":
+		return false
+	case "json":
+		return true
+	default:
+		Fatalf("Unknown output format %q, supported formats: text, json", format)
+	}
+	return false
+}
+
+// readPasswords retrieves the raw password lines from a password source, which
+// is either a file path or one of the below prefixed forms:
+//
+//	env:<VAR>      the value of an environment variable, cleared after reading
+//	fd:<N>         the contents of an inherited file descriptor
+//	cmd:<command>  the standard output of a command, e.g. a secrets manager CLI
+//
+// For the prefixed forms a single trailing newline is dropped.
+func readPasswords(source string) ([]byte, error) {
+	var (
+		text []byte
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(source, "env:"):
+		name := strings.TrimPrefix(source, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s not set", name)
+		}
+		// Don't leak the password into the environment of child processes
+		os.Unsetenv(name)
+		text = []byte(value)
+
+	case strings.HasPrefix(source, "fd:"):
+		fd, err := strconv.ParseUint(strings.TrimPrefix(source, "fd:"), 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor: %v", err)
+		}
+		file := os.NewFile(uintptr(fd), source)
+		defer file.Close()
+
+		if text, err = ioutil.ReadAll(file); err != nil {
+			return nil, err
+		}
+
+	case strings.HasPrefix(source, "cmd:"):
+		args := strings.Fields(strings.TrimPrefix(source, "cmd:"))
+		if len(args) == 0 {
+			return nil, errors.New("empty password command")
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+		if text, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("password command failed: %v", err)
+		}
+
+	default:
+		return ioutil.ReadFile(source)
+	}
+	return bytes.TrimSuffix(bytes.TrimSuffix(text, []byte("\n")), []byte("\r")), nil
+}
+
+// SetP2PConfig applies P2P-related command line flags to the config, loading the
+// node key if one is given.
 func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setNodeKey(ctx, cfg)
+	overlayP2PConfig(ctx, cfg)
+}
+
+// overlayP2PConfig applies the P2P-related command line flags overriding config
+// file settings to the config.
+func overlayP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setNAT(ctx, cfg)
 	setListenAddress(ctx, cfg)
 	setDiscoveryV5Address(ctx, cfg)
@@ -763,7 +1030,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
-	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || ctx.GlobalBool(LightModeFlag.Name) {
+	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || ctx.GlobalBool(LightModeFlag.Name) || ctx.GlobalBool(StaticSyncFlag.Name) {
 		cfg.NoDiscovery = true
 	}
 
@@ -784,6 +1051,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(ProxyFlag.Name) {
+		cfg.Proxy = ctx.GlobalString(ProxyFlag.Name)
+	}
 
 	if ctx.GlobalBool(DevModeFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -795,9 +1065,29 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
-// SetNodeConfig applies node-related command line flags to the config.
+// SetNodeConfig applies node-related command line flags to the config, loading
+// the node key and reading its password from their sources.
 func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
-	SetP2PConfig(ctx, &cfg.P2P)
+	setNodeKey(ctx, &cfg.P2P)
+	if ctx.GlobalIsSet(NodeKeyPasswordFlag.Name) {
+		text, err := readPasswords(ctx.GlobalString(NodeKeyPasswordFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read node key password: %v", err)
+		}
+		cfg.NodeKeyPassphrase = strings.TrimRight(strings.Split(string(text), "\n")[0], "\r")
+	}
+	if ctx.GlobalIsSet(NodeKeyMachineFlag.Name) {
+		cfg.NodeKeyMachineBound = ctx.GlobalBool(NodeKeyMachineFlag.Name)
+	}
+	OverlayNodeConfig(ctx, cfg)
+}
+
+// OverlayNodeConfig applies the node-related command line flags overriding config
+// file settings to the config. Unlike SetNodeConfig it doesn't touch any key or
+// password source, which may be consumed on first use (e.g. env: and fd: ones),
+// so it can be applied again on top of a reloaded config file.
+func OverlayNodeConfig(ctx *cli.Context, cfg *node.Config) {
+	overlayP2PConfig(ctx, &cfg.P2P)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
@@ -835,9 +1125,30 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
+	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
+		locals := strings.Split(ctx.GlobalString(TxPoolLocalsFlag.Name), ",")
+		for _, account := range locals {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --txpool.locals: %s", trimmed)
+			} else if err := common.VerifyAddressChecksum(trimmed); err != nil {
+				Fatalf("Invalid account in --txpool.locals: %v", err)
+			} else {
+				cfg.Locals = append(cfg.Locals, common.HexToAddress(trimmed))
+			}
+		}
+	}
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRebroadcastFlag.Name) {
+		cfg.Rebroadcast = ctx.GlobalDuration(TxPoolRebroadcastFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -861,6 +1172,23 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	}
 }
 
+func setTxRescue(ctx *cli.Context, cfg *eth.RescueConfig) {
+	if ctx.GlobalIsSet(TxRescueFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(TxRescueFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxRescueWindowFlag.Name) {
+		cfg.Window = ctx.GlobalDuration(TxRescueWindowFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxRescuePriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.GlobalUint64(TxRescuePriceBumpFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxRescuePriceCapFlag.Name) {
+		if price := GlobalBig(ctx, TxRescuePriceCapFlag.Name); price.Sign() > 0 {
+			cfg.PriceCap = price
+		}
+	}
+}
+
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EthashCacheDirFlag.Name) {
 		cfg.EthashCacheDir = ctx.GlobalString(EthashCacheDirFlag.Name)
@@ -904,6 +1232,59 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// SetGovernorConfig applies memory governor related command line flags to the config.
+func SetGovernorConfig(ctx *cli.Context, cfg *governor.Config) {
+	if ctx.GlobalIsSet(GovernorHighFlag.Name) {
+		cfg.HighWatermark = ctx.GlobalUint64(GovernorHighFlag.Name)
+	}
+	if ctx.GlobalIsSet(GovernorCriticalFlag.Name) {
+		cfg.CriticalWatermark = ctx.GlobalUint64(GovernorCriticalFlag.Name)
+	}
+}
+
+// SetWebhooksConfig applies web-hooks related command line flags to the config.
+func SetWebhooksConfig(ctx *cli.Context, cfg *webhooks.Config) {
+	if ctx.GlobalIsSet(WebhooksFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(WebhooksFlag.Name)
+	}
+	if ctx.GlobalIsSet(WebhooksRetriesFlag.Name) {
+		cfg.Retries = ctx.GlobalInt(WebhooksRetriesFlag.Name)
+	}
+}
+
+// SetEventSinkConfig applies event sink related command line flags to the config.
+func SetEventSinkConfig(ctx *cli.Context, cfg *eventsink.Config) {
+	if ctx.GlobalIsSet(EventSinkURLFlag.Name) {
+		cfg.URL = ctx.GlobalString(EventSinkURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(EventSinkEncodingFlag.Name) {
+		cfg.Encoding = ctx.GlobalString(EventSinkEncodingFlag.Name)
+	}
+}
+
+// SetTracingConfig applies tracing related command line flags to the config.
+func SetTracingConfig(ctx *cli.Context, cfg *otlp.Config) {
+	if ctx.GlobalIsSet(TracingEndpointFlag.Name) {
+		cfg.Endpoint = ctx.GlobalString(TracingEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(TracingServiceFlag.Name) {
+		cfg.Service = ctx.GlobalString(TracingServiceFlag.Name)
+	}
+	if ctx.GlobalIsSet(TracingSampleFlag.Name) {
+		cfg.SampleRatio = ctx.GlobalFloat64(TracingSampleFlag.Name)
+	}
+	if ctx.GlobalIsSet(TracingHeadersFlag.Name) {
+		cfg.Headers = make(map[string]string)
+		for _, header := range strings.Split(ctx.GlobalString(TracingHeadersFlag.Name), ",") {
+			kv := strings.SplitN(header, "=", 2)
+			if len(kv) != 2 {
+				Fatalf("Invalid tracing header %q, want key=value", header)
+			}
+			cfg.Headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+}
+
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
@@ -914,6 +1295,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setTxRescue(ctx, &cfg.TxRescue)
 	setEthash(ctx, cfg)
 
 	switch {
@@ -924,12 +1306,41 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	case ctx.GlobalBool(LightModeFlag.Name):
 		cfg.SyncMode = downloader.LightSync
 	}
+	switch gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode {
+	case "full":
+		cfg.StateGC = true
+	case "archive":
+		cfg.StateGC = false
+	default:
+		Fatalf("Option %q: invalid garbage collection mode %q, want \"full\" or \"archive\"", GCModeFlag.Name, gcmode)
+	}
+	if ctx.GlobalIsSet(StaticSyncFlag.Name) {
+		cfg.StaticSync = ctx.GlobalBool(StaticSyncFlag.Name)
+	}
+	if ctx.GlobalIsSet(SyncAnchorFlag.Name) {
+		if err := cfg.SyncAnchor.UnmarshalText([]byte(ctx.GlobalString(SyncAnchorFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", SyncAnchorFlag.Name, err)
+		}
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightRetentionFlag.Name) {
+		cfg.LightHeaderRetention = ctx.GlobalUint64(LightRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightProbeFlag.Name) {
+		cfg.LightProbeLatency = ctx.GlobalBool(LightProbeFlag.Name)
+	}
+	if nets := ctx.GlobalString(LightPreferNetsFlag.Name); nets != "" {
+		list, err := netutil.ParseNetlist(nets)
+		if err != nil {
+			Fatalf("Option %q: %v", LightPreferNetsFlag.Name, err)
+		}
+		cfg.LightPreferNets = list
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -939,10 +1350,29 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	cfg.MaxPeers = ctx.GlobalInt(MaxPeersFlag.Name)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) {
-		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
+		budget := eth.SplitCache(ctx.GlobalInt(CacheFlag.Name))
+		cfg.DatabaseCache = budget.Database
+		cfg.TrieCache = budget.Trie
+		cfg.GCHeadroom = budget.GC
+
+		// Light clients keep no state tries, an explicit trie cache generation limit
+		// opts out of automatic balancing
+		if cfg.SyncMode == downloader.LightSync {
+			cfg.DatabaseCache += cfg.TrieCache
+			cfg.TrieCache, cfg.GCHeadroom = 0, 0
+		}
+		if ctx.GlobalIsSet(TrieCacheGenFlag.Name) {
+			cfg.TrieCache, cfg.GCHeadroom = 0, 0
+		}
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.StateSnapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
@@ -952,6 +1382,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(MinerTxOrderFlag.Name) {
+		cfg.MinerTxOrder = ctx.GlobalString(MinerTxOrderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
+		cfg.MinerNotify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(RemoteSealerFlag.Name) {
+		cfg.RemoteSealer = ctx.GlobalString(RemoteSealerFlag.Name)
+	}
+	if ctx.GlobalIsSet(SigningPolicyFlag.Name) {
+		cfg.SigningPolicy = ctx.GlobalString(SigningPolicyFlag.Name)
+	}
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
@@ -959,6 +1401,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMTrackAccessFlag.Name) {
+		cfg.TrackStateAccess = ctx.GlobalBool(VMTrackAccessFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMParallelFlag.Name) {
+		cfg.ParallelExecution = ctx.GlobalBool(VMParallelFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMWitnessFlag.Name) {
+		cfg.RecordWitness = ctx.GlobalBool(VMWitnessFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -982,7 +1433,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
-		state.MaxTrieCacheGen = uint16(gen)
+		state.SetMaxTrieCacheGen(uint16(gen))
+	}
+	if limit := ctx.GlobalInt(HeaderCacheFlag.Name); limit > 0 {
+		core.HeaderCacheLimit = limit
 	}
 }
 
@@ -991,13 +1445,21 @@ func RegisterEthService(stack *node.Node, cfg *eth.Config) {
 	var err error
 	if cfg.SyncMode == downloader.LightSync {
 		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, cfg)
+			lightNode, err := les.New(ctx, cfg)
+			if err != nil {
+				return nil, err
+			}
+			lightNode.Supervise(ctx.Go)
+			return lightNode, nil
 		})
 	} else {
 		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			fullNode, err := eth.New(ctx, cfg)
 			if fullNode != nil && cfg.LightServ > 0 {
-				ls, _ := les.NewLesServer(fullNode, cfg)
+				ls, err := les.NewLesServer(fullNode, cfg)
+				if err != nil {
+					return nil, err
+				}
 				fullNode.AddLesServer(ls)
 			}
 			return fullNode, err
@@ -1011,15 +1473,18 @@ func RegisterEthService(stack *node.Node, cfg *eth.Config) {
 // RegisterShhService configures Whisper and adds it to the given node.
 func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
-		return whisper.New(cfg), nil
+		shh := whisper.New(cfg)
+		shh.Supervise(n.Go)
+		return shh, nil
 	}); err != nil {
 		Fatalf("Failed to register the Whisper service: %v", err)
 	}
 }
 
 // RegisterEthStatsService configures the NetworkChain Stats daemon and adds it to
-// th egiven node.
-func RegisterEthStatsService(stack *node.Node, url string) {
+// the given node. Apart from reporting to the monitoring servers in url, the stats
+// are optionally exported into a local file and/or unix socket.
+func RegisterEthStatsService(stack *node.Node, url string, file string, socket string) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		// Retrieve both eth and les services
 		var ethServ *eth.NetworkChain
@@ -1028,12 +1493,119 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 		var lesServ *les.LightNetworkChain
 		ctx.Service(&lesServ)
 
-		return ethstats.New(url, ethServ, lesServ)
+		stats, err := ethstats.New(url, ethServ, lesServ)
+		if err != nil {
+			return nil, err
+		}
+		stats.Supervise(ctx.Go)
+		if file != "" {
+			stats.ExportFile(stack.ResolvePath(file))
+		}
+		if socket != "" {
+			stats.ExportSocket(stack.ResolvePath(socket))
+		}
+		return stats, nil
 	}); err != nil {
 		Fatalf("Failed to register the NetworkChain Stats service: %v", err)
 	}
 }
 
+// RegisterWebhooksService configures the web-hooks service and adds it to the
+// given node, notifying the registered callbacks of the address activity in the
+// blocks imported by the full NetworkChain service.
+func RegisterWebhooksService(stack *node.Node, cfg *webhooks.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.NetworkChain
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, fmt.Errorf("web-hooks require a full node: %v", err)
+		}
+		config := *cfg
+		if config.Store != "" {
+			config.Store = stack.ResolvePath(config.Store)
+		}
+		return webhooks.New(config, ethServ.EventMux(), ethServ.BlockChain().Config())
+	}); err != nil {
+		Fatalf("Failed to register the web-hooks service: %v", err)
+	}
+}
+
+// RegisterEventSinkService configures the event sink and adds it to the given
+// node, streaming the chain events of the full or light NetworkChain service into
+// the configured message broker.
+func RegisterEventSinkService(stack *node.Node, cfg *eventsink.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.NetworkChain
+		if ctx.Service(&ethServ) == nil {
+			return eventsink.New(*cfg, ethServ.EventMux())
+		}
+		var lesServ *les.LightNetworkChain
+		if ctx.Service(&lesServ) == nil {
+			return eventsink.New(*cfg, lesServ.EventMux())
+		}
+		return nil, errors.New("no networkchain service to stream events of")
+	}); err != nil {
+		Fatalf("Failed to register the event sink: %v", err)
+	}
+}
+
+// RegisterTracingService configures the tracing span exporter and adds it to the
+// given node, exporting the spans recorded by all services while the node runs.
+func RegisterTracingService(stack *node.Node, cfg *otlp.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return otlp.New(*cfg)
+	}); err != nil {
+		Fatalf("Failed to register the tracing span exporter: %v", err)
+	}
+}
+
+// RegisterGovernorService configures the memory governor and adds it to the given
+// node, shedding the caches and background work of the running NetworkChain
+// service when the resident memory exceeds the configured watermarks.
+func RegisterGovernorService(stack *node.Node, cfg *governor.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		gov := governor.New(*cfg)
+
+		var ethServ *eth.NetworkChain
+		if ctx.Service(&ethServ) == nil {
+			gov.Register("blockchain", func(level governor.Level) {
+				ethServ.BlockChain().CapCaches(level > governor.Normal)
+				capBlockCache(ethServ.ChainDb(), level > governor.Normal)
+				ethServ.PauseIndexing(level == governor.Critical)
+				ethServ.PauseSync(level == governor.Critical)
+			})
+		}
+		var lesServ *les.LightNetworkChain
+		if ctx.Service(&lesServ) == nil {
+			gov.Register("lightchain", func(level governor.Level) {
+				lesServ.BlockChain().CapCaches(level > governor.Normal)
+				capBlockCache(lesServ.ChainDb(), level > governor.Normal)
+				lesServ.BlockChain().PausePruning(level == governor.Critical)
+			})
+		}
+		return gov, nil
+	}); err != nil {
+		Fatalf("Failed to register the memory governor: %v", err)
+	}
+}
+
+// capBlockCache shrinks (or restores) the block cache of a chain database, if it
+// supports resizing it.
+func capBlockCache(db ethdb.Database, capped bool) {
+	if db, ok := db.(interface {
+		CapBlockCache(capped bool)
+	}); ok {
+		db.CapBlockCache(capped)
+	}
+}
+
+// SetupAddressChecksums configures the encoding and validation of addresses. It
+// must run before any config file or address is parsed.
+func SetupAddressChecksums(ctx *cli.Context) {
+	if ctx.GlobalBool(NoAddressChecksumFlag.Name) {
+		common.AddressChecksums = false
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1050,7 +1622,15 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if ctx.GlobalBool(LightModeFlag.Name) {
 		name = "lightchaindata"
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if ctx.GlobalBool(ReadOnlyFlag.Name) {
+		chainDb, err = stack.OpenDatabaseReadOnly(name, cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabase(name, cache, handles)
+	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1079,11 +1659,27 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if !ctx.GlobalBool(FakePoWFlag.Name) {
 		engine = ethash.New("", 1, 0, "", 1, 0)
 	}
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
-	if err != nil {
-		Fatalf("%v", err)
+	var config *params.ChainConfig
+	if ctx.GlobalBool(ReadOnlyFlag.Name) {
+		// Read-only databases can't be initialized, use whatever is stored
+		genesis := core.GetCanonicalHash(chainDb, 0)
+		if genesis == (common.Hash{}) {
+			Fatalf("Database contains no genesis block")
+		}
+		if config, err = core.GetChainConfig(chainDb, genesis); err != nil {
+			Fatalf("Failed to load chain config: %v", err)
+		}
+	} else {
+		if config, _, err = core.SetupGenesisBlock(chainDb, MakeGenesis(ctx)); err != nil {
+			Fatalf("%v", err)
+		}
+	}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		TrackStateAccess:        ctx.GlobalBool(VMTrackAccessFlag.Name),
+		ParallelExecution:       ctx.GlobalBool(VMParallelFlag.Name),
+		RecordWitness:           ctx.GlobalBool(VMWitnessFlag.Name),
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, config, engine, new(event.TypeMux), vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
@@ -0,0 +1,60 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of networkchain.
+//
+// networkchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// networkchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with networkchain. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// Tests that passwords can be retrieved from all the supported sources.
+func TestReadPasswords(t *testing.T) {
+	// Passwords from a file are returned verbatim
+	file, err := ioutil.TempFile("", "password-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("file-pass\n")
+	file.Close()
+
+	if text, err := readPasswords(file.Name()); err != nil || string(text) != "file-pass\n" {
+		t.Errorf("file password mismatch: have %q (%v), want %q", text, err, "file-pass\n")
+	}
+	// Passwords from the environment are cleared after reading
+	os.Setenv("NETK_TEST_PASSWORD", "env-pass\n")
+	if text, err := readPasswords("env:NETK_TEST_PASSWORD"); err != nil || string(text) != "env-pass" {
+		t.Errorf("env password mismatch: have %q (%v), want %q", text, err, "env-pass")
+	}
+	if _, ok := os.LookupEnv("NETK_TEST_PASSWORD"); ok {
+		t.Errorf("password environment variable not cleared")
+	}
+	if _, err := readPasswords("env:NETK_TEST_PASSWORD"); err == nil {
+		t.Errorf("expected error for unset environment variable")
+	}
+	// Passwords from commands are read from their output
+	if runtime.GOOS != "windows" {
+		if text, err := readPasswords("cmd:echo cmd-pass"); err != nil || string(text) != "cmd-pass" {
+			t.Errorf("cmd password mismatch: have %q (%v), want %q", text, err, "cmd-pass")
+		}
+		if _, err := readPasswords("cmd:false"); err == nil {
+			t.Errorf("expected error for failing password command")
+		}
+	}
+}
@@ -20,11 +20,15 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 	"reflect"
 	"strconv"
 )
 
+// streamChunk is the number of bytes hex encoded at a time by StreamJSON.
+const streamChunk = 4096
+
 var (
 	textZero = []byte(`0x0`)
 	bytesT   = reflect.TypeOf(Bytes(nil))
@@ -45,6 +49,20 @@ func (b Bytes) MarshalText() ([]byte, error) {
 	return result, nil
 }
 
+// MarshalJSON implements json.Marshaler, encoding the quoted hex string directly
+// instead of going through MarshalText and the JSON string escaping, which for
+// large byte slices would build the encoding in memory multiple times.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return encodeJSON(b, true), nil
+}
+
+// StreamJSON writes the same encoding as MarshalJSON to w piecewise, only ever
+// holding a small chunk of the hex string in memory. The RPC server uses it to
+// stream large results to the client.
+func (b Bytes) StreamJSON(w io.Writer) error {
+	return streamJSON(w, b, true)
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (b *Bytes) UnmarshalJSON(input []byte) error {
 	if !isString(input) {
@@ -73,6 +91,46 @@ func (b Bytes) String() string {
 	return Encode(b)
 }
 
+// UnprefixedBytes marshals/unmarshals as a JSON string without 0x prefix.
+type UnprefixedBytes []byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (b UnprefixedBytes) MarshalText() ([]byte, error) {
+	result := make([]byte, len(b)*2)
+	hex.Encode(result, b)
+	return result, nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the quoted hex string directly.
+func (b UnprefixedBytes) MarshalJSON() ([]byte, error) {
+	return encodeJSON(b, false), nil
+}
+
+// StreamJSON writes the same encoding as MarshalJSON to w piecewise.
+func (b UnprefixedBytes) StreamJSON(w io.Writer) error {
+	return streamJSON(w, b, false)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *UnprefixedBytes) UnmarshalText(input []byte) error {
+	raw, err := checkText(input, false)
+	if err != nil {
+		return err
+	}
+	dec := make([]byte, len(raw)/2)
+	if _, err = hex.Decode(dec, raw); err != nil {
+		err = mapError(err)
+	} else {
+		*b = dec
+	}
+	return err
+}
+
+// String returns the unprefixed hex encoding of b.
+func (b UnprefixedBytes) String() string {
+	return hex.EncodeToString(b)
+}
+
 // UnmarshalFixedJSON decodes the input as a string with 0x prefix. The length of out
 // determines the required input length. This function is commonly used to implement the
 // UnmarshalJSON method for fixed-size types.
@@ -270,6 +328,46 @@ func (b Uint) String() string {
 	return EncodeUint64(uint64(b))
 }
 
+// encodeJSON encodes b as a quoted hex string, optionally 0x prefixed, into a
+// single exactly sized buffer.
+func encodeJSON(b []byte, prefix bool) []byte {
+	offset := 1
+	if prefix {
+		offset += 2
+	}
+	result := make([]byte, offset+len(b)*2+1)
+	copy(result, `"0x`[:offset])
+	hex.Encode(result[offset:], b)
+	result[len(result)-1] = '"'
+	return result
+}
+
+// streamJSON writes b as a quoted hex string, optionally 0x prefixed, to w in
+// chunks of at most streamChunk bytes.
+func streamJSON(w io.Writer, b []byte, prefix bool) error {
+	buf := make([]byte, 3+2*streamChunk)
+
+	n := 1
+	if prefix {
+		n += 2
+	}
+	copy(buf, `"0x`[:n])
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > streamChunk {
+			chunk = chunk[:streamChunk]
+		}
+		hex.Encode(buf[n:], chunk)
+		if _, err := w.Write(buf[:n+2*len(chunk)]); err != nil {
+			return err
+		}
+		b, n = b[len(chunk):], 0
+	}
+	buf[n] = '"'
+	_, err := w.Write(buf[:n+1])
+	return err
+}
+
 func isString(input []byte) bool {
 	return len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"'
 }
@@ -124,6 +124,71 @@ func TestMarshalBytes(t *testing.T) {
 	}
 }
 
+func TestStreamBytes(t *testing.T) {
+	large := make([]byte, 3*streamChunk+5)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	inputs := [][]byte{{}, {0x01, 0x02}, large}
+	for _, test := range encodeBytesTests {
+		inputs = append(inputs, test.input.([]byte))
+	}
+	for _, in := range inputs {
+		want, _ := json.Marshal(Bytes(in))
+
+		var out bytes.Buffer
+		if err := Bytes(in).StreamJSON(&out); err != nil {
+			t.Errorf("%x: %v", in, err)
+			continue
+		}
+		if out.String() != string(want) {
+			t.Errorf("%x: StreamJSON output mismatch: got %q, want %q", in, out.String(), want)
+		}
+		want, _ = json.Marshal(UnprefixedBytes(in))
+
+		out.Reset()
+		if err := UnprefixedBytes(in).StreamJSON(&out); err != nil {
+			t.Errorf("%x: %v", in, err)
+			continue
+		}
+		if out.String() != string(want) {
+			t.Errorf("%x: unprefixed StreamJSON output mismatch: got %q, want %q", in, out.String(), want)
+		}
+	}
+}
+
+func BenchmarkMarshalBytes(b *testing.B) {
+	input := Bytes(make([]byte, 1024*1024))
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMarshalUnprefixedBytes(t *testing.T) {
+	for _, test := range encodeBytesTests {
+		in := test.input.([]byte)
+		out, err := json.Marshal(UnprefixedBytes(in))
+		if err != nil {
+			t.Errorf("%x: %v", in, err)
+			continue
+		}
+		if want := `"` + test.want[2:] + `"`; string(out) != want {
+			t.Errorf("%x: MarshalJSON output mismatch: got %q, want %q", in, out, want)
+			continue
+		}
+		var dec UnprefixedBytes
+		if err := json.Unmarshal(out, &dec); err != nil {
+			t.Errorf("%x: failed to unmarshal: %v", in, err)
+			continue
+		}
+		if !bytes.Equal(dec, in) {
+			t.Errorf("%x: roundtrip mismatch: got %x", in, dec)
+		}
+	}
+}
+
 var unmarshalBigTests = []unmarshalTest{
 	// invalid encoding
 	{input: "", wantErr: errJSONEOF},
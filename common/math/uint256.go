@@ -0,0 +1,500 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Uint256 is a fixed-width 256 bit unsigned integer, stored as four 64 bit limbs
+// in little endian order. Arithmetic wraps modulo 2^256 like the EVM does, with
+// overflow-checked variants reporting whether the result was truncated. Signed
+// operations interpret the value in two's complement. Being a value type, it
+// does not allocate, unlike big.Int.
+type Uint256 [4]uint64
+
+// NewUint256 creates a 256 bit integer from a uint64.
+func NewUint256(x uint64) Uint256 {
+	return Uint256{x}
+}
+
+// Uint256FromBig converts a non-negative big integer into a 256 bit integer,
+// returning whether it did not fit into 256 bits (in which case it's truncated).
+func Uint256FromBig(x *big.Int) (Uint256, bool) {
+	var z Uint256
+	words := x.Bits()
+	for i := 0; i < len(words) && i*wordBits < 256; i++ {
+		if wordBits == 64 {
+			z[i] = uint64(words[i])
+		} else {
+			z[i/2] |= uint64(words[i]) << (32 * uint(i%2))
+		}
+	}
+	return z, x.BitLen() > 256
+}
+
+// Uint256FromBytes interprets b as a big endian unsigned integer, truncating it
+// to the lowest 256 bits if longer than 32 bytes.
+func Uint256FromBytes(b []byte) Uint256 {
+	var z Uint256
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	for i := 0; i < len(b); i++ {
+		z[i/8] |= uint64(b[len(b)-1-i]) << (8 * uint(i%8))
+	}
+	return z
+}
+
+// Big returns the value as a big integer.
+func (x Uint256) Big() *big.Int {
+	b := x.Bytes32()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// Bytes32 returns the value as a 32 byte big endian array.
+func (x Uint256) Bytes32() [32]byte {
+	var b [32]byte
+	for i := 0; i < 32; i++ {
+		b[31-i] = byte(x[i/8] >> (8 * uint(i%8)))
+	}
+	return b
+}
+
+// String returns the decimal representation of the value.
+func (x Uint256) String() string {
+	return x.Big().String()
+}
+
+// Uint64 returns the lowest 64 bits of the value.
+func (x Uint256) Uint64() uint64 {
+	return x[0]
+}
+
+// IsUint64 returns whether the value fits into a uint64.
+func (x Uint256) IsUint64() bool {
+	return x[1]|x[2]|x[3] == 0
+}
+
+// Uint64WithOverflow returns the lowest 64 bits of the value, and whether the
+// value did not fit into them.
+func (x Uint256) Uint64WithOverflow() (uint64, bool) {
+	return x[0], !x.IsUint64()
+}
+
+// IsZero returns whether the value is zero.
+func (x Uint256) IsZero() bool {
+	return x[0]|x[1]|x[2]|x[3] == 0
+}
+
+// IsNeg returns whether the value is negative in two's complement.
+func (x Uint256) IsNeg() bool {
+	return x[3]>>63 != 0
+}
+
+// BitLen returns the number of bits required to represent the value.
+func (x Uint256) BitLen() int {
+	for i := 3; i >= 0; i-- {
+		if x[i] != 0 {
+			return i*64 + bits.Len64(x[i])
+		}
+	}
+	return 0
+}
+
+// Cmp compares x and y, returning -1 if x < y, 0 if x == y and +1 if x > y.
+func (x Uint256) Cmp(y Uint256) int {
+	for i := 3; i >= 0; i-- {
+		switch {
+		case x[i] < y[i]:
+			return -1
+		case x[i] > y[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// SCmp compares x and y as two's complement signed integers, returning -1 if
+// x < y, 0 if x == y and +1 if x > y.
+func (x Uint256) SCmp(y Uint256) int {
+	switch xneg, yneg := x.IsNeg(), y.IsNeg(); {
+	case xneg && !yneg:
+		return -1
+	case !xneg && yneg:
+		return 1
+	}
+	return x.Cmp(y)
+}
+
+// Add returns x + y modulo 2^256.
+func (x Uint256) Add(y Uint256) Uint256 {
+	z, _ := x.AddOverflow(y)
+	return z
+}
+
+// AddOverflow returns x + y modulo 2^256, and whether the addition overflowed.
+func (x Uint256) AddOverflow(y Uint256) (Uint256, bool) {
+	var (
+		z     Uint256
+		carry uint64
+	)
+	z[0], carry = bits.Add64(x[0], y[0], 0)
+	z[1], carry = bits.Add64(x[1], y[1], carry)
+	z[2], carry = bits.Add64(x[2], y[2], carry)
+	z[3], carry = bits.Add64(x[3], y[3], carry)
+	return z, carry != 0
+}
+
+// Sub returns x - y modulo 2^256.
+func (x Uint256) Sub(y Uint256) Uint256 {
+	z, _ := x.SubUnderflow(y)
+	return z
+}
+
+// SubUnderflow returns x - y modulo 2^256, and whether the subtraction underflowed.
+func (x Uint256) SubUnderflow(y Uint256) (Uint256, bool) {
+	var (
+		z      Uint256
+		borrow uint64
+	)
+	z[0], borrow = bits.Sub64(x[0], y[0], 0)
+	z[1], borrow = bits.Sub64(x[1], y[1], borrow)
+	z[2], borrow = bits.Sub64(x[2], y[2], borrow)
+	z[3], borrow = bits.Sub64(x[3], y[3], borrow)
+	return z, borrow != 0
+}
+
+// Neg returns -x modulo 2^256.
+func (x Uint256) Neg() Uint256 {
+	return Uint256{}.Sub(x)
+}
+
+// abs returns the absolute value of x interpreted in two's complement.
+func (x Uint256) abs() Uint256 {
+	if x.IsNeg() {
+		return x.Neg()
+	}
+	return x
+}
+
+// Mul returns x * y modulo 2^256.
+func (x Uint256) Mul(y Uint256) Uint256 {
+	// Schoolbook multiplication skipping the limbs above 256 bits
+	var z Uint256
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; i+j < 4; j++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			lo, c := bits.Add64(lo, z[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			z[i+j], carry = lo, hi
+		}
+	}
+	return z
+}
+
+// MulOverflow returns x * y modulo 2^256, and whether the multiplication overflowed.
+func (x Uint256) MulOverflow(y Uint256) (Uint256, bool) {
+	res := x.mulFull(y)
+	return Uint256{res[0], res[1], res[2], res[3]}, res[4]|res[5]|res[6]|res[7] != 0
+}
+
+// mulFull returns the full 512 bit product of x and y.
+func (x Uint256) mulFull(y Uint256) [8]uint64 {
+	// Schoolbook multiplication into a 512 bit result
+	var res [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			lo, c := bits.Add64(lo, res[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			res[i+j], carry = lo, hi
+		}
+		res[i+4] = carry
+	}
+	return res
+}
+
+// Div returns x / y, or zero if y is zero (as per the EVM semantics).
+func (x Uint256) Div(y Uint256) Uint256 {
+	quo, _ := x.divmod(y)
+	return quo
+}
+
+// Mod returns x % y, or zero if y is zero (as per the EVM semantics).
+func (x Uint256) Mod(y Uint256) Uint256 {
+	_, rem := x.divmod(y)
+	return rem
+}
+
+// SDiv returns x / y as two's complement signed integers, rounding towards zero,
+// or zero if y is zero (as per the EVM semantics).
+func (x Uint256) SDiv(y Uint256) Uint256 {
+	quo := x.abs().Div(y.abs())
+	if x.IsNeg() != y.IsNeg() {
+		return quo.Neg()
+	}
+	return quo
+}
+
+// SMod returns x % y as two's complement signed integers, the result taking the
+// sign of x, or zero if y is zero (as per the EVM semantics).
+func (x Uint256) SMod(y Uint256) Uint256 {
+	rem := x.abs().Mod(y.abs())
+	if x.IsNeg() {
+		return rem.Neg()
+	}
+	return rem
+}
+
+// AddMod returns (x + y) % m without truncating the intermediate sum, or zero if
+// m is zero (as per the EVM semantics).
+func (x Uint256) AddMod(y, m Uint256) Uint256 {
+	if m.IsZero() {
+		return Uint256{}
+	}
+	sum, carry := x.AddOverflow(y)
+	if !carry {
+		return sum.Mod(m)
+	}
+	return udivrem(nil, []uint64{sum[0], sum[1], sum[2], sum[3], 1}, m)
+}
+
+// MulMod returns (x * y) % m without truncating the intermediate product, or
+// zero if m is zero (as per the EVM semantics).
+func (x Uint256) MulMod(y, m Uint256) Uint256 {
+	if m.IsZero() {
+		return Uint256{}
+	}
+	res := x.mulFull(y)
+	return udivrem(nil, res[:], m)
+}
+
+// Exp returns x ** y modulo 2^256.
+func (x Uint256) Exp(y Uint256) Uint256 {
+	z, base := NewUint256(1), x
+	for i, n := 0, y.BitLen(); i < n; i++ {
+		if y[i/64]>>uint(i%64)&1 != 0 {
+			z = z.Mul(base)
+		}
+		base = base.Mul(base)
+	}
+	return z
+}
+
+// divmod computes the quotient and remainder of x / y, both being zero if y is
+// zero.
+func (x Uint256) divmod(y Uint256) (quo, rem Uint256) {
+	if y.IsZero() {
+		return Uint256{}, Uint256{}
+	}
+	if x.Cmp(y) < 0 {
+		return Uint256{}, x
+	}
+	if x.IsUint64() {
+		return NewUint256(x[0] / y[0]), NewUint256(x[0] % y[0])
+	}
+	rem = udivrem(quo[:], x[:], y)
+	return quo, rem
+}
+
+// udivrem divides the little endian limbs of u by the non-zero d using Knuth's
+// algorithm D, returning the remainder. The quotient is stored into quo if it's
+// non-nil, which must hold at least len(u) limbs.
+func udivrem(quo, u []uint64, d Uint256) Uint256 {
+	// Strip the insignificant limbs of the divisor and the dividend
+	n := 4
+	for d[n-1] == 0 {
+		n--
+	}
+	m := len(u)
+	for m > 0 && u[m-1] == 0 {
+		m--
+	}
+	if m < n {
+		var rem Uint256
+		copy(rem[:], u[:m])
+		return rem
+	}
+	// Single limb divisors are divided limb by limb
+	if n == 1 {
+		var r uint64
+		for i := m - 1; i >= 0; i-- {
+			var q uint64
+			q, r = bits.Div64(r, u[i], d[0])
+			if quo != nil {
+				quo[i] = q
+			}
+		}
+		return NewUint256(r)
+	}
+	// Normalise the operands so the top limb of the divisor has its high bit set,
+	// which keeps the quotient digit estimates off by at most two
+	var (
+		shift = uint(bits.LeadingZeros64(d[n-1]))
+		dn    [4]uint64
+		buf   [9]uint64
+		un    = buf[:m+1]
+	)
+	for i := n - 1; i > 0; i-- {
+		dn[i] = d[i]<<shift | d[i-1]>>(64-shift)
+	}
+	dn[0] = d[0] << shift
+
+	un[m] = u[m-1] >> (64 - shift)
+	for i := m - 1; i > 0; i-- {
+		un[i] = u[i]<<shift | u[i-1]>>(64-shift)
+	}
+	un[0] = u[0] << shift
+
+	for j := m - n; j >= 0; j-- {
+		// Estimate the quotient digit from the top limbs, then correct it
+		var (
+			qhat, rhat uint64
+			overflow   bool
+		)
+		if un[j+n] >= dn[n-1] {
+			var carry uint64
+			qhat = MaxUint64
+			rhat, carry = bits.Add64(un[j+n-1], dn[n-1], 0)
+			overflow = carry != 0
+		} else {
+			qhat, rhat = bits.Div64(un[j+n], un[j+n-1], dn[n-1])
+		}
+		for !overflow {
+			hi, lo := bits.Mul64(qhat, dn[n-2])
+			if hi < rhat || (hi == rhat && lo <= un[j+n-2]) {
+				break
+			}
+			qhat--
+
+			var carry uint64
+			rhat, carry = bits.Add64(rhat, dn[n-1], 0)
+			overflow = carry != 0
+		}
+		// Multiply and subtract, adding back if the digit was still one too large
+		var carry, borrow uint64
+		for i := 0; i < n; i++ {
+			hi, lo := bits.Mul64(qhat, dn[i])
+			lo, c := bits.Add64(lo, carry, 0)
+			carry = hi + c
+			un[i+j], borrow = bits.Sub64(un[i+j], lo, borrow)
+		}
+		un[j+n], borrow = bits.Sub64(un[j+n], carry, borrow)
+		if borrow != 0 {
+			qhat--
+
+			var c uint64
+			for i := 0; i < n; i++ {
+				un[i+j], c = bits.Add64(un[i+j], dn[i], c)
+			}
+			un[j+n] += c
+		}
+		if quo != nil {
+			quo[j] = qhat
+		}
+	}
+	// Denormalise the remainder
+	var rem Uint256
+	for i := 0; i < n; i++ {
+		rem[i] = un[i]>>shift | un[i+1]<<(64-shift)
+	}
+	return rem
+}
+
+// And returns the bitwise x & y.
+func (x Uint256) And(y Uint256) Uint256 {
+	return Uint256{x[0] & y[0], x[1] & y[1], x[2] & y[2], x[3] & y[3]}
+}
+
+// Or returns the bitwise x | y.
+func (x Uint256) Or(y Uint256) Uint256 {
+	return Uint256{x[0] | y[0], x[1] | y[1], x[2] | y[2], x[3] | y[3]}
+}
+
+// Xor returns the bitwise x ^ y.
+func (x Uint256) Xor(y Uint256) Uint256 {
+	return Uint256{x[0] ^ y[0], x[1] ^ y[1], x[2] ^ y[2], x[3] ^ y[3]}
+}
+
+// Not returns the bitwise complement of x.
+func (x Uint256) Not() Uint256 {
+	return Uint256{^x[0], ^x[1], ^x[2], ^x[3]}
+}
+
+// Lsh returns x << n modulo 2^256.
+func (x Uint256) Lsh(n uint) Uint256 {
+	if n >= 256 {
+		return Uint256{}
+	}
+	var z Uint256
+	limbs, shift := int(n/64), n%64
+	for i := 3; i >= limbs; i-- {
+		z[i] = x[i-limbs] << shift
+		if shift > 0 && i-limbs-1 >= 0 {
+			z[i] |= x[i-limbs-1] >> (64 - shift)
+		}
+	}
+	return z
+}
+
+// Rsh returns x >> n.
+func (x Uint256) Rsh(n uint) Uint256 {
+	if n >= 256 {
+		return Uint256{}
+	}
+	var z Uint256
+	limbs, shift := int(n/64), n%64
+	for i := 0; i+limbs < 4; i++ {
+		z[i] = x[i+limbs] >> shift
+		if shift > 0 && i+limbs+1 < 4 {
+			z[i] |= x[i+limbs+1] << (64 - shift)
+		}
+	}
+	return z
+}
+
+// Byte returns the n'th byte of the 32 byte big endian representation of x, or
+// zero if n is out of range (as per the EVM semantics).
+func (x Uint256) Byte(n Uint256) Uint256 {
+	if !n.IsUint64() || n[0] >= 32 {
+		return Uint256{}
+	}
+	i := n[0]
+	return NewUint256(x[3-i/8] >> (56 - 8*(i%8)) & 0xff)
+}
+
+// SignExtend extends the sign of x from the (back+1)'th lowest byte onwards, or
+// returns x unmodified if back is 31 or more (as per the EVM semantics).
+func (x Uint256) SignExtend(back Uint256) Uint256 {
+	if !back.IsUint64() || back[0] >= 31 {
+		return x
+	}
+	bit := uint(back[0]*8 + 7)
+	mask := Uint256{}.Not().Lsh(bit + 1)
+	if x[bit/64]>>(bit%64)&1 != 0 {
+		return x.Or(mask)
+	}
+	return x.And(mask.Not())
+}
@@ -0,0 +1,241 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package math
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randUint256 generates a random 256 bit integer, biased towards edge cases.
+func randUint256(rnd *rand.Rand) Uint256 {
+	var z Uint256
+	switch rnd.Intn(6) {
+	case 0: // small
+		z[0] = uint64(rnd.Intn(1000))
+	case 1: // single limb
+		z[0] = rnd.Uint64()
+	case 2: // all ones in random limbs
+		for i := range z {
+			if rnd.Intn(2) == 0 {
+				z[i] = MaxUint64
+			}
+		}
+	case 3: // random number of low limbs, exercising the division normalisation
+		for i := 0; i <= rnd.Intn(4); i++ {
+			z[i] = rnd.Uint64() >> uint(rnd.Intn(64))
+		}
+	case 4: // small negative
+		z = NewUint256(uint64(rnd.Intn(1000))).Neg()
+	default: // fully random
+		for i := range z {
+			z[i] = rnd.Uint64()
+		}
+	}
+	return z
+}
+
+// bigMod returns x % y, or zero if y is zero, like the EVM does.
+func bigMod(z, x, y *big.Int) *big.Int {
+	if y.Sign() == 0 {
+		return z.SetInt64(0)
+	}
+	return z.Mod(x, y)
+}
+
+// Tests that the fixed-width arithmetic matches big integers modulo 2^256.
+func TestUint256Arithmetic(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	type op struct {
+		name  string
+		fixed func(x, y Uint256) (Uint256, bool)
+		big   func(z, x, y *big.Int) *big.Int
+	}
+	ops := []op{
+		{"add", Uint256.AddOverflow, (*big.Int).Add},
+		{"sub", Uint256.SubUnderflow, (*big.Int).Sub},
+		{"mul", Uint256.MulOverflow, (*big.Int).Mul},
+		{"div", func(x, y Uint256) (Uint256, bool) { return x.Div(y), false }, func(z, x, y *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return z.SetInt64(0)
+			}
+			return z.Div(x, y)
+		}},
+		{"mod", func(x, y Uint256) (Uint256, bool) { return x.Mod(y), false }, bigMod},
+		{"sdiv", func(x, y Uint256) (Uint256, bool) { return x.SDiv(y), false }, func(z, x, y *big.Int) *big.Int {
+			x, y = S256(new(big.Int).Set(x)), S256(new(big.Int).Set(y))
+			if y.Sign() == 0 {
+				return z.SetInt64(0)
+			}
+			return U256(z.Quo(x, y))
+		}},
+		{"smod", func(x, y Uint256) (Uint256, bool) { return x.SMod(y), false }, func(z, x, y *big.Int) *big.Int {
+			x, y = S256(new(big.Int).Set(x)), S256(new(big.Int).Set(y))
+			if y.Sign() == 0 {
+				return z.SetInt64(0)
+			}
+			return U256(z.Rem(x, y))
+		}},
+		{"exp", func(x, y Uint256) (Uint256, bool) { return x.Exp(y), false }, func(z, x, y *big.Int) *big.Int {
+			return z.Exp(x, y, tt256)
+		}},
+		{"and", func(x, y Uint256) (Uint256, bool) { return x.And(y), false }, (*big.Int).And},
+		{"or", func(x, y Uint256) (Uint256, bool) { return x.Or(y), false }, (*big.Int).Or},
+		{"xor", func(x, y Uint256) (Uint256, bool) { return x.Xor(y), false }, (*big.Int).Xor},
+		{"byte", func(x, y Uint256) (Uint256, bool) { return x.Byte(y), false }, func(z, x, y *big.Int) *big.Int {
+			if y.Cmp(big.NewInt(32)) >= 0 {
+				return z.SetInt64(0)
+			}
+			return z.SetUint64(uint64(Byte(x, 32, int(y.Int64()))))
+		}},
+	}
+	for i := 0; i < 5000; i++ {
+		x, y := randUint256(rnd), randUint256(rnd)
+		if i%8 == 0 {
+			y = NewUint256(uint64(rnd.Intn(40))) // Exercise the byte and sign extension ranges
+		}
+		bx, by := x.Big(), y.Big()
+
+		for _, op := range ops {
+			have, overflow := op.fixed(x, y)
+			want := op.big(new(big.Int), bx, by)
+			wantOverflow := want.Sign() < 0 || want.BitLen() > 256
+
+			if have.Big().Cmp(U256(new(big.Int).Set(want))) != 0 {
+				t.Fatalf("%s(%x, %x): result mismatch: have %x, want %x", op.name, bx, by, have.Big(), U256(want))
+			}
+			if overflow != wantOverflow {
+				t.Fatalf("%s(%x, %x): overflow mismatch: have %v, want %v", op.name, bx, by, overflow, wantOverflow)
+			}
+		}
+		if have, want := x.Cmp(y), bx.Cmp(by); have != want {
+			t.Fatalf("cmp(%x, %x): have %d, want %d", bx, by, have, want)
+		}
+		if have, want := x.SCmp(y), S256(new(big.Int).Set(bx)).Cmp(S256(new(big.Int).Set(by))); have != want {
+			t.Fatalf("scmp(%x, %x): have %d, want %d", bx, by, have, want)
+		}
+		shift := uint(rnd.Intn(300))
+		if have, want := x.Lsh(shift).Big(), U256(new(big.Int).Lsh(bx, shift)); have.Cmp(want) != 0 {
+			t.Fatalf("lsh(%x, %d): have %x, want %x", bx, shift, have, want)
+		}
+		if have, want := x.Rsh(shift).Big(), new(big.Int).Rsh(bx, shift); have.Cmp(want) != 0 {
+			t.Fatalf("rsh(%x, %d): have %x, want %x", bx, shift, have, want)
+		}
+		if have, want := x.Not().Big(), U256(new(big.Int).Not(bx)); have.Cmp(want) != 0 {
+			t.Fatalf("not(%x): have %x, want %x", bx, have, want)
+		}
+		if have, want := x.SignExtend(y).Big(), bigSignExtend(bx, by); have.Cmp(want) != 0 {
+			t.Fatalf("signextend(%x, %x): have %x, want %x", bx, by, have, want)
+		}
+		m := randUint256(rnd)
+		bm := m.Big()
+		if have, want := x.AddMod(y, m).Big(), bigMod(new(big.Int), new(big.Int).Add(bx, by), bm); have.Cmp(want) != 0 {
+			t.Fatalf("addmod(%x, %x, %x): have %x, want %x", bx, by, bm, have, want)
+		}
+		if have, want := x.MulMod(y, m).Big(), bigMod(new(big.Int), new(big.Int).Mul(bx, by), bm); have.Cmp(want) != 0 {
+			t.Fatalf("mulmod(%x, %x, %x): have %x, want %x", bx, by, bm, have, want)
+		}
+	}
+}
+
+// bigSignExtend is the big integer sign extension of the EVM's SIGNEXTEND.
+func bigSignExtend(x, back *big.Int) *big.Int {
+	if back.Cmp(big.NewInt(31)) >= 0 {
+		return new(big.Int).Set(x)
+	}
+	bit := uint(back.Uint64()*8 + 7)
+	mask := new(big.Int).Lsh(big.NewInt(1), bit)
+	mask.Sub(mask, big.NewInt(1))
+
+	num := new(big.Int).Set(x)
+	if num.Bit(int(bit)) > 0 {
+		num.Or(num, mask.Not(mask))
+	} else {
+		num.And(num, mask)
+	}
+	return U256(num)
+}
+
+// Tests the conversions between fixed-width and big integers and byte slices.
+func TestUint256Conversions(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		x := randUint256(rnd)
+		bx := x.Big()
+
+		if y, overflow := Uint256FromBig(bx); overflow || y != x {
+			t.Fatalf("big roundtrip mismatch: have %x (overflow %v), want %x", y.Big(), overflow, bx)
+		}
+		b := x.Bytes32()
+		if y := Uint256FromBytes(b[:]); y != x {
+			t.Fatalf("bytes roundtrip mismatch: have %x, want %x", y.Big(), bx)
+		}
+		if have, want := x.BitLen(), bx.BitLen(); have != want {
+			t.Fatalf("bitlen mismatch for %x: have %d, want %d", bx, have, want)
+		}
+		if have, overflow := x.Uint64WithOverflow(); have != bx.Uint64() || overflow != !bx.IsUint64() {
+			t.Fatalf("uint64 mismatch for %x: have %d (overflow %v)", bx, have, overflow)
+		}
+		if have, want := x.String(), bx.String(); have != want {
+			t.Fatalf("string mismatch: have %s, want %s", have, want)
+		}
+	}
+	if _, overflow := Uint256FromBig(new(big.Int).Lsh(big.NewInt(1), 256)); !overflow {
+		t.Errorf("2^256 converted without overflow")
+	}
+}
+
+func benchmarkUint256(b *testing.B, op func(x, y Uint256) Uint256) {
+	x, y := Uint256{1, 2, 3, 4}, Uint256{5, 6, 7}
+	for i := 0; i < b.N; i++ {
+		x = op(x, y)
+	}
+}
+
+func benchmarkBig(b *testing.B, op func(z, x, y *big.Int) *big.Int) {
+	x, y := Uint256{1, 2, 3, 4}.Big(), Uint256{5, 6, 7}.Big()
+	z := new(big.Int)
+	for i := 0; i < b.N; i++ {
+		x, z = U256(op(z, x, y)), x
+	}
+}
+
+func BenchmarkUint256Add(b *testing.B) { benchmarkUint256(b, Uint256.Add) }
+func BenchmarkBigAdd(b *testing.B)     { benchmarkBig(b, (*big.Int).Add) }
+func BenchmarkUint256Mul(b *testing.B) { benchmarkUint256(b, Uint256.Mul) }
+func BenchmarkBigMul(b *testing.B)     { benchmarkBig(b, (*big.Int).Mul) }
+
+func BenchmarkUint256Div(b *testing.B) {
+	benchmarkUint256(b, func(x, y Uint256) Uint256 { return x.Div(y).Add(Uint256{0, 0, 0, 1}) })
+}
+
+func BenchmarkBigDiv(b *testing.B) {
+	one := Uint256{0, 0, 0, 1}.Big()
+	benchmarkBig(b, func(z, x, y *big.Int) *big.Int { return z.Add(z.Div(x, y), one) })
+}
+
+func BenchmarkUint256MulMod(b *testing.B) {
+	m := Uint256{9, 10, 11, 12}
+	benchmarkUint256(b, func(x, y Uint256) Uint256 { return x.MulMod(y, m).Add(y) })
+}
+
+func BenchmarkBigMulMod(b *testing.B) {
+	m := Uint256{9, 10, 11, 12}.Big()
+	benchmarkBig(b, func(z, x, y *big.Int) *big.Int { return z.Add(z.Mod(z.Mul(x, y), m), y) })
+}
@@ -22,8 +22,10 @@ import (
 	"math/big"
 	"math/rand"
 	"reflect"
+	"strings"
 
 	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/crypto/sha3"
 )
 
 const (
@@ -36,6 +38,12 @@ var (
 	addressT = reflect.TypeOf(Address{})
 )
 
+// AddressChecksums controls whether addresses are encoded in their mixed-case
+// checksummed form (ERC-55) and whether the checksums of mixed-case inputs are
+// validated. It can be disabled for compatibility with tools expecting lowercase
+// addresses.
+var AddressChecksums = true
+
 // Hash represents the 32 byte Keccak256 hash of arbitrary data.
 type Hash [HashLength]byte
 
@@ -146,6 +154,26 @@ func StringToAddress(s string) Address { return BytesToAddress([]byte(s)) }
 func BigToAddress(b *big.Int) Address  { return BytesToAddress(b.Bytes()) }
 func HexToAddress(s string) Address    { return BytesToAddress(FromHex(s)) }
 
+// VerifyAddressChecksum checks the ERC-55 checksum of a hex encoded address. All
+// lowercase and all uppercase addresses carry no checksum and are accepted, as
+// is any input if address checksums are disabled.
+func VerifyAddressChecksum(s string) error {
+	if !AddressChecksums {
+		return nil
+	}
+	raw := s
+	if len(raw) >= 2 && raw[0] == '0' && (raw[1] == 'x' || raw[1] == 'X') {
+		raw = raw[2:]
+	}
+	if strings.ToLower(raw) == raw || strings.ToUpper(raw) == raw {
+		return nil
+	}
+	if want := HexToAddress(raw).hex()[2:]; raw != string(want) {
+		return fmt.Errorf("invalid address checksum for %s, expected 0x%s", s, want)
+	}
+	return nil
+}
+
 // IsHexAddress verifies whether a string can represent a valid hex-encoded
 // NetworkChain address or not.
 func IsHexAddress(s string) bool {
@@ -163,13 +191,51 @@ func (a Address) Str() string   { return string(a[:]) }
 func (a Address) Bytes() []byte { return a[:] }
 func (a Address) Big() *big.Int { return new(big.Int).SetBytes(a[:]) }
 func (a Address) Hash() Hash    { return BytesToHash(a[:]) }
-func (a Address) Hex() string   { return hexutil.Encode(a[:]) }
+
+// Hex returns the hex string representation of the address, checksummed as per
+// ERC-55 unless address checksums are disabled.
+func (a Address) Hex() string {
+	return string(a.hex())
+}
+
+// hex returns the 0x prefixed hex encoding of the address, checksummed as per
+// ERC-55 unless address checksums are disabled.
+func (a Address) hex() []byte {
+	buf := make([]byte, 2+2*AddressLength)
+	copy(buf, "0x")
+	hex.Encode(buf[2:], a[:])
+	if !AddressChecksums {
+		return buf
+	}
+	sha := sha3.NewKeccak256()
+	sha.Write(buf[2:])
+	hash := sha.Sum(nil)
+
+	for i := 2; i < len(buf); i++ {
+		nibble := hash[(i-2)/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		} else {
+			nibble &= 0xf
+		}
+		if buf[i] > '9' && nibble > 7 {
+			buf[i] -= 32
+		}
+	}
+	return buf
+}
 
 // String implements the stringer interface and is used also by the logger.
 func (a Address) String() string {
 	return a.Hex()
 }
 
+// TerminalString implements log.TerminalStringer, formatting a string for console
+// output during logging. The address is not checksummed, sparing the hashing.
+func (a Address) TerminalString() string {
+	return hexutil.Encode(a[:])
+}
+
 // Format implements fmt.Formatter, forcing the byte slice to be formatted as is,
 // without going through the stringer interface used for logging.
 func (a Address) Format(s fmt.State, c rune) {
@@ -194,19 +260,28 @@ func (a *Address) Set(other Address) {
 	}
 }
 
-// MarshalText returns the hex representation of a.
+// MarshalText returns the hex representation of a, checksummed as per ERC-55
+// unless address checksums are disabled.
 func (a Address) MarshalText() ([]byte, error) {
-	return hexutil.Bytes(a[:]).MarshalText()
+	return a.hex(), nil
 }
 
-// UnmarshalText parses a hash in hex syntax.
+// UnmarshalText parses an address in hex syntax, validating the checksum of
+// mixed-case inputs.
 func (a *Address) UnmarshalText(input []byte) error {
-	return hexutil.UnmarshalFixedText("Address", input, a[:])
+	if err := hexutil.UnmarshalFixedText("Address", input, a[:]); err != nil {
+		return err
+	}
+	return VerifyAddressChecksum(string(input))
 }
 
-// UnmarshalJSON parses a hash in hex syntax.
+// UnmarshalJSON parses an address in hex syntax, validating the checksum of
+// mixed-case inputs.
 func (a *Address) UnmarshalJSON(input []byte) error {
-	return hexutil.UnmarshalFixedJSON(addressT, input, a[:])
+	if err := hexutil.UnmarshalFixedJSON(addressT, input, a[:]); err != nil {
+		return err
+	}
+	return VerifyAddressChecksum(string(input[1 : len(input)-1]))
 }
 
 // UnprefixedHash allows marshaling an Address without 0x prefix.
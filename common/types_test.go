@@ -94,3 +94,65 @@ func TestAddressUnmarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestAddressHexChecksum(t *testing.T) {
+	var tests = []struct {
+		Input  string
+		Output string
+	}{
+		// Test cases from https://github.com/ethereum/EIPs/blob/master/EIPS/eip-55.md#specification
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{"0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+		{"0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb", "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"},
+		{"0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb", "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb"},
+	}
+	for i, test := range tests {
+		output := HexToAddress(test.Input).Hex()
+		if output != test.Output {
+			t.Errorf("test #%d: failed to match when it should (%s != %s)", i, output, test.Output)
+		}
+		if err := VerifyAddressChecksum(test.Output); err != nil {
+			t.Errorf("test #%d: valid checksum rejected: %v", i, err)
+		}
+	}
+}
+
+func TestAddressChecksumValidation(t *testing.T) {
+	var tests = []struct {
+		Input     string
+		ShouldErr bool
+	}{
+		{`"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"`, false},
+		{`"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"`, false},
+		{`"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED"`, false},
+		{`"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"`, true},
+	}
+	for i, test := range tests {
+		var v Address
+		err := json.Unmarshal([]byte(test.Input), &v)
+		if err != nil && !test.ShouldErr {
+			t.Errorf("test #%d: unexpected error: %v", i, err)
+		}
+		if err == nil && test.ShouldErr {
+			t.Errorf("test #%d: expected error, got none", i)
+		}
+	}
+	// Ensure that disabling the checksums accepts anything and emits lowercase
+	AddressChecksums = false
+	defer func() { AddressChecksums = true }()
+
+	var v Address
+	if err := json.Unmarshal([]byte(tests[3].Input), &v); err != nil {
+		t.Errorf("invalid checksum rejected in compatibility mode: %v", err)
+	}
+	if hex := v.Hex(); hex != strings.ToLower(hex) {
+		t.Errorf("checksummed output in compatibility mode: %s", hex)
+	}
+}
+
+func TestAddressTerminalString(t *testing.T) {
+	addr := HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	if have, want := addr.TerminalString(), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"; have != want {
+		t.Errorf("terminal string mismatch: have %s, want %s", have, want)
+	}
+}
@@ -117,3 +117,29 @@ func (api *API) Discard(address common.Address) {
 
 	delete(api.clique.proposals, address)
 }
+
+// SnapshotAPI is the read only part of API, inspecting the authorized signers
+// without the voting controls.
+type SnapshotAPI struct {
+	api *API
+}
+
+// GetSnapshot retrieves the state snapshot at a given block.
+func (api *SnapshotAPI) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	return api.api.GetSnapshot(number)
+}
+
+// GetSnapshotAtHash retrieves the state snapshot at a given block.
+func (api *SnapshotAPI) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
+	return api.api.GetSnapshotAtHash(hash)
+}
+
+// GetSigners retrieves the list of authorized signers at the specified block.
+func (api *SnapshotAPI) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
+	return api.api.GetSigners(number)
+}
+
+// GetSignersAtHash retrieves the state snapshot at a given block.
+func (api *SnapshotAPI) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
+	return api.api.GetSignersAtHash(hash)
+}
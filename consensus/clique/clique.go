@@ -654,3 +654,15 @@ func (c *Clique) APIs(chain consensus.ChainReader) []rpc.API {
 		Public:    false,
 	}}
 }
+
+// SnapshotAPIs returns the read only part of the RPC API, inspecting the signers
+// without the voting controls. It is meant for nodes that don't seal blocks, and
+// so would never cast the votes of their proposals.
+func (c *Clique) SnapshotAPIs(chain consensus.ChainReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "clique",
+		Version:   "1.0",
+		Service:   &SnapshotAPI{api: &API{chain: chain, clique: c}},
+		Public:    false,
+	}}
+}
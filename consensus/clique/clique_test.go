@@ -0,0 +1,158 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/light"
+	"github.com/networkchain/networkchain/params"
+)
+
+// noOdr is a light client backend without any network access, sufficient for
+// importing headers.
+type noOdr struct{ db ethdb.Database }
+
+func (odr *noOdr) Database() ethdb.Database { return odr.db }
+func (odr *noOdr) Retrieve(ctx context.Context, req light.OdrRequest) error {
+	return errors.New("no peers")
+}
+
+// Tests that a proof-of-authority chain spanning multiple epochs can be produced
+// by the engine and imported both by full and light chains, while blocks signed
+// by unauthorized keys are rejected.
+func TestImportChain(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.AllProtocolChanges
+	config.Ethash = nil
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 3}
+
+	genspec := &core.Genesis{
+		Config:    &config,
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genspec.ExtraData[extraVanity:], signer[:])
+
+	// Produce a chain with the engine, importing each block to build the next
+	db, _ := ethdb.NewMemDatabase()
+	genspec.MustCommit(db)
+
+	engine := New(config.Clique, db)
+	engine.Authorize(signer, nil)
+
+	chain, err := core.NewBlockChain(db, &config, engine, new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create producer chain: %v", err)
+	}
+	var blocks []*types.Block
+	for i := 0; i < 7; i++ {
+		block := makeBlock(t, chain, engine, db)
+		block = block.WithSeal(sealBlock(block.Header(), key))
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert into producer chain: %v", i+1, err)
+		}
+		blocks = append(blocks, block)
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	// Import the chain into a fresh full and light chain
+	fulldb, _ := ethdb.NewMemDatabase()
+	genspec.MustCommit(fulldb)
+	full, err := core.NewBlockChain(fulldb, &config, New(config.Clique, fulldb), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create full chain: %v", err)
+	}
+	if _, err := full.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import full chain: %v", err)
+	}
+	if head := full.CurrentBlock().NumberU64(); head != uint64(len(blocks)) {
+		t.Errorf("full chain head mismatch: have %d, want %d", head, len(blocks))
+	}
+	lightdb, _ := ethdb.NewMemDatabase()
+	genspec.MustCommit(lightdb)
+	lightchain, err := light.NewLightChain(&noOdr{lightdb}, &config, New(config.Clique, lightdb), new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create light chain: %v", err)
+	}
+	if _, err := lightchain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to import light chain: %v", err)
+	}
+	if head := lightchain.CurrentHeader().Number.Uint64(); head != uint64(len(headers)) {
+		t.Errorf("light chain head mismatch: have %d, want %d", head, len(headers))
+	}
+	// Ensure a block signed by an unauthorized key is rejected by both
+	forger, _ := crypto.GenerateKey()
+
+	block := makeBlock(t, chain, engine, db)
+	block = block.WithSeal(sealBlock(block.Header(), forger))
+
+	if _, err := full.InsertChain(types.Blocks{block}); err != errUnauthorized {
+		t.Errorf("full chain forged block error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+	if _, err := lightchain.InsertHeaderChain([]*types.Header{block.Header()}, 1); err != errUnauthorized {
+		t.Errorf("light chain forged header error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+}
+
+// makeBlock assembles an empty, unsealed block on top of the head of the chain.
+func makeBlock(t *testing.T, chain *core.BlockChain, engine *Clique, db ethdb.Database) *types.Block {
+	parent := chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent),
+		GasUsed:    new(big.Int),
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	// Keep the timestamps in the past to avoid future block rejections
+	header.Time = new(big.Int).Add(parent.Time(), common.Big1)
+
+	statedb, err := state.New(parent.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open parent state: %v", err)
+	}
+	block, err := engine.Finalize(chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	return block
+}
+
+// sealBlock signs the header with the given key, returning the sealed header.
+func sealBlock(header *types.Header, key *ecdsa.PrivateKey) *types.Header {
+	sig, _ := crypto.Sign(sigHash(header).Bytes(), key)
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return header
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package remote implements a consensus engine delegating header verification
+// and block sealing to an external process over RPC.
+//
+// The external sealer needs to expose the following methods in the "sealer"
+// namespace (over IPC, HTTP or WebSocket):
+//
+//	sealer_verifyHeader(header, parent, seal) error
+//	sealer_verifySeal(header) error
+//	sealer_seal(header) header
+//
+// Everything requiring access to the local state (block preparation, uncle
+// verification, finalization) is still executed by a local base engine.
+package remote
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus"
+	"github.com/networkchain/networkchain/consensus/clique"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+var (
+	// errUnknownBlock is returned when the list of headers to verify contains a
+	// header without a block number.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidSeal is returned if the external sealer returns a sealed header
+	// differing from the one it was asked to seal in anything but the seal.
+	errInvalidSeal = errors.New("sealed header mismatch")
+)
+
+// cliqueExtraSeal is the number of extra-data suffix bytes clique reserves for
+// the signer seal.
+const cliqueExtraSeal = 65
+
+// Remote is a consensus engine delegating header verification and sealing to
+// an external process, while running everything else through a base engine.
+type Remote struct {
+	base     consensus.Engine // Local engine running the state dependent operations
+	endpoint string           // RPC endpoint of the external sealer
+
+	dial   func(ctx context.Context) (*rpc.Client, error) // Connects to the external sealer
+	client *rpc.Client                                    // Live connection to the external sealer (nil if not connected)
+	lock   sync.Mutex                                     // Protects the client connection
+}
+
+// New creates a consensus engine delegating to the external sealer reachable at
+// the given endpoint. The connection is established lazily and is re-established
+// on failure, so the external process may be (re)started at any time.
+func New(base consensus.Engine, endpoint string) *Remote {
+	return &Remote{
+		base:     base,
+		endpoint: endpoint,
+		dial: func(ctx context.Context) (*rpc.Client, error) {
+			return rpc.DialContext(ctx, endpoint)
+		},
+	}
+}
+
+// call invokes a method of the external sealer, connecting to it if necessary.
+// Any transport failure drops the connection so the next call redials.
+func (r *Remote) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	r.lock.Lock()
+	client := r.client
+	if client == nil {
+		var err error
+		if client, err = r.dial(ctx); err != nil {
+			r.lock.Unlock()
+			log.Warn("Failed to connect to remote sealer", "endpoint", r.endpoint, "err", err)
+			return err
+		}
+		r.client = client
+	}
+	r.lock.Unlock()
+
+	err := client.CallContext(ctx, result, method, args...)
+	if _, ok := err.(rpc.Error); err != nil && !ok && err != ctx.Err() {
+		r.lock.Lock()
+		if r.client == client {
+			r.client.Close()
+			r.client = nil
+		}
+		r.lock.Unlock()
+		log.Warn("Remote sealer call failed", "endpoint", r.endpoint, "method", method, "err", err)
+	}
+	return err
+}
+
+// Author implements consensus.Engine, returning the author as defined by the
+// base engine.
+func (r *Remote) Author(header *types.Header) (common.Address, error) {
+	return r.base.Author(header)
+}
+
+// VerifyHeader implements consensus.Engine, delegating the verification of the
+// header along with its parent to the external sealer.
+func (r *Remote) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	return r.verifyHeader(chain, header, nil, seal)
+}
+
+// VerifyHeaders implements consensus.Engine, verifying a batch of headers one by
+// one against the external sealer. The method returns a quit channel to abort
+// the operations and a results channel to retrieve the async verifications.
+func (r *Remote) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := r.verifyHeader(chain, header, headers[:i], seals[i])
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader looks up the parent of a header (from the batch of preceding
+// headers if available, otherwise from the database) and delegates the header
+// verification to the external sealer.
+func (r *Remote) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header, seal bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else if number > 0 {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if number > 0 && (parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash) {
+		return consensus.ErrUnknownAncestor
+	}
+	return r.call(context.Background(), nil, "sealer_verifyHeader", header, parent, seal)
+}
+
+// VerifyUncles implements consensus.Engine, delegating to the base engine.
+func (r *Remote) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	return r.base.VerifyUncles(chain, block)
+}
+
+// VerifySeal implements consensus.Engine, delegating the seal verification to
+// the external sealer.
+func (r *Remote) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return r.call(context.Background(), nil, "sealer_verifySeal", header)
+}
+
+// Prepare implements consensus.Engine, delegating to the base engine.
+func (r *Remote) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	return r.base.Prepare(chain, header)
+}
+
+// Finalize implements consensus.Engine, delegating to the base engine.
+func (r *Remote) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	return r.base.Finalize(chain, header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine, asking the external sealer to seal the header
+// of the block, and assembling the final block from the returned header.
+func (r *Remote) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	sealed := new(types.Header)
+	if err := r.call(ctx, sealed, "sealer_seal", block.Header()); err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	header, err := r.sealHeader(block.Header(), sealed)
+	if err != nil {
+		return nil, err
+	}
+	return block.WithSeal(header), nil
+}
+
+// sealHeader copies the seal of a header returned by the external sealer onto the
+// header sent to it. The sealed block is written and broadcast without being
+// validated again, so the sealer may not change anything but the seal: the nonce
+// and mix digest, and for clique the signature at the end of the extra-data.
+func (r *Remote) sealHeader(header, sealed *types.Header) (*types.Header, error) {
+	header.Nonce, header.MixDigest = sealed.Nonce, sealed.MixDigest
+	if _, ok := r.base.(*clique.Clique); ok && len(header.Extra) >= cliqueExtraSeal && len(sealed.Extra) == len(header.Extra) {
+		extra := common.CopyBytes(header.Extra)
+		copy(extra[len(extra)-cliqueExtraSeal:], sealed.Extra[len(extra)-cliqueExtraSeal:])
+		header.Extra = extra
+	}
+	if header.Hash() != sealed.Hash() {
+		return nil, errInvalidSeal
+	}
+	return header, nil
+}
+
+// APIs implements consensus.Engine, returning the APIs of the base engine.
+func (r *Remote) APIs(chain consensus.ChainReader) []rpc.API {
+	return r.base.APIs(chain)
+}
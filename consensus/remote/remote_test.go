@@ -0,0 +1,228 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus"
+	"github.com/networkchain/networkchain/consensus/clique"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// TestSealer is an external sealer accepting headers with non-zero difficulty
+// and sealing blocks with a fixed nonce.
+type TestSealer struct{}
+
+func (s *TestSealer) VerifyHeader(header *types.Header, parent *types.Header, seal bool) error {
+	if header.Difficulty.Sign() == 0 {
+		return errors.New("zero difficulty")
+	}
+	return nil
+}
+
+func (s *TestSealer) VerifySeal(header *types.Header) error {
+	if header.Nonce.Uint64() != 42 {
+		return errors.New("invalid nonce")
+	}
+	return nil
+}
+
+func (s *TestSealer) Seal(header *types.Header) *types.Header {
+	header.Nonce = types.EncodeNonce(42)
+	return header
+}
+
+// testChain is a chain reader serving headers from a fixed set.
+type testChain struct {
+	consensus.ChainReader
+	headers map[common.Hash]*types.Header
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+// testHeader creates a header with all the fields required for RPC encoding set.
+func testHeader(number, difficulty int64, parent common.Hash) *types.Header {
+	return &types.Header{
+		ParentHash: parent,
+		Number:     big.NewInt(number),
+		Difficulty: big.NewInt(difficulty),
+		GasLimit:   big.NewInt(0),
+		GasUsed:    big.NewInt(0),
+		Time:       big.NewInt(0),
+	}
+}
+
+// TamperingSealer is an external sealer sealing blocks with a fixed nonce, while
+// also modifying other fields of the header.
+type TamperingSealer struct {
+	tamper func(header *types.Header)
+}
+
+func (s *TamperingSealer) Seal(header *types.Header) *types.Header {
+	header.Nonce = types.EncodeNonce(42)
+	s.tamper(header)
+	return header
+}
+
+// newTestRemote creates a remote engine connected to an in-process test sealer.
+func newTestRemote(t *testing.T) *Remote {
+	return newTestRemoteWith(t, ethash.NewFaker(), new(TestSealer))
+}
+
+// newTestRemoteWith creates a remote engine on top of the given base engine,
+// connected to the given in-process sealer.
+func newTestRemoteWith(t *testing.T, base consensus.Engine, sealer interface{}) *Remote {
+	server := rpc.NewServer()
+	if err := server.RegisterName("sealer", sealer); err != nil {
+		t.Fatalf("failed to register test sealer: %v", err)
+	}
+	engine := New(base, "")
+	engine.dial = func(ctx context.Context) (*rpc.Client, error) {
+		return rpc.DialInProc(server), nil
+	}
+	return engine
+}
+
+// Tests that header verification is delegated to the external sealer, with the
+// parents looked up from the batch or the local chain.
+func TestRemoteVerifyHeaders(t *testing.T) {
+	engine := newTestRemote(t)
+
+	genesis := testHeader(0, 1, common.Hash{})
+	valid := testHeader(1, 1, genesis.Hash())
+	invalid := testHeader(2, 0, valid.Hash())
+	orphan := testHeader(2, 1, common.Hash{0x01})
+
+	chain := &testChain{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}}
+
+	headers := []*types.Header{valid, invalid}
+	_, results := engine.VerifyHeaders(chain, headers, []bool{true, true})
+	if err := <-results; err != nil {
+		t.Errorf("valid header rejected: %v", err)
+	}
+	if err := <-results; err == nil {
+		t.Errorf("invalid header accepted")
+	}
+	if err := engine.VerifyHeader(chain, orphan, true); err != consensus.ErrUnknownAncestor {
+		t.Errorf("orphan header error mismatch: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+}
+
+// Tests that sealing is delegated to the external sealer, and that the sealed
+// block passes the external seal verification.
+func TestRemoteSeal(t *testing.T) {
+	engine := newTestRemote(t)
+
+	block := types.NewBlockWithHeader(testHeader(1, 1, common.Hash{}))
+	sealed, err := engine.Seal(nil, block, make(chan struct{}))
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	if sealed.Nonce() != 42 {
+		t.Errorf("sealed nonce mismatch: have %d, want %d", sealed.Nonce(), 42)
+	}
+	if err := engine.VerifySeal(nil, sealed.Header()); err != nil {
+		t.Errorf("sealed block rejected: %v", err)
+	}
+	if err := engine.VerifySeal(nil, block.Header()); err == nil {
+		t.Errorf("unsealed block accepted")
+	}
+}
+
+// Tests that a sealed header differing from the one sent to the external sealer
+// in anything but the seal is rejected.
+func TestRemoteSealTampering(t *testing.T) {
+	tests := []struct {
+		field  string
+		tamper func(header *types.Header)
+	}{
+		{"ReceiptHash", func(header *types.Header) { header.ReceiptHash = common.Hash{0x01} }},
+		{"Bloom", func(header *types.Header) { header.Bloom[0] = 0x01 }},
+		{"GasUsed", func(header *types.Header) { header.GasUsed = big.NewInt(1) }},
+		{"Coinbase", func(header *types.Header) { header.Coinbase = common.Address{0x01} }},
+		{"Time", func(header *types.Header) { header.Time = big.NewInt(1) }},
+		{"Extra", func(header *types.Header) { header.Extra = []byte{0x01} }},
+		{"Difficulty", func(header *types.Header) { header.Difficulty = big.NewInt(2) }},
+		{"GasLimit", func(header *types.Header) { header.GasLimit = big.NewInt(1) }},
+		{"Number", func(header *types.Header) { header.Number = big.NewInt(2) }},
+		{"Root", func(header *types.Header) { header.Root = common.Hash{0x01} }},
+	}
+	for _, tt := range tests {
+		engine := newTestRemoteWith(t, ethash.NewFaker(), &TamperingSealer{tamper: tt.tamper})
+
+		block := types.NewBlockWithHeader(testHeader(1, 1, common.Hash{}))
+		if _, err := engine.Seal(nil, block, make(chan struct{})); err != errInvalidSeal {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.field, err, errInvalidSeal)
+		}
+	}
+}
+
+// Tests that on top of clique, the external sealer may only fill in the signer
+// seal at the end of the extra-data.
+func TestRemoteSealClique(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	base := clique.New(&params.CliqueConfig{Period: 1, Epoch: 30000}, db)
+
+	tests := []struct {
+		offset int // Offset of the byte set by the sealer in the extra-data
+		valid  bool
+	}{
+		{extraVanity, true},
+		{len(testCliqueExtra) - 1, true},
+		{0, false},
+		{extraVanity - 1, false},
+	}
+	for i, tt := range tests {
+		offset := tt.offset
+		engine := newTestRemoteWith(t, base, &TamperingSealer{tamper: func(header *types.Header) {
+			header.Extra[offset] = 0xff
+		}})
+		header := testHeader(1, 1, common.Hash{})
+		header.Extra = testCliqueExtra
+
+		sealed, err := engine.Seal(nil, types.NewBlockWithHeader(header), make(chan struct{}))
+		if !tt.valid {
+			if err != errInvalidSeal {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, errInvalidSeal)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to seal block: %v", i, err)
+			continue
+		}
+		if sealed.Extra()[offset] != 0xff {
+			t.Errorf("test %d: seal not copied", i)
+		}
+	}
+}
+
+const extraVanity = 32 // Number of extra-data prefix bytes clique reserves for the signer vanity
+
+// testCliqueExtra is an extra-data of a clique header with an empty signer seal.
+var testCliqueExtra = make([]byte, extraVanity+cliqueExtraSeal)
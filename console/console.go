@@ -57,6 +57,17 @@ type Config struct {
 	Prompter UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
 	Printer  io.Writer    // Output writer to serialize any display strings to (defaults to os.Stdout)
 	Preload  []string     // Absolute paths to JavaScript files to preload
+	Argv     []string     // Command line arguments to expose to scripts via process.argv
+}
+
+// ExitError is returned by Execute if the script terminated itself by calling
+// process.exit.
+type ExitError struct {
+	Code int // Exit code requested by the script
+}
+
+func (err *ExitError) Error() string {
+	return fmt.Sprintf("script exited with code %d", err.Code)
 }
 
 // Console is a JavaScript interpreted runtime environment. It is a fully fleged
@@ -70,6 +81,7 @@ type Console struct {
 	histPath string       // Absolute path to the console scrollback history
 	history  []string     // Scroll history maintained by the console
 	printer  io.Writer    // Output writer to serialize any display strings to
+	exitCode *int         // Exit code requested by a script via process.exit
 }
 
 func New(config Config) (*Console, error) {
@@ -92,7 +104,7 @@ func New(config Config) (*Console, error) {
 		printer:  config.Printer,
 		histPath: filepath.Join(config.DataDir, HistoryFile),
 	}
-	if err := console.init(config.Preload); err != nil {
+	if err := console.init(config.Preload, config.Argv); err != nil {
 		return nil, err
 	}
 	return console, nil
@@ -100,7 +112,7 @@ func New(config Config) (*Console, error) {
 
 // init retrieves the available APIs from the remote RPC provider and initializes
 // the console's JavaScript namespaces based on the exposed modules.
-func (c *Console) init(preload []string) error {
+func (c *Console) init(preload []string, argv []string) error {
 	// Initialize the JavaScript <-> Go RPC bridge
 	bridge := newBridge(c.client, c.prompter, c.printer)
 	c.jsre.Set("jeth", struct{}{})
@@ -113,6 +125,16 @@ func (c *Console) init(preload []string) error {
 	consoleObj.Object().Set("log", c.consoleOutput)
 	consoleObj.Object().Set("error", c.consoleOutput)
 
+	// Expose the script arguments and a way to terminate with an exit code
+	if argv == nil {
+		argv = []string{}
+	}
+	c.jsre.Set("process", struct{}{})
+
+	processObj, _ := c.jsre.Get("process")
+	processObj.Object().Set("argv", argv)
+	processObj.Object().Set("exit", c.exit)
+
 	// Load all the internal utility JavaScript libraries
 	if err := c.jsre.Compile("bignumber.js", jsre.BigNumber_JS); err != nil {
 		return fmt.Errorf("bignumber.js: %v", err)
@@ -150,6 +172,13 @@ func (c *Console) init(preload []string) error {
 	if _, err = c.jsre.Run(flatten); err != nil {
 		return fmt.Errorf("namespace flattening: %v", err)
 	}
+	// Bind the server methods missing from the JavaScript extensions, so that the
+	// console and its autocompletion reflect the actual API of the remote node
+	if methods, err := c.client.SupportedMethods(); err == nil {
+		if _, err = c.jsre.Run(methodBindings(apis, methods)); err != nil {
+			return fmt.Errorf("method bindings: %v", err)
+		}
+	}
 	// Initialize the global name register (disabled for now)
 	//c.jsre.Run(`var GlobalRegistrar = eth.contract(` + registrar.GlobalRegistrarAbi + `);   registrar = GlobalRegistrar.at("` + registrar.GlobalRegistrarAddr + `");`)
 
@@ -210,6 +239,50 @@ func (c *Console) init(preload []string) error {
 	return nil
 }
 
+// methodBindings generates the JavaScript code binding the RPC methods exposed by
+// the server but not present in the console's web3 extensions. The bindings issue
+// the requests verbatim and display the argument types of the methods.
+func methodBindings(apis map[string]string, methods map[string]map[string][]string) string {
+	code := `jeth.bindMethod = function(name, signature) {
+		var method = function() {
+			return web3._requestManager.send({method: name, params: Array.prototype.slice.call(arguments)});
+		};
+		method.toString = function() { return signature; };
+		return method;
+	};
+	`
+	modules := make([]string, 0, len(methods))
+	for module := range methods {
+		if _, ok := apis[module]; ok && module != "web3" {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		// Modules of networks hosted in-process (e.g. "testnet.eth") are nested
+		// into an object of the network
+		parts := strings.Split(module, ".")
+		for i := 1; i < len(parts); i++ {
+			parent := strings.Join(parts[:i], ".")
+			code += fmt.Sprintf("if (typeof web3.%s === 'undefined') { web3.%s = {}; }\n", parent, parent)
+		}
+		code += fmt.Sprintf("if (typeof web3.%s === 'undefined') { web3.%s = {}; } var %s = web3.%s;\n", module, module, parts[0], parts[0])
+
+		names := make([]string, 0, len(methods[module]))
+		for name := range methods[module] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			signature := fmt.Sprintf("function(%s)", strings.Join(methods[module][name], ", "))
+			code += fmt.Sprintf("if (!('%s' in %s)) { %s.%s = jeth.bindMethod('%s_%s', %q); }\n", name, module, module, name, module, name, signature)
+		}
+	}
+	return code
+}
+
 // consoleOutput is an override for the console.log and console.error methods to
 // stream the output into the configured output stream instead of stdout.
 func (c *Console) consoleOutput(call otto.FunctionCall) otto.Value {
@@ -221,6 +294,16 @@ func (c *Console) consoleOutput(call otto.FunctionCall) otto.Value {
 	return otto.Value{}
 }
 
+// exit is the implementation of process.exit, recording the requested exit code
+// and aborting the running script.
+func (c *Console) exit(call otto.FunctionCall) otto.Value {
+	code, _ := call.Argument(0).ToInteger()
+	exitCode := int(code)
+	c.exitCode = &exitCode
+
+	panic(call.Otto.MakeCustomError("ExitError", fmt.Sprintf("exit code %d", exitCode)))
+}
+
 // AutoCompleteInput is a pre-assembled word completer to be used by the user
 // input prompter to provide hints to the user about the methods available.
 func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, string) {
@@ -401,9 +484,14 @@ func countIndents(input string) int {
 	return indents
 }
 
-// Execute runs the JavaScript file specified as the argument.
+// Execute runs the JavaScript file specified as the argument. If the script
+// terminates itself via process.exit, an *ExitError is returned.
 func (c *Console) Execute(path string) error {
-	return c.jsre.Exec(path)
+	err := c.jsre.Exec(path)
+	if c.exitCode != nil {
+		return &ExitError{Code: *c.exitCode}
+	}
+	return err
 }
 
 // Stop cleans up the console and terminates the runtime envorinment.
@@ -169,7 +169,7 @@ func TestWelcome(t *testing.T) {
 	if want := fmt.Sprintf("instance: %s", testInstance); !strings.Contains(output, want) {
 		t.Fatalf("console output missing instance: have\n%s\nwant also %s", output, want)
 	}
-	if want := fmt.Sprintf("coinbase: %s", testAddress); !strings.Contains(output, want) {
+	if want := fmt.Sprintf("coinbase: %s", common.HexToAddress(testAddress).Hex()); !strings.Contains(output, want) {
 		t.Fatalf("console output missing coinbase: have\n%s\nwant also %s", output, want)
 	}
 	if want := "at block: 0"; !strings.Contains(output, want) {
@@ -246,6 +246,21 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+// Tests that scripts can terminate themselves with an explicit exit code.
+func TestExecuteExit(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	err := tester.console.Execute("exit.js")
+	if exit, ok := err.(*ExitError); !ok || exit.Code != 3 {
+		t.Fatalf("exit error mismatch: have %v, want exit code 3", err)
+	}
+	tester.console.Evaluate("typeof unreachable")
+	if output := string(tester.output.Bytes()); !strings.Contains(output, "undefined") {
+		t.Fatalf("script continued after exit: have %s", output)
+	}
+}
+
 // Tests that the JavaScript objects returned by statement executions are properly
 // pretty printed instead of just displaing "[object]".
 func TestPrettyPrint(t *testing.T) {
@@ -335,3 +350,33 @@ func TestIndenting(t *testing.T) {
 		}
 	}
 }
+
+// Tests that server methods without a web3 extension get bound into the console
+// namespaces along with their argument types.
+func TestMethodBindings(t *testing.T) {
+	apis := map[string]string{"eth": "1.0", "foo": "1.0", "testnet.eth": "1.0"}
+	methods := map[string]map[string][]string{
+		"eth":         {"callBundle": {"[]ethapi.CallArgs", "rpc.BlockNumber"}},
+		"foo":         {"bar": {}},
+		"baz":         {"qux": {"string"}},
+		"testnet.eth": {"blockNumber": {}},
+	}
+	code := methodBindings(apis, methods)
+
+	for _, want := range []string{
+		`var eth = web3.eth;`,
+		`if (!('callBundle' in eth)) { eth.callBundle = jeth.bindMethod('eth_callBundle', "function([]ethapi.CallArgs, rpc.BlockNumber)"); }`,
+		`if (typeof web3.foo === 'undefined') { web3.foo = {}; } var foo = web3.foo;`,
+		`foo.bar = jeth.bindMethod('foo_bar', "function()");`,
+		`if (typeof web3.testnet === 'undefined') { web3.testnet = {}; }`,
+		`if (typeof web3.testnet.eth === 'undefined') { web3.testnet.eth = {}; } var testnet = web3.testnet;`,
+		`testnet.eth.blockNumber = jeth.bindMethod('testnet.eth_blockNumber', "function()");`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("binding missing: %s", want)
+		}
+	}
+	if strings.Contains(code, "baz") {
+		t.Errorf("bound method of module not advertised by rpc_modules")
+	}
+}
@@ -0,0 +1,3 @@
+var exitCode = process.argv.length + 3;
+process.exit(exitCode);
+var unreachable = true;
@@ -0,0 +1,67 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/state"
+)
+
+// AccessStats summarises the dependencies between the transactions of a block,
+// as derived from the state read and written by each of them. A transaction
+// depends on an earlier one if it reads or overwrites state the earlier one
+// modified, in which case the two could not have been executed in parallel.
+type AccessStats struct {
+	Number       uint64 `json:"number"`       // Number of the block
+	Txs          int    `json:"txs"`          // Number of transactions in the block
+	Reads        int    `json:"reads"`        // Total accounts and slots read by the transactions
+	Writes       int    `json:"writes"`       // Total accounts and slots written by the transactions
+	Conflicting  int    `json:"conflicting"`  // Number of transactions depending on an earlier one
+	Conflicts    int    `json:"conflicts"`    // Number of dependent transaction pairs
+	CriticalPath int    `json:"criticalPath"` // Length of the longest chain of dependent transactions
+}
+
+// ComputeAccessStats derives the conflict statistics of a block from the access
+// sets recorded during its processing. The given account (usually the coinbase,
+// which is credited by every transaction) is disregarded during comparison.
+func ComputeAccessStats(number uint64, accesses []*state.TxAccess, ignore common.Address) *AccessStats {
+	stats := &AccessStats{Number: number, Txs: len(accesses)}
+
+	depth := make([]int, len(accesses))
+	for i, access := range accesses {
+		stats.Reads += access.Reads.Len()
+		stats.Writes += access.Writes.Len()
+
+		depth[i] = 1
+		for j := 0; j < i; j++ {
+			if !access.DependsOn(accesses[j], ignore) {
+				continue
+			}
+			stats.Conflicts++
+			if depth[j]+1 > depth[i] {
+				depth[i] = depth[j] + 1
+			}
+		}
+		if depth[i] > 1 {
+			stats.Conflicting++
+		}
+		if depth[i] > stats.CriticalPath {
+			stats.CriticalPath = depth[i]
+		}
+	}
+	return stats
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/ethdb"
+)
+
+// Tests that state accesses are recorded per transaction and that dependencies
+// between them are correctly detected.
+func TestAccessStats(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.EnableAccessTracking()
+
+	var (
+		coinbase = common.Address{0xff}
+		alice    = common.Address{0x01}
+		bob      = common.Address{0x02}
+		carol    = common.Address{0x03}
+		contract = common.Address{0x04}
+		slot     = common.Hash{0x01}
+	)
+	// tx0: alice pays bob
+	statedb.Prepare(common.Hash{0}, common.Hash{}, 0)
+	statedb.GetBalance(alice)
+	statedb.SubBalance(alice, big.NewInt(1))
+	statedb.AddBalance(bob, big.NewInt(1))
+	statedb.AddBalance(coinbase, big.NewInt(1))
+
+	// tx1: carol writes a contract slot, independent of tx0 except for the coinbase
+	statedb.Prepare(common.Hash{1}, common.Hash{}, 1)
+	statedb.GetState(contract, slot)
+	statedb.SetState(contract, slot, common.Hash{0x01})
+	statedb.SetNonce(carol, 1)
+	statedb.AddBalance(coinbase, big.NewInt(1))
+
+	// tx2: reads the slot written by tx1 and the balance written by tx0
+	statedb.Prepare(common.Hash{2}, common.Hash{}, 2)
+	statedb.GetState(contract, slot)
+	statedb.GetBalance(bob)
+
+	// Block rewards must not be attributed to the last transaction
+	statedb.StopAccessTracking()
+	statedb.AddBalance(carol, big.NewInt(1))
+
+	accesses := statedb.AccessSets()
+	if len(accesses) != 3 {
+		t.Fatalf("access set count mismatch: have %d, want 3", len(accesses))
+	}
+	if _, ok := accesses[2].Writes.Accounts[carol]; ok {
+		t.Errorf("state access recorded after tracking stopped")
+	}
+	stats := ComputeAccessStats(1, accesses, coinbase)
+	want := AccessStats{Number: 1, Txs: 3, Reads: 4, Writes: 6, Conflicting: 1, Conflicts: 2, CriticalPath: 2}
+	if *stats != want {
+		t.Errorf("stats mismatch: have %+v, want %+v", *stats, want)
+	}
+	// Without ignoring the coinbase, the first two transactions conflict too
+	stats = ComputeAccessStats(1, accesses, common.Address{})
+	if stats.Conflicting != 2 || stats.Conflicts != 3 || stats.CriticalPath != 3 {
+		t.Errorf("coinbase conflicts mismatch: have %+v", *stats)
+	}
+}
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -32,15 +33,16 @@ import (
 	"github.com/networkchain/networkchain/common/mclock"
 	"github.com/networkchain/networkchain/consensus"
 	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/state/snapshot"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/core/vm"
-	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/metrics"
 	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/tracing"
 	"github.com/networkchain/networkchain/trie"
 	"github.com/hashicorp/golang-lru"
 )
@@ -48,6 +50,10 @@ import (
 var (
 	blockInsertTimer = metrics.NewTimer("chain/inserts")
 
+	accessTxMeter          = metrics.NewMeter("chain/access/txs")
+	accessConflictingMeter = metrics.NewMeter("chain/access/conflicting")
+	accessCriticalMeter    = metrics.NewMeter("chain/access/critical")
+
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 )
 
@@ -57,9 +63,18 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
+	accessStatsLimit    = 256
+	witnessCacheLimit   = 32
+	snapshotLayers      = 128 // Number of recent blocks whose state snapshot layers are kept in memory
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
-	BlockChainVersion = 3
+	BlockChainVersion = 4
+
+	// SeparateTdBlockChainVersion is the last database version storing headers and
+	// their total difficulties separately. Such databases are still read as is and
+	// are upgraded in place, but the upgrade is one-way: headers written since are
+	// stored in the compact header table, which earlier releases can't read.
+	SeparateTdBlockChainVersion = 3
 )
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -93,6 +108,8 @@ type BlockChain struct {
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	stateGC      *stateGC       // In-memory buffer garbage collecting old states, nil if archiving
+	snaps        *snapshot.Tree // State snapshot serving account and storage reads, nil if disabled
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -109,7 +126,11 @@ type BlockChain struct {
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
-	badBlocks *lru.Cache // Bad block cache
+	logIndex bool // Whether to maintain the log index of contract events
+
+	badBlocks   *lru.Cache // Bad block cache
+	accessStats *lru.Cache // Transaction conflict statistics of recently processed blocks
+	witnesses   *lru.Cache // Execution witnesses of recently processed blocks
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -121,6 +142,8 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
+	accessStats, _ := lru.New(accessStatsLimit)
+	witnesses, _ := lru.New(witnessCacheLimit)
 
 	bc := &BlockChain{
 		config:       config,
@@ -135,6 +158,8 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 		engine:       engine,
 		vmConfig:     vmConfig,
 		badBlocks:    badBlocks,
+		accessStats:  accessStats,
+		witnesses:    witnesses,
 	}
 	bc.SetValidator(NewBlockValidator(config, bc, engine))
 	bc.SetProcessor(NewStateProcessor(config, bc, engine))
@@ -192,21 +217,29 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		// Dangling block without a state associated, rewind to the last one with state
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if currentBlock = bc.repair(currentBlock); currentBlock == nil {
+			return bc.Reset()
+		}
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
 
-	// Restore the last known head header
+	// Restore the last known head header, only rewriting the marker if it's stale
+	// so that read-only databases can be loaded too
 	currentHeader := bc.currentBlock.Header()
-	if head := GetHeadHeaderHash(bc.chainDb); head != (common.Hash{}) {
-		if header := bc.GetHeaderByHash(head); header != nil {
+	headHeader := GetHeadHeaderHash(bc.chainDb)
+	if headHeader != (common.Hash{}) {
+		if header := bc.GetHeaderByHash(headHeader); header != nil {
 			currentHeader = header
 		}
 	}
-	bc.hc.SetCurrentHeader(currentHeader)
+	if currentHeader.Hash() != headHeader {
+		bc.hc.SetCurrentHeader(currentHeader)
+	} else {
+		bc.hc.currentHeader, bc.hc.currentHeaderHash = currentHeader, headHeader
+	}
 
 	// Restore the last known head fast block
 	bc.currentFastBlock = bc.currentBlock
@@ -228,6 +261,21 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// repair walks back from a block whose state is missing, e.g. because it was only
+// kept in memory when the node went down, to the nearest ancestor whose state is
+// available. Nil is returned if no such ancestor exists.
+func (bc *BlockChain) repair(head *types.Block) *types.Block {
+	for {
+		if _, err := state.New(head.Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", head.Number(), "hash", head.Hash())
+			return head
+		}
+		if head = bc.GetBlock(head.ParentHash(), head.NumberU64()-1); head == nil {
+			return nil
+		}
+	}
+}
+
 // SetHead rewinds the local chain to a new head. In the case of headers, everything
 // above the new head will be deleted and the new one set. In the case of blocks
 // though, the head may be further rewound if block bodies are missing (non-archive
@@ -351,6 +399,107 @@ func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.processor = processor
 }
 
+// SetLogIndexing enables or disables maintaining the contract event log index
+// during block import. Enabling the index makes it cover all blocks imported
+// from the current head onwards; disabling it marks the index as unusable.
+func (bc *BlockChain) SetLogIndexing(enabled bool) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.logIndex = enabled
+	if !enabled {
+		DeleteLogIndexTail(bc.chainDb)
+		return
+	}
+	if _, ok := GetLogIndexTail(bc.chainDb); !ok {
+		tail := bc.CurrentBlock().NumberU64() + 1
+		log.Info("Starting contract event log index", "tail", tail)
+		WriteLogIndexTail(bc.chainDb, tail)
+	}
+}
+
+// EnableSnapshots starts maintaining a flat snapshot of the state to serve account
+// and storage reads from. The snapshot of the current head is loaded from the
+// database, or regenerated in the background if missing or outdated.
+func (bc *BlockChain) EnableSnapshots() error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	snaps, err := snapshot.New(bc.StateDatabase(), bc.CurrentBlock().Root())
+	if err != nil {
+		return err
+	}
+	bc.snaps = snaps
+	return nil
+}
+
+// EnableStateGC switches from writing the state of every imported block to disk
+// to keeping the states of recent blocks in memory, garbage collecting them once
+// they fall out of the recent window. A state is flushed to disk every so often,
+// whenever the buffered states exceed the memory limit and on shutdown. It must
+// be called before any blocks are imported.
+func (bc *BlockChain) EnableStateGC(limit common.StorageSize) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.stateGC = newStateGC(bc.chainDb, limit)
+	bc.stateCache = state.NewDatabase(bc.stateGC)
+}
+
+// StateDatabase returns the database to read state trie nodes and contract codes
+// from. With state garbage collection enabled it also serves the recent states
+// not yet flushed to disk.
+func (bc *BlockChain) StateDatabase() ethdb.Database {
+	if bc.stateGC != nil {
+		if _, ok := bc.chainDb.(ethdb.Iteratee); ok {
+			return iterableStateGC{bc.stateGC}
+		}
+		return bc.stateGC
+	}
+	return bc.chainDb
+}
+
+// CommitState writes the state changes of a processed block. With state garbage
+// collection enabled the changes are buffered in memory and the states of blocks
+// falling out of the recent window are released.
+func (bc *BlockChain) CommitState(block *types.Block, statedb *state.StateDB) error {
+	deleteEmpty := bc.config.IsEIP158(block.Number())
+	if bc.stateGC == nil {
+		_, err := statedb.CommitTo(bc.chainDb, deleteEmpty)
+		return err
+	}
+	root, err := statedb.CommitTo(bc.stateGC.writer(), deleteEmpty)
+	if err != nil {
+		return err
+	}
+	bc.stateGC.reference(block.NumberU64(), root)
+	return bc.stateGC.cap(block.NumberU64())
+}
+
+// capSnapshots flattens the state snapshot layers too far below a new head block
+// into the disk layer. If the head state isn't covered by the snapshot, e.g. after
+// a deep reorg, the snapshot is regenerated.
+func (bc *BlockChain) capSnapshots(root common.Hash) {
+	if bc.snaps == nil {
+		return
+	}
+	if bc.snaps.Snapshot(root) == nil {
+		bc.snaps.Rebuild(root)
+		return
+	}
+	if err := bc.snaps.Cap(root, snapshotLayers); err != nil {
+		log.Warn("Failed to cap state snapshot", "root", root, "err", err)
+	}
+}
+
+// writeLogIndex indexes the receipts' logs of a block if the log index is enabled.
+func (bc *BlockChain) writeLogIndex(number uint64, receipts types.Receipts) error {
+	if !bc.logIndex {
+		return nil
+	}
+	return WriteLogIndex(bc.chainDb, number, receipts)
+}
+
 // SetValidator sets the validator which is used to validate incoming blocks.
 func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.procmu.Lock()
@@ -379,7 +528,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -398,11 +547,11 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	defer bc.mu.Unlock()
 
 	// Prepare the genesis block and reinitialise the chain
-	if err := bc.hc.WriteTd(genesis.Hash(), genesis.NumberU64(), genesis.Difficulty()); err != nil {
-		log.Crit("Failed to write genesis block TD", "err", err)
+	if err := WriteBody(bc.chainDb, genesis.Hash(), genesis.NumberU64(), genesis.Body()); err != nil {
+		log.Crit("Failed to write genesis block body", "err", err)
 	}
-	if err := WriteBlock(bc.chainDb, genesis); err != nil {
-		log.Crit("Failed to write genesis block", "err", err)
+	if err := bc.hc.WriteHeaderTd(genesis.Header(), genesis.Difficulty()); err != nil {
+		log.Crit("Failed to write genesis block header", "err", err)
 	}
 	bc.genesisBlock = genesis
 	bc.insert(bc.genesisBlock)
@@ -491,7 +640,7 @@ func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 		return nil
 	}
 	// Cache the found body for next time and return
-	bc.bodyCache.Add(hash, body)
+	cacheCapped(bc.bodyCache, bc.hc.Capped(), hash, body)
 	return body
 }
 
@@ -507,10 +656,16 @@ func (bc *BlockChain) GetBodyRLP(hash common.Hash) rlp.RawValue {
 		return nil
 	}
 	// Cache the found body for next time and return
-	bc.bodyRLPCache.Add(hash, body)
+	cacheCapped(bc.bodyRLPCache, bc.hc.Capped(), hash, body)
 	return body
 }
 
+// GetReceiptsByHash retrieves the receipts of all the transactions in a block,
+// with only the consensus fields populated.
+func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	return GetBlockReceipts(bc.chainDb, hash, bc.hc.GetBlockNumber(hash))
+}
+
 // HasBlock checks if a block is fully present in the database or not, caching
 // it if present.
 func (bc *BlockChain) HasBlock(hash common.Hash) bool {
@@ -542,7 +697,7 @@ func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 		return nil
 	}
 	// Cache the found block for next time and return
-	bc.blockCache.Add(block.Hash(), block)
+	cacheCapped(bc.blockCache, bc.hc.Capped(), block.Hash(), block)
 	return block
 }
 
@@ -588,6 +743,38 @@ func (bc *BlockChain) GetUnclesInChain(block *types.Block, length int) []*types.
 	return uncles
 }
 
+// PurgeCaches drops all the headers, blocks and state tries cached in memory, e.g.
+// to relieve memory pressure. Subsequent accesses are served from the database.
+func (bc *BlockChain) PurgeCaches() {
+	bc.hc.PurgeCaches()
+	bc.bodyCache.Purge()
+	bc.bodyRLPCache.Purge()
+	bc.blockCache.Purge()
+
+	if db, ok := bc.stateCache.(interface {
+		Purge()
+	}); ok {
+		db.Purge()
+	}
+}
+
+// CapCaches limits (or lifts the limit on) the headers, blocks and state tries
+// cached in memory, keeping them from refilling while the node is under memory
+// pressure. Capping also drops the current contents of the caches.
+func (bc *BlockChain) CapCaches(capped bool) {
+	bc.hc.CapCaches(capped)
+	if capped {
+		bc.bodyCache.Purge()
+		bc.bodyRLPCache.Purge()
+		bc.blockCache.Purge()
+	}
+	if db, ok := bc.stateCache.(interface {
+		Cap(capped bool)
+	}); ok {
+		db.Cap(capped)
+	}
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -598,6 +785,19 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	if bc.stateGC != nil {
+		nodes, size := bc.stateGC.stats()
+		if err := bc.stateGC.commit(bc.CurrentBlock().Root()); err != nil {
+			log.Error("Failed to flush head state", "err", err)
+		} else {
+			log.Info("Flushed head state to disk", "number", bc.CurrentBlock().Number(), "buffered", nodes, "size", size)
+		}
+	}
+	if bc.snaps != nil {
+		if err := bc.snaps.Persist(bc.CurrentBlock().Root()); err != nil {
+			log.Warn("Failed to persist state snapshot", "err", err)
+		}
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -652,37 +852,9 @@ func (bc *BlockChain) Rollback(chain []common.Hash) {
 }
 
 // SetReceiptsData computes all the non-consensus fields of the receipts
-func SetReceiptsData(config *params.ChainConfig, block *types.Block, receipts types.Receipts) {
+func SetReceiptsData(config *params.ChainConfig, block *types.Block, receipts types.Receipts) error {
 	signer := types.MakeSigner(config, block.Number())
-
-	transactions, logIndex := block.Transactions(), uint(0)
-
-	for j := 0; j < len(receipts); j++ {
-		// The transaction hash can be retrieved from the transaction itself
-		receipts[j].TxHash = transactions[j].Hash()
-
-		// The contract address can be derived from the transaction itself
-		if transactions[j].To() == nil {
-			// Deriving the signer is expensive, only do if it's actually needed
-			from, _ := types.Sender(signer, transactions[j])
-			receipts[j].ContractAddress = crypto.CreateAddress(from, transactions[j].Nonce())
-		}
-		// The used gas can be calculated based on previous receipts
-		if j == 0 {
-			receipts[j].GasUsed = new(big.Int).Set(receipts[j].CumulativeGasUsed)
-		} else {
-			receipts[j].GasUsed = new(big.Int).Sub(receipts[j].CumulativeGasUsed, receipts[j-1].CumulativeGasUsed)
-		}
-		// The derived log fields can simply be set from the block and transaction
-		for k := 0; k < len(receipts[j].Logs); k++ {
-			receipts[j].Logs[k].BlockNumber = block.NumberU64()
-			receipts[j].Logs[k].BlockHash = block.Hash()
-			receipts[j].Logs[k].TxHash = receipts[j].TxHash
-			receipts[j].Logs[k].TxIndex = uint(j)
-			receipts[j].Logs[k].Index = logIndex
-			logIndex++
-		}
-	}
+	return receipts.DeriveFields(signer, block.Hash(), block.NumberU64(), block.Transactions())
 }
 
 // InsertReceiptChain attempts to complete an already existing header chain with
@@ -739,7 +911,11 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 				continue
 			}
 			// Compute all the non-consensus fields of the receipts
-			SetReceiptsData(bc.config, block, receipts)
+			if err := SetReceiptsData(bc.config, block, receipts); err != nil {
+				errs[index] = fmt.Errorf("failed to set receipts data: %v", err)
+				atomic.AddInt32(&failed, 1)
+				return
+			}
 			// Write all the data out into the database
 			if err := WriteBody(bc.chainDb, block.Hash(), block.NumberU64(), block.Body()); err != nil {
 				errs[index] = fmt.Errorf("failed to write block body: %v", err)
@@ -759,6 +935,12 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 				log.Crit("Failed to write log blooms", "err", err)
 				return
 			}
+			if err := bc.writeLogIndex(block.NumberU64(), receipts); err != nil {
+				errs[index] = fmt.Errorf("failed to write log index: %v", err)
+				atomic.AddInt32(&failed, 1)
+				log.Crit("Failed to write log index", "err", err)
+				return
+			}
 			if err := WriteTransactions(bc.chainDb, block); err != nil {
 				errs[index] = fmt.Errorf("failed to write individual transactions: %v", err)
 				atomic.AddInt32(&failed, 1)
@@ -836,12 +1018,13 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 	localTd := bc.GetTd(bc.currentBlock.Hash(), bc.currentBlock.NumberU64())
 	externTd := new(big.Int).Add(block.Difficulty(), ptd)
 
-	// Irrelevant of the canonical status, write the block itself to the database
-	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
-		log.Crit("Failed to write block total difficulty", "err", err)
+	// Irrelevant of the canonical status, write the block itself to the database.
+	// The body goes first so the header signals full block ownership.
+	if err := WriteBody(bc.chainDb, block.Hash(), block.NumberU64(), block.Body()); err != nil {
+		log.Crit("Failed to write block body", "err", err)
 	}
-	if err := WriteBlock(bc.chainDb, block); err != nil {
-		log.Crit("Failed to write block contents", "err", err)
+	if err := bc.hc.WriteHeaderTd(block.Header(), externTd); err != nil {
+		log.Crit("Failed to write block header and total difficulty", "err", err)
 	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
@@ -855,6 +1038,7 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 			}
 		}
 		bc.insert(block) // Insert the block as the new head of the chain
+		bc.capSnapshots(block.Root())
 		status = CanonStatTy
 	} else {
 		status = SideStatTy
@@ -865,6 +1049,27 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 	return
 }
 
+// cacheSenders recovers the senders of all transactions in the chain, batching
+// the transactions of consecutive blocks sharing the same signer.
+func (bc *BlockChain) cacheSenders(chain types.Blocks) {
+	var (
+		signer types.Signer
+		txs    []*types.Transaction
+	)
+	for _, block := range chain {
+		blockSigner := types.MakeSigner(bc.config, block.Number())
+		if signer != nil && !signer.Equal(blockSigner) {
+			types.CacheSenders(signer, txs)
+			txs = txs[:0]
+		}
+		signer = blockSigner
+		txs = append(txs, block.Transactions()...)
+	}
+	if len(txs) > 0 {
+		types.CacheSenders(signer, txs)
+	}
+}
+
 // InsertChain will attempt to insert the given chain in to the canonical chain or, otherwise, create a fork. If an error is returned
 // it will return the index number of the failing block as well an error describing what went wrong (for possible errors see core/errors.go).
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
@@ -905,7 +1110,17 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
 
-	// Iterate over the blocks and insert when the verifier permits
+	// Recover the transaction senders in batches while the headers are verified
+	bc.cacheSenders(chain)
+
+	// Iterate over the blocks and insert when the verifier permits. Each block
+	// is traced in a span, ended when the next block is started or on return.
+	var (
+		ctx  context.Context
+		span *tracing.Span
+	)
+	defer func() { span.Finish(nil) }()
+
 	for i, block := range chain {
 		// If the chain is terminating, stop processing blocks
 		if atomic.LoadInt32(&bc.procInterrupt) == 1 {
@@ -920,10 +1135,14 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 		// Wait for the block's verification to complete
 		bstart := time.Now()
 
+		span.Finish(nil)
+		ctx, span = tracing.Start(context.Background(), "core.insertBlock", "number", block.NumberU64(), "hash", block.Hash(), "txs", len(block.Transactions()))
+
 		err := <-results
 		if err == nil {
 			err = bc.Validator().ValidateBody(block)
 		}
+		tracing.Record(ctx, "core.verifyBlock", bstart, err)
 		if err != nil {
 			if err == ErrKnownBlock {
 				stats.ignored++
@@ -960,24 +1179,35 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 		} else {
 			parent = chain[i-1]
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		state, witness, err := bc.processingState(parent.Root())
 		if err != nil {
 			return i, err
 		}
 		// Process block using the parent state as reference point.
+		pstart := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		tracing.Record(ctx, "core.processBlock", pstart, err)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return i, err
 		}
+		if bc.vmConfig.TrackStateAccess {
+			bc.recordAccessStats(block, state.AccessSets())
+		}
 		// Validate the state using the default validator
+		vstart := time.Now()
 		err = bc.Validator().ValidateState(block, parent, state, receipts, usedGas)
+		tracing.Record(ctx, "core.validateState", vstart, err)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return i, err
 		}
+		if witness != nil {
+			bc.witnesses.Add(block.Hash(), newWitness(block.NumberU64(), block.Hash(), parent.Root(), witness))
+		}
 		// Write state changes to database
-		if _, err = state.CommitTo(bc.chainDb, bc.config.IsEIP158(block.Number())); err != nil {
+		wstart := time.Now()
+		if err = bc.CommitState(block, state); err != nil {
 			return i, err
 		}
 
@@ -990,6 +1220,7 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 
 		// write the block to the chain and get the status
 		status, err := bc.WriteBlock(block)
+		tracing.Record(ctx, "core.writeBlock", wstart, err)
 		if err != nil {
 			return i, err
 		}
@@ -1014,6 +1245,10 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			if err := WriteMipmapBloom(bc.chainDb, block.NumberU64(), receipts); err != nil {
 				return i, err
 			}
+			// Write the contract event log index
+			if err := bc.writeLogIndex(block.NumberU64(), receipts); err != nil {
+				return i, err
+			}
 			// Write hash preimages
 			if err := WritePreimages(bc.chainDb, block.NumberU64(), state.Preimages()); err != nil {
 				return i, err
@@ -1099,7 +1334,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		// These logs are later announced as deleted.
 		collectLogs = func(h common.Hash) {
 			// Coalesce logs and set 'Removed'.
-			receipts := GetBlockReceipts(bc.chainDb, h, bc.hc.GetBlockNumber(h))
+			receipts := GetDerivedBlockReceipts(bc.chainDb, h, bc.hc.GetBlockNumber(h), bc.config)
 			for _, receipt := range receipts {
 				for _, log := range receipt.Logs {
 					del := *log
@@ -1171,7 +1406,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		if err := WriteTransactions(bc.chainDb, block); err != nil {
 			return err
 		}
-		receipts := GetBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64())
+		receipts := GetDerivedBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64(), bc.config)
 		// write receipts
 		if err := WriteReceipts(bc.chainDb, receipts); err != nil {
 			return err
@@ -1180,6 +1415,10 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		if err := WriteMipmapBloom(bc.chainDb, block.NumberU64(), receipts); err != nil {
 			return err
 		}
+		// Write the contract event log index
+		if err := bc.writeLogIndex(block.NumberU64(), receipts); err != nil {
+			return err
+		}
 		addedTxs = append(addedTxs, block.Transactions()...)
 	}
 
@@ -1260,6 +1499,51 @@ func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
 	return headers, nil
 }
 
+// recordAccessStats computes the transaction conflict statistics of a processed
+// block and caches them for later retrieval.
+func (bc *BlockChain) recordAccessStats(block *types.Block, accesses []*state.TxAccess) {
+	stats := ComputeAccessStats(block.NumberU64(), accesses, block.Coinbase())
+	bc.accessStats.Add(block.Hash(), stats)
+
+	accessTxMeter.Mark(int64(stats.Txs))
+	accessConflictingMeter.Mark(int64(stats.Conflicting))
+	accessCriticalMeter.Mark(int64(stats.CriticalPath))
+
+	log.Debug("Tracked transaction conflicts", "number", stats.Number, "hash", block.Hash(), "txs", stats.Txs,
+		"conflicting", stats.Conflicting, "conflicts", stats.Conflicts, "critical", stats.CriticalPath)
+}
+
+// AccessStats returns the transaction conflict statistics of a recently processed
+// block, or nil if they are unavailable (e.g. access tracking is disabled).
+func (bc *BlockChain) AccessStats(hash common.Hash) *AccessStats {
+	if stats, ok := bc.accessStats.Get(hash); ok {
+		return stats.(*AccessStats)
+	}
+	return nil
+}
+
+// processingState opens the parent state a block is processed on. If witness
+// recording is enabled, the state is read through a fresh witness database,
+// bypassing the shared trie caches and the snapshot.
+func (bc *BlockChain) processingState(root common.Hash) (*state.StateDB, *state.WitnessDatabase, error) {
+	if !bc.vmConfig.RecordWitness {
+		statedb, err := state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
+		return statedb, nil, err
+	}
+	witness := state.NewWitnessDatabase(bc.chainDb)
+	statedb, err := state.New(root, witness)
+	return statedb, witness, err
+}
+
+// Witness returns the execution witness of a recently processed block, or nil if
+// unavailable (e.g. witness recording is disabled).
+func (bc *BlockChain) Witness(hash common.Hash) *Witness {
+	if witness, ok := bc.witnesses.Get(hash); ok {
+		return witness.(*Witness)
+	}
+	return nil
+}
+
 // addBadBlock adds a bad block to the bad-block LRU cache
 func (bc *BlockChain) addBadBlock(block *types.Block) {
 	bc.badBlocks.Add(block.Header().Hash(), block.Header())
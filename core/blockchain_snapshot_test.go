@@ -0,0 +1,187 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that blocks are imported on top of a state snapshot, and that the state
+// served from the persisted snapshot after a restart matches the trie.
+func TestSnapshotImport(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+
+		counter  = common.Address{0xc0} // Increments slot 0 and emits a log on every call
+		suicider = common.Address{0xdd} // Self destructs, wiping its storage
+		orphan   = common.Address{0xde} // Self destructs, never recreated
+
+		gspec = &Genesis{
+			Config: &params.ChainConfig{ChainId: big.NewInt(1), HomesteadBlock: new(big.Int), EIP155Block: new(big.Int)},
+			Alloc: GenesisAlloc{
+				addr:     {Balance: big.NewInt(1000000000)},
+				counter:  {Balance: new(big.Int), Code: common.FromHex("60005460010160005560006000a0")},
+				suicider: {Balance: new(big.Int), Code: common.FromHex("33ff"), Storage: map[common.Hash]common.Hash{{1}: {1}}},
+				orphan:   {Balance: new(big.Int), Code: common.FromHex("33ff"), Storage: map[common.Hash]common.Hash{{1}: {1}}},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 4, func(i int, block *BlockGen) {
+		send := func(to common.Address, value int64, gas int64) {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(value), big.NewInt(gas), big.NewInt(1), nil), signer, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(tx)
+		}
+		send(counter, 0, 100000)
+		send(common.Address{byte(i + 1)}, 1000, 21000)
+		switch i {
+		case 1:
+			send(suicider, 0, 100000)
+			send(orphan, 0, 100000)
+		case 2:
+			send(suicider, 1000, 21000) // Recreates the destructed account
+		}
+	})
+	db, _ = ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if err := chain.EnableSnapshots(); err != nil {
+		t.Fatalf("failed to enable snapshots: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	head := chain.CurrentBlock().Root()
+	if chain.snaps.Snapshot(head) == nil {
+		t.Fatalf("head state snapshot missing")
+	}
+	chain.Stop()
+
+	// Restart the chain, reloading the persisted snapshot
+	chain, err = NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if err := chain.EnableSnapshots(); err != nil {
+		t.Fatalf("failed to enable snapshots: %v", err)
+	}
+	snap := chain.snaps.Snapshot(head)
+	if snap == nil {
+		t.Fatalf("persisted snapshot not loaded")
+	}
+	trie, _ := chain.stateCache.OpenTrie(head)
+	for _, account := range []common.Address{addr, counter, suicider, orphan, {1}, {4}, {0xff}} {
+		want, _ := trie.TryGet(account[:])
+		if have, err := snap.Account(crypto.Keccak256Hash(account[:])); err == nil && string(have) != string(want) {
+			t.Errorf("account %x mismatch: have %x, want %x", account, have, want)
+		}
+	}
+	for _, account := range []common.Address{suicider, orphan} {
+		if have, err := snap.Storage(crypto.Keccak256Hash(account[:]), crypto.Keccak256Hash(common.Hash{1}.Bytes())); err == nil && have != nil {
+			t.Errorf("destructed storage of %x retained: %x", account, have)
+		}
+	}
+	state, _ := chain.State()
+	if have := state.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(4)) {
+		t.Errorf("counter mismatch: have %x, want %x", have, 4)
+	}
+	if have := state.GetState(suicider, common.Hash{1}); have != (common.Hash{}) {
+		t.Errorf("destructed storage retained: %x", have)
+	}
+	if have := state.GetBalance(suicider); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("recreated account balance mismatch: have %v, want %v", have, 1000)
+	}
+}
+
+// Tests that snapshots can be maintained on top of the in-memory state garbage
+// collection, the combination of --gcmode full and --snapshot.
+func TestSnapshotImportStateGC(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 4, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	db, _ = ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	chain.EnableStateGC(DefaultStateGCLimit)
+	if err := chain.EnableSnapshots(); err != nil {
+		t.Fatalf("failed to enable snapshots: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	head := chain.CurrentBlock().Root()
+	snap := chain.snaps.Snapshot(head)
+	if snap == nil {
+		t.Fatalf("head state snapshot missing")
+	}
+	trie, _ := chain.stateCache.OpenTrie(head)
+	for _, account := range []common.Address{addr, {1}, {4}, {0xff}} {
+		want, _ := trie.TryGet(account[:])
+		if have, err := snap.Account(crypto.Keccak256Hash(account[:])); err == nil && string(have) != string(want) {
+			t.Errorf("account %x mismatch: have %x, want %x", account, have, want)
+		}
+	}
+	state, _ := chain.State()
+	if have := state.GetBalance(common.Address{4}); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", have, 1000)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
 	"github.com/networkchain/networkchain/params"
+	"github.com/hashicorp/golang-lru"
 )
 
 // newTestBlockChain creates a blockchain without validation.
@@ -1151,3 +1152,50 @@ func TestEIP161AccountRemoval(t *testing.T) {
 		t.Error("account should not exist")
 	}
 }
+
+// Tests that capping the caches of a chain keeps them from refilling beyond the
+// capped limit until lifted again.
+func TestCapCaches(t *testing.T) {
+	_, blockchain, err := newCanonical(2*cappedCacheLimit, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	access := func() {
+		for i := uint64(0); i <= blockchain.CurrentBlock().NumberU64(); i++ {
+			block := blockchain.GetBlockByNumber(i)
+			blockchain.GetBody(block.Hash())
+			blockchain.GetBodyRLP(block.Hash())
+			blockchain.GetHeaderByHash(block.Hash())
+			blockchain.GetTd(block.Hash(), i)
+		}
+	}
+	caches := map[string]*lru.Cache{
+		"header": blockchain.hc.headerCache,
+		"td":     blockchain.hc.tdCache,
+		"number": blockchain.hc.numberCache,
+		"body":   blockchain.bodyCache,
+		"rlp":    blockchain.bodyRLPCache,
+		"block":  blockchain.blockCache,
+	}
+	blockchain.CapCaches(true)
+	for name, cache := range caches {
+		if cache.Len() != 0 {
+			t.Errorf("%s cache: entry count mismatch after capping: have %d, want 0", name, cache.Len())
+		}
+	}
+	access()
+	for name, cache := range caches {
+		if cache.Len() > cappedCacheLimit {
+			t.Errorf("%s cache: capped entry count exceeded: have %d, want at most %d", name, cache.Len(), cappedCacheLimit)
+		}
+	}
+	blockchain.CapCaches(false)
+	access()
+	for name, cache := range caches {
+		if cache.Len() <= cappedCacheLimit {
+			t.Errorf("%s cache: entry count still capped: have %d, want more than %d", name, cache.Len(), cappedCacheLimit)
+		}
+	}
+}
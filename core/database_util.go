@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/networkchain/networkchain/common"
@@ -43,6 +44,7 @@ var (
 	tdSuffix            = []byte("t")   // headerPrefix + num (uint64 big endian) + hash + tdSuffix -> td
 	numSuffix           = []byte("n")   // headerPrefix + num (uint64 big endian) + numSuffix -> hash
 	blockHashPrefix     = []byte("H")   // blockHashPrefix + hash -> num (uint64 big endian)
+	headerTdPrefix      = []byte("T")   // headerTdPrefix + num (uint64 big endian) + hash -> header and td entry
 	bodyPrefix          = []byte("b")   // bodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r")   // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	preimagePrefix      = "secure-key-" // preimagePrefix + hash -> preimage
@@ -53,6 +55,9 @@ var (
 	mipmapPre    = []byte("mipmap-log-bloom-")
 	MIPMapLevels = []uint64{1000000, 500000, 100000, 50000, 1000}
 
+	logIndexPrefix  = []byte("log-index-")   // logIndexPrefix + address + topic0 + section (uint64 big endian) -> block numbers
+	logIndexTailKey = []byte("LogIndexTail") // first block number covered by the log index
+
 	configPrefix = []byte("networkchain-config-") // config prefix for the db
 
 	// used by old (non-sequential keys) db, now only used for conversion
@@ -67,6 +72,7 @@ var (
 	ErrChainConfigNotFound = errors.New("ChainConfig not found") // general config not found error
 
 	mipmapBloomMu sync.Mutex // protect against race condition when updating mipmap blooms
+	logIndexMu    sync.Mutex // protect against race condition when updating log index entries
 
 	preimageCounter    = metrics.NewCounter("db/preimage/total")
 	preimageHitCounter = metrics.NewCounter("db/preimage/hits")
@@ -147,9 +153,34 @@ func GetHeadFastBlockHash(db ethdb.Database) common.Hash {
 	return common.BytesToHash(data)
 }
 
+// headerTdEntry is the compact database encoding of a header bundled together
+// with its total difficulty, allowing both to be retrieved with a single read.
+type headerTdEntry struct {
+	Td     *big.Int
+	Header rlp.RawValue
+}
+
+// getHeaderTdEntry retrieves the compact header and total difficulty entry of
+// a block, or nil if the block was stored in the separate header/td layout.
+func getHeaderTdEntry(db ethdb.Database, hash common.Hash, number uint64) *headerTdEntry {
+	data, _ := db.Get(append(append(headerTdPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(headerTdEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid header and total difficulty RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return entry
+}
+
 // GetHeaderRLP retrieves a block header in its raw RLP database encoding, or nil
 // if the header's not found.
 func GetHeaderRLP(db ethdb.Database, hash common.Hash, number uint64) rlp.RawValue {
+	if entry := getHeaderTdEntry(db, hash, number); entry != nil {
+		return entry.Header
+	}
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 	if len(data) == 0 {
 		data, _ = db.Get(append(append(oldBlockPrefix, hash.Bytes()...), oldHeaderSuffix...))
@@ -199,6 +230,9 @@ func GetBody(db ethdb.Database, hash common.Hash, number uint64) *types.Body {
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db ethdb.Database, hash common.Hash, number uint64) *big.Int {
+	if entry := getHeaderTdEntry(db, hash, number); entry != nil {
+		return entry.Td
+	}
 	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
 	if len(data) == 0 {
 		data, _ = db.Get(append(append(oldBlockPrefix, hash.Bytes()...), oldTdSuffix...))
@@ -214,6 +248,23 @@ func GetTd(db ethdb.Database, hash common.Hash, number uint64) *big.Int {
 	return td
 }
 
+// GetHeaderTd retrieves a block header together with its total difficulty. Blocks
+// stored in the compact layout are served with a single database read, others
+// fall back to looking up the header and td separately. Either return value is
+// nil if not found.
+func GetHeaderTd(db ethdb.Database, hash common.Hash, number uint64) (*types.Header, *big.Int) {
+	entry := getHeaderTdEntry(db, hash, number)
+	if entry == nil {
+		return GetHeader(db, hash, number), GetTd(db, hash, number)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(entry.Header, header); err != nil {
+		log.Error("Invalid block header RLP", "hash", hash, "err", err)
+		return nil, entry.Td
+	}
+	return header, entry.Td
+}
+
 // GetBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -256,6 +307,24 @@ func GetBlockReceipts(db ethdb.Database, hash common.Hash, number uint64) types.
 	return receipts
 }
 
+// GetDerivedBlockReceipts retrieves the receipts of a block like GetBlockReceipts,
+// and fills in the fields not secured by consensus based on the block itself.
+func GetDerivedBlockReceipts(db ethdb.Database, hash common.Hash, number uint64, config *params.ChainConfig) types.Receipts {
+	receipts := GetBlockReceipts(db, hash, number)
+	if receipts == nil {
+		return nil
+	}
+	block := GetBlock(db, hash, number)
+	if block == nil {
+		return nil
+	}
+	if err := SetReceiptsData(config, block, receipts); err != nil {
+		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
+		return nil
+	}
+	return receipts
+}
+
 // GetTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func GetTransaction(db ethdb.Database, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -298,6 +367,19 @@ func GetReceipt(db ethdb.Database, hash common.Hash) *types.Receipt {
 	return (*types.Receipt)(&receipt)
 }
 
+// GetDerivedReceipt retrieves a transaction receipt by the transaction hash, with
+// the fields not secured by consensus filled in from its containing block. If the
+// block receipts are not available, the individually stored receipt is returned.
+func GetDerivedReceipt(db ethdb.Database, hash common.Hash, config *params.ChainConfig) *types.Receipt {
+	if _, blockHash, number, index := GetTransaction(db, hash); blockHash != (common.Hash{}) {
+		receipts := GetDerivedBlockReceipts(db, blockHash, number, config)
+		if index < uint64(len(receipts)) && receipts[index].TxHash == hash {
+			return receipts[index]
+		}
+	}
+	return GetReceipt(db, hash)
+}
+
 // WriteCanonicalHash stores the canonical hash for the given block number.
 func WriteCanonicalHash(db ethdb.Database, hash common.Hash, number uint64) error {
 	key := append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...)
@@ -351,6 +433,32 @@ func WriteHeader(db ethdb.Database, header *types.Header) error {
 	return nil
 }
 
+// WriteHeaderTd serializes a block header together with its total difficulty
+// into the compact header table, also storing the hash to number mapping.
+//
+// Releases prior to BlockChainVersion 4 don't know about the compact table, so
+// writing into it makes the database unusable for them. The change is one-way,
+// there is no conversion back into the separate header and td layout.
+func WriteHeaderTd(db ethdb.Database, header *types.Header, td *big.Int) error {
+	headerRLP, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return err
+	}
+	data, err := rlp.EncodeToBytes(&headerTdEntry{Td: td, Header: headerRLP})
+	if err != nil {
+		return err
+	}
+	hash := header.Hash().Bytes()
+	encNum := encodeBlockNumber(header.Number.Uint64())
+	if err := db.Put(append(blockHashPrefix, hash...), encNum); err != nil {
+		log.Crit("Failed to store hash to number mapping", "err", err)
+	}
+	if err := db.Put(append(append(headerTdPrefix, encNum...), hash...), data); err != nil {
+		log.Crit("Failed to store header and total difficulty", "err", err)
+	}
+	return nil
+}
+
 // WriteBody serializes the body of a block into the database.
 func WriteBody(db ethdb.Database, hash common.Hash, number uint64, body *types.Body) error {
 	data, err := rlp.EncodeToBytes(body)
@@ -499,6 +607,7 @@ func DeleteCanonicalHash(db ethdb.Database, number uint64) {
 func DeleteHeader(db ethdb.Database, hash common.Hash, number uint64) {
 	db.Delete(append(blockHashPrefix, hash.Bytes()...))
 	db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	db.Delete(append(append(headerTdPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
 // DeleteBody removes all block body data associated with a hash.
@@ -506,9 +615,12 @@ func DeleteBody(db ethdb.Database, hash common.Hash, number uint64) {
 	db.Delete(append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
-// DeleteTd removes all block total difficulty data associated with a hash.
+// DeleteTd removes all block total difficulty data associated with a hash. As
+// the compact header table stores the td alongside the header, that entry is
+// dropped too.
 func DeleteTd(db ethdb.Database, hash common.Hash, number uint64) {
 	db.Delete(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...), tdSuffix...))
+	db.Delete(append(append(headerTdPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
 // DeleteBlock removes all block data associated with a hash.
@@ -577,6 +689,101 @@ func GetMipmapBloom(db ethdb.Database, number, level uint64) types.Bloom {
 	return types.BytesToBloom(bloomDat)
 }
 
+// LogIndexSectionSize is the number of consecutive blocks sharing a single log
+// index entry per contract address and event signature.
+const LogIndexSectionSize = 4096
+
+// logIndexKey = logIndexPrefix + address + topic0 + section (uint64 big endian)
+func logIndexKey(address common.Address, topic common.Hash, section uint64) []byte {
+	key := append(append(append([]byte{}, logIndexPrefix...), address.Bytes()...), topic.Bytes()...)
+	return append(key, encodeBlockNumber(section)...)
+}
+
+// WriteLogIndex adds the block number to the log index entries of every contract
+// address and event signature (first topic) pair emitted by the receipts' logs.
+func WriteLogIndex(db ethdb.Database, number uint64, receipts types.Receipts) error {
+	logIndexMu.Lock()
+	defer logIndexMu.Unlock()
+
+	section := number / LogIndexSectionSize
+	updated := make(map[string]bool)
+
+	batch := db.NewBatch()
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if len(log.Topics) == 0 {
+				continue
+			}
+			key := logIndexKey(log.Address, log.Topics[0], section)
+			if updated[string(key)] {
+				continue
+			}
+			updated[string(key)] = true
+
+			// Insert the block number in order, skipping it if already
+			// indexed (e.g. when re-inserting blocks after a reorg)
+			numbers := GetLogIndex(db, log.Address, log.Topics[0], section)
+			pos := sort.Search(len(numbers), func(i int) bool { return numbers[i] >= number })
+			if pos < len(numbers) && numbers[pos] == number {
+				continue
+			}
+			numbers = append(numbers, 0)
+			copy(numbers[pos+1:], numbers[pos:])
+			numbers[pos] = number
+
+			data, err := rlp.EncodeToBytes(numbers)
+			if err != nil {
+				return err
+			}
+			batch.Put(key, data)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("log index write fail for: %d: %v", number, err)
+	}
+	return nil
+}
+
+// GetLogIndex returns the ordered numbers of the blocks within the given section
+// that contain logs emitted by the contract with the event signature as their
+// first topic. The numbers may include blocks no longer in the canonical chain.
+func GetLogIndex(db ethdb.Database, address common.Address, topic common.Hash, section uint64) []uint64 {
+	data, _ := db.Get(logIndexKey(address, topic, section))
+	if len(data) == 0 {
+		return nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		log.Error("Invalid log index entry", "address", address, "topic", topic, "section", section, "err", err)
+		return nil
+	}
+	return numbers
+}
+
+// GetLogIndexTail returns the number of the first block covered by the log
+// index, or false if the log index is not maintained.
+func GetLogIndexTail(db ethdb.Database) (uint64, bool) {
+	data, _ := db.Get(logIndexTailKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// WriteLogIndexTail stores the number of the first block covered by the log index.
+func WriteLogIndexTail(db ethdb.Database, number uint64) error {
+	if err := db.Put(logIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store log index tail", "err", err)
+	}
+	return nil
+}
+
+// DeleteLogIndexTail marks the log index as no longer maintained. Entries already
+// written are left in place, but won't be consulted anymore.
+func DeleteLogIndexTail(db ethdb.Database) {
+	db.Delete(logIndexTailKey)
+}
+
 // PreimageTable returns a Database instance with the key prefix for preimage entries.
 func PreimageTable(db ethdb.Database) ethdb.Database {
 	return ethdb.NewTable(db, preimagePrefix)
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/networkchain/networkchain/common"
@@ -225,6 +226,53 @@ func TestTdStorage(t *testing.T) {
 	}
 }
 
+// Tests that headers stored together with their total difficulty in the compact
+// table can be retrieved through both the combined and the individual accessors,
+// and that legacy entries are still served.
+func TestHeaderTdStorage(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	header := &types.Header{Number: big.NewInt(42), Extra: []byte("test header")}
+	td := big.NewInt(314)
+	if entry, entryTd := GetHeaderTd(db, header.Hash(), 42); entry != nil || entryTd != nil {
+		t.Fatalf("Non existent header and TD returned: %v, %v", entry, entryTd)
+	}
+	// Write and verify the entry in the database
+	if err := WriteHeaderTd(db, header, td); err != nil {
+		t.Fatalf("Failed to write header and TD into database: %v", err)
+	}
+	if entry, entryTd := GetHeaderTd(db, header.Hash(), 42); entry == nil || entryTd == nil {
+		t.Fatalf("Stored header and TD not found")
+	} else if entry.Hash() != header.Hash() || entryTd.Cmp(td) != 0 {
+		t.Fatalf("Retrieved entry mismatch: have %v/%v, want %v/%v", entry.Hash(), entryTd, header.Hash(), td)
+	}
+	if entry := GetHeader(db, header.Hash(), 42); entry == nil || entry.Hash() != header.Hash() {
+		t.Fatalf("Retrieved header mismatch: have %v, want %v", entry, header)
+	}
+	if want, _ := rlp.EncodeToBytes(header); !bytes.Equal(GetHeaderRLP(db, header.Hash(), 42), want) {
+		t.Fatalf("Retrieved header RLP mismatch")
+	}
+	if entry := GetTd(db, header.Hash(), 42); entry == nil || entry.Cmp(td) != 0 {
+		t.Fatalf("Retrieved TD mismatch: have %v, want %v", entry, td)
+	}
+	if number := GetBlockNumber(db, header.Hash()); number != 42 {
+		t.Fatalf("Retrieved block number mismatch: have %d, want 42", number)
+	}
+	// Delete the entry and verify the execution
+	DeleteHeader(db, header.Hash(), 42)
+	if entry, entryTd := GetHeaderTd(db, header.Hash(), 42); entry != nil || entryTd != nil {
+		t.Fatalf("Deleted header and TD returned: %v, %v", entry, entryTd)
+	}
+	// Store the header and TD in the legacy layout and ensure they're still served
+	WriteHeader(db, header)
+	WriteTd(db, header.Hash(), 42, td)
+	if entry, entryTd := GetHeaderTd(db, header.Hash(), 42); entry == nil || entryTd == nil {
+		t.Fatalf("Legacy header and TD not found")
+	} else if entry.Hash() != header.Hash() || entryTd.Cmp(td) != 0 {
+		t.Fatalf("Retrieved legacy entry mismatch: have %v/%v, want %v/%v", entry.Hash(), entryTd, header.Hash(), td)
+	}
+}
+
 // Tests that canonical numbers can be mapped to hashes and retrieved.
 func TestCanonicalMappingStorage(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
@@ -491,6 +539,59 @@ func TestMipmapBloom(t *testing.T) {
 	}
 }
 
+// Tests that the log index tracks blocks per contract address and event signature,
+// keeping the block numbers ordered and free of duplicates.
+func TestLogIndexStorage(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	addr1, addr2 := common.BytesToAddress([]byte("test")), common.BytesToAddress([]byte("address"))
+	topic1, topic2 := common.BytesToHash([]byte("event1")), common.BytesToHash([]byte("event2"))
+
+	receipt1 := &types.Receipt{Logs: []*types.Log{
+		{Address: addr1, Topics: []common.Hash{topic1}},
+		{Address: addr1, Topics: []common.Hash{topic1, topic2}},
+		{Address: addr2},
+	}}
+	receipt2 := &types.Receipt{Logs: []*types.Log{
+		{Address: addr2, Topics: []common.Hash{topic2}},
+	}}
+	// Write out of order and repeatedly, as happening during reorgs
+	WriteLogIndex(db, 5, types.Receipts{receipt1})
+	WriteLogIndex(db, 3, types.Receipts{receipt1, receipt2})
+	WriteLogIndex(db, 5, types.Receipts{receipt1})
+	WriteLogIndex(db, LogIndexSectionSize+1, types.Receipts{receipt1})
+
+	tests := []struct {
+		addr    common.Address
+		topic   common.Hash
+		section uint64
+		want    []uint64
+	}{
+		{addr1, topic1, 0, []uint64{3, 5}},
+		{addr1, topic1, 1, []uint64{LogIndexSectionSize + 1}},
+		{addr1, topic2, 0, nil},
+		{addr2, topic2, 0, []uint64{3}},
+		{addr2, topic2, 1, nil},
+	}
+	for i, tt := range tests {
+		if have := GetLogIndex(db, tt.addr, tt.topic, tt.section); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: block numbers mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Check the index tail storage
+	if _, ok := GetLogIndexTail(db); ok {
+		t.Fatalf("non existent log index tail returned")
+	}
+	WriteLogIndexTail(db, 42)
+	if tail, ok := GetLogIndexTail(db); !ok || tail != 42 {
+		t.Fatalf("log index tail mismatch: have %d/%v, want %d", tail, ok, 42)
+	}
+	DeleteLogIndexTail(db)
+	if _, ok := GetLogIndexTail(db); ok {
+		t.Fatalf("deleted log index tail returned")
+	}
+}
+
 func TestMipmapChain(t *testing.T) {
 	dir, err := ioutil.TempDir("", "mipmap")
 	if err != nil {
@@ -22,7 +22,22 @@ import (
 )
 
 // TxPreEvent is posted when a transaction enters the transaction pool.
-type TxPreEvent struct{ Tx *types.Transaction }
+type TxPreEvent struct {
+	Tx      *types.Transaction
+	Private bool // Transaction must not be announced to the network
+}
+
+// TxDropEvent is posted when a transaction leaves the pool without being included
+// in a block. If it was superseded by another transaction with the same nonce,
+// the Replacement field is set.
+type TxDropEvent struct {
+	Tx          *types.Transaction
+	Replacement *types.Transaction
+}
+
+// TxRebroadcastEvent is posted periodically with the still pending local
+// transactions, requesting them to be announced to the network again.
+type TxRebroadcastEvent struct{ Txs types.Transactions }
 
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
@@ -0,0 +1,171 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkid implements a compact identifier of the chain configuration a
+// node is running, derived from the genesis hash and the fork schedule.
+//
+// Nodes exchange their fork identifiers during the eth handshake, which allows
+// dropping peers on an incompatible chain or fork schedule before any block is
+// downloaded from them.
+package forkid
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/params"
+)
+
+var (
+	// ErrRemoteStale is returned by the filter if the remote fork checksum is a
+	// subset of our already passed forks, but the fork the remote node announces
+	// as its next one is not the one we have passed after it.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by the filter if the remote fork
+	// checksum does not match any of the local checksums, signalling that the
+	// two chains diverged at some point, possibly at genesis.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+)
+
+// ID is a fork identifier.
+type ID struct {
+	Hash [4]byte // CRC32 checksum of the genesis hash and the passed fork block numbers
+	Next uint64  // Block number of the next upcoming fork, or 0 if none is known
+}
+
+// Filter validates a remotely advertised fork identifier.
+type Filter func(id ID) error
+
+// NewID calculates the fork identifier of a chain from its configuration, the
+// genesis hash and the current head block number.
+func NewID(config *params.ChainConfig, genesis common.Hash, head uint64) ID {
+	hash := crc32.ChecksumIEEE(genesis[:])
+
+	var next uint64
+	for _, fork := range gatherForks(config) {
+		if fork <= head {
+			hash = checksumUpdate(hash, fork)
+			continue
+		}
+		next = fork
+		break
+	}
+	return ID{Hash: checksumToBytes(hash), Next: next}
+}
+
+// NewFilter creates a filter that accepts the fork identifiers of peers on the
+// same chain, either ahead of or behind the local node, and rejects the rest.
+// The head callback returns the current local head block number.
+func NewFilter(config *params.ChainConfig, genesis common.Hash, head func() uint64) Filter {
+	// Precalculate the checksums of all the fork combinations we can be at
+	var (
+		forks = gatherForks(config)
+		sums  = make([][4]byte, len(forks)+1) // 0th is the genesis
+	)
+	hash := crc32.ChecksumIEEE(genesis[:])
+	sums[0] = checksumToBytes(hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		sums[i+1] = checksumToBytes(hash)
+	}
+	// Add a sentinel so the last fork is never considered passed
+	forks = append(forks, math.MaxUint64)
+
+	return func(id ID) error {
+		// Find the local fork checksum we are at, then walk the others to find
+		// the remote one
+		number := head()
+		for i, fork := range forks {
+			if number >= fork {
+				continue
+			}
+			// Both nodes on the same fork: the remote's next fork must not have
+			// been passed locally already
+			if sums[i] == id.Hash {
+				if id.Next > 0 && number >= id.Next {
+					return ErrLocalIncompatibleOrStale
+				}
+				return nil
+			}
+			// Remote behind us: it must announce the fork we passed next, otherwise
+			// it is not aware of it and will fork off at that block
+			for j := 0; j < i; j++ {
+				if sums[j] == id.Hash {
+					if forks[j] != id.Next {
+						return ErrRemoteStale
+					}
+					return nil
+				}
+			}
+			// Remote ahead of us: accept it if it passed forks we know of
+			for j := i + 1; j < len(sums); j++ {
+				if sums[j] == id.Hash {
+					return nil
+				}
+			}
+			return ErrLocalIncompatibleOrStale
+		}
+		return ErrLocalIncompatibleOrStale // unreachable, the sentinel is never passed
+	}
+}
+
+// checksumUpdate extends a fork checksum with the next fork block number.
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+// checksumToBytes converts a checksum into its big endian byte representation.
+func checksumToBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
+}
+
+// gatherForks collects the distinct, non-genesis fork block numbers of a chain
+// configuration in ascending order.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+	for _, block := range []*big.Int{
+		config.HomesteadBlock,
+		config.DAOForkBlock,
+		config.EIP150Block,
+		config.EIP155Block,
+		config.EIP158Block,
+		config.MetropolisBlock,
+	} {
+		if block != nil && block.Sign() > 0 {
+			forks = append(forks, block.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	// Deduplicate forks activating at the same block
+	for i := 1; i < len(forks); i++ {
+		if forks[i] == forks[i-1] {
+			forks = append(forks[:i], forks[i+1:]...)
+			i--
+		}
+	}
+	return forks
+}
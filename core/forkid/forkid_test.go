@@ -0,0 +1,114 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"math"
+	"testing"
+
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that fork identifiers are correctly calculated for different phases of
+// the chain.
+func TestCreation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: checksumToBytes(0xfc64ec04), Next: 1150000}},                       // Unsynced
+		{1149999, ID{Hash: checksumToBytes(0xfc64ec04), Next: 1150000}},                 // Last Frontier block
+		{1150000, ID{Hash: checksumToBytes(0x97c2c34c), Next: 1920000}},                 // First Homestead block
+		{1919999, ID{Hash: checksumToBytes(0x97c2c34c), Next: 1920000}},                 // Last Homestead block
+		{1920000, ID{Hash: checksumToBytes(0x91d1f948), Next: 2463000}},                 // First DAO block
+		{2462999, ID{Hash: checksumToBytes(0x91d1f948), Next: 2463000}},                 // Last DAO block
+		{2463000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2675000}},                 // First EIP150 block
+		{2674999, ID{Hash: checksumToBytes(0x7a64da13), Next: 2675000}},                 // Last EIP150 block
+		{2675000, ID{Hash: checksumToBytes(0x3edd5b10), Next: math.MaxInt64}},           // First EIP155/158 block
+		{math.MaxInt64 - 1, ID{Hash: checksumToBytes(0x3edd5b10), Next: math.MaxInt64}}, // Last EIP155/158 block
+	}
+	for i, tt := range tests {
+		if have := NewID(params.MainnetChainConfig, params.MainnetGenesisHash, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that remote fork identifiers are correctly accepted or rejected based
+// on the local chain head.
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local and remote on the same fork, no future fork announced
+		{2675000, ID{Hash: checksumToBytes(0x3edd5b10), Next: 0}, nil},
+
+		// Local and remote on the same fork, same next fork announced
+		{2675000, ID{Hash: checksumToBytes(0x3edd5b10), Next: math.MaxInt64}, nil},
+
+		// Local and remote on the same fork, remote announces a fork we don't know of yet
+		{2463000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2700000}, nil},
+
+		// Local and remote on the same fork, remote announces a fork we already passed
+		// without switching to it, so we are on a different chain
+		{2675000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2600000}, ErrRemoteStale},
+
+		// Local ahead of remote, remote syncing and aware of the next fork
+		{2675000, ID{Hash: checksumToBytes(0x91d1f948), Next: 2463000}, nil},
+
+		// Local ahead of remote, remote syncing but not aware of our next fork
+		{2675000, ID{Hash: checksumToBytes(0x97c2c34c), Next: 0}, ErrRemoteStale},
+
+		// Local behind remote, remote passed forks we know of
+		{1150000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2675000}, nil},
+
+		// Local behind remote, remote at a fork we know of but passed its next
+		{2000000, ID{Hash: checksumToBytes(0x3edd5b10), Next: math.MaxInt64}, nil},
+
+		// Local at a fork the remote claims to have passed already
+		{2675000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2463000}, ErrRemoteStale},
+
+		// Remote on a completely different chain
+		{2675000, ID{Hash: checksumToBytes(0xafec6b27), Next: 0}, ErrLocalIncompatibleOrStale},
+
+		// Local passed the fork the remote announces as upcoming on the same checksum,
+		// meaning we are the ones stuck on an outdated schedule
+		{2500000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2400000}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := NewFilter(params.MainnetChainConfig, params.MainnetGenesisHash, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that forks activating at the same block are only counted once.
+func TestGatherForks(t *testing.T) {
+	forks := gatherForks(params.MainnetChainConfig)
+	want := []uint64{1150000, 1920000, 2463000, 2675000, math.MaxInt64}
+	if len(forks) != len(want) {
+		t.Fatalf("fork count mismatch: have %v, want %v", forks, want)
+	}
+	for i := range forks {
+		if forks[i] != want[i] {
+			t.Errorf("fork %d mismatch: have %d, want %d", i, forks[i], want[i])
+		}
+	}
+}
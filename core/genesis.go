@@ -30,10 +30,12 @@ import (
 	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/core/state"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/trie"
 )
 
 //go:generate gencodec -type Genesis -field-override genesisSpecMarshaling -out gen_genesis.go
@@ -264,10 +266,10 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if _, err := statedb.CommitTo(db, false); err != nil {
 		return nil, fmt.Errorf("cannot write state: %v", err)
 	}
-	if err := WriteTd(db, block.Hash(), block.NumberU64(), g.Difficulty); err != nil {
+	if err := WriteBody(db, block.Hash(), block.NumberU64(), block.Body()); err != nil {
 		return nil, err
 	}
-	if err := WriteBlock(db, block); err != nil {
+	if err := WriteHeaderTd(db, block.Header(), g.Difficulty); err != nil {
 		return nil, err
 	}
 	if err := WriteBlockReceipts(db, block.Hash(), block.NumberU64(), nil); err != nil {
@@ -299,6 +301,100 @@ func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
 	return block
 }
 
+// ReadGenesis reconstructs the genesis specification a database was initialised
+// with from its stored genesis block, chain configuration and genesis state. The
+// result is verified to produce the stored genesis block again.
+func ReadGenesis(db ethdb.Database) (*Genesis, error) {
+	stored := GetCanonicalHash(db, 0)
+	if stored == (common.Hash{}) {
+		return nil, ErrNoGenesis
+	}
+	header := GetHeader(db, stored, 0)
+	if header == nil {
+		return nil, fmt.Errorf("missing genesis header %x", stored)
+	}
+	config, err := GetChainConfig(db, stored)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := readGenesisAlloc(db, header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("can't read genesis state: %v", err)
+	}
+	genesis := &Genesis{
+		Config:     config,
+		Nonce:      header.Nonce.Uint64(),
+		Timestamp:  header.Time.Uint64(),
+		ExtraData:  header.Extra,
+		GasLimit:   header.GasLimit.Uint64(),
+		Difficulty: header.Difficulty,
+		Mixhash:    header.MixDigest,
+		Coinbase:   header.Coinbase,
+		Alloc:      alloc,
+		GasUsed:    header.GasUsed.Uint64(),
+		ParentHash: header.ParentHash,
+	}
+	if block, _ := genesis.ToBlock(); block.Hash() != stored {
+		return nil, fmt.Errorf("reconstructed genesis hash mismatch: have %x, want %x", block.Hash(), stored)
+	}
+	return genesis, nil
+}
+
+// readGenesisAlloc collects all the accounts of the state with the given root,
+// resolving their addresses and storage keys through the stored preimages.
+func readGenesisAlloc(db ethdb.Database, root common.Hash) (GenesisAlloc, error) {
+	accounts, err := trie.NewSecure(root, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	alloc := make(GenesisAlloc)
+
+	it := trie.NewIterator(accounts.NodeIterator(nil))
+	for it.Next() {
+		addr := accounts.GetKey(it.Key)
+		if addr == nil {
+			return nil, fmt.Errorf("missing preimage of account hash %x", it.Key)
+		}
+		var data state.Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return nil, err
+		}
+		account := GenesisAccount{Balance: data.Balance, Nonce: data.Nonce}
+		if codeHash := common.BytesToHash(data.CodeHash); codeHash != crypto.Keccak256Hash(nil) {
+			if account.Code, err = db.Get(codeHash[:]); err != nil {
+				return nil, fmt.Errorf("missing code %x of account %x", codeHash, addr)
+			}
+		}
+		storage, err := trie.NewSecure(data.Root, db, 0)
+		if err != nil {
+			return nil, err
+		}
+		sit := trie.NewIterator(storage.NodeIterator(nil))
+		for sit.Next() {
+			key := storage.GetKey(sit.Key)
+			if key == nil {
+				return nil, fmt.Errorf("missing preimage of storage slot hash %x of account %x", sit.Key, addr)
+			}
+			_, value, _, err := rlp.Split(sit.Value)
+			if err != nil {
+				return nil, err
+			}
+			if account.Storage == nil {
+				account.Storage = make(map[common.Hash]common.Hash)
+			}
+			account.Storage[common.BytesToHash(key)] = common.BytesToHash(value)
+		}
+		if sit.Err != nil {
+			return nil, sit.Err
+		}
+		alloc[common.BytesToAddress(addr)] = account
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	return alloc, nil
+}
+
 // GenesisBlockForTesting creates and writes a block in which addr has the given wei balance.
 func GenesisBlockForTesting(db ethdb.Database, addr common.Address, balance *big.Int) *types.Block {
 	g := Genesis{Alloc: GenesisAlloc{addr: {Balance: balance}}}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -159,3 +160,53 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the genesis specification a database was initialised with can be
+// reconstructed from it and survives a round trip through JSON.
+func TestReadGenesis(t *testing.T) {
+	custom := &Genesis{
+		Config:     &params.ChainConfig{ChainId: big.NewInt(1337), HomesteadBlock: big.NewInt(10)},
+		Nonce:      0x42,
+		Timestamp:  1500000000,
+		ExtraData:  []byte("custom network"),
+		GasLimit:   4712388,
+		Difficulty: big.NewInt(131072),
+		Alloc: GenesisAlloc{
+			common.Address{0x01}: {Balance: big.NewInt(1)},
+			common.Address{0x02}: {
+				Balance: new(big.Int),
+				Nonce:   3,
+				Code:    common.FromHex("600035600055"),
+				Storage: map[common.Hash]common.Hash{{0x01}: {0x02}, {0x03}: common.BigToHash(big.NewInt(4))},
+			},
+		},
+	}
+	for i, spec := range []*Genesis{custom, DefaultTestnetGenesisBlock()} {
+		db, _ := ethdb.NewMemDatabase()
+		want := spec.MustCommit(db).Hash()
+
+		genesis, err := ReadGenesis(db)
+		if err != nil {
+			t.Fatalf("test %d: failed to read genesis: %v", i, err)
+		}
+		blob, err := json.Marshal(genesis)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode genesis: %v", i, err)
+		}
+		decoded := new(Genesis)
+		if err := json.Unmarshal(blob, decoded); err != nil {
+			t.Fatalf("test %d: failed to decode genesis: %v", i, err)
+		}
+		if block, _ := decoded.ToBlock(); block.Hash() != want {
+			t.Errorf("test %d: round-tripped genesis hash mismatch: have %x, want %x", i, block.Hash(), want)
+		}
+		if !reflect.DeepEqual(decoded.Config, spec.Config) {
+			t.Errorf("test %d: chain config mismatch: have %v, want %v", i, decoded.Config, spec.Config)
+		}
+	}
+	// A database without a genesis block must be rejected
+	db, _ := ethdb.NewMemDatabase()
+	if _, err := ReadGenesis(db); err != ErrNoGenesis {
+		t.Errorf("empty database error mismatch: have %v, want %v", err, ErrNoGenesis)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"math"
 	"math/big"
 	mrand "math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/networkchain/networkchain/common"
@@ -34,11 +35,17 @@ import (
 	"github.com/hashicorp/golang-lru"
 )
 
-const (
-	headerCacheLimit = 512
-	tdCacheLimit     = 1024
-	numberCacheLimit = 2048
-)
+const numberCacheLimit = 2048
+
+// HeaderCacheLimit is the number of recent block headers kept in memory by the
+// header chain, with twice as many total difficulties cached alongside. It is
+// tuned independently of the state caches as header verification and light
+// client serving mostly hit headers only.
+var HeaderCacheLimit = 512
+
+// cappedCacheLimit is the number of entries the in-memory caches of a chain are
+// trimmed to while capped under memory pressure.
+const cappedCacheLimit = 16
 
 // HeaderChain implements the basic block header chain logic that is shared by
 // core.BlockChain and light.LightChain. It is not usable in itself, only as
@@ -57,6 +64,7 @@ type HeaderChain struct {
 	headerCache *lru.Cache // Cache for the most recent block headers
 	tdCache     *lru.Cache // Cache for the most recent block total difficulties
 	numberCache *lru.Cache // Cache for the most recent block numbers
+	capped      int32      // Whether the caches are capped under memory pressure (atomic)
 
 	procInterrupt func() bool
 
@@ -69,8 +77,8 @@ type HeaderChain struct {
 //  procInterrupt points to the parent's interrupt semaphore
 //  wg points to the parent's shutdown wait group
 func NewHeaderChain(chainDb ethdb.Database, config *params.ChainConfig, engine consensus.Engine, procInterrupt func() bool) (*HeaderChain, error) {
-	headerCache, _ := lru.New(headerCacheLimit)
-	tdCache, _ := lru.New(tdCacheLimit)
+	headerCache, _ := lru.New(HeaderCacheLimit)
+	tdCache, _ := lru.New(2 * HeaderCacheLimit)
 	numberCache, _ := lru.New(numberCacheLimit)
 
 	// Seed a fast but crypto originating random generator
@@ -114,7 +122,7 @@ func (hc *HeaderChain) GetBlockNumber(hash common.Hash) uint64 {
 	}
 	number := GetBlockNumber(hc.chainDb, hash)
 	if number != missingNumber {
-		hc.numberCache.Add(hash, number)
+		hc.cache(hc.numberCache, hash, number)
 	}
 	return number
 }
@@ -143,11 +151,8 @@ func (hc *HeaderChain) WriteHeader(header *types.Header) (status WriteStatus, er
 	externTd := new(big.Int).Add(header.Difficulty, ptd)
 
 	// Irrelevant of the canonical status, write the td and header to the database
-	if err := hc.WriteTd(hash, number, externTd); err != nil {
-		log.Crit("Failed to write header total difficulty", "err", err)
-	}
-	if err := WriteHeader(hc.chainDb, header); err != nil {
-		log.Crit("Failed to write header content", "err", err)
+	if err := hc.WriteHeaderTd(header, externTd); err != nil {
+		log.Crit("Failed to write header and total difficulty", "err", err)
 	}
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -188,9 +193,6 @@ func (hc *HeaderChain) WriteHeader(header *types.Header) (status WriteStatus, er
 		status = SideStatTy
 	}
 
-	hc.headerCache.Add(hash, header)
-	hc.numberCache.Add(hash, number)
-
 	return
 }
 
@@ -314,12 +316,7 @@ func (hc *HeaderChain) GetTd(hash common.Hash, number uint64) *big.Int {
 	if cached, ok := hc.tdCache.Get(hash); ok {
 		return cached.(*big.Int)
 	}
-	td := GetTd(hc.chainDb, hash, number)
-	if td == nil {
-		return nil
-	}
-	// Cache the found body for next time and return
-	hc.tdCache.Add(hash, td)
+	_, td := hc.loadHeaderTd(hash, number)
 	return td
 }
 
@@ -335,7 +332,7 @@ func (hc *HeaderChain) WriteTd(hash common.Hash, number uint64, td *big.Int) err
 	if err := WriteTd(hc.chainDb, hash, number, td); err != nil {
 		return err
 	}
-	hc.tdCache.Add(hash, new(big.Int).Set(td))
+	hc.cache(hc.tdCache, hash, new(big.Int).Set(td))
 	return nil
 }
 
@@ -346,15 +343,38 @@ func (hc *HeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header
 	if header, ok := hc.headerCache.Get(hash); ok {
 		return header.(*types.Header)
 	}
-	header := GetHeader(hc.chainDb, hash, number)
-	if header == nil {
-		return nil
-	}
-	// Cache the found header for next time and return
-	hc.headerCache.Add(hash, header)
+	header, _ := hc.loadHeaderTd(hash, number)
 	return header
 }
 
+// loadHeaderTd retrieves a block header and its total difficulty from the
+// database in one go, caching whichever of them is found. Header verification
+// almost always needs both the parent header and its td, so fetching them
+// together saves a database read for every block in the compact layout.
+func (hc *HeaderChain) loadHeaderTd(hash common.Hash, number uint64) (*types.Header, *big.Int) {
+	header, td := GetHeaderTd(hc.chainDb, hash, number)
+	if header != nil {
+		hc.cache(hc.headerCache, hash, header)
+	}
+	if td != nil {
+		hc.cache(hc.tdCache, hash, td)
+	}
+	return header, td
+}
+
+// WriteHeaderTd stores a block header together with its total difficulty into
+// the database, also caching both along the way.
+func (hc *HeaderChain) WriteHeaderTd(header *types.Header, td *big.Int) error {
+	if err := WriteHeaderTd(hc.chainDb, header, td); err != nil {
+		return err
+	}
+	hash := header.Hash()
+	hc.cache(hc.headerCache, hash, header)
+	hc.cache(hc.tdCache, hash, new(big.Int).Set(td))
+	hc.cache(hc.numberCache, hash, header.Number.Uint64())
+	return nil
+}
+
 // GetHeaderByHash retrieves a block header from the database by hash, caching it if
 // found.
 func (hc *HeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
@@ -433,6 +453,49 @@ func (hc *HeaderChain) SetHead(head uint64, delFn DeleteCallback) {
 	}
 }
 
+// PurgeCaches drops all the headers, total difficulties and block numbers cached
+// in memory, e.g. to relieve memory pressure.
+func (hc *HeaderChain) PurgeCaches() {
+	hc.headerCache.Purge()
+	hc.tdCache.Purge()
+	hc.numberCache.Purge()
+}
+
+// CapCaches limits (or lifts the limit on) the number of headers, total
+// difficulties and block numbers cached in memory, e.g. to keep them from
+// refilling while the node is under memory pressure. Capping also drops the
+// current contents of the caches.
+func (hc *HeaderChain) CapCaches(capped bool) {
+	if !capped {
+		atomic.StoreInt32(&hc.capped, 0)
+		return
+	}
+	atomic.StoreInt32(&hc.capped, 1)
+	hc.PurgeCaches()
+}
+
+// Capped returns whether the caches of the chain are capped.
+func (hc *HeaderChain) Capped() bool {
+	return atomic.LoadInt32(&hc.capped) == 1
+}
+
+// cache adds an entry to one of the caches of the chain, evicting the oldest
+// entries beyond cappedCacheLimit while the caches are capped.
+func (hc *HeaderChain) cache(cache *lru.Cache, key, value interface{}) {
+	cacheCapped(cache, hc.Capped(), key, value)
+}
+
+// cacheCapped adds an entry to an LRU cache, evicting the oldest entries beyond
+// cappedCacheLimit if the cache is capped.
+func cacheCapped(cache *lru.Cache, capped bool, key, value interface{}) {
+	cache.Add(key, value)
+	if capped {
+		for cache.Len() > cappedCacheLimit {
+			cache.RemoveOldest()
+		}
+	}
+}
+
 // SetGenesis sets a new genesis block header for the chain
 func (hc *HeaderChain) SetGenesis(head *types.Header) {
 	hc.genesisHeader = head
@@ -0,0 +1,187 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/metrics"
+)
+
+var (
+	speculativeTxMeter = metrics.NewMeter("chain/parallel/speculative")
+	reexecutedTxMeter  = metrics.NewMeter("chain/parallel/reexecuted")
+)
+
+// speculation is the outcome of executing a transaction on the parent state of
+// its block, disregarding all the transactions preceding it.
+type speculation struct {
+	statedb *state.StateDB  // Private state the transaction was executed on
+	access  *state.TxAccess // Accounts and slots accessed during execution
+	msg     types.Message   // Message derived from the transaction
+	gas     *big.Int        // Gas used by the transaction
+	credit  *big.Int        // Amount credited to the coinbase
+	err     error           // Execution failure, if any
+
+	done chan struct{} // Closed when the execution finished
+}
+
+// applyParallel executes the transactions of a block concurrently, each on its
+// own copy of the parent state, and merges their changes into statedb in block
+// order. Merging is only done if none of the state accessed by a transaction was
+// modified by the ones preceding it, otherwise the transaction is re-executed
+// serially on the up to date state.
+func (p *StateProcessor) applyParallel(block *types.Block, statedb *state.StateDB, gp *GasPool, usedGas *big.Int, cfg vm.Config) (types.Receipts, error) {
+	var (
+		txs    = block.Transactions()
+		header = block.Header()
+		specs  = make([]*speculation, len(txs))
+		tasks  = make(chan int, len(txs))
+		abort  = make(chan struct{})
+		wg     sync.WaitGroup
+	)
+	parent := p.bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	for i := range txs {
+		specs[i] = &speculation{done: make(chan struct{})}
+		tasks <- i
+	}
+	close(tasks)
+
+	// Start the workers executing the transactions on the parent state
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				select {
+				case <-abort:
+					return
+				default:
+				}
+				p.speculate(specs[i], parent.Root, block, txs[i], i, cfg)
+				close(specs[i].done)
+			}
+		}()
+	}
+	defer func() {
+		close(abort)
+		wg.Wait()
+	}()
+
+	// Merge the results in block order, re-executing the conflicting transactions
+	statedb.EnableAccessTracking()
+
+	var (
+		receipts   = make(types.Receipts, 0, len(txs))
+		reexecuted int
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		spec := specs[i]
+		<-spec.done
+
+		var receipt *types.Receipt
+		if spec.err == nil && spec.valid(statedb.AccessSets()[:i], header.Coinbase) {
+			if err := statedb.Merge(spec.statedb, spec.access, header.Coinbase); err == nil {
+				if err := gp.SubGas(spec.msg.Gas()); err != nil {
+					return nil, err
+				}
+				gp.AddGas(new(big.Int).Sub(spec.msg.Gas(), spec.gas))
+				statedb.AddBalance(header.Coinbase, spec.credit)
+
+				receipt = newReceipt(p.config, statedb, header, tx, spec.msg, spec.gas, usedGas)
+			}
+		}
+		if receipt == nil {
+			var err error
+			if receipt, _, err = ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg); err != nil {
+				return nil, err
+			}
+			reexecuted++
+		}
+		receipts = append(receipts, receipt)
+		spec.statedb = nil // Release the private state early
+	}
+	speculativeTxMeter.Mark(int64(len(txs) - reexecuted))
+	reexecutedTxMeter.Mark(int64(reexecuted))
+
+	log.Debug("Executed transactions in parallel", "number", block.Number(), "hash", block.Hash(), "txs", len(txs), "reexecuted", reexecuted)
+	return receipts, nil
+}
+
+// speculate executes a transaction on a private copy of the parent state with
+// access tracking enabled.
+func (p *StateProcessor) speculate(spec *speculation, root common.Hash, block *types.Block, tx *types.Transaction, index int, cfg vm.Config) {
+	header := block.Header()
+
+	statedb, err := state.NewWithSnapshot(root, p.bc.stateCache, p.bc.snaps)
+	if err != nil {
+		spec.err = err
+		return
+	}
+	spec.msg, err = tx.AsMessage(types.MakeSigner(p.config, header.Number))
+	if err != nil {
+		spec.err = err
+		return
+	}
+	balance := new(big.Int).Set(statedb.GetBalance(header.Coinbase))
+
+	statedb.EnableAccessTracking()
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+
+	vmenv := vm.NewEVM(NewEVMContext(spec.msg, header, p.bc, nil), statedb, p.config, cfg)
+	if _, spec.gas, spec.err = ApplyMessage(vmenv, spec.msg, new(GasPool).AddGas(header.GasLimit)); spec.err != nil {
+		return
+	}
+	statedb.StopAccessTracking()
+
+	spec.statedb = statedb
+	spec.access = statedb.AccessSets()[0]
+	spec.credit = new(big.Int).Sub(statedb.GetBalance(header.Coinbase), balance)
+}
+
+// valid reports whether the speculative execution of a transaction is consistent
+// with executing it after the given preceding ones. This is the case if it didn't
+// access any state they modified. The coinbase is exempt as long as it wasn't
+// read, since crediting it is commutative.
+func (spec *speculation) valid(preceding []*state.TxAccess, coinbase common.Address) bool {
+	if _, ok := spec.access.Reads.Accounts[coinbase]; ok {
+		return false
+	}
+	for _, prev := range preceding {
+		if spec.access.DependsOn(prev, coinbase) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,267 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// Tests that blocks mixing independent and conflicting transactions are imported
+// identically with speculative parallel execution as with serial one.
+func TestParallelExecution(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 8)
+		addrs = make([]common.Address, len(keys))
+		funds = big.NewInt(1000000000)
+
+		counter = common.Address{0xc0} // Increments slot 0 and emits a log on every call
+		empty   = common.Address{0xee} // Touched with zero value transfers
+
+		gspec = &Genesis{
+			Config: &params.ChainConfig{
+				ChainId:        big.NewInt(1),
+				HomesteadBlock: new(big.Int),
+				EIP155Block:    new(big.Int),
+				EIP158Block:    big.NewInt(3),
+			},
+			Alloc: GenesisAlloc{
+				counter: {Balance: new(big.Int), Code: common.FromHex("60005460010160005560006000a0")},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		gspec.Alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 6, func(i int, block *BlockGen) {
+		send := func(key int, to common.Address, value int64, gas int64) {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addrs[key]), to, big.NewInt(value), big.NewInt(gas), big.NewInt(1), nil), signer, keys[key])
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(tx)
+		}
+		// Make one of the senders collect the fees, forcing it to be read
+		if i == 2 {
+			block.SetCoinbase(addrs[4])
+		}
+		// Independent transfers to fresh accounts
+		for key := 0; key < 6; key++ {
+			send(key, common.Address{byte(i + 1), byte(key + 1)}, 1000, 21000)
+		}
+		send(0, addrs[1], 1000, 21000)   // Same sender as an earlier transaction
+		send(6, addrs[7], 100000, 21000) // Funds spent by the next transaction
+		send(7, addrs[6], 1000, 21000)
+		send(1, counter, 0, 100000) // Same storage slot updated twice
+		send(2, counter, 0, 100000)
+		send(3, empty, 0, 21000) // Touches an empty account, deleted after EIP158
+	})
+	// Import the chain both serially and in parallel
+	for _, parallel := range []bool{false, true} {
+		db, _ := ethdb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		config := vm.Config{ParallelExecution: parallel, TrackStateAccess: true}
+		chain, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), config)
+		if err != nil {
+			t.Fatalf("parallel %v: failed to create chain: %v", parallel, err)
+		}
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("parallel %v: failed to insert block %d: %v", parallel, n, err)
+		}
+		for _, block := range blocks {
+			stats := chain.AccessStats(block.Hash())
+			if stats == nil {
+				t.Fatalf("parallel %v: missing access stats for block %d", parallel, block.NumberU64())
+			}
+			if stats.Txs != len(block.Transactions()) || stats.Conflicting == 0 {
+				t.Errorf("parallel %v: block %d: unexpected access stats: %+v", parallel, block.NumberU64(), stats)
+			}
+		}
+		state, _ := chain.State()
+		if have := state.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(12)) {
+			t.Errorf("parallel %v: counter mismatch: have %x, want %x", parallel, have, 12)
+		}
+		chain.Stop()
+	}
+}
+
+// Tests that contract creations, self destructs and failed calls touching empty
+// accounts (including the RIPEMD precompile, whose touch survives reverts) are
+// imported identically in parallel as serially across the EIP158 and Metropolis
+// transitions, down to the receipts of every transaction.
+func TestParallelExecutionForks(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 8)
+		addrs = make([]common.Address, len(keys))
+		funds = big.NewInt(1000000000)
+
+		empty  = common.Address{0xee}                     // Touched by successful calls
+		kept   = common.Address{0xef}                     // Created before EIP158, later only touched by failed calls
+		ripemd = common.BytesToAddress([]byte{3})         // Precompile touched by successful and failed calls
+		killer = common.Address{0xdd}                     // Self destructs to the caller
+		thrown = common.Address{0xf0}                     // Calls the kept empty account, then throws
+		rthrow = common.Address{0xf3}                     // Calls the RIPEMD precompile, then throws
+		deploy = common.FromHex("6133ff6000526002601ef3") // Deploys a contract self destructing to the caller
+
+		gspec = &Genesis{
+			Config: &params.ChainConfig{
+				ChainId:         big.NewInt(1),
+				HomesteadBlock:  new(big.Int),
+				EIP150Block:     new(big.Int),
+				EIP155Block:     new(big.Int),
+				EIP158Block:     big.NewInt(3),
+				MetropolisBlock: big.NewInt(5),
+			},
+			Alloc: GenesisAlloc{
+				killer: {Balance: big.NewInt(1000), Code: common.FromHex("33ff")},
+				thrown: {Balance: new(big.Int), Code: common.FromHex("600060006000600060007300000000000000000000000000000000000000ef61fffff1fe")},
+				rthrow: {Balance: new(big.Int), Code: common.FromHex("600060006000600060007300000000000000000000000000000000000000036161a8f1fe")},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		gspec.Alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, receipts := GenerateChain(gspec.Config, genesis, db, 6, func(i int, block *BlockGen) {
+		send := func(key int, to *common.Address, value int64, gas int64, data []byte) common.Address {
+			var (
+				nonce = block.TxNonce(addrs[key])
+				tx    *types.Transaction
+			)
+			if to == nil {
+				tx = types.NewContractCreation(nonce, big.NewInt(value), big.NewInt(gas), big.NewInt(1), data)
+			} else {
+				tx = types.NewTransaction(nonce, *to, big.NewInt(value), big.NewInt(gas), big.NewInt(1), data)
+			}
+			tx, err := types.SignTx(tx, signer, keys[key])
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(tx)
+			return crypto.CreateAddress(addrs[key], nonce)
+		}
+		// Constructor self destructing to a fresh beneficiary
+		beneficiary := common.Address{0xbe, byte(i)}
+		send(0, nil, 1000, 100000, append(append([]byte{0x73}, beneficiary[:]...), 0xff))
+
+		// Contract deployed and destroyed by the next transaction
+		created := send(1, nil, 500, 100000, deploy)
+		send(2, &created, 0, 100000, nil)
+
+		// Independent contract deployment
+		send(3, nil, 0, 100000, deploy)
+
+		// Failed calls touching the empty account and the RIPEMD precompile
+		send(4, &thrown, 0, 100000, nil)
+		send(5, &rthrow, 0, 100000, nil)
+
+		// Successful calls touching the empty accounts and the RIPEMD precompile
+		send(6, &empty, 0, 21000, nil)
+		send(7, &ripemd, 0, 100000, nil)
+		if i < 2 {
+			send(6, &kept, 0, 21000, nil)
+		}
+
+		// Self destruct of a genesis contract, called again once gone
+		if i == 1 || i == 4 {
+			send(7, &killer, 0, 100000, nil)
+		}
+	})
+	// Import the chain both serially and in parallel, comparing the receipts with
+	// the ones of the serial generation
+	for _, parallel := range []bool{false, true} {
+		db, _ := ethdb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		config := vm.Config{ParallelExecution: parallel, TrackStateAccess: true}
+		chain, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), config)
+		if err != nil {
+			t.Fatalf("parallel %v: failed to create chain: %v", parallel, err)
+		}
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("parallel %v: failed to insert block %d: %v", parallel, n, err)
+		}
+		for i, block := range blocks {
+			if root := chain.GetBlockByHash(block.Hash()).Root(); root != block.Root() {
+				t.Errorf("parallel %v: block %d: root mismatch: have %x, want %x", parallel, block.NumberU64(), root, block.Root())
+			}
+			have := GetBlockReceipts(db, block.Hash(), block.NumberU64())
+			if err := compareReceipts(have, receipts[i]); err != nil {
+				t.Errorf("parallel %v: block %d: %v", parallel, block.NumberU64(), err)
+			}
+			if stats := chain.AccessStats(block.Hash()); parallel && stats.Conflicting == stats.Txs {
+				t.Errorf("parallel %v: block %d: no transaction merged speculatively: %+v", parallel, block.NumberU64(), stats)
+			}
+		}
+		state, _ := chain.State()
+		if state.Exist(killer) {
+			t.Errorf("parallel %v: self destructed contract exists", parallel)
+		}
+		if state.Exist(ripemd) || state.Exist(empty) {
+			t.Errorf("parallel %v: touched empty accounts not deleted after EIP158", parallel)
+		}
+		if !state.Exist(kept) {
+			t.Errorf("parallel %v: empty account deleted by reverted touches", parallel)
+		}
+		chain.Stop()
+	}
+}
+
+// compareReceipts checks that the consensus and the derived fields of two lists
+// of receipts are equal.
+func compareReceipts(have, want types.Receipts) error {
+	if len(have) != len(want) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		haveRLP, _ := rlp.EncodeToBytes(have[i])
+		wantRLP, _ := rlp.EncodeToBytes(want[i])
+		if !bytes.Equal(haveRLP, wantRLP) {
+			return fmt.Errorf("receipt %d: consensus fields mismatch: have %x, want %x", i, haveRLP, wantRLP)
+		}
+		if have[i].TxHash != want[i].TxHash || have[i].ContractAddress != want[i].ContractAddress || have[i].GasUsed.Cmp(want[i].GasUsed) != 0 {
+			return fmt.Errorf("receipt %d: derived fields mismatch: have %v, want %v", i, have[i], want[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/networkchain/networkchain/common"
+)
+
+// errAccountRecreated is returned when merging the changes of a transaction that
+// overwrote an existing account by a contract creation, which can't be replayed
+// from its access set alone.
+var errAccountRecreated = errors.New("existing account recreated")
+
+// AccessSet is a collection of accounts and storage slots touched during the
+// execution of a transaction.
+type AccessSet struct {
+	Accounts map[common.Address]struct{}                 // Accounts whose balance, nonce or code was accessed
+	Slots    map[common.Address]map[common.Hash]struct{} // Storage slots accessed, grouped by account
+}
+
+// newAccessSet creates an empty access set.
+func newAccessSet() *AccessSet {
+	return &AccessSet{
+		Accounts: make(map[common.Address]struct{}),
+		Slots:    make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// addAccount marks an account as accessed.
+func (s *AccessSet) addAccount(addr common.Address) {
+	s.Accounts[addr] = struct{}{}
+}
+
+// addSlot marks a storage slot of an account as accessed.
+func (s *AccessSet) addSlot(addr common.Address, key common.Hash) {
+	slots, ok := s.Slots[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		s.Slots[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+// merge adds all the accounts and storage slots of another set to this one.
+func (s *AccessSet) merge(other *AccessSet) {
+	for addr := range other.Accounts {
+		s.addAccount(addr)
+	}
+	for addr, slots := range other.Slots {
+		for key := range slots {
+			s.addSlot(addr, key)
+		}
+	}
+}
+
+// Len returns the number of accounts and storage slots in the set.
+func (s *AccessSet) Len() int {
+	size := len(s.Accounts)
+	for _, slots := range s.Slots {
+		size += len(slots)
+	}
+	return size
+}
+
+// Overlaps reports whether the two sets have any account or storage slot in
+// common, disregarding the given account (e.g. the coinbase collecting fees).
+func (s *AccessSet) Overlaps(other *AccessSet, ignore common.Address) bool {
+	for addr := range s.Accounts {
+		if _, ok := other.Accounts[addr]; ok && addr != ignore {
+			return true
+		}
+	}
+	for addr, slots := range s.Slots {
+		theirs, ok := other.Slots[addr]
+		if !ok {
+			continue
+		}
+		for key := range slots {
+			if _, ok := theirs[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TxAccess contains the state read and written while executing a transaction.
+type TxAccess struct {
+	TxHash common.Hash // Hash of the transaction
+	Reads  *AccessSet  // Accounts and slots read by the transaction
+	Writes *AccessSet  // Accounts and slots modified by the transaction
+
+	recreated bool // Whether an existing account was overwritten by a contract creation
+}
+
+// DependsOn reports whether the transaction read or overwrote any state written
+// by an earlier transaction, disregarding the given account.
+func (a *TxAccess) DependsOn(earlier *TxAccess, ignore common.Address) bool {
+	return a.Reads.Overlaps(earlier.Writes, ignore) || a.Writes.Overlaps(earlier.Writes, ignore)
+}
+
+// EnableAccessTracking starts recording the read and write sets of the executed
+// transactions, a new set being started by each call to Prepare.
+func (self *StateDB) EnableAccessTracking() {
+	self.accessTracking = true
+	self.accesses = nil
+}
+
+// StopAccessTracking stops recording state accesses, retaining the sets recorded
+// so far. It is used to exclude block finalisation from the last transaction.
+func (self *StateDB) StopAccessTracking() {
+	self.accessTracking = false
+}
+
+// AccessSets returns the read and write sets recorded for each transaction since
+// access tracking was enabled.
+func (self *StateDB) AccessSets() []*TxAccess {
+	return self.accesses
+}
+
+// currentAccess returns the access record of the transaction being executed, or
+// nil if access tracking is disabled.
+func (self *StateDB) currentAccess() *TxAccess {
+	if !self.accessTracking || len(self.accesses) == 0 {
+		return nil
+	}
+	return self.accesses[len(self.accesses)-1]
+}
+
+// recordRead marks an account as read by the current transaction.
+func (self *StateDB) recordRead(addr common.Address) {
+	if access := self.currentAccess(); access != nil {
+		access.Reads.addAccount(addr)
+	}
+}
+
+// recordWrite marks an account as modified by the current transaction.
+func (self *StateDB) recordWrite(addr common.Address) {
+	if access := self.currentAccess(); access != nil {
+		access.Writes.addAccount(addr)
+	}
+}
+
+// recordRecreate marks that the current transaction overwrote an existing account.
+func (self *StateDB) recordRecreate() {
+	if access := self.currentAccess(); access != nil {
+		access.recreated = true
+	}
+}
+
+// recordSlotRead marks a storage slot as read by the current transaction.
+func (self *StateDB) recordSlotRead(addr common.Address, key common.Hash) {
+	if access := self.currentAccess(); access != nil {
+		access.Reads.addSlot(addr, key)
+	}
+}
+
+// recordSlotWrite marks a storage slot as modified by the current transaction.
+func (self *StateDB) recordSlotWrite(addr common.Address, key common.Hash) {
+	if access := self.currentAccess(); access != nil {
+		access.Writes.addSlot(addr, key)
+	}
+}
+
+// Merge applies the changes made by a transaction executed on a different state
+// to this one, using the access set recorded during its execution. The source
+// state must have been derived from the same root as this one, and the accessed
+// accounts and slots must not have been modified since. Changes to the skipped
+// account are ignored, allowing fees credited to the coinbase to be applied by
+// the caller instead.
+//
+// If access tracking is enabled, the recorded reads and writes of the merged
+// transaction are attributed to the current one.
+func (self *StateDB) Merge(src *StateDB, access *TxAccess, skip common.Address) error {
+	if access.recreated {
+		return errAccountRecreated
+	}
+	for addr := range access.Writes.Accounts {
+		// Only replay accounts left dirty, as reverted touches of empty accounts
+		// must not be marked for deletion
+		if _, dirty := src.stateObjectsDirty[addr]; !dirty || addr == skip {
+			continue
+		}
+		obj := src.getStateObject(addr)
+		if obj == nil {
+			continue
+		}
+		self.SetBalance(addr, new(big.Int).Set(obj.Balance()))
+		self.SetNonce(addr, obj.Nonce())
+		if !bytes.Equal(self.getStateObject(addr).CodeHash(), obj.CodeHash()) {
+			self.SetCode(addr, obj.Code(src.db))
+		}
+		if obj.suicided {
+			self.Suicide(addr)
+		}
+	}
+	for addr, slots := range access.Writes.Slots {
+		obj := src.getStateObject(addr)
+		if obj == nil {
+			continue
+		}
+		for key := range slots {
+			self.SetState(addr, key, obj.GetState(src.db, key))
+		}
+	}
+	for _, log := range src.GetLogs(access.TxHash) {
+		cpy := *log
+		self.AddLog(&cpy)
+	}
+	for hash, preimage := range src.Preimages() {
+		self.AddPreimage(hash, preimage)
+	}
+	if current := self.currentAccess(); current != nil {
+		current.Reads.merge(access.Reads)
+	}
+	return nil
+}
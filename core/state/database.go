@@ -19,6 +19,7 @@ package state
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/ethdb"
@@ -26,8 +27,21 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 )
 
-// Trie cache generation limit after which to evic trie nodes from memory.
-var MaxTrieCacheGen = uint16(120)
+// Trie cache generation limit after which to evict trie nodes from memory. It is
+// accessed atomically as it may be retuned while tries are being opened.
+var maxTrieCacheGen = uint32(120)
+
+// MaxTrieCacheGen retrieves the trie cache generation limit after which trie
+// nodes are evicted from memory.
+func MaxTrieCacheGen() uint16 {
+	return uint16(atomic.LoadUint32(&maxTrieCacheGen))
+}
+
+// SetMaxTrieCacheGen sets the trie cache generation limit after which trie nodes
+// are evicted from memory. Only tries opened afterwards are affected.
+func SetMaxTrieCacheGen(gen uint16) {
+	atomic.StoreUint32(&maxTrieCacheGen, uint32(gen))
+}
 
 const (
 	// Number of past tries to keep. This value is chosen such that
@@ -36,6 +50,9 @@ const (
 
 	// Number of codehash->size associations to keep.
 	codeSizeCacheSize = 100000
+
+	// Number of codehash->size associations to keep while capped.
+	cappedCodeSizeCacheSize = 1000
 )
 
 // Database wraps access to tries and contract code.
@@ -75,6 +92,7 @@ type cachingDB struct {
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
+	capped        int32 // Whether the caches are capped under memory pressure (atomic)
 }
 
 func (db *cachingDB) OpenTrie(root common.Hash) (Trie, error) {
@@ -86,7 +104,7 @@ func (db *cachingDB) OpenTrie(root common.Hash) (Trie, error) {
 			return cachedTrie{db.pastTries[i].Copy(), db}, nil
 		}
 	}
-	tr, err := trie.NewSecure(root, db.db, MaxTrieCacheGen)
+	tr, err := trie.NewSecure(root, db.db, MaxTrieCacheGen())
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +115,10 @@ func (db *cachingDB) pushTrie(t *trie.SecureTrie) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	if atomic.LoadInt32(&db.capped) == 1 {
+		db.pastTries = nil
+		return
+	}
 	if len(db.pastTries) >= maxPastTries {
 		copy(db.pastTries, db.pastTries[1:])
 		db.pastTries[len(db.pastTries)-1] = t
@@ -105,6 +127,39 @@ func (db *cachingDB) pushTrie(t *trie.SecureTrie) {
 	}
 }
 
+// Purge drops all the past tries and contract code sizes retained in memory, e.g.
+// to relieve memory pressure. Subsequent accesses are served from the database.
+func (db *cachingDB) Purge() {
+	db.mu.Lock()
+	db.pastTries = nil
+	db.mu.Unlock()
+
+	db.codeSizeCache.Purge()
+}
+
+// Cap stops (or resumes) retaining past tries in memory and limits the number of
+// contract code sizes cached, e.g. to keep the caches from refilling while under
+// memory pressure. Capping also drops the current contents of the caches.
+func (db *cachingDB) Cap(capped bool) {
+	if !capped {
+		atomic.StoreInt32(&db.capped, 0)
+		return
+	}
+	atomic.StoreInt32(&db.capped, 1)
+	db.Purge()
+}
+
+// cacheCodeSize caches the size of a contract code, evicting the oldest entries
+// beyond cappedCodeSizeCacheSize while the caches are capped.
+func (db *cachingDB) cacheCodeSize(codeHash common.Hash, size int) {
+	db.codeSizeCache.Add(codeHash, size)
+	if atomic.LoadInt32(&db.capped) == 1 {
+		for db.codeSizeCache.Len() > cappedCodeSizeCacheSize {
+			db.codeSizeCache.RemoveOldest()
+		}
+	}
+}
+
 func (db *cachingDB) OpenStorageTrie(addrHash, root common.Hash) (Trie, error) {
 	return trie.NewSecure(root, db.db, 0)
 }
@@ -123,7 +178,7 @@ func (db *cachingDB) CopyTrie(t Trie) Trie {
 func (db *cachingDB) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
 	code, err := db.db.Get(codeHash[:])
 	if err == nil {
-		db.codeSizeCache.Add(codeHash, len(code))
+		db.cacheCodeSize(codeHash, len(code))
 	}
 	return code, err
 }
@@ -134,7 +189,7 @@ func (db *cachingDB) ContractCodeSize(addrHash, codeHash common.Hash) (int, erro
 	}
 	code, err := db.ContractCode(addrHash, codeHash)
 	if err == nil {
-		db.codeSizeCache.Add(codeHash, len(code))
+		db.cacheCodeSize(codeHash, len(code))
 	}
 	return len(code), err
 }
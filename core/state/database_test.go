@@ -0,0 +1,87 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+)
+
+// Tests that capping the state caches stops retaining past tries and keeps the
+// contract code sizes cached below the capped limit until lifted again.
+func TestCachingDBCap(t *testing.T) {
+	mem, _ := ethdb.NewMemDatabase()
+	db := NewDatabase(mem).(*cachingDB)
+
+	// Store a batch of contract codes to retrieve the sizes of
+	codes := make([]common.Hash, 2*cappedCodeSizeCacheSize)
+	for i := range codes {
+		code := make([]byte, 8)
+		binary.BigEndian.PutUint64(code, uint64(i))
+		codes[i] = crypto.Keccak256Hash(code)
+		mem.Put(codes[i][:], code)
+	}
+	// commit opens and commits a trie, pushing it into the past tries
+	commit := func(i int) {
+		tr, err := db.OpenTrie(common.Hash{})
+		if err != nil {
+			t.Fatalf("failed to open trie: %v", err)
+		}
+		tr.TryUpdate([]byte{byte(i)}, []byte{byte(i)})
+		if _, err := tr.CommitTo(mem); err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+	}
+	commit(0)
+	db.ContractCodeSize(common.Hash{}, codes[0])
+
+	db.Cap(true)
+	if len(db.pastTries) != 0 || db.codeSizeCache.Len() != 0 {
+		t.Fatalf("caches not purged when capped: %d past tries, %d code sizes", len(db.pastTries), db.codeSizeCache.Len())
+	}
+	for i := 0; i < 4; i++ {
+		commit(i)
+	}
+	for _, hash := range codes {
+		if size, err := db.ContractCodeSize(common.Hash{}, hash); err != nil || size != 8 {
+			t.Fatalf("code size mismatch: have %d/%v, want 8/nil", size, err)
+		}
+	}
+	if len(db.pastTries) != 0 {
+		t.Errorf("past tries retained while capped: have %d, want 0", len(db.pastTries))
+	}
+	if db.codeSizeCache.Len() > cappedCodeSizeCacheSize {
+		t.Errorf("capped code size count exceeded: have %d, want at most %d", db.codeSizeCache.Len(), cappedCodeSizeCacheSize)
+	}
+	db.Cap(false)
+	for i := 0; i < 4; i++ {
+		commit(i)
+	}
+	for _, hash := range codes {
+		db.ContractCodeSize(common.Hash{}, hash)
+	}
+	if len(db.pastTries) != 4 {
+		t.Errorf("past tries not retained after lifting the cap: have %d, want 4", len(db.pastTries))
+	}
+	if db.codeSizeCache.Len() != len(codes) {
+		t.Errorf("code sizes not cached after lifting the cap: have %d, want %d", db.codeSizeCache.Len(), len(codes))
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/trie"
+)
+
+// emptyRoot is the known root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// MarkState adds the hashes of all the trie nodes and contract codes making up the
+// state with the given root to the marked set. Subtries already in the set are not
+// walked again, so successive calls over related states only visit the parts they
+// don't share. An error is returned if any of the data is missing.
+func MarkState(db ethdb.Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	return markTrie(db, root, marked, func(blob []byte) error {
+		var account Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return err
+		}
+		if account.Root != emptyRoot {
+			if err := MarkTrie(db, account.Root, marked); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+			hash := common.BytesToHash(account.CodeHash)
+			if _, ok := marked[hash]; !ok {
+				if _, err := db.Get(hash[:]); err != nil {
+					return fmt.Errorf("missing code %x", hash)
+				}
+				marked[hash] = struct{}{}
+			}
+		}
+		return nil
+	})
+}
+
+// MarkTrie adds the hashes of all the nodes of the plain trie with the given root
+// to the marked set, skipping subtries already in it.
+func MarkTrie(db ethdb.Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	return markTrie(db, root, marked, nil)
+}
+
+// markTrie walks the nodes of a trie not yet in the marked set, adding them to it
+// and invoking the callback, if any, on all the leaves encountered.
+func markTrie(db ethdb.Database, root common.Hash, marked map[common.Hash]struct{}, onLeaf func([]byte) error) error {
+	if root == emptyRoot || root == (common.Hash{}) {
+		return nil
+	}
+	if _, ok := marked[root]; ok {
+		return nil
+	}
+	t, err := trie.New(root, db)
+	if err != nil {
+		return err
+	}
+	it, descend := t.NodeIterator(nil), true
+	for it.Next(descend) {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := marked[hash]; ok {
+				descend = false
+				continue
+			}
+			marked[hash] = struct{}{}
+		}
+		if it.Leaf() && onLeaf != nil {
+			if err := onLeaf(it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
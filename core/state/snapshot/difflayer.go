@@ -0,0 +1,129 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+)
+
+// diffLayer is an in-memory snapshot layer holding the state changes of a single
+// block on top of its parent layer.
+type diffLayer struct {
+	parent snapshot    // Layer below this one, either a diff or the disk layer
+	root   common.Hash // State root the layer represents
+	stale  bool        // Whether the layer was flattened or discarded
+
+	destructs map[common.Hash]struct{}               // Accounts deleted, storage included, before the changes
+	accounts  map[common.Hash][]byte                 // Modified accounts, nil for deletions
+	storage   map[common.Hash]map[common.Hash][]byte // Modified storage slots, nil for deletions
+
+	lock sync.RWMutex
+}
+
+// newDiffLayer creates a diff layer on top of the given parent.
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return &diffLayer{
+		parent:    parent,
+		root:      root,
+		destructs: destructs,
+		accounts:  accounts,
+		storage:   storage,
+	}
+}
+
+// Root returns the state root the layer represents.
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+// parentLayer returns the layer below this one.
+func (dl *diffLayer) parentLayer() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// setParent reattaches the layer to a new parent after flattening.
+func (dl *diffLayer) setParent(parent snapshot) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.parent = parent
+}
+
+// Stale reports whether the layer was invalidated.
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale invalidates the layer.
+func (dl *diffLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// Account retrieves the RLP encoded account with the given hash, falling back to
+// the parent layers if not modified in this one.
+func (dl *diffLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if blob, ok := dl.accounts[hash]; ok {
+		dl.lock.RUnlock()
+		return blob, nil
+	}
+	if _, ok := dl.destructs[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Account(hash)
+}
+
+// Storage retrieves the RLP encoded storage slot of an account, falling back to
+// the parent layers if not modified in this one.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if blob, ok := dl.storage[accountHash][storageHash]; ok {
+		dl.lock.RUnlock()
+		return blob, nil
+	}
+	if _, ok := dl.destructs[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Storage(accountHash, storageHash)
+}
@@ -0,0 +1,214 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/log"
+)
+
+// diskLayer is the bottom-most snapshot layer, persisted in the database.
+type diskLayer struct {
+	diskdb ethdb.Database // Database holding the snapshot entries
+	root   common.Hash    // State root covered by the layer
+	stale  bool           // Whether the layer was invalidated by flattening
+
+	genMarker  []byte             // Last key covered by the generator, nil if the snapshot is complete
+	genAbort   chan chan struct{} // Channel to request the generator to stop
+	genPending chan struct{}      // Channel closed when the generator stops
+
+	lock sync.RWMutex
+}
+
+// loadDiskLayer loads the disk layer of the given state root from the database,
+// resuming its generation if it was interrupted. Nil is returned if the stored
+// snapshot belongs to a different state.
+func loadDiskLayer(diskdb ethdb.Database, root common.Hash) *diskLayer {
+	if stored, _ := diskdb.Get(snapshotRootKey); common.BytesToHash(stored) != root {
+		return nil
+	}
+	base := &diskLayer{diskdb: diskdb, root: root}
+	if marker, err := diskdb.Get(snapshotGeneratorKey); err == nil {
+		base.genMarker = append([]byte{}, marker...)
+		base.startGeneration()
+	}
+	return base
+}
+
+// newGeneratingLayer creates a disk layer for the given state root and starts
+// generating its entries from scratch.
+func newGeneratingLayer(diskdb ethdb.Database, root common.Hash) *diskLayer {
+	batch := diskdb.NewBatch()
+	batch.Put(snapshotRootKey, root[:])
+	batch.Put(snapshotGeneratorKey, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot generator", "err", err)
+	}
+	base := &diskLayer{diskdb: diskdb, root: root, genMarker: []byte{}}
+	base.startGeneration()
+	return base
+}
+
+// Root returns the state root the layer represents.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// parentLayer returns nil, the disk layer being the bottom-most one.
+func (dl *diskLayer) parentLayer() snapshot {
+	return nil
+}
+
+// Stale reports whether the layer was invalidated.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale invalidates the layer.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.stale = true
+}
+
+// covered reports whether the snapshot entries of the given key (account hash,
+// optionally followed by a slot hash) were already generated.
+func (dl *diskLayer) covered(key []byte) bool {
+	return dl.genMarker == nil || bytes.Compare(key, dl.genMarker) <= 0
+}
+
+// Account retrieves the RLP encoded account with the given hash.
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash[:]) {
+		return nil, ErrNotCoveredYet
+	}
+	blob, _ := dl.diskdb.Get(accountKey(hash))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return blob, nil
+}
+
+// Storage retrieves the RLP encoded storage slot of an account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(append(accountHash[:], storageHash[:]...)) {
+		return nil, ErrNotCoveredYet
+	}
+	blob, _ := dl.diskdb.Get(storageKey(accountHash, storageHash))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return blob, nil
+}
+
+// flatten writes the contents of a diff layer on top of this disk layer into the
+// database, returning a new disk layer for the state root of the diff. Both the
+// current disk layer and the diff layer are invalidated.
+//
+// If the snapshot is still being generated, only the entries already covered
+// are written, the rest being generated from the new state root afterwards.
+func (dl *diskLayer) flatten(diff *diffLayer) *diskLayer {
+	dl.stopGeneration()
+	dl.markStale()
+	diff.markStale()
+
+	marker := dl.genMarker
+	covered := func(key []byte) bool {
+		return marker == nil || bytes.Compare(key, marker) <= 0
+	}
+	// Deletions can't be batched, so invalidate the stored snapshot until the
+	// batch is written to have it regenerated if interrupted in between
+	if err := dl.diskdb.Delete(snapshotRootKey); err != nil {
+		log.Crit("Failed to invalidate snapshot", "err", err)
+	}
+	for hash := range diff.destructs {
+		if covered(hash[:]) {
+			dl.diskdb.Delete(accountKey(hash))
+			wipePrefix(dl.diskdb, storageKey(hash, common.Hash{})[:len(snapshotStoragePrefix)+common.HashLength])
+		}
+	}
+	batch := dl.diskdb.NewBatch()
+	for hash, blob := range diff.accounts {
+		if !covered(hash[:]) {
+			continue
+		}
+		if len(blob) == 0 {
+			dl.diskdb.Delete(accountKey(hash))
+		} else {
+			batch.Put(accountKey(hash), blob)
+		}
+	}
+	for accountHash, slots := range diff.storage {
+		for storageHash, blob := range slots {
+			if !covered(append(accountHash[:], storageHash[:]...)) {
+				continue
+			}
+			if len(blob) == 0 {
+				dl.diskdb.Delete(storageKey(accountHash, storageHash))
+			} else {
+				batch.Put(storageKey(accountHash, storageHash), blob)
+			}
+		}
+	}
+	batch.Put(snapshotRootKey, diff.root[:])
+	if marker != nil {
+		batch.Put(snapshotGeneratorKey, marker)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write flattened snapshot", "err", err)
+	}
+	base := &diskLayer{diskdb: dl.diskdb, root: diff.root, genMarker: marker}
+	if marker != nil {
+		base.startGeneration()
+	}
+	return base
+}
+
+// wipePrefix deletes all the database entries whose keys start with the prefix.
+func wipePrefix(diskdb ethdb.Database, prefix []byte) {
+	var keys [][]byte
+
+	it := diskdb.(ethdb.Iteratee).NewIteratorWithPrefix(prefix)
+	for it.Next() {
+		keys = append(keys, common.CopyBytes(it.Key()))
+	}
+	it.Release()
+
+	for _, key := range keys {
+		diskdb.Delete(key)
+	}
+}
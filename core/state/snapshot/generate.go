@@ -0,0 +1,182 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/trie"
+)
+
+// generatorBatchItems is the number of snapshot entries the generator writes out
+// in a single batch, along with its progress marker.
+const generatorBatchItems = 10000
+
+// emptyRoot is the known root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// account is the consensus representation of accounts, as stored in the state
+// trie. Only the storage root is needed by the generator.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// startGeneration starts generating the snapshot entries of the layer in the
+// background, resuming from the current marker.
+func (dl *diskLayer) startGeneration() {
+	dl.genAbort = make(chan chan struct{})
+	dl.genPending = make(chan struct{})
+	go dl.generate()
+}
+
+// stopGeneration aborts a running generator, waiting until its progress is
+// persisted.
+func (dl *diskLayer) stopGeneration() {
+	if dl.genAbort == nil {
+		return
+	}
+	done := make(chan struct{})
+	select {
+	case dl.genAbort <- done:
+		<-done
+	case <-dl.genPending:
+	}
+}
+
+// generate iterates over the state trie of the layer, writing out the snapshot
+// entries of all the accounts and storage slots after the current marker.
+func (dl *diskLayer) generate() {
+	defer close(dl.genPending)
+
+	var (
+		marker = dl.genMarker
+		batch  = dl.diskdb.NewBatch()
+		items  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	if len(marker) == 0 {
+		wipePrefix(dl.diskdb, snapshotAccountPrefix)
+		wipePrefix(dl.diskdb, snapshotStoragePrefix)
+	}
+	// checkpoint flushes the batch and the progress marker every once in a while
+	// or if an abort is requested, returning whether generation should stop.
+	checkpoint := func(key []byte) bool {
+		items++
+
+		var done chan struct{}
+		select {
+		case done = <-dl.genAbort:
+		default:
+		}
+		if items%generatorBatchItems != 0 && done == nil {
+			return false
+		}
+		batch.Put(snapshotGeneratorKey, key)
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write snapshot entries", "err", err)
+		}
+		batch = dl.diskdb.NewBatch()
+
+		dl.lock.Lock()
+		dl.genMarker = key
+		dl.lock.Unlock()
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "at", common.BytesToHash(key[:common.HashLength]), "items", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		if done != nil {
+			log.Debug("Aborted state snapshot generation", "root", dl.root, "items", items)
+			close(done)
+			return true
+		}
+		return false
+	}
+	accTrie, err := trie.New(dl.root, dl.diskdb)
+	if err != nil {
+		log.Error("Failed to open state trie for snapshot generation", "root", dl.root, "err", err)
+		return
+	}
+	var accMarker []byte
+	if len(marker) > 0 {
+		accMarker = marker[:common.HashLength]
+	}
+	it := trie.NewIterator(accTrie.NodeIterator(accMarker))
+	for it.Next() {
+		var (
+			accountHash = common.BytesToHash(it.Key)
+			acc         account
+		)
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot generation", "hash", accountHash, "err", err)
+		}
+		batch.Put(accountKey(accountHash), common.CopyBytes(it.Value))
+		if checkpoint(common.CopyBytes(accountHash[:])) {
+			return
+		}
+		if acc.Root == emptyRoot {
+			continue
+		}
+		// Resume the storage iteration of a partially generated account
+		var storeMarker []byte
+		if len(marker) > common.HashLength && bytes.Equal(accountHash[:], marker[:common.HashLength]) {
+			storeMarker = marker[common.HashLength:]
+		}
+		storeTrie, err := trie.New(acc.Root, dl.diskdb)
+		if err != nil {
+			log.Error("Failed to open storage trie for snapshot generation", "root", acc.Root, "err", err)
+			return
+		}
+		storeIt := trie.NewIterator(storeTrie.NodeIterator(storeMarker))
+		for storeIt.Next() {
+			storageHash := common.BytesToHash(storeIt.Key)
+			batch.Put(storageKey(accountHash, storageHash), common.CopyBytes(storeIt.Value))
+			if checkpoint(append(common.CopyBytes(accountHash[:]), storageHash[:]...)) {
+				return
+			}
+		}
+		if storeIt.Err != nil {
+			log.Error("Failed to iterate storage trie for snapshot generation", "root", acc.Root, "err", storeIt.Err)
+			return
+		}
+	}
+	if it.Err != nil {
+		log.Error("Failed to iterate state trie for snapshot generation", "root", dl.root, "err", it.Err)
+		return
+	}
+	// Generation complete, drop the progress marker
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot entries", "err", err)
+	}
+	if err := dl.diskdb.Delete(snapshotGeneratorKey); err != nil {
+		log.Crit("Failed to delete snapshot generator", "err", err)
+	}
+	dl.lock.Lock()
+	dl.genMarker = nil
+	dl.lock.Unlock()
+
+	log.Info("Generated state snapshot", "root", dl.root, "items", items, "elapsed", common.PrettyDuration(time.Since(start)))
+}
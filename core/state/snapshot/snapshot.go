@@ -0,0 +1,241 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat key-value snapshot of the state, allowing
+// accounts and storage slots to be read without traversing the tries.
+//
+// The snapshot consists of a persistent disk layer holding the state of some
+// block, and in-memory diff layers on top of it, one for every subsequently
+// imported block (including side chain ones). The bottom-most diff layers are
+// periodically flattened into the disk layer, whereas diff layers of abandoned
+// side chains are dropped.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/log"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer was invalidated by flattening or by a chain reorganisation.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the snapshot is being
+	// generated and the requested item is not yet in the covered range.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errNotIterable is returned if the database can't be iterated over, which
+	// is needed to wipe the storage of destructed accounts.
+	errNotIterable = errors.New("database not iterable")
+)
+
+var (
+	snapshotRootKey      = []byte("SnapshotRoot")      // state root covered by the disk layer
+	snapshotGeneratorKey = []byte("SnapshotGenerator") // last key written while generating, if in progress
+
+	snapshotAccountPrefix = []byte("snapshot-account-") // snapshotAccountPrefix + account hash -> account RLP
+	snapshotStoragePrefix = []byte("snapshot-storage-") // snapshotStoragePrefix + account hash + slot hash -> slot RLP
+)
+
+// accountKey returns the database key of an account snapshot entry.
+func accountKey(hash common.Hash) []byte {
+	return append(append([]byte{}, snapshotAccountPrefix...), hash[:]...)
+}
+
+// storageKey returns the database key of a storage slot snapshot entry.
+func storageKey(accountHash, storageHash common.Hash) []byte {
+	return append(append(append([]byte{}, snapshotStoragePrefix...), accountHash[:]...), storageHash[:]...)
+}
+
+// Snapshot is a read only view of the state at a specific root.
+type Snapshot interface {
+	// Root returns the state root the snapshot represents.
+	Root() common.Hash
+
+	// Account retrieves the RLP encoded account with the given hash, as stored in
+	// the account trie, or nil if the account doesn't exist.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage retrieves the RLP encoded storage slot of an account, as stored in
+	// the storage trie, or nil if the slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is a layer of the snapshot tree, either a disk or a diff layer.
+type snapshot interface {
+	Snapshot
+
+	// parentLayer returns the layer below this one, or nil for the disk layer.
+	parentLayer() snapshot
+
+	// Stale reports whether the layer was invalidated.
+	Stale() bool
+}
+
+// Tree maintains the disk layer and the diff layers built on top of it, keyed by
+// the state root they represent.
+type Tree struct {
+	diskdb ethdb.Database
+	layers map[common.Hash]snapshot
+	lock   sync.RWMutex
+}
+
+// New loads the snapshot of the given state root from the database. If the disk
+// layer belongs to a different state or is missing, it is regenerated from the
+// state trie in the background. Until done, reads outside the already covered
+// range return ErrNotCoveredYet.
+func New(diskdb ethdb.Database, root common.Hash) (*Tree, error) {
+	if _, ok := diskdb.(ethdb.Iteratee); !ok {
+		return nil, errNotIterable
+	}
+	base := loadDiskLayer(diskdb, root)
+	if base == nil {
+		log.Info("Rebuilding state snapshot", "root", root)
+		base = newGeneratingLayer(diskdb, root)
+	}
+	return &Tree{
+		diskdb: diskdb,
+		layers: map[common.Hash]snapshot{root: base},
+	}, nil
+}
+
+// Snapshot returns the snapshot of the given state root, or nil if unavailable.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[root]; ok {
+		return layer
+	}
+	return nil
+}
+
+// Update adds a diff layer for a new state root on top of its parent. The diff
+// contains the accounts whose storage was wiped, as well as all the accounts and
+// storage slots modified, nil values denoting deletions.
+func (t *Tree) Update(root common.Hash, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil // Same state reached through a different block
+	}
+	layer, ok := t.layers[parent]
+	if !ok {
+		return fmt.Errorf("parent snapshot %x missing", parent)
+	}
+	t.layers[root] = newDiffLayer(layer, root, destructs, accounts, storage)
+	return nil
+}
+
+// Cap flattens the diff layers below the given state root into the disk layer,
+// keeping the top-most ones (including root itself) in memory. All layers built
+// on top of the flattened ones but not leading to root are discarded.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	layer, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot %x missing", root)
+	}
+	// Collect the diff layers leading down to the disk layer
+	var chain []*diffLayer
+	for {
+		diff, ok := layer.(*diffLayer)
+		if !ok {
+			break
+		}
+		chain = append(chain, diff)
+		layer = diff.parentLayer()
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+	// Flatten the bottom-most diff layers and attach the remaining ones to the
+	// new disk layer
+	base := layer.(*diskLayer)
+	for i := len(chain) - 1; i >= layers; i-- {
+		base = base.flatten(chain[i])
+	}
+	if layers > 0 {
+		chain[layers-1].setParent(base)
+	}
+	t.layers[base.root] = base
+
+	// Discard all the layers which were built on top of invalidated ones
+	for root, layer := range t.layers {
+		if isStale(layer) {
+			if diff, ok := layer.(*diffLayer); ok {
+				diff.markStale()
+			}
+			delete(t.layers, root)
+		}
+	}
+	return nil
+}
+
+// Rebuild discards all snapshot layers and regenerates the disk layer from the
+// given state root in the background.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.stopGeneration()
+			layer.markStale()
+		case *diffLayer:
+			layer.markStale()
+		}
+	}
+	log.Info("Rebuilding state snapshot", "root", root)
+	t.layers = map[common.Hash]snapshot{root: newGeneratingLayer(t.diskdb, root)}
+}
+
+// Persist flattens all the diff layers below and including the given state root
+// into the disk layer and stops any running generation, persisting its progress.
+// The snapshot can afterwards be reloaded with New for the same root.
+func (t *Tree) Persist(root common.Hash) error {
+	err := t.Cap(root, 0)
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, layer := range t.layers {
+		if base, ok := layer.(*diskLayer); ok {
+			base.stopGeneration()
+		}
+	}
+	return err
+}
+
+// isStale reports whether a layer or any of the ones below it was invalidated.
+func isStale(layer snapshot) bool {
+	for ; layer != nil; layer = layer.parentLayer() {
+		if layer.Stale() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/trie"
+)
+
+// makeState creates a state trie with a number of accounts, every other one also
+// having some storage, returning the root along with the expected contents.
+func makeState(t *testing.T, db ethdb.Database, accounts, slots int) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	accTrie, _ := trie.NewSecure(common.Hash{}, db, 0)
+
+	accs := make(map[common.Hash][]byte)
+	storage := make(map[common.Hash]map[common.Hash][]byte)
+	for i := 0; i < accounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		acc := account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)}
+
+		if i%2 == 0 {
+			storeTrie, _ := trie.NewSecure(common.Hash{}, db, 0)
+			hash := crypto.Keccak256Hash(addr[:])
+			storage[hash] = make(map[common.Hash][]byte)
+			for j := 0; j < slots; j++ {
+				key := common.BigToHash(big.NewInt(int64(j)))
+				val, _ := rlp.EncodeToBytes(big.NewInt(int64(i*slots + j + 1)))
+				storeTrie.Update(key[:], val)
+				storage[hash][crypto.Keccak256Hash(key[:])] = val
+			}
+			root, err := storeTrie.CommitTo(db)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			acc.Root = root
+		}
+		blob, _ := rlp.EncodeToBytes(&acc)
+		accTrie.Update(addr[:], blob)
+		accs[crypto.Keccak256Hash(addr[:])] = blob
+	}
+	root, err := accTrie.CommitTo(db)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	return root, accs, storage
+}
+
+// checkSnapshot verifies that the snapshot contains exactly the given accounts and
+// storage slots.
+func checkSnapshot(t *testing.T, snap Snapshot, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+	for hash, want := range accounts {
+		if blob, err := snap.Account(hash); err != nil || !bytes.Equal(blob, want) {
+			t.Errorf("account %x mismatch: have %x (%v), want %x", hash, blob, err, want)
+		}
+	}
+	for hash, slots := range storage {
+		for slot, want := range slots {
+			if blob, err := snap.Storage(hash, slot); err != nil || !bytes.Equal(blob, want) {
+				t.Errorf("slot %x of %x mismatch: have %x (%v), want %x", slot, hash, blob, err, want)
+			}
+		}
+	}
+}
+
+// Tests that the generated disk layer contains all the accounts and storage slots
+// of the state trie, and that interrupted generation is resumed.
+func TestGeneration(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	root, accounts, storage := makeState(t, db, 32, 8)
+
+	// Start generating and interrupt it right away
+	tree, err := New(db, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	if err := tree.Persist(root); err != nil {
+		t.Fatalf("failed to persist snapshot: %v", err)
+	}
+	// Reload the snapshot and wait for the generation to finish
+	tree, err = New(db, root)
+	if err != nil {
+		t.Fatalf("failed to reload snapshot tree: %v", err)
+	}
+	base := tree.Snapshot(root).(*diskLayer)
+	<-base.genPending
+
+	if base.genMarker != nil {
+		t.Fatalf("generation not completed: marker %x", base.genMarker)
+	}
+	if _, err := db.Get(snapshotGeneratorKey); err == nil {
+		t.Errorf("generator marker not deleted")
+	}
+	checkSnapshot(t, base, accounts, storage)
+
+	if blob, err := base.Account(common.Hash{1}); err != nil || blob != nil {
+		t.Errorf("missing account found: %x (%v)", blob, err)
+	}
+}
+
+// Tests that reads are served through the diff layers, and that capping the tree
+// flattens the bottom-most ones and discards the side chains.
+func TestDiffLayers(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	root, accounts, storage := makeState(t, db, 8, 4)
+
+	tree, err := New(db, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	<-tree.Snapshot(root).(*diskLayer).genPending
+
+	var (
+		modified  = common.Hash{0xaa}
+		destroyed common.Hash
+	)
+	for hash := range storage {
+		destroyed = hash
+		break
+	}
+	// Build a chain of two diffs and a side chain forking off the first one
+	tree.Update(common.Hash{1}, root, map[common.Hash]struct{}{destroyed: {}}, map[common.Hash][]byte{destroyed: nil, modified: {0x01}}, nil)
+	tree.Update(common.Hash{2}, common.Hash{1}, nil, map[common.Hash][]byte{modified: {0x02}}, map[common.Hash]map[common.Hash][]byte{modified: {{0x01}: {0x03}}})
+	tree.Update(common.Hash{3}, common.Hash{1}, nil, map[common.Hash][]byte{modified: {0x04}}, nil)
+
+	if err := tree.Update(common.Hash{5}, common.Hash{4}, nil, nil, nil); err == nil {
+		t.Errorf("diff layer created on missing parent")
+	}
+	snap := tree.Snapshot(common.Hash{2})
+	if blob, _ := snap.Account(modified); !bytes.Equal(blob, []byte{0x02}) {
+		t.Errorf("modified account mismatch: have %x, want %x", blob, []byte{0x02})
+	}
+	if blob, _ := tree.Snapshot(common.Hash{3}).Account(modified); !bytes.Equal(blob, []byte{0x04}) {
+		t.Errorf("side chain account mismatch: have %x, want %x", blob, []byte{0x04})
+	}
+	for slot := range storage[destroyed] {
+		if blob, err := snap.Storage(destroyed, slot); err != nil || blob != nil {
+			t.Errorf("destroyed slot %x accessible: %x (%v)", slot, blob, err)
+		}
+	}
+	delete(accounts, destroyed)
+	delete(storage, destroyed)
+	checkSnapshot(t, snap, accounts, storage)
+
+	// Flatten the first diff, the side chain survives as it builds on the second
+	bottom := tree.Snapshot(common.Hash{1})
+	if err := tree.Cap(common.Hash{2}, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if _, err := bottom.Account(modified); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if tree.Snapshot(root) != nil {
+		t.Errorf("old disk layer retained")
+	}
+	if tree.Snapshot(common.Hash{3}) != nil {
+		t.Errorf("side chain layer retained")
+	}
+	base := tree.Snapshot(common.Hash{1}).(*diskLayer)
+	if blob, _ := base.Account(modified); !bytes.Equal(blob, []byte{0x01}) {
+		t.Errorf("flattened account mismatch: have %x, want %x", blob, []byte{0x01})
+	}
+	checkSnapshot(t, tree.Snapshot(common.Hash{2}), accounts, storage)
+
+	// Persist everything and check that the snapshot reloads
+	if err := tree.Persist(common.Hash{2}); err != nil {
+		t.Fatalf("failed to persist snapshot: %v", err)
+	}
+	if loadDiskLayer(db, common.Hash{2}) == nil {
+		t.Fatalf("persisted snapshot not loaded")
+	}
+	tree, _ = New(db, common.Hash{2})
+	checkSnapshot(t, tree.Snapshot(common.Hash{2}), accounts, storage)
+	if blob, _ := tree.Snapshot(common.Hash{2}).Storage(modified, common.Hash{0x01}); !bytes.Equal(blob, []byte{0x03}) {
+		t.Errorf("flattened slot mismatch: have %x, want %x", blob, []byte{0x03})
+	}
+}
@@ -88,6 +88,7 @@ type stateObject struct {
 	suicided  bool
 	touched   bool
 	deleted   bool
+	loaded    bool                      // whether the object was loaded with its storage from the state root
 	onDirty   func(addr common.Address) // Callback method to mark a state object newly dirty
 }
 
@@ -175,8 +176,18 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	// Load from the snapshot or the DB in case it is missing.
+	var (
+		enc []byte
+		err error
+	)
+	snap := self.db.snap
+	if snap != nil && self.loaded {
+		enc, err = snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if snap == nil || !self.loaded || err != nil {
+		enc, err = self.getTrie(db).TryGet(key[:])
+	}
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -214,18 +225,46 @@ func (self *stateObject) setState(key, value common.Hash) {
 	}
 }
 
+// SetStorage replaces the entire storage of the account with the given one. The
+// change is not journaled and can't be reverted, so it must only be used on
+// states that are discarded afterwards, e.g. for simulated calls.
+func (self *stateObject) SetStorage(db Database, storage map[common.Hash]common.Hash) {
+	self.trie, _ = db.OpenStorageTrie(self.addrHash, common.Hash{})
+	self.loaded = false
+	self.cachedStorage = make(Storage)
+	self.dirtyStorage = make(Storage)
+	for key, value := range storage {
+		self.setState(key, value)
+	}
+}
+
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
+
+	// The storage of a new object starts out empty, wipe any previous one from
+	// the snapshot unless already done for this block
+	record := self.db.snap != nil
+	if record && !self.loaded {
+		if _, ok := self.db.snapDestructs[self.addrHash]; !ok {
+			self.db.snapDestruct(self.addrHash)
+		}
+	}
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
+			if record {
+				self.db.snapUpdateStorage(self.addrHash, key, nil)
+			}
 			continue
 		}
 		// Encoding []byte cannot fail, ok to ignore the error.
 		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
 		self.setError(tr.TryUpdate(key[:], v))
+		if record {
+			self.db.snapUpdateStorage(self.addrHash, key, v)
+		}
 	}
 	return tr
 }
@@ -304,6 +343,7 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
+	stateObject.loaded = self.loaded
 	return stateObject
 }
 
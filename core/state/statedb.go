@@ -24,6 +24,7 @@ import (
 	"sync"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/state/snapshot"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/log"
@@ -67,6 +68,18 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// State snapshot serving account and storage reads, and the changes to be
+	// added to it on commit, keyed by account and slot hashes
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
+	// Per-transaction read and write sets, recorded if access tracking is enabled
+	accessTracking bool
+	accesses       []*TxAccess
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        journal
@@ -78,11 +91,18 @@ type StateDB struct {
 
 // Create a new state from a given trie
 func New(root common.Hash, db Database) (*StateDB, error) {
+	return NewWithSnapshot(root, db, nil)
+}
+
+// NewWithSnapshot creates a new state from a given trie, serving account and
+// storage reads from the state snapshot of the root if one is available. Once
+// committed, the changes are added to the snapshot tree as a new layer.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
 	tr, err := db.OpenTrie(root)
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                     db,
 		trie:                   tr,
 		stateObjects:           make(map[common.Address]*stateObject),
@@ -91,7 +111,24 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		refund:                 new(big.Int),
 		logs:                   make(map[common.Hash][]*types.Log),
 		preimages:              make(map[common.Hash][]byte),
-	}, nil
+		snaps:                  snaps,
+	}
+	sdb.openSnapshot(root)
+	return sdb, nil
+}
+
+// openSnapshot switches the state snapshot reads are served from to the one of
+// the given root, if available.
+func (self *StateDB) openSnapshot(root common.Hash) {
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+	if self.snaps == nil {
+		return
+	}
+	if self.snap = self.snaps.Snapshot(root); self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
 }
 
 // setError remembers the first non-nil error it is called with.
@@ -122,6 +159,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	self.openSnapshot(root)
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -172,18 +210,21 @@ func (self *StateDB) AddRefund(gas *big.Int) {
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
+	self.recordRead(addr)
 	return self.getStateObject(addr) != nil
 }
 
 // Empty returns whether the state object is either non-existent
 // or empty according to the EIP161 specification (balance = nonce = code = 0)
 func (self *StateDB) Empty(addr common.Address) bool {
+	self.recordRead(addr)
 	so := self.getStateObject(addr)
 	return so == nil || so.empty()
 }
 
 // Retrieve the balance from the given address or 0 if object not found
 func (self *StateDB) GetBalance(addr common.Address) *big.Int {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Balance()
@@ -192,6 +233,7 @@ func (self *StateDB) GetBalance(addr common.Address) *big.Int {
 }
 
 func (self *StateDB) GetNonce(addr common.Address) uint64 {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Nonce()
@@ -201,6 +243,7 @@ func (self *StateDB) GetNonce(addr common.Address) uint64 {
 }
 
 func (self *StateDB) GetCode(addr common.Address) []byte {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Code(self.db)
@@ -209,6 +252,7 @@ func (self *StateDB) GetCode(addr common.Address) []byte {
 }
 
 func (self *StateDB) GetCodeSize(addr common.Address) int {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return 0
@@ -224,6 +268,7 @@ func (self *StateDB) GetCodeSize(addr common.Address) int {
 }
 
 func (self *StateDB) GetCodeHash(addr common.Address) common.Hash {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return common.Hash{}
@@ -232,6 +277,7 @@ func (self *StateDB) GetCodeHash(addr common.Address) common.Hash {
 }
 
 func (self *StateDB) GetState(a common.Address, b common.Hash) common.Hash {
+	self.recordSlotRead(a, b)
 	stateObject := self.getStateObject(a)
 	if stateObject != nil {
 		return stateObject.GetState(self.db, b)
@@ -251,6 +297,7 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 }
 
 func (self *StateDB) HasSuicided(addr common.Address) bool {
+	self.recordRead(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.suicided
@@ -264,6 +311,7 @@ func (self *StateDB) HasSuicided(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr
 func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	self.recordWrite(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -272,6 +320,7 @@ func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 
 // SubBalance subtracts amount from the account associated with addr
 func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
+	self.recordWrite(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SubBalance(amount)
@@ -279,6 +328,7 @@ func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 }
 
 func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
+	self.recordWrite(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetBalance(amount)
@@ -286,6 +336,7 @@ func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 }
 
 func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
+	self.recordWrite(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetNonce(nonce)
@@ -293,6 +344,7 @@ func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
 }
 
 func (self *StateDB) SetCode(addr common.Address, code []byte) {
+	self.recordWrite(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetCode(crypto.Keccak256Hash(code), code)
@@ -300,18 +352,29 @@ func (self *StateDB) SetCode(addr common.Address, code []byte) {
 }
 
 func (self *StateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	self.recordSlotWrite(addr, key)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetState(self.db, key, value)
 	}
 }
 
+// SetStorage replaces the entire storage of the specified account with the given
+// one. The change can't be reverted and must only be used on discarded states.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(self.db, storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
 // The account's state object is still available until the state is committed,
 // getStateObject will return a non-nil account after Suicide.
 func (self *StateDB) Suicide(addr common.Address) bool {
+	self.recordWrite(addr)
 	stateObject := self.getStateObject(addr)
 	if stateObject == nil {
 		return false
@@ -340,6 +403,10 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -347,6 +414,29 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	if self.snap != nil {
+		self.snapDestruct(stateObject.addrHash)
+		self.snapAccounts[stateObject.addrHash] = nil
+	}
+}
+
+// snapDestruct records the wiping of an account's storage for the snapshot,
+// dropping the slot changes recorded so far.
+func (self *StateDB) snapDestruct(hash common.Hash) {
+	self.snapDestructs[hash] = struct{}{}
+	delete(self.snapStorage, hash)
+}
+
+// snapUpdateStorage records a storage slot change for the snapshot, a nil value
+// denoting deletion.
+func (self *StateDB) snapUpdateStorage(hash common.Hash, key common.Hash, value []byte) {
+	slots := self.snapStorage[hash]
+	if slots == nil {
+		slots = make(map[common.Hash][]byte)
+		self.snapStorage[hash] = slots
+	}
+	slots[crypto.Keccak256Hash(key[:])] = value
 }
 
 // Retrieve a state object given my the address. Returns nil if not found.
@@ -359,8 +449,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if available, or the database otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -372,6 +471,7 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 	}
 	// Insert into the live set.
 	obj := newObject(self, addr, data, self.MarkStateObjectDirty)
+	obj.loaded = true
 	self.setStateObject(obj)
 	return obj
 }
@@ -421,9 +521,11 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 //
 // Carrying over the balance ensures that Ether doesn't disappear.
 func (self *StateDB) CreateAccount(addr common.Address) {
+	self.recordWrite(addr)
 	new, prev := self.createObject(addr)
 	if prev != nil {
 		new.setBalance(prev.data.Balance)
+		self.recordRecreate()
 	}
 }
 
@@ -544,6 +646,10 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.thash = thash
 	self.bhash = bhash
 	self.txIndex = ti
+
+	if self.accessTracking {
+		self.accesses = append(self.accesses, &TxAccess{TxHash: thash, Reads: newAccessSet(), Writes: newAccessSet()})
+	}
 }
 
 // Finalise finalises the state by removing the self destructed objects
@@ -618,5 +724,15 @@ func (s *StateDB) CommitTo(dbw trie.DatabaseWriter, deleteEmptyObjects bool) (ro
 	// Write trie changes.
 	root, err = s.trie.CommitTo(dbw)
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
+
+	// Add the changes to the snapshot tree as a new layer
+	if err == nil && s.snap != nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				log.Warn("Failed to update state snapshot", "root", root, "parent", parent, "err", err)
+			}
+		}
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
 	return root, err
 }
@@ -62,6 +62,31 @@ func TestUpdateLeaks(t *testing.T) {
 	}
 }
 
+// Tests that replacing the storage of an account drops all its previous slots,
+// both the committed and the not yet committed ones.
+func TestSetStorage(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetState(addr, common.Hash{1}, common.Hash{1})
+	root, _ := state.CommitTo(db, false)
+
+	state, _ = New(root, NewDatabase(db))
+	state.SetState(addr, common.Hash{2}, common.Hash{2})
+	state.SetStorage(addr, map[common.Hash]common.Hash{{3}: {3}})
+
+	for key, want := range map[common.Hash]common.Hash{{1}: {}, {2}: {}, {3}: {3}} {
+		if have := state.GetState(addr, key); have != want {
+			t.Errorf("slot %x: have %x, want %x", key, have, want)
+		}
+	}
+	state.IntermediateRoot(false)
+	if have := state.GetState(addr, common.Hash{1}); have != (common.Hash{}) {
+		t.Errorf("replaced slot resurrected after root computation: %x", have)
+	}
+}
+
 // Tests that no intermediate state of an object is stored into the database,
 // only the one right before the commit.
 func TestIntermediateLeaks(t *testing.T) {
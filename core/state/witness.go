@@ -0,0 +1,106 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/ethdb"
+)
+
+// WitnessDatabase is a state database recording the trie nodes and contract codes
+// read through it, which suffice to reexecute the state transitions without the
+// full state. No tries are cached between accesses, so that every node touched is
+// read from the backing database and recorded.
+type WitnessDatabase struct {
+	Database
+	reader *witnessReader
+}
+
+// witnessReader wraps a database, recording all the hash keyed values read.
+type witnessReader struct {
+	ethdb.Database
+
+	values map[common.Hash][]byte // Trie nodes and codes read, keyed by hash
+	codes  map[common.Hash]struct{}
+	lock   sync.Mutex
+}
+
+// NewWitnessDatabase creates a state database recording the data read from the
+// given database.
+func NewWitnessDatabase(db ethdb.Database) *WitnessDatabase {
+	reader := &witnessReader{
+		Database: db,
+		values:   make(map[common.Hash][]byte),
+		codes:    make(map[common.Hash]struct{}),
+	}
+	return &WitnessDatabase{Database: NewDatabase(reader), reader: reader}
+}
+
+// Get retrieves a value from the database, recording it if keyed by a hash.
+func (r *witnessReader) Get(key []byte) ([]byte, error) {
+	value, err := r.Database.Get(key)
+	if err == nil && len(key) == common.HashLength {
+		r.lock.Lock()
+		r.values[common.BytesToHash(key)] = common.CopyBytes(value)
+		r.lock.Unlock()
+	}
+	return value, err
+}
+
+// ContractCode retrieves the code of a contract, recording it.
+func (db *WitnessDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.Database.ContractCode(addrHash, codeHash)
+	if err == nil {
+		db.reader.lock.Lock()
+		db.reader.codes[codeHash] = struct{}{}
+		db.reader.lock.Unlock()
+	}
+	return code, err
+}
+
+// ContractCodeSize retrieves the code size of a contract. The code itself is read
+// and recorded, as it is needed to determine the size statelessly.
+func (db *WitnessDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// Witness returns the trie nodes and the contract codes read so far, each sorted
+// by hash.
+func (db *WitnessDatabase) Witness() (nodes [][]byte, codes [][]byte) {
+	db.reader.lock.Lock()
+	defer db.reader.lock.Unlock()
+
+	hashes := make([]common.Hash, 0, len(db.reader.values))
+	for hash := range db.reader.values {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+
+	for _, hash := range hashes {
+		if _, ok := db.reader.codes[hash]; ok {
+			codes = append(codes, db.reader.values[hash])
+		} else {
+			nodes = append(nodes, db.reader.values[hash])
+		}
+	}
+	return nodes, codes
+}
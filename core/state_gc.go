@@ -0,0 +1,269 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/trie"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+const (
+	triesInMemory      = 128  // Number of recent block states kept referenced in memory
+	stateFlushInterval = 4096 // Number of blocks between states flushed to disk regardless of memory use
+)
+
+// DefaultStateGCLimit is the default memory allowance of the buffered state tries
+// above which old states are flushed to disk instead of being garbage collected.
+var DefaultStateGCLimit = common.StorageSize(256 * 1024 * 1024)
+
+// gcNode is a trie node or contract code buffered in memory.
+type gcNode struct {
+	blob     []byte
+	children []common.Hash // Buffered nodes referenced by this one
+	parents  int           // Number of buffered nodes and state roots referencing this one
+}
+
+// gcRoot is the state root of a recently imported block.
+type gcRoot struct {
+	number uint64
+	root   common.Hash
+}
+
+// stateGC is a reference counted buffer of state trie nodes and contract codes in
+// front of the chain database. The states committed by block imports are kept in
+// memory while recent, after which they are either dereferenced, dropping all the
+// nodes no newer state uses, or once in a while flushed to disk. Reads are served
+// from the buffer first and the chain database second.
+type stateGC struct {
+	ethdb.Database // Persistent database the buffered nodes are flushed into
+
+	nodes map[common.Hash]*gcNode // Buffered nodes not yet written to disk
+	size  common.StorageSize      // Approximate memory used by the buffered nodes
+	roots []gcRoot                // Referenced recent state roots, oldest first
+	limit common.StorageSize      // Memory allowance above which old states are flushed
+
+	lock sync.RWMutex
+}
+
+// newStateGC creates a state trie buffer on top of the given database.
+func newStateGC(db ethdb.Database, limit common.StorageSize) *stateGC {
+	return &stateGC{
+		Database: db,
+		nodes:    make(map[common.Hash]*gcNode),
+		limit:    limit,
+	}
+}
+
+// Get retrieves a value from the buffer, or from the database if not buffered.
+func (gc *stateGC) Get(key []byte) ([]byte, error) {
+	if len(key) == common.HashLength {
+		gc.lock.RLock()
+		node := gc.nodes[common.BytesToHash(key)]
+		gc.lock.RUnlock()
+
+		if node != nil {
+			return common.CopyBytes(node.blob), nil
+		}
+	}
+	return gc.Database.Get(key)
+}
+
+// iterableStateGC is a state buffer in front of a database able to iterate over
+// its entries, as needed by the state snapshot. Only the entries on disk are
+// iterated: the buffered ones are all hash keyed trie nodes and contract codes,
+// whereas everything iterated by prefix is written straight to the database.
+type iterableStateGC struct {
+	*stateGC
+}
+
+// NewIteratorWithPrefix returns an iterator over the database entries whose keys
+// start with the given prefix.
+func (gc iterableStateGC) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return gc.Database.(ethdb.Iteratee).NewIteratorWithPrefix(prefix)
+}
+
+// writer returns a database writer buffering the trie nodes and contract codes
+// of a state commit in memory.
+func (gc *stateGC) writer() trie.DatabaseWriter {
+	return gcWriter{gc}
+}
+
+// gcWriter inserts hash keyed entries into the state buffer and writes everything
+// else, e.g. secure trie preimages, straight to the database.
+type gcWriter struct {
+	gc *stateGC
+}
+
+func (w gcWriter) Put(key []byte, value []byte) error {
+	if len(key) != common.HashLength {
+		return w.gc.Database.Put(key, value)
+	}
+	w.gc.insert(common.BytesToHash(key), common.CopyBytes(value))
+	return nil
+}
+
+// insert adds a trie node or contract code to the buffer, referencing all the
+// buffered nodes it points to. Accounts stored in the node also reference their
+// storage trie root and contract code.
+func (gc *stateGC) insert(hash common.Hash, blob []byte) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+
+	if _, ok := gc.nodes[hash]; ok {
+		return
+	}
+	var accounts []common.Hash
+	children, err := trie.ChildHashes(blob, func(leaf []byte) {
+		var account state.Account
+		if err := rlp.DecodeBytes(leaf, &account); err == nil {
+			accounts = append(accounts, account.Root, common.BytesToHash(account.CodeHash))
+		}
+	})
+	if err != nil {
+		// Not a trie node, must be contract code
+		children, accounts = nil, nil
+	}
+	children = append(children, accounts...)
+
+	node := &gcNode{blob: blob}
+	for _, child := range children {
+		if cnode, ok := gc.nodes[child]; ok {
+			cnode.parents++
+			node.children = append(node.children, child)
+		}
+	}
+	gc.nodes[hash] = node
+	gc.size += common.StorageSize(common.HashLength + len(blob))
+}
+
+// reference marks the state root of a newly imported block as in use, keeping
+// the state in memory until the block falls out of the recent window.
+func (gc *stateGC) reference(number uint64, root common.Hash) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+
+	if node, ok := gc.nodes[root]; ok {
+		node.parents++
+	}
+	gc.roots = append(gc.roots, gcRoot{number: number, root: root})
+}
+
+// cap releases the states of blocks falling out of the recent window relative to
+// the given head. They are dereferenced, or flushed to disk if the buffer grew
+// beyond its allowance or the block is at a flush interval.
+func (gc *stateGC) cap(head uint64) error {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+
+	for len(gc.roots) > 0 && gc.roots[0].number+triesInMemory <= head {
+		old := gc.roots[0]
+		if gc.size > gc.limit || old.number%stateFlushInterval == 0 {
+			nodes, size := len(gc.nodes), gc.size
+			if err := gc.flush(old.root); err != nil {
+				return err
+			}
+			log.Debug("Flushed state to disk", "number", old.number, "root", old.root, "nodes", nodes-len(gc.nodes), "size", size-gc.size)
+		} else {
+			gc.dereference(old.root)
+		}
+		gc.roots = gc.roots[1:]
+	}
+	return nil
+}
+
+// dereference drops a reference to a buffered node, deleting it and recursively
+// dereferencing its children if it is no longer referenced by anything.
+func (gc *stateGC) dereference(hash common.Hash) {
+	node, ok := gc.nodes[hash]
+	if !ok {
+		return
+	}
+	if node.parents--; node.parents > 0 {
+		return
+	}
+	delete(gc.nodes, hash)
+	gc.size -= common.StorageSize(common.HashLength + len(node.blob))
+
+	for _, child := range node.children {
+		gc.dereference(child)
+	}
+}
+
+// commit writes the state with the given root to disk.
+func (gc *stateGC) commit(root common.Hash) error {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+
+	return gc.flush(root)
+}
+
+// flush writes all the buffered nodes reachable from the given root to disk in a
+// single batch and removes them from the buffer.
+func (gc *stateGC) flush(root common.Hash) error {
+	var (
+		batch   = gc.Database.NewBatch()
+		flushed = make(map[common.Hash]struct{})
+	)
+	if err := gc.flushNode(batch, root, flushed); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for hash := range flushed {
+		gc.size -= common.StorageSize(common.HashLength + len(gc.nodes[hash].blob))
+		delete(gc.nodes, hash)
+	}
+	return nil
+}
+
+// flushNode adds a buffered node and, before it, all its buffered descendants not
+// yet flushed to the batch.
+func (gc *stateGC) flushNode(batch ethdb.Batch, hash common.Hash, flushed map[common.Hash]struct{}) error {
+	node, ok := gc.nodes[hash]
+	if !ok {
+		return nil
+	}
+	if _, ok := flushed[hash]; ok {
+		return nil
+	}
+	for _, child := range node.children {
+		if err := gc.flushNode(batch, child, flushed); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(hash[:], node.blob); err != nil {
+		return err
+	}
+	flushed[hash] = struct{}{}
+	return nil
+}
+
+// stats returns the number of buffered nodes and their approximate memory use.
+func (gc *stateGC) stats() (int, common.StorageSize) {
+	gc.lock.RLock()
+	defer gc.lock.RUnlock()
+
+	return len(gc.nodes), gc.size
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that with state garbage collection enabled only the recent states are
+// kept, old ones being dropped or flushed to disk depending on the memory limit,
+// and that a chain whose head state was lost is rewound on restart.
+func TestStateGC(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		gendb, _ = ethdb.NewMemDatabase()
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				addr:     {Balance: big.NewInt(1000000000000)},
+				contract: {Balance: new(big.Int), Code: common.FromHex("600035600055")}, // sstore(0, calldataload(0))
+			},
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	// Every block modifies both the accounts and the contract storage
+	blocks, _ := GenerateChain(gspec.Config, genesis, gendb, 2*triesInMemory, func(i int, gen *BlockGen) {
+		data := common.BigToHash(big.NewInt(int64(i + 1))).Bytes()
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), contract, big.NewInt(1), big.NewInt(100000), big.NewInt(1), data), signer, key)
+		gen.AddTx(tx)
+	})
+	head := blocks[len(blocks)-1]
+
+	// onDisk checks whether the entire state of a block was written to disk
+	onDisk := func(db ethdb.Database, block *types.Block) bool {
+		return state.MarkState(db, block.Root(), make(map[common.Hash]struct{})) == nil
+	}
+	for _, limit := range []common.StorageSize{DefaultStateGCLimit, 0} {
+		db, _ := ethdb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+		chain.EnableStateGC(limit)
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("limit %v: failed to insert chain: %v", limit, err)
+		}
+		// Recent states must be available from memory, older ones only if flushed
+		for _, block := range blocks {
+			recent := block.NumberU64()+triesInMemory > head.NumberU64()
+			if _, err := chain.StateAt(block.Root()); recent && err != nil {
+				t.Errorf("limit %v: recent state of block #%d missing: %v", limit, block.NumberU64(), err)
+			}
+			if flushed := onDisk(db, block); flushed != (limit == 0 && !recent) {
+				t.Errorf("limit %v: state of block #%d on disk mismatch: have %v, want %v", limit, block.NumberU64(), flushed, !flushed)
+			}
+		}
+		if nodes, _ := chain.stateGC.stats(); limit != 0 && nodes == 0 {
+			t.Errorf("limit %v: no state buffered in memory", limit)
+		}
+		// Reopen the chain without a clean shutdown, losing the states in memory
+		restarted, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+		if err != nil {
+			t.Fatalf("limit %v: failed to reopen chain: %v", limit, err)
+		}
+		want := uint64(0)
+		if limit == 0 {
+			want = head.NumberU64() - triesInMemory
+		}
+		if number := restarted.CurrentBlock().NumberU64(); number != want {
+			t.Errorf("limit %v: repaired head mismatch: have #%d, want #%d", limit, number, want)
+		}
+		restarted.Stop()
+
+		// A clean shutdown must flush the head state to disk
+		chain.Stop()
+		if !onDisk(db, head) {
+			t.Errorf("limit %v: head state not flushed on shutdown", limit)
+		}
+	}
+}
@@ -64,19 +64,36 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		gp           = new(GasPool).AddGas(block.GasLimit())
 	)
 	// Mutate the the block and state according to any hard-fork specs
-	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
+	daoFork := p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0
+	if daoFork {
 		misc.ApplyDAOHardFork(statedb)
 	}
-	// Iterate over and process the individual transactions
-	for i, tx := range block.Transactions() {
-		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, totalUsedGas, cfg)
-		if err != nil {
+	if cfg.TrackStateAccess {
+		statedb.EnableAccessTracking()
+	}
+	// Iterate over and process the individual transactions, speculatively in
+	// parallel if requested and the state is still at the parent root
+	if cfg.ParallelExecution && !cfg.Debug && !cfg.RecordWitness && !daoFork && len(block.Transactions()) > 1 {
+		var err error
+		if receipts, err = p.applyParallel(block, statedb, gp, totalUsedGas, cfg); err != nil {
 			return nil, nil, nil, err
 		}
-		receipts = append(receipts, receipt)
-		allLogs = append(allLogs, receipt.Logs...)
+		for _, receipt := range receipts {
+			allLogs = append(allLogs, receipt.Logs...)
+		}
+	} else {
+		for i, tx := range block.Transactions() {
+			statedb.Prepare(tx.Hash(), block.Hash(), i)
+			receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, totalUsedGas, cfg)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+		}
 	}
+	statedb.StopAccessTracking()
+
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
 
@@ -103,6 +120,12 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 		return nil, nil, err
 	}
 
+	return newReceipt(config, statedb, header, tx, msg, gas, usedGas), gas, err
+}
+
+// newReceipt updates the state with the pending changes of an applied transaction
+// and creates its receipt.
+func newReceipt(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, msg types.Message, gas, usedGas *big.Int) *types.Receipt {
 	// Update the state with pending changes
 	usedGas.Add(usedGas, gas)
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
@@ -113,12 +136,12 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	receipt.GasUsed = new(big.Int).Set(gas)
 	// if the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
-		receipt.ContractAddress = crypto.CreateAddress(vmenv.Context.Origin, tx.Nonce())
+		receipt.ContractAddress = crypto.CreateAddress(msg.From(), tx.Nonce())
 	}
 
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 
-	return receipt, gas, err
+	return receipt
 }
@@ -0,0 +1,202 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/log"
+)
+
+// BlockRange is an inclusive range of block numbers.
+type BlockRange struct {
+	First, Last uint64
+}
+
+// String implements fmt.Stringer.
+func (r BlockRange) String() string {
+	if r.First == r.Last {
+		return strconv.FormatUint(r.First, 10)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// ParseBlockRanges parses a comma separated list of block numbers and inclusive
+// block ranges, e.g. "1000-2000,4370000".
+func ParseBlockRanges(spec string) ([]BlockRange, error) {
+	var ranges []BlockRange
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block range %q: %v", item, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid block range %q: %v", item, err)
+			}
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid block range %q: first block above last", item)
+		}
+		ranges = append(ranges, BlockRange{first, last})
+	}
+	return ranges, nil
+}
+
+// PruneStats contains the results of a state pruning run.
+type PruneStats struct {
+	Retained int                // Number of blocks whose state was retained
+	Missing  int                // Number of pinned blocks whose state was already gone
+	Marked   int                // Number of trie nodes and contract codes retained
+	Deleted  int                // Number of trie nodes and contract codes deleted
+	Freed    common.StorageSize // Size of the deleted data
+}
+
+// PruneState deletes all the state trie nodes and contract codes from the chain
+// database that aren't part of the state of a retained block: the recent blocks
+// leading up to the current head, and all the blocks within the pinned ranges.
+// The nodes of the auxiliary tries (e.g. CHTs) with the given roots are kept too.
+//
+// Pruning is a mark and sweep over the entire database, so the database must not
+// be used by a running node in the meantime. Pinned blocks whose state has been
+// pruned before are skipped, history can't be brought back by pinning it later.
+func PruneState(db ethdb.Database, pinned []BlockRange, recent uint64, tries []common.Hash) (*PruneStats, error) {
+	iteratee, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return nil, errors.New("database not iterable")
+	}
+	if recent == 0 {
+		return nil, errors.New("state of the head block must be retained")
+	}
+	head := GetBlockNumber(db, GetHeadBlockHash(db))
+	if head == missingNumber {
+		return nil, errors.New("missing head block")
+	}
+	// Assemble the sorted and merged list of block ranges to retain
+	var recentFirst uint64
+	if head >= recent {
+		recentFirst = head - recent + 1
+	}
+	ranges := []BlockRange{{recentFirst, head}}
+	for _, r := range pinned {
+		if r.First > head {
+			continue
+		}
+		if r.Last > head {
+			r.Last = head
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].First < ranges[j].First })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		if last := &merged[len(merged)-1]; r.First <= last.Last+1 {
+			if r.Last > last.Last {
+				last.Last = r.Last
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	// Mark the state of all the retained blocks, successive states sharing most
+	// of their nodes, along with the auxiliary tries
+	var (
+		stats  = new(PruneStats)
+		marked = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for _, r := range merged {
+		log.Info("Marking retained state", "blocks", r)
+		for number := r.First; ; number++ {
+			header := GetHeader(db, GetCanonicalHash(db, number), number)
+			if header == nil {
+				return nil, fmt.Errorf("missing canonical header #%d", number)
+			}
+			if _, err := db.Get(header.Root[:]); err != nil && header.Root != types.EmptyRootHash {
+				if number >= recentFirst {
+					return nil, fmt.Errorf("missing state of recent block #%d", number)
+				}
+				stats.Missing++
+			} else {
+				if err := state.MarkState(db, header.Root, marked); err != nil {
+					return nil, fmt.Errorf("failed to mark state of block #%d: %v", number, err)
+				}
+				stats.Retained++
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Marking retained state", "number", number, "nodes", len(marked), "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+			if number == r.Last {
+				break
+			}
+		}
+	}
+	for _, root := range tries {
+		if err := state.MarkTrie(db, root, marked); err != nil {
+			return nil, fmt.Errorf("failed to mark trie %x: %v", root, err)
+		}
+	}
+	stats.Marked = len(marked)
+	log.Info("Marked retained state", "blocks", stats.Retained, "nodes", stats.Marked, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep all the unmarked trie nodes and codes, both stored under their hash
+	it := iteratee.NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if _, ok := marked[common.BytesToHash(key)]; ok {
+			continue
+		}
+		if err := db.Delete(common.CopyBytes(key)); err != nil {
+			return nil, err
+		}
+		stats.Deleted++
+		stats.Freed += common.StorageSize(len(key) + len(it.Value()))
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Deleting pruned state", "nodes", stats.Deleted, "size", stats.Freed, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	log.Info("Pruned state", "retained", stats.Retained, "missing", stats.Missing, "deleted", stats.Deleted, "size", stats.Freed, "elapsed", common.PrettyDuration(time.Since(start)))
+	return stats, nil
+}
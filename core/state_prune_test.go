@@ -0,0 +1,142 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+func TestParseBlockRanges(t *testing.T) {
+	tests := []struct {
+		spec   string
+		ranges []BlockRange
+		fail   bool
+	}{
+		{spec: "", ranges: nil},
+		{spec: "7", ranges: []BlockRange{{7, 7}}},
+		{spec: "1000-2000, 5,10-10", ranges: []BlockRange{{1000, 2000}, {5, 5}, {10, 10}}},
+		{spec: "20-10", fail: true},
+		{spec: "1-", fail: true},
+		{spec: "a", fail: true},
+	}
+	for _, tt := range tests {
+		ranges, err := ParseBlockRanges(tt.spec)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(ranges, tt.ranges) {
+			t.Errorf("%q: ranges mismatch: have %v, want %v", tt.spec, ranges, tt.ranges)
+		}
+	}
+}
+
+// Tests that pruning retains the state of the recent and pinned blocks only, and
+// that the chain remains operational afterwards.
+func TestPruneState(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000aaa")
+		db, _    = ethdb.NewMemDatabase()
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				addr:     {Balance: big.NewInt(1000000000000)},
+				contract: {Balance: new(big.Int), Code: common.FromHex("600035600055")}, // sstore(0, calldataload(0))
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	// Every block modifies both the accounts and the contract storage
+	generate := func(parent *types.Block, n int, offset int) []*types.Block {
+		blocks, _ := GenerateChain(gspec.Config, parent, db, n, func(i int, gen *BlockGen) {
+			data := common.BigToHash(big.NewInt(int64(offset + i + 1))).Bytes()
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), contract, big.NewInt(1), big.NewInt(100000), big.NewInt(1), data), signer, key)
+			gen.AddTx(tx)
+		})
+		return blocks
+	}
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	blocks := generate(genesis, 20, 0)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	stats, err := PruneState(db, []BlockRange{{5, 7}, {100, 200}}, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if stats.Retained != 7 || stats.Missing != 0 || stats.Deleted == 0 {
+		t.Fatalf("prune stats mismatch: %+v", stats)
+	}
+	// Check that exactly the retained states are available in full
+	for number := uint64(0); number <= 20; number++ {
+		retained := (number >= 5 && number <= 7) || number > 16
+		err := state.MarkState(db, GetHeader(db, GetCanonicalHash(db, number), number).Root, make(map[common.Hash]struct{}))
+		if retained && err != nil {
+			t.Errorf("state of block #%d incomplete: %v", number, err)
+		}
+		if !retained && err == nil {
+			t.Errorf("state of block #%d retained", number)
+		}
+	}
+	// Pruning again must skip the already pruned pinned blocks
+	if stats, err = PruneState(db, []BlockRange{{3, 7}}, 4, nil); err != nil {
+		t.Fatalf("failed to prune state again: %v", err)
+	}
+	if stats.Retained != 7 || stats.Missing != 2 || stats.Deleted != 0 {
+		t.Errorf("repeated prune stats mismatch: %+v", stats)
+	}
+	// Reopen the chain and ensure it can be extended
+	chain, err = NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock().NumberU64(); head != 20 {
+		t.Fatalf("head mismatch after prune: have #%d, want #20", head)
+	}
+	if _, err := chain.InsertChain(generate(chain.CurrentBlock(), 5, 20)); err != nil {
+		t.Fatalf("failed to extend pruned chain: %v", err)
+	}
+	statedb, _ := chain.State()
+	if slot := statedb.GetState(contract, common.Hash{}); slot != common.BigToHash(big.NewInt(25)) {
+		t.Errorf("contract storage mismatch: have %x, want %x", slot, common.BigToHash(big.NewInt(25)))
+	}
+}
@@ -59,6 +59,7 @@ type StateTransition struct {
 	value      *big.Int
 	data       []byte
 	state      vm.StateDB
+	vmerr      error // Error the EVM aborted the execution with, if any
 
 	evm *vm.EVM
 }
@@ -128,10 +129,37 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 // indicates a core error meaning that the message would always fail for that particular
 // state and would never be accepted within a block.
 func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, *big.Int, error) {
+	result, err := ExecuteMessage(evm, msg, gp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.ReturnData, result.UsedGas, nil
+}
+
+// ExecutionResult is the outcome of a message executed by the EVM. Errors the
+// EVM aborted the execution with (e.g. running out of gas) don't invalidate the
+// message, the gas is consumed regardless.
+type ExecutionResult struct {
+	ReturnData []byte   // Data returned by the EVM execution
+	UsedGas    *big.Int // Gas used by the execution, including refunds
+	Err        error    // Error the EVM aborted the execution with, if any
+}
+
+// Failed returns whether the EVM aborted the execution.
+func (result *ExecutionResult) Failed() bool {
+	return result.Err != nil
+}
+
+// ExecuteMessage applies the given message like ApplyMessage, but additionally
+// reports the error the EVM aborted the execution with in the result.
+func ExecuteMessage(evm *vm.EVM, msg Message, gp *GasPool) (*ExecutionResult, error) {
 	st := NewStateTransition(evm, msg, gp)
 
 	ret, _, gasUsed, err := st.TransitionDb()
-	return ret, gasUsed, err
+	if err != nil {
+		return nil, err
+	}
+	return &ExecutionResult{ReturnData: ret, UsedGas: gasUsed, Err: st.vmerr}, nil
 }
 
 func (st *StateTransition) from() vm.AccountRef {
@@ -243,6 +271,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 		ret, st.gas, vmerr = evm.Call(sender, st.to().Address(), st.data, st.gas, st.value)
 	}
 	if vmerr != nil {
+		st.vmerr = vmerr
 		log.Debug("VM returned with error", "err", vmerr)
 		// The only possible consensus-error would be if there wasn't
 		// sufficient balance to make the transfer happen. The first
@@ -266,9 +295,12 @@ func (st *StateTransition) refundGas() {
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
 	st.state.AddBalance(sender.Address(), remaining)
 
-	// Apply refund counter, capped to half of the used gas.
-	uhalf := remaining.Div(st.gasUsed(), common.Big2)
-	refund := math.BigMin(uhalf, st.state.GetRefund())
+	// Apply refund counter, capped to a fraction of the used gas (none if zero).
+	refund := new(big.Int)
+	if quotient := st.evm.ChainConfig().GasTable(st.evm.BlockNumber).RefundQuotient; quotient > 0 {
+		limit := remaining.Div(st.gasUsed(), new(big.Int).SetUint64(quotient))
+		refund = math.BigMin(limit, st.state.GetRefund())
+	}
 	st.gas += refund.Uint64()
 
 	st.state.AddBalance(sender.Address(), refund.Mul(refund, st.gasPrice))
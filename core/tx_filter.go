@@ -0,0 +1,94 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+// TxFilter is a hook consulted before a transaction is accepted into a transaction
+// pool, and thus before it is relayed to the network. It allows embedders to
+// enforce local admission rules (e.g. compliance blocklists or value caps) on
+// top of the consensus validity checks.
+//
+// Filters are invoked with the pool lock held, so they must be fast and must not
+// call back into the pool.
+type TxFilter interface {
+	// FilterTx returns a non-nil error if the transaction sent by the given
+	// account should be rejected. Local is set for transactions submitted
+	// through the node itself rather than received from the network.
+	FilterTx(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// TxFilterFunc is an adapter to allow the use of ordinary functions as
+// transaction filters.
+type TxFilterFunc func(tx *types.Transaction, from common.Address, local bool) error
+
+// FilterTx implements TxFilter, calling f(tx, from, local).
+func (f TxFilterFunc) FilterTx(tx *types.Transaction, from common.Address, local bool) error {
+	return f(tx, from, local)
+}
+
+// TxFilters is a list of transaction filters, rejecting a transaction if any of
+// its members does.
+type TxFilters []TxFilter
+
+// FilterTx implements TxFilter, running all member filters in order.
+func (fs TxFilters) FilterTx(tx *types.Transaction, from common.Address, local bool) error {
+	for _, f := range fs {
+		if err := f.FilterTx(tx, from, local); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewBlocklistFilter creates a transaction filter rejecting all transactions
+// sent from or to any of the given accounts.
+func NewBlocklistFilter(addrs ...common.Address) TxFilter {
+	blocked := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		blocked[addr] = struct{}{}
+	}
+	return TxFilterFunc(func(tx *types.Transaction, from common.Address, local bool) error {
+		if _, ok := blocked[from]; ok {
+			return fmt.Errorf("blocklisted sender %x", from)
+		}
+		if to := tx.To(); to != nil {
+			if _, ok := blocked[*to]; ok {
+				return fmt.Errorf("blocklisted recipient %x", *to)
+			}
+		}
+		return nil
+	})
+}
+
+// NewValueCapFilter creates a transaction filter rejecting all transactions
+// transferring more than the given value.
+func NewValueCapFilter(max *big.Int) TxFilter {
+	max = new(big.Int).Set(max)
+	return TxFilterFunc(func(tx *types.Transaction, from common.Address, local bool) error {
+		if tx.Value().Cmp(max) > 0 {
+			return fmt.Errorf("value %v exceeds cap %v", tx.Value(), max)
+		}
+		return nil
+	})
+}
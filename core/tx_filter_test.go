@@ -0,0 +1,80 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
+)
+
+// Tests that transactions rejected by the registered filters are not accepted
+// into the pool, whereas others pass through unaffected.
+func TestTransactionFilters(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	currentState, _ := pool.currentState()
+	currentState.AddBalance(from, big.NewInt(1000000000))
+
+	blocked := common.HexToAddress("0xdeadbeef")
+	pool.AddFilter(NewBlocklistFilter(blocked))
+	pool.AddFilter(NewValueCapFilter(big.NewInt(1000)))
+
+	var seen []bool
+	pool.AddFilter(TxFilterFunc(func(tx *types.Transaction, sender common.Address, local bool) error {
+		if sender != from {
+			t.Errorf("filter sender mismatch: have %x, want %x", sender, from)
+		}
+		seen = append(seen, local)
+		return nil
+	}))
+	sign := func(nonce uint64, to common.Address, value int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), big.NewInt(100000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	if err := pool.AddRemote(sign(0, blocked, 1)); err == nil {
+		t.Errorf("transaction to blocklisted account accepted")
+	}
+	if err := pool.AddLocal(sign(0, common.Address{}, 1001)); err == nil {
+		t.Errorf("transaction above value cap accepted")
+	}
+	if err := pool.AddLocal(sign(0, common.Address{}, 1000)); err != nil {
+		t.Errorf("valid local transaction rejected: %v", err)
+	}
+	if err := pool.AddRemote(sign(1, common.Address{}, 1)); err != nil {
+		t.Errorf("valid remote transaction rejected: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Errorf("pool stats mismatch: have %d/%d, want 2/0", pending, queued)
+	}
+	if len(seen) != 2 || !seen[0] || seen[1] {
+		t.Errorf("filter locality mismatch: have %v, want [true false]", seen)
+	}
+	// Blocklisted senders should also be rejected
+	pool.AddFilter(NewBlocklistFilter(from))
+	if err := pool.AddLocal(sign(2, common.Address{}, 1)); err == nil {
+		t.Errorf("transaction from blocklisted account accepted")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
@@ -316,6 +316,17 @@ func (l *txList) Cap(threshold int) types.Transactions {
 	return l.txs.Cap(threshold)
 }
 
+// Exceeds checks whether inserting a transaction into a list already holding
+// threshold items would get it capped off right away, i.e. it doesn't replace
+// an existing transaction and its nonce is above all the contained ones.
+func (l *txList) Exceeds(tx *types.Transaction, threshold int) bool {
+	if l.txs.Len() < threshold || l.txs.Get(tx.Nonce()) != nil {
+		return false
+	}
+	txs := l.txs.Flatten()
+	return len(txs) > 0 && txs[len(txs)-1].Nonce() < tx.Nonce()
+}
+
 // Remove deletes a transaction from the maintained list, returning whether the
 // transaction was found, and also returning any transaction invalidated due to
 // the deletion (strict mode only).
@@ -70,6 +70,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrQueueFull is returned if a remote transaction is not executable and its
+	// sender already filled its allowance of queued transactions.
+	ErrQueueFull = errors.New("account queue full")
 )
 
 var (
@@ -93,13 +97,18 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewCounter("txpool/invalid")
 	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
+	filteredTxCounter    = metrics.NewCounter("txpool/filtered")
 )
 
 type stateFn func() (*state.StateDB, error)
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals bool // Whether local transaction handling should be disabled
+	NoLocals    bool             // Whether local transaction handling should be disabled
+	Locals      []common.Address // Addresses that should be treated by default as local
+	Journal     string           // Journal of local transactions to survive node restarts
+	Rejournal   time.Duration    // Time interval to regenerate the local transaction journal
+	Rebroadcast time.Duration    // Time interval to re-announce pending local transactions (0 = disabled)
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -115,6 +124,9 @@ type TxPoolConfig struct {
 // DefaultTxPoolConfig contains the default configurations for the transaction
 // pool.
 var DefaultTxPoolConfig = TxPoolConfig{
+	Rejournal:   time.Hour,
+	Rebroadcast: 10 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -130,6 +142,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 // unreasonable or unworkable.
 func (config *TxPoolConfig) sanitize() TxPoolConfig {
 	conf := *config
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -157,7 +173,10 @@ type TxPool struct {
 	gasPrice     *big.Int
 	eventMux     *event.TypeMux
 	events       *event.TypeMuxSubscription
-	locals       *accountSet
+	locals       *accountSet              // Set of local accounts to exempt from eviction rules
+	journal      *txJournal               // Journal of local transactions to back up to disk
+	filters      TxFilters                // Embedder hooks consulted before accepting transactions
+	private      map[common.Hash]struct{} // Local transactions never to be announced to the network
 	signer       types.Signer
 	mu           sync.RWMutex
 
@@ -188,6 +207,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *e
 		queue:        make(map[common.Address]*txList),
 		beats:        make(map[common.Address]time.Time),
 		all:          make(map[common.Hash]*types.Transaction),
+		private:      make(map[common.Hash]struct{}),
 		eventMux:     eventMux,
 		currentState: currentStateFn,
 		gasLimit:     gasLimitFn,
@@ -197,9 +217,25 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *e
 		quit:         make(chan struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	for _, addr := range config.Locals {
+		log.Info("Setting new local account", "address", addr)
+		pool.locals.add(addr)
+	}
 	pool.priced = newTxPricedList(&pool.all)
 	pool.resetState()
 
+	// If local transactions and journaling is enabled, load from disk
+	if !config.NoLocals && config.Journal != "" {
+		pool.journal = newTxJournal(config.Journal)
+
+		if err := pool.journal.load(pool.AddLocal); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		if err := pool.journal.rotate(pool.local()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+	}
+
 	// Start the various events loops and return
 	pool.wg.Add(2)
 	go pool.eventLoop()
@@ -217,6 +253,16 @@ func (pool *TxPool) eventLoop() {
 	report := time.NewTicker(statsReportInterval)
 	defer report.Stop()
 
+	journal := time.NewTicker(pool.config.Rejournal)
+	defer journal.Stop()
+
+	var rebroadcast <-chan time.Time
+	if pool.config.Rebroadcast > 0 {
+		ticker := time.NewTicker(pool.config.Rebroadcast)
+		defer ticker.Stop()
+		rebroadcast = ticker.C
+	}
+
 	// Track chain events. When a chain events occurs (new chain canon block)
 	// we need to know the new state. The new state will help us determine
 	// the nonces in the managed state
@@ -253,6 +299,32 @@ func (pool *TxPool) eventLoop() {
 				log.Debug("Transaction pool status report", "executable", pending, "queued", queued, "stales", stales)
 				prevPending, prevQueued, prevStales = pending, queued, stales
 			}
+
+		// Handle local transaction journal rotation
+		case <-journal.C:
+			if pool.journal != nil {
+				pool.mu.Lock()
+				if err := pool.journal.rotate(pool.local()); err != nil {
+					log.Warn("Failed to rotate local tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
+
+		// Handle local transaction rebroadcasts until they get mined
+		case <-rebroadcast:
+			pool.mu.RLock()
+			var txs types.Transactions
+			for addr, list := range pool.pending {
+				if pool.locals.contains(addr) {
+					txs = append(txs, pool.public(list.Flatten())...)
+				}
+			}
+			pool.mu.RUnlock()
+
+			if len(txs) > 0 {
+				log.Debug("Rebroadcasting local transactions", "count", len(txs))
+				go pool.eventMux.Post(TxRebroadcastEvent{txs})
+			}
 		}
 	}
 }
@@ -287,6 +359,10 @@ func (pool *TxPool) Stop() {
 	close(pool.quit)
 	pool.wg.Wait()
 
+	if pool.journal != nil {
+		pool.journal.close()
+	}
+
 	log.Info("Transaction pool stopped")
 }
 
@@ -298,6 +374,12 @@ func (pool *TxPool) GasPrice() *big.Int {
 	return new(big.Int).Set(pool.gasPrice)
 }
 
+// PriceBump returns the minimum price bump percentage the transaction pool
+// requires to replace an already pending transaction.
+func (pool *TxPool) PriceBump() uint64 {
+	return pool.config.PriceBump
+}
+
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
@@ -311,6 +393,16 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// AddFilter registers a hook to be consulted before any new transaction is
+// accepted into the pool (and thus broadcast to the network). Transactions
+// already in the pool are not re-checked.
+func (pool *TxPool) AddFilter(filter TxFilter) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.filters = append(pool.filters, filter)
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -359,7 +451,7 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 	return pending, queued
 }
 
-// Pending retrieves all currently processable transactions, groupped by origin
+// Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
 func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
@@ -373,6 +465,49 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// PendingFrom retrieves the processable transactions of the given accounts,
+// sorted by nonce. Accounts without any pending transactions are omitted.
+func (pool *TxPool) PendingFrom(addrs []common.Address) map[common.Address]types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pending := make(map[common.Address]types.Transactions)
+	for _, addr := range addrs {
+		if list := pool.pending[addr]; list != nil {
+			pending[addr] = list.Flatten()
+		}
+	}
+	return pending
+}
+
+// Locals retrieves the accounts currently considered local by the pool.
+func (pool *TxPool) Locals() []common.Address {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	locals := make([]common.Address, 0, len(pool.locals.accounts))
+	for addr := range pool.locals.accounts {
+		locals = append(locals, addr)
+	}
+	return locals
+}
+
+// local retrieves all currently known local transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) local() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr := range pool.locals.accounts {
+		if pending := pool.pending[addr]; pending != nil {
+			txs[addr] = append(txs[addr], pool.public(pending.Flatten())...)
+		}
+		if queued := pool.queue[addr]; queued != nil {
+			txs[addr] = append(txs[addr], pool.public(queued.Flatten())...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -419,6 +554,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// filterTx runs the transaction through the embedder supplied filters.
+func (pool *TxPool) filterTx(tx *types.Transaction, local bool) error {
+	if len(pool.filters) == 0 {
+		return nil
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	return pool.filters.FilterTx(tx, from, local)
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -440,6 +584,22 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	if err := pool.filterTx(tx, local); err != nil {
+		log.Debug("Discarding filtered transaction", "hash", hash, "err", err)
+		filteredTxCounter.Inc(1)
+		return false, err
+	}
+	// If the sender already filled its allowance of non-executable transactions
+	// and this one would be capped off anyway, drop it before it gets a chance
+	// to evict others (queues starting at the pending nonce are still promotable)
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	if !local && !pool.locals.contains(from) {
+		if list := pool.queue[from]; list != nil && list.Exceeds(tx, int(pool.config.AccountQueue)) && list.Flatten()[0].Nonce() > pool.pendingState.GetNonce(from) {
+			log.Trace("Discarding queue-exceeding transaction", "hash", hash, "from", from, "nonce", tx.Nonce())
+			queuedRateLimitCounter.Inc(1)
+			return false, ErrQueueFull
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -457,7 +617,6 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -470,9 +629,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			delete(pool.all, old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.notifyDrop(old, tx)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
+		pool.journalTx(from, tx)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 		return old != nil, nil
@@ -485,6 +646,8 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if local {
 		pool.locals.add(from)
 	}
+	pool.journalTx(from, tx)
+
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replace, nil
 }
@@ -509,12 +672,32 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.notifyDrop(old, tx)
+	}
+	// Postponed pending transactions are already tracked, don't double count
+	if pool.all[hash] == nil {
+		pool.all[hash] = tx
+		pool.priced.Put(tx)
 	}
-	pool.all[hash] = tx
-	pool.priced.Put(tx)
 	return old != nil, nil
 }
 
+// journalTx adds the specified transaction to the local disk journal if it is
+// deemed to have been sent from a local account.
+func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
+	// Only journal if it's enabled and the transaction is local
+	if pool.journal == nil || !pool.locals.contains(from) {
+		return
+	}
+	// Private transactions are only meant for the current run of the local miner
+	if _, ok := pool.private[tx.Hash()]; ok {
+		return
+	}
+	if err := pool.journal.insert(tx); err != nil {
+		log.Warn("Failed to journal local transaction", "err", err)
+	}
+}
+
 // promoteTx adds a transaction to the pending (processable) list of transactions.
 //
 // Note, this method assumes the pool lock is held!
@@ -532,6 +715,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.notifyDrop(tx, list.txs.Get(tx.Nonce()))
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -540,6 +724,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.notifyDrop(old, tx)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -549,7 +734,15 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
-	go pool.eventMux.Post(TxPreEvent{tx})
+	_, private := pool.private[hash]
+	go pool.eventMux.Post(TxPreEvent{Tx: tx, Private: private})
+}
+
+// notifyDrop announces to any subsystems that a transaction left the pool without
+// being included, optionally superseded by a replacement.
+func (pool *TxPool) notifyDrop(tx *types.Transaction, replacement *types.Transaction) {
+	delete(pool.private, tx.Hash())
+	go pool.eventMux.Post(TxDropEvent{Tx: tx, Replacement: replacement})
 }
 
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
@@ -559,6 +752,50 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 	return pool.addTx(tx, !pool.config.NoLocals)
 }
 
+// AddPrivate enqueues a single local transaction into the pool if it is valid,
+// marking it private: it will only ever be included by the local miner and is
+// never announced or rebroadcast to the network, nor persisted to the journal.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	hash := tx.Hash()
+	_, known := pool.private[hash]
+
+	pool.private[hash] = struct{}{}
+	if err := pool.addTxLocked(tx, !pool.config.NoLocals); err != nil {
+		if !known {
+			delete(pool.private, hash)
+		}
+		return err
+	}
+	return nil
+}
+
+// IsPrivate reports whether the transaction with the given hash was added to the
+// pool as a private one and must not be announced to the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
+// public filters the private transactions out of a list.
+func (pool *TxPool) public(txs types.Transactions) types.Transactions {
+	if len(pool.private) == 0 {
+		return txs
+	}
+	public := make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		if _, ok := pool.private[tx.Hash()]; !ok {
+			public = append(public, tx)
+		}
+	}
+	return public
+}
+
 // AddRemote enqueues a single transaction into the pool if it is valid. If the
 // sender is not among the locally tracked ones, full pricing constraints will
 // apply.
@@ -585,6 +822,13 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	return pool.addTxLocked(tx, local)
+}
+
+// addTxLocked enqueues a single transaction into the pool if it is valid.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) addTxLocked(tx *types.Transaction, local bool) error {
 	// Try to inject the transaction and update any state
 	replace, err := pool.add(tx, local)
 	if err != nil {
@@ -604,6 +848,9 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
+	// Recover the senders in one concurrent batch before taking the lock
+	types.CacheSenders(pool.signer, txs)
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -672,6 +919,7 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	// Remove it from the list of known transactions
 	delete(pool.all, hash)
 	pool.priced.Removed()
+	pool.notifyDrop(tx, nil)
 
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
@@ -680,11 +928,10 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 			if pending.Empty() {
 				delete(pool.pending, addr)
 				delete(pool.beats, addr)
-			} else {
-				// Otherwise postpone any invalidated transactions
-				for _, tx := range invalids {
-					pool.enqueueTx(tx.Hash(), tx)
-				}
+			}
+			// Postpone any invalidated transactions (even if the list is gone)
+			for _, tx := range invalids {
+				pool.enqueueTx(tx.Hash(), tx)
 			}
 			// Update the account nonce if needed
 			if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -727,6 +974,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			log.Trace("Removed old queued transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notifyDrop(tx, nil)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(state.GetBalance(addr), gaslimit)
@@ -736,6 +984,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
+			pool.notifyDrop(tx, nil)
 		}
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
@@ -750,6 +999,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 				delete(pool.all, hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.notifyDrop(tx, nil)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -794,6 +1044,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 							hash := tx.Hash()
 							delete(pool.all, hash)
 							pool.priced.Removed()
+							pool.notifyDrop(tx, nil)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -816,6 +1067,7 @@ func (pool *TxPool) promoteExecutables(state *state.StateDB, accounts []common.A
 						hash := tx.Hash()
 						delete(pool.all, hash)
 						pool.priced.Removed()
+						pool.notifyDrop(tx, nil)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -887,6 +1139,7 @@ func (pool *TxPool) demoteUnexecutables(state *state.StateDB) {
 			log.Trace("Removed old pending transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notifyDrop(tx, nil)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(state.GetBalance(addr), gaslimit)
@@ -896,6 +1149,7 @@ func (pool *TxPool) demoteUnexecutables(state *state.StateDB) {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.notifyDrop(tx, nil)
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -19,8 +19,10 @@ package core
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
 	"testing"
 	"time"
 
@@ -619,9 +621,13 @@ func TestTransactionQueueAccountLimiting(t *testing.T) {
 
 	// Keep queuing up transactions and make sure all above a limit are dropped
 	for i := uint64(1); i <= DefaultTxPoolConfig.AccountQueue+5; i++ {
-		if err := pool.AddRemote(transaction(i, big.NewInt(100000), key)); err != nil {
+		err := pool.AddRemote(transaction(i, big.NewInt(100000), key))
+		if i <= DefaultTxPoolConfig.AccountQueue && err != nil {
 			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
 		}
+		if i > DefaultTxPoolConfig.AccountQueue && err != ErrQueueFull {
+			t.Fatalf("tx %d: error mismatch: have %v, want %v", i, err, ErrQueueFull)
+		}
 		if len(pool.pending) != 0 {
 			t.Errorf("tx %d: pending pool size mismatch: have %d, want %d", i, len(pool.pending), 0)
 		}
@@ -640,6 +646,72 @@ func TestTransactionQueueAccountLimiting(t *testing.T) {
 	}
 }
 
+// Tests that once an account fills up its queue allowance, further future
+// transactions from it are rejected without evicting anything from other
+// accounts, even if the pool is full and they pay more.
+func TestTransactionQueueAccountAdmission(t *testing.T) {
+	// Create the pool to test the limit enforcement with
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := DefaultTxPoolConfig
+	config.AccountSlots = 4
+	config.GlobalSlots = 4
+	config.AccountQueue = 4
+	config.GlobalQueue = 4
+
+	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	defer pool.Stop()
+	pool.resetState()
+
+	// Create a spammer and an honest account and fund them
+	state, _ := pool.currentState()
+
+	spammer, _ := crypto.GenerateKey()
+	honest, _ := crypto.GenerateKey()
+	state.AddBalance(crypto.PubkeyToAddress(spammer.PublicKey), big.NewInt(1000000000))
+	state.AddBalance(crypto.PubkeyToAddress(honest.PublicKey), big.NewInt(1000000000))
+
+	// Fill up the pool with pending honest and queued spam transactions
+	for i := uint64(0); i < config.GlobalSlots; i++ {
+		if err := pool.AddRemote(transaction(i, big.NewInt(100000), honest)); err != nil {
+			t.Fatalf("honest tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	for i := uint64(1); i <= config.AccountQueue; i++ {
+		if err := pool.AddRemote(transaction(i, big.NewInt(100000), spammer)); err != nil {
+			t.Fatalf("spam tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	pending, queued := pool.Stats()
+	if pending != int(config.GlobalSlots) {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, config.GlobalSlots)
+	}
+	if queued != int(config.AccountQueue) {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, config.AccountQueue)
+	}
+	// Flood the pool with expensive future transactions and ensure they are rejected
+	// without evicting anything
+	for i := config.AccountQueue + 1; i <= 4*config.AccountQueue; i++ {
+		if err := pool.AddRemote(pricedTransaction(i, big.NewInt(100000), big.NewInt(100), spammer)); err != ErrQueueFull {
+			t.Fatalf("spam tx %d: error mismatch: have %v, want %v", i, err, ErrQueueFull)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != int(config.GlobalSlots) {
+		t.Fatalf("pending transactions evicted: have %d, want %d", pending, config.GlobalSlots)
+	}
+	if _, queued := pool.Stats(); queued != int(config.AccountQueue) {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, config.AccountQueue)
+	}
+	// Replacing an existing queued transaction should still be allowed
+	if err := pool.AddRemote(pricedTransaction(config.AccountQueue, big.NewInt(100000), big.NewInt(2), spammer)); err != nil {
+		t.Fatalf("failed to replace queued transaction: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that if the transaction count belonging to multiple accounts go above
 // some threshold, the higher transactions are dropped to prevent DOS attacks.
 //
@@ -833,6 +905,52 @@ func TestTransactionPendingLimiting(t *testing.T) {
 	}
 }
 
+// Tests that the pending transactions of specific accounts can be retrieved,
+// excluding queued ones and those of other accounts.
+func TestTransactionPendingFrom(t *testing.T) {
+	pool, _ := setupTxPool()
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+
+		state, _ := pool.currentState()
+		state.AddBalance(addrs[i], big.NewInt(1000000))
+	}
+	pool.resetState()
+
+	for i, key := range keys {
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			if err := pool.AddRemote(transaction(nonce, big.NewInt(100000), key)); err != nil {
+				t.Fatalf("account %d, tx %d: failed to add transaction: %v", i, nonce, err)
+			}
+		}
+		// Add a gapped transaction too that may not be returned
+		if err := pool.AddRemote(transaction(5, big.NewInt(100000), key)); err != nil {
+			t.Fatalf("account %d: failed to add queued transaction: %v", i, err)
+		}
+	}
+	pending := pool.PendingFrom([]common.Address{addrs[0], addrs[2], {0x01}})
+	if len(pending) != 2 {
+		t.Fatalf("pending account count mismatch: have %d, want %d", len(pending), 2)
+	}
+	for _, addr := range []common.Address{addrs[0], addrs[2]} {
+		txs := pending[addr]
+		if len(txs) != 3 {
+			t.Errorf("account %x: pending transaction count mismatch: have %d, want %d", addr, len(txs), 3)
+			continue
+		}
+		for nonce, tx := range txs {
+			if tx.Nonce() != uint64(nonce) {
+				t.Errorf("account %x: transaction %d nonce mismatch: have %d, want %d", addr, nonce, tx.Nonce(), nonce)
+			}
+		}
+	}
+}
+
 // Tests that the transaction limits are enforced the same way irrelevant whether
 // the transactions are added one by one or in batches.
 func TestTransactionQueueLimitingEquivalency(t *testing.T)   { testTransactionLimitingEquivalency(t, 1) }
@@ -846,7 +964,7 @@ func testTransactionLimitingEquivalency(t *testing.T, origin uint64) {
 	state1.AddBalance(account1, big.NewInt(1000000))
 
 	for i := uint64(0); i < DefaultTxPoolConfig.AccountQueue+5; i++ {
-		if err := pool1.AddRemote(transaction(origin+i, big.NewInt(100000), key1)); err != nil {
+		if err := pool1.AddRemote(transaction(origin+i, big.NewInt(100000), key1)); err != nil && err != ErrQueueFull {
 			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
 		}
 	}
@@ -1185,6 +1303,47 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that transactions leaving the pool unmined are announced, together with
+// their replacement if superseded by another transaction.
+func TestTransactionDropEvents(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	state, _ := pool.currentState()
+	state.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	sub := pool.eventMux.Subscribe(TxDropEvent{})
+	defer sub.Unsubscribe()
+
+	waitDrop := func(tx, replacement *types.Transaction) {
+		select {
+		case ev := <-sub.Chan():
+			drop := ev.Data.(TxDropEvent)
+			if drop.Tx.Hash() != tx.Hash() {
+				t.Errorf("dropped transaction mismatch: have %x, want %x", drop.Tx.Hash(), tx.Hash())
+			}
+			if (drop.Replacement == nil) != (replacement == nil) || (replacement != nil && drop.Replacement.Hash() != replacement.Hash()) {
+				t.Errorf("replacement mismatch: have %v, want %v", drop.Replacement, replacement)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop event timeout")
+		}
+	}
+	original := pricedTransaction(0, big.NewInt(100000), big.NewInt(1), key)
+	replacement := pricedTransaction(0, big.NewInt(100000), big.NewInt(2), key)
+
+	if err := pool.AddRemote(original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to replace original transaction: %v", err)
+	}
+	waitDrop(original, replacement)
+
+	pool.Remove(replacement.Hash())
+	waitDrop(replacement, nil)
+}
+
 // Tests that the pool rejects replacement transactions that don't meet the minimum
 // price bump required.
 func TestTransactionReplacement(t *testing.T) {
@@ -1250,6 +1409,180 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that local transactions are journaled to disk, but remote transactions
+// get discarded between restarts.
+func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
+func TestTransactionJournalingNoLocals(t *testing.T) { testTransactionJournaling(t, true) }
+
+func testTransactionJournaling(t *testing.T, nolocals bool) {
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	stateFn := func() (*state.StateDB, error) { return statedb, nil }
+	gasLimitFn := func() *big.Int { return big.NewInt(1000000) }
+
+	config := DefaultTxPoolConfig
+	config.NoLocals = nolocals
+	config.Journal = journal
+	config.Rejournal = time.Second
+
+	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), stateFn, gasLimitFn)
+
+	// Create two test accounts to ensure remotes expire but locals do not
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	statedb.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	statedb.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add three local and a remote transactions and ensure they are queued up
+	if err := pool.AddLocal(pricedTransaction(0, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(1, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(2, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, big.NewInt(100000), big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pending, queued := pool.Stats()
+	if pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Terminate the old pool, bump the local nonce, create a new pool and ensure relevant transaction survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
+	pool = NewTxPool(config, params.TestChainConfig, new(event.TypeMux), stateFn, gasLimitFn)
+
+	pending, queued = pool.Stats()
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if nolocals {
+		if pending != 0 {
+			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 0)
+		}
+	} else {
+		if pending != 2 {
+			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+}
+
+// Tests that accounts configured as locals are exempt from the pricing
+// constraints even if their transactions arrive from the network.
+func TestTransactionConfiguredLocals(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	statedb.AddBalance(addr, big.NewInt(1000000000))
+
+	config := DefaultTxPoolConfig
+	config.PriceLimit = 2
+	config.Locals = []common.Address{addr}
+
+	pool := NewTxPool(config, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	defer pool.Stop()
+
+	if err := pool.AddRemote(pricedTransaction(0, big.NewInt(100000), big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add underpriced configured local transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+}
+
+// Tests that private transactions are announced as such, are excluded from the
+// local transactions persisted to the journal, and lose their status once they
+// leave the pool.
+func TestTransactionPrivate(t *testing.T) {
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	state, _ := pool.currentState()
+	state.AddBalance(from, big.NewInt(1000000000))
+
+	sub := pool.eventMux.Subscribe(TxPreEvent{})
+	defer sub.Unsubscribe()
+
+	waitPre := func(tx *types.Transaction, private bool) {
+		select {
+		case ev := <-sub.Chan():
+			pre := ev.Data.(TxPreEvent)
+			if pre.Tx.Hash() != tx.Hash() {
+				t.Errorf("announced transaction mismatch: have %x, want %x", pre.Tx.Hash(), tx.Hash())
+			}
+			if pre.Private != private {
+				t.Errorf("privacy mismatch: have %v, want %v", pre.Private, private)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("pre event timeout")
+		}
+	}
+	public := transaction(0, big.NewInt(100000), key)
+	private := transaction(1, big.NewInt(100000), key)
+
+	if err := pool.AddLocal(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	waitPre(public, false)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	waitPre(private, true)
+
+	if pool.IsPrivate(public.Hash()) || !pool.IsPrivate(private.Hash()) {
+		t.Errorf("privacy flags mismatch: public %v, private %v", pool.IsPrivate(public.Hash()), pool.IsPrivate(private.Hash()))
+	}
+	// Re-adding a known private transaction must not lose its status
+	if err := pool.AddPrivate(private); err == nil {
+		t.Errorf("duplicate private transaction accepted")
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Errorf("private transaction lost status on duplicate insertion")
+	}
+	// Only the public transaction should be considered for persistence
+	pool.mu.RLock()
+	local := pool.local()[from]
+	pool.mu.RUnlock()
+	if len(local) != 1 || local[0].Hash() != public.Hash() {
+		t.Errorf("journaled transactions mismatch: have %v, want [%x]", local, public.Hash())
+	}
+	// Removing the transaction should drop its private status
+	pool.Remove(private.Hash())
+	if pool.IsPrivate(private.Hash()) {
+		t.Errorf("removed transaction still private")
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
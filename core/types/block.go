@@ -65,6 +65,7 @@ func (n *BlockNonce) UnmarshalText(input []byte) error {
 }
 
 //go:generate gencodec -type Header -field-override headerMarshaling -out gen_header_json.go
+//go:generate rlpgen -type Header -out gen_header_rlp.go
 
 // Header represents a block header in the NetworkChain blockchain.
 type Header struct {
@@ -102,6 +103,13 @@ func (h *Header) Hash() common.Hash {
 	return rlpHash(h)
 }
 
+// Size returns the RLP encoded size of the header.
+func (h *Header) Size() common.StorageSize {
+	c := writeCounter(0)
+	rlp.Encode(&c, h)
+	return common.StorageSize(c)
+}
+
 // HashNoNonce returns the hash which is used as input for the proof-of-work search.
 func (h *Header) HashNoNonce() common.Hash {
 	return rlpHash([]interface{}{
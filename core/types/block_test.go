@@ -68,3 +68,78 @@ func TestBlockEncoding(t *testing.T) {
 		t.Errorf("encoded block mismatch:\ngot:  %x\nwant: %x", ourBlockEnc, blockEnc)
 	}
 }
+
+// TestGeneratedRLP checks that the generated header and receipt codecs produce
+// the same encoding as the reflection based encoder and round trip correctly.
+func TestGeneratedRLP(t *testing.T) {
+	// Type without the generated methods, forcing reflection encoding
+	type reflectHeader Header
+
+	header := &Header{
+		ParentHash: common.HexToHash("0x01"),
+		Coinbase:   common.HexToAddress("0x8888f1f195afa192cfee860698584c030f4c9db1"),
+		Difficulty: big.NewInt(131072),
+		Number:     big.NewInt(100),
+		GasLimit:   big.NewInt(3141592),
+		GasUsed:    big.NewInt(21000),
+		Time:       big.NewInt(1426516743),
+		Extra:      []byte("extra"),
+		Nonce:      EncodeNonce(0xa13a5a8c8f2bb1c4),
+	}
+	receipt := &Receipt{
+		PostState:         common.HexToHash("0x02").Bytes(),
+		CumulativeGasUsed: big.NewInt(42000),
+		Logs: []*Log{
+			{Address: common.HexToAddress("0x03"), Topics: []common.Hash{common.HexToHash("0x04")}, Data: []byte{5}},
+			{Address: common.HexToAddress("0x06"), Topics: []common.Hash{}, Data: []byte{}},
+		},
+	}
+	for _, test := range []struct {
+		name      string
+		generated interface{}
+		reflected interface{}
+		decoded   interface{}
+	}{
+		{"header", header, (*reflectHeader)(header), new(Header)},
+		{"receipt", receipt, []interface{}{receipt.PostState, receipt.CumulativeGasUsed, receipt.Bloom, []interface{}{
+			[]interface{}{receipt.Logs[0].Address, receipt.Logs[0].Topics, receipt.Logs[0].Data},
+			[]interface{}{receipt.Logs[1].Address, receipt.Logs[1].Topics, receipt.Logs[1].Data},
+		}}, new(Receipt)},
+	} {
+		have, err := rlp.EncodeToBytes(test.generated)
+		if err != nil {
+			t.Fatalf("%s: generated encoding failed: %v", test.name, err)
+		}
+		want, err := rlp.EncodeToBytes(test.reflected)
+		if err != nil {
+			t.Fatalf("%s: reflection encoding failed: %v", test.name, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: encoding mismatch:\nhave %x\nwant %x", test.name, have, want)
+		}
+		if err := rlp.DecodeBytes(have, test.decoded); err != nil {
+			t.Fatalf("%s: decoding failed: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(test.decoded, test.generated) {
+			t.Errorf("%s: round trip mismatch:\nhave %+v\nwant %+v", test.name, test.decoded, test.generated)
+		}
+	}
+	// Nil logs encode as empty lists, like nil struct pointers with reflection
+	receipt.Logs = append(receipt.Logs, nil)
+	have, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		t.Fatalf("nil log encoding failed: %v", err)
+	}
+	var dec struct {
+		PostState         []byte
+		CumulativeGasUsed *big.Int
+		Bloom             Bloom
+		Logs              []rlp.RawValue
+	}
+	if err := rlp.DecodeBytes(have, &dec); err != nil {
+		t.Fatalf("nil log decoding failed: %v", err)
+	}
+	if len(dec.Logs) != 3 || !bytes.Equal(dec.Logs[2], []byte{0xc0}) {
+		t.Errorf("nil log encoding mismatch: have %x, want c0", dec.Logs)
+	}
+}
@@ -0,0 +1,129 @@
+// Code generated by rlpgen. DO NOT EDIT.
+
+package types
+
+import (
+	"io"
+
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// EncodeRLP implements rlp.Encoder.
+func (obj *Header) EncodeRLP(_w io.Writer) error {
+	w := rlp.NewEncoderBuffer(_w)
+	if obj == nil {
+		w.ListEnd(w.List())
+		return w.Flush()
+	}
+	_tmp1 := w.List()
+	w.WriteBytes(obj.ParentHash[:])
+	w.WriteBytes(obj.UncleHash[:])
+	w.WriteBytes(obj.Coinbase[:])
+	w.WriteBytes(obj.Root[:])
+	w.WriteBytes(obj.TxHash[:])
+	w.WriteBytes(obj.ReceiptHash[:])
+	w.WriteBytes(obj.Bloom[:])
+	if err := w.WriteBigInt(obj.Difficulty); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(obj.Number); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(obj.GasLimit); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(obj.GasUsed); err != nil {
+		return err
+	}
+	if err := w.WriteBigInt(obj.Time); err != nil {
+		return err
+	}
+	w.WriteBytes(obj.Extra)
+	w.WriteBytes(obj.MixDigest[:])
+	w.WriteBytes(obj.Nonce[:])
+	w.ListEnd(_tmp1)
+	return w.Flush()
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (obj *Header) DecodeRLP(dec *rlp.Stream) error {
+	if _, err := dec.List(); err != nil {
+		return err
+	}
+	// ParentHash:
+	if err := dec.ReadBytes(obj.ParentHash[:]); err != nil {
+		return err
+	}
+	// UncleHash:
+	if err := dec.ReadBytes(obj.UncleHash[:]); err != nil {
+		return err
+	}
+	// Coinbase:
+	if err := dec.ReadBytes(obj.Coinbase[:]); err != nil {
+		return err
+	}
+	// Root:
+	if err := dec.ReadBytes(obj.Root[:]); err != nil {
+		return err
+	}
+	// TxHash:
+	if err := dec.ReadBytes(obj.TxHash[:]); err != nil {
+		return err
+	}
+	// ReceiptHash:
+	if err := dec.ReadBytes(obj.ReceiptHash[:]); err != nil {
+		return err
+	}
+	// Bloom:
+	if err := dec.ReadBytes(obj.Bloom[:]); err != nil {
+		return err
+	}
+	// Difficulty:
+	_tmp2, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.Difficulty = _tmp2
+	// Number:
+	_tmp3, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.Number = _tmp3
+	// GasLimit:
+	_tmp4, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.GasLimit = _tmp4
+	// GasUsed:
+	_tmp5, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.GasUsed = _tmp5
+	// Time:
+	_tmp6, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.Time = _tmp6
+	// Extra:
+	_tmp7, err := dec.Bytes()
+	if err != nil {
+		return err
+	}
+	obj.Extra = _tmp7
+	// MixDigest:
+	if err := dec.ReadBytes(obj.MixDigest[:]); err != nil {
+		return err
+	}
+	// Nonce:
+	if err := dec.ReadBytes(obj.Nonce[:]); err != nil {
+		return err
+	}
+	if err := dec.ListEnd(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Code generated by rlpgen. DO NOT EDIT.
+
+package types
+
+import (
+	"io"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// EncodeRLP implements rlp.Encoder.
+func (obj *Log) EncodeRLP(_w io.Writer) error {
+	w := rlp.NewEncoderBuffer(_w)
+	if obj == nil {
+		w.ListEnd(w.List())
+		return w.Flush()
+	}
+	_tmp1 := w.List()
+	w.WriteBytes(obj.Address[:])
+	_tmp2 := w.List()
+	for _, _tmp3 := range obj.Topics {
+		w.WriteBytes(_tmp3[:])
+	}
+	w.ListEnd(_tmp2)
+	w.WriteBytes(obj.Data)
+	w.ListEnd(_tmp1)
+	return w.Flush()
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (obj *Log) DecodeRLP(dec *rlp.Stream) error {
+	if _, err := dec.List(); err != nil {
+		return err
+	}
+	// Address:
+	if err := dec.ReadBytes(obj.Address[:]); err != nil {
+		return err
+	}
+	// Topics:
+	_tmp4 := []common.Hash{}
+	if _, err := dec.List(); err != nil {
+		return err
+	}
+	for dec.MoreDataInList() {
+		var _tmp5 common.Hash
+		if err := dec.ReadBytes(_tmp5[:]); err != nil {
+			return err
+		}
+		_tmp4 = append(_tmp4, _tmp5)
+	}
+	if err := dec.ListEnd(); err != nil {
+		return err
+	}
+	obj.Topics = _tmp4
+	// Data:
+	_tmp6, err := dec.Bytes()
+	if err != nil {
+		return err
+	}
+	obj.Data = _tmp6
+	if err := dec.ListEnd(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Code generated by rlpgen. DO NOT EDIT.
+
+package types
+
+import (
+	"io"
+
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// EncodeRLP implements rlp.Encoder.
+func (obj *Receipt) EncodeRLP(_w io.Writer) error {
+	w := rlp.NewEncoderBuffer(_w)
+	if obj == nil {
+		w.ListEnd(w.List())
+		return w.Flush()
+	}
+	_tmp1 := w.List()
+	w.WriteBytes(obj.PostState)
+	if err := w.WriteBigInt(obj.CumulativeGasUsed); err != nil {
+		return err
+	}
+	w.WriteBytes(obj.Bloom[:])
+	_tmp2 := w.List()
+	for _, _tmp3 := range obj.Logs {
+		if err := _tmp3.EncodeRLP(w); err != nil {
+			return err
+		}
+	}
+	w.ListEnd(_tmp2)
+	w.ListEnd(_tmp1)
+	return w.Flush()
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (obj *Receipt) DecodeRLP(dec *rlp.Stream) error {
+	if _, err := dec.List(); err != nil {
+		return err
+	}
+	// PostState:
+	_tmp4, err := dec.Bytes()
+	if err != nil {
+		return err
+	}
+	obj.PostState = _tmp4
+	// CumulativeGasUsed:
+	_tmp5, err := dec.BigInt()
+	if err != nil {
+		return err
+	}
+	obj.CumulativeGasUsed = _tmp5
+	// Bloom:
+	if err := dec.ReadBytes(obj.Bloom[:]); err != nil {
+		return err
+	}
+	// Logs:
+	_tmp6 := []*Log{}
+	if _, err := dec.List(); err != nil {
+		return err
+	}
+	for dec.MoreDataInList() {
+		_tmp7 := new(Log)
+		if err := _tmp7.DecodeRLP(dec); err != nil {
+			return err
+		}
+		_tmp6 = append(_tmp6, _tmp7)
+	}
+	if err := dec.ListEnd(); err != nil {
+		return err
+	}
+	obj.Logs = _tmp6
+	if err := dec.ListEnd(); err != nil {
+		return err
+	}
+	return nil
+}
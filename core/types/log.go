@@ -26,6 +26,7 @@ import (
 )
 
 //go:generate gencodec -type Log -field-override logMarshaling -out gen_log_json.go
+//go:generate rlpgen -type Log -fields Address,Topics,Data -out gen_log_rlp.go
 
 // Log represents a contract log event. These events are generated by the LOG opcode and
 // stored/indexed by the node.
@@ -63,12 +64,6 @@ type logMarshaling struct {
 	Index       hexutil.Uint
 }
 
-type rlpLog struct {
-	Address common.Address
-	Topics  []common.Hash
-	Data    []byte
-}
-
 type rlpStorageLog struct {
 	Address     common.Address
 	Topics      []common.Hash
@@ -80,21 +75,6 @@ type rlpStorageLog struct {
 	Index       uint
 }
 
-// EncodeRLP implements rlp.Encoder.
-func (l *Log) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, rlpLog{Address: l.Address, Topics: l.Topics, Data: l.Data})
-}
-
-// DecodeRLP implements rlp.Decoder.
-func (l *Log) DecodeRLP(s *rlp.Stream) error {
-	var dec rlpLog
-	err := s.Decode(&dec)
-	if err == nil {
-		l.Address, l.Topics, l.Data = dec.Address, dec.Topics, dec.Data
-	}
-	return err
-}
-
 func (l *Log) String() string {
 	return fmt.Sprintf(`log: %x %x %x %x %d %x %d`, l.Address, l.Topics, l.Data, l.TxHash, l.TxIndex, l.BlockHash, l.Index)
 }
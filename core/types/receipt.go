@@ -17,16 +17,21 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/rlp"
 )
 
+var errReceiptsMismatch = errors.New("receipt count mismatches transaction count")
+
 //go:generate gencodec -type Receipt -field-override receiptMarshaling -out gen_receipt_json.go
+//go:generate rlpgen -type Receipt -fields PostState,CumulativeGasUsed,Bloom,Logs -out gen_receipt_rlp.go
 
 // Receipt represents the results of a transaction.
 type Receipt struct {
@@ -53,26 +58,11 @@ func NewReceipt(root []byte, cumulativeGasUsed *big.Int) *Receipt {
 	return &Receipt{PostState: common.CopyBytes(root), CumulativeGasUsed: new(big.Int).Set(cumulativeGasUsed)}
 }
 
-// EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
-// into an RLP stream.
-func (r *Receipt) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, []interface{}{r.PostState, r.CumulativeGasUsed, r.Bloom, r.Logs})
-}
-
-// DecodeRLP implements rlp.Decoder, and loads the consensus fields of a receipt
-// from an RLP stream.
-func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
-	var receipt struct {
-		PostState         []byte
-		CumulativeGasUsed *big.Int
-		Bloom             Bloom
-		Logs              []*Log
-	}
-	if err := s.Decode(&receipt); err != nil {
-		return err
-	}
-	r.PostState, r.CumulativeGasUsed, r.Bloom, r.Logs = receipt.PostState, receipt.CumulativeGasUsed, receipt.Bloom, receipt.Logs
-	return nil
+// Size returns the RLP encoded size of the consensus fields of the receipt.
+func (r *Receipt) Size() common.StorageSize {
+	c := writeCounter(0)
+	rlp.Encode(&c, r)
+	return common.StorageSize(c)
 }
 
 // String implements the Stringer interface.
@@ -135,3 +125,40 @@ func (r Receipts) GetRlp(i int) []byte {
 	}
 	return bytes
 }
+
+// DeriveFields fills the receipts and their logs with the fields that are not
+// secured by consensus, but can be computed from the block containing them. The
+// signer is only used to derive the address of contract creations.
+func (r Receipts) DeriveFields(signer Signer, hash common.Hash, number uint64, txs Transactions) error {
+	if len(txs) != len(r) {
+		return errReceiptsMismatch
+	}
+	logIndex := uint(0)
+	for i := 0; i < len(r); i++ {
+		// The transaction hash can be retrieved from the transaction itself
+		r[i].TxHash = txs[i].Hash()
+
+		// The contract address can be derived from the transaction itself
+		if txs[i].To() == nil {
+			// Deriving the signer is expensive, only do if it's actually needed
+			from, _ := Sender(signer, txs[i])
+			r[i].ContractAddress = crypto.CreateAddress(from, txs[i].Nonce())
+		}
+		// The used gas can be calculated based on previous receipts
+		if i == 0 {
+			r[i].GasUsed = new(big.Int).Set(r[i].CumulativeGasUsed)
+		} else {
+			r[i].GasUsed = new(big.Int).Sub(r[i].CumulativeGasUsed, r[i-1].CumulativeGasUsed)
+		}
+		// The derived log fields can simply be set from the block and transaction
+		for j := 0; j < len(r[i].Logs); j++ {
+			r[i].Logs[j].BlockNumber = number
+			r[i].Logs[j].BlockHash = hash
+			r[i].Logs[j].TxHash = r[i].TxHash
+			r[i].Logs[j].TxIndex = uint(i)
+			r[i].Logs[j].Index = logIndex
+			logIndex++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/crypto"
+)
+
+// Tests that the fields not secured by consensus are filled in correctly from
+// the block containing the receipts.
+func TestDeriveFields(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := HomesteadSigner{}
+
+	transfer, _ := SignTx(NewTransaction(0, common.Address{0x01}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+	create, _ := SignTx(NewContractCreation(1, big.NewInt(0), big.NewInt(100000), big.NewInt(1), nil), signer, key)
+	txs := Transactions{transfer, create}
+
+	receipts := Receipts{
+		{CumulativeGasUsed: big.NewInt(21000), Logs: []*Log{{Address: common.Address{0x11}}, {Address: common.Address{0x12}}}},
+		{CumulativeGasUsed: big.NewInt(75000), Logs: []*Log{{Address: common.Address{0x21}}}},
+	}
+	hash, number := common.Hash{0xff}, uint64(42)
+	if err := receipts.DeriveFields(signer, hash, number, txs); err != nil {
+		t.Fatalf("failed to derive fields: %v", err)
+	}
+	// Check the receipt fields derived from the transactions
+	for i, receipt := range receipts {
+		if receipt.TxHash != txs[i].Hash() {
+			t.Errorf("receipt %d: transaction hash mismatch: have %x, want %x", i, receipt.TxHash, txs[i].Hash())
+		}
+	}
+	if receipts[0].ContractAddress != (common.Address{}) {
+		t.Errorf("contract address set for transfer: %x", receipts[0].ContractAddress)
+	}
+	if want := crypto.CreateAddress(crypto.PubkeyToAddress(key.PublicKey), 1); receipts[1].ContractAddress != want {
+		t.Errorf("contract address mismatch: have %x, want %x", receipts[1].ContractAddress, want)
+	}
+	if receipts[0].GasUsed.Cmp(big.NewInt(21000)) != 0 || receipts[1].GasUsed.Cmp(big.NewInt(54000)) != 0 {
+		t.Errorf("gas used mismatch: have %v and %v, want 21000 and 54000", receipts[0].GasUsed, receipts[1].GasUsed)
+	}
+	// Check the log fields, the log index running across the entire block
+	index := uint(0)
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.BlockHash != hash || log.BlockNumber != number {
+				t.Errorf("log %d: block mismatch: have %x #%d, want %x #%d", index, log.BlockHash, log.BlockNumber, hash, number)
+			}
+			if log.TxHash != txs[i].Hash() || log.TxIndex != uint(i) {
+				t.Errorf("log %d: transaction mismatch: have %x @%d, want %x @%d", index, log.TxHash, log.TxIndex, txs[i].Hash(), i)
+			}
+			if log.Index != index {
+				t.Errorf("log %d: index mismatch: have %d", index, log.Index)
+			}
+			index++
+		}
+	}
+	// Receipts not matching the transactions must be rejected
+	if err := receipts[:1].DeriveFields(signer, hash, number, txs); err == nil {
+		t.Errorf("mismatching receipt count not detected")
+	}
+}
@@ -96,6 +96,52 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// recoverer is implemented by the built in signers, allowing the recovery of
+// transaction senders to be batched.
+type recoverer interface {
+	recoveryInput(tx *Transaction) (common.Hash, []byte, error)
+}
+
+// CacheSenders derives the senders of a batch of transactions, recovering all
+// signatures concurrently, and caches them in the transactions for subsequent
+// Sender calls with the same signer. Transactions failing recovery are skipped,
+// their error is reported when their sender is requested.
+func CacheSenders(signer Signer, txs []*Transaction) {
+	r, ok := signer.(recoverer)
+	if !ok {
+		return
+	}
+	var (
+		pending = make([]*Transaction, 0, len(txs))
+		hashes  = make([][]byte, 0, len(txs))
+		sigs    = make([][]byte, 0, len(txs))
+	)
+	for _, tx := range txs {
+		if sc := tx.from.Load(); sc != nil && sc.(sigCache).signer.Equal(signer) {
+			continue
+		}
+		hash, sig, err := r.recoveryInput(tx)
+		if err != nil {
+			continue
+		}
+		pending = append(pending, tx)
+		hashes = append(hashes, hash[:])
+		sigs = append(sigs, sig)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	pubs, errs := crypto.EcrecoverBatch(hashes, sigs)
+	for i, tx := range pending {
+		if errs[i] != nil || checkPubkey(pubs[i]) != nil {
+			continue
+		}
+		var addr common.Address
+		copy(addr[:], crypto.Keccak256(pubs[i][1:])[12:])
+		tx.from.Store(sigCache{signer: signer, from: addr})
+	}
+}
+
 type Signer interface {
 	// Hash returns the rlp encoded hash for signatures
 	Hash(tx *Transaction) common.Hash
@@ -132,36 +178,30 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 }
 
 func (s EIP155Signer) PublicKey(tx *Transaction) ([]byte, error) {
+	hash, sig, err := s.recoveryInput(tx)
+	if err != nil {
+		return nil, err
+	}
+	return recoverPlain(hash, sig)
+}
+
+// recoveryInput returns the signature hash and the uncompressed signature from
+// which the public key of the sender can be recovered.
+func (s EIP155Signer) recoveryInput(tx *Transaction) (common.Hash, []byte, error) {
 	// if the transaction is not protected fall back to homestead signer
 	if !tx.Protected() {
-		return (HomesteadSigner{}).PublicKey(tx)
+		return (HomesteadSigner{}).recoveryInput(tx)
 	}
 
 	if tx.ChainId().Cmp(s.chainId) != 0 {
-		return nil, ErrInvalidChainId
+		return common.Hash{}, nil, ErrInvalidChainId
 	}
 
 	V := byte(new(big.Int).Sub(tx.data.V, s.chainIdMul).Uint64() - 35)
 	if !crypto.ValidateSignatureValues(V, tx.data.R, tx.data.S, true) {
-		return nil, ErrInvalidSig
+		return common.Hash{}, nil, ErrInvalidSig
 	}
-	// encode the signature in uncompressed format
-	R, S := tx.data.R.Bytes(), tx.data.S.Bytes()
-	sig := make([]byte, 65)
-	copy(sig[32-len(R):32], R)
-	copy(sig[64-len(S):64], S)
-	sig[64] = V
-
-	// recover the public key from the signature
-	hash := s.Hash(tx)
-	pub, err := crypto.Ecrecover(hash[:], sig)
-	if err != nil {
-		return nil, err
-	}
-	if len(pub) == 0 || pub[0] != 4 {
-		return nil, errors.New("invalid public key")
-	}
-	return pub, nil
+	return s.Hash(tx), encodeSignature(tx.data.R, tx.data.S, V), nil
 }
 
 // WithSignature returns a new transaction with the given signature. This signature
@@ -219,30 +259,24 @@ func (hs HomesteadSigner) WithSignature(tx *Transaction, sig []byte) (*Transacti
 }
 
 func (hs HomesteadSigner) PublicKey(tx *Transaction) ([]byte, error) {
+	hash, sig, err := hs.recoveryInput(tx)
+	if err != nil {
+		return nil, err
+	}
+	return recoverPlain(hash, sig)
+}
+
+// recoveryInput returns the signature hash and the uncompressed signature from
+// which the public key of the sender can be recovered.
+func (hs HomesteadSigner) recoveryInput(tx *Transaction) (common.Hash, []byte, error) {
 	if tx.data.V.BitLen() > 8 {
-		return nil, ErrInvalidSig
+		return common.Hash{}, nil, ErrInvalidSig
 	}
 	V := byte(tx.data.V.Uint64() - 27)
 	if !crypto.ValidateSignatureValues(V, tx.data.R, tx.data.S, true) {
-		return nil, ErrInvalidSig
-	}
-	// encode the snature in uncompressed format
-	r, s := tx.data.R.Bytes(), tx.data.S.Bytes()
-	sig := make([]byte, 65)
-	copy(sig[32-len(r):32], r)
-	copy(sig[64-len(s):64], s)
-	sig[64] = V
-
-	// recover the public key from the snature
-	hash := hs.Hash(tx)
-	pub, err := crypto.Ecrecover(hash[:], sig)
-	if err != nil {
-		return nil, err
+		return common.Hash{}, nil, ErrInvalidSig
 	}
-	if len(pub) == 0 || pub[0] != 4 {
-		return nil, errors.New("invalid public key")
-	}
-	return pub, nil
+	return hs.Hash(tx), encodeSignature(tx.data.R, tx.data.S, V), nil
 }
 
 type FrontierSigner struct{}
@@ -279,31 +313,53 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) PublicKey(tx *Transaction) ([]byte, error) {
+	hash, sig, err := fs.recoveryInput(tx)
+	if err != nil {
+		return nil, err
+	}
+	return recoverPlain(hash, sig)
+}
+
+// recoveryInput returns the signature hash and the uncompressed signature from
+// which the public key of the sender can be recovered.
+func (fs FrontierSigner) recoveryInput(tx *Transaction) (common.Hash, []byte, error) {
 	if tx.data.V.BitLen() > 8 {
-		return nil, ErrInvalidSig
+		return common.Hash{}, nil, ErrInvalidSig
 	}
 
 	V := byte(tx.data.V.Uint64() - 27)
 	if !crypto.ValidateSignatureValues(V, tx.data.R, tx.data.S, false) {
-		return nil, ErrInvalidSig
+		return common.Hash{}, nil, ErrInvalidSig
 	}
-	// encode the snature in uncompressed format
-	r, s := tx.data.R.Bytes(), tx.data.S.Bytes()
+	return fs.Hash(tx), encodeSignature(tx.data.R, tx.data.S, V), nil
+}
+
+// encodeSignature encodes the signature values in the uncompressed [R || S || V]
+// format.
+func encodeSignature(R, S *big.Int, V byte) []byte {
+	r, s := R.Bytes(), S.Bytes()
 	sig := make([]byte, 65)
 	copy(sig[32-len(r):32], r)
 	copy(sig[64-len(s):64], s)
 	sig[64] = V
+	return sig
+}
 
-	// recover the public key from the snature
-	hash := fs.Hash(tx)
+// recoverPlain recovers the public key from the signature of the given hash.
+func recoverPlain(hash common.Hash, sig []byte) ([]byte, error) {
 	pub, err := crypto.Ecrecover(hash[:], sig)
 	if err != nil {
 		return nil, err
 	}
+	return pub, checkPubkey(pub)
+}
+
+// checkPubkey verifies that a recovered public key is in uncompressed format.
+func checkPubkey(pub []byte) error {
 	if len(pub) == 0 || pub[0] != 4 {
-		return nil, errors.New("invalid public key")
+		return errors.New("invalid public key")
 	}
-	return pub, nil
+	return nil
 }
 
 // deriveChainId derives the chain id from the given v parameter
@@ -136,3 +136,46 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestCacheSenders(t *testing.T) {
+	signer := NewEIP155Signer(big.NewInt(18))
+
+	var (
+		txs   = make([]*Transaction, 64)
+		addrs = make([]common.Address, len(txs))
+	)
+	for i := range txs {
+		key, _ := crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+
+		tx, err := SignTx(NewTransaction(uint64(i), addrs[i], new(big.Int), new(big.Int), new(big.Int), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs[i] = tx
+	}
+	// Sign one transaction for a different chain, it must not get cached
+	key, _ := crypto.GenerateKey()
+	txs[7], _ = SignTx(NewTransaction(7, addrs[7], new(big.Int), new(big.Int), new(big.Int), nil), NewEIP155Signer(big.NewInt(1)), key)
+
+	CacheSenders(signer, txs)
+	for i, tx := range txs {
+		sc := tx.from.Load()
+		if i == 7 {
+			if sc != nil {
+				t.Errorf("tx %d: sender cached for foreign chain", i)
+			}
+			if _, err := Sender(signer, tx); err != ErrInvalidChainId {
+				t.Errorf("tx %d: error mismatch: have %v, want %v", i, err, ErrInvalidChainId)
+			}
+			continue
+		}
+		if sc == nil {
+			t.Errorf("tx %d: sender not cached", i)
+			continue
+		}
+		if from := sc.(sigCache).from; from != addrs[i] {
+			t.Errorf("tx %d: cached sender mismatch: have %x, want %x", i, from, addrs[i])
+		}
+	}
+}
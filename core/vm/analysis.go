@@ -17,9 +17,8 @@
 package vm
 
 import (
-	"math/big"
-
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/math"
 )
 
 // destinations stores one map per contract (keyed by hash of code).
@@ -28,11 +27,11 @@ import (
 type destinations map[common.Hash][]byte
 
 // has checks whether code has a JUMPDEST at dest.
-func (d destinations) has(codehash common.Hash, code []byte, dest *big.Int) bool {
-	// PC cannot go beyond len(code) and certainly can't be bigger than 63bits.
+func (d destinations) has(codehash common.Hash, code []byte, dest math.Uint256) bool {
+	// PC cannot go beyond len(code) and certainly can't be bigger than 64bits.
 	// Don't bother checking for JUMPDEST in that case.
-	udest := dest.Uint64()
-	if dest.BitLen() >= 63 || udest >= uint64(len(code)) {
+	udest, overflow := dest.Uint64WithOverflow()
+	if overflow || udest >= uint64(len(code)) {
 		return false
 	}
 
@@ -23,29 +23,61 @@ import (
 	"github.com/networkchain/networkchain/common/math"
 )
 
-// calculates the memory size required for a step
-func calcMemSize(off, l *big.Int) *big.Int {
-	if l.Sign() == 0 {
-		return common.Big0
+// calcMemSize calculates the required memory size, returning whether it does
+// not fit into a uint64.
+func calcMemSize(off, l *math.Uint256) (uint64, bool) {
+	length, overflow := l.Uint64WithOverflow()
+	if overflow {
+		return 0, true
 	}
+	return calcMemSizeWithUint(off, length)
+}
 
-	return new(big.Int).Add(off, l)
+// calcMemSizeWithUint calculates the required memory size for a fixed length,
+// returning whether it does not fit into a uint64.
+func calcMemSizeWithUint(off *math.Uint256, length uint64) (uint64, bool) {
+	if length == 0 {
+		return 0, false
+	}
+	offset, overflow := off.Uint64WithOverflow()
+	if overflow {
+		return 0, true
+	}
+	return math.SafeAdd(offset, length)
 }
 
 // getData returns a slice from the data based on the start and size and pads
 // up to size with zero's. This function is overflow safe.
-func getData(data []byte, start, size *big.Int) []byte {
-	dlen := big.NewInt(int64(len(data)))
+func getData(data []byte, start math.Uint256, size uint64) []byte {
+	length := uint64(len(data))
+
+	s := length
+	if start.IsUint64() && start.Uint64() < length {
+		s = start.Uint64()
+	}
+	e := length
+	if size < length-s {
+		e = s + size
+	}
+	return common.RightPadBytes(data[s:e], int(size))
+}
+
+// bigToUint256 converts a non-negative big integer from the chain context into
+// a stack item, truncating it to 256 bits.
+func bigToUint256(v *big.Int) math.Uint256 {
+	x, _ := math.Uint256FromBig(v)
+	return x
+}
 
-	s := math.BigMin(start, dlen)
-	e := math.BigMin(new(big.Int).Add(s, size), dlen)
-	return common.RightPadBytes(data[s.Uint64():e.Uint64()], int(size.Uint64()))
+// addressToUint256 converts an address into a stack item.
+func addressToUint256(addr common.Address) math.Uint256 {
+	return math.Uint256FromBytes(addr[:])
 }
 
-// bigUint64 returns the integer casted to a uint64 and returns whether it
-// overflowed in the process.
-func bigUint64(v *big.Int) (uint64, bool) {
-	return v.Uint64(), v.BitLen() > 64
+// uint256ToAddress returns the address held in the lowest 20 bytes of a stack item.
+func uint256ToAddress(x math.Uint256) common.Address {
+	b := x.Bytes32()
+	return common.BytesToAddress(b[12:])
 }
 
 // toWordSize returns the ceiled word size required for memory expansion.
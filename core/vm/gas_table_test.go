@@ -16,7 +16,12 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common/math"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +39,30 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestCalcMemSize(t *testing.T) {
+	tests := []struct {
+		off, size *big.Int
+		want      uint64
+		overflow  bool
+	}{
+		{big.NewInt(0), big.NewInt(0), 0, false},
+		{math.MaxBig256, big.NewInt(0), 0, false}, // zero size never touches memory
+		{big.NewInt(32), big.NewInt(64), 96, false},
+		{new(big.Int).SetUint64(math.MaxUint64), big.NewInt(0), 0, false},
+		{new(big.Int).SetUint64(math.MaxUint64), big.NewInt(1), 0, true},
+		{big.NewInt(1), math.MaxBig256, 0, true},
+		{math.MaxBig256, big.NewInt(1), 0, true},
+	}
+	for i, tt := range tests {
+		off, _ := math.Uint256FromBig(tt.off)
+		l, _ := math.Uint256FromBig(tt.size)
+		size, overflow := calcMemSize(&off, &l)
+		if overflow != tt.overflow {
+			t.Errorf("test %d: overflow mismatch: have %v, want %v", i, overflow, tt.overflow)
+		}
+		if !overflow && size != tt.want {
+			t.Errorf("test %d: size mismatch: have %d, want %d", i, size, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/networkchain/networkchain/params"
+)
+
+// instructionSetVersion is a batch of instruction changes activated together by
+// a fork. Versions are applied in registration order on top of the frontier
+// instruction set, so later versions may reprice or replace earlier operations.
+type instructionSetVersion struct {
+	name   string                  // Name of the version, identifying it in rule set names
+	active func(params.Rules) bool // Whether the version is enabled under a set of fork rules
+	enable func(*[256]operation)   // Registers the new or modified operations of the version
+}
+
+var (
+	instructionSetVersions []instructionSetVersion           // Registered versions in activation order
+	instructionSetCache    = make(map[string][256]operation) // Assembled jump tables keyed by rule set name
+	instructionSetLock     sync.Mutex                        // Lock protecting the registry and cache
+)
+
+// registerInstructionSet adds a new instruction set version, enabled on top of
+// all previously registered ones whenever the given fork rules activate it. It
+// is meant to be called from init functions, before any EVM is created.
+func registerInstructionSet(name string, active func(params.Rules) bool, enable func(*[256]operation)) {
+	instructionSetLock.Lock()
+	defer instructionSetLock.Unlock()
+
+	for _, version := range instructionSetVersions {
+		if version.name == name {
+			panic("duplicate instruction set version: " + name)
+		}
+	}
+	instructionSetVersions = append(instructionSetVersions, instructionSetVersion{name: name, active: active, enable: enable})
+	instructionSetCache = make(map[string][256]operation)
+}
+
+// ruleSetName returns the name of the rule set active under the given fork rules,
+// listing the enabled instruction set versions on top of frontier.
+func ruleSetName(rules params.Rules) string {
+	instructionSetLock.Lock()
+	defer instructionSetLock.Unlock()
+
+	return ruleSetNameLocked(rules)
+}
+
+// ruleSetNameLocked assembles the rule set name from the enabled versions. The lock
+// must be held by the caller.
+func ruleSetNameLocked(rules params.Rules) string {
+	names := []string{"frontier"}
+	for _, version := range instructionSetVersions {
+		if version.active(rules) {
+			names = append(names, version.name)
+		}
+	}
+	return strings.Join(names, "+")
+}
+
+// instructionSetFor returns the jump table of the rule set active under the given
+// fork rules, assembling it on first use.
+func instructionSetFor(rules params.Rules) [256]operation {
+	instructionSetLock.Lock()
+	defer instructionSetLock.Unlock()
+
+	name := ruleSetNameLocked(rules)
+	if table, ok := instructionSetCache[name]; ok {
+		return table
+	}
+	table := NewFrontierInstructionSet()
+	for _, version := range instructionSetVersions {
+		if version.active(rules) {
+			version.enable(&table)
+		}
+	}
+	instructionSetCache[name] = table
+	return table
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/params"
+)
+
+// traceRuleSet executes the given code under the rule set active at the given
+// block of the chain config, returning the structured execution trace.
+func traceRuleSet(config *params.ChainConfig, number int64, code []byte) []StructLog {
+	logger := NewStructLogger(nil)
+	env := NewEVM(Context{BlockNumber: big.NewInt(number)}, NoopStateDB{}, config, Config{Debug: true, Tracer: logger})
+
+	contract := NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+	contract.Code = code
+	env.interpreter.Run(0, contract, nil)
+
+	return logger.StructLogs()
+}
+
+// diffTraces returns the index of the first step at which two execution traces
+// diverge, or -1 if they are identical.
+func diffTraces(a, b []StructLog) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].Pc != b[i].Pc || a[i].Op != b[i].Op || a[i].Gas != b[i].Gas || a[i].GasCost != b[i].GasCost || (a[i].Err == nil) != (b[i].Err == nil) {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return len(a)
+		}
+		return len(b)
+	}
+	return -1
+}
+
+func TestRuleSetNames(t *testing.T) {
+	if name := ruleSetName(params.Rules{}); name != "frontier" {
+		t.Errorf("frontier rule set name mismatch: have %s", name)
+	}
+	if name := ruleSetName(params.Rules{IsHomestead: true}); name != "frontier+homestead" {
+		t.Errorf("homestead rule set name mismatch: have %s", name)
+	}
+}
+
+// Tests that newly registered instruction set versions only enable their opcodes
+// under the rule sets activating them.
+func TestInstructionSetRegistration(t *testing.T) {
+	defer func(versions []instructionSetVersion) {
+		instructionSetVersions = versions
+		instructionSetCache = make(map[string][256]operation)
+	}(instructionSetVersions)
+
+	const testOp = OpCode(0x0c) // unassigned opcode
+	registerInstructionSet("test", func(rules params.Rules) bool { return rules.IsMetropolis }, func(instructionSet *[256]operation) {
+		instructionSet[testOp] = operation{
+			execute:       opStop,
+			gasCost:       constGasFunc(GasQuickStep),
+			validateStack: makeStackFunc(0, 0),
+			halts:         true,
+			valid:         true,
+		}
+	})
+	if table := instructionSetFor(params.Rules{IsHomestead: true}); table[testOp].valid {
+		t.Errorf("test opcode enabled before activation")
+	}
+	table := instructionSetFor(params.Rules{IsHomestead: true, IsMetropolis: true})
+	if !table[testOp].valid {
+		t.Errorf("test opcode not enabled after activation")
+	}
+	if !table[DELEGATECALL].valid {
+		t.Errorf("earlier instruction set version not enabled")
+	}
+	if name := ruleSetName(params.Rules{IsHomestead: true, IsMetropolis: true}); name != "frontier+homestead+test" {
+		t.Errorf("rule set name mismatch: have %s", name)
+	}
+}
+
+// Tests that the execution traces of the same code only diverge across rule sets
+// at the instructions that differ between them.
+func TestRuleSetTraces(t *testing.T) {
+	config := &params.ChainConfig{HomesteadBlock: big.NewInt(1)}
+
+	// Code not touching any versioned instructions must trace identically
+	plain := []byte{byte(PUSH1), 0x01, byte(PUSH1), 0x02, byte(ADD), byte(PUSH1), 0x00, byte(MSTORE), byte(STOP)}
+	if step := diffTraces(traceRuleSet(config, 0, plain), traceRuleSet(config, 1, plain)); step != -1 {
+		t.Errorf("unversioned code traces diverge at step %d", step)
+	}
+	// Code using a homestead instruction must diverge exactly at its invocation
+	code := []byte{
+		byte(PUSH1), 0x00, byte(PUSH1), 0x00, byte(PUSH1), 0x00,
+		byte(PUSH1), 0x00, byte(PUSH1), 0x00, byte(PUSH1), 0x00,
+		byte(DELEGATECALL), byte(STOP),
+	}
+	frontier, homestead := traceRuleSet(config, 0, code), traceRuleSet(config, 1, code)
+	if step := diffTraces(frontier, homestead); step != 6 {
+		t.Fatalf("traces diverge at step %d, want 6", step)
+	}
+	if frontier[6].Err == nil {
+		t.Errorf("delegatecall not rejected by %s", ruleSetName(config.Rules(big.NewInt(0))))
+	}
+	if homestead[6].Op != DELEGATECALL || homestead[6].Err != nil {
+		t.Errorf("delegatecall not executed by %s: %+v", ruleSetName(config.Rules(big.NewInt(1))), homestead[6])
+	}
+}
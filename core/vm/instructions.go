@@ -18,7 +18,6 @@ package vm
 
 import (
 	"fmt"
-	"math/big"
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/math"
@@ -28,321 +27,211 @@ import (
 )
 
 var (
-	bigZero = new(big.Int)
+	uint256Zero = math.Uint256{}
+	uint256One  = math.NewUint256(1)
 )
 
 func opAdd(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(math.U256(x.Add(x, y)))
-
-	evm.interpreter.intPool.put(y)
-
+	x, y := stack.pop(), stack.peek()
+	*y = x.Add(*y)
 	return nil, nil
 }
 
 func opSub(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(math.U256(x.Sub(x, y)))
-
-	evm.interpreter.intPool.put(y)
-
+	x, y := stack.pop(), stack.peek()
+	*y = x.Sub(*y)
 	return nil, nil
 }
 
 func opMul(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(math.U256(x.Mul(x, y)))
-
-	evm.interpreter.intPool.put(y)
-
+	x, y := stack.pop(), stack.peek()
+	*y = x.Mul(*y)
 	return nil, nil
 }
 
 func opDiv(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	if y.Sign() != 0 {
-		stack.push(math.U256(x.Div(x, y)))
-	} else {
-		stack.push(new(big.Int))
-	}
-
-	evm.interpreter.intPool.put(y)
-
+	x, y := stack.pop(), stack.peek()
+	*y = x.Div(*y)
 	return nil, nil
 }
 
 func opSdiv(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := math.S256(stack.pop()), math.S256(stack.pop())
-	if y.Sign() == 0 {
-		stack.push(new(big.Int))
-		return nil, nil
-	} else {
-		n := new(big.Int)
-		if evm.interpreter.intPool.get().Mul(x, y).Sign() < 0 {
-			n.SetInt64(-1)
-		} else {
-			n.SetInt64(1)
-		}
-
-		res := x.Div(x.Abs(x), y.Abs(y))
-		res.Mul(res, n)
-
-		stack.push(math.U256(res))
-	}
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.SDiv(*y)
 	return nil, nil
 }
 
 func opMod(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	if y.Sign() == 0 {
-		stack.push(new(big.Int))
-	} else {
-		stack.push(math.U256(x.Mod(x, y)))
-	}
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.Mod(*y)
 	return nil, nil
 }
 
 func opSmod(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := math.S256(stack.pop()), math.S256(stack.pop())
-
-	if y.Sign() == 0 {
-		stack.push(new(big.Int))
-	} else {
-		n := new(big.Int)
-		if x.Sign() < 0 {
-			n.SetInt64(-1)
-		} else {
-			n.SetInt64(1)
-		}
-
-		res := x.Mod(x.Abs(x), y.Abs(y))
-		res.Mul(res, n)
-
-		stack.push(math.U256(res))
-	}
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.SMod(*y)
 	return nil, nil
 }
 
 func opExp(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	base, exponent := stack.pop(), stack.pop()
-	stack.push(math.Exp(base, exponent))
-
-	evm.interpreter.intPool.put(base, exponent)
-
+	base, exponent := stack.pop(), stack.peek()
+	*exponent = base.Exp(*exponent)
 	return nil, nil
 }
 
 func opSignExtend(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	back := stack.pop()
-	if back.Cmp(big.NewInt(31)) < 0 {
-		bit := uint(back.Uint64()*8 + 7)
-		num := stack.pop()
-		mask := back.Lsh(common.Big1, bit)
-		mask.Sub(mask, common.Big1)
-		if num.Bit(int(bit)) > 0 {
-			num.Or(num, mask.Not(mask))
-		} else {
-			num.And(num, mask)
-		}
-
-		stack.push(math.U256(num))
-	}
-
-	evm.interpreter.intPool.put(back)
+	back, num := stack.pop(), stack.peek()
+	*num = num.SignExtend(back)
 	return nil, nil
 }
 
 func opNot(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x := stack.pop()
-	stack.push(math.U256(x.Not(x)))
+	x := stack.peek()
+	*x = x.Not()
 	return nil, nil
 }
 
 func opLt(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	if x.Cmp(y) < 0 {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+	x, y := stack.pop(), stack.peek()
+	if x.Cmp(*y) < 0 {
+		*y = uint256One
 	} else {
-		stack.push(new(big.Int))
+		*y = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x, y)
 	return nil, nil
 }
 
 func opGt(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	if x.Cmp(y) > 0 {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+	x, y := stack.pop(), stack.peek()
+	if x.Cmp(*y) > 0 {
+		*y = uint256One
 	} else {
-		stack.push(new(big.Int))
+		*y = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x, y)
 	return nil, nil
 }
 
 func opSlt(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := math.S256(stack.pop()), math.S256(stack.pop())
-	if x.Cmp(math.S256(y)) < 0 {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+	x, y := stack.pop(), stack.peek()
+	if x.SCmp(*y) < 0 {
+		*y = uint256One
 	} else {
-		stack.push(new(big.Int))
+		*y = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x, y)
 	return nil, nil
 }
 
 func opSgt(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := math.S256(stack.pop()), math.S256(stack.pop())
-	if x.Cmp(y) > 0 {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+	x, y := stack.pop(), stack.peek()
+	if x.SCmp(*y) > 0 {
+		*y = uint256One
 	} else {
-		stack.push(new(big.Int))
+		*y = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x, y)
 	return nil, nil
 }
 
 func opEq(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	if x.Cmp(y) == 0 {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+	x, y := stack.pop(), stack.peek()
+	if x == *y {
+		*y = uint256One
 	} else {
-		stack.push(new(big.Int))
+		*y = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x, y)
 	return nil, nil
 }
 
 func opIszero(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x := stack.pop()
-	if x.Sign() > 0 {
-		stack.push(new(big.Int))
+	x := stack.peek()
+	if x.IsZero() {
+		*x = uint256One
 	} else {
-		stack.push(evm.interpreter.intPool.get().SetUint64(1))
+		*x = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(x)
 	return nil, nil
 }
 
 func opAnd(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(x.And(x, y))
-
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.And(*y)
 	return nil, nil
 }
 func opOr(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(x.Or(x, y))
-
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.Or(*y)
 	return nil, nil
 }
 func opXor(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y := stack.pop(), stack.pop()
-	stack.push(x.Xor(x, y))
-
-	evm.interpreter.intPool.put(y)
+	x, y := stack.pop(), stack.peek()
+	*y = x.Xor(*y)
 	return nil, nil
 }
 
 func opByte(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	th, val := stack.pop(), stack.peek()
-	if th.Cmp(common.Big32) < 0 {
-		b := math.Byte(val, 32, int(th.Int64()))
-		val.SetUint64(uint64(b))
-	} else {
-		val.SetUint64(0)
-	}
-	evm.interpreter.intPool.put(th)
+	*val = val.Byte(th)
 	return nil, nil
 }
 func opAddmod(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y, z := stack.pop(), stack.pop(), stack.pop()
-	if z.Cmp(bigZero) > 0 {
-		add := x.Add(x, y)
-		add.Mod(add, z)
-		stack.push(math.U256(add))
-	} else {
-		stack.push(new(big.Int))
-	}
-
-	evm.interpreter.intPool.put(y, z)
+	x, y, z := stack.pop(), stack.pop(), stack.peek()
+	*z = x.AddMod(y, *z)
 	return nil, nil
 }
 func opMulmod(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	x, y, z := stack.pop(), stack.pop(), stack.pop()
-	if z.Cmp(bigZero) > 0 {
-		mul := x.Mul(x, y)
-		mul.Mod(mul, z)
-		stack.push(math.U256(mul))
-	} else {
-		stack.push(new(big.Int))
-	}
-
-	evm.interpreter.intPool.put(y, z)
+	x, y, z := stack.pop(), stack.pop(), stack.peek()
+	*z = x.MulMod(y, *z)
 	return nil, nil
 }
 
 func opSha3(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	offset, size := stack.pop(), stack.pop()
-	data := memory.Get(offset.Int64(), size.Int64())
+	offset, size := stack.pop(), stack.peek()
+	data := memory.Get(int64(offset.Uint64()), int64(size.Uint64()))
 	hash := crypto.Keccak256(data)
 
 	if evm.vmConfig.EnablePreimageRecording {
 		evm.StateDB.AddPreimage(common.BytesToHash(hash), data)
 	}
 
-	stack.push(new(big.Int).SetBytes(hash))
-
-	evm.interpreter.intPool.put(offset, size)
+	*size = math.Uint256FromBytes(hash)
 	return nil, nil
 }
 
 func opAddress(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(contract.Address().Big())
+	stack.push(addressToUint256(contract.Address()))
 	return nil, nil
 }
 
 func opBalance(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	addr := common.BigToAddress(stack.pop())
-	balance := evm.StateDB.GetBalance(addr)
+	slot := stack.peek()
+	balance := evm.StateDB.GetBalance(uint256ToAddress(*slot))
 
-	stack.push(new(big.Int).Set(balance))
+	*slot = bigToUint256(balance)
 	return nil, nil
 }
 
 func opOrigin(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.Origin.Big())
+	stack.push(addressToUint256(evm.Origin))
 	return nil, nil
 }
 
 func opCaller(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(contract.Caller().Big())
+	stack.push(addressToUint256(contract.Caller()))
 	return nil, nil
 }
 
 func opCallValue(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().Set(contract.value))
+	stack.push(bigToUint256(contract.value))
 	return nil, nil
 }
 
 func opCalldataLoad(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(new(big.Int).SetBytes(getData(contract.Input, stack.pop(), common.Big32)))
+	x := stack.peek()
+	*x = math.Uint256FromBytes(getData(contract.Input, *x, 32))
 	return nil, nil
 }
 
 func opCalldataSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().SetInt64(int64(len(contract.Input))))
+	stack.push(math.NewUint256(uint64(len(contract.Input))))
 	return nil, nil
 }
 
@@ -352,25 +241,18 @@ func opCalldataCopy(pc *uint64, evm *EVM, contract *Contract, memory *Memory, st
 		cOff = stack.pop()
 		l    = stack.pop()
 	)
-	memory.Set(mOff.Uint64(), l.Uint64(), getData(contract.Input, cOff, l))
-
-	evm.interpreter.intPool.put(mOff, cOff, l)
+	memory.Set(mOff.Uint64(), l.Uint64(), getData(contract.Input, cOff, l.Uint64()))
 	return nil, nil
 }
 
 func opExtCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	a := stack.pop()
-
-	addr := common.BigToAddress(a)
-	a.SetInt64(int64(evm.StateDB.GetCodeSize(addr)))
-	stack.push(a)
-
+	slot := stack.peek()
+	*slot = math.NewUint256(uint64(evm.StateDB.GetCodeSize(uint256ToAddress(*slot))))
 	return nil, nil
 }
 
 func opCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	l := evm.interpreter.intPool.get().SetInt64(int64(len(contract.Code)))
-	stack.push(l)
+	stack.push(math.NewUint256(uint64(len(contract.Code))))
 	return nil, nil
 }
 
@@ -380,117 +262,105 @@ func opCodeCopy(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack
 		cOff = stack.pop()
 		l    = stack.pop()
 	)
-	codeCopy := getData(contract.Code, cOff, l)
+	codeCopy := getData(contract.Code, cOff, l.Uint64())
 
 	memory.Set(mOff.Uint64(), l.Uint64(), codeCopy)
-
-	evm.interpreter.intPool.put(mOff, cOff, l)
 	return nil, nil
 }
 
 func opExtCodeCopy(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	var (
-		addr = common.BigToAddress(stack.pop())
+		addr = uint256ToAddress(stack.pop())
 		mOff = stack.pop()
 		cOff = stack.pop()
 		l    = stack.pop()
 	)
-	codeCopy := getData(evm.StateDB.GetCode(addr), cOff, l)
+	codeCopy := getData(evm.StateDB.GetCode(addr), cOff, l.Uint64())
 
 	memory.Set(mOff.Uint64(), l.Uint64(), codeCopy)
-
-	evm.interpreter.intPool.put(mOff, cOff, l)
-
 	return nil, nil
 }
 
 func opGasprice(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().Set(evm.GasPrice))
+	stack.push(bigToUint256(evm.GasPrice))
 	return nil, nil
 }
 
 func opBlockhash(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	num := stack.pop()
+	num := stack.peek()
 
-	n := evm.interpreter.intPool.get().Sub(evm.BlockNumber, common.Big257)
-	if num.Cmp(n) > 0 && num.Cmp(evm.BlockNumber) < 0 {
-		stack.push(evm.GetHash(num.Uint64()).Big())
+	// Only the hashes of the 256 most recent blocks are available
+	n, overflow := num.Uint64WithOverflow()
+	if current := evm.BlockNumber.Uint64(); !overflow && n < current && (current < 257 || n > current-257) {
+		hash := evm.GetHash(n)
+		*num = math.Uint256FromBytes(hash[:])
 	} else {
-		stack.push(new(big.Int))
+		*num = uint256Zero
 	}
-
-	evm.interpreter.intPool.put(num, n)
 	return nil, nil
 }
 
 func opCoinbase(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.Coinbase.Big())
+	stack.push(addressToUint256(evm.Coinbase))
 	return nil, nil
 }
 
 func opTimestamp(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(math.U256(new(big.Int).Set(evm.Time)))
+	stack.push(bigToUint256(evm.Time))
 	return nil, nil
 }
 
 func opNumber(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(math.U256(new(big.Int).Set(evm.BlockNumber)))
+	stack.push(bigToUint256(evm.BlockNumber))
 	return nil, nil
 }
 
 func opDifficulty(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(math.U256(new(big.Int).Set(evm.Difficulty)))
+	stack.push(bigToUint256(evm.Difficulty))
 	return nil, nil
 }
 
 func opGasLimit(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(math.U256(new(big.Int).Set(evm.GasLimit)))
+	stack.push(bigToUint256(evm.GasLimit))
 	return nil, nil
 }
 
 func opPop(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	evm.interpreter.intPool.put(stack.pop())
+	stack.pop()
 	return nil, nil
 }
 
 func opMload(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	offset := stack.pop()
-	val := new(big.Int).SetBytes(memory.Get(offset.Int64(), 32))
-	stack.push(val)
-
-	evm.interpreter.intPool.put(offset)
+	offset := stack.peek()
+	*offset = math.Uint256FromBytes(memory.GetPtr(int64(offset.Uint64()), 32))
 	return nil, nil
 }
 
 func opMstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	// pop value of the stack
 	mStart, val := stack.pop(), stack.pop()
-	memory.Set(mStart.Uint64(), 32, math.PaddedBigBytes(val, 32))
-
-	evm.interpreter.intPool.put(mStart, val)
+	word := val.Bytes32()
+	memory.Set(mStart.Uint64(), 32, word[:])
 	return nil, nil
 }
 
 func opMstore8(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	off, val := stack.pop().Int64(), stack.pop().Int64()
-	memory.store[off] = byte(val & 0xff)
+	off, val := stack.pop(), stack.pop()
+	memory.store[off.Uint64()] = byte(val.Uint64())
 
 	return nil, nil
 }
 
 func opSload(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	loc := common.BigToHash(stack.pop())
-	val := evm.StateDB.GetState(contract.Address(), loc).Big()
-	stack.push(val)
+	loc := stack.peek()
+	val := evm.StateDB.GetState(contract.Address(), common.Hash(loc.Bytes32()))
+	*loc = math.Uint256FromBytes(val[:])
 	return nil, nil
 }
 
 func opSstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	loc := common.BigToHash(stack.pop())
-	val := stack.pop()
-	evm.StateDB.SetState(contract.Address(), loc, common.BigToHash(val))
-
-	evm.interpreter.intPool.put(val)
+	loc, val := stack.pop(), stack.pop()
+	evm.StateDB.SetState(contract.Address(), common.Hash(loc.Bytes32()), common.Hash(val.Bytes32()))
 	return nil, nil
 }
 
@@ -501,13 +371,11 @@ func opJump(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Sta
 		return nil, fmt.Errorf("invalid jump destination (%v) %v", nop, pos)
 	}
 	*pc = pos.Uint64()
-
-	evm.interpreter.intPool.put(pos)
 	return nil, nil
 }
 func opJumpi(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	pos, cond := stack.pop(), stack.pop()
-	if cond.Sign() != 0 {
+	if !cond.IsZero() {
 		if !contract.jumpdests.has(contract.CodeHash, contract.Code, pos) {
 			nop := contract.GetOp(pos.Uint64())
 			return nil, fmt.Errorf("invalid jump destination (%v) %v", nop, pos)
@@ -516,8 +384,6 @@ func opJumpi(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *St
 	} else {
 		*pc++
 	}
-
-	evm.interpreter.intPool.put(pos, cond)
 	return nil, nil
 }
 func opJumpdest(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
@@ -525,17 +391,17 @@ func opJumpdest(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack
 }
 
 func opPc(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().SetUint64(*pc))
+	stack.push(math.NewUint256(*pc))
 	return nil, nil
 }
 
 func opMsize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().SetInt64(int64(memory.Len())))
+	stack.push(math.NewUint256(uint64(memory.Len())))
 	return nil, nil
 }
 
 func opGas(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	stack.push(evm.interpreter.intPool.get().SetUint64(contract.Gas))
+	stack.push(math.NewUint256(contract.Gas))
 	return nil, nil
 }
 
@@ -543,7 +409,7 @@ func opCreate(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 	var (
 		value        = stack.pop()
 		offset, size = stack.pop(), stack.pop()
-		input        = memory.Get(offset.Int64(), size.Int64())
+		input        = memory.Get(int64(offset.Uint64()), int64(size.Uint64()))
 		gas          = contract.Gas
 	)
 	if evm.ChainConfig().IsEIP150(evm.BlockNumber) {
@@ -551,22 +417,20 @@ func opCreate(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 	}
 
 	contract.UseGas(gas)
-	_, addr, returnGas, suberr := evm.Create(contract, input, gas, value)
+	_, addr, returnGas, suberr := evm.Create(contract, input, gas, value.Big())
 	// Push item on the stack based on the returned error. If the ruleset is
 	// homestead we must check for CodeStoreOutOfGasError (homestead only
 	// rule) and treat as an error, if the ruleset is frontier we must
 	// ignore this error and pretend the operation was successful.
 	if evm.ChainConfig().IsHomestead(evm.BlockNumber) && suberr == ErrCodeStoreOutOfGas {
-		stack.push(new(big.Int))
+		stack.push(uint256Zero)
 	} else if suberr != nil && suberr != ErrCodeStoreOutOfGas {
-		stack.push(new(big.Int))
+		stack.push(uint256Zero)
 	} else {
-		stack.push(addr.Big())
+		stack.push(addressToUint256(addr))
 	}
 	contract.Gas += returnGas
 
-	evm.interpreter.intPool.put(value, offset, size)
-
 	return nil, nil
 }
 
@@ -574,32 +438,30 @@ func opCall(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Sta
 	gas := stack.pop().Uint64()
 	// pop gas and value of the stack.
 	addr, value := stack.pop(), stack.pop()
-	value = math.U256(value)
 	// pop input size and offset
 	inOffset, inSize := stack.pop(), stack.pop()
 	// pop return size and offset
 	retOffset, retSize := stack.pop(), stack.pop()
 
-	address := common.BigToAddress(addr)
+	address := uint256ToAddress(addr)
 
 	// Get the arguments from the memory
-	args := memory.Get(inOffset.Int64(), inSize.Int64())
+	args := memory.Get(int64(inOffset.Uint64()), int64(inSize.Uint64()))
 
-	if value.Sign() != 0 {
+	if !value.IsZero() {
 		gas += params.CallStipend
 	}
 
-	ret, returnGas, err := evm.Call(contract, address, args, gas, value)
+	ret, returnGas, err := evm.Call(contract, address, args, gas, value.Big())
 	if err != nil {
-		stack.push(new(big.Int))
+		stack.push(uint256Zero)
 	} else {
-		stack.push(big.NewInt(1))
+		stack.push(uint256One)
 
 		memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	contract.Gas += returnGas
 
-	evm.interpreter.intPool.put(addr, value, inOffset, inSize, retOffset, retSize)
 	return ret, nil
 }
 
@@ -607,60 +469,55 @@ func opCallCode(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack
 	gas := stack.pop().Uint64()
 	// pop gas and value of the stack.
 	addr, value := stack.pop(), stack.pop()
-	value = math.U256(value)
 	// pop input size and offset
 	inOffset, inSize := stack.pop(), stack.pop()
 	// pop return size and offset
 	retOffset, retSize := stack.pop(), stack.pop()
 
-	address := common.BigToAddress(addr)
+	address := uint256ToAddress(addr)
 
 	// Get the arguments from the memory
-	args := memory.Get(inOffset.Int64(), inSize.Int64())
+	args := memory.Get(int64(inOffset.Uint64()), int64(inSize.Uint64()))
 
-	if value.Sign() != 0 {
+	if !value.IsZero() {
 		gas += params.CallStipend
 	}
 
-	ret, returnGas, err := evm.CallCode(contract, address, args, gas, value)
+	ret, returnGas, err := evm.CallCode(contract, address, args, gas, value.Big())
 	if err != nil {
-		stack.push(new(big.Int))
+		stack.push(uint256Zero)
 
 	} else {
-		stack.push(big.NewInt(1))
+		stack.push(uint256One)
 
 		memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
 	contract.Gas += returnGas
 
-	evm.interpreter.intPool.put(addr, value, inOffset, inSize, retOffset, retSize)
 	return ret, nil
 }
 
 func opDelegateCall(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	gas, to, inOffset, inSize, outOffset, outSize := stack.pop().Uint64(), stack.pop(), stack.pop(), stack.pop(), stack.pop(), stack.pop()
 
-	toAddr := common.BigToAddress(to)
-	args := memory.Get(inOffset.Int64(), inSize.Int64())
+	toAddr := uint256ToAddress(to)
+	args := memory.Get(int64(inOffset.Uint64()), int64(inSize.Uint64()))
 
 	ret, returnGas, err := evm.DelegateCall(contract, toAddr, args, gas)
 	if err != nil {
-		stack.push(new(big.Int))
+		stack.push(uint256Zero)
 	} else {
-		stack.push(big.NewInt(1))
+		stack.push(uint256One)
 		memory.Set(outOffset.Uint64(), outSize.Uint64(), ret)
 	}
 	contract.Gas += returnGas
 
-	evm.interpreter.intPool.put(to, inOffset, inSize, outOffset, outSize)
 	return ret, nil
 }
 
 func opReturn(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	offset, size := stack.pop(), stack.pop()
-	ret := memory.GetPtr(offset.Int64(), size.Int64())
-
-	evm.interpreter.intPool.put(offset, size)
+	ret := memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
 
 	return ret, nil
 }
@@ -671,7 +528,7 @@ func opStop(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Sta
 
 func opSuicide(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	balance := evm.StateDB.GetBalance(contract.Address())
-	evm.StateDB.AddBalance(common.BigToAddress(stack.pop()), balance)
+	evm.StateDB.AddBalance(uint256ToAddress(stack.pop()), balance)
 
 	evm.StateDB.Suicide(contract.Address())
 
@@ -686,10 +543,10 @@ func makeLog(size int) executionFunc {
 		topics := make([]common.Hash, size)
 		mStart, mSize := stack.pop(), stack.pop()
 		for i := 0; i < size; i++ {
-			topics[i] = common.BigToHash(stack.pop())
+			topics[i] = common.Hash(stack.pop().Bytes32())
 		}
 
-		d := memory.Get(mStart.Int64(), mSize.Int64())
+		d := memory.Get(int64(mStart.Uint64()), int64(mSize.Uint64()))
 		evm.StateDB.AddLog(&types.Log{
 			Address: contract.Address(),
 			Topics:  topics,
@@ -699,7 +556,6 @@ func makeLog(size int) executionFunc {
 			BlockNumber: evm.BlockNumber.Uint64(),
 		})
 
-		evm.interpreter.intPool.put(mStart, mSize)
 		return nil, nil
 	}
 }
@@ -719,8 +575,7 @@ func makePush(size uint64, pushByteSize int) executionFunc {
 			endMin = startMin + pushByteSize
 		}
 
-		integer := evm.interpreter.intPool.get()
-		stack.push(integer.SetBytes(common.RightPadBytes(contract.Code[startMin:endMin], pushByteSize)))
+		stack.push(math.Uint256FromBytes(common.RightPadBytes(contract.Code[startMin:endMin], pushByteSize)))
 
 		*pc += size
 		return nil, nil
@@ -730,7 +585,7 @@ func makePush(size uint64, pushByteSize int) executionFunc {
 // make push instruction function
 func makeDup(size int64) executionFunc {
 	return func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-		stack.dup(evm.interpreter.intPool, int(size))
+		stack.dup(int(size))
 		return nil, nil
 	}
 }
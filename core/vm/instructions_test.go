@@ -3,9 +3,11 @@ package vm
 import (
 	"fmt"
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/params"
 )
 
@@ -17,31 +19,159 @@ func TestByteOp(t *testing.T) {
 	tests := []struct {
 		v        string
 		th       uint64
-		expected *big.Int
+		expected uint64
 	}{
-		{"ABCDEF0908070605040302010000000000000000000000000000000000000000", 0, big.NewInt(0xAB)},
-		{"ABCDEF0908070605040302010000000000000000000000000000000000000000", 1, big.NewInt(0xCD)},
-		{"00CDEF090807060504030201ffffffffffffffffffffffffffffffffffffffff", 0, big.NewInt(0x00)},
-		{"00CDEF090807060504030201ffffffffffffffffffffffffffffffffffffffff", 1, big.NewInt(0xCD)},
-		{"0000000000000000000000000000000000000000000000000000000000102030", 31, big.NewInt(0x30)},
-		{"0000000000000000000000000000000000000000000000000000000000102030", 30, big.NewInt(0x20)},
-		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 32, big.NewInt(0x0)},
-		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 0xFFFFFFFFFFFFFFFF, big.NewInt(0x0)},
+		{"ABCDEF0908070605040302010000000000000000000000000000000000000000", 0, 0xAB},
+		{"ABCDEF0908070605040302010000000000000000000000000000000000000000", 1, 0xCD},
+		{"00CDEF090807060504030201ffffffffffffffffffffffffffffffffffffffff", 0, 0x00},
+		{"00CDEF090807060504030201ffffffffffffffffffffffffffffffffffffffff", 1, 0xCD},
+		{"0000000000000000000000000000000000000000000000000000000000102030", 31, 0x30},
+		{"0000000000000000000000000000000000000000000000000000000000102030", 30, 0x20},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 32, 0x0},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 0xFFFFFFFFFFFFFFFF, 0x0},
 	}
 	pc := uint64(0)
 	for _, test := range tests {
-		val := new(big.Int).SetBytes(common.Hex2Bytes(test.v))
-		th := new(big.Int).SetUint64(test.th)
-		stack.push(val)
-		stack.push(th)
+		stack.push(math.Uint256FromBytes(common.Hex2Bytes(test.v)))
+		stack.push(math.NewUint256(test.th))
 		opByte(&pc, env, nil, nil, stack)
 		actual := stack.pop()
-		if actual.Cmp(test.expected) != 0 {
+		if actual != math.NewUint256(test.expected) {
 			t.Fatalf("Expected  [%v] %v:th byte to be %v, was %v.", test.v, test.th, test.expected, actual)
 		}
 	}
 }
 
+// Tests that the arithmetic, comparison and bitwise opcodes on fixed-width stack
+// items match their big integer definitions in the yellow paper, on random and
+// edge case operands.
+func TestArithmeticOps(t *testing.T) {
+	u256 := func(x *big.Int) *big.Int { return math.U256(x) }
+	s256 := func(x *big.Int) *big.Int { return math.S256(new(big.Int).Set(x)) }
+	bool256 := func(ok bool) *big.Int {
+		if ok {
+			return big.NewInt(1)
+		}
+		return new(big.Int)
+	}
+	tests := []struct {
+		name string
+		op   executionFunc
+		args int
+		want func(x, y, z *big.Int) *big.Int
+	}{
+		{"ADD", opAdd, 2, func(x, y, z *big.Int) *big.Int { return u256(new(big.Int).Add(x, y)) }},
+		{"SUB", opSub, 2, func(x, y, z *big.Int) *big.Int { return u256(new(big.Int).Sub(x, y)) }},
+		{"MUL", opMul, 2, func(x, y, z *big.Int) *big.Int { return u256(new(big.Int).Mul(x, y)) }},
+		{"DIV", opDiv, 2, func(x, y, z *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Div(x, y)
+		}},
+		{"SDIV", opSdiv, 2, func(x, y, z *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return u256(new(big.Int).Quo(s256(x), s256(y)))
+		}},
+		{"MOD", opMod, 2, func(x, y, z *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return new(big.Int).Mod(x, y)
+		}},
+		{"SMOD", opSmod, 2, func(x, y, z *big.Int) *big.Int {
+			if y.Sign() == 0 {
+				return new(big.Int)
+			}
+			return u256(new(big.Int).Rem(s256(x), s256(y)))
+		}},
+		{"ADDMOD", opAddmod, 3, func(x, y, z *big.Int) *big.Int {
+			if z.Sign() == 0 {
+				return new(big.Int)
+			}
+			sum := new(big.Int).Add(x, y)
+			return sum.Mod(sum, z)
+		}},
+		{"MULMOD", opMulmod, 3, func(x, y, z *big.Int) *big.Int {
+			if z.Sign() == 0 {
+				return new(big.Int)
+			}
+			prod := new(big.Int).Mul(x, y)
+			return prod.Mod(prod, z)
+		}},
+		{"EXP", opExp, 2, func(x, y, z *big.Int) *big.Int { return math.Exp(new(big.Int).Set(x), y) }},
+		{"SIGNEXTEND", opSignExtend, 2, func(x, y, z *big.Int) *big.Int {
+			if x.Cmp(big.NewInt(31)) >= 0 {
+				return new(big.Int).Set(y)
+			}
+			bit := uint(x.Uint64()*8 + 7)
+			mask := new(big.Int).Lsh(common.Big1, bit)
+			mask.Sub(mask, common.Big1)
+			num := new(big.Int).Set(y)
+			if num.Bit(int(bit)) > 0 {
+				num.Or(num, mask.Not(mask))
+			} else {
+				num.And(num, mask)
+			}
+			return u256(num)
+		}},
+		{"LT", opLt, 2, func(x, y, z *big.Int) *big.Int { return bool256(x.Cmp(y) < 0) }},
+		{"GT", opGt, 2, func(x, y, z *big.Int) *big.Int { return bool256(x.Cmp(y) > 0) }},
+		{"SLT", opSlt, 2, func(x, y, z *big.Int) *big.Int { return bool256(s256(x).Cmp(s256(y)) < 0) }},
+		{"SGT", opSgt, 2, func(x, y, z *big.Int) *big.Int { return bool256(s256(x).Cmp(s256(y)) > 0) }},
+		{"EQ", opEq, 2, func(x, y, z *big.Int) *big.Int { return bool256(x.Cmp(y) == 0) }},
+		{"ISZERO", opIszero, 1, func(x, y, z *big.Int) *big.Int { return bool256(x.Sign() == 0) }},
+		{"AND", opAnd, 2, func(x, y, z *big.Int) *big.Int { return new(big.Int).And(x, y) }},
+		{"OR", opOr, 2, func(x, y, z *big.Int) *big.Int { return new(big.Int).Or(x, y) }},
+		{"XOR", opXor, 2, func(x, y, z *big.Int) *big.Int { return new(big.Int).Xor(x, y) }},
+		{"NOT", opNot, 1, func(x, y, z *big.Int) *big.Int { return u256(new(big.Int).Not(x)) }},
+		{"BYTE", opByte, 2, func(x, y, z *big.Int) *big.Int {
+			if x.Cmp(common.Big32) >= 0 {
+				return new(big.Int)
+			}
+			return big.NewInt(int64(math.Byte(y, 32, int(x.Int64()))))
+		}},
+	}
+	operands := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(2), big.NewInt(30), big.NewInt(31), big.NewInt(32),
+		math.BigPow(2, 64), new(big.Int).Sub(math.BigPow(2, 64), common.Big1),
+		math.BigPow(2, 128), math.BigPow(2, 255), new(big.Int).Sub(math.BigPow(2, 255), common.Big1),
+		math.MaxBig256, new(big.Int).Sub(math.MaxBig256, common.Big1),
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		operands = append(operands, new(big.Int).Rand(rnd, math.BigPow(2, int64(rnd.Intn(257)))))
+	}
+	var (
+		env   = NewEVM(Context{}, nil, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
+		stack = newstack()
+		pc    = uint64(0)
+	)
+	for _, test := range tests {
+		for i, x := range operands {
+			z := operands[(i*5+1)%len(operands)]
+			for _, y := range []*big.Int{operands[(i*7+3)%len(operands)], operands[len(operands)-1-i]} {
+				args := []*big.Int{x, y, z}[:test.args]
+				for j := len(args) - 1; j >= 0; j-- {
+					item, _ := math.Uint256FromBig(args[j])
+					stack.push(item)
+				}
+				if _, err := test.op(&pc, env, nil, nil, stack); err != nil {
+					t.Fatalf("%s%x: failed to execute: %v", test.name, args, err)
+				}
+				if stack.len() != 1 {
+					t.Fatalf("%s%x: stack size mismatch: have %d, want 1", test.name, args, stack.len())
+				}
+				if have, want := stack.pop().Big(), test.want(x, y, z); have.Cmp(want) != 0 {
+					t.Errorf("%s%x: result mismatch: have %x, want %x", test.name, args, have, want)
+				}
+			}
+		}
+	}
+}
+
 func opBenchmark(bench *testing.B, op func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error), args ...string) {
 	var (
 		env   = NewEVM(Context{}, nil, params.TestChainConfig, Config{EnableJit: false, ForceJit: false})
@@ -56,8 +186,7 @@ func opBenchmark(bench *testing.B, op func(pc *uint64, evm *EVM, contract *Contr
 	bench.ResetTimer()
 	for i := 0; i < bench.N; i++ {
 		for _, arg := range byteArgs {
-			a := new(big.Int).SetBytes(arg)
-			stack.push(a)
+			stack.push(math.Uint256FromBytes(arg))
 		}
 		op(&pc, env, nil, nil, stack)
 		stack.pop()
@@ -0,0 +1,153 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/math"
+	"github.com/networkchain/networkchain/params"
+)
+
+// returnTop is the code returning the item on the top of the stack as a word.
+var returnTop = []byte{byte(PUSH1), 0, byte(MSTORE), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN)}
+
+// push32 returns the code pushing the given word onto the stack.
+func push32(x *big.Int) []byte {
+	return append([]byte{byte(PUSH32)}, math.PaddedBigBytes(x, 32)...)
+}
+
+// loopCode returns the code running body n times with the counter and the
+// accumulator (initialised to acc) on the stack, returning the accumulator. The
+// body must replace the accumulator, leaving the counter on the top.
+func loopCode(acc byte, n uint16, body []byte) []byte {
+	code := []byte{
+		byte(PUSH1), acc,
+		byte(PUSH2), byte(n >> 8), byte(n),
+		byte(JUMPDEST), // pc 5
+	}
+	code = append(code, body...)
+	code = append(code,
+		byte(PUSH1), 1, byte(SWAP1), byte(SUB), // counter--
+		byte(DUP1), byte(PUSH1), 5, byte(JUMPI),
+		byte(POP),
+	)
+	return append(code, returnTop...)
+}
+
+var (
+	// sumBody adds the counter to the accumulator.
+	sumBody = []byte{byte(DUP1), byte(SWAP2), byte(ADD), byte(SWAP1)}
+
+	// mulmodPrime is the modulus of the accumulator in mulmodBody.
+	mulmodPrime, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+
+	// mulmodBody multiplies the accumulator by the counter modulo mulmodPrime.
+	mulmodBody = append(push32(mulmodPrime), byte(DUP2), byte(DUP4), byte(MULMOD), byte(SWAP2), byte(POP))
+)
+
+// runCode executes the code in a fresh interpreter, returning its output.
+func runCode(code, input []byte) ([]byte, error) {
+	env := NewEVM(Context{BlockNumber: new(big.Int)}, nil, params.TestChainConfig, Config{})
+
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(common.Address{}), new(big.Int), 1000000000)
+	contract.SetCode(common.Hash{}, code)
+
+	return env.interpreter.Run(0, contract, input)
+}
+
+// Tests that programs mixing arithmetic with stack, memory and control flow
+// operations run to the expected results on the fixed-width stack.
+func TestInterpreterArithmetic(t *testing.T) {
+	maxMulMod := new(big.Int).Mul(math.MaxBig256, math.MaxBig256)
+	maxMulMod.Mod(maxMulMod, big.NewInt(7))
+
+	mulmodWant := big.NewInt(1)
+	for i := int64(1); i <= 300; i++ {
+		mulmodWant.Mul(mulmodWant, big.NewInt(i))
+		mulmodWant.Mod(mulmodWant, mulmodPrime)
+	}
+	tests := []struct {
+		name  string
+		code  []byte
+		input []byte
+		want  *big.Int
+	}{
+		{"add", []byte{byte(PUSH1), 3, byte(PUSH1), 5, byte(ADD)}, nil, big.NewInt(8)},
+		{"sub", []byte{byte(PUSH1), 3, byte(PUSH1), 5, byte(SUB)}, nil, big.NewInt(2)},
+		{"sub-wrap", []byte{byte(PUSH1), 5, byte(PUSH1), 3, byte(SUB)}, nil, new(big.Int).Sub(math.MaxBig256, common.Big1)},
+		{"sdiv", []byte{byte(PUSH1), 3, byte(PUSH1), 8, byte(PUSH1), 0, byte(SUB), byte(SDIV)}, nil, new(big.Int).Sub(math.MaxBig256, common.Big1)},
+		{"exp", []byte{byte(PUSH1), 255, byte(PUSH1), 2, byte(EXP)}, nil, math.BigPow(2, 255)},
+		{"mulmod", append(append([]byte{byte(PUSH1), 7}, append(push32(math.MaxBig256), push32(math.MaxBig256)...)...), byte(MULMOD)), nil, maxMulMod},
+		{"signextend", []byte{byte(PUSH1), 0x80, byte(PUSH1), 0, byte(SIGNEXTEND)}, nil, new(big.Int).Sub(math.MaxBig256, big.NewInt(0x7f))},
+		{"calldataload", []byte{byte(PUSH1), 1, byte(CALLDATALOAD)}, []byte{0x01, 0x02, 0x03}, new(big.Int).Lsh(big.NewInt(0x0203), 240)},
+		{"calldataload-oob", append(push32(math.MaxBig256), byte(CALLDATALOAD)), []byte{0x01}, new(big.Int)},
+		{"mload", []byte{byte(PUSH1), 0x2a, byte(PUSH1), 1, byte(MSTORE8), byte(PUSH1), 0, byte(MLOAD)}, nil, new(big.Int).Lsh(big.NewInt(0x2a), 240)},
+		{"sum-loop", loopCode(0, 100, sumBody), nil, big.NewInt(5050)},
+		{"mulmod-loop", loopCode(1, 300, mulmodBody), nil, mulmodWant},
+	}
+
+	for _, tt := range tests {
+		code := tt.code
+		if !bytes.HasSuffix(code, returnTop) {
+			code = append(append([]byte{}, code...), returnTop...)
+		}
+		ret, err := runCode(code, tt.input)
+		if err != nil {
+			t.Errorf("%s: failed to run code: %v", tt.name, err)
+			continue
+		}
+		if want := math.PaddedBigBytes(tt.want, 32); !bytes.Equal(ret, want) {
+			t.Errorf("%s: result mismatch: have %x, want %x", tt.name, ret, want)
+		}
+	}
+}
+
+// Tests that jumps to destinations not fitting into a uint64 are rejected rather
+// than truncated onto a valid JUMPDEST.
+func TestInterpreterJumpOverflow(t *testing.T) {
+	// The JUMPDEST is at 35, which the lowest 64 bits of the target point to
+	dest := new(big.Int).Add(math.BigPow(2, 64), big.NewInt(35))
+	code := append(push32(dest), byte(JUMP), byte(STOP), byte(JUMPDEST), byte(STOP))
+	if _, err := runCode(code, nil); err == nil {
+		t.Fatalf("jump to 2^64+35 succeeded")
+	}
+	code = append(push32(big.NewInt(35)), byte(JUMP), byte(STOP), byte(JUMPDEST), byte(STOP))
+	if _, err := runCode(code, nil); err != nil {
+		t.Fatalf("jump to 35 failed: %v", err)
+	}
+}
+
+func benchmarkInterpreter(b *testing.B, code []byte) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := runCode(code, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpreterSumLoop(b *testing.B) {
+	benchmarkInterpreter(b, loopCode(0, 1000, sumBody))
+}
+
+func BenchmarkInterpreterMulModLoop(b *testing.B) {
+	benchmarkInterpreter(b, loopCode(1, 1000, mulmodBody))
+}
@@ -18,7 +18,6 @@ package vm
 
 import (
 	"errors"
-	"math/big"
 
 	"github.com/networkchain/networkchain/params"
 )
@@ -27,7 +26,7 @@ type (
 	executionFunc       func(pc *uint64, env *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error)
 	gasFunc             func(params.GasTable, *EVM, *Contract, *Stack, *Memory, uint64) (uint64, error) // last parameter is the requested memory size as a uint64
 	stackValidationFunc func(*Stack) error
-	memorySizeFunc      func(*Stack) *big.Int
+	memorySizeFunc      func(*Stack) (uint64, bool)
 )
 
 var errGasUintOverflow = errors.New("gas uint64 overflow")
@@ -55,15 +54,20 @@ type operation struct {
 	reverts bool
 }
 
-var (
-	frontierInstructionSet  = NewFrontierInstructionSet()
-	homesteadInstructionSet = NewHomesteadInstructionSet()
-)
+func init() {
+	registerInstructionSet("homestead", func(rules params.Rules) bool { return rules.IsHomestead }, enableHomestead)
+}
 
 // NewHomesteadInstructionSet returns the frontier and homestead
 // instructions that can be executed during the homestead phase.
 func NewHomesteadInstructionSet() [256]operation {
 	instructionSet := NewFrontierInstructionSet()
+	enableHomestead(&instructionSet)
+	return instructionSet
+}
+
+// enableHomestead adds the instructions introduced by the homestead phase.
+func enableHomestead(instructionSet *[256]operation) {
 	instructionSet[DELEGATECALL] = operation{
 		execute:       opDelegateCall,
 		gasCost:       gasDelegateCall,
@@ -71,7 +75,6 @@ func NewHomesteadInstructionSet() [256]operation {
 		memorySize:    memoryDelegateCall,
 		valid:         true,
 	}
-	return instructionSet
 }
 
 // NewFrontierInstructionSet returns the frontier instructions
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/params"
 )
 
@@ -54,8 +55,8 @@ func TestStoreCapture(t *testing.T) {
 		stack    = newstack()
 		contract = NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 0)
 	)
-	stack.push(big.NewInt(1))
-	stack.push(big.NewInt(0))
+	stack.push(math.NewUint256(1))
+	stack.push(math.NewUint256(0))
 
 	var index common.Hash
 
@@ -18,35 +18,37 @@ package vm
 
 import (
 	"fmt"
-	"math/big"
+
+	"github.com/networkchain/networkchain/common/math"
 )
 
-// stack is an object for basic stack operations. Items popped to the stack are
-// expected to be changed and modified. stack does not take care of adding newly
-// initialised objects.
+// stack is an object for basic stack operations. Items are fixed-width values,
+// so pushing and popping them doesn't allocate. The stack is preallocated to its
+// limit, so pointers into it returned by peek and Back stay valid until the item
+// is popped.
 type Stack struct {
-	data []*big.Int
+	data []math.Uint256
 }
 
 func newstack() *Stack {
-	return &Stack{data: make([]*big.Int, 0, 1024)}
+	return &Stack{data: make([]math.Uint256, 0, 1024)}
 }
 
-func (st *Stack) Data() []*big.Int {
+// Data returns the items on the stack, bottom first. The slice is backed by the
+// stack itself, copy it to retain it past the current operation.
+func (st *Stack) Data() []math.Uint256 {
 	return st.data
 }
 
-func (st *Stack) push(d *big.Int) {
+func (st *Stack) push(d math.Uint256) {
 	// NOTE push limit (1024) is checked in baseCheck
-	//stackItem := new(big.Int).Set(d)
-	//st.data = append(st.data, stackItem)
 	st.data = append(st.data, d)
 }
-func (st *Stack) pushN(ds ...*big.Int) {
+func (st *Stack) pushN(ds ...math.Uint256) {
 	st.data = append(st.data, ds...)
 }
 
-func (st *Stack) pop() (ret *big.Int) {
+func (st *Stack) pop() (ret math.Uint256) {
 	ret = st.data[len(st.data)-1]
 	st.data = st.data[:len(st.data)-1]
 	return
@@ -60,19 +62,18 @@ func (st *Stack) swap(n int) {
 	st.data[st.len()-n], st.data[st.len()-1] = st.data[st.len()-1], st.data[st.len()-n]
 }
 
-func (st *Stack) dup(pool *intPool, n int) {
-	st.push(pool.get().Set(st.data[st.len()-n]))
+func (st *Stack) dup(n int) {
+	st.push(st.data[st.len()-n])
 }
 
-func (st *Stack) peek() *big.Int {
-	return st.data[st.len()-1]
+func (st *Stack) peek() *math.Uint256 {
+	return &st.data[st.len()-1]
 }
 
 // Back returns the n'th item in stack
-func (st *Stack) Back(n int) *big.Int {
-	return st.data[st.len()-n-1]
+func (st *Stack) Back(n int) *math.Uint256 {
+	return &st.data[st.len()-n-1]
 }
-
 func (st *Stack) require(n int) error {
 	if st.len() < n {
 		return fmt.Errorf("stack underflow (%d <=> %d)", len(st.data), n)
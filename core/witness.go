@@ -0,0 +1,71 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+)
+
+// Witness is the execution witness of a block: the trie nodes and contract codes
+// of the parent state touched while processing it. It suffices to reexecute the
+// block and verify its post state without access to the full state.
+type Witness struct {
+	Number uint64          `json:"number"`
+	Hash   common.Hash     `json:"hash"`
+	Root   common.Hash     `json:"root"` // State root of the parent block
+	Nodes  []hexutil.Bytes `json:"nodes"`
+	Codes  []hexutil.Bytes `json:"codes"`
+}
+
+// newWitness assembles the witness of a block from the data recorded by a witness
+// database while processing it.
+func newWitness(number uint64, hash common.Hash, root common.Hash, db *state.WitnessDatabase) *Witness {
+	nodes, codes := db.Witness()
+
+	witness := &Witness{
+		Number: number,
+		Hash:   hash,
+		Root:   root,
+		Nodes:  make([]hexutil.Bytes, len(nodes)),
+		Codes:  make([]hexutil.Bytes, len(codes)),
+	}
+	for i, node := range nodes {
+		witness.Nodes[i] = node
+	}
+	for i, code := range codes {
+		witness.Codes[i] = code
+	}
+	return witness
+}
+
+// State returns the parent state of the witnessed block, backed only by the data
+// in the witness. Accessing state not covered by the witness results in missing
+// trie node errors.
+func (w *Witness) State() (*state.StateDB, error) {
+	db, _ := ethdb.NewMemDatabase()
+	for _, node := range w.Nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	for _, code := range w.Codes {
+		db.Put(crypto.Keccak256(code), code)
+	}
+	return state.New(w.Root, state.NewDatabase(db))
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// Tests that the recorded execution witnesses suffice to reexecute the blocks and
+// reproduce their post state roots.
+func TestWitnessReexecution(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+
+		counter = common.Address{0xc0} // Increments slot 0 and emits a log on every call
+		sizer   = common.Address{0xc1} // Stores the code size of the counter
+		empty   = common.Address{0xee} // Touched with zero value transfers
+
+		gspec = &Genesis{
+			Config: &params.ChainConfig{
+				ChainId:        big.NewInt(1),
+				HomesteadBlock: new(big.Int),
+				EIP155Block:    new(big.Int),
+				EIP158Block:    big.NewInt(2),
+			},
+			Alloc: GenesisAlloc{
+				addr:    {Balance: big.NewInt(1000000000)},
+				counter: {Balance: new(big.Int), Code: common.FromHex("60005460010160005560006000a0")},
+				sizer:   {Balance: new(big.Int), Code: common.FromHex("60c03b600055")},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 4, func(i int, block *BlockGen) {
+		send := func(to common.Address, value int64, gas int64) {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), to, big.NewInt(value), big.NewInt(gas), big.NewInt(1), nil), signer, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(tx)
+		}
+		send(counter, 0, 100000)
+		send(sizer, 0, 100000)
+		send(common.Address{byte(i + 1)}, 1000, 21000)
+		send(empty, 0, 21000) // Touches an empty account, deleted after EIP158
+	})
+	db, _ = ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{RecordWitness: true})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	processor := NewStateProcessor(gspec.Config, chain, ethash.NewFaker())
+	for _, block := range blocks {
+		witness := chain.Witness(block.Hash())
+		if witness == nil {
+			t.Fatalf("block %d: missing witness", block.NumberU64())
+		}
+		if witness.Root != chain.GetBlockByHash(block.ParentHash()).Root() {
+			t.Errorf("block %d: witness root mismatch", block.NumberU64())
+		}
+		if len(witness.Codes) != 2 {
+			t.Errorf("block %d: witnessed code count mismatch: have %d, want %d", block.NumberU64(), len(witness.Codes), 2)
+		}
+		statedb, err := witness.State()
+		if err != nil {
+			t.Fatalf("block %d: failed to open witness state: %v", block.NumberU64(), err)
+		}
+		if _, _, _, err := processor.Process(block, statedb, vm.Config{}); err != nil {
+			t.Fatalf("block %d: failed to reexecute: %v", block.NumberU64(), err)
+		}
+		if root := statedb.IntermediateRoot(gspec.Config.IsEIP158(block.Number())); root != block.Root() {
+			t.Errorf("block %d: post state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+		if err := statedb.Error(); err != nil {
+			t.Errorf("block %d: state access outside the witness: %v", block.NumberU64(), err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import bls12381 "github.com/kilic/bls12-381"
+
+// AggregateG1 returns the sum of the given G1 points, or the identity if there
+// are none. The inputs are left unmodified.
+func AggregateG1(points []*G1) *G1 {
+	g := bls12381.NewG1()
+	acc := g.Zero()
+	for _, p := range points {
+		g.Add(acc, acc, p.p)
+	}
+	return &G1{g.Affine(acc)}
+}
+
+// AggregateG2 returns the sum of the given G2 points, or the identity if there
+// are none. The inputs are left unmodified.
+func AggregateG2(points []*G2) *G2 {
+	g := bls12381.NewG2()
+	acc := g.Zero()
+	for _, p := range points {
+		g.Add(acc, acc, p.p)
+	}
+	return &G2{g.Affine(acc)}
+}
+
+// AggregateCompressedG1 decodes a batch of points in the compressed format and
+// returns their sum. Every point is checked to be in the prime order subgroup
+// before being added, so the batch may come from an untrusted source.
+func AggregateCompressedG1(encs [][]byte) (*G1, error) {
+	points := make([]*G1, len(encs))
+	for i, enc := range encs {
+		p, err := new(G1).UnmarshalCompressed(enc)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return AggregateG1(points), nil
+}
+
+// AggregateCompressedG2 decodes a batch of points in the compressed format and
+// returns their sum. Every point is checked to be in the prime order subgroup
+// before being added, so the batch may come from an untrusted source.
+func AggregateCompressedG2(encs [][]byte) (*G2, error) {
+	points := make([]*G2, len(encs))
+	for i, enc := range encs {
+		p, err := new(G2).UnmarshalCompressed(enc)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return AggregateG2(points), nil
+}
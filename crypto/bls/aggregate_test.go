@@ -0,0 +1,165 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Tests that aggregating points sums them up without touching the inputs.
+func TestAggregate(t *testing.T) {
+	if !AggregateG1(nil).IsInfinity() || !AggregateG2(nil).IsInfinity() {
+		t.Fatalf("empty aggregate is not the identity")
+	}
+	var (
+		sum = new(big.Int)
+		g1s []*G1
+		g2s []*G2
+	)
+	for i := 0; i < 4; i++ {
+		k := randScalar(t)
+		sum.Add(sum, k)
+		g1s = append(g1s, new(G1).ScalarBaseMult(k))
+		g2s = append(g2s, new(G2).ScalarBaseMult(k))
+	}
+	sum.Mod(sum, Order)
+
+	first := g1s[0].Marshal()
+	if !AggregateG1(g1s).Equal(new(G1).ScalarBaseMult(sum)) {
+		t.Errorf("G1 aggregate mismatch")
+	}
+	if !AggregateG2(g2s).Equal(new(G2).ScalarBaseMult(sum)) {
+		t.Errorf("G2 aggregate mismatch")
+	}
+	if !bytes.Equal(g1s[0].Marshal(), first) {
+		t.Errorf("aggregation modified its input")
+	}
+	// A point cancelled by its negation must aggregate into the identity
+	if !AggregateG1([]*G1{g1s[1], new(G1).Neg(g1s[1])}).IsInfinity() {
+		t.Errorf("G1 aggregate of opposite points is not the identity")
+	}
+	if !AggregateG2([]*G2{g2s[1], new(G2).Neg(g2s[1])}).IsInfinity() {
+		t.Errorf("G2 aggregate of opposite points is not the identity")
+	}
+}
+
+// Tests that aggregating compressed points decodes and sums them, rejecting the
+// whole batch if any point is invalid.
+func TestAggregateCompressed(t *testing.T) {
+	var (
+		g1s  []*G1
+		g2s  []*G2
+		enc1 [][]byte
+		enc2 [][]byte
+	)
+	for i := 0; i < 3; i++ {
+		k := randScalar(t)
+		g1s = append(g1s, new(G1).ScalarBaseMult(k))
+		g2s = append(g2s, new(G2).ScalarBaseMult(k))
+		enc1 = append(enc1, g1s[i].MarshalCompressed())
+		enc2 = append(enc2, g2s[i].MarshalCompressed())
+	}
+	if sum, err := AggregateCompressedG1(enc1); err != nil {
+		t.Errorf("failed to aggregate G1 points: %v", err)
+	} else if !sum.Equal(AggregateG1(g1s)) {
+		t.Errorf("compressed G1 aggregate mismatch")
+	}
+	if sum, err := AggregateCompressedG2(enc2); err != nil {
+		t.Errorf("failed to aggregate G2 points: %v", err)
+	} else if !sum.Equal(AggregateG2(g2s)) {
+		t.Errorf("compressed G2 aggregate mismatch")
+	}
+	enc1 = append(enc1, enc1[0][:fpBytes-1])
+	if _, err := AggregateCompressedG1(enc1); err != errInvalidLength {
+		t.Errorf("G1 error mismatch: have %v, want %v", err, errInvalidLength)
+	}
+	enc2 = append(enc2, make([]byte, 2*fpBytes))
+	if _, err := AggregateCompressedG2(enc2); err != errNotCompressed {
+		t.Errorf("G2 error mismatch: have %v, want %v", err, errNotCompressed)
+	}
+}
+
+// Tests that the product of pairings matches pairing the points one by one,
+// and that it verifies aggregates the way aggregate signatures are checked.
+func TestMultiPair(t *testing.T) {
+	a, b := randScalar(t), randScalar(t)
+
+	g1s := []*G1{new(G1).ScalarBaseMult(a), G1Generator()}
+	g2s := []*G2{G2Generator(), new(G2).ScalarBaseMult(b)}
+	have, err := MultiPair(g1s, g2s)
+	if err != nil {
+		t.Fatalf("failed to calculate multi-pairing: %v", err)
+	}
+	ab := new(big.Int).Add(a, b)
+	if want := Pair(new(G1).ScalarBaseMult(ab.Mod(ab, Order)), G2Generator()); !have.Equal(want) {
+		t.Errorf("e(aP, Q)·e(P, bQ) != e((a+b)P, Q)")
+	}
+	if _, err := MultiPair(g1s, g2s[:1]); err != errPairingLength {
+		t.Errorf("length error mismatch: have %v, want %v", err, errPairingLength)
+	}
+	if PairingCheck(g1s, g2s[:1]) {
+		t.Errorf("pairing check passed on mismatching lengths")
+	}
+	if e, err := MultiPair(nil, nil); err != nil || !e.IsOne() {
+		t.Errorf("empty multi-pairing is not the identity: %v", err)
+	}
+	// With keys kᵢ·P and points kᵢ·H, e(-P, Σkᵢ·H)·e(Σkᵢ·P, H) must be one
+	h := new(G2).ScalarBaseMult(randScalar(t))
+
+	var (
+		keys   []*G1
+		points []*G2
+	)
+	for i := 0; i < 3; i++ {
+		k := randScalar(t)
+		keys = append(keys, new(G1).ScalarBaseMult(k))
+		points = append(points, new(G2).ScalarMult(h, k))
+	}
+	neg := new(G1).Neg(G1Generator())
+	if !PairingCheck([]*G1{neg, AggregateG1(keys)}, []*G2{AggregateG2(points), h}) {
+		t.Errorf("aggregate pairing check failed on valid input")
+	}
+	points[0] = new(G2).ScalarMult(h, randScalar(t))
+	if PairingCheck([]*G1{neg, AggregateG1(keys)}, []*G2{AggregateG2(points), h}) {
+		t.Errorf("aggregate pairing check passed on invalid input")
+	}
+}
+
+// Tests that aggregating random compressed inputs never accepts points outside
+// of the prime order subgroup.
+func TestAggregateFuzz(t *testing.T) {
+	valid := G1Generator().MarshalCompressed()
+	for i := 0; i < 50; i++ {
+		blob := make([]byte, fpBytes)
+		rand.Read(blob)
+		blob[0] |= flagCompressed
+		if sum, err := AggregateCompressedG1([][]byte{valid, blob}); err == nil && !bls12381.NewG1().InCorrectSubgroup(sum.p) {
+			t.Errorf("random G1 encoding aggregated outside the subgroup: %x", blob)
+		}
+		g2blob := make([]byte, 2*fpBytes)
+		rand.Read(g2blob)
+		g2blob[0] |= flagCompressed
+		if sum, err := AggregateCompressedG2([][]byte{g2blob}); err == nil && !bls12381.NewG2().InCorrectSubgroup(sum.p) {
+			t.Errorf("random G2 encoding aggregated outside the subgroup: %x", g2blob)
+		}
+	}
+}
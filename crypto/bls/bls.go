@@ -0,0 +1,140 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bls implements the BLS12-381 pairing friendly curve: the groups G1 and
+// G2, the optimal ate pairing into GT and the subgroup checks needed to safely
+// accept points from untrusted sources. Points of either group can be aggregated
+// into their sum and checked against a product of pairings, the building blocks
+// of aggregate signature verification.
+//
+// Points are encoded in the uncompressed format of EIP-2537, the input format of
+// the BLS12-381 precompiles, and in the compressed format of the ZCash BLS12-381
+// serialization used by most other implementations.
+//
+// The curve arithmetic is delegated to github.com/kilic/bls12-381, whose field
+// arithmetic is constant time. Its scalar multiplication is not, as it recodes
+// the scalar into a windowed NAF, so the package is still meant for operations
+// on public inputs, such as precompile arguments, and must not be used with
+// secret scalars. For that reason the package deliberately does not implement
+// BLS signatures, which also need the hash to curve suites of the IETF to
+// interoperate.
+package bls
+
+import (
+	"errors"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+var (
+	errNotCompressed   = errors.New("bls: compression flag not set")
+	errInvalidInfinity = errors.New("bls: invalid encoding of the identity")
+)
+
+// Flags stored in the three most significant bits of compressed encodings.
+const (
+	flagCompressed = 1 << 7 // Encoding holds the x coordinate only
+	flagInfinity   = 1 << 6 // Encoded point is the identity
+	flagLargest    = 1 << 5 // y is the lexicographically larger of ±y
+
+	flagMask = flagCompressed | flagInfinity | flagLargest
+)
+
+// MarshalCompressed encodes e in the 48 byte compressed format of the ZCash
+// serialization: the big endian x coordinate, with the top bits flagging the
+// compression, the identity and whether y is the larger of its two candidates.
+func (e *G1) MarshalCompressed() []byte {
+	return bls12381.NewG1().ToCompressed(new(bls12381.PointG1).Set(e.p))
+}
+
+// UnmarshalCompressed sets e to the point encoded in m in the compressed format,
+// verifying that it is on the curve and in the prime order subgroup.
+func (e *G1) UnmarshalCompressed(m []byte) (*G1, error) {
+	if len(m) != fpBytes {
+		return nil, errInvalidLength
+	}
+	flags, x, err := splitFlags(m)
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG1()
+	if flags&flagInfinity != 0 {
+		e.p = g.Zero()
+		return e, nil
+	}
+	if err := checkFp(x); err != nil {
+		return nil, err
+	}
+	p, err := g.FromCompressed(m)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	e.p = p
+	return e, nil
+}
+
+// MarshalCompressed encodes e in the 96 byte compressed format of the ZCash
+// serialization: the big endian x coordinate as c1 || c0, with the top bits
+// flagging the compression, the identity and whether y is the larger of its two
+// candidates.
+func (e *G2) MarshalCompressed() []byte {
+	return bls12381.NewG2().ToCompressed(new(bls12381.PointG2).Set(e.p))
+}
+
+// UnmarshalCompressed sets e to the point encoded in m in the compressed format,
+// verifying that it is on the curve and in the prime order subgroup.
+func (e *G2) UnmarshalCompressed(m []byte) (*G2, error) {
+	if len(m) != 2*fpBytes {
+		return nil, errInvalidLength
+	}
+	flags, x, err := splitFlags(m)
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG2()
+	if flags&flagInfinity != 0 {
+		e.p = g.Zero()
+		return e, nil
+	}
+	if err := checkFp(x[:fpBytes]); err != nil {
+		return nil, err
+	}
+	if err := checkFp(x[fpBytes:]); err != nil {
+		return nil, err
+	}
+	p, err := g.FromCompressed(m)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	e.p = p
+	return e, nil
+}
+
+// splitFlags separates the flags of a compressed encoding from the coordinate
+// bytes, validating them. The returned bytes are a copy with the flags cleared.
+func splitFlags(m []byte) (byte, []byte, error) {
+	flags := m[0] & flagMask
+	if flags&flagCompressed == 0 {
+		return 0, nil, errNotCompressed
+	}
+	m = append([]byte{}, m...)
+	m[0] &^= flagMask
+
+	if flags&flagInfinity != 0 && (flags&flagLargest != 0 || !allZero(m)) {
+		return 0, nil, errInvalidInfinity
+	}
+	return flags, m, nil
+}
@@ -0,0 +1,291 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Tests the encodings of the generators and the identities against the values
+// given by the ZCash serialization format and EIP-2537.
+func TestEncodingVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		have []byte
+		want string
+	}{
+		{
+			name: "compressed G1 generator",
+			have: G1Generator().MarshalCompressed(),
+			want: "97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb",
+		},
+		{
+			name: "compressed G2 generator",
+			have: G2Generator().MarshalCompressed(),
+			want: "93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e" +
+				"024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8",
+		},
+		{
+			name: "compressed G1 identity",
+			have: new(G1).ScalarBaseMult(new(big.Int)).MarshalCompressed(),
+			want: "c0" + zeroHex(fpBytes-1),
+		},
+		{
+			name: "compressed G2 identity",
+			have: new(G2).ScalarBaseMult(new(big.Int)).MarshalCompressed(),
+			want: "c0" + zeroHex(2*fpBytes-1),
+		},
+		{
+			name: "G1 generator",
+			have: G1Generator().Marshal(),
+			want: zeroHex(16) + "17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb" +
+				zeroHex(16) + "08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1",
+		},
+		{
+			name: "G2 generator",
+			have: G2Generator().Marshal(),
+			want: zeroHex(16) + "024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8" +
+				zeroHex(16) + "13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e" +
+				zeroHex(16) + "0ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801" +
+				zeroHex(16) + "0606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+		},
+	}
+	for _, tt := range tests {
+		if have := hex.EncodeToString(tt.have); have != tt.want {
+			t.Errorf("%s: encoding mismatch:\nhave %s\nwant %s", tt.name, have, tt.want)
+		}
+	}
+	// The negated generators only differ in the flag selecting the larger y
+	if have := new(G1).Neg(G1Generator()).MarshalCompressed(); have[0] != 0xb7 || !bytes.Equal(have[1:], G1Generator().MarshalCompressed()[1:]) {
+		t.Errorf("compressed negated G1 generator mismatch: %x", have)
+	}
+	if have := new(G2).Neg(G2Generator()).MarshalCompressed(); have[0] != 0xb3 || !bytes.Equal(have[1:], G2Generator().MarshalCompressed()[1:]) {
+		t.Errorf("compressed negated G2 generator mismatch: %x", have)
+	}
+}
+
+func zeroHex(n int) string { return hex.EncodeToString(make([]byte, n)) }
+
+// Tests that random points, their negations and the identities survive both the
+// uncompressed and the compressed encodings.
+func TestMarshalRoundtrip(t *testing.T) {
+	g1s := []*G1{new(G1).ScalarBaseMult(new(big.Int))}
+	g2s := []*G2{new(G2).ScalarBaseMult(new(big.Int))}
+	for i := 0; i < 4; i++ {
+		p1 := new(G1).ScalarBaseMult(randScalar(t))
+		p2 := new(G2).ScalarBaseMult(randScalar(t))
+		g1s = append(g1s, p1, new(G1).Neg(p1))
+		g2s = append(g2s, p2, new(G2).Neg(p2))
+	}
+	for i, p := range g1s {
+		if q, err := new(G1).Unmarshal(p.Marshal()); err != nil || !q.Equal(p) {
+			t.Errorf("G1 point %d: roundtrip failed: %v", i, err)
+		}
+		if q, err := new(G1).UnmarshalCompressed(p.MarshalCompressed()); err != nil || !q.Equal(p) {
+			t.Errorf("G1 point %d: compressed roundtrip failed: %v", i, err)
+		}
+	}
+	for i, p := range g2s {
+		if q, err := new(G2).Unmarshal(p.Marshal()); err != nil || !q.Equal(p) {
+			t.Errorf("G2 point %d: roundtrip failed: %v", i, err)
+		}
+		if q, err := new(G2).UnmarshalCompressed(p.MarshalCompressed()); err != nil || !q.Equal(p) {
+			t.Errorf("G2 point %d: compressed roundtrip failed: %v", i, err)
+		}
+	}
+}
+
+// Tests that malformed compressed encodings are rejected.
+func TestUnmarshalCompressedInvalid(t *testing.T) {
+	gen := G1Generator().MarshalCompressed()
+
+	// Find an x coordinate without a point on E(Fp)
+	var offcurve []byte
+	for x := int64(1); offcurve == nil; x++ {
+		rhs := new(big.Int).Exp(big.NewInt(x), big.NewInt(3), P)
+		if new(big.Int).ModSqrt(rhs.Add(rhs, big.NewInt(4)), P) == nil {
+			offcurve = make([]byte, fpBytes)
+			putBig(offcurve, big.NewInt(x))
+			offcurve[0] |= flagCompressed
+		}
+	}
+	unreduced := make([]byte, fpBytes)
+	putBig(unreduced, P)
+	unreduced[0] |= flagCompressed
+
+	tests := []struct {
+		name string
+		blob []byte
+		err  error
+	}{
+		{"short", gen[1:], errInvalidLength},
+		{"uncompressed", append([]byte{gen[0] &^ flagCompressed}, gen[1:]...), errNotCompressed},
+		{"infinity with sign", append([]byte{flagCompressed | flagInfinity | flagLargest}, make([]byte, fpBytes-1)...), errInvalidInfinity},
+		{"infinity with data", append([]byte{flagCompressed | flagInfinity}, gen[1:]...), errInvalidInfinity},
+		{"unreduced", unreduced, errInvalidField},
+		{"off curve", offcurve, errNotOnCurve},
+	}
+	for _, tt := range tests {
+		if _, err := new(G1).UnmarshalCompressed(tt.blob); err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	blob := G2Generator().MarshalCompressed()
+	blob[0] &^= flagCompressed
+	if _, err := new(G2).UnmarshalCompressed(blob); err != errNotCompressed {
+		t.Errorf("uncompressed G2: error mismatch: have %v, want %v", err, errNotCompressed)
+	}
+}
+
+// Tests that points on the curves but outside of the prime order subgroups are
+// rejected by both encodings.
+func TestSubgroupChecks(t *testing.T) {
+	// Find a point on E(Fp), which is outside of G1 with overwhelming probability
+	for x := int64(1); ; x++ {
+		px := big.NewInt(x)
+		rhs := new(big.Int).Exp(px, big.NewInt(3), P)
+		py := new(big.Int).ModSqrt(rhs.Add(rhs, big.NewInt(4)), P)
+		if py == nil {
+			continue
+		}
+		blob := make([]byte, 2*paddedFpBytes)
+		putBig(blob[:paddedFpBytes], px)
+		putBig(blob[paddedFpBytes:], py)
+		if _, err := new(G1).Unmarshal(blob); err != errNotInSubgroup {
+			t.Errorf("G1 error mismatch: have %v, want %v", err, errNotInSubgroup)
+		}
+		blob = make([]byte, fpBytes)
+		putBig(blob, px)
+		blob[0] |= flagCompressed
+		if _, err := new(G1).UnmarshalCompressed(blob); err != errNotInSubgroup {
+			t.Errorf("compressed G1 error mismatch: have %v, want %v", err, errNotInSubgroup)
+		}
+		break
+	}
+	// Find a point on E'(Fp2), which is outside of G2 with overwhelming probability
+	for x := int64(1); ; x++ {
+		px := fp2{big.NewInt(x), big.NewInt(1)}
+		py, ok := px.mul(px).mul(px).add(fp2{big.NewInt(4), big.NewInt(4)}).sqrt()
+		if !ok {
+			continue
+		}
+		blob := make([]byte, 4*paddedFpBytes)
+		for i, c := range []*big.Int{px[0], px[1], py[0], py[1]} {
+			putBig(blob[i*paddedFpBytes:(i+1)*paddedFpBytes], c)
+		}
+		if _, err := new(G2).Unmarshal(blob); err != errNotInSubgroup {
+			t.Errorf("G2 error mismatch: have %v, want %v", err, errNotInSubgroup)
+		}
+		blob = make([]byte, 2*fpBytes)
+		putBig(blob[:fpBytes], px[1])
+		putBig(blob[fpBytes:], px[0])
+		blob[0] |= flagCompressed
+		if _, err := new(G2).UnmarshalCompressed(blob); err != errNotInSubgroup {
+			t.Errorf("compressed G2 error mismatch: have %v, want %v", err, errNotInSubgroup)
+		}
+		break
+	}
+}
+
+// Tests that decoding random and corrupted inputs never panics or accepts an
+// invalid point.
+func TestUnmarshalFuzz(t *testing.T) {
+	valid := G2Generator().Marshal()
+	for i := 0; i < 50; i++ {
+		blob := make([]byte, len(valid))
+		rand.Read(blob)
+		if _, err := new(G2).Unmarshal(blob); err == nil {
+			t.Errorf("random G2 encoding accepted: %x", blob)
+		}
+		copy(blob, valid)
+		blob[i%len(blob)] ^= 1 << uint(i%8)
+		if p, err := new(G2).Unmarshal(blob); err == nil && !bls12381.NewG2().InCorrectSubgroup(p.p) {
+			t.Errorf("corrupted G2 encoding accepted outside the subgroup: %x", blob)
+		}
+		g1blob := make([]byte, 2*paddedFpBytes)
+		rand.Read(g1blob)
+		if _, err := new(G1).Unmarshal(g1blob); err == nil {
+			t.Errorf("random G1 encoding accepted: %x", g1blob)
+		}
+	}
+	if _, err := new(G1).Unmarshal(valid[:2*paddedFpBytes-1]); err != errInvalidLength {
+		t.Errorf("short encoding error mismatch: have %v, want %v", err, errInvalidLength)
+	}
+	padded := G1Generator().Marshal()
+	padded[paddedFpBytes] = 1
+	if _, err := new(G1).Unmarshal(padded); err != errInvalidPad {
+		t.Errorf("padding error mismatch: have %v, want %v", err, errInvalidPad)
+	}
+	if !bytes.Equal(new(G2).Neg(new(G2).ScalarBaseMult(new(big.Int))).Marshal(), make([]byte, 4*paddedFpBytes)) {
+		t.Errorf("identity encoding mismatch")
+	}
+}
+
+// putBig writes the big endian encoding of a base field element into out, right
+// aligned.
+func putBig(out []byte, a *big.Int) {
+	b := a.Bytes()
+	copy(out[len(out)-len(b):], b)
+}
+
+// fp2 is an element c0 + c1·u of Fp2, implemented on big integers to build the
+// points outside of the subgroups that the library refuses to construct.
+type fp2 [2]*big.Int
+
+func (a fp2) add(b fp2) fp2 {
+	c0 := new(big.Int).Add(a[0], b[0])
+	c1 := new(big.Int).Add(a[1], b[1])
+	return fp2{c0.Mod(c0, P), c1.Mod(c1, P)}
+}
+
+func (a fp2) mul(b fp2) fp2 {
+	c0 := new(big.Int).Mul(a[0], b[0])
+	c0.Sub(c0, new(big.Int).Mul(a[1], b[1]))
+	c1 := new(big.Int).Mul(a[0], b[1])
+	c1.Add(c1, new(big.Int).Mul(a[1], b[0]))
+	return fp2{c0.Mod(c0, P), c1.Mod(c1, P)}
+}
+
+// sqrt computes a square root of a, if it has one, with the complex method: a
+// root x0 + x1·u has x0² = (a0 ± |a|)/2 and x1 = a1/(2·x0).
+func (a fp2) sqrt() (fp2, bool) {
+	norm := new(big.Int).Mul(a[0], a[0])
+	norm.Add(norm, new(big.Int).Mul(a[1], a[1]))
+	s := new(big.Int).ModSqrt(norm.Mod(norm, P), P)
+	if s == nil {
+		return fp2{}, false
+	}
+	half := new(big.Int).ModInverse(big.NewInt(2), P)
+	for _, root := range []*big.Int{s, new(big.Int).Neg(s)} {
+		d := new(big.Int).Add(a[0], root)
+		d.Mul(d, half).Mod(d, P)
+		x0 := new(big.Int).ModSqrt(d, P)
+		if x0 == nil || x0.Sign() == 0 {
+			continue
+		}
+		x1 := new(big.Int).Lsh(x0, 1)
+		x1.ModInverse(x1, P).Mul(x1, a[1]).Mod(x1, P)
+		return fp2{x0, x1}, true
+	}
+	return fp2{}, false
+}
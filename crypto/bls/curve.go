@@ -0,0 +1,281 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+var (
+	errInvalidLength = errors.New("bls: invalid encoding length")
+	errInvalidField  = errors.New("bls: coordinate not in field")
+	errInvalidPad    = errors.New("bls: non-zero coordinate padding")
+	errNotOnCurve    = errors.New("bls: point not on curve")
+	errNotInSubgroup = errors.New("bls: point not in prime order subgroup")
+	errPairingLength = errors.New("bls: mismatching number of G1 and G2 points")
+)
+
+var (
+	// P is the modulus of the base field.
+	P = fromHex("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab")
+
+	// Order is the prime order of the G1, G2 and GT groups.
+	Order = fromHex("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+
+	// fpModulus is the big endian encoding of P, to range check coordinates.
+	fpModulus = P.Bytes()
+)
+
+// fromHex parses a hexadecimal constant, panicking on failure.
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bls: invalid constant " + s)
+	}
+	return n
+}
+
+// fpBytes is the size of a big endian base field element and paddedFpBytes the
+// size of its EIP-2537 encoding, left padded with zeroes.
+const (
+	fpBytes       = 48
+	paddedFpBytes = 64
+)
+
+// G1 is an element of the group G1, a point of prime order on E(Fp). Points are
+// immutable once constructed and kept in affine form, so they may be shared
+// between goroutines. The group instances of the library carry scratch space
+// and are not safe for concurrent use, so every operation creates its own.
+type G1 struct {
+	p *bls12381.PointG1
+}
+
+// G1Generator returns the standard generator of G1.
+func G1Generator() *G1 { return &G1{bls12381.NewG1().One()} }
+
+// ScalarBaseMult sets e to k·G, where G is the generator of G1, and returns e.
+func (e *G1) ScalarBaseMult(k *big.Int) *G1 {
+	g := bls12381.NewG1()
+	e.p = g.Affine(g.MulScalarBig(g.New(), g.One(), new(big.Int).Mod(k, Order)))
+	return e
+}
+
+// ScalarMult sets e to k·a and returns e.
+func (e *G1) ScalarMult(a *G1, k *big.Int) *G1 {
+	g := bls12381.NewG1()
+	e.p = g.Affine(g.MulScalarBig(g.New(), a.p, new(big.Int).Mod(k, Order)))
+	return e
+}
+
+// Add sets e to a+b and returns e.
+func (e *G1) Add(a, b *G1) *G1 {
+	g := bls12381.NewG1()
+	e.p = g.Affine(g.Add(g.New(), a.p, b.p))
+	return e
+}
+
+// Neg sets e to -a and returns e.
+func (e *G1) Neg(a *G1) *G1 {
+	g := bls12381.NewG1()
+	e.p = g.Affine(g.Neg(g.New(), a.p))
+	return e
+}
+
+// IsInfinity reports whether e is the identity element.
+func (e *G1) IsInfinity() bool { return bls12381.NewG1().IsZero(e.p) }
+
+// Equal reports whether e and a are the same point.
+func (e *G1) Equal(a *G1) bool { return bls12381.NewG1().Equal(e.p, a.p) }
+
+// Marshal encodes e in the 128 byte format of EIP-2537, the concatenation of its
+// padded affine coordinates. The identity is encoded as all zeroes.
+func (e *G1) Marshal() []byte {
+	// The encoders normalise the point in place, hand them a copy
+	raw := bls12381.NewG1().ToBytes(new(bls12381.PointG1).Set(e.p))
+
+	out := make([]byte, 2*paddedFpBytes)
+	putFp(out[0:], raw[0:fpBytes])
+	putFp(out[paddedFpBytes:], raw[fpBytes:])
+	return out
+}
+
+// Unmarshal sets e to the point encoded in m in the EIP-2537 format, verifying
+// that it is on the curve and in the prime order subgroup.
+func (e *G1) Unmarshal(m []byte) (*G1, error) {
+	if len(m) != 2*paddedFpBytes {
+		return nil, errInvalidLength
+	}
+	raw, err := getFps(m, 2)
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG1()
+	p, err := g.FromBytes(raw)
+	if err != nil {
+		return nil, errNotOnCurve
+	}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errNotInSubgroup
+	}
+	e.p = p
+	return e, nil
+}
+
+// G2 is an element of the group G2, a point of prime order on the twist E'(Fp2).
+// Points are immutable once constructed and kept in affine form, so they may be
+// shared between goroutines.
+type G2 struct {
+	p *bls12381.PointG2
+}
+
+// G2Generator returns the standard generator of G2.
+func G2Generator() *G2 { return &G2{bls12381.NewG2().One()} }
+
+// ScalarBaseMult sets e to k·G, where G is the generator of G2, and returns e.
+func (e *G2) ScalarBaseMult(k *big.Int) *G2 {
+	g := bls12381.NewG2()
+	e.p = g.Affine(g.MulScalarBig(g.New(), g.One(), new(big.Int).Mod(k, Order)))
+	return e
+}
+
+// ScalarMult sets e to k·a and returns e.
+func (e *G2) ScalarMult(a *G2, k *big.Int) *G2 {
+	g := bls12381.NewG2()
+	e.p = g.Affine(g.MulScalarBig(g.New(), a.p, new(big.Int).Mod(k, Order)))
+	return e
+}
+
+// Add sets e to a+b and returns e.
+func (e *G2) Add(a, b *G2) *G2 {
+	g := bls12381.NewG2()
+	e.p = g.Affine(g.Add(g.New(), a.p, b.p))
+	return e
+}
+
+// Neg sets e to -a and returns e.
+func (e *G2) Neg(a *G2) *G2 {
+	g := bls12381.NewG2()
+	e.p = g.Affine(g.Neg(g.New(), a.p))
+	return e
+}
+
+// IsInfinity reports whether e is the identity element.
+func (e *G2) IsInfinity() bool { return bls12381.NewG2().IsZero(e.p) }
+
+// Equal reports whether e and a are the same point.
+func (e *G2) Equal(a *G2) bool { return bls12381.NewG2().Equal(e.p, a.p) }
+
+// Marshal encodes e in the 256 byte format of EIP-2537, the concatenation of its
+// padded affine coordinates, each as c0 || c1. The identity is encoded as all
+// zeroes.
+func (e *G2) Marshal() []byte {
+	raw := swapFp2(bls12381.NewG2().ToBytes(new(bls12381.PointG2).Set(e.p)))
+
+	out := make([]byte, 4*paddedFpBytes)
+	for i := 0; i < 4; i++ {
+		putFp(out[i*paddedFpBytes:], raw[i*fpBytes:(i+1)*fpBytes])
+	}
+	return out
+}
+
+// Unmarshal sets e to the point encoded in m in the EIP-2537 format, verifying
+// that it is on the curve and in the prime order subgroup.
+func (e *G2) Unmarshal(m []byte) (*G2, error) {
+	if len(m) != 4*paddedFpBytes {
+		return nil, errInvalidLength
+	}
+	raw, err := getFps(m, 4)
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG2()
+	p, err := g.FromBytes(swapFp2(raw))
+	if err != nil {
+		return nil, errNotOnCurve
+	}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errNotInSubgroup
+	}
+	e.p = p
+	return e, nil
+}
+
+// putFp writes the padded encoding of a big endian base field element into out.
+func putFp(out, fp []byte) {
+	copy(out[paddedFpBytes-fpBytes:paddedFpBytes], fp)
+}
+
+// getFps decodes n consecutive padded base field elements, rejecting non-zero
+// padding and unreduced values, and returns their concatenated big endian
+// encodings.
+func getFps(in []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, n*fpBytes)
+	for i := 0; i < n; i++ {
+		fp := in[i*paddedFpBytes : (i+1)*paddedFpBytes]
+		if !allZero(fp[:paddedFpBytes-fpBytes]) {
+			return nil, errInvalidPad
+		}
+		if err := checkFp(fp[paddedFpBytes-fpBytes:]); err != nil {
+			return nil, err
+		}
+		out = append(out, fp[paddedFpBytes-fpBytes:]...)
+	}
+	return out, nil
+}
+
+// swapFp2 swaps the coefficients of each big endian Fp2 element in b, converting
+// between the c0 || c1 order of EIP-2537 and the c1 || c0 order of the library.
+func swapFp2(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := 0; i < len(b); i += 2 * fpBytes {
+		copy(out[i:], b[i+fpBytes:i+2*fpBytes])
+		copy(out[i+fpBytes:], b[i:i+fpBytes])
+	}
+	return out
+}
+
+// checkFp rejects big endian base field elements that are not fully reduced.
+func checkFp(fp []byte) error {
+	if bytes.Compare(fp, fpModulus) >= 0 {
+		return errInvalidField
+	}
+	return nil
+}
+
+// decodeError maps a failure of the library to decode a point, once the length,
+// flags and coordinates have been validated, to the error of this package. The
+// library doesn't export its errors, so they are told apart by their text.
+func decodeError(err error) error {
+	if strings.Contains(err.Error(), "subgroup") {
+		return errNotInSubgroup
+	}
+	return errNotOnCurve
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
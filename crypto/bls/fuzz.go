@@ -0,0 +1,123 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package bls
+
+import (
+	"bytes"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test point decoding and point
+// aggregation on arbitrary input.
+func Fuzz(data []byte) int {
+	if len(data) == 0 {
+		return -1
+	}
+	if data[0]%2 == 0 {
+		return fuzzDecode(data[1:])
+	}
+	return fuzzAggregate(data[1:])
+}
+
+// fuzzDecode implements a go-fuzz fuzzer method to test point decoding on
+// arbitrary input in both the uncompressed and the compressed encodings.
+func fuzzDecode(data []byte) int {
+	switch len(data) {
+	case 2 * paddedFpBytes:
+		p, err := new(G1).Unmarshal(data)
+		if err != nil {
+			return 0
+		}
+		if !bytes.Equal(p.Marshal(), data) {
+			panic("G1 encoding mismatch")
+		}
+		return 1
+
+	case 4 * paddedFpBytes:
+		p, err := new(G2).Unmarshal(data)
+		if err != nil {
+			return 0
+		}
+		if !bytes.Equal(p.Marshal(), data) {
+			panic("G2 encoding mismatch")
+		}
+		return 1
+
+	case fpBytes:
+		p, err := new(G1).UnmarshalCompressed(data)
+		if err != nil {
+			return 0
+		}
+		if !bytes.Equal(p.MarshalCompressed(), data) {
+			panic("compressed G1 encoding mismatch")
+		}
+		return 1
+
+	case 2 * fpBytes:
+		p, err := new(G2).UnmarshalCompressed(data)
+		if err != nil {
+			return 0
+		}
+		if !bytes.Equal(p.MarshalCompressed(), data) {
+			panic("compressed G2 encoding mismatch")
+		}
+		return 1
+	}
+	return 0
+}
+
+// fuzzAggregate implements a go-fuzz fuzzer method to test the aggregation of
+// compressed G1 points, checking the sum against the pairings of its parts.
+func fuzzAggregate(data []byte) int {
+	if len(data) == 0 || len(data)%fpBytes != 0 || len(data) > 4*fpBytes {
+		return -1
+	}
+	var (
+		encs   [][]byte
+		points []*G1
+	)
+	for i := 0; i < len(data); i += fpBytes {
+		enc := data[i : i+fpBytes]
+		p, err := new(G1).UnmarshalCompressed(enc)
+		if err != nil {
+			if _, err := AggregateCompressedG1([][]byte{enc}); err == nil {
+				panic("invalid point aggregated")
+			}
+			return 0
+		}
+		encs, points = append(encs, enc), append(points, p)
+	}
+	sum, err := AggregateCompressedG1(encs)
+	if err != nil {
+		panic(err)
+	}
+	if !bls12381.NewG1().InCorrectSubgroup(sum.p) {
+		panic("aggregate outside of the subgroup")
+	}
+	// e(Σpᵢ, Q)·Πe(-pᵢ, Q) must be the identity
+	g1s, g2s := []*G1{sum}, []*G2{G2Generator()}
+	for _, p := range points {
+		g1s, g2s = append(g1s, new(G1).Neg(p)), append(g2s, G2Generator())
+	}
+	if !PairingCheck(g1s, g2s) {
+		panic("aggregate pairing mismatch")
+	}
+	return 1
+}
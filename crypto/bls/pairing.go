@@ -0,0 +1,63 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import bls12381 "github.com/kilic/bls12-381"
+
+// GT is an element of the target group, the order r subgroup of Fp12*.
+type GT struct {
+	v *bls12381.E
+}
+
+// Equal reports whether e and a are the same element.
+func (e *GT) Equal(a *GT) bool { return e.v.Equal(a.v) }
+
+// IsOne reports whether e is the identity element.
+func (e *GT) IsOne() bool { return e.v.IsOne() }
+
+// Pair calculates the optimal ate pairing of a G1 and a G2 point.
+func Pair(g1 *G1, g2 *G2) *GT {
+	engine := bls12381.NewEngine()
+	engine.AddPair(new(bls12381.PointG1).Set(g1.p), new(bls12381.PointG2).Set(g2.p))
+	return &GT{engine.Result()}
+}
+
+// MultiPair calculates the product of the pairings of each pair of points. It
+// shares a single final exponentiation across all pairs, so it is considerably
+// faster than pairing them one by one.
+func MultiPair(a []*G1, b []*G2) (*GT, error) {
+	if len(a) != len(b) {
+		return nil, errPairingLength
+	}
+	// The engine normalises the points in place, hand it copies
+	engine := bls12381.NewEngine()
+	for i := range a {
+		engine.AddPair(new(bls12381.PointG1).Set(a[i].p), new(bls12381.PointG2).Set(b[i].p))
+	}
+	return &GT{engine.Result()}, nil
+}
+
+// PairingCheck calculates the product of the pairings of each pair of points and
+// reports whether it is the identity, the check performed by the pairing
+// precompile. Mismatching numbers of points fail the check.
+func PairingCheck(a []*G1, b []*G2) bool {
+	e, err := MultiPair(a, b)
+	if err != nil {
+		return false
+	}
+	return e.IsOne()
+}
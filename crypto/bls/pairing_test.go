@@ -0,0 +1,96 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// Tests that the derived curve constants match the well known values.
+func TestConstants(t *testing.T) {
+	wantP := fromHex("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab")
+	if P.Cmp(wantP) != 0 {
+		t.Errorf("field modulus mismatch: have %x, want %x", P, wantP)
+	}
+	wantR := fromHex("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+	if Order.Cmp(wantR) != 0 {
+		t.Errorf("group order mismatch: have %x, want %x", Order, wantR)
+	}
+	if !P.ProbablyPrime(20) || !Order.ProbablyPrime(20) {
+		t.Errorf("field modulus or group order not prime")
+	}
+}
+
+// Tests that the generators are valid points of the prime order subgroups.
+func TestGenerators(t *testing.T) {
+	if g := bls12381.NewG1(); !g.IsOnCurve(G1Generator().p) || !g.InCorrectSubgroup(G1Generator().p) {
+		t.Errorf("G1 generator not in G1")
+	}
+	if g := bls12381.NewG2(); !g.IsOnCurve(G2Generator().p) || !g.InCorrectSubgroup(G2Generator().p) {
+		t.Errorf("G2 generator not in G2")
+	}
+}
+
+// Tests that the pairing is bilinear and non-degenerate.
+func TestPairingBilinearity(t *testing.T) {
+	a, b := randScalar(t), randScalar(t)
+
+	e := Pair(G1Generator(), G2Generator())
+	if e.IsOne() {
+		t.Fatalf("pairing of generators is degenerate")
+	}
+	ab := new(big.Int).Mul(a, b)
+	want := gtExp(e, ab.Mod(ab, Order))
+
+	have := Pair(new(G1).ScalarBaseMult(a), new(G2).ScalarBaseMult(b))
+	if !have.Equal(want) {
+		t.Errorf("e(aP, bQ) != e(P, Q)^ab")
+	}
+	// The order of the pairing result must be r
+	if !gtExp(e, Order).IsOne() {
+		t.Errorf("pairing result not in the order r subgroup")
+	}
+	// e(aP, Q)·e(-P, aQ) = 1
+	g1s := []*G1{new(G1).ScalarBaseMult(a), new(G1).Neg(G1Generator())}
+	g2s := []*G2{G2Generator(), new(G2).ScalarBaseMult(a)}
+	if !PairingCheck(g1s, g2s) {
+		t.Errorf("pairing check failed on valid input")
+	}
+	g2s[1] = new(G2).ScalarBaseMult(b)
+	if PairingCheck(g1s, g2s) {
+		t.Errorf("pairing check passed on invalid input")
+	}
+}
+
+func randScalar(t *testing.T) *big.Int {
+	k, err := rand.Int(rand.Reader, Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// gtExp raises an element of the target group to the power of k.
+func gtExp(e *GT, k *big.Int) *GT {
+	r := new(bls12381.E)
+	bls12381.NewGT().Exp(r, e.v, k)
+	return &GT{r}
+}
@@ -46,6 +46,29 @@ static int secp256k1_ecdsa_recover_pubkey(
 	return secp256k1_ec_pubkey_serialize(ctx, pubkey_out, &outputlen, &pubkey, SECP256K1_EC_UNCOMPRESSED);
 }
 
+// secp256k1_ecdsa_recover_pubkeys recovers the public keys of a batch of encoded
+// compact signatures, amortizing the cost of crossing into C over the batch.
+//
+// Args:    ctx:         pointer to a context object (cannot be NULL)
+//  Out:    pubkeys_out: the serialized 65-byte public keys of the signers (cannot be NULL)
+//          results:     the recovery result of each signature, 1 on success (cannot be NULL)
+//  In:     sigdata:     pointer to the 65-byte signatures with the recovery ids at the end (cannot be NULL)
+//          msgdata:     pointer to the 32-byte messages (cannot be NULL)
+//          count:       number of signatures in the batch
+static void secp256k1_ecdsa_recover_pubkeys(
+	const secp256k1_context* ctx,
+	unsigned char *pubkeys_out,
+	int *results,
+	const unsigned char *sigdata,
+	const unsigned char *msgdata,
+	size_t count
+) {
+	size_t i;
+	for (i = 0; i < count; i++) {
+		results[i] = secp256k1_ecdsa_recover_pubkey(ctx, pubkeys_out + 65*i, sigdata + 65*i, msgdata + 32*i);
+	}
+}
+
 // secp256k1_pubkey_scalar_mul multiplies a point by a scalar in constant time.
 //
 // Returns: 1: multiplication was successful
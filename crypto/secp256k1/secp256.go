@@ -38,11 +38,20 @@ import "C"
 
 import (
 	"errors"
+	"runtime"
+	"sync"
 	"unsafe"
 )
 
+// context is the libsecp256k1 context shared by all operations. Signing and
+// recovery only read the context, so it is safe for concurrent use and reused
+// across goroutines instead of creating a new one per call.
 var context *C.secp256k1_context
 
+// minRecoverBatch is the minimum number of signatures worth handing to a
+// separate goroutine during batch recovery.
+const minRecoverBatch = 16
+
 func init() {
 	// around 20 ms on a modern CPU.
 	context = C.secp256k1_context_create_sign_verify()
@@ -119,6 +128,78 @@ func RecoverPubkey(msg []byte, sig []byte) ([]byte, error) {
 	return pubkey, nil
 }
 
+// RecoverPubkeys recovers the public keys of a batch of signatures, spreading the
+// work over all available CPUs. The inputs follow the same rules as RecoverPubkey,
+// and the results are returned in the same order, with a nil public key and a
+// non-nil error for every signature that failed to recover.
+func RecoverPubkeys(msgs [][]byte, sigs [][]byte) ([][]byte, []error) {
+	if len(msgs) != len(sigs) {
+		panic("secp256k1: message and signature count mismatch")
+	}
+	var (
+		pubkeys = make([][]byte, len(sigs))
+		errs    = make([]error, len(sigs))
+		valid   = make([]int, 0, len(sigs))
+	)
+	// Sort out the malformed inputs and pack the rest into contiguous buffers
+	for i := range sigs {
+		if len(msgs[i]) != 32 {
+			errs[i] = ErrInvalidMsgLen
+		} else if err := checkSignature(sigs[i]); err != nil {
+			errs[i] = err
+		} else {
+			valid = append(valid, i)
+		}
+	}
+	if len(valid) == 0 {
+		return pubkeys, errs
+	}
+	var (
+		msgdata = make([]byte, 32*len(valid))
+		sigdata = make([]byte, 65*len(valid))
+		pubdata = make([]byte, 65*len(valid))
+		results = make([]C.int, len(valid))
+	)
+	for j, i := range valid {
+		copy(msgdata[32*j:], msgs[i])
+		copy(sigdata[65*j:], sigs[i])
+	}
+	// Recover the signatures in chunks, one per available CPU
+	workers := runtime.NumCPU()
+	if max := (len(valid) + minRecoverBatch - 1) / minRecoverBatch; workers > max {
+		workers = max
+	}
+	chunk := (len(valid) + workers - 1) / workers
+
+	var pend sync.WaitGroup
+	for start := 0; start < len(valid); start += chunk {
+		end := start + chunk
+		if end > len(valid) {
+			end = len(valid)
+		}
+		pend.Add(1)
+		go func(start, end int) {
+			defer pend.Done()
+			C.secp256k1_ecdsa_recover_pubkeys(context,
+				(*C.uchar)(unsafe.Pointer(&pubdata[65*start])),
+				(*C.int)(unsafe.Pointer(&results[start])),
+				(*C.uchar)(unsafe.Pointer(&sigdata[65*start])),
+				(*C.uchar)(unsafe.Pointer(&msgdata[32*start])),
+				C.size_t(end-start))
+		}(start, end)
+	}
+	pend.Wait()
+
+	for j, i := range valid {
+		if results[j] == 0 {
+			errs[i] = ErrRecoverFailed
+			continue
+		}
+		pubkeys[i] = pubdata[65*j : 65*(j+1) : 65*(j+1)]
+	}
+	return pubkeys, errs
+}
+
 func checkSignature(sig []byte) error {
 	if len(sig) != 65 {
 		return ErrInvalidSignatureLen
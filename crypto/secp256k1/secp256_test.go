@@ -217,6 +217,38 @@ func TestRecoverSanity(t *testing.T) {
 	}
 }
 
+func TestRecoverPubkeys(t *testing.T) {
+	var (
+		msgs = make([][]byte, 100)
+		sigs = make([][]byte, 100)
+		keys = make([][]byte, 100)
+	)
+	for i := range msgs {
+		var seckey []byte
+		keys[i], seckey = generateKeyPair()
+		msgs[i] = randentropy.GetEntropyCSPRNG(32)
+		sigs[i], _ = Sign(msgs[i], seckey)
+	}
+	// Corrupt a few inputs to check error reporting
+	msgs[10] = msgs[10][:31]
+	sigs[20] = sigs[20][:64]
+	sigs[30][64] = 4
+
+	pubkeys, errs := RecoverPubkeys(msgs, sigs)
+	for i := range msgs {
+		want, wantErr := RecoverPubkey(msgs[i], sigs[i])
+		if errs[i] != wantErr {
+			t.Errorf("signature %d: error mismatch: have %v, want %v", i, errs[i], wantErr)
+		}
+		if !bytes.Equal(pubkeys[i], want) {
+			t.Errorf("signature %d: pubkey mismatch: have %x, want %x", i, pubkeys[i], want)
+		}
+		if wantErr == nil && !bytes.Equal(pubkeys[i], keys[i]) {
+			t.Errorf("signature %d: recovered wrong pubkey: have %x, want %x", i, pubkeys[i], keys[i])
+		}
+	}
+}
+
 func BenchmarkSign(b *testing.B) {
 	_, seckey := generateKeyPair()
 	msg := randentropy.GetEntropyCSPRNG(32)
@@ -237,3 +269,31 @@ func BenchmarkRecover(b *testing.B) {
 		RecoverPubkey(msg, sig)
 	}
 }
+
+func BenchmarkRecoverSequential(b *testing.B) { benchmarkRecover(b, false) }
+func BenchmarkRecoverBatch(b *testing.B)      { benchmarkRecover(b, true) }
+
+// benchmarkRecover measures the recovery of 256 signatures, either one by one
+// or as a single batch.
+func benchmarkRecover(b *testing.B, batch bool) {
+	var (
+		msgs = make([][]byte, 256)
+		sigs = make([][]byte, 256)
+	)
+	for i := range msgs {
+		_, seckey := generateKeyPair()
+		msgs[i] = randentropy.GetEntropyCSPRNG(32)
+		sigs[i], _ = Sign(msgs[i], seckey)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if batch {
+			RecoverPubkeys(msgs, sigs)
+		} else {
+			for j := range msgs {
+				RecoverPubkey(msgs[j], sigs[j])
+			}
+		}
+	}
+}
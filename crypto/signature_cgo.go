@@ -31,6 +31,13 @@ func Ecrecover(hash, sig []byte) ([]byte, error) {
 	return secp256k1.RecoverPubkey(hash, sig)
 }
 
+// EcrecoverBatch returns the uncompressed public keys that created the given
+// signatures, recovering them concurrently. Failed recoveries are reported by a
+// nil public key and a non-nil error at the same index.
+func EcrecoverBatch(hashes, sigs [][]byte) ([][]byte, []error) {
+	return secp256k1.RecoverPubkeys(hashes, sigs)
+}
+
 func SigToPub(hash, sig []byte) (*ecdsa.PublicKey, error) {
 	s, err := Ecrecover(hash, sig)
 	if err != nil {
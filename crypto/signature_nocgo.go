@@ -35,6 +35,20 @@ func Ecrecover(hash, sig []byte) ([]byte, error) {
 	return bytes, err
 }
 
+// EcrecoverBatch returns the uncompressed public keys that created the given
+// signatures. Failed recoveries are reported by a nil public key and a non-nil
+// error at the same index.
+func EcrecoverBatch(hashes, sigs [][]byte) ([][]byte, []error) {
+	var (
+		pubs = make([][]byte, len(sigs))
+		errs = make([]error, len(sigs))
+	)
+	for i := range sigs {
+		pubs[i], errs[i] = Ecrecover(hashes[i], sigs[i])
+	}
+	return pubs, errs
+}
+
 func SigToPub(hash, sig []byte) (*ecdsa.PublicKey, error) {
 	// Convert to btcec input format with 'recovery id' v at the beginning.
 	btcsig := make([]byte, 65)
@@ -20,7 +20,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,14 +40,16 @@ type Environment struct {
 	Name                string // name of the environment
 	Repo                string // name of GitHub repo
 	Commit, Branch, Tag string // Git info
+	Date                string // Commit date (YYYYMMDD), used as the build date
+	Dirty               bool   // Whether the tree has uncommitted changes
 	Buildnum            string
 	IsPullRequest       bool
 	IsCronJob           bool
 }
 
 func (env Environment) String() string {
-	return fmt.Sprintf("%s env (commit:%s branch:%s tag:%s buildnum:%s pr:%t)",
-		env.Name, env.Commit, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
+	return fmt.Sprintf("%s env (commit:%s date:%s dirty:%t branch:%s tag:%s buildnum:%s pr:%t)",
+		env.Name, env.Commit, env.Date, env.Dirty, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
 }
 
 // Env returns metadata about the current CI environment, falling back to LocalEnv
@@ -53,7 +57,7 @@ func (env Environment) String() string {
 func Env() Environment {
 	switch {
 	case os.Getenv("CI") == "true" && os.Getenv("TRAVIS") == "true":
-		return Environment{
+		return applyGitState(Environment{
 			Name:          "travis",
 			Repo:          os.Getenv("TRAVIS_REPO_SLUG"),
 			Commit:        os.Getenv("TRAVIS_COMMIT"),
@@ -62,9 +66,9 @@ func Env() Environment {
 			Buildnum:      os.Getenv("TRAVIS_BUILD_NUMBER"),
 			IsPullRequest: os.Getenv("TRAVIS_PULL_REQUEST") != "false",
 			IsCronJob:     os.Getenv("TRAVIS_EVENT_TYPE") == "cron",
-		}
+		})
 	case os.Getenv("CI") == "True" && os.Getenv("APPVEYOR") == "True":
-		return Environment{
+		return applyGitState(Environment{
 			Name:          "appveyor",
 			Repo:          os.Getenv("APPVEYOR_REPO_NAME"),
 			Commit:        os.Getenv("APPVEYOR_REPO_COMMIT"),
@@ -73,7 +77,7 @@ func Env() Environment {
 			Buildnum:      os.Getenv("APPVEYOR_BUILD_NUMBER"),
 			IsPullRequest: os.Getenv("APPVEYOR_PULL_REQUEST_NUMBER") != "",
 			IsCronJob:     os.Getenv("APPVEYOR_SCHEDULED_BUILD") == "True",
-		}
+		})
 	default:
 		return LocalEnv()
 	}
@@ -96,6 +100,22 @@ func LocalEnv() Environment {
 	if env.Tag == "" {
 		env.Tag = firstLine(RunGit("tag", "-l", "--points-at", "HEAD"))
 	}
+	return applyGitState(env)
+}
+
+// applyGitState fills in the commit date and the dirty flag from the local
+// checkout. The commit date is used as the build date instead of the current
+// time so that builds of the same commit are reproducible.
+func applyGitState(env Environment) Environment {
+	if _, err := os.Stat(".git"); err != nil || env.Commit == "" {
+		return env
+	}
+	if env.Date == "" {
+		if stamp, err := strconv.ParseInt(RunGit("show", "-s", "--format=%ct", env.Commit), 10, 64); err == nil {
+			env.Date = time.Unix(stamp, 0).UTC().Format("20060102")
+		}
+	}
+	env.Dirty = RunGit("status", "--porcelain", "--untracked-files=no") != ""
 	return env
 }
 
@@ -187,6 +187,11 @@ func (tt *TestCmd) Interrupt() {
 	tt.cmd.Process.Signal(os.Interrupt)
 }
 
+// Signal sends the given signal to the child process.
+func (tt *TestCmd) Signal(sig os.Signal) {
+	tt.cmd.Process.Signal(sig)
+}
+
 // StderrText returns any stderr output written so far.
 // The returned text holds all log lines after ExpectExit has
 // returned.
@@ -22,6 +22,7 @@ package debug
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/user"
@@ -34,6 +35,7 @@ import (
 	"time"
 
 	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rpc"
 )
 
 // Handler is the global debugging handler.
@@ -82,6 +84,28 @@ func (*HandlerT) GcStats() *debug.GCStats {
 	return s
 }
 
+// SetGCPercent sets the garbage collection target percentage. It returns the
+// previous setting. A negative value disables GC.
+func (*HandlerT) SetGCPercent(v int) int {
+	return debug.SetGCPercent(v)
+}
+
+// FreeOSMemory forces a garbage collection and returns as much memory to the
+// operating system as possible.
+func (*HandlerT) FreeOSMemory() {
+	debug.FreeOSMemory()
+}
+
+// SetMaxThreads sets the maximum number of operating system threads the process
+// may use, returning the previous setting. As exceeding the limit crashes the
+// process, limits below the number of threads already created are rejected.
+func (*HandlerT) SetMaxThreads(threads int) (int, error) {
+	if created := pprof.Lookup("threadcreate").Count(); threads < created {
+		return 0, fmt.Errorf("thread limit %d below threads already created (%d)", threads, created)
+	}
+	return debug.SetMaxThreads(threads), nil
+}
+
 // CpuProfile turns on CPU profiling for nsec seconds and writes
 // profile data to file.
 func (h *HandlerT) CpuProfile(file string, nsec uint) error {
@@ -140,7 +164,7 @@ func (h *HandlerT) GoTrace(file string, nsec uint) error {
 	return nil
 }
 
-// BlockProfile turns on CPU profiling for nsec seconds and writes
+// BlockProfile turns on goroutine block profiling for nsec seconds and writes
 // profile data to file. It uses a profile rate of 1 for most accurate
 // information. If a different rate is desired, set the rate
 // and write the profile manually.
@@ -162,6 +186,40 @@ func (*HandlerT) WriteBlockProfile(file string) error {
 	return writeProfile("block", file)
 }
 
+// MutexProfile turns on mutex contention profiling for nsec seconds and writes
+// profile data to file. It uses a profile fraction of 1 for most accurate
+// information. If a different fraction is desired, set it and write the
+// profile manually.
+func (*HandlerT) MutexProfile(file string, nsec uint) error {
+	runtime.SetMutexProfileFraction(1)
+	time.Sleep(time.Duration(nsec) * time.Second)
+	defer runtime.SetMutexProfileFraction(0)
+	return writeProfile("mutex", file)
+}
+
+// SetMutexProfileFraction sets the rate of mutex contention profile data
+// collection. rate 0 disables mutex profiling.
+func (*HandlerT) SetMutexProfileFraction(rate int) {
+	runtime.SetMutexProfileFraction(rate)
+}
+
+// SetRpcTraces sets the number of recently served RPC requests whose traces are
+// kept. Zero disables tracing history.
+func (*HandlerT) SetRpcTraces(limit int) {
+	rpc.SetTraceHistory(limit)
+}
+
+// RpcTraces returns the traces of the most recently served RPC requests, the
+// most recent first.
+func (*HandlerT) RpcTraces() []*rpc.Trace {
+	return rpc.RecentTraces()
+}
+
+// WriteMutexProfile writes a mutex contention profile to the given file.
+func (*HandlerT) WriteMutexProfile(file string) error {
+	return writeProfile("mutex", file)
+}
+
 // WriteMemProfile writes an allocation profile to the given file.
 // Note that the profiling rate cannot be set through the API,
 // it must be set on the command line.
@@ -26,6 +26,7 @@ import (
 
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/log/term"
+	"github.com/networkchain/networkchain/rpc"
 	colorable "github.com/mattn/go-colorable"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -73,6 +74,10 @@ var (
 		Name:  "blockprofilerate",
 		Usage: "Turn on block profiling with the given rate",
 	}
+	mutexprofilefractionFlag = cli.IntFlag{
+		Name:  "mutexprofilefraction",
+		Usage: "Turn on mutex contention profiling with the given fraction",
+	}
 	cpuprofileFlag = cli.StringFlag{
 		Name:  "cpuprofile",
 		Usage: "Write CPU profile to the given file",
@@ -81,13 +86,18 @@ var (
 		Name:  "trace",
 		Usage: "Write execution trace to the given file",
 	}
+	rpcTracesFlag = cli.IntFlag{
+		Name:  "rpctraces",
+		Usage: "Number of recently served RPC requests to keep traces of for debug_rpcTraces",
+	}
 )
 
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
-	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
+	memprofilerateFlag, blockprofilerateFlag, mutexprofilefractionFlag, cpuprofileFlag, traceFlag,
+	rpcTracesFlag,
 }
 
 var glogger *log.GlogHandler
@@ -110,10 +120,12 @@ func Setup(ctx *cli.Context) error {
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
 	log.Root().SetHandler(glogger)
+	rpc.SetTraceHistory(ctx.GlobalInt(rpcTracesFlag.Name))
 
 	// profiling, tracing
 	runtime.MemProfileRate = ctx.GlobalInt(memprofilerateFlag.Name)
 	Handler.SetBlockProfileRate(ctx.GlobalInt(blockprofilerateFlag.Name))
+	Handler.SetMutexProfileFraction(ctx.GlobalInt(mutexprofilefractionFlag.Name))
 	if traceFile := ctx.GlobalString(traceFlag.Name); traceFile != "" {
 		if err := Handler.StartGoTrace(traceFile); err != nil {
 			return err
@@ -138,6 +150,25 @@ func Setup(ctx *cli.Context) error {
 	return nil
 }
 
+// SetLogLevels adjusts the log verbosity and per-module levels to the given ones,
+// unless they were explicitly set on the command line. Empty values leave the
+// current setting unchanged.
+func SetLogLevels(ctx *cli.Context, level string, vmodule string) error {
+	if level != "" && !ctx.GlobalIsSet(verbosityFlag.Name) {
+		lvl, err := log.LvlFromString(level)
+		if err != nil {
+			return err
+		}
+		glogger.Verbosity(lvl)
+	}
+	if vmodule != "" && !ctx.GlobalIsSet(vmoduleFlag.Name) {
+		if err := glogger.Vmodule(vmodule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Exit stops all running profiles, flushing their output to the
 // respective file.
 func Exit() {
@@ -32,6 +32,7 @@ import (
 	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/consensus/ethash"
 	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/state"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/core/vm"
 	"github.com/networkchain/networkchain/crypto"
@@ -118,7 +119,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -126,7 +127,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -249,6 +250,22 @@ func (s *PrivateAccountAPI) ListWallets() []rawWallet {
 	return wallets
 }
 
+// OpenWallet initiates a hardware wallet opening procedure, establishing a USB
+// connection and attempting to authenticate via the provided passphrase. Note,
+// the method may return an extra challenge requiring a second open (e.g. the
+// Trezor PIN matrix challenge).
+func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return err
+	}
+	pass := ""
+	if passphrase != nil {
+		pass = *passphrase
+	}
+	return wallet.Open(pass)
+}
+
 // DeriveAccount requests a HD wallet to derive a new account, optionally pinning
 // it for later reuse.
 func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (accounts.Account, error) {
@@ -343,11 +360,16 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
+	cancel, err := s.b.SigningPolicy().AuthorizeTx(args.From, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
 	signed, err := wallet.SignTxWithPassphrase(account, passwd, tx, chainID)
 	if err != nil {
+		cancel()
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(ctx, s.b, signed, args.Private)
 }
 
 // signHash is a helper function that calculates a hash for the given message that can be
@@ -379,6 +401,9 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	if err != nil {
 		return nil, err
 	}
+	if err := s.b.SigningPolicy().AuthorizeMessage(addr); err != nil {
+		return nil, err
+	}
 	// Assemble sign the data with the wallet
 	signature, err := wallet.SignHashWithPassphrase(account, passwd, signHash(data))
 	if err != nil {
@@ -456,7 +481,7 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
 	block, err := s.b.BlockByNumber(ctx, blockNr)
 	if block != nil {
-		response, err := s.rpcOutputBlock(block, true, fullTx)
+		response, err := s.rpcOutputBlock(ctx, block, true, fullTx)
 		if err == nil && blockNr == rpc.PendingBlockNumber {
 			// Pending blocks need to nil out a few fields
 			for _, field := range []string{"hash", "nonce", "miner"} {
@@ -473,7 +498,7 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.
 func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
 	block, err := s.b.GetBlock(ctx, blockHash)
 	if block != nil {
-		return s.rpcOutputBlock(block, true, fullTx)
+		return s.rpcOutputBlock(ctx, block, true, fullTx)
 	}
 	return nil, err
 }
@@ -485,11 +510,11 @@ func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context,
 	if block != nil {
 		uncles := block.Uncles()
 		if index >= hexutil.Uint(len(uncles)) {
-			log.Debug("Requested uncle not found", "number", blockNr, "hash", block.Hash(), "index", index)
+			rpc.TraceFromContext(ctx).Log("Requested uncle not found", "number", blockNr, "hash", block.Hash(), "index", index)
 			return nil, nil
 		}
 		block = types.NewBlockWithHeader(uncles[index])
-		return s.rpcOutputBlock(block, false, false)
+		return s.rpcOutputBlock(ctx, block, false, false)
 	}
 	return nil, err
 }
@@ -501,11 +526,11 @@ func (s *PublicBlockChainAPI) GetUncleByBlockHashAndIndex(ctx context.Context, b
 	if block != nil {
 		uncles := block.Uncles()
 		if index >= hexutil.Uint(len(uncles)) {
-			log.Debug("Requested uncle not found", "number", block.Number(), "hash", blockHash, "index", index)
+			rpc.TraceFromContext(ctx).Log("Requested uncle not found", "number", block.Number(), "hash", blockHash, "index", index)
 			return nil, nil
 		}
 		block = types.NewBlockWithHeader(uncles[index])
-		return s.rpcOutputBlock(block, false, false)
+		return s.rpcOutputBlock(ctx, block, false, false)
 	}
 	return nil, err
 }
@@ -538,6 +563,17 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 	return code, state.Error()
 }
 
+// GetCodeByBlockHash returns the code stored at the given address in the state
+// of the given block, which doesn't need to be part of the canonical chain.
+func (s *PublicBlockChainAPI) GetCodeByBlockHash(ctx context.Context, address common.Address, blockHash common.Hash) (hexutil.Bytes, error) {
+	state, _, err := s.b.StateAndHeaderByHash(ctx, blockHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	code := state.GetCode(address)
+	return code, state.Error()
+}
+
 // GetStorageAt returns the storage from the state at the given address, key and
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
@@ -580,13 +616,82 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// OverrideAccount specifies the fields of an account to be replaced while
+// executing a call. Unset fields retain their value from the original state.
+// State replaces the entire storage of the account, whereas StateDiff only
+// replaces the given slots; the two are mutually exclusive.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce"`
+	Code      *hexutil.Bytes              `json:"code"`
+	Balance   *hexutil.Big                `json:"balance"`
+	State     map[common.Hash]common.Hash `json:"state"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts in the given state.
+func (diff StateOverride) Apply(state *state.StateDB) error {
+	for addr, account := range diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, account.Balance.ToInt())
+		}
+		if account.State != nil {
+			state.SetStorage(addr, account.State)
+		}
+		for key, value := range account.StateDiff {
+			state.SetState(addr, key, value)
+		}
+	}
+	return nil
+}
+
+// ToMessage converts the call arguments into a message sent from the given
+// address, using default values for the gas allowance and price if unset.
+func (args *CallArgs) ToMessage(from common.Address) types.Message {
+	gas, gasPrice := args.Gas.ToInt(), args.GasPrice.ToInt()
+	if gas.Sign() == 0 {
+		gas = big.NewInt(50000000)
+	}
+	if gasPrice.Sign() == 0 {
+		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+	}
+	return types.NewMessage(from, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+}
+
+// doCall executes a call message on top of the state of the given block. The error
+// the EVM aborted the execution with is reported in the result, separately from
+// any error preventing the execution itself.
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config) (*core.ExecutionResult, error) {
+	defer func(start time.Time) {
+		rpc.TraceFromContext(ctx).Log("Executing EVM call finished", "runtime", time.Since(start))
+	}(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, common.Big0, err
+		return nil, err
 	}
+	if overrides != nil {
+		if err := overrides.Apply(state); err != nil {
+			return nil, err
+		}
+	}
+	return s.applyCall(ctx, args, state, header, vmCfg)
+}
+
+// applyCall executes a call message on top of the given state, leaving all
+// modifications made by the call in place.
+func (s *PublicBlockChainAPI) applyCall(ctx context.Context, args CallArgs, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*core.ExecutionResult, error) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -596,17 +701,8 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 			}
 		}
 	}
-	// Set default gas & gas price if none were set
-	gas, gasPrice := args.Gas.ToInt(), args.GasPrice.ToInt()
-	if gas.Sign() == 0 {
-		gas = big.NewInt(50000000)
-	}
-	if gasPrice.Sign() == 0 {
-		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
-	}
-
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := args.ToMessage(addr)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -623,7 +719,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, common.Big0, err
+		return nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -637,26 +733,82 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxBig256)
-	res, gas, err := core.ApplyMessage(evm, msg, gp)
+	result, err := core.ExecuteMessage(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, common.Big0, err
+		return nil, err
 	}
-	return res, gas, err
+	return result, err
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, err := s.doCall(ctx, args, blockNr, vm.Config{DisableGasMetering: true})
-	return (hexutil.Bytes)(result), err
+// The optional overrides replace the balance, nonce, code or storage of accounts during the call.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, err := s.doCall(ctx, args, blockNr, overrides, vm.Config{DisableGasMetering: true})
+	if err != nil {
+		return nil, err
+	}
+	return (hexutil.Bytes)(result.ReturnData), nil
+}
+
+// BundleCallResult is the outcome of a single call executed within a bundle.
+type BundleCallResult struct {
+	ReturnValue hexutil.Bytes `json:"returnValue"`
+	GasUsed     *hexutil.Big  `json:"gasUsed"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// CallBundle executes a sequence of calls on the state of the given block, each
+// call seeing the state modifications of the previous ones. The optional state
+// overrides are applied before the first call. Calls failing to execute are
+// reported in their results and don't abort the remainder of the bundle.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) ([]BundleCallResult, error) {
+	defer func(start time.Time) {
+		rpc.TraceFromContext(ctx).Log("Executing EVM call bundle finished", "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(calls) == 0 {
+		return nil, errors.New("empty call bundle")
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		if err := overrides.Apply(state); err != nil {
+			return nil, err
+		}
+	}
+	results := make([]BundleCallResult, len(calls))
+	for i, args := range calls {
+		result, err := s.applyCall(ctx, args, state, header, vm.Config{})
+		switch {
+		case err != nil:
+			results[i] = BundleCallResult{GasUsed: new(hexutil.Big), Error: err.Error()}
+		case result.Failed():
+			results[i] = BundleCallResult{ReturnValue: result.ReturnData, GasUsed: (*hexutil.Big)(result.UsedGas), Error: result.Err.Error()}
+		default:
+			results[i] = BundleCallResult{ReturnValue: result.ReturnData, GasUsed: (*hexutil.Big)(result.UsedGas)}
+		}
+		state.Finalise()
+	}
+	return results, nil
 }
 
-// EstimateGas returns an estimate of the amount of gas needed to execute the given transaction.
+// EstimateGas returns an estimate of the amount of gas needed to execute the given
+// transaction. It searches for the lowest gas allowance the transaction succeeds
+// with, which may be well above the gas it ends up using due to refunds and the
+// gas withheld from inner calls. If the transaction fails even with the highest
+// allowance, the error it failed with is returned.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
-	// Binary search the gas requirement, as it may be higher than the amount used
-	var lo, hi uint64
-	if (*big.Int)(&args.Gas).Sign() != 0 {
-		hi = (*big.Int)(&args.Gas).Uint64()
+	// Determine the highest gas limit can be used during the estimation
+	var (
+		lo     = params.TxGas - 1
+		hi     uint64
+		gasCap uint64
+	)
+	if gas := args.Gas.ToInt(); gas.Sign() != 0 && gas.Uint64() >= params.TxGas {
+		hi = gas.Uint64()
 	} else {
 		// Retrieve the current pending block to act as the gas ceiling
 		block, err := s.b.BlockByNumber(ctx, rpc.PendingBlockNumber)
@@ -665,20 +817,31 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*
 		}
 		hi = block.GasLimit().Uint64()
 	}
+	gasCap = hi
+
+	// Create a helper to check if a gas allowance results in a successful execution
+	execute := func(gas uint64) (*core.ExecutionResult, error) {
+		args.Gas = hexutil.Big(*new(big.Int).SetUint64(gas))
+		return s.doCall(ctx, args, rpc.PendingBlockNumber, nil, vm.Config{})
+	}
+	// Binary search the lowest gas allowance that still executes successfully
 	for lo+1 < hi {
-		// Take a guess at the gas, and check transaction validity
 		mid := (hi + lo) / 2
-		(*big.Int)(&args.Gas).SetUint64(mid)
-
-		_, gas, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{})
-
-		// If the transaction became invalid or used all the gas (failed), raise the gas limit
-		if err != nil || gas.Cmp((*big.Int)(&args.Gas)) == 0 {
+		if result, err := execute(mid); err != nil || result.Failed() {
 			lo = mid
-			continue
+		} else {
+			hi = mid
+		}
+	}
+	// Reject the transaction as invalid if it still fails at the highest allowance
+	if hi == gasCap {
+		result, err := execute(hi)
+		if err != nil {
+			return nil, err
+		}
+		if result.Failed() {
+			return nil, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction: %v", gasCap, result.Err)
 		}
-		// Otherwise assume the transaction succeeded, lower the gas limit
-		hi = mid
 	}
 	return (*hexutil.Big)(new(big.Int).SetUint64(hi)), nil
 }
@@ -687,23 +850,23 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*
 // while replaying a transaction in debug mode as well as the amount of
 // gas used and the return value
 type ExecutionResult struct {
-	Gas         *big.Int       `json:"gas"`
-	ReturnValue string         `json:"returnValue"`
-	StructLogs  []StructLogRes `json:"structLogs"`
+	Gas         *big.Int                `json:"gas"`
+	ReturnValue hexutil.UnprefixedBytes `json:"returnValue"`
+	StructLogs  []StructLogRes          `json:"structLogs"`
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
 // transaction in debug mode
 type StructLogRes struct {
-	Pc      uint64            `json:"pc"`
-	Op      string            `json:"op"`
-	Gas     uint64            `json:"gas"`
-	GasCost uint64            `json:"gasCost"`
-	Depth   int               `json:"depth"`
-	Error   error             `json:"error"`
-	Stack   []string          `json:"stack"`
-	Memory  []string          `json:"memory"`
-	Storage map[string]string `json:"storage"`
+	Pc      uint64                    `json:"pc"`
+	Op      string                    `json:"op"`
+	Gas     uint64                    `json:"gas"`
+	GasCost uint64                    `json:"gasCost"`
+	Depth   int                       `json:"depth"`
+	Error   error                     `json:"error"`
+	Stack   []string                  `json:"stack"`
+	Memory  []hexutil.UnprefixedBytes `json:"memory"`
+	Storage map[string]string         `json:"storage"`
 }
 
 // formatLogs formats EVM returned structured logs for json output
@@ -725,8 +888,10 @@ func FormatLogs(structLogs []vm.StructLog) []StructLogRes {
 			formattedStructLogs[index].Stack[i] = fmt.Sprintf("%x", math.PaddedBigBytes(stackValue, 32))
 		}
 
+		// Memory words are encoded straight from the captured memory, avoiding an
+		// intermediate hex string for every word
 		for i := 0; i+32 <= len(trace.Memory); i += 32 {
-			formattedStructLogs[index].Memory = append(formattedStructLogs[index].Memory, fmt.Sprintf("%x", trace.Memory[i:i+32]))
+			formattedStructLogs[index].Memory = append(formattedStructLogs[index].Memory, hexutil.UnprefixedBytes(trace.Memory[i:i+32]))
 		}
 
 		for i, storageValue := range trace.Storage {
@@ -738,8 +903,67 @@ func FormatLogs(structLogs []vm.StructLog) []StructLogRes {
 
 // rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
 // returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
-// transaction hashes.
-func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+// transaction hashes. Blocks of the canonical chain are annotated with their number of confirmations.
+func (s *PublicBlockChainAPI) rpcOutputBlock(ctx context.Context, b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields, err := RPCMarshalBlock(b, inclTx, fullTx)
+	if err != nil {
+		return nil, err
+	}
+	fields["totalDifficulty"] = (*hexutil.Big)(s.b.GetTd(b.Hash()))
+	if confs := confirmations(s.b, b.Hash(), b.NumberU64()); confs != nil {
+		fields["confirmations"] = confs
+	}
+	return fields, nil
+}
+
+// confirmations returns the number of confirmations of a block, counting the block
+// itself, or nil if the block is not part of the canonical chain. Canonicity is
+// checked against the canonical hash index, sparing the retrieval of the header.
+func confirmations(b Backend, hash common.Hash, number uint64) *hexutil.Uint64 {
+	head := b.CurrentBlock()
+	switch {
+	case number > head.NumberU64():
+		return nil
+	case number == head.NumberU64():
+		if head.Hash() != hash {
+			return nil
+		}
+	default:
+		if core.GetCanonicalHash(b.ChainDb(), number) != hash {
+			return nil
+		}
+	}
+	confs := hexutil.Uint64(head.NumberU64() - number + 1)
+	return &confs
+}
+
+// ResolveFinality resolves the safe and finalized block tags into the number of
+// the newest block having the given number of confirmations on top of the head
+// block, clamped to the genesis block. Other block numbers are returned as is.
+func ResolveFinality(blockNr rpc.BlockNumber, head uint64, safeDepth, finalityDepth uint64) rpc.BlockNumber {
+	var depth uint64
+	switch blockNr {
+	case rpc.SafeBlockNumber:
+		depth = safeDepth
+	case rpc.FinalizedBlockNumber:
+		depth = finalityDepth
+	default:
+		return blockNr
+	}
+	if depth == 0 {
+		depth = 1
+	}
+	if head+1 < depth {
+		return rpc.EarliestBlockNumber
+	}
+	return rpc.BlockNumber(head + 1 - depth)
+}
+
+// RPCMarshalBlock converts the given block to the RPC output, without any chain
+// dependent fields such as the total difficulty. If inclTx is true transactions
+// are returned. When fullTx is true the returned block contains full transaction
+// details, otherwise it will only contain transaction hashes.
+func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
 	head := b.Header() // copies the header once
 	fields := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
@@ -752,7 +976,6 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"stateRoot":        head.Root,
 		"miner":            head.Coinbase,
 		"difficulty":       (*hexutil.Big)(head.Difficulty),
-		"totalDifficulty":  (*hexutil.Big)(s.b.GetTd(b.Hash())),
 		"extraData":        hexutil.Bytes(head.Extra),
 		"size":             hexutil.Uint64(uint64(b.Size().Int64())),
 		"gasLimit":         (*hexutil.Big)(head.GasLimit),
@@ -810,10 +1033,11 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+	Confirmations    *hexutil.Uint64 `json:"confirmations,omitempty"`
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -937,7 +1161,9 @@ func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Co
 // GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (*RPCTransaction, error) {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint(index))
+		tx, err := newRPCTransactionFromBlockIndex(block, uint(index))
+		s.setConfirmations(tx, block)
+		return tx, err
 	}
 	return nil, nil
 }
@@ -945,11 +1171,21 @@ func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx conte
 // GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (*RPCTransaction, error) {
 	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint(index))
+		tx, err := newRPCTransactionFromBlockIndex(block, uint(index))
+		s.setConfirmations(tx, block)
+		return tx, err
 	}
 	return nil, nil
 }
 
+// setConfirmations annotates a transaction included in the given block with the
+// number of confirmations of the block, if it's part of the canonical chain.
+func (s *PublicTransactionPoolAPI) setConfirmations(tx *RPCTransaction, block *types.Block) {
+	if tx != nil {
+		tx.Confirmations = confirmations(s.b, block.Hash(), block.NumberU64())
+	}
+}
+
 // GetRawTransactionByBlockNumberAndIndex returns the bytes of the transaction for the given block number and index.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (hexutil.Bytes, error) {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
@@ -1005,23 +1241,25 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	var err error
 
 	if tx, isPending, err = getTransaction(s.b.ChainDb(), s.b, hash); err != nil {
-		log.Debug("Failed to retrieve transaction", "hash", hash, "err", err)
+		rpc.TraceFromContext(ctx).Log("Failed to retrieve transaction", "hash", hash, "err", err)
 		return nil, nil
 	} else if tx == nil {
 		return nil, nil
 	}
 	if isPending {
-		return newRPCPendingTransaction(tx), nil
+		return NewRPCPendingTransaction(tx), nil
 	}
 
 	blockHash, _, _, err := getTransactionBlockData(s.b.ChainDb(), hash)
 	if err != nil {
-		log.Debug("Failed to retrieve transaction block", "hash", hash, "err", err)
+		rpc.TraceFromContext(ctx).Log("Failed to retrieve transaction block", "hash", hash, "err", err)
 		return nil, nil
 	}
 
 	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
-		return newRPCTransaction(block, hash)
+		tx, err := newRPCTransaction(block, hash)
+		s.setConfirmations(tx, block)
+		return tx, err
 	}
 	return nil, nil
 }
@@ -1032,7 +1270,7 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 	var err error
 
 	if tx, _, err = getTransaction(s.b.ChainDb(), s.b, hash); err != nil {
-		log.Debug("Failed to retrieve transaction", "hash", hash, "err", err)
+		rpc.TraceFromContext(ctx).Log("Failed to retrieve transaction", "hash", hash, "err", err)
 		return nil, nil
 	} else if tx == nil {
 		return nil, nil
@@ -1042,22 +1280,22 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
-	receipt := core.GetReceipt(s.b.ChainDb(), hash)
+func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	receipt := core.GetDerivedReceipt(s.b.ChainDb(), hash, s.b.ChainConfig())
 	if receipt == nil {
-		log.Debug("Receipt not found for transaction", "hash", hash)
+		rpc.TraceFromContext(ctx).Log("Receipt not found for transaction", "hash", hash)
 		return nil, nil
 	}
 
 	tx, _, err := getTransaction(s.b.ChainDb(), s.b, hash)
 	if err != nil {
-		log.Debug("Failed to retrieve transaction", "hash", hash, "err", err)
+		rpc.TraceFromContext(ctx).Log("Failed to retrieve transaction", "hash", hash, "err", err)
 		return nil, nil
 	}
 
 	txBlock, blockIndex, index, err := getTransactionBlockData(s.b.ChainDb(), hash)
 	if err != nil {
-		log.Debug("Failed to retrieve transaction block", "hash", hash, "err", err)
+		rpc.TraceFromContext(ctx).Log("Failed to retrieve transaction block", "hash", hash, "err", err)
 		return nil, nil
 	}
 
@@ -1088,6 +1326,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	if confs := confirmations(s.b, txBlock, blockIndex); confs != nil {
+		fields["confirmations"] = confs
+	}
 	return fields, nil
 }
 
@@ -1105,7 +1346,16 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
-	return wallet.SignTx(account, tx, chainID)
+	cancel, err := s.b.SigningPolicy().AuthorizeTx(addr, tx)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return signed, nil
 }
 
 // SendTxArgs represents the arguments to sumbit a new transaction into the transaction pool.
@@ -1117,6 +1367,10 @@ type SendTxArgs struct {
 	Value    *hexutil.Big    `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
+
+	// Private transactions are only included by the local miner and are never
+	// announced to the network.
+	Private bool `json:"private"`
 }
 
 // prepareSendTxArgs is a helper function that fills in default values for unspecified tx fields.
@@ -1152,8 +1406,12 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 }
 
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
-func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
-	if err := b.SendTx(ctx, tx); err != nil {
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, private bool) (common.Hash, error) {
+	send := b.SendTx
+	if private {
+		send = b.SendPrivateTx
+	}
+	if err := send(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
 	if tx.To() == nil {
@@ -1197,11 +1455,16 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
+	cancel, err := s.b.SigningPolicy().AuthorizeTx(args.From, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
 	signed, err := wallet.SignTx(account, tx, chainID)
 	if err != nil {
+		cancel()
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(ctx, s.b, signed, args.Private)
 }
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
@@ -1231,6 +1494,17 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	return tx.Hash().Hex(), nil
 }
 
+// SendPrivateRawTransaction adds the signed transaction to the transaction pool
+// as a private one, which is only included by the local miner and never announced
+// to the network.
+func (s *PublicTransactionPoolAPI) SendPrivateRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, tx, true)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19NetworkChain Signed Message:\n" + len(message) + message).
 //
@@ -1248,6 +1522,9 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.b.SigningPolicy().AuthorizeMessage(addr); err != nil {
+		return nil, err
+	}
 	// Sign the requested hash with the wallet
 	signature, err := wallet.SignHash(account, signHash(data))
 	if err == nil {
@@ -1286,9 +1563,105 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	return &SignTransactionResult{data, tx}, nil
 }
 
+// SignTransactions signs a batch of transactions originating from the same
+// account with a single wallet lookup. Missing nonces are filled in sequentially
+// starting from the pending nonce of the account, whereas explicitly specified
+// ones must follow on from the previous transaction in the batch. The node needs
+// to have the private key of the account and it needs to be unlocked.
+func (s *PublicTransactionPoolAPI) SignTransactions(ctx context.Context, args []SendTxArgs) ([]*SignTransactionResult, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	from := args[0].From
+	for i := range args {
+		if args[i].From != from {
+			return nil, fmt.Errorf("transaction %d: sender %x differs from batch sender %x", i, args[i].From, from)
+		}
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: from}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Hold the address's mutex for the whole batch to prevent concurrent assignment
+	// of the same nonces to other transactions.
+	s.nonceLock.LockAddr(from)
+	defer s.nonceLock.UnlockAddr(from)
+
+	// Assign and validate the nonces of the batch
+	var nonce uint64
+	if args[0].Nonce != nil {
+		nonce = uint64(*args[0].Nonce)
+	} else if nonce, err = s.b.GetPoolNonce(ctx, from); err != nil {
+		return nil, err
+	}
+	for i := range args {
+		if args[i].Nonce != nil && uint64(*args[i].Nonce) != nonce {
+			return nil, fmt.Errorf("transaction %d: nonce %d not sequential, expected %d", i, uint64(*args[i].Nonce), nonce)
+		}
+		n := nonce
+		args[i].Nonce = (*hexutil.Uint64)(&n)
+		nonce++
+	}
+	// Sign all the transactions with the same chain configuration
+	var chainID *big.Int
+	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+	// The signing policy is checked against the whole batch, so any reservations
+	// made for it are returned if a later transaction fails.
+	var cancels []func()
+	abort := func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	results := make([]*SignTransactionResult, len(args))
+	for i := range args {
+		if err := args[i].setDefaults(ctx, s.b); err != nil {
+			abort()
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		unsigned := args[i].toTransaction()
+		cancel, err := s.b.SigningPolicy().AuthorizeTx(from, unsigned)
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		cancels = append(cancels, cancel)
+
+		tx, err := wallet.SignTx(account, unsigned, chainID)
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		data, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			abort()
+			return nil, err
+		}
+		results[i] = &SignTransactionResult{data, tx}
+	}
+	return results, nil
+}
+
 // PendingTransactions returns the transactions that are in the transaction pool and have a from address that is one of
-// the accounts this node manages.
-func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
+// the accounts this node manages. If a set of addresses is given, the pending transactions sent from those are returned
+// instead, grouped by sender and sorted by nonce. Light clients only know about the transactions submitted through them.
+func (s *PublicTransactionPoolAPI) PendingTransactions(addrs *[]common.Address) ([]*RPCTransaction, error) {
+	if addrs != nil {
+		pending, err := s.b.GetPoolTransactionsFrom(*addrs)
+		if err != nil {
+			return nil, err
+		}
+		transactions := make([]*RPCTransaction, 0, len(pending))
+		for _, tx := range pending {
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
+		}
+		return transactions, nil
+	}
 	pending, err := s.b.GetPoolTransactions()
 	if err != nil {
 		return nil, err
@@ -1302,7 +1675,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1343,7 +1716,12 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 				return common.Hash{}, err
 			}
 			s.b.RemoveTx(p.Hash())
-			if err = s.b.SendTx(ctx, signedTx); err != nil {
+
+			send := s.b.SendTx
+			if sendArgs.Private {
+				send = s.b.SendPrivateTx
+			}
+			if err = send(ctx, signedTx); err != nil {
 				return common.Hash{}, err
 			}
 			return signedTx.Hash(), nil
@@ -1353,6 +1731,24 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	return common.Hash{}, fmt.Errorf("Transaction %#x not found", matchTx.Hash())
 }
 
+// PublicAdminAPI is the collection of NetworkChain APIs exposed over the public
+// admin endpoint.
+type PublicAdminAPI struct {
+	b Backend
+}
+
+// NewPublicAdminAPI creates a new API definition for the public admin methods
+// of the NetworkChain service.
+func NewPublicAdminAPI(b Backend) *PublicAdminAPI {
+	return &PublicAdminAPI{b: b}
+}
+
+// ChainConfig returns the chain configuration the node is running with, which
+// contains the fork schedule and the consensus engine parameters.
+func (api *PublicAdminAPI) ChainConfig() *params.ChainConfig {
+	return api.b.ChainConfig()
+}
+
 // PublicDebugAPI is the collection of Etheruem APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
@@ -1424,6 +1820,18 @@ func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
 	return ldb.LDB().GetProperty(property)
 }
 
+// ChaindbStats returns the compaction, open file and amplification statistics of
+// the chain database.
+func (api *PrivateDebugAPI) ChaindbStats() (*ethdb.Stats, error) {
+	ldb, ok := api.b.ChainDb().(interface {
+		Stats() (*ethdb.Stats, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("chaindbStats does not work for memory databases")
+	}
+	return ldb.Stats()
+}
+
 func (api *PrivateDebugAPI) ChaindbCompact() error {
 	ldb, ok := api.b.ChainDb().(interface {
 		LDB() *leveldb.DB
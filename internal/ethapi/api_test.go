@@ -0,0 +1,351 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/accounts/keystore"
+	"github.com/networkchain/networkchain/accounts/policy"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/common/math"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// testBackend is an API backend on top of a local chain, implementing only the
+// methods needed by the tests. Calling any other method panics.
+type testBackend struct {
+	Backend
+	db        ethdb.Database
+	chain     *core.BlockChain
+	accman    *accounts.Manager
+	poolNonce uint64
+}
+
+// newTestBackend creates a backend on top of a chain of n blocks generated from the
+// given genesis specification.
+func newTestBackend(t *testing.T, n int, gspec *core.Genesis, gen func(int, *core.BlockGen)) *testBackend {
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, n, gen)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return &testBackend{db: db, chain: chain}
+}
+
+func (b *testBackend) ChainDb() ethdb.Database           { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig  { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block        { return b.chain.CurrentBlock() }
+func (b *testBackend) AccountManager() *accounts.Manager { return b.accman }
+func (b *testBackend) SigningPolicy() *policy.Policy     { return nil }
+
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(params.Shannon), nil
+}
+
+func (b *testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return b.poolNonce, nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	if blockNr != rpc.LatestBlockNumber {
+		header = b.chain.GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), vmCfg), func() error { return nil }, nil
+}
+
+// Tests that the safe and finalized block tags resolve to the newest block with
+// enough confirmations, and that other block numbers are left alone.
+func TestResolveFinality(t *testing.T) {
+	tests := []struct {
+		blockNr  rpc.BlockNumber
+		head     uint64
+		safe     uint64
+		final    uint64
+		resolved rpc.BlockNumber
+	}{
+		// Safe and finalized blocks of a long enough chain
+		{rpc.SafeBlockNumber, 100, 6, 12, 95},
+		{rpc.FinalizedBlockNumber, 100, 6, 12, 89},
+
+		// A single confirmation is the head, zero depths count as one
+		{rpc.SafeBlockNumber, 100, 1, 12, 100},
+		{rpc.SafeBlockNumber, 100, 0, 12, 100},
+		{rpc.FinalizedBlockNumber, 100, 6, 0, 100},
+
+		// Chains too short for the depth resolve to the genesis block
+		{rpc.SafeBlockNumber, 5, 6, 12, 0},
+		{rpc.FinalizedBlockNumber, 5, 6, 12, rpc.EarliestBlockNumber},
+		{rpc.FinalizedBlockNumber, 0, 6, 12, rpc.EarliestBlockNumber},
+
+		// Any other block number is returned as is
+		{rpc.LatestBlockNumber, 100, 6, 12, rpc.LatestBlockNumber},
+		{rpc.PendingBlockNumber, 100, 6, 12, rpc.PendingBlockNumber},
+		{rpc.EarliestBlockNumber, 100, 6, 12, rpc.EarliestBlockNumber},
+		{rpc.BlockNumber(42), 100, 6, 12, rpc.BlockNumber(42)},
+		{rpc.BlockNumber(142), 100, 6, 12, rpc.BlockNumber(142)},
+	}
+	for i, tt := range tests {
+		if resolved := ResolveFinality(tt.blockNr, tt.head, tt.safe, tt.final); resolved != tt.resolved {
+			t.Errorf("test %d: resolved block mismatch: have %d, want %d", i, resolved, tt.resolved)
+		}
+	}
+}
+
+// Tests that blocks are annotated with their confirmations only if they are part
+// of the canonical chain.
+func TestConfirmations(t *testing.T) {
+	gspec := &core.Genesis{Config: params.TestChainConfig}
+	b := newTestBackend(t, 8, gspec, nil)
+	defer b.chain.Stop()
+
+	// Import a shorter side chain forking off the third block
+	fork, _ := core.GenerateChain(gspec.Config, b.chain.GetBlockByNumber(3), b.db, 2, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := b.chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to import side chain: %v", err)
+	}
+	if head := b.CurrentBlock().NumberU64(); head != 8 {
+		t.Fatalf("head mismatch: have %d, want %d", head, 8)
+	}
+	tests := []struct {
+		block *types.Block
+		confs uint64 // 0 = not canonical
+	}{
+		{b.chain.Genesis(), 9},
+		{b.chain.GetBlockByNumber(3), 6},
+		{b.chain.GetBlockByNumber(7), 2},
+		{b.CurrentBlock(), 1},
+		{fork[0], 0},
+		{fork[1], 0},
+		{types.NewBlockWithHeader(&types.Header{Number: b.CurrentBlock().Number(), Extra: []byte("sibling")}), 0},
+		{types.NewBlockWithHeader(&types.Header{Number: new(big.Int).Add(b.CurrentBlock().Number(), common.Big1)}), 0},
+	}
+	for i, tt := range tests {
+		confs := confirmations(b, tt.block.Hash(), tt.block.NumberU64())
+		switch {
+		case tt.confs == 0 && confs != nil:
+			t.Errorf("test %d: block #%d annotated with %d confirmations, want none", i, tt.block.NumberU64(), *confs)
+		case tt.confs != 0 && confs == nil:
+			t.Errorf("test %d: block #%d not annotated, want %d confirmations", i, tt.block.NumberU64(), tt.confs)
+		case tt.confs != 0 && uint64(*confs) != tt.confs:
+			t.Errorf("test %d: block #%d confirmations mismatch: have %d, want %d", i, tt.block.NumberU64(), *confs, tt.confs)
+		}
+	}
+}
+
+// Tests that the calls of a bundle see the state modifications of the previous
+// ones, and that failing calls are reported without aborting the bundle.
+func TestCallBundle(t *testing.T) {
+	// Deploy a counter incrementing and returning its first storage slot:
+	//   PUSH1 0 SLOAD PUSH1 1 ADD DUP1 PUSH1 0 SSTORE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	var (
+		counter = common.Address{0xcc}
+		code    = common.FromHex("0x6000546001018060005560005260206000f3")
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{counter: {Code: code, Balance: new(big.Int)}},
+		}
+	)
+	b := newTestBackend(t, 1, gspec, nil)
+	defer b.chain.Stop()
+
+	api := NewPublicBlockChainAPI(b)
+	call := func(gas int64) CallArgs {
+		return CallArgs{From: common.Address{0x01}, To: &counter, Gas: hexutil.Big(*big.NewInt(gas))}
+	}
+	results, err := api.CallBundle(context.Background(), []CallArgs{
+		call(100000), // first increment, allocating the slot
+		call(100000), // second increment, modifying the slot
+		call(25000),  // runs out of gas storing the slot, reverting the increment
+		call(20000),  // doesn't cover the intrinsic gas, not executed at all
+		call(100000), // third increment, unaffected by the failures
+	}, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	want := []struct {
+		value uint64
+		gas   uint64
+		err   string
+	}{
+		{1, 41230, ""},
+		{2, 26230, ""},
+		{0, 25000, "out of gas"},
+		{0, 0, "out of gas"},
+		{3, 26230, ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(want))
+	}
+	for i, tt := range want {
+		res := results[i]
+		if res.Error != tt.err {
+			t.Errorf("test %d: error mismatch: have %q, want %q", i, res.Error, tt.err)
+		}
+		if gas := res.GasUsed.ToInt(); gas.Uint64() != tt.gas {
+			t.Errorf("test %d: gas used mismatch: have %v, want %d", i, gas, tt.gas)
+		}
+		if tt.err != "" {
+			if len(res.ReturnValue) != 0 {
+				t.Errorf("test %d: return value of failed call: %x", i, res.ReturnValue)
+			}
+			continue
+		}
+		if value := new(big.Int).SetBytes(res.ReturnValue); value.Uint64() != tt.value {
+			t.Errorf("test %d: return value mismatch: have %v, want %d", i, value, tt.value)
+		}
+	}
+	// Overrides apply before the first call and the chain state is left untouched
+	overrides := &StateOverride{counter: {StateDiff: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(41))}}}
+	results, err = api.CallBundle(context.Background(), []CallArgs{call(100000)}, rpc.LatestBlockNumber, overrides)
+	if err != nil {
+		t.Fatalf("failed to execute overridden bundle: %v", err)
+	}
+	if value := new(big.Int).SetBytes(results[0].ReturnValue); value.Uint64() != 42 {
+		t.Errorf("overridden return value mismatch: have %v, want %d", value, 42)
+	}
+	statedb, _ := b.chain.State()
+	if slot := statedb.GetState(counter, common.Hash{}); slot != (common.Hash{}) {
+		t.Errorf("bundle modified the chain state: slot %x", slot)
+	}
+	if _, err := api.CallBundle(context.Background(), nil, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("empty bundle executed")
+	}
+}
+
+// Tests that batches of transactions are signed with sequential nonces, filled in
+// from the pool or following on from explicit ones, and rejected otherwise.
+func TestSignTransactions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signtxs-test")
+	if err != nil {
+		t.Fatalf("failed to create keystore dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	key, _ := crypto.GenerateKey()
+	account, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig}
+	b := newTestBackend(t, 0, gspec, nil)
+	defer b.chain.Stop()
+
+	b.accman = accounts.NewManager(ks)
+	b.poolNonce = 5
+	api := NewPublicTransactionPoolAPI(b, new(AddrLocker))
+
+	nonce := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+	stranger := common.Address{0xff}
+	tests := []struct {
+		from   []common.Address  // Sender of each transaction, account if nil
+		nonces []*hexutil.Uint64 // Explicit nonce of each transaction, pool filled if nil
+		want   []uint64          // Signed nonces, nil if the batch is rejected
+		err    string            // Error the batch is rejected with
+	}{
+		// Missing nonces are filled sequentially from the pool
+		{nil, []*hexutil.Uint64{nil, nil, nil}, []uint64{5, 6, 7}, ""},
+
+		// Explicit nonces are used as is if sequential, independent of the pool
+		{nil, []*hexutil.Uint64{nonce(9), nil, nonce(11)}, []uint64{9, 10, 11}, ""},
+		{nil, []*hexutil.Uint64{nil, nonce(6)}, []uint64{5, 6}, ""},
+
+		// Non-sequential explicit nonces reject the whole batch
+		{nil, []*hexutil.Uint64{nonce(5), nonce(7)}, nil, "transaction 1: nonce 7 not sequential, expected 6"},
+		{nil, []*hexutil.Uint64{nil, nonce(5)}, nil, "transaction 1: nonce 5 not sequential, expected 6"},
+		{nil, []*hexutil.Uint64{nonce(3), nonce(3)}, nil, "transaction 1: nonce 3 not sequential, expected 4"},
+
+		// Batches mixing senders or from unknown accounts are rejected
+		{[]common.Address{account.Address, stranger}, []*hexutil.Uint64{nil, nil}, nil, "transaction 1: sender"},
+		{[]common.Address{stranger, stranger}, []*hexutil.Uint64{nil, nil}, nil, accounts.ErrUnknownAccount.Error()},
+	}
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+	for i, tt := range tests {
+		args := make([]SendTxArgs, len(tt.nonces))
+		for j := range args {
+			args[j] = SendTxArgs{From: account.Address, To: &stranger, Nonce: tt.nonces[j]}
+			if tt.from != nil {
+				args[j].From = tt.from[j]
+			}
+		}
+		results, err := api.SignTransactions(context.Background(), args)
+		if tt.want == nil {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to sign batch: %v", i, err)
+			continue
+		}
+		if len(results) != len(tt.want) {
+			t.Errorf("test %d: result count mismatch: have %d, want %d", i, len(results), len(tt.want))
+			continue
+		}
+		for j, res := range results {
+			if res.Tx.Nonce() != tt.want[j] {
+				t.Errorf("test %d, tx %d: nonce mismatch: have %d, want %d", i, j, res.Tx.Nonce(), tt.want[j])
+			}
+			if from, err := types.Sender(signer, res.Tx); err != nil || from != account.Address {
+				t.Errorf("test %d, tx %d: sender mismatch: have %x (%v), want %x", i, j, from, err, account.Address)
+			}
+		}
+	}
+	if results, err := api.SignTransactions(context.Background(), nil); results != nil || err != nil {
+		t.Errorf("empty batch: have %v, %v, want nothing", results, err)
+	}
+}
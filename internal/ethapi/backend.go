@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/accounts/policy"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/state"
@@ -44,11 +45,13 @@ type Backend interface {
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
+	SigningPolicy() *policy.Policy
 	// BlockChain API
 	SetHead(number uint64)
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
@@ -56,8 +59,10 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error
 	RemoveTx(txHash common.Hash)
 	GetPoolTransactions() (types.Transactions, error)
+	GetPoolTransactionsFrom(addrs []common.Address) (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
@@ -90,6 +95,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTxPoolAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPublicAdminAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -0,0 +1,231 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package governor implements a memory governor that watches the resident memory
+// of the process and asks the registered subsystems to shed load (shrink caches,
+// pause background work) when configurable watermarks are exceeded, degrading
+// gracefully instead of being killed by the operating system.
+package governor
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// Level is the memory pressure the process is under.
+type Level int
+
+const (
+	// Normal means the process is below the high watermark.
+	Normal Level = iota
+
+	// High means the process is above the high watermark. Subsystems should drop
+	// their caches and keep them capped until the pressure returns to normal.
+	High
+
+	// Critical means the process is above the critical watermark. Subsystems should
+	// additionally suspend any background work, such as chain synchronisation,
+	// header pruning and the generation of light client indexes.
+	Critical
+)
+
+// hysteresis is the fraction of a watermark by which the resident memory needs to
+// drop below it before the memory pressure is lowered, to avoid flapping between
+// levels around a watermark.
+const hysteresis = 0.1
+
+// String implements fmt.Stringer.
+func (l Level) String() string {
+	switch l {
+	case Normal:
+		return "normal"
+	case High:
+		return "high"
+	case Critical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Config contains the watermarks of the memory governor.
+type Config struct {
+	HighWatermark     uint64        // Resident memory in MB above which caches are shrunk (0 = disabled)
+	CriticalWatermark uint64        // Resident memory in MB above which background work is paused (0 = disabled)
+	Interval          time.Duration // Interval between resident memory checks
+}
+
+// DefaultConfig contains the default settings of the memory governor (disabled).
+var DefaultConfig = Config{
+	Interval: 5 * time.Second,
+}
+
+// Enabled returns whether any watermark is configured.
+func (c *Config) Enabled() bool {
+	return c.HighWatermark > 0 || c.CriticalWatermark > 0
+}
+
+// handler is a named subsystem reacting to the memory pressure.
+type handler struct {
+	name string
+	fn   func(level Level)
+}
+
+// Governor periodically checks the resident memory of the process and notifies
+// the registered subsystems of the memory pressure.
+type Governor struct {
+	config   Config
+	rss      func() (uint64, error) // Retrieves the resident memory of the process in bytes
+	handlers []handler
+	level    Level
+	lock     sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a memory governor with the given watermarks.
+func New(config Config) *Governor {
+	if config.Interval <= 0 {
+		config.Interval = DefaultConfig.Interval
+	}
+	return &Governor{
+		config: config,
+		rss:    residentMemory,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Register adds a subsystem to be notified of the memory pressure. The callback
+// is invoked whenever the pressure changes level, including when it returns to
+// normal, so any suspended work may be resumed.
+func (g *Governor) Register(name string, fn func(level Level)) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.handlers = append(g.handlers, handler{name: name, fn: fn})
+}
+
+// Level returns the memory pressure measured by the last check.
+func (g *Governor) Level() Level {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.level
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the memory governor (nil as it doesn't use the devp2p overlay network).
+func (g *Governor) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// memory governor (nil as it doesn't provide any user callable APIs).
+func (g *Governor) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, launching the periodic memory checks.
+func (g *Governor) Start(server *p2p.Server) error {
+	g.wg.Add(1)
+	go g.loop()
+
+	log.Info("Memory governor started", "high", g.config.HighWatermark, "critical", g.config.CriticalWatermark)
+	return nil
+}
+
+// Stop implements node.Service, terminating the periodic memory checks.
+func (g *Governor) Stop() error {
+	close(g.quit)
+	g.wg.Wait()
+
+	log.Info("Memory governor stopped")
+	return nil
+}
+
+// loop checks the resident memory until termination.
+func (g *Governor) loop() {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			g.check()
+		case <-g.quit:
+			return
+		}
+	}
+}
+
+// check measures the resident memory of the process and notifies the subsystems
+// of the resulting memory pressure.
+func (g *Governor) check() {
+	rss, err := g.rss()
+	if err != nil {
+		log.Debug("Failed to retrieve resident memory", "err", err)
+		return
+	}
+	g.lock.Lock()
+	prev := g.level
+	level := g.classify(rss, prev)
+	g.level = level
+	handlers := g.handlers
+	g.lock.Unlock()
+
+	// Only act when crossing a watermark, the subsystems keep their caches capped
+	// and their work paused until notified of a lower pressure
+	if level == prev {
+		return
+	}
+	log.Warn("Memory pressure changed", "level", level, "rss", rss>>20, "prev", prev)
+	for _, h := range handlers {
+		log.Debug("Shedding memory load", "subsystem", h.name, "level", level)
+		h.fn(level)
+	}
+	if level > Normal {
+		debug.FreeOSMemory()
+	}
+}
+
+// classify maps a resident memory size in bytes to a memory pressure level. A
+// level already reached is only left once the memory drops below its watermark
+// by more than the hysteresis.
+func (g *Governor) classify(rss uint64, prev Level) Level {
+	mb := float64(rss >> 20)
+
+	above := func(watermark uint64, level Level) bool {
+		if watermark == 0 {
+			return false
+		}
+		if prev >= level {
+			return mb >= float64(watermark)*(1-hysteresis)
+		}
+		return mb >= float64(watermark)
+	}
+	switch {
+	case above(g.config.CriticalWatermark, Critical):
+		return Critical
+	case above(g.config.HighWatermark, High):
+		return High
+	default:
+		return Normal
+	}
+}
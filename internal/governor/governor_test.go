@@ -0,0 +1,63 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package governor
+
+import "testing"
+
+// Tests that the subsystems are notified whenever the memory pressure crosses a
+// watermark, and that the pressure is only lowered below the hysteresis band.
+func TestGovernorNotifications(t *testing.T) {
+	gov := New(Config{HighWatermark: 100, CriticalWatermark: 200})
+
+	var rss uint64
+	gov.rss = func() (uint64, error) { return rss << 20, nil }
+
+	var levels []Level
+	gov.Register("test", func(level Level) { levels = append(levels, level) })
+
+	tests := []struct {
+		rss    uint64
+		level  Level
+		notify bool
+	}{
+		{50, Normal, false},
+		{150, High, true},
+		{150, High, false},
+		{95, High, false},
+		{250, Critical, true},
+		{250, Critical, false},
+		{185, Critical, false},
+		{150, High, true},
+		{85, Normal, true},
+		{50, Normal, false},
+		{99, Normal, false},
+	}
+	for i, tt := range tests {
+		levels = levels[:0]
+		rss = tt.rss
+		gov.check()
+
+		if level := gov.Level(); level != tt.level {
+			t.Errorf("test %d: level mismatch: have %v, want %v", i, level, tt.level)
+		}
+		if notified := len(levels) > 0; notified != tt.notify {
+			t.Errorf("test %d: notification mismatch: have %v, want %v", i, notified, tt.notify)
+		} else if notified && levels[0] != tt.level {
+			t.Errorf("test %d: notified level mismatch: have %v, want %v", i, levels[0], tt.level)
+		}
+	}
+}
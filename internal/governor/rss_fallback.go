@@ -14,18 +14,16 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
 
-// +build VERIFY_EVM_INTEGER_POOL
+// +build !linux
 
-package vm
+package governor
 
-import "fmt"
+import "runtime"
 
-const verifyPool = true
-
-func verifyIntegerPool(ip *intPool) {
-	for i, item := range ip.pool.data {
-		if item.Cmp(checkVal) != 0 {
-			panic(fmt.Sprintf("%d'th item failed aggressive pool check. Value was modified", i))
-		}
-	}
+// residentMemory approximates the resident memory of the process in bytes with
+// the memory obtained from the operating system by the Go runtime.
+func residentMemory() (uint64, error) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys, nil
 }
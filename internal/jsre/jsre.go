@@ -24,6 +24,9 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/networkchain/networkchain/common"
@@ -50,6 +53,9 @@ type JSRE struct {
 	evalQueue     chan *evalReq
 	stopEventLoop chan bool
 	closed        chan struct{}
+
+	modules map[string]otto.Value // Exports of the already loaded local modules
+	scripts []string              // Directories of the scripts being executed, for relative requires
 }
 
 // jsTimer is a single timer instance with a callback function
@@ -74,9 +80,11 @@ func New(assetPath string, output io.Writer) *JSRE {
 		closed:        make(chan struct{}),
 		evalQueue:     make(chan *evalReq),
 		stopEventLoop: make(chan bool),
+		modules:       make(map[string]otto.Value),
 	}
 	go re.runEventLoop()
 	re.Set("loadScript", re.loadScript)
+	re.Set("require", re.require)
 	re.Set("inspect", re.prettyPrintJS)
 	return re
 }
@@ -242,7 +250,8 @@ func (self *JSRE) Stop(waitForCallbacks bool) {
 // Exec(file) loads and runs the contents of a file
 // if a relative path is given, the jsre's assetPath is used
 func (self *JSRE) Exec(file string) error {
-	code, err := ioutil.ReadFile(common.AbsolutePath(self.assetPath, file))
+	path := common.AbsolutePath(self.assetPath, file)
+	code, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
@@ -252,6 +261,9 @@ func (self *JSRE) Exec(file string) error {
 		if err != nil {
 			return
 		}
+		self.scripts = append(self.scripts, filepath.Dir(path))
+		defer func() { self.scripts = self.scripts[:len(self.scripts)-1] }()
+
 		_, err = vm.Run(script)
 	})
 	return err
@@ -303,6 +315,65 @@ func (self *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 	return otto.TrueValue()
 }
 
+// require loads a CommonJS style module from the local file system and returns
+// its exports. Paths starting with ./ or ../ are resolved relative to the script
+// doing the require, other relative paths relative to the asset path. Modules are
+// only executed once, subsequent requires returning the cached exports.
+func (self *JSRE) require(call otto.FunctionCall) otto.Value {
+	name, err := call.Argument(0).ToString()
+	if err != nil || name == "" {
+		panic(call.Otto.MakeTypeError("require: module name required"))
+	}
+	path := self.resolveModule(name)
+	if exports, ok := self.modules[path]; ok {
+		return exports
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", fmt.Sprintf("Cannot find module '%s'", name)))
+	}
+	// Wrap the module into a function to give it its own scope and run it
+	wrapper, err := compileAndRun(call.Otto, path, "(function(exports, require, module, __filename, __dirname) {"+string(source)+"\n})")
+	if err != nil {
+		panic(call.Otto.MakeSyntaxError(fmt.Sprintf("%s: %v", path, err)))
+	}
+	module, _ := call.Otto.Object(`({exports: {}})`)
+	exports, _ := module.Get("exports")
+	requireFn, _ := call.Otto.Get("require")
+
+	self.modules[path] = exports // Allow cyclic requires to see partial exports
+	self.scripts = append(self.scripts, filepath.Dir(path))
+	defer func() { self.scripts = self.scripts[:len(self.scripts)-1] }()
+
+	if _, err := wrapper.Call(otto.UndefinedValue(), exports, requireFn, module, path, filepath.Dir(path)); err != nil {
+		delete(self.modules, path)
+		if _, ok := err.(*otto.Error); ok {
+			panic(err) // Preserve the stack trace of the failure
+		}
+		panic(call.Otto.MakeCustomError("Error", err.Error()))
+	}
+	exports, _ = module.Get("exports")
+	self.modules[path] = exports
+	return exports
+}
+
+// resolveModule converts a module name into the absolute path of its source.
+func (self *JSRE) resolveModule(name string) string {
+	var path string
+	switch {
+	case filepath.IsAbs(name):
+		path = name
+	case (strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../")) && len(self.scripts) > 0:
+		path = filepath.Join(self.scripts[len(self.scripts)-1], name)
+	default:
+		path = common.AbsolutePath(self.assetPath, name)
+	}
+	if _, err := os.Stat(path); err != nil && filepath.Ext(path) != ".js" {
+		path += ".js"
+	}
+	return path
+}
+
 // Evaluate executes code and pretty prints the result to the specified output
 // stream.
 func (self *JSRE) Evaluate(code string, w io.Writer) error {
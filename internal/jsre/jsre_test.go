@@ -135,3 +135,35 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestRequire(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `var lib = require("./lib/math"); result = lib.double(21) + require("./lib/math.js").calls;`)
+	defer os.RemoveAll(dir)
+	defer jsre.Stop(false)
+
+	if err := os.Mkdir(path.Join(dir, "lib"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	lib := `var calls = require("./counter"); exports.double = function(x) { return 2 * x; }; exports.calls = calls.increment();`
+	if err := ioutil.WriteFile(path.Join(dir, "lib", "math.js"), []byte(lib), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	counter := `var n = 0; module.exports = {increment: function() { return ++n; }};`
+	if err := ioutil.WriteFile(path.Join(dir, "lib", "counter.js"), []byte(counter), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsre.Exec("test.js"); err != nil {
+		t.Fatalf("failed to execute script: %v", err)
+	}
+	val, err := jsre.Run("result")
+	if err != nil {
+		t.Fatalf("failed to retrieve result: %v", err)
+	}
+	// Modules are only executed once, so the counter was only incremented once
+	if got, _ := val.ToInteger(); got != 43 {
+		t.Errorf("result mismatch: have %d, want 43", got)
+	}
+	if _, err := jsre.Run(`require("./missing")`); err == nil {
+		t.Errorf("expected error for missing module")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package supervisor
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Tests that state updates are delivered to the systemd notification socket and
+// that the watchdog interval is picked up from the environment.
+func TestNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supervisor-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to open notification socket: %v", err)
+	}
+	defer conn.Close()
+
+	// Without a socket, notifications should be silently dropped
+	os.Unsetenv("NOTIFY_SOCKET")
+	if err := notify("READY=1"); err != nil {
+		t.Fatalf("failed to skip notification: %v", err)
+	}
+	os.Setenv("NOTIFY_SOCKET", path)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	Ready()
+	Status("Syncing block %d of %d", 1, 2)
+
+	buf := make([]byte, 128)
+	for _, want := range []string{"READY=1", "STATUS=Syncing block 1 of 2"} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("failed to read notification: %v", err)
+		}
+		if have := string(buf[:n]); have != want {
+			t.Errorf("notification mismatch: have %q, want %q", have, want)
+		}
+	}
+	// Check the watchdog interval, which only applies to the targeted process
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Setenv("WATCHDOG_USEC", "3000000")
+	if interval := watchdogInterval(); interval != 3*time.Second {
+		t.Errorf("watchdog interval mismatch: have %v, want %v", interval, 3*time.Second)
+	}
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if interval := watchdogInterval(); interval != 0 {
+		t.Errorf("watchdog of other process enabled: have %v", interval)
+	}
+}
@@ -14,10 +14,20 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
 
-// +build !VERIFY_EVM_INTEGER_POOL
+//go:build !linux && !windows
+// +build !linux,!windows
 
-package vm
+package supervisor
 
-const verifyPool = false
+import "time"
 
-func verifyIntegerPool(ip *intPool) {}
+// notify does nothing, there is no supervisor integration on this platform.
+func notify(state string) error {
+	return nil
+}
+
+// watchdogInterval always returns zero, there is no supervisor watchdog on this
+// platform.
+func watchdogInterval() time.Duration {
+	return 0
+}
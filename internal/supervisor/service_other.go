@@ -0,0 +1,49 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package supervisor
+
+import "errors"
+
+var errNoServiceManager = errors.New("service management is only supported on Windows")
+
+// RunService always returns ErrNotService, services are only supported on Windows.
+func RunService(run func(stop <-chan struct{}) error) error {
+	return ErrNotService
+}
+
+// InstallService is only supported on Windows.
+func InstallService(name, description string, args []string) error {
+	return errNoServiceManager
+}
+
+// RemoveService is only supported on Windows.
+func RemoveService(name string) error {
+	return errNoServiceManager
+}
+
+// StartService is only supported on Windows.
+func StartService(name string) error {
+	return errNoServiceManager
+}
+
+// StopService is only supported on Windows.
+func StopService(name string) error {
+	return errNoServiceManager
+}
@@ -0,0 +1,303 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build windows
+// +build windows
+
+package supervisor
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	serviceWin32OwnProcess = 0x10
+	serviceAutoStart       = 2
+	serviceErrorNormal     = 1
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+
+	serviceAcceptStop     = 1
+	serviceAcceptShutdown = 4
+
+	scManagerAllAccess = 0xF003F
+	serviceAllAccess   = 0xF01FF
+
+	errCallNotImplemented             = 120
+	errServiceSpecificError           = 1066
+	errFailedServiceControllerConnect = syscall.Errno(1063)
+	pendingWaitHint                   = 60 * time.Second
+)
+
+var (
+	advapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procStartServiceCtrlDispatcher   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerEx = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus             = advapi32.NewProc("SetServiceStatus")
+	procOpenSCManager                = advapi32.NewProc("OpenSCManagerW")
+	procOpenService                  = advapi32.NewProc("OpenServiceW")
+	procCreateService                = advapi32.NewProc("CreateServiceW")
+	procDeleteService                = advapi32.NewProc("DeleteService")
+	procStartService                 = advapi32.NewProc("StartServiceW")
+	procControlService               = advapi32.NewProc("ControlService")
+	procCloseServiceHandle           = advapi32.NewProc("CloseServiceHandle")
+)
+
+// serviceStatus is the SERVICE_STATUS structure reported to the service control
+// manager.
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+// serviceTableEntry is the SERVICE_TABLE_ENTRY structure passed to the service
+// control dispatcher.
+type serviceTableEntry struct {
+	name *uint16
+	proc uintptr
+}
+
+// service is the state of the service run by this process.
+var service struct {
+	run  func(stop <-chan struct{}) error
+	stop chan struct{}
+	err  error
+
+	handle uintptr
+	status serviceStatus
+	lock   sync.Mutex // Protects the handle and status
+}
+
+// RunService connects the process to the service control manager and runs the
+// service until it returns. The stop channel passed to run is closed when the
+// service is asked to stop. If the process was not started as a service, the
+// function returns ErrNotService immediately.
+func RunService(run func(stop <-chan struct{}) error) error {
+	service.run = run
+	service.stop = make(chan struct{})
+
+	// The service name is ignored for services running in their own process
+	name, err := syscall.UTF16PtrFromString("")
+	if err != nil {
+		return err
+	}
+	table := []serviceTableEntry{{name, syscall.NewCallback(serviceMain)}, {nil, 0}}
+	if r, _, err := procStartServiceCtrlDispatcher.Call(uintptr(unsafe.Pointer(&table[0]))); r == 0 {
+		if err == errFailedServiceControllerConnect {
+			return ErrNotService
+		}
+		return err
+	}
+	return service.err
+}
+
+// serviceMain is the entry point of the service invoked by the dispatcher.
+func serviceMain(argc, argv uintptr) uintptr {
+	name, _ := syscall.UTF16PtrFromString("")
+	handle, _, err := procRegisterServiceCtrlHandlerEx.Call(uintptr(unsafe.Pointer(name)), syscall.NewCallback(serviceHandler), 0)
+	if handle == 0 {
+		service.err = err
+		return 0
+	}
+	service.lock.Lock()
+	service.handle = handle
+	setStatus(serviceStartPending)
+	service.lock.Unlock()
+
+	service.err = service.run(service.stop)
+
+	service.lock.Lock()
+	if service.err != nil {
+		service.status.Win32ExitCode = errServiceSpecificError
+		service.status.ServiceSpecificExitCode = 1
+	}
+	setStatus(serviceStopped)
+	service.lock.Unlock()
+
+	return 0
+}
+
+// serviceHandler handles the control requests sent to the service.
+func serviceHandler(ctrl, eventType, eventData, context uintptr) uintptr {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	switch ctrl {
+	case serviceControlStop, serviceControlShutdown:
+		if service.status.CurrentState != serviceStopPending {
+			setStatus(serviceStopPending)
+			close(service.stop)
+		}
+	case serviceControlInterrogate:
+		setStatus(service.status.CurrentState)
+	default:
+		return errCallNotImplemented
+	}
+	return 0
+}
+
+// setStatus reports a new state of the service to the service control manager.
+// The caller must hold the service lock.
+func setStatus(state uint32) error {
+	status := &service.status
+
+	status.ServiceType = serviceWin32OwnProcess
+	status.ControlsAccepted = 0
+	if state == serviceRunning {
+		status.ControlsAccepted = serviceAcceptStop | serviceAcceptShutdown
+	}
+	status.CheckPoint, status.WaitHint = 0, 0
+	if state == serviceStartPending || state == serviceStopPending {
+		if status.CurrentState == state {
+			status.CheckPoint++
+		}
+		status.WaitHint = uint32(pendingWaitHint / time.Millisecond)
+	}
+	status.CurrentState = state
+
+	if r, _, err := procSetServiceStatus.Call(service.handle, uintptr(unsafe.Pointer(status))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// notify maps the systemd style state updates to service states. It does nothing
+// if the process is not running as a service.
+func notify(state string) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if service.handle == 0 {
+		return nil
+	}
+	switch {
+	case state == "READY=1" && service.status.CurrentState == serviceStartPending:
+		return setStatus(serviceRunning)
+	case state == "STOPPING=1" && service.status.CurrentState == serviceRunning:
+		return setStatus(serviceStopPending)
+	}
+	return nil
+}
+
+// watchdogInterval always returns zero, the service control manager has no
+// watchdog.
+func watchdogInterval() time.Duration {
+	return 0
+}
+
+// InstallService registers the running executable as an automatically started
+// service, invoked with the given arguments.
+func InstallService(name, displayName string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := syscall.EscapeArg(exe)
+	for _, arg := range args {
+		cmd += " " + syscall.EscapeArg(arg)
+	}
+	return withService(name, func(scm uintptr) error {
+		namep, _ := syscall.UTF16PtrFromString(name)
+		displayp, _ := syscall.UTF16PtrFromString(displayName)
+		cmdp, _ := syscall.UTF16PtrFromString(cmd)
+
+		handle, _, err := procCreateService.Call(scm, uintptr(unsafe.Pointer(namep)), uintptr(unsafe.Pointer(displayp)),
+			serviceAllAccess, serviceWin32OwnProcess, serviceAutoStart, serviceErrorNormal,
+			uintptr(unsafe.Pointer(cmdp)), 0, 0, 0, 0, 0)
+		if handle == 0 {
+			return err
+		}
+		procCloseServiceHandle.Call(handle)
+		return nil
+	}, nil)
+}
+
+// RemoveService unregisters a service.
+func RemoveService(name string) error {
+	return withService(name, nil, func(handle uintptr) error {
+		if r, _, err := procDeleteService.Call(handle); r == 0 {
+			return err
+		}
+		return nil
+	})
+}
+
+// StartService asks the service control manager to start a service.
+func StartService(name string) error {
+	return withService(name, nil, func(handle uintptr) error {
+		if r, _, err := procStartService.Call(handle, 0, 0); r == 0 {
+			return err
+		}
+		return nil
+	})
+}
+
+// StopService asks the service control manager to stop a service.
+func StopService(name string) error {
+	return withService(name, nil, func(handle uintptr) error {
+		var status serviceStatus
+		if r, _, err := procControlService.Call(handle, serviceControlStop, uintptr(unsafe.Pointer(&status))); r == 0 {
+			return err
+		}
+		return nil
+	})
+}
+
+// withService connects to the service control manager and runs either the given
+// function on its handle, or the function on an opened handle of the service.
+func withService(name string, onManager func(scm uintptr) error, onService func(handle uintptr) error) error {
+	scm, _, err := procOpenSCManager.Call(0, 0, scManagerAllAccess)
+	if scm == 0 {
+		return err
+	}
+	defer procCloseServiceHandle.Call(scm)
+
+	if onManager != nil {
+		return onManager(scm)
+	}
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	handle, _, err := procOpenService.Call(scm, uintptr(unsafe.Pointer(namep)), serviceAllAccess)
+	if handle == 0 {
+		if err == syscall.Errno(1060) {
+			return errors.New("service " + name + " does not exist")
+		}
+		return err
+	}
+	defer procCloseServiceHandle.Call(handle)
+
+	return onService(handle)
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package supervisor integrates the node with process supervisors, reporting its
+// readiness and liveness to systemd on Linux and running it as a service of the
+// Windows service control manager.
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+)
+
+// ErrNotService is returned by RunService if the process was not started by the
+// service control manager.
+var ErrNotService = errors.New("not running as a service")
+
+// Ready notifies the supervisor that the node finished starting up.
+func Ready() {
+	if err := notify("READY=1"); err != nil {
+		log.Warn("Failed to notify supervisor of readiness", "err", err)
+	}
+}
+
+// Stopping notifies the supervisor that the node began shutting down.
+func Stopping() {
+	if err := notify("STOPPING=1"); err != nil {
+		log.Warn("Failed to notify supervisor of shutdown", "err", err)
+	}
+}
+
+// Status reports a free-form status of the node (e.g. sync progress) to the
+// supervisor.
+func Status(format string, args ...interface{}) {
+	if err := notify("STATUS=" + fmt.Sprintf(format, args...)); err != nil {
+		log.Debug("Failed to notify supervisor of status", "err", err)
+	}
+}
+
+// StartWatchdog keeps pinging the supervisor's watchdog as long as the health
+// check succeeds, until quit is closed. It does nothing if the supervisor did not
+// enable a watchdog.
+func StartWatchdog(check func(timeout time.Duration) error, quit <-chan struct{}) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+	log.Info("Supervisor watchdog enabled", "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := check(interval / 2); err != nil {
+					log.Warn("Health check failed, skipping watchdog ping", "err", err)
+					continue
+				}
+				if err := notify("WATCHDOG=1"); err != nil {
+					log.Warn("Failed to ping supervisor watchdog", "err", err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package version exposes the version control metadata stamped into the binary
+// at build time.
+//
+// The values are injected by build/ci.go through linker flags, for example
+//
+//	-X github.com/networkchain/networkchain/internal/version.gitCommit=<hash>
+//
+// The build date is the commit date rather than the wall clock, so that building
+// the same commit twice yields identical binaries.
+package version
+
+import (
+	"fmt"
+
+	"github.com/networkchain/networkchain/params"
+)
+
+var (
+	gitCommit = "" // Git SHA1 commit hash of the release
+	gitDate   = "" // Commit date of the release in YYYYMMDD format
+	gitDirty  = "" // Set to "true" if the tree had local modifications
+)
+
+// Info is the version control metadata of the running binary.
+type Info struct {
+	Version string `json:"version"`         // Release version from params
+	Commit  string `json:"commit"`          // Git commit hash, empty if unknown
+	Date    string `json:"date"`            // Commit date (YYYYMMDD), empty if unknown
+	Dirty   bool   `json:"dirty,omitempty"` // Whether the tree had uncommitted changes
+}
+
+// Get returns the version control metadata stamped into the running binary.
+func Get() Info {
+	return Info{
+		Version: params.Version,
+		Commit:  gitCommit,
+		Date:    gitDate,
+		Dirty:   gitDirty == "true",
+	}
+}
+
+// Commit returns the git commit hash the binary was built from.
+func Commit() string {
+	return gitCommit
+}
+
+// String returns the full version string, extending the release version with
+// the abbreviated commit hash, the commit date and a dirty marker if known.
+func (i Info) String() string {
+	vsn := params.VersionWithCommit(i.Commit)
+	if i.Commit != "" && i.Date != "" {
+		vsn += "-" + i.Date
+	}
+	if i.Dirty {
+		vsn += "-dirty"
+	}
+	return vsn
+}
+
+// Print writes the metadata in the format used by the version subcommands.
+func (i Info) Print() {
+	fmt.Println("Version:", i.Version)
+	if i.Commit != "" {
+		fmt.Println("Git Commit:", i.Commit)
+	}
+	if i.Date != "" {
+		fmt.Println("Git Commit Date:", i.Date)
+	}
+	if i.Dirty {
+		fmt.Println("Git Tree: dirty")
+	}
+}
@@ -13,43 +13,28 @@
 //
 // You should have received a copy of the GNU Lesser General Public License
 // along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
-
-package vm
-
-import "math/big"
-
-var checkVal = big.NewInt(-42)
-
-const poolLimit = 256
-
-// intPool is a pool of big integers that
-// can be reused for all big.Int operations.
-type intPool struct {
-	pool *Stack
-}
-
-func newIntPool() *intPool {
-	return &intPool{pool: newstack()}
-}
-
-func (p *intPool) get() *big.Int {
-	if p.pool.len() > 0 {
-		return p.pool.pop()
-	}
-	return new(big.Int)
-}
-func (p *intPool) put(is ...*big.Int) {
-	if len(p.pool.data) > poolLimit {
-		return
+package version
+
+import (
+	"testing"
+
+	"github.com/networkchain/networkchain/params"
+)
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{}, params.Version},
+		{Info{Date: "20170801"}, params.Version},
+		{Info{Commit: "1a2b3c4d5e6f"}, params.Version + "-1a2b3c4d"},
+		{Info{Commit: "1a2b3c4d5e6f", Date: "20170801"}, params.Version + "-1a2b3c4d-20170801"},
+		{Info{Commit: "1a2b3c4d5e6f", Date: "20170801", Dirty: true}, params.Version + "-1a2b3c4d-20170801-dirty"},
 	}
-
-	for _, i := range is {
-		// verifyPool is a build flag. Pool verification makes sure the integrity
-		// of the integer pool by comparing values to a default value.
-		if verifyPool {
-			i.Set(checkVal)
+	for i, tt := range tests {
+		if have := tt.info.String(); have != tt.want {
+			t.Errorf("test %d: version mismatch: have %q, want %q", i, have, tt.want)
 		}
-
-		p.pool.push(i)
 	}
 }
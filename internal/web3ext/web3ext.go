@@ -23,6 +23,7 @@ var Modules = map[string]string{
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
 	"eth":        Eth_JS,
+	"les":        Les_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -30,6 +31,7 @@ var Modules = map[string]string{
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
+	"webhooks":   Webhooks_JS,
 }
 
 const Chequebook_JS = `
@@ -177,6 +179,10 @@ web3._extend({
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
+		}),
+		new web3._extend.Property({
+			name: 'chainConfig',
+			getter: 'admin_chainConfig'
 		})
 	]
 });
@@ -238,6 +244,10 @@ web3._extend({
 			params: 1,
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'chaindbStats',
+			call: 'debug_chaindbStats',
+		}),
 		new web3._extend.Method({
 			name: 'chaindbCompact',
 			call: 'debug_chaindbCompact',
@@ -278,6 +288,26 @@ web3._extend({
 			call: 'debug_gcStats',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'setGCPercent',
+			call: 'debug_setGCPercent',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'freeOSMemory',
+			call: 'debug_freeOSMemory',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'setMaxThreads',
+			call: 'debug_setMaxThreads',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'purgeCaches',
+			call: 'debug_purgeCaches',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'cpuProfile',
 			call: 'debug_cpuProfile',
@@ -323,6 +353,31 @@ web3._extend({
 			call: 'debug_writeBlockProfile',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'mutexProfile',
+			call: 'debug_mutexProfile',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setMutexProfileFraction',
+			call: 'debug_setMutexProfileFraction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'writeMutexProfile',
+			call: 'debug_writeMutexProfile',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setRpcTraces',
+			call: 'debug_setRpcTraces',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'rpcTraces',
+			call: 'debug_rpcTraces',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'writeMemProfile',
 			call: 'debug_writeMemProfile',
@@ -334,6 +389,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -345,6 +406,18 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'accessStats',
+			call: 'debug_accessStats',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'executionWitness',
+			call: 'debug_executionWitness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -378,12 +451,23 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'signTransactions',
+			call: 'eth_signTransactions',
+			params: 1,
+			inputFormatter: [function(txs) { return txs.map(web3._extend.formatters.inputTransactionFormatter); }]
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'eth_submitTransaction',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendPrivateRawTransaction',
+			call: 'eth_sendPrivateRawTransaction',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'eth_getRawTransactionByHash',
@@ -396,6 +480,37 @@ web3._extend({
 			},
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getCodeByBlockHash',
+			call: 'eth_getCodeByBlockHash',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'pollFilterChanges',
+			call: 'eth_pollFilterChanges',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'pendingTransactionsFrom',
+			call: 'eth_pendingTransactions',
+			params: 1,
+			outputFormatter: function(txs) {
+				var formatted = [];
+				for (var i = 0; i < txs.length; i++) {
+					formatted.push(web3._extend.formatters.outputTransactionFormatter(txs[i]));
+					formatted[i].blockHash = null;
+				}
+				return formatted;
+			}
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
 		})
 	],
 	properties:
@@ -492,6 +607,11 @@ web3._extend({
 			call: 'personal_ecRecover',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'openWallet',
+			call: 'personal_openWallet',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'deriveAccount',
 			call: 'personal_deriveAccount',
@@ -669,6 +789,24 @@ web3._extend({
 });
 `
 
+const Les_JS = `
+web3._extend({
+	property: 'les',
+	methods: [],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'serverBudgets',
+			getter: 'les_serverBudgets'
+		}),
+		new web3._extend.Property({
+			name: 'relayStatus',
+			getter: 'les_relayStatus'
+		})
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
@@ -695,3 +833,29 @@ web3._extend({
 	]
 });
 `
+
+const Webhooks_JS = `
+web3._extend({
+	property: 'webhooks',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'register',
+			call: 'webhooks_register',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'unregister',
+			call: 'webhooks_unregister',
+			params: 1
+		})
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'list',
+			getter: 'webhooks_list'
+		}),
+	]
+});
+`
@@ -0,0 +1,150 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/light"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// requestNames maps the request message codes to the names used when reporting
+// the announced cost tables of servers.
+var requestNames = map[uint64]string{
+	GetBlockHeadersMsg: "getBlockHeaders",
+	GetBlockBodiesMsg:  "getBlockBodies",
+	GetReceiptsMsg:     "getReceipts",
+	GetProofsMsg:       "getProofs",
+	GetCodeMsg:         "getCode",
+	SendTxMsg:          "sendTx",
+	GetHeaderProofsMsg: "getHeaderProofs",
+	GetBloomBitsMsg:    "getBloomBits",
+}
+
+// PublicLesAPI provides an API to inspect the light client's view of the flow
+// control state of the servers it is connected to, and to follow the status of
+// locally submitted transactions.
+type PublicLesAPI struct {
+	peers    *peerSet
+	relay    *LesTxRelay
+	pool     *serverPool
+	eventMux *event.TypeMux
+}
+
+// NewPublicLesAPI creates a new LES client API.
+func NewPublicLesAPI(peers *peerSet, relay *LesTxRelay, pool *serverPool, eventMux *event.TypeMux) *PublicLesAPI {
+	return &PublicLesAPI{peers: peers, relay: relay, pool: pool, eventMux: eventMux}
+}
+
+// RequestCost is the cost of a request type announced by a server, charged as
+// the base cost plus the request cost for every requested item.
+type RequestCost struct {
+	BaseCost uint64 `json:"baseCost"`
+	ReqCost  uint64 `json:"reqCost"`
+}
+
+// ServerBudget is the flow control state of a server as estimated by the client.
+type ServerBudget struct {
+	ID          string                 `json:"id"`
+	BufLimit    uint64                 `json:"bufLimit"`    // Buffer size announced by the server
+	MinRecharge uint64                 `json:"minRecharge"` // Buffer recharge per millisecond
+	Remaining   uint64                 `json:"remaining"`   // Estimated buffer currently available
+	Reserve     uint64                 `json:"reserve"`     // Part of the buffer left unused to avoid rejections
+	Pending     int                    `json:"pending"`     // Requests awaiting a reply
+	RTT         uint64                 `json:"rtt"`         // Probed round trip time in milliseconds (0 = not probed)
+	Costs       map[string]RequestCost `json:"costs"`       // Announced request cost table
+}
+
+// ServerBudgets returns the estimated remaining request budget of every
+// connected server, along with the request costs announced by them.
+func (api *PublicLesAPI) ServerBudgets() []ServerBudget {
+	var budgets []ServerBudget
+	for _, p := range api.peers.AllPeers() {
+		if p.fcServer == nil {
+			continue
+		}
+		status := p.fcServer.Status()
+		budget := ServerBudget{
+			ID:          p.id,
+			BufLimit:    status.BufLimit,
+			MinRecharge: status.MinRecharge,
+			Remaining:   status.BufEstimate,
+			Reserve:     status.Reserve,
+			Pending:     status.Pending,
+			Costs:       make(map[string]RequestCost),
+		}
+		if api.pool != nil {
+			budget.RTT = uint64(api.pool.rtt(p.poolEntry) / time.Millisecond)
+		}
+		for code, costs := range p.fcCosts {
+			if name, ok := requestNames[code]; ok {
+				budget.Costs[name] = RequestCost{BaseCost: costs.baseCost, ReqCost: costs.reqCost}
+			}
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets
+}
+
+// RelayStatus returns the servers each pending transaction was relayed to, and
+// which of them acknowledged having accepted it.
+func (api *PublicLesAPI) RelayStatus() map[common.Hash]TxRelayStatus {
+	if api.relay == nil {
+		return nil
+	}
+	return api.relay.status()
+}
+
+// txStatusNotification is the payload of transaction status notifications.
+type txStatusNotification struct {
+	Hash   common.Hash    `json:"hash"`
+	Status light.TxStatus `json:"status"`
+}
+
+// TxStatus creates a subscription that is notified whenever a transaction sent
+// through the light client becomes pending, gets mined or is dropped.
+func (api *PublicLesAPI) TxStatus(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	statusSub := api.eventMux.Subscribe(light.TxStatusEvent{})
+
+	go func() {
+		defer statusSub.Unsubscribe()
+		for {
+			select {
+			case ev, ok := <-statusSub.Chan():
+				if !ok {
+					return
+				}
+				status := ev.Data.(light.TxStatusEvent)
+				notifier.Notify(rpcSub.ID, &txStatusNotification{Hash: status.Hash, Status: status.Status})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -18,9 +18,11 @@ package les
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/accounts/policy"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/core"
@@ -31,14 +33,19 @@ import (
 	"github.com/networkchain/networkchain/eth/gasprice"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
 	"github.com/networkchain/networkchain/light"
 	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rpc"
 )
 
 type LesApiBackend struct {
-	eth *LightNetworkChain
-	gpo *gasprice.Oracle
+	eth    *LightNetworkChain
+	gpo    *gasprice.Oracle
+	policy *policy.Policy // Restrictions on the signing RPC endpoints
+
+	safeDepth     uint64 // Confirmations resolving the "safe" block tag
+	finalityDepth uint64 // Confirmations resolving the "finalized" block tag
 }
 
 func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
@@ -58,6 +65,7 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	blockNr = ethapi.ResolveFinality(blockNr, b.eth.blockchain.CurrentHeader().Number.Uint64(), b.safeDepth, b.finalityDepth)
 
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
@@ -78,12 +86,20 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return light.NewState(ctx, header, b.eth.odr), header, nil
 }
 
+func (b *LesApiBackend) StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.eth.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	return light.NewState(ctx, header, b.eth.odr), header, nil
+}
+
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.eth.blockchain.GetBlockByHash(ctx, blockHash)
 }
 
 func (b *LesApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
-	return light.GetBlockReceipts(ctx, b.eth.odr, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash))
+	return light.GetBlockReceipts(ctx, b.eth.odr, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash), b.eth.chainConfig)
 }
 
 func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
@@ -100,6 +116,10 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return errors.New("private transactions are not supported by light clients")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.RemoveTx(txHash)
 }
@@ -108,6 +128,10 @@ func (b *LesApiBackend) GetPoolTransactions() (types.Transactions, error) {
 	return b.eth.txPool.GetTransactions()
 }
 
+func (b *LesApiBackend) GetPoolTransactionsFrom(addrs []common.Address) (types.Transactions, error) {
+	return b.eth.txPool.GetTransactionsFrom(addrs), nil
+}
+
 func (b *LesApiBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction {
 	return b.eth.txPool.GetTransaction(txHash)
 }
@@ -147,3 +171,7 @@ func (b *LesApiBackend) EventMux() *event.TypeMux {
 func (b *LesApiBackend) AccountManager() *accounts.Manager {
 	return b.eth.accountManager
 }
+
+func (b *LesApiBackend) SigningPolicy() *policy.Policy {
+	return b.policy
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/ethdb"
+)
+
+// Tests that the light client reports the flow control state and announced
+// request costs of the servers it is connected to.
+func TestServerBudgets(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	ldb, _ := ethdb.NewMemDatabase()
+
+	peers := newPeerSet()
+	dist := newRequestDistributor(peers, make(chan struct{}))
+	odr := NewLesOdr(ldb, newRetrieveManager(peers, dist, nil))
+
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	lpm := newTestProtocolManagerMust(t, true, 0, nil, peers, odr, ldb)
+
+	_, err1, lpeer, err2 := newTestPeerPair("peer", lpv2, pm, lpm)
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case err := <-err1:
+		t.Fatalf("peer 1 handshake error: %v", err)
+	case err := <-err2:
+		t.Fatalf("peer 2 handshake error: %v", err)
+	}
+	api := &PublicLesAPI{peers: peers}
+
+	budgets := api.ServerBudgets()
+	if len(budgets) != 1 {
+		t.Fatalf("server count mismatch: have %d, want 1", len(budgets))
+	}
+	budget := budgets[0]
+	if budget.ID != lpeer.id {
+		t.Errorf("server id mismatch: have %s, want %s", budget.ID, lpeer.id)
+	}
+	if budget.BufLimit != testBufLimit || budget.MinRecharge != 1 {
+		t.Errorf("buffer parameters mismatch: have %d/%d, want %d/%d", budget.BufLimit, budget.MinRecharge, testBufLimit, 1)
+	}
+	if budget.Remaining != testBufLimit || budget.Reserve != 0 || budget.Pending != 0 {
+		t.Errorf("idle budget mismatch: have remaining %d, reserve %d, pending %d", budget.Remaining, budget.Reserve, budget.Pending)
+	}
+	for _, code := range servedReqList {
+		name, ok := requestNames[code]
+		if !ok {
+			t.Errorf("served request %d unnamed", code)
+			continue
+		}
+		if _, ok := budget.Costs[name]; !ok {
+			t.Errorf("cost of %s missing", name)
+		}
+	}
+	// Requests awaiting a reply are reflected in the budget
+	lpeer.fcServer.QueueRequest(1, testBufLimit/2)
+
+	budget = api.ServerBudgets()[0]
+	if budget.Pending != 1 {
+		t.Errorf("pending requests mismatch: have %d, want 1", budget.Pending)
+	}
+	if budget.Remaining >= testBufLimit {
+		t.Errorf("queued request not deducted from the remaining budget: have %d", budget.Remaining)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/consensus"
+	"github.com/networkchain/networkchain/consensus/clique"
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/eth"
@@ -74,8 +75,12 @@ type LightNetworkChain struct {
 	wg       sync.WaitGroup
 }
 
+// DatabaseName is the name of the light chain database within the data directory
+// of the node.
+const DatabaseName = "lightchaindata"
+
 func New(ctx *node.ServiceContext, config *eth.Config) (*LightNetworkChain, error) {
-	chainDb, err := eth.CreateDB(ctx, config, "lightchaindata")
+	chainDb, err := eth.CreateDB(ctx, config, DatabaseName)
 	if err != nil {
 		return nil, err
 	}
@@ -85,13 +90,17 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightNetworkChain, erro
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	signingPolicy, err := eth.CreateSigningPolicy(ctx, config)
+	if err != nil {
+		return nil, err
+	}
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
 
 	eth := &LightNetworkChain{
 		chainConfig:    chainConfig,
 		chainDb:        chainDb,
-		eventMux:       ctx.EventMux,
+		eventMux:       new(event.TypeMux), // Owned by the service, as stopping it stops the mux
 		peers:          peers,
 		reqDist:        newRequestDistributor(peers, quitSync),
 		accountManager: ctx.AccountManager,
@@ -101,12 +110,13 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightNetworkChain, erro
 	}
 
 	eth.relay = NewLesTxRelay(peers, eth.reqDist)
-	eth.serverPool = newServerPool(chainDb, quitSync, &eth.wg)
+	eth.serverPool = newServerPool(chainDb, quitSync, &eth.wg, config.LightProbeLatency, config.LightPreferNets)
 	eth.retriever = newRetrieveManager(peers, eth.reqDist, eth.serverPool)
 	eth.odr = NewLesOdr(chainDb, eth.retriever)
 	if eth.blockchain, err = light.NewLightChain(eth.odr, eth.chainConfig, eth.engine, eth.eventMux); err != nil {
 		return nil, err
 	}
+	eth.blockchain.SetHeaderRetention(config.LightHeaderRetention)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -118,7 +128,7 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightNetworkChain, erro
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, true, config.NetworkId, eth.eventMux, eth.engine, eth.peers, eth.blockchain, nil, chainDb, eth.odr, eth.relay, quitSync, &eth.wg); err != nil {
 		return nil, err
 	}
-	eth.ApiBackend = &LesApiBackend{eth, nil}
+	eth.ApiBackend = &LesApiBackend{eth: eth, policy: signingPolicy, safeDepth: config.SafeDepth, finalityDepth: config.FinalityDepth}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.GasPrice
@@ -156,7 +166,15 @@ func (s *LightDummyAPI) Mining() bool {
 // APIs returns the collection of RPC services the networkchain package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *LightNetworkChain) APIs() []rpc.API {
-	return append(ethapi.GetAPIs(s.ApiBackend), []rpc.API{
+	apis := ethapi.GetAPIs(s.ApiBackend)
+
+	// Append the signer inspection APIs of the consensus engine. Light clients
+	// don't seal blocks, so the voting controls are left out.
+	if engine, ok := s.engine.(*clique.Clique); ok {
+		apis = append(apis, engine.SnapshotAPIs(s.BlockChain().HeaderChain())...)
+	}
+
+	return append(apis, []rpc.API{
 		{
 			Namespace: "eth",
 			Version:   "1.0",
@@ -177,6 +195,11 @@ func (s *LightNetworkChain) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLesAPI(s.peers, s.relay, s.serverPool, s.eventMux),
+			Public:    true,
 		},
 	}...)
 }
@@ -191,6 +214,7 @@ func (s *LightNetworkChain) Engine() consensus.Engine           { return s.engin
 func (s *LightNetworkChain) LesVersion() int                    { return int(s.protocolManager.SubProtocols[0].Version) }
 func (s *LightNetworkChain) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 func (s *LightNetworkChain) EventMux() *event.TypeMux           { return s.eventMux }
+func (s *LightNetworkChain) ChainDb() ethdb.Database            { return s.chainDb }
 
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
@@ -208,6 +232,14 @@ func (s *LightNetworkChain) Start(srvr *p2p.Server) error {
 	return nil
 }
 
+// Supervise requests the chain synchronisation and server pool routines to be run
+// through the given spawner (e.g. node.ServiceContext.Go), restarting the service
+// should they crash. It must be called before the service is started.
+func (s *LightNetworkChain) Supervise(spawn func(name string, routine func())) {
+	s.protocolManager.spawn = spawn
+	s.serverPool.spawn = spawn
+}
+
 // Stop implements node.Service, terminating all internal goroutines used by the
 // NetworkChain protocol.
 func (s *LightNetworkChain) Stop() error {
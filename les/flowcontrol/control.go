@@ -95,6 +95,7 @@ func (peer *ClientNode) RequestProcessed(cost uint64) (bv, realCost uint64) {
 
 type ServerNode struct {
 	bufEstimate uint64
+	reserve     uint64 // buffer kept unused after the server reported less than estimated
 	lastTime    mclock.AbsTime
 	params      *ServerParams
 	sumCost     uint64            // sum of req costs sent to this server
@@ -128,7 +129,7 @@ const safetyMargin = time.Millisecond
 
 func (peer *ServerNode) canSend(maxCost uint64) (time.Duration, float64) {
 	peer.recalcBLE(mclock.Now())
-	maxCost += uint64(safetyMargin) * peer.params.MinRecharge / uint64(fcTimeConst)
+	maxCost += uint64(safetyMargin)*peer.params.MinRecharge/uint64(fcTimeConst) + peer.reserve
 	if maxCost > peer.params.BufLimit {
 		maxCost = peer.params.BufLimit
 	}
@@ -163,7 +164,10 @@ func (peer *ServerNode) QueueRequest(reqID, maxCost uint64) {
 }
 
 // GotReply adjusts estimated buffer value according to the value included in
-// the latest request reply.
+// the latest request reply. If the server reports less buffer than estimated,
+// the shortfall is added to a reserve that subsequent requests leave untouched,
+// backing off before the server would start rejecting requests. The reserve is
+// halved with every reply confirming the estimate.
 func (peer *ServerNode) GotReply(reqID, bv uint64) {
 
 	peer.lock.Lock()
@@ -177,10 +181,45 @@ func (peer *ServerNode) GotReply(reqID, bv uint64) {
 		return
 	}
 	delete(peer.pending, reqID)
+
+	peer.recalcBLE(mclock.Now())
+	estimate := peer.bufEstimate
+
 	cc := peer.sumCost - sc
 	peer.bufEstimate = 0
 	if bv > cc {
 		peer.bufEstimate = bv - cc
 	}
-	peer.lastTime = mclock.Now()
+	if peer.bufEstimate < estimate {
+		peer.reserve += estimate - peer.bufEstimate
+		if peer.reserve > peer.params.BufLimit/2 {
+			peer.reserve = peer.params.BufLimit / 2
+		}
+	} else {
+		peer.reserve /= 2
+	}
+}
+
+// ServerStatus is a snapshot of the client side flow control state of a server.
+type ServerStatus struct {
+	BufLimit    uint64 // Buffer size announced by the server
+	MinRecharge uint64 // Buffer recharge rate announced by the server
+	BufEstimate uint64 // Estimated current buffer value
+	Reserve     uint64 // Part of the buffer kept unused
+	Pending     int    // Number of requests awaiting a reply
+}
+
+// Status returns the current flow control state of the server.
+func (peer *ServerNode) Status() ServerStatus {
+	peer.lock.Lock()
+	defer peer.lock.Unlock()
+
+	peer.recalcBLE(mclock.Now())
+	return ServerStatus{
+		BufLimit:    peer.params.BufLimit,
+		MinRecharge: peer.params.MinRecharge,
+		BufEstimate: peer.bufEstimate,
+		Reserve:     peer.reserve,
+		Pending:     len(peer.pending),
+	}
 }
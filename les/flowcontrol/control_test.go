@@ -0,0 +1,118 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package flowcontrol
+
+import (
+	"testing"
+)
+
+// Buffer parameters of the test server. The buffer recharges by a single unit
+// per millisecond, making the recharge negligible compared to the request costs
+// for the duration of a test.
+const (
+	testBufLimit    = 1000000
+	testMinRecharge = 1
+	testSlack       = 1000 // tolerated recharge during a test
+)
+
+// checkReserve verifies that the reserve of a server node is within the recharge
+// slack of the expected value.
+func checkReserve(t *testing.T, node *ServerNode, want uint64) {
+	reserve := node.Status().Reserve
+	if reserve < want || reserve > want+testSlack {
+		t.Fatalf("reserve mismatch: have %d, want %d (+%d)", reserve, want, testSlack)
+	}
+}
+
+// Tests that buffer shortfalls reported by the server are accumulated in the
+// reserve, and that the reserve is left untouched by subsequent requests.
+func TestServerNodeReserveGrowth(t *testing.T) {
+	node := NewServerNode(&ServerParams{BufLimit: testBufLimit, MinRecharge: testMinRecharge})
+
+	// Reply with a buffer 100K short of the estimated one
+	node.QueueRequest(1, 100000)
+	node.GotReply(1, 800000)
+	checkReserve(t, node, 100000)
+
+	// A request fitting into the buffer but not into the buffer minus the reserve
+	// must wait, while a smaller one doesn't
+	if wait, _ := node.CanSend(750000); wait == 0 {
+		t.Errorf("request eating into the reserve allowed without waiting")
+	}
+	if wait, level := node.CanSend(600000); wait != 0 {
+		t.Errorf("request outside of the reserve delayed by %v", wait)
+	} else if level > 0.11 {
+		t.Errorf("relative buffer level mismatch: have %f, want about %f", level, 0.1)
+	}
+	// Another shortfall grows the reserve further
+	node.QueueRequest(2, 100000)
+	node.GotReply(2, 600000)
+	checkReserve(t, node, 200000)
+
+	// Shortfalls of replies to overlapping requests are measured against the
+	// costs of the requests sent after them
+	node.QueueRequest(3, 100000)
+	node.QueueRequest(4, 100000)
+	node.GotReply(3, 450000) // estimate 400K, server has 350K after request 4
+	checkReserve(t, node, 250000)
+}
+
+// Tests that the reserve is capped at half of the buffer limit, and that requests
+// are never asked to wait for more than the full buffer.
+func TestServerNodeReserveCap(t *testing.T) {
+	node := NewServerNode(&ServerParams{BufLimit: testBufLimit, MinRecharge: testMinRecharge})
+
+	for i := uint64(0); i < 10; i++ {
+		node.QueueRequest(i, 200000)
+		node.GotReply(i, 0)
+	}
+	checkReserve(t, node, testBufLimit/2)
+
+	// With the reserve at its cap, the largest request waits for a full buffer
+	// only, the time needed to recharge it from empty
+	if wait, _ := node.CanSend(testBufLimit); wait > testBufLimit*fcTimeConst/testMinRecharge {
+		t.Errorf("wait time exceeds full recharge: have %v", wait)
+	}
+}
+
+// Tests that the reserve is halved with every reply confirming the estimated
+// buffer, and that replies to unknown requests are ignored.
+func TestServerNodeReserveDecay(t *testing.T) {
+	node := NewServerNode(&ServerParams{BufLimit: testBufLimit, MinRecharge: testMinRecharge})
+
+	node.QueueRequest(1, 400000)
+	node.GotReply(1, 200000)
+	checkReserve(t, node, 400000)
+
+	// Unknown and duplicate replies don't affect the reserve
+	node.GotReply(1, testBufLimit)
+	node.GotReply(100, testBufLimit)
+	checkReserve(t, node, 400000)
+
+	// Replies at or above the estimate halve it
+	for i, want := range []uint64{200000, 100000, 50000, 25000} {
+		node.QueueRequest(uint64(10+i), 1000)
+		node.GotReply(uint64(10+i), testBufLimit)
+		checkReserve(t, node, want)
+	}
+	if status := node.Status(); status.BufEstimate+testSlack < testBufLimit-1000 {
+		t.Errorf("buffer estimate not restored: have %d, want %d", status.BufEstimate, testBufLimit-1000)
+	}
+	if status := node.Status(); status.Pending != 0 {
+		t.Errorf("pending requests mismatch: have %d, want 0", status.Pending)
+	}
+}
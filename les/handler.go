@@ -32,9 +32,11 @@ import (
 	"github.com/networkchain/networkchain/core/state"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/eth"
+	"github.com/networkchain/networkchain/eth/chainaccess"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/light"
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
@@ -56,6 +58,7 @@ const (
 	MaxCodeFetch         = 64  // Amount of contract codes to allow fetching per request
 	MaxProofsFetch       = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxHeaderProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
+	MaxBloomBitsFetch    = 64  // Amount of bloom bits vectors to be fetched per retrieval request
 	MaxTxSend            = 64  // Amount of transactions to be send per request
 
 	disableClientRemovePeer = false
@@ -89,6 +92,9 @@ type BlockChain interface {
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) error
+
+	// Get should return a transaction if it is contained in the pool.
+	Get(hash common.Hash) *types.Transaction
 }
 
 type ProtocolManager struct {
@@ -98,6 +104,7 @@ type ProtocolManager struct {
 	networkId   uint64
 	chainConfig *params.ChainConfig
 	blockchain  BlockChain
+	chain       chainaccess.Backend // Chain data served to clients, nil on light clients
 	chainDb     ethdb.Database
 	odr         *LesOdr
 	server      *LesServer
@@ -123,9 +130,14 @@ type ProtocolManager struct {
 	syncing  bool
 	syncDone chan struct{}
 
+	indexPaused int32         // Whether the CHT and bloom trie generation is suspended (atomic)
+	indexResume chan struct{} // Channel to restart the generation once resumed
+
 	// wait group is used for graceful shutdowns during downloading
 	// and processing
 	wg *sync.WaitGroup
+
+	spawn func(name string, routine func()) // Spawner of the long lived routines
 }
 
 // NewProtocolManager returns a new networkchain sub protocol manager. The NetworkChain sub protocol manages peers capable
@@ -146,7 +158,12 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, network
 		newPeerCh:   make(chan *peer),
 		quitSync:    quitSync,
 		wg:          wg,
-		noMorePeers: make(chan struct{}),
+		noMorePeers: make(chan struct{}, 1), // Buffered so stopping doesn't depend on a live syncer
+		indexResume: make(chan struct{}, 1),
+		spawn:       func(name string, routine func()) { go routine() },
+	}
+	if backend, ok := blockchain.(chainaccess.Backend); ok {
+		manager.chain = backend
 	}
 	if odr != nil {
 		manager.retriever = odr.retriever
@@ -173,11 +190,10 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, network
 				case manager.newPeerCh <- peer:
 					manager.wg.Add(1)
 					defer manager.wg.Done()
-					err := manager.handle(peer)
 					if entry != nil {
-						manager.serverPool.disconnect(entry)
+						defer manager.serverPool.disconnect(entry)
 					}
-					return err
+					return manager.handle(peer)
 				case <-manager.quitSync:
 					if entry != nil {
 						manager.serverPool.disconnect(entry)
@@ -221,7 +237,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 func (pm *ProtocolManager) Start() {
 	if pm.lightSync {
-		go pm.syncer()
+		pm.spawn("les/syncer", pm.syncer)
 	} else {
 		go func() {
 			for range pm.newPeerCh {
@@ -320,8 +336,17 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
+// reqList contains the requests every server must announce the costs of.
 var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg}
 
+// optReqList contains the requests added after the initial protocol release.
+// Servers announce their costs alongside reqList, clients only send them to
+// servers which did.
+var optReqList = []uint64{GetBloomBitsMsg}
+
+// servedReqList contains all the requests a server announces the costs of.
+var servedReqList = append(append([]uint64{}, reqList...), optReqList...)
+
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
 func (pm *ProtocolManager) handleMsg(p *peer) error {
@@ -392,68 +417,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if reject(query.Amount, MaxHeaderFetch) {
 			return errResp(ErrRequestRejected, "")
 		}
-
-		hashMode := query.Origin.Hash != (common.Hash{})
-
 		// Gather headers until the fetch or network limits is reached
-		var (
-			bytes   common.StorageSize
-			headers []*types.Header
-			unknown bool
-		)
-		for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit {
-			// Retrieve the next header satisfying the query
-			var origin *types.Header
-			if hashMode {
-				origin = pm.blockchain.GetHeaderByHash(query.Origin.Hash)
-			} else {
-				origin = pm.blockchain.GetHeaderByNumber(query.Origin.Number)
-			}
-			if origin == nil {
-				break
-			}
-			number := origin.Number.Uint64()
-			headers = append(headers, origin)
-			bytes += estHeaderRlpSize
-
-			// Advance to the next header of the query
-			switch {
-			case query.Origin.Hash != (common.Hash{}) && query.Reverse:
-				// Hash based traversal towards the genesis block
-				for i := 0; i < int(query.Skip)+1; i++ {
-					if header := pm.blockchain.GetHeader(query.Origin.Hash, number); header != nil {
-						query.Origin.Hash = header.ParentHash
-						number--
-					} else {
-						unknown = true
-						break
-					}
-				}
-			case query.Origin.Hash != (common.Hash{}) && !query.Reverse:
-				// Hash based traversal towards the leaf block
-				if header := pm.blockchain.GetHeaderByNumber(origin.Number.Uint64() + query.Skip + 1); header != nil {
-					if pm.blockchain.GetBlockHashesFromHash(header.Hash(), query.Skip+1)[query.Skip] == query.Origin.Hash {
-						query.Origin.Hash = header.Hash()
-					} else {
-						unknown = true
-					}
-				} else {
-					unknown = true
-				}
-			case query.Reverse:
-				// Number based traversal towards the genesis block
-				if query.Origin.Number >= query.Skip+1 {
-					query.Origin.Number -= (query.Skip + 1)
-				} else {
-					unknown = true
-				}
-
-			case !query.Reverse:
-				// Number based traversal towards the leaf block
-				query.Origin.Number += (query.Skip + 1)
-			}
+		headers, err := chainaccess.Headers(pm.blockchain, query.headerQuery(), MaxHeaderFetch)
+		if err == chainaccess.ErrSkipOverflow {
+			p.Log().Warn("GetBlockHeaders skip overflow attack", "skip", query.Skip)
 		}
-
 		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + query.Amount*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, query.Amount, rcost)
 		return p.SendBlockHeaders(req.ReqID, bv, headers)
@@ -492,25 +460,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Gather blocks until the fetch or network limits is reached
-		var (
-			bytes  int
-			bodies []rlp.RawValue
-		)
 		reqCnt := len(req.Hashes)
 		if reject(uint64(reqCnt), MaxBodyFetch) {
 			return errResp(ErrRequestRejected, "")
 		}
-		for _, hash := range req.Hashes {
-			if bytes >= softResponseLimit {
-				break
-			}
-			// Retrieve the requested block body, stopping if enough was found
-			if data := core.GetBodyRLP(pm.chainDb, hash, core.GetBlockNumber(pm.chainDb, hash)); len(data) != 0 {
-				bodies = append(bodies, data)
-				bytes += len(data)
-			}
-		}
+		// Gather blocks until the fetch or network limits is reached
+		bodies := chainaccess.BodiesRLP(pm.chain, req.Hashes)
 		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendBlockBodiesRLP(req.ReqID, bv, bodies)
@@ -607,34 +562,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Gather state data until the fetch or network limits is reached
-		var (
-			bytes    int
-			receipts []rlp.RawValue
-		)
 		reqCnt := len(req.Hashes)
 		if reject(uint64(reqCnt), MaxReceiptFetch) {
 			return errResp(ErrRequestRejected, "")
 		}
-		for _, hash := range req.Hashes {
-			if bytes >= softResponseLimit {
-				break
-			}
-			// Retrieve the requested block's receipts, skipping if unknown to us
-			results := core.GetBlockReceipts(pm.chainDb, hash, core.GetBlockNumber(pm.chainDb, hash))
-			if results == nil {
-				if header := pm.blockchain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
-					continue
-				}
-			}
-			// If known, encode and queue for response packet
-			if encoded, err := rlp.EncodeToBytes(results); err != nil {
-				log.Error("Failed to encode receipt", "err", err)
-			} else {
-				receipts = append(receipts, encoded)
-				bytes += len(encoded)
-			}
-		}
+		// Gather receipts until the fetch or network limits is reached
+		receipts := chainaccess.ReceiptsRLP(pm.chain, req.Hashes)
 		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
 		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
 		return p.SendReceiptsRLP(req.ReqID, bv, receipts)
@@ -787,13 +720,97 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			Obj:     resp.Data,
 		}
 
-	case SendTxMsg:
+	case GetBloomBitsMsg:
+		p.Log().Trace("Received bloom bits request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID uint64
+			Reqs  []BloomReq
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Gather the bloom trie proofs until the fetch or network limits is reached
+		var (
+			bytes  int
+			proofs [][]rlp.RawValue
+		)
+		reqCnt := len(req.Reqs)
+		if reject(uint64(reqCnt), MaxBloomBitsFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		var (
+			lastTrieNum uint64
+			tr          *trie.Trie
+		)
+		for _, req := range req.Reqs {
+			if bytes >= softResponseLimit {
+				break
+			}
+			// Proofs must be positional, reply with an empty one if unavailable
+			var proof []rlp.RawValue
+			if req.Bit < light.BloomBitLength && req.Section < req.BloomTrieNum {
+				if tr == nil || req.BloomTrieNum != lastTrieNum {
+					tr, lastTrieNum = nil, req.BloomTrieNum
+					if root := getBloomTrieRoot(pm.chainDb, req.BloomTrieNum); root != (common.Hash{}) {
+						tr, _ = trie.New(root, pm.chainDb)
+					}
+				}
+				if tr != nil {
+					proof = tr.Prove(light.BloomTrieKey(uint(req.Bit), req.Section))
+				}
+			}
+			proofs = append(proofs, proof)
+			for _, node := range proof {
+				bytes += len(node)
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendBloomBits(req.ReqID, bv, proofs)
+
+	case BloomBitsMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received bloom bits response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      [][]rlp.RawValue
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgBloomBits,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
+	case SendTxMsg, SendTxV2Msg:
 		if pm.txpool == nil {
 			return errResp(ErrUnexpectedResponse, "")
 		}
 		// Transactions arrived, parse all of them and deliver to the pool
-		var txs []*types.Transaction
-		if err := msg.Decode(&txs); err != nil {
+		var (
+			reqID uint64
+			txs   []*types.Transaction
+		)
+		if msg.Code == SendTxV2Msg {
+			var req struct {
+				ReqID uint64
+				Txs   []*types.Transaction
+			}
+			if err := msg.Decode(&req); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			reqID, txs = req.ReqID, req.Txs
+
+			// Both versions of the message share the announced cost
+			costs = p.fcCosts[SendTxMsg]
+		} else if err := msg.Decode(&txs); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		reqCnt := len(txs)
@@ -805,8 +822,34 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrUnexpectedResponse, "msg: %v", err)
 		}
 
-		_, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
-		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(SendTxMsg, uint64(reqCnt), rcost)
+
+		if msg.Code == SendTxV2Msg {
+			accepted := make([]common.Hash, 0, len(txs))
+			for _, tx := range txs {
+				if pm.txpool.Get(tx.Hash()) != nil {
+					accepted = append(accepted, tx.Hash())
+				}
+			}
+			return p.SendTxStatus(reqID, bv, accepted)
+		}
+
+	case TxStatusMsg:
+		if pm.txrelay == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received transaction status response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      []common.Hash
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		pm.txrelay.acknowledge(p, resp.Data)
 
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
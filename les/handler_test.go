@@ -17,6 +17,8 @@
 package les
 
 import (
+	"bytes"
+	"math/big"
 	"math/rand"
 	"testing"
 
@@ -26,7 +28,9 @@ import (
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/light"
 	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rlp"
 	"github.com/networkchain/networkchain/trie"
 )
@@ -342,3 +346,99 @@ func testGetProofs(t *testing.T, protocol int) {
 		t.Errorf("proofs mismatch: %v", err)
 	}
 }
+
+// Tests that les/2 servers acknowledge the relayed transactions they accepted.
+func TestTransactionStatusLes2(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 0, testChainGen, nil, nil, db)
+	chain := pm.blockchain.(*core.BlockChain)
+	txpool := core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, pm.eventMux, chain.State, chain.GasLimit)
+	defer txpool.Stop()
+	pm.txpool = txpool
+
+	peer, _ := newTestPeer(t, "peer", lpv2, pm, true)
+	defer peer.close()
+
+	// Relay a funded and an unfunded transaction, only the first is accepted
+	funded, _ := types.SignTx(types.NewTransaction(0, acc1Addr, big.NewInt(10000), bigTxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testBankKey)
+	unfunded, _ := types.SignTx(types.NewTransaction(0, testBankAddress, big.NewInt(10000), bigTxGas, big.NewInt(1), nil), types.HomesteadSigner{}, acc1Key)
+	txs := types.Transactions{funded, unfunded}
+
+	cost := peer.GetRequestCost(SendTxMsg, len(txs))
+	sendRequest(peer.app, SendTxV2Msg, 42, cost, txs)
+	if err := expectResponse(peer.app, TxStatusMsg, 42, testBufLimit, []common.Hash{funded.Hash()}); err != nil {
+		t.Errorf("transaction status mismatch: %v", err)
+	}
+}
+
+// Tests that bloom bits vectors can be retrieved from les/2 servers along with
+// their bloom trie proofs, and that the proofs validate on the client side.
+func TestGetBloomBitsLes2(t *testing.T) {
+	defer func(frequency, confirmations uint64) {
+		light.BloomTrieFrequency, light.BloomTrieConfirmations = frequency, confirmations
+	}(light.BloomTrieFrequency, light.BloomTrieConfirmations)
+	light.BloomTrieFrequency, light.BloomTrieConfirmations = 8, 0
+
+	// Assemble the test environment with two sections of blocks indexed, each
+	// block emitting a log from a newly created contract
+	logger := func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewContractCreation(block.TxNonce(testBankAddress), big.NewInt(0), big.NewInt(100000), big.NewInt(0), common.Hex2Bytes("60006000a0")), types.HomesteadSigner{}, testBankKey)
+		block.AddTx(tx)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 16, logger, nil, nil, db)
+	bc := pm.blockchain.(*core.BlockChain)
+	for makeBloomTrie(db) {
+	}
+	root := getBloomTrieRoot(db, 2)
+	if root == (common.Hash{}) {
+		t.Fatalf("bloom trie not generated")
+	}
+	peer, _ := newTestPeer(t, "peer", lpv2, pm, true)
+	defer peer.close()
+
+	// Request a bit set in the chain (if any) and an unset one in both sections
+	var (
+		reqs    []BloomReq
+		proofs  [][]rlp.RawValue
+		vectors [][]byte
+	)
+	tr, _ := trie.New(root, db)
+	for section := uint64(0); section < 2; section++ {
+		blooms := make([]types.Bloom, light.BloomTrieFrequency)
+		for i := range blooms {
+			blooms[i] = bc.GetHeaderByNumber(section*light.BloomTrieFrequency + uint64(i)).Bloom
+		}
+		bits := []uint{0}
+		for bit := uint(0); bit < light.BloomBitLength; bit++ {
+			if vector := light.BloomBitsVector(blooms, bit); vector[0] != 0 {
+				bits = append(bits, bit)
+				break
+			}
+		}
+		for _, bit := range bits {
+			reqs = append(reqs, BloomReq{BloomTrieNum: 2, Bit: uint64(bit), Section: section})
+			proofs = append(proofs, tr.Prove(light.BloomTrieKey(bit, section)))
+			vectors = append(vectors, light.BloomBitsVector(blooms, bit))
+		}
+	}
+	if len(reqs) != 4 {
+		t.Fatalf("bloom bits missing from the test chain: have %d requests, want %d", len(reqs), 4)
+	}
+	// Send the bloom bits request and verify the response
+	cost := peer.GetRequestCost(GetBloomBitsMsg, len(reqs))
+	sendRequest(peer.app, GetBloomBitsMsg, 42, cost, reqs)
+	if err := expectResponse(peer.app, BloomBitsMsg, 42, testBufLimit, proofs); err != nil {
+		t.Fatalf("bloom bits proofs mismatch: %v", err)
+	}
+	// Validate the proofs as a light client would
+	for i, req := range reqs {
+		r := &BloomRequest{BloomTrieNum: 2, BloomTrieRoot: root, Bit: uint(req.Bit), Section: req.Section}
+		if err := r.Validate(db, &Msg{MsgType: MsgBloomBits, Obj: [][]rlp.RawValue{proofs[i]}}); err != nil {
+			t.Fatalf("request %d: proof validation failed: %v", i, err)
+		}
+		if !bytes.Equal(r.Vector, vectors[i]) {
+			t.Errorf("request %d: vector mismatch: have %x, want %x", i, r.Vector, vectors[i])
+		}
+	}
+}
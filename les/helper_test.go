@@ -119,8 +119,8 @@ func testChainGen(i int, block *core.BlockGen) {
 }
 
 func testRCL() RequestCostList {
-	cl := make(RequestCostList, len(reqList))
-	for i, code := range reqList {
+	cl := make(RequestCostList, len(servedReqList))
+	for i, code := range servedReqList {
 		cl[i].MsgCode = code
 		cl[i].BaseCost = 0
 		cl[i].ReqCost = 0
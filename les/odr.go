@@ -18,10 +18,11 @@ package les
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/light"
-	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rpc"
 )
 
 // LesOdr implements light.OdrBackend
@@ -53,6 +54,7 @@ const (
 	MsgReceipts
 	MsgProofs
 	MsgHeaderProofs
+	MsgBloomBits
 )
 
 // Msg encodes a LES message that delivers reply data for a request
@@ -68,6 +70,10 @@ func (self *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err err
 	lreq := LesRequest(req)
 
 	reqID := genReqID()
+	trace := rpc.TraceFromContext(ctx)
+	if trace != nil {
+		trace.Log("Retrieving data from network", "type", fmt.Sprintf("%T", req), "reqid", reqID)
+	}
 	rq := &distReq{
 		getCost: func(dp distPeer) uint64 {
 			return lreq.GetCost(dp.(*peer))
@@ -87,8 +93,11 @@ func (self *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err err
 	if err = self.retriever.retrieve(ctx, reqID, rq, func(p distPeer, msg *Msg) error { return lreq.Validate(self.db, msg) }); err == nil {
 		// retrieved from network, store in db
 		req.StoreResult(self.db)
+		if trace != nil {
+			trace.Log("Retrieved data from network", "reqid", reqID)
+		}
 	} else {
-		log.Debug("Failed to retrieve data from network", "err", err)
+		trace.Log("Failed to retrieve data from network", "reqid", reqID, "err", err)
 	}
 	return
 }
@@ -43,6 +43,7 @@ var (
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
+	errBloomBitsLength     = errors.New("bloom bits length mismatch")
 )
 
 type LesOdrRequest interface {
@@ -64,6 +65,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
+	case *light.BloomRequest:
+		return (*BloomRequest)(r)
 	default:
 		return nil
 	}
@@ -354,3 +357,78 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 
 	return nil
 }
+
+type BloomReq struct {
+	BloomTrieNum, Bit, Section uint64
+}
+
+// ODR request type for requesting bloom bits vectors by bloom trie, see LesOdrRequest interface
+type BloomRequest light.BloomRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *BloomRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetBloomBitsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *BloomRequest) CanSend(peer *peer) bool {
+	if !peer.ServesBloomBits() {
+		return false
+	}
+	peer.lock.RLock()
+	defer peer.lock.RUnlock()
+
+	if peer.headInfo.Number < light.BloomTrieConfirmations {
+		return false
+	}
+	return r.BloomTrieNum <= (peer.headInfo.Number-light.BloomTrieConfirmations)/light.BloomTrieFrequency
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *BloomRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting bloom bits", "bloomTrie", r.BloomTrieNum, "bit", r.Bit, "section", r.Section)
+	req := &BloomReq{
+		BloomTrieNum: r.BloomTrieNum,
+		Bit:          uint64(r.Bit),
+		Section:      r.Section,
+	}
+	return peer.RequestBloomBits(reqID, r.GetCost(peer), []*BloomReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *BloomRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating bloom bits", "bloomTrie", r.BloomTrieNum, "bit", r.Bit, "section", r.Section)
+
+	// Ensure we have a correct message with a single proof element
+	if msg.MsgType != MsgBloomBits {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.([][]rlp.RawValue)
+	if len(proofs) != 1 {
+		return errMultipleEntries
+	}
+	proof := proofs[0]
+
+	// Verify the vector against the bloom trie, absent entries are all zero
+	var value []byte
+	if r.BloomTrieRoot != types.EmptyRootHash || len(proof) != 0 {
+		var err error
+		if value, err = trie.VerifyProof(r.BloomTrieRoot, light.BloomTrieKey(r.Bit, r.Section), proof); err != nil {
+			return err
+		}
+	}
+	if value == nil {
+		value = make([]byte, light.BloomTrieFrequency/8)
+	}
+	if len(value) != int(light.BloomTrieFrequency/8) {
+		return errBloomBitsLength
+	}
+	// Verifications passed, store and return
+	r.Vector = value
+	r.Proof = proof
+
+	return nil
+}
@@ -256,11 +256,43 @@ func (p *peer) RequestHeaderProofs(reqID, cost uint64, reqs []*ChtReq) error {
 	return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqs)
 }
 
+// SendBloomBits sends a batch of bloom trie proofs of bloom bits vectors,
+// corresponding to the ones requested.
+func (p *peer) SendBloomBits(reqID, bv uint64, proofs [][]rlp.RawValue) error {
+	return sendResponse(p.rw, BloomBitsMsg, reqID, bv, proofs)
+}
+
+// RequestBloomBits fetches a batch of bloom bits vectors from a remote node.
+func (p *peer) RequestBloomBits(reqID, cost uint64, reqs []*BloomReq) error {
+	p.Log().Debug("Fetching batch of bloom bits", "count", len(reqs))
+	return sendRequest(p.rw, GetBloomBitsMsg, reqID, cost, reqs)
+}
+
+// ServesBloomBits reports whether the remote server can be asked for bloom bits:
+// the requests are part of les/2 and only servers supporting them announce their
+// costs.
+func (p *peer) ServesBloomBits() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.version >= lpv2 && p.fcCosts[GetBloomBitsMsg] != nil
+}
+
+// SendTxs sends a batch of transactions to be relayed by the server. Servers
+// speaking les/2 acknowledge the accepted ones in a reply to the request.
 func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
-	p.Log().Debug("Fetching batch of transactions", "count", len(txs))
+	p.Log().Debug("Sending batch of transactions", "count", len(txs))
+	if p.version >= lpv2 {
+		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
+	}
 	return p2p.Send(p.rw, SendTxMsg, txs)
 }
 
+// SendTxStatus acknowledges the transactions of a request accepted into the pool.
+func (p *peer) SendTxStatus(reqID, bv uint64, accepted []common.Hash) error {
+	return sendResponse(p.rw, TxStatusMsg, reqID, bv, accepted)
+}
+
 type keyValueEntry struct {
 	Key   string
 	Value rlp.RawValue
@@ -413,13 +445,22 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if err := recv.get("flowControl/MRR", &params.MinRecharge); err != nil {
 			return err
 		}
+		if params.MinRecharge == 0 {
+			return errResp(ErrUselessPeer, "zero buffer recharge rate")
+		}
 		var MRC RequestCostList
 		if err := recv.get("flowControl/MRC", &MRC); err != nil {
 			return err
 		}
+		costs := MRC.decode()
+		for _, code := range reqList {
+			if costs[code] == nil {
+				return errResp(ErrUselessPeer, "no cost announced for message %d", code)
+			}
+		}
 		p.fcServerParams = params
 		p.fcServer = flowcontrol.NewServerNode(params)
-		p.fcCosts = MRC.decode()
+		p.fcCosts = costs
 	}
 
 	p.headInfo = &announceData{Td: rTd, Hash: rHash, Number: rNum}
@@ -24,19 +24,21 @@ import (
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/eth/chainaccess"
 	"github.com/networkchain/networkchain/rlp"
 )
 
 // Constants to match up protocol versions and messages
 const (
 	lpv1 = 1
+	lpv2 = 2
 )
 
 // Supported versions of the les protocol (first is primary).
-var ProtocolVersions = []uint{lpv1}
+var ProtocolVersions = []uint{lpv2, lpv1}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{15}
+var ProtocolLengths = []uint64{19, 15}
 
 const (
 	NetworkId          = 1
@@ -61,6 +63,11 @@ const (
 	SendTxMsg          = 0x0c
 	GetHeaderProofsMsg = 0x0d
 	HeaderProofsMsg    = 0x0e
+	// Protocol messages belonging to LPV2
+	SendTxV2Msg     = 0x0f
+	TxStatusMsg     = 0x10
+	GetBloomBitsMsg = 0x11
+	BloomBitsMsg    = 0x12
 )
 
 type errCode int
@@ -145,6 +152,17 @@ type getBlockHeadersData struct {
 	Reverse bool         // Query direction (false = rising towards latest, true = falling towards genesis)
 }
 
+// headerQuery converts the query into the form served from the chain.
+func (q *getBlockHeadersData) headerQuery() chainaccess.HeaderQuery {
+	return chainaccess.HeaderQuery{
+		Hash:    q.Origin.Hash,
+		Number:  q.Origin.Number,
+		Amount:  q.Amount,
+		Skip:    q.Skip,
+		Reverse: q.Reverse,
+	}
+}
+
 // hashOrNumber is a combined field for specifying an origin block.
 type hashOrNumber struct {
 	Hash   common.Hash // Block hash from which to retrieve headers (excludes Number)
@@ -0,0 +1,207 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package les
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/p2p/discover"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test the handling of les protocol
+// messages. The first byte of the input is the message code, the rest is the
+// message payload. Status messages are run through the client side handshake,
+// all others are decoded the same way the protocol handler does.
+func Fuzz(data []byte) int {
+	if len(data) == 0 || len(data) > ProtocolMaxMsgSize {
+		return -1
+	}
+	if data[0] == StatusMsg {
+		if err := fuzzHandshake(data[1:]); err != nil {
+			return 0
+		}
+		return 1
+	}
+	msg := p2p.Msg{
+		Code:    uint64(data[0]),
+		Size:    uint32(len(data) - 1),
+		Payload: bytes.NewReader(data[1:]),
+	}
+	if err := fuzzMsg(msg); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// fuzzHandshake runs the client side of the handshake against a server sending
+// the given status, and uses the negotiated flow control parameters afterwards.
+func fuzzHandshake(status []byte) error {
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+	defer remote.Close()
+
+	go func() {
+		if msg, err := remote.ReadMsg(); err == nil {
+			msg.Discard()
+		}
+	}()
+	go p2p.Send(remote, StatusMsg, rlp.RawValue(status))
+
+	p := newPeer(lpv2, NetworkId, p2p.NewPeer(discover.NodeID{}, "fuzz", nil), local)
+	if err := p.Handshake(big.NewInt(0), common.Hash{}, 0, common.Hash{}, nil); err != nil {
+		return err
+	}
+	for _, code := range reqList {
+		cost := p.GetRequestCost(code, 1)
+		p.fcServer.CanSend(cost)
+		p.fcServer.QueueRequest(0, cost)
+		p.fcServer.GotReply(0, cost)
+	}
+	return nil
+}
+
+// fuzzMsg decodes a single protocol message into the types used by the protocol
+// handler for both request and reply messages.
+func fuzzMsg(msg p2p.Msg) error {
+	switch msg.Code {
+	case AnnounceMsg:
+		var req announceData
+		if err := msg.Decode(&req); err != nil {
+			return err
+		}
+		req.Update.decode()
+
+	case GetBlockHeadersMsg:
+		var req struct {
+			ReqID uint64
+			Query getBlockHeadersData
+		}
+		return msg.Decode(&req)
+
+	case BlockHeadersMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Headers   []*types.Header
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return err
+		}
+		for _, header := range resp.Headers {
+			header.Hash()
+		}
+
+	case GetBlockBodiesMsg, GetReceiptsMsg:
+		var req struct {
+			ReqID  uint64
+			Hashes []common.Hash
+		}
+		return msg.Decode(&req)
+
+	case BlockBodiesMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Data      []*types.Body
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return err
+		}
+		for _, body := range resp.Data {
+			types.DeriveSha(types.Transactions(body.Transactions))
+			types.CalcUncleHash(body.Uncles)
+		}
+
+	case GetCodeMsg:
+		var req struct {
+			ReqID uint64
+			Reqs  []CodeReq
+		}
+		return msg.Decode(&req)
+
+	case CodeMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Data      [][]byte
+		}
+		return msg.Decode(&resp)
+
+	case ReceiptsMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Receipts  []types.Receipts
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return err
+		}
+		for _, receipts := range resp.Receipts {
+			types.DeriveSha(receipts)
+		}
+
+	case GetProofsMsg:
+		var req struct {
+			ReqID uint64
+			Reqs  []ProofReq
+		}
+		return msg.Decode(&req)
+
+	case ProofsMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Data      [][]rlp.RawValue
+		}
+		return msg.Decode(&resp)
+
+	case GetHeaderProofsMsg:
+		var req struct {
+			ReqID uint64
+			Reqs  []ChtReq
+		}
+		return msg.Decode(&req)
+
+	case HeaderProofsMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Data      []ChtResp
+		}
+		return msg.Decode(&resp)
+
+	case SendTxMsg:
+		var txs []*types.Transaction
+		return msg.Decode(&txs)
+
+	case SendTxV2Msg:
+		var req struct {
+			ReqID uint64
+			Txs   []*types.Transaction
+		}
+		return msg.Decode(&req)
+
+	case TxStatusMsg:
+		var resp struct {
+			ReqID, BV uint64
+			Data      []common.Hash
+		}
+		return msg.Decode(&resp)
+	}
+	return nil
+}
@@ -22,10 +22,13 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/networkchain/networkchain/common/mclock"
+	"github.com/networkchain/networkchain/rpc"
+	"github.com/networkchain/networkchain/tracing"
 )
 
 var (
@@ -34,6 +37,11 @@ var (
 	hardRequestTimeout = time.Second * 10
 )
 
+var (
+	errInvalidReply   = errors.New("invalid reply")
+	errRequestTimeout = errors.New("request timed out")
+)
+
 // retrieveManager is a layer on top of requestDistributor which takes care of
 // matching replies by request ID and handles timeouts and resends if necessary.
 type retrieveManager struct {
@@ -59,6 +67,7 @@ type sentReq struct {
 	req      *distReq
 	id       uint64
 	validate validatorFunc
+	ctx      context.Context // context of the retrieval, carrying its RPC trace and tracing span
 
 	eventsCh chan reqPeerEvent
 	stopCh   chan struct{}
@@ -112,22 +121,27 @@ func newRetrieveManager(peers *peerSet, dist *requestDistributor, serverPool pee
 // validator callback. It returns when a valid answer is delivered or the context is
 // cancelled.
 func (rm *retrieveManager) retrieve(ctx context.Context, reqID uint64, req *distReq, val validatorFunc) error {
-	sentReq := rm.sendReq(reqID, req, val)
+	ctx, span := tracing.Start(ctx, "les.retrieve", "reqid", reqID)
+
+	sentReq := rm.sendReq(ctx, reqID, req, val)
 	select {
 	case <-sentReq.stopCh:
 	case <-ctx.Done():
 		sentReq.stop(ctx.Err())
 	}
-	return sentReq.getError()
+	err := sentReq.getError()
+	span.Finish(err)
+	return err
 }
 
 // sendReq starts a process that keeps trying to retrieve a valid answer for a
 // request from any suitable peers until stopped or succeeded.
-func (rm *retrieveManager) sendReq(reqID uint64, req *distReq, val validatorFunc) *sentReq {
+func (rm *retrieveManager) sendReq(ctx context.Context, reqID uint64, req *distReq, val validatorFunc) *sentReq {
 	r := &sentReq{
 		rm:       rm,
 		req:      req,
 		id:       reqID,
+		ctx:      ctx,
 		sentTo:   make(map[distPeer]sentReqToPeer),
 		stopCh:   make(chan struct{}),
 		eventsCh: make(chan reqPeerEvent, 10),
@@ -293,12 +307,19 @@ func (r *sentReq) tryRequest() {
 
 	r.eventsCh <- reqPeerEvent{rpSent, p}
 	if p == nil {
+		r.log("No suitable peer for request", "reqid", r.id)
 		return
 	}
+	r.log("Sent request", "reqid", r.id, "peer", p)
 
 	reqSent := mclock.Now()
 	srto, hrto := false, false
 
+	var err error
+	defer func(start time.Time) {
+		tracing.Record(r.ctx, "les.request", start, err, "reqid", r.id, "peer", p, "softTimeout", srto)
+	}(time.Now())
+
 	r.lock.RLock()
 	s, ok := r.sentTo[p]
 	r.lock.RUnlock()
@@ -327,30 +348,45 @@ func (r *sentReq) tryRequest() {
 
 	select {
 	case ok := <-s.valid:
+		r.log("Received reply", "reqid", r.id, "peer", p, "valid", ok)
 		if ok {
 			r.eventsCh <- reqPeerEvent{rpDeliveredValid, p}
 		} else {
+			err = errInvalidReply
 			r.eventsCh <- reqPeerEvent{rpDeliveredInvalid, p}
 		}
 		return
 	case <-time.After(softRequestTimeout):
 		srto = true
+		r.log("Request timed out soft", "reqid", r.id, "peer", p)
 		r.eventsCh <- reqPeerEvent{rpSoftTimeout, p}
 	}
 
 	select {
 	case ok := <-s.valid:
+		r.log("Received reply", "reqid", r.id, "peer", p, "valid", ok)
 		if ok {
 			r.eventsCh <- reqPeerEvent{rpDeliveredValid, p}
 		} else {
+			err = errInvalidReply
 			r.eventsCh <- reqPeerEvent{rpDeliveredInvalid, p}
 		}
 	case <-time.After(hardRequestTimeout):
 		hrto = true
+		err = errRequestTimeout
+		r.log("Request timed out hard", "reqid", r.id, "peer", p)
 		r.eventsCh <- reqPeerEvent{rpHardTimeout, p}
 	}
 }
 
+// log records an event of the retrieval in the trace of the RPC request it
+// serves. Retrievals not caused by RPC requests are not logged.
+func (r *sentReq) log(msg string, ctx ...interface{}) {
+	if trace := rpc.TraceFromContext(r.ctx); trace != nil {
+		trace.Log(msg, ctx...)
+	}
+}
+
 // deliver a reply belonging to this request
 func (r *sentReq) deliver(peer distPeer, msg *Msg) error {
 	r.lock.Lock()
@@ -18,9 +18,11 @@
 package les
 
 import (
+	"bytes"
 	"encoding/binary"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/networkchain/networkchain/common"
@@ -49,7 +51,7 @@ type LesServer struct {
 
 func NewLesServer(eth *eth.NetworkChain, config *eth.Config) (*LesServer, error) {
 	quitSync := make(chan struct{})
-	pm, err := NewProtocolManager(eth.BlockChain().Config(), false, config.NetworkId, eth.EventMux(), eth.Engine(), newPeerSet(), eth.BlockChain(), eth.TxPool(), eth.ChainDb(), nil, nil, quitSync, new(sync.WaitGroup))
+	pm, err := NewProtocolManager(eth.BlockChain().Config(), false, config.NetworkId, eth.EventMux(), eth.Engine(), newPeerSet(), eth.BlockChain(), eth.TxPool(), eth.BlockChain().StateDatabase(), nil, nil, quitSync, new(sync.WaitGroup))
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +89,12 @@ func (s *LesServer) Start(srvr *p2p.Server) {
 	}()
 }
 
+// PauseIndexing suspends (or resumes) the generation of the CHTs and bloom tries
+// served to light clients.
+func (s *LesServer) PauseIndexing(paused bool) {
+	s.protocolManager.PauseIndexing(paused)
+}
+
 // Stop stops the LES service
 func (s *LesServer) Stop() {
 	s.fcCostStats.store()
@@ -120,7 +128,7 @@ func (list RequestCostList) decode() requestCostTable {
 
 func (table requestCostTable) encode() RequestCostList {
 	list := make(RequestCostList, len(table))
-	for idx, code := range reqList {
+	for idx, code := range servedReqList {
 		list[idx].MsgCode = code
 		list[idx].BaseCost = table[code].baseCost
 		list[idx].ReqCost = table[code].reqCost
@@ -203,7 +211,7 @@ var rcStatsKey = []byte("_requestCostStats")
 
 func newCostStats(db ethdb.Database) *requestCostStats {
 	stats := make(map[uint64]*linReg)
-	for _, code := range reqList {
+	for _, code := range servedReqList {
 		stats[code] = &linReg{cnt: 100}
 	}
 
@@ -234,8 +242,8 @@ func (s *requestCostStats) store() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	statsRlp := make(requestCostStatsRlp, len(reqList))
-	for i, code := range reqList {
+	statsRlp := make(requestCostStatsRlp, len(servedReqList))
+	for i, code := range servedReqList {
 		statsRlp[i].MsgCode = code
 		statsRlp[i].Data = s.stats[code].toBytes()
 	}
@@ -249,9 +257,9 @@ func (s *requestCostStats) getCurrentList() RequestCostList {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	list := make(RequestCostList, len(reqList))
+	list := make(RequestCostList, len(servedReqList))
 	//fmt.Println("RequestCostList")
-	for idx, code := range reqList {
+	for idx, code := range servedReqList {
 		b, m := s.stats[code].calc()
 		//fmt.Println(code, s.stats[code].cnt, b/1000000, m/1000000)
 		if m < 0 {
@@ -280,6 +288,23 @@ func (s *requestCostStats) update(msgCode, reqCnt, cost uint64) {
 	c.add(float64(reqCnt), float64(cost))
 }
 
+// PauseIndexing suspends (or resumes) the generation of the CHTs and bloom tries
+// served to light clients, e.g. to avoid the extra memory and database churn while
+// the node is under memory pressure. Sections skipped in the meantime are generated
+// once resumed.
+func (pm *ProtocolManager) PauseIndexing(paused bool) {
+	if !paused {
+		if atomic.CompareAndSwapInt32(&pm.indexPaused, 1, 0) {
+			select {
+			case pm.indexResume <- struct{}{}:
+			default:
+			}
+		}
+		return
+	}
+	atomic.StoreInt32(&pm.indexPaused, 1)
+}
+
 func (pm *ProtocolManager) blockLoop() {
 	pm.wg.Add(1)
 	sub := pm.eventMux.Subscribe(core.ChainHeadEvent{})
@@ -320,15 +345,23 @@ func (pm *ProtocolManager) blockLoop() {
 				}
 				newCht <- struct{}{}
 			case <-newCht:
+				if atomic.LoadInt32(&pm.indexPaused) == 1 {
+					continue
+				}
 				go func() {
 					mu.Lock()
 					more := makeCht(pm.chainDb)
+					if makeBloomTrie(pm.chainDb) {
+						more = true
+					}
 					mu.Unlock()
 					if more {
 						time.Sleep(time.Millisecond * 10)
 						newCht <- struct{}{}
 					}
 				}()
+			case <-pm.indexResume:
+				newCht <- struct{}{}
 			case <-pm.quitSync:
 				sub.Unsubscribe()
 				pm.wg.Done()
@@ -356,6 +389,23 @@ func storeChtRoot(db ethdb.Database, num uint64, root common.Hash) {
 	db.Put(append(chtPrefix, encNumber[:]...), root[:])
 }
 
+// ChtRoots returns the roots of all the CHTs generated into the chain database.
+// Their nodes are stored alongside the state tries and need to be retained when
+// the state is pruned.
+func ChtRoots(db ethdb.Database) []common.Hash {
+	var lastChtNum uint64
+	if data, _ := db.Get(lastChtKey); len(data) == 8 {
+		lastChtNum = binary.BigEndian.Uint64(data)
+	}
+	var roots []common.Hash
+	for num := uint64(1); num <= lastChtNum; num++ {
+		if root := getChtRoot(db, num); root != (common.Hash{}) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
 func makeCht(db ethdb.Database) bool {
 	headHash := core.GetHeadBlockHash(db)
 	headNum := core.GetBlockNumber(db, headHash)
@@ -420,3 +470,109 @@ func makeCht(db ethdb.Database) bool {
 
 	return newChtNum > lastChtNum
 }
+
+var (
+	lastBloomTrieKey = []byte("LastBloomTrieNumber") // bloomTrieNum (uint64 big endian)
+	bloomTriePrefix  = []byte("bloomTrie")           // bloomTriePrefix + bloomTrieNum (uint64 big endian) -> trie root hash
+)
+
+func getBloomTrieRoot(db ethdb.Database, num uint64) common.Hash {
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], num)
+	data, _ := db.Get(append(bloomTriePrefix, encNumber[:]...))
+	return common.BytesToHash(data)
+}
+
+func storeBloomTrieRoot(db ethdb.Database, num uint64, root common.Hash) {
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], num)
+	db.Put(append(bloomTriePrefix, encNumber[:]...), root[:])
+}
+
+// BloomTrieRoots returns the roots of all the bloom tries generated into the
+// chain database. Like the CHTs, their nodes are stored alongside the state tries
+// and need to be retained when the state is pruned.
+func BloomTrieRoots(db ethdb.Database) []common.Hash {
+	var lastNum uint64
+	if data, _ := db.Get(lastBloomTrieKey); len(data) == 8 {
+		lastNum = binary.BigEndian.Uint64(data)
+	}
+	var roots []common.Hash
+	for num := uint64(1); num <= lastNum; num++ {
+		if root := getBloomTrieRoot(db, num); root != (common.Hash{}) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// makeBloomTrie extends the bloom trie with the bloom bits vectors of the next
+// section of canonical blocks if it is old enough, returning whether further
+// sections are pending. The bloom trie numbered n covers the first n sections
+// and maps each bit of each section to its vector (see light.BloomBitsVector),
+// omitting all zero vectors.
+func makeBloomTrie(db ethdb.Database) bool {
+	headHash := core.GetHeadBlockHash(db)
+	headNum := core.GetBlockNumber(db, headHash)
+
+	var newNum uint64
+	if headNum > light.BloomTrieConfirmations {
+		newNum = (headNum - light.BloomTrieConfirmations) / light.BloomTrieFrequency
+	}
+
+	var lastNum uint64
+	if data, _ := db.Get(lastBloomTrieKey); len(data) == 8 {
+		lastNum = binary.BigEndian.Uint64(data)
+	}
+	if newNum <= lastNum {
+		return false
+	}
+
+	var t *trie.Trie
+	if lastNum > 0 {
+		var err error
+		t, err = trie.New(getBloomTrieRoot(db, lastNum), db)
+		if err != nil {
+			lastNum = 0
+		}
+	}
+	if lastNum == 0 {
+		t, _ = trie.New(common.Hash{}, db)
+	}
+
+	blooms := make([]types.Bloom, light.BloomTrieFrequency)
+	for i := range blooms {
+		num := lastNum*light.BloomTrieFrequency + uint64(i)
+		hash := core.GetCanonicalHash(db, num)
+		if hash == (common.Hash{}) {
+			panic("Canonical hash not found")
+		}
+		header := core.GetHeader(db, hash, num)
+		if header == nil {
+			panic("Header not found")
+		}
+		blooms[i] = header.Bloom
+	}
+	empty := make([]byte, light.BloomTrieFrequency/8)
+	for bit := uint(0); bit < light.BloomBitLength; bit++ {
+		if vector := light.BloomBitsVector(blooms, bit); !bytes.Equal(vector, empty) {
+			t.Update(light.BloomTrieKey(bit, lastNum), vector)
+		}
+	}
+
+	root, err := t.Commit()
+	if err != nil {
+		lastNum = 0
+	} else {
+		lastNum++
+
+		log.Trace("Generated bloom trie", "number", lastNum, "root", root.Hex())
+
+		storeBloomTrieRoot(db, lastNum, root)
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], lastNum)
+		db.Put(lastBloomTrieKey, data[:])
+	}
+
+	return newNum > lastNum
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/light"
+)
+
+// Tests that the CHT generation of a light server can be paused, catching up on
+// the skipped sections once resumed.
+func TestPauseIndexing(t *testing.T) {
+	defer func(frequency, confirmations uint64) {
+		light.ChtFrequency, light.ChtConfirmations = frequency, confirmations
+	}(light.ChtFrequency, light.ChtConfirmations)
+	light.ChtFrequency, light.ChtConfirmations = 8, 4
+
+	db, _ := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 20, nil, nil, nil, db)
+	defer pm.Stop()
+
+	lastCht := func() uint64 {
+		if data, _ := db.Get(lastChtKey); len(data) == 8 {
+			return binary.BigEndian.Uint64(data)
+		}
+		return 0
+	}
+	pm.PauseIndexing(true)
+	pm.blockLoop()
+
+	time.Sleep(100 * time.Millisecond)
+	if num := lastCht(); num != 0 {
+		t.Fatalf("CHT generated while paused: have %d, want 0", num)
+	}
+	pm.PauseIndexing(false)
+
+	// (20 - 4 confirmations) / 8 blocks per section = 2 sections
+	for start := time.Now(); lastCht() != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("CHT not generated after resuming: have %d, want 2", lastCht())
+		}
+	}
+	if root := getChtRoot(db, 2); root == (common.Hash{}) {
+		t.Errorf("CHT root of the last section missing")
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
 	"github.com/networkchain/networkchain/p2p/discv5"
+	"github.com/networkchain/networkchain/p2p/netutil"
 	"github.com/networkchain/networkchain/rlp"
 )
 
@@ -89,6 +90,18 @@ const (
 	// initStatsWeight is used to initialize previously unknown peers with good
 	// statistics to give a chance to prove themselves
 	initStatsWeight = 1
+	// latencyProbeTimeout is the time after which a latency probe is abandoned and
+	// the server is considered to be as distant as the timeout
+	latencyProbeTimeout = time.Second * 2
+	// maxLatencyProbes is the maximum number of latency probes running in parallel
+	maxLatencyProbes = 8
+	// rttScoreTC is the exponential decay time constant for calculating selection
+	// chances from probed round trip times. Servers not probed yet are assumed to
+	// have a round trip time of rttScoreTC.
+	rttScoreTC = time.Millisecond * 100
+	// preferredNetBoost is the selection weight multiplier of servers located in
+	// one of the preferred networks
+	preferredNetBoost = 10
 )
 
 // serverPool implements a pool for storing and selecting newly discovered and already
@@ -117,10 +130,20 @@ type serverPool struct {
 	knownSelect, newSelect     *weightedRandomSelect
 	knownSelected, newSelected int
 	fastDiscover               bool
+
+	probeLatency bool             // Whether to measure the round trip time of servers
+	preferNets   *netutil.Netlist // Networks whose servers are preferred, if any
+	probeSlots   chan struct{}    // Semaphore limiting the number of parallel probes
+	probed       chan latencyProbe
+
+	spawn func(name string, routine func()) // Spawner of the event loop
 }
 
-// newServerPool creates a new serverPool instance
-func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup) *serverPool {
+// newServerPool creates a new serverPool instance. If probeLatency is set, the
+// round trip time of discovered servers is measured and nearby servers are
+// favoured when selecting whom to connect to. Servers in the preferNets networks
+// are favoured regardless of latency.
+func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup, probeLatency bool, preferNets *netutil.Netlist) *serverPool {
 	pool := &serverPool{
 		db:           db,
 		quit:         quit,
@@ -132,6 +155,11 @@ func newServerPool(db ethdb.Database, quit chan struct{}, wg *sync.WaitGroup) *s
 		knownSelect:  newWeightedRandomSelect(),
 		newSelect:    newWeightedRandomSelect(),
 		fastDiscover: true,
+		probeLatency: probeLatency,
+		preferNets:   preferNets,
+		probeSlots:   make(chan struct{}, maxLatencyProbes),
+		probed:       make(chan latencyProbe, maxLatencyProbes),
+		spawn:        func(name string, routine func()) { go routine() },
 	}
 	pool.knownQueue = newPoolEntryQueue(maxKnownEntries, pool.removeEntry)
 	pool.newQueue = newPoolEntryQueue(maxNewEntries, pool.removeEntry)
@@ -145,7 +173,7 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	pool.wg.Add(1)
 	pool.loadNodes()
 
-	go pool.eventLoop()
+	pool.spawn("les/serverpool", pool.eventLoop)
 
 	pool.checkDial()
 	if pool.server.DiscV5 != nil {
@@ -276,6 +304,8 @@ func (pool *serverPool) adjustResponseTime(entry *poolEntry, time time.Duration,
 
 // eventLoop handles pool events and mutex locking for all internal functions
 func (pool *serverPool) eventLoop() {
+	defer pool.wg.Done()
+
 	lookupCnt := 0
 	var convTime mclock.AbsTime
 	if pool.discSetPeriod != nil {
@@ -311,6 +341,17 @@ func (pool *serverPool) eventLoop() {
 			}
 			pool.lock.Unlock()
 
+		case res := <-pool.probed:
+			pool.lock.Lock()
+			if !res.entry.removed {
+				res.entry.rtt = res.rtt
+				pool.updateLocality(res.entry)
+				if res.entry.state == psNotConnected {
+					pool.updateCheckDial(res.entry)
+				}
+			}
+			pool.lock.Unlock()
+
 		case node := <-pool.discNodes:
 			pool.lock.Lock()
 			entry := pool.findOrNewNode(discover.NodeID(node.ID), node.IP, node.TCP)
@@ -337,7 +378,6 @@ func (pool *serverPool) eventLoop() {
 			}
 			pool.connWg.Wait()
 			pool.saveNodes()
-			pool.wg.Done()
 			return
 
 		}
@@ -371,9 +411,11 @@ func (pool *serverPool) findOrNewNode(id discover.NodeID, ip net.IP, port uint16
 		addr = a
 	} else {
 		entry.addr[addr.strKey()] = addr
+		pool.probe(entry, addr)
 	}
 	addr.lastSeen = now
 	entry.addrSelect.update(addr)
+	pool.updateLocality(entry)
 	if !entry.known {
 		pool.newQueue.setLatest(entry)
 	}
@@ -400,7 +442,9 @@ func (pool *serverPool) loadNodes() {
 			"timeout", fmt.Sprintf("%v/%v", e.timeoutStats.avg, e.timeoutStats.weight))
 		pool.entries[e.id] = e
 		pool.knownQueue.setLatest(e)
+		pool.updateLocality(e)
 		pool.knownSelect.update((*knownEntry)(e))
+		pool.probe(e, e.lastConnected)
 	}
 }
 
@@ -417,6 +461,77 @@ func (pool *serverPool) saveNodes() {
 	}
 }
 
+// latencyProbe is the result of measuring the round trip time of a server.
+type latencyProbe struct {
+	entry *poolEntry
+	rtt   time.Duration
+}
+
+// probe measures the round trip time of a server address in the background by
+// timing the establishment of a TCP connection, which is closed right away. The
+// probe is skipped if latency probing is disabled or too many are in progress,
+// it will be retried when the server is discovered at a new address.
+func (pool *serverPool) probe(entry *poolEntry, addr *poolEntryAddress) {
+	if !pool.probeLatency {
+		return
+	}
+	select {
+	case pool.probeSlots <- struct{}{}:
+	default:
+		return
+	}
+	dest := addr.strKey()
+	go func() {
+		defer func() { <-pool.probeSlots }()
+
+		start := mclock.Now()
+		rtt := latencyProbeTimeout
+		if conn, err := net.DialTimeout("tcp", dest, latencyProbeTimeout); err == nil {
+			rtt = time.Duration(mclock.Now() - start)
+			conn.Close()
+		}
+		log.Trace("Probed server latency", "lesaddr", entry.id.String()+"@"+dest, "rtt", rtt)
+		select {
+		case <-pool.quit:
+		case pool.probed <- latencyProbe{entry, rtt}:
+		}
+	}()
+}
+
+// updateLocality recalculates the selection weight multiplier of an entry from
+// its probed round trip time and whether any of its addresses is located in one
+// of the preferred networks.
+func (pool *serverPool) updateLocality(entry *poolEntry) {
+	entry.locality = 1
+	if pool.probeLatency {
+		rtt := entry.rtt
+		if rtt == 0 {
+			rtt = rttScoreTC
+		}
+		entry.locality = math.Exp(-float64(rtt) / float64(rttScoreTC))
+	}
+	if pool.preferNets != nil {
+		for _, addr := range entry.addr {
+			if pool.preferNets.Contains(addr.ip) {
+				entry.locality *= preferredNetBoost
+				break
+			}
+		}
+	}
+}
+
+// rtt returns the latest probed round trip time of a server, or zero if it has
+// not been probed.
+func (pool *serverPool) rtt(entry *poolEntry) time.Duration {
+	if entry == nil {
+		return 0
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return entry.rtt
+}
+
 // removeEntry removes a pool entry when the entry count limit is reached.
 // Note that it is called by the new/known queues from which the entry has already
 // been removed so removing it from the queues is not necessary.
@@ -561,6 +676,9 @@ type poolEntry struct {
 
 	delayedRetry bool
 	shortRetry   int
+
+	rtt      time.Duration // Latest probed round trip time, zero if unknown
+	locality float64       // Selection weight multiplier derived from the server's location
 }
 
 func (e *poolEntry) EncodeRLP(w io.Writer) error {
@@ -604,9 +722,9 @@ func (e *discoveredEntry) Weight() int64 {
 	}
 	t := time.Duration(mclock.Now() - e.lastDiscovered)
 	if t <= discoverExpireStart {
-		return 1000000000
+		return int64(1000000000 * e.locality)
 	} else {
-		return int64(1000000000 * math.Exp(-float64(t-discoverExpireStart)/float64(discoverExpireConst)))
+		return int64(1000000000 * e.locality * math.Exp(-float64(t-discoverExpireStart)/float64(discoverExpireConst)))
 	}
 }
 
@@ -618,7 +736,7 @@ func (e *knownEntry) Weight() int64 {
 	if e.state != psNotConnected || !e.known || e.delayedRetry {
 		return 0
 	}
-	return int64(1000000000 * e.connectStats.recentAvg() * math.Exp(-float64(e.lastConnected.fails)*failDropLn-e.responseStats.recentAvg()/float64(responseScoreTC)-e.delayStats.recentAvg()/float64(delayScoreTC)) * math.Pow((1-e.timeoutStats.recentAvg()), timeoutPow))
+	return int64(1000000000 * e.locality * e.connectStats.recentAvg() * math.Exp(-float64(e.lastConnected.fails)*failDropLn-e.responseStats.recentAvg()/float64(responseScoreTC)-e.delayStats.recentAvg()/float64(delayScoreTC)) * math.Pow((1-e.timeoutStats.recentAvg()), timeoutPow))
 }
 
 // poolEntryAddress is a separate object because currently it is necessary to remember
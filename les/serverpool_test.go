@@ -0,0 +1,87 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/p2p/discover"
+	"github.com/networkchain/networkchain/p2p/netutil"
+)
+
+// Tests that nearby servers and servers in the preferred networks are weighted
+// higher than distant ones.
+func TestServerPoolLocality(t *testing.T) {
+	nets, _ := netutil.ParseNetlist("10.0.0.0/8")
+	pool := newServerPool(nil, make(chan struct{}), new(sync.WaitGroup), true, nets)
+	pool.probeLatency = false // don't dial the test addresses
+
+	near := pool.findOrNewNode(discover.NodeID{1}, net.IP{192, 168, 0, 1}, 30303)
+	far := pool.findOrNewNode(discover.NodeID{2}, net.IP{192, 168, 0, 2}, 30303)
+	preferred := pool.findOrNewNode(discover.NodeID{3}, net.IP{10, 0, 0, 1}, 30303)
+
+	pool.probeLatency = true
+	near.rtt, far.rtt, preferred.rtt = 10*time.Millisecond, 300*time.Millisecond, 300*time.Millisecond
+	for _, entry := range []*poolEntry{near, far, preferred} {
+		pool.updateLocality(entry)
+	}
+	if (*discoveredEntry)(near).Weight() <= (*discoveredEntry)(far).Weight() {
+		t.Errorf("nearby server not favoured: near %d, far %d", (*discoveredEntry)(near).Weight(), (*discoveredEntry)(far).Weight())
+	}
+	if (*discoveredEntry)(preferred).Weight() <= (*discoveredEntry)(far).Weight() {
+		t.Errorf("preferred server not favoured: preferred %d, far %d", (*discoveredEntry)(preferred).Weight(), (*discoveredEntry)(far).Weight())
+	}
+}
+
+// Tests that latency probes measure the round trip time of reachable servers.
+func TestServerPoolProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	quit := make(chan struct{})
+	defer close(quit)
+
+	pool := newServerPool(nil, quit, new(sync.WaitGroup), true, nil)
+	addr := l.Addr().(*net.TCPAddr)
+	entry := pool.findOrNewNode(discover.NodeID{1}, addr.IP, uint16(addr.Port))
+
+	select {
+	case res := <-pool.probed:
+		if res.entry != entry {
+			t.Fatalf("probe result for wrong entry")
+		}
+		if res.rtt <= 0 || res.rtt >= latencyProbeTimeout {
+			t.Errorf("unexpected round trip time: %v", res.rtt)
+		}
+	case <-time.After(2 * latencyProbeTimeout):
+		t.Fatalf("probe timed out")
+	}
+}
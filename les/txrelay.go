@@ -21,11 +21,13 @@ import (
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
 )
 
 type ltrInfo struct {
-	tx     *types.Transaction
-	sentTo map[*peer]struct{}
+	tx      *types.Transaction
+	sentTo  map[*peer]struct{}
+	ackedBy map[*peer]struct{} // servers confirming they accepted the tx (les/2 only)
 }
 
 type LesTxRelay struct {
@@ -50,18 +52,83 @@ func NewLesTxRelay(ps *peerSet, reqDist *requestDistributor) *LesTxRelay {
 	return r
 }
 
+// registerPeer updates the list of servers and relays the pending transactions
+// that are not known to be held by any of them to the new server.
 func (self *LesTxRelay) registerPeer(p *peer) {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
 	self.peerList = self.ps.AllPeers()
+	self.resendOrphans()
 }
 
+// unregisterPeer forgets about the transactions sent to a disconnected server,
+// re-relaying the pending ones that are not held by any other server anymore.
 func (self *LesTxRelay) unregisterPeer(p *peer) {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
 	self.peerList = self.ps.AllPeers()
+	for _, ltr := range self.txSent {
+		delete(ltr.sentTo, p)
+		delete(ltr.ackedBy, p)
+	}
+	self.resendOrphans()
+}
+
+// resendOrphans relays the pending transactions not sent to any connected server.
+func (self *LesTxRelay) resendOrphans() {
+	var orphans types.Transactions
+	for hash := range self.txPending {
+		if ltr, ok := self.txSent[hash]; ok && len(ltr.sentTo) == 0 {
+			orphans = append(orphans, ltr.tx)
+		}
+	}
+	if len(orphans) > 0 && len(self.peerList) > 0 {
+		log.Debug("Re-relaying orphaned transactions", "count", len(orphans))
+		self.send(orphans, 1)
+	}
+}
+
+// acknowledge marks the transactions accepted by a server as held by it.
+func (self *LesTxRelay) acknowledge(p *peer, hashes []common.Hash) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, hash := range hashes {
+		if ltr, ok := self.txSent[hash]; ok {
+			ltr.ackedBy[p] = struct{}{}
+		}
+	}
+}
+
+// TxRelayStatus describes which servers a pending transaction was relayed to.
+type TxRelayStatus struct {
+	SentTo  []string `json:"sentTo"`
+	AckedBy []string `json:"ackedBy"`
+}
+
+// status returns the relay status of the pending transactions.
+func (self *LesTxRelay) status() map[common.Hash]TxRelayStatus {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	status := make(map[common.Hash]TxRelayStatus, len(self.txPending))
+	for hash := range self.txPending {
+		ltr, ok := self.txSent[hash]
+		if !ok {
+			continue
+		}
+		st := TxRelayStatus{SentTo: []string{}, AckedBy: []string{}}
+		for p := range ltr.sentTo {
+			st.SentTo = append(st.SentTo, p.id)
+		}
+		for p := range ltr.ackedBy {
+			st.AckedBy = append(st.AckedBy, p.id)
+		}
+		status[hash] = st
+	}
+	return status
 }
 
 // send sends a list of transactions to at most a given number of peers at
@@ -79,8 +146,9 @@ func (self *LesTxRelay) send(txs types.Transactions, count int) {
 		ltr, ok := self.txSent[hash]
 		if !ok {
 			ltr = &ltrInfo{
-				tx:     tx,
-				sentTo: make(map[*peer]struct{}),
+				tx:      tx,
+				sentTo:  make(map[*peer]struct{}),
+				ackedBy: make(map[*peer]struct{}),
 			}
 			self.txSent[hash] = ltr
 			self.txPending[hash] = struct{}{}
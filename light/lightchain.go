@@ -36,8 +36,10 @@ import (
 )
 
 var (
-	bodyCacheLimit  = 256
-	blockCacheLimit = 256
+	bodyCacheLimit   = 256
+	blockCacheLimit  = 256
+	pruneBatchLimit  = uint64(1024) // Maximum number of headers to prune after a single import
+	cappedCacheLimit = 16           // Number of bodies and blocks kept in memory while the caches are capped
 )
 
 // LightChain represents a canonical chain that by default only handles block
@@ -64,7 +66,9 @@ type LightChain struct {
 	procInterrupt int32 // interrupt signaler for block processing
 	wg            sync.WaitGroup
 
-	engine consensus.Engine
+	engine    consensus.Engine
+	retention uint64 // Number of recent headers to keep in the database (0 = keep all)
+	paused    int32  // Whether header pruning is temporarily suspended (atomic)
 }
 
 // NewLightChain returns a fully initialised light chain using information
@@ -195,11 +199,11 @@ func (bc *LightChain) ResetWithGenesisBlock(genesis *types.Block) {
 	defer bc.mu.Unlock()
 
 	// Prepare the genesis block and reinitialise the chain
-	if err := core.WriteTd(bc.chainDb, genesis.Hash(), genesis.NumberU64(), genesis.Difficulty()); err != nil {
-		log.Crit("Failed to write genesis block TD", "err", err)
+	if err := core.WriteBody(bc.chainDb, genesis.Hash(), genesis.NumberU64(), genesis.Body()); err != nil {
+		log.Crit("Failed to write genesis block body", "err", err)
 	}
-	if err := core.WriteBlock(bc.chainDb, genesis); err != nil {
-		log.Crit("Failed to write genesis block", "err", err)
+	if err := bc.hc.WriteHeaderTd(genesis.Header(), genesis.Difficulty()); err != nil {
+		log.Crit("Failed to write genesis block header", "err", err)
 	}
 	bc.genesisBlock = genesis
 	bc.hc.SetGenesis(bc.genesisBlock.Header())
@@ -211,6 +215,10 @@ func (bc *LightChain) ResetWithGenesisBlock(genesis *types.Block) {
 // Engine retrieves the light chain's consensus engine.
 func (bc *LightChain) Engine() consensus.Engine { return bc.engine }
 
+// HeaderChain returns the underlying header chain, usable as a chain reader by
+// the consensus engine.
+func (bc *LightChain) HeaderChain() *core.HeaderChain { return bc.hc }
+
 // Genesis returns the genesis block
 func (bc *LightChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -229,7 +237,7 @@ func (self *LightChain) GetBody(ctx context.Context, hash common.Hash) (*types.B
 		return nil, err
 	}
 	// Cache the found body for next time and return
-	self.bodyCache.Add(hash, body)
+	self.cache(self.bodyCache, hash, body)
 	return body, nil
 }
 
@@ -245,7 +253,7 @@ func (self *LightChain) GetBodyRLP(ctx context.Context, hash common.Hash) (rlp.R
 		return nil, err
 	}
 	// Cache the found body for next time and return
-	self.bodyRLPCache.Add(hash, body)
+	self.cache(self.bodyRLPCache, hash, body)
 	return body, nil
 }
 
@@ -268,7 +276,7 @@ func (self *LightChain) GetBlock(ctx context.Context, hash common.Hash, number u
 		return nil, err
 	}
 	// Cache the found block for next time and return
-	self.blockCache.Add(block.Hash(), block)
+	self.cache(self.blockCache, block.Hash(), block)
 	return block, nil
 }
 
@@ -376,10 +384,116 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 		return err
 	}
 	i, err := self.hc.InsertHeaderChain(chain, whFunc, start)
+	if self.retention > 0 && atomic.LoadInt32(&self.paused) == 0 {
+		self.pruneHeaders()
+	}
 	go self.postChainEvents(events)
 	return i, err
 }
 
+// SetHeaderRetention sets the number of recent headers the light chain keeps in
+// its database. Older headers are pruned as new ones are imported, and can be
+// retrieved on demand from the network if needed again. Zero disables pruning.
+func (self *LightChain) SetHeaderRetention(retention uint64) {
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+
+	self.retention = retention
+}
+
+// HeaderRetention returns the number of recent headers the light chain keeps in
+// its database, zero meaning all of them.
+func (self *LightChain) HeaderRetention() uint64 {
+	self.chainmu.RLock()
+	defer self.chainmu.RUnlock()
+
+	return self.retention
+}
+
+// PausePruning temporarily suspends (or resumes) the pruning of old headers, e.g.
+// to avoid the extra database churn while the node is under memory pressure.
+func (self *LightChain) PausePruning(paused bool) {
+	if paused {
+		atomic.StoreInt32(&self.paused, 1)
+	} else {
+		atomic.StoreInt32(&self.paused, 0)
+	}
+}
+
+// PurgeCaches drops all the headers and blocks cached in memory, e.g. to relieve
+// memory pressure. Subsequent accesses are served from the database or the ODR.
+func (self *LightChain) PurgeCaches() {
+	self.hc.PurgeCaches()
+	self.bodyCache.Purge()
+	self.bodyRLPCache.Purge()
+	self.blockCache.Purge()
+}
+
+// CapCaches limits (or lifts the limit on) the headers and blocks cached in
+// memory, keeping them from refilling while the node is under memory pressure.
+// Capping also drops the current contents of the caches.
+func (self *LightChain) CapCaches(capped bool) {
+	self.hc.CapCaches(capped)
+	if capped {
+		self.bodyCache.Purge()
+		self.bodyRLPCache.Purge()
+		self.blockCache.Purge()
+	}
+}
+
+// cache adds an entry to one of the caches of the chain, evicting the oldest
+// entries beyond cappedCacheLimit while the caches are capped.
+func (self *LightChain) cache(cache *lru.Cache, key, value interface{}) {
+	cache.Add(key, value)
+	if self.hc.Capped() {
+		for cache.Len() > cappedCacheLimit {
+			cache.RemoveOldest()
+		}
+	}
+}
+
+// pruneHeaders deletes the canonical headers (along with any cached bodies and
+// receipts) older than the retention window. Only headers covered by the trusted
+// CHT are pruned, since those can be retrieved again by number. The genesis and
+// the last header of each CHT section are kept as checkpoint anchors.
+//
+// The caller must hold the chain mutex.
+func (self *LightChain) pruneHeaders() {
+	head := self.hc.CurrentHeader().Number.Uint64()
+	if head <= self.retention {
+		return
+	}
+	limit := head - self.retention
+	if trusted := GetTrustedCht(self.chainDb).Number * ChtFrequency; limit > trusted {
+		limit = trusted
+	}
+	tail := getPruneTail(self.chainDb)
+	if tail >= limit {
+		return
+	}
+	if limit > tail+pruneBatchLimit {
+		limit = tail + pruneBatchLimit
+	}
+	pruned := 0
+	for number := tail; number < limit; number++ {
+		if number%ChtFrequency == ChtFrequency-1 {
+			continue
+		}
+		hash := core.GetCanonicalHash(self.chainDb, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		core.DeleteCanonicalHash(self.chainDb, number)
+		core.DeleteHeader(self.chainDb, hash, number)
+		core.DeleteTd(self.chainDb, hash, number)
+		core.DeleteBody(self.chainDb, hash, number)
+		core.DeleteBlockReceipts(self.chainDb, hash, number)
+		pruned++
+	}
+	writePruneTail(self.chainDb, limit)
+	log.Debug("Pruned old headers", "from", tail, "to", limit-1, "count", pruned)
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internal cache.
 func (self *LightChain) CurrentHeader() *types.Header {
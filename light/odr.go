@@ -151,9 +151,25 @@ type ChtRequest struct {
 // StoreResult stores the retrieved data in local database
 func (req *ChtRequest) StoreResult(db ethdb.Database) {
 	// if there is a canonical hash, there is a header too
-	core.WriteHeader(db, req.Header)
-	hash, num := req.Header.Hash(), req.Header.Number.Uint64()
-	core.WriteTd(db, hash, num, req.Td)
-	core.WriteCanonicalHash(db, hash, num)
+	core.WriteHeaderTd(db, req.Header, req.Td)
+	core.WriteCanonicalHash(db, req.Header.Hash(), req.Header.Number.Uint64())
 	//storeProof(db, req.Proof)
 }
+
+// BloomRequest is the ODR request type for retrieving a bloom bits vector: the
+// given bit of the header blooms of all the blocks in a section, proven by the
+// bloom trie.
+type BloomRequest struct {
+	OdrRequest
+	BloomTrieNum  uint64
+	BloomTrieRoot common.Hash
+	Bit           uint
+	Section       uint64
+	Vector        []byte
+	Proof         []rlp.RawValue
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *BloomRequest) StoreResult(db ethdb.Database) {
+	storeBloomBits(db, req.Bit, req.Section, req.Vector)
+}
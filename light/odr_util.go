@@ -19,7 +19,9 @@ package light
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/networkchain/networkchain/common"
@@ -27,6 +29,7 @@ import (
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rlp"
 )
 
@@ -39,8 +42,20 @@ var (
 	ChtFrequency     = uint64(4096)
 	ChtConfirmations = uint64(2048)
 	trustedChtKey    = []byte("TrustedCHT")
+	pruneTailKey     = []byte("LightPruneTail")
+
+	ErrNoTrustedBloomTrie = errors.New("No trusted bloom trie")
+
+	BloomTrieFrequency     = uint64(4096)
+	BloomTrieConfirmations = uint64(2048)
+	trustedBloomTrieKey    = []byte("TrustedBloomTrie")
+	bloomBitsPrefix        = []byte("lightBloomBits-") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) -> vector
 )
 
+// BloomBitLength is the number of bits in a header bloom filter, each of which
+// is indexed separately by the bloom trie.
+const BloomBitLength = 2048
+
 type ChtNode struct {
 	Hash common.Hash
 	Td   *big.Int
@@ -69,6 +84,99 @@ func DeleteTrustedCht(db ethdb.Database) {
 	db.Delete(trustedChtKey)
 }
 
+// TrustedBloomTrie is the number of sections covered by a bloom trie and the
+// root hash of the trie the light client trusts.
+type TrustedBloomTrie struct {
+	Number uint64
+	Root   common.Hash
+}
+
+func GetTrustedBloomTrie(db ethdb.Database) TrustedBloomTrie {
+	data, _ := db.Get(trustedBloomTrieKey)
+	var res TrustedBloomTrie
+	if err := rlp.DecodeBytes(data, &res); err != nil {
+		return TrustedBloomTrie{0, common.Hash{}}
+	}
+	return res
+}
+
+func WriteTrustedBloomTrie(db ethdb.Database, bt TrustedBloomTrie) {
+	data, _ := rlp.EncodeToBytes(bt)
+	db.Put(trustedBloomTrieKey, data)
+}
+
+// BloomTrieKey returns the key of the bloom bits vector of the given bit and
+// section within the bloom trie.
+func BloomTrieKey(bit uint, section uint64) []byte {
+	var key [10]byte
+	binary.BigEndian.PutUint16(key[0:2], uint16(bit))
+	binary.BigEndian.PutUint64(key[2:10], section)
+	return key[:]
+}
+
+// BloomBitsVector extracts a bloom bits vector from the header blooms of all
+// the blocks in a section: bit i of the vector (most significant bit first) is
+// set if the given bit is set in the bloom of the i-th block of the section.
+// The bloom bits are numbered like in types.BloomLookup, starting from the
+// least significant bit of the bloom.
+func BloomBitsVector(blooms []types.Bloom, bit uint) []byte {
+	vector := make([]byte, (len(blooms)+7)/8)
+	for i, bloom := range blooms {
+		if bloom[len(bloom)-1-int(bit/8)]&(1<<(bit%8)) != 0 {
+			vector[i/8] |= 1 << (7 - uint(i%8))
+		}
+	}
+	return vector
+}
+
+func getBloomBits(db ethdb.Database, bit uint, section uint64) []byte {
+	data, _ := db.Get(append(bloomBitsPrefix, BloomTrieKey(bit, section)...))
+	return data
+}
+
+func storeBloomBits(db ethdb.Database, bit uint, section uint64, vector []byte) {
+	db.Put(append(bloomBitsPrefix, BloomTrieKey(bit, section)...), vector)
+}
+
+// GetBloomBits retrieves the bloom bits vector of the given bit for a section of
+// BloomTrieFrequency blocks, proven by the trusted bloom trie. Bits not set in
+// any block of the section yield an all zero vector.
+func GetBloomBits(ctx context.Context, odr OdrBackend, bit uint, section uint64) ([]byte, error) {
+	if bit >= BloomBitLength {
+		return nil, fmt.Errorf("bloom bit %d out of range", bit)
+	}
+	db := odr.Database()
+	if vector := getBloomBits(db, bit, section); vector != nil {
+		return vector, nil
+	}
+	bt := GetTrustedBloomTrie(db)
+	if section >= bt.Number {
+		return nil, ErrNoTrustedBloomTrie
+	}
+	r := &BloomRequest{BloomTrieRoot: bt.Root, BloomTrieNum: bt.Number, Bit: bit, Section: section}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Vector, nil
+}
+
+// getPruneTail retrieves the number of the first header not yet considered for
+// pruning, or 1 (the first non-genesis header) if nothing has been pruned yet.
+func getPruneTail(db ethdb.Database) uint64 {
+	data, _ := db.Get(pruneTailKey)
+	var tail uint64
+	if err := rlp.DecodeBytes(data, &tail); err != nil || tail == 0 {
+		return 1
+	}
+	return tail
+}
+
+// writePruneTail stores the number of the first header not yet pruned.
+func writePruneTail(db ethdb.Database, tail uint64) {
+	data, _ := rlp.EncodeToBytes(tail)
+	db.Put(pruneTailKey, data)
+}
+
 func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*types.Header, error) {
 	db := odr.Database()
 	hash := core.GetCanonicalHash(db, number)
@@ -150,15 +258,23 @@ func GetBlock(ctx context.Context, odr OdrBackend, hash common.Hash, number uint
 }
 
 // GetBlockReceipts retrieves the receipts generated by the transactions included
-// in a block given by its hash.
-func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, number uint64) (types.Receipts, error) {
+// in a block given by its hash, with the fields not secured by consensus filled
+// in based on the block itself.
+func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, number uint64, config *params.ChainConfig) (types.Receipts, error) {
 	receipts := core.GetBlockReceipts(odr.Database(), hash, number)
-	if receipts != nil {
-		return receipts, nil
+	if receipts == nil {
+		r := &ReceiptsRequest{Hash: hash, Number: number}
+		if err := odr.Retrieve(ctx, r); err != nil {
+			return nil, err
+		}
+		receipts = r.Receipts
 	}
-	r := &ReceiptsRequest{Hash: hash, Number: number}
-	if err := odr.Retrieve(ctx, r); err != nil {
+	block, err := GetBlock(ctx, odr, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.SetReceiptsData(config, block, receipts); err != nil {
 		return nil, err
 	}
-	return r.Receipts, nil
+	return receipts, nil
 }
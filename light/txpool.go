@@ -19,6 +19,8 @@ package light
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -37,6 +39,45 @@ import (
 // considered permanent and no rollback is expected
 var txPermanent = uint64(500)
 
+// txPriceBump is the minimum gas price bump percentage required to replace a
+// pending transaction with another one of the same nonce
+var txPriceBump = core.DefaultTxPoolConfig.PriceBump
+
+// TxStatus is the status of a locally submitted transaction.
+type TxStatus uint
+
+const (
+	TxStatusPending TxStatus = iota // Waiting to be included in a block
+	TxStatusMined                   // Included in a block of the canonical chain
+	TxStatusDropped                 // Replaced, or its nonce was used by another transaction
+)
+
+// String implements fmt.Stringer.
+func (s TxStatus) String() string {
+	switch s {
+	case TxStatusPending:
+		return "pending"
+	case TxStatusMined:
+		return "mined"
+	case TxStatusDropped:
+		return "dropped"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s TxStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// TxStatusEvent is posted when the status of a local transaction changes. Rolled
+// back transactions become pending again.
+type TxStatusEvent struct {
+	Hash   common.Hash
+	Status TxStatus
+}
+
 // TxPool implements the transaction pool for light clients, which keeps track
 // of the status of locally created transactions, detecting if they are included
 // in a block (mined) or rolled back. There are no queued transactions since we
@@ -53,6 +94,7 @@ type TxPool struct {
 	odr      OdrBackend
 	chainDb  ethdb.Database
 	relay    TxRelayBackend
+	filters  core.TxFilters // Embedder hooks consulted before accepting transactions
 	head     common.Hash
 	nonce    map[common.Address]uint64            // "pending" nonce
 	pending  map[common.Hash]*types.Transaction   // pending transactions by tx hash
@@ -194,14 +236,10 @@ func (pool *TxPool) checkMinedTxs(ctx context.Context, hash common.Hash, idx uin
 		if tx, ok := pool.pending[txHash]; ok {
 			//fmt.Println("TX FOUND")
 			if receipts == nil {
-				receipts, err = GetBlockReceipts(ctx, pool.odr, hash, idx)
+				receipts, err = GetBlockReceipts(ctx, pool.odr, hash, idx, pool.config)
 				if err != nil {
 					return err
 				}
-				if len(receipts) != len(block.Transactions()) {
-					panic(nil) // should never happen if hashes did match
-				}
-				core.SetReceiptsData(pool.config, block, receipts)
 			}
 			//fmt.Println("WriteReceipt", receipts[i].TxHash)
 			core.WriteReceipt(pool.chainDb, receipts[i])
@@ -322,11 +360,67 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	ctx, cancel := context.WithTimeout(context.Background(), blockCheckTimeout)
 	defer cancel()
 
-	txc, _ := pool.reorgOnNewHead(ctx, head)
+	txc, err := pool.reorgOnNewHead(ctx, head)
 	m, r := txc.getLists()
 	pool.relay.NewHead(pool.head, m, r)
 	pool.homestead = pool.config.IsHomestead(head.Number)
 	pool.signer = types.MakeSigner(pool.config, head.Number)
+
+	// Only look for dropped transactions if all new blocks were checked,
+	// otherwise mined ones might not have been detected yet
+	var dropped []common.Hash
+	if err == nil {
+		dropped = pool.checkDroppedTxs(ctx)
+	}
+	pool.postStatusEvents(m, TxStatusMined)
+	pool.postStatusEvents(r, TxStatusPending)
+	pool.postStatusEvents(dropped, TxStatusDropped)
+}
+
+// checkDroppedTxs removes the pending transactions whose nonce has already been
+// used in the state of the current head, meaning that another transaction with
+// the same nonce got mined instead. It also updates the pending nonces of the
+// local accounts and returns the hashes of the dropped transactions.
+func (pool *TxPool) checkDroppedTxs(ctx context.Context) []common.Hash {
+	if len(pool.pending) == 0 {
+		return nil
+	}
+	accounts := make(map[common.Address][]*types.Transaction)
+	for _, tx := range pool.pending {
+		from, _ := types.Sender(pool.signer, tx)
+		accounts[from] = append(accounts[from], tx)
+	}
+	state := pool.currentState(ctx)
+
+	var dropped []common.Hash
+	for addr, txs := range accounts {
+		nonce := state.GetNonce(addr)
+		if state.Error() != nil {
+			break
+		}
+		next := nonce
+		for _, tx := range txs {
+			if tx.Nonce() < nonce {
+				log.Debug("Dropping transaction with used nonce", "hash", tx.Hash(), "nonce", tx.Nonce(), "state", nonce)
+				pool.removeTx(tx.Hash())
+				dropped = append(dropped, tx.Hash())
+			} else if tx.Nonce() >= next {
+				next = tx.Nonce() + 1
+			}
+		}
+		pool.nonce[addr] = next
+	}
+	if len(dropped) > 0 {
+		pool.relay.Discard(dropped)
+	}
+	return dropped
+}
+
+// postStatusEvents notifies the subscribers about status changes of transactions.
+func (pool *TxPool) postStatusEvents(hashes []common.Hash, status TxStatus) {
+	for _, hash := range hashes {
+		go pool.eventMux.Post(TxStatusEvent{Hash: hash, Status: status})
+	}
 }
 
 // Stop stops the light transaction pool
@@ -393,7 +487,9 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 }
 
 // add validates a new transaction and sets its state pending if processable.
-// It also updates the locally stored nonce if necessary.
+// A pending transaction with the same sender and nonce is replaced if the new one
+// pays a sufficiently higher gas price. It also updates the locally stored nonce
+// if necessary.
 func (self *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 	hash := tx.Hash()
 
@@ -404,9 +500,29 @@ func (self *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	if len(self.filters) > 0 {
+		from, _ := types.Sender(self.signer, tx) // already validated
+		if err := self.filters.FilterTx(tx, from, true); err != nil {
+			log.Debug("Discarding filtered transaction", "hash", hash, "err", err)
+			return err
+		}
+	}
+	if old := self.sameNonceTx(tx); old != nil {
+		// Require the gas price to be raised by at least the price bump percentage
+		threshold := new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(txPriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.GasPrice().Cmp(threshold) < 0 {
+			return core.ErrReplaceUnderpriced
+		}
+		log.Debug("Replacing pending transaction", "old", old.Hash(), "new", hash, "nonce", tx.Nonce())
+		self.removeTx(old.Hash())
+		self.relay.Discard([]common.Hash{old.Hash()})
+		self.postStatusEvents([]common.Hash{old.Hash()}, TxStatusDropped)
+	}
 
 	if _, ok := self.pending[hash]; !ok {
 		self.pending[hash] = tx
+		self.postStatusEvents([]common.Hash{hash}, TxStatusPending)
 
 		nonce := tx.Nonce() + 1
 
@@ -426,6 +542,16 @@ func (self *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 	return nil
 }
 
+// AddFilter registers a hook to be consulted before any new transaction is
+// accepted into the pool and relayed to the network. Transactions already in
+// the pool are not re-checked.
+func (self *TxPool) AddFilter(filter core.TxFilter) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.filters = append(self.filters, filter)
+}
+
 // Add adds a transaction to the pool if valid and passes it to the tx relay
 // backend
 func (self *TxPool) Add(ctx context.Context, tx *types.Transaction) error {
@@ -464,6 +590,27 @@ func (self *TxPool) AddBatch(ctx context.Context, txs []*types.Transaction) {
 	}
 }
 
+// sameNonceTx returns the pending transaction with the same sender and nonce as
+// the given one, if any.
+func (self *TxPool) sameNonceTx(tx *types.Transaction) *types.Transaction {
+	from, _ := types.Sender(self.signer, tx)
+	for _, ptx := range self.pending {
+		if ptx.Nonce() != tx.Nonce() {
+			continue
+		}
+		if sender, _ := types.Sender(self.signer, ptx); sender == from {
+			return ptx
+		}
+	}
+	return nil
+}
+
+// removeTx removes a transaction from the pending set and the database.
+func (self *TxPool) removeTx(hash common.Hash) {
+	delete(self.pending, hash)
+	self.chainDb.Delete(hash[:])
+}
+
 // GetTransaction returns a transaction if it is contained in the pool
 // and nil otherwise.
 func (tp *TxPool) GetTransaction(hash common.Hash) *types.Transaction {
@@ -489,6 +636,32 @@ func (self *TxPool) GetTransactions() (txs types.Transactions, err error) {
 	return txs, nil
 }
 
+// GetTransactionsFrom returns the currently processable transactions sent by any
+// of the given accounts, grouped by account in the requested order and sorted by
+// nonce.
+func (self *TxPool) GetTransactionsFrom(addrs []common.Address) types.Transactions {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	senders := make(map[common.Address]types.Transactions, len(addrs))
+	for _, addr := range addrs {
+		senders[addr] = nil
+	}
+	for _, tx := range self.pending {
+		account, _ := types.Sender(self.signer, tx)
+		if txs, ok := senders[account]; ok {
+			senders[account] = append(txs, tx)
+		}
+	}
+	var txs types.Transactions
+	for _, addr := range addrs {
+		sort.Sort(types.TxByNonce(senders[addr]))
+		txs = append(txs, senders[addr]...)
+		delete(senders, addr)
+	}
+	return txs
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and nonce.
 func (self *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
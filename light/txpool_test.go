@@ -143,3 +143,58 @@ func TestTxPool(t *testing.T) {
 		}
 	}
 }
+
+func TestTxPoolReplacement(t *testing.T) {
+	var (
+		evmux  = new(event.TypeMux)
+		sdb, _ = ethdb.NewMemDatabase()
+		ldb, _ = ethdb.NewMemDatabase()
+		gspec  = core.Genesis{Alloc: core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}}}
+	)
+	gspec.MustCommit(sdb)
+	gspec.MustCommit(ldb)
+
+	odr := &testOdr{sdb: sdb, ldb: ldb}
+	relay := &testTxRelay{
+		send:    make(chan int, 1),
+		discard: make(chan int, 1),
+		mined:   make(chan int, 1),
+	}
+	lightchain, _ := NewLightChain(odr, params.TestChainConfig, ethash.NewFullFaker(), evmux)
+	pool := NewTxPool(params.TestChainConfig, evmux, lightchain, relay)
+	defer pool.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	sign := func(price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, acc1Addr, big.NewInt(10000), bigTxGas, big.NewInt(price), nil), types.HomesteadSigner{}, testBankKey)
+		return tx
+	}
+	original := sign(100)
+	if err := pool.Add(ctx, original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	<-relay.send
+
+	// Replacing with an insufficient price bump must fail
+	if err := pool.Add(ctx, sign(105)); err != core.ErrReplaceUnderpriced {
+		t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, core.ErrReplaceUnderpriced)
+	}
+	// Replacing with a sufficient price bump must discard the original
+	replacement := sign(110)
+	if err := pool.Add(ctx, replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if discarded := <-relay.discard; discarded != 1 {
+		t.Errorf("discarded transaction count mismatch: have %d, want %d", discarded, 1)
+	}
+	<-relay.send
+
+	if pool.GetTransaction(original.Hash()) != nil {
+		t.Errorf("replaced transaction still pending")
+	}
+	if pool.GetTransaction(replacement.Hash()) == nil {
+		t.Errorf("replacement transaction not pending")
+	}
+}
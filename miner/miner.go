@@ -155,6 +155,12 @@ func (self *Miner) HashRate() (tot int64) {
 	return
 }
 
+// SetTxOrdering sets the strategy used to order pending transactions when
+// assembling new blocks.
+func (self *Miner) SetTxOrdering(ordering TxOrderingFunc) {
+	self.worker.setOrdering(ordering)
+}
+
 func (self *Miner) SetExtra(extra []byte) error {
 	if uint64(len(extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("Extra exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
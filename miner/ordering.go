@@ -0,0 +1,185 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"math/big"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+// TxOrdering is an iterator over a set of pending transactions, deciding the
+// order in which the miner attempts to include them into a block. Implementations
+// must honour the nonce ordering of the transactions from the same account.
+type TxOrdering interface {
+	// Peek returns the next transaction to try to include, or nil if the set has
+	// been exhausted.
+	Peek() *types.Transaction
+
+	// Shift marks the current transaction as included, replacing it with the next
+	// one from the same account.
+	Shift()
+
+	// Pop discards the current transaction along with all the subsequent ones from
+	// the same account, as they cannot be executed any more.
+	Pop()
+}
+
+// TxOrderingFunc creates a transaction ordering over the pending transactions
+// of the pool, grouped by account and sorted by nonce. The arrival function
+// returns the time a transaction was first seen by the miner, or the zero time
+// if it is unknown.
+type TxOrderingFunc func(signer types.Signer, pending map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering
+
+// TxOrderings contains the built in transaction ordering strategies.
+var TxOrderings = map[string]TxOrderingFunc{
+	"price":      NewTxsByPrice,
+	"pricenonce": NewTxsByPackagePrice,
+	"fifo":       NewTxsByArrival,
+}
+
+// NewTxsByPrice orders transactions by their individual gas price, always picking
+// the best priced head transaction of all accounts. This is the default strategy.
+func NewTxsByPrice(signer types.Signer, pending map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering {
+	return types.NewTransactionsByPriceAndNonce(pending)
+}
+
+// accountTxs is a nonce sorted list of transactions from a single account, along
+// with the sort key of its current head.
+type accountTxs struct {
+	txs   types.Transactions
+	price *big.Int  // Sort key for package price ordering
+	time  time.Time // Sort key for arrival ordering
+}
+
+// accountHeap is a heap of accounts ordered by an arbitrary head comparison.
+type accountHeap struct {
+	accounts []*accountTxs
+	less     func(a, b *accountTxs) bool
+}
+
+func (h accountHeap) Len() int            { return len(h.accounts) }
+func (h accountHeap) Less(i, j int) bool  { return h.less(h.accounts[i], h.accounts[j]) }
+func (h accountHeap) Swap(i, j int)       { h.accounts[i], h.accounts[j] = h.accounts[j], h.accounts[i] }
+func (h *accountHeap) Push(x interface{}) { h.accounts = append(h.accounts, x.(*accountTxs)) }
+
+func (h *accountHeap) Pop() interface{} {
+	old := h.accounts
+	n := len(old)
+	x := old[n-1]
+	h.accounts = old[0 : n-1]
+	return x
+}
+
+// accountOrdering is a TxOrdering picking the head transaction of the account
+// currently considered best by a heap, re-keying the account on every shift.
+type accountOrdering struct {
+	heads *accountHeap
+	rekey func(acc *accountTxs)
+}
+
+// newAccountOrdering creates a heap based ordering over the given accounts.
+func newAccountOrdering(pending map[common.Address]types.Transactions, rekey func(acc *accountTxs), less func(a, b *accountTxs) bool) *accountOrdering {
+	heads := &accountHeap{less: less}
+	for _, txs := range pending {
+		if len(txs) == 0 {
+			continue
+		}
+		acc := &accountTxs{txs: txs}
+		rekey(acc)
+		heads.accounts = append(heads.accounts, acc)
+	}
+	heap.Init(heads)
+
+	return &accountOrdering{heads: heads, rekey: rekey}
+}
+
+// Peek implements TxOrdering, returning the head of the best account.
+func (o *accountOrdering) Peek() *types.Transaction {
+	if o.heads.Len() == 0 {
+		return nil
+	}
+	return o.heads.accounts[0].txs[0]
+}
+
+// Shift implements TxOrdering, moving on to the next transaction of the best
+// account and re-evaluating its position.
+func (o *accountOrdering) Shift() {
+	acc := o.heads.accounts[0]
+	if acc.txs = acc.txs[1:]; len(acc.txs) == 0 {
+		heap.Pop(o.heads)
+		return
+	}
+	o.rekey(acc)
+	heap.Fix(o.heads, 0)
+}
+
+// Pop implements TxOrdering, dropping the best account altogether.
+func (o *accountOrdering) Pop() {
+	heap.Pop(o.heads)
+}
+
+// NewTxsByPackagePrice orders accounts by the best gas weighted average price
+// achievable by including a nonce prefix of their transactions. This allows an
+// unprofitable transaction in the middle of a nonce chain to be paid for by the
+// ones following it, instead of stalling the entire chain behind it.
+func NewTxsByPackagePrice(signer types.Signer, pending map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering {
+	rekey := func(acc *accountTxs) {
+		var (
+			fees = new(big.Int)
+			gas  = new(big.Int)
+			best *big.Int
+		)
+		for _, tx := range acc.txs {
+			fees.Add(fees, new(big.Int).Mul(tx.GasPrice(), tx.Gas()))
+			gas.Add(gas, tx.Gas())
+			if gas.Sign() == 0 {
+				continue
+			}
+			if avg := new(big.Int).Div(fees, gas); best == nil || avg.Cmp(best) > 0 {
+				best = avg
+			}
+		}
+		if best == nil {
+			best = acc.txs[0].GasPrice()
+		}
+		acc.price = best
+	}
+	less := func(a, b *accountTxs) bool {
+		return a.price.Cmp(b.price) > 0
+	}
+	return newAccountOrdering(pending, rekey, less)
+}
+
+// NewTxsByArrival orders transactions in the order the miner first saw them,
+// falling back to gas price ordering among transactions that arrived at the
+// same time (or whose arrival is unknown).
+func NewTxsByArrival(signer types.Signer, pending map[common.Address]types.Transactions, arrival func(common.Hash) time.Time) TxOrdering {
+	rekey := func(acc *accountTxs) {
+		acc.time = arrival(acc.txs[0].Hash())
+	}
+	less := func(a, b *accountTxs) bool {
+		if !a.time.Equal(b.time) {
+			return a.time.Before(b.time)
+		}
+		return a.txs[0].GasPrice().Cmp(b.txs[0].GasPrice()) > 0
+	}
+	return newAccountOrdering(pending, rekey, less)
+}
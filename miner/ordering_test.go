@@ -0,0 +1,126 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+// orderingTx creates an unsigned transaction with the given nonce and gas price.
+func orderingTx(nonce uint64, price int64) *types.Transaction {
+	return types.NewTransaction(nonce, common.Address{}, big.NewInt(0), big.NewInt(21000), big.NewInt(price), nil)
+}
+
+// drainOrdering iterates over a transaction ordering, including everything.
+func drainOrdering(ordering TxOrdering) types.Transactions {
+	var txs types.Transactions
+	for tx := ordering.Peek(); tx != nil; tx = ordering.Peek() {
+		txs = append(txs, tx)
+		ordering.Shift()
+	}
+	return txs
+}
+
+// Tests that the package price ordering lets a cheap transaction in the middle of
+// a nonce chain be carried by the expensive ones following it.
+func TestTxOrderingPackagePrice(t *testing.T) {
+	var (
+		alice = common.Address{0x01}
+		bob   = common.Address{0x02}
+	)
+	pending := map[common.Address]types.Transactions{
+		alice: {orderingTx(0, 10), orderingTx(1, 1), orderingTx(2, 100)},
+		bob:   {orderingTx(0, 20), orderingTx(1, 20)},
+	}
+	owners := map[*types.Transaction]common.Address{}
+	for addr, txs := range pending {
+		for _, tx := range txs {
+			owners[tx] = addr
+		}
+	}
+	txs := drainOrdering(NewTxsByPackagePrice(nil, pending, nil))
+	if len(txs) != 5 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), 5)
+	}
+	// Alice's package average (10+1+100)/3 = 37 beats Bob, so her whole chain
+	// should be included first, in nonce order
+	for i := 0; i < 3; i++ {
+		if owners[txs[i]] != alice || txs[i].Nonce() != uint64(i) {
+			t.Errorf("tx %d: have %x/%d, want %x/%d", i, owners[txs[i]], txs[i].Nonce(), alice, i)
+		}
+	}
+	for i := 3; i < 5; i++ {
+		if owners[txs[i]] != bob || txs[i].Nonce() != uint64(i-3) {
+			t.Errorf("tx %d: have %x/%d, want %x/%d", i, owners[txs[i]], txs[i].Nonce(), bob, i-3)
+		}
+	}
+}
+
+// Tests that popping an account in the middle of its nonce chain drops all its
+// subsequent transactions too.
+func TestTxOrderingPop(t *testing.T) {
+	pending := map[common.Address]types.Transactions{
+		common.Address{0x01}: {orderingTx(0, 10), orderingTx(1, 10), orderingTx(2, 10)},
+	}
+	for name, ordering := range TxOrderings {
+		if name == "price" {
+			continue // Requires signed transactions, covered in core/types
+		}
+		txs := ordering(nil, pending, func(common.Hash) time.Time { return time.Time{} })
+		txs.Shift()
+		txs.Pop()
+		if tx := txs.Peek(); tx != nil {
+			t.Errorf("%s: transaction remained after pop: nonce %d", name, tx.Nonce())
+		}
+	}
+}
+
+// Tests that the arrival ordering includes transactions first come first served,
+// regardless of their gas price, while still honouring nonces.
+func TestTxOrderingArrival(t *testing.T) {
+	var (
+		alice = common.Address{0x01}
+		bob   = common.Address{0x02}
+	)
+	pending := map[common.Address]types.Transactions{
+		alice: {orderingTx(0, 1), orderingTx(1, 1)},
+		bob:   {orderingTx(0, 100)},
+	}
+	start := time.Now()
+	arrivals := map[common.Hash]time.Time{
+		pending[alice][0].Hash(): start,
+		pending[bob][0].Hash():   start.Add(time.Second),
+		pending[alice][1].Hash(): start.Add(2 * time.Second),
+	}
+	arrival := func(hash common.Hash) time.Time { return arrivals[hash] }
+
+	txs := drainOrdering(NewTxsByArrival(nil, pending, arrival))
+	want := types.Transactions{pending[alice][0], pending[bob][0], pending[alice][1]}
+	if len(txs) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(want))
+	}
+	for i, tx := range txs {
+		if tx != want[i] {
+			t.Errorf("tx %d: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+	}
+}
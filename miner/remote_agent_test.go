@@ -0,0 +1,74 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+// Tests that new work is pushed to the notification URLs, and that solutions to
+// pushed work are accepted without the remote miner ever calling GetWork.
+func TestRemoteAgentNotify(t *testing.T) {
+	packages := make(chan [3]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var work [3]string
+		if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+			t.Errorf("failed to decode work package: %v", err)
+		}
+		packages <- work
+	}))
+	defer server.Close()
+
+	results := make(chan *Result, 1)
+
+	agent := NewRemoteAgent(nil, ethash.NewFaker(), []string{server.URL})
+	agent.SetReturnCh(results)
+	agent.Start()
+	defer agent.Stop()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)})
+	agent.Work() <- &Work{Block: block, createdAt: time.Now()}
+
+	select {
+	case work := <-packages:
+		if want := workPackage(block); work != want {
+			t.Fatalf("work package mismatch: have %v, want %v", work, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("work package not delivered")
+	}
+	if !agent.SubmitWork(types.BlockNonce{}, common.Hash{}, block.HashNoNonce()) {
+		t.Fatalf("solution to pushed work rejected")
+	}
+	select {
+	case result := <-results:
+		if result.Block.HashNoNonce() != block.HashNoNonce() {
+			t.Errorf("sealed block mismatch: have %x, want %x", result.Block.HashNoNonce(), block.HashNoNonce())
+		}
+	default:
+		t.Errorf("no sealed block returned")
+	}
+}
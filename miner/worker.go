@@ -112,6 +112,10 @@ type worker struct {
 	txQueueMu sync.Mutex
 	txQueue   map[common.Hash]*types.Transaction
 
+	ordering  TxOrderingFunc            // Strategy to order pending transactions with
+	arrivalMu sync.Mutex                // Protects the transaction arrival times
+	arrivals  map[common.Hash]time.Time // Time each pending transaction was first seen
+
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
 
 	// atomic status counters
@@ -134,6 +138,8 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		possibleUncles: make(map[common.Hash]*types.Block),
 		coinbase:       coinbase,
 		txQueue:        make(map[common.Hash]*types.Transaction),
+		ordering:       NewTxsByPrice,
+		arrivals:       make(map[common.Hash]time.Time),
 		agents:         make(map[Agent]struct{}),
 		unconfirmed:    newUnconfirmedBlocks(eth.BlockChain(), 5),
 		fullValidation: false,
@@ -153,6 +159,20 @@ func (self *worker) setEtherbase(addr common.Address) {
 	self.coinbase = addr
 }
 
+func (self *worker) setOrdering(ordering TxOrderingFunc) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.ordering = ordering
+}
+
+// arrival returns the time a transaction was first seen by the worker, or the
+// zero time if it's unknown.
+func (self *worker) arrival(hash common.Hash) time.Time {
+	self.arrivalMu.Lock()
+	defer self.arrivalMu.Unlock()
+	return self.arrivals[hash]
+}
+
 func (self *worker) setExtra(extra []byte) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -240,6 +260,13 @@ func (self *worker) update() {
 			self.possibleUncles[ev.Block.Hash()] = ev.Block
 			self.uncleMu.Unlock()
 		case core.TxPreEvent:
+			// Track the arrival time of the transaction for ordering purposes
+			self.arrivalMu.Lock()
+			if _, ok := self.arrivals[ev.Tx.Hash()]; !ok {
+				self.arrivals[ev.Tx.Hash()] = time.Now()
+			}
+			self.arrivalMu.Unlock()
+
 			// Apply transaction to the pending state if we're not mining
 			if atomic.LoadInt32(&self.mining) == 0 {
 				self.currentMu.Lock()
@@ -249,6 +276,9 @@ func (self *worker) update() {
 				txset := types.NewTransactionsByPriceAndNonce(txs)
 
 				self.current.commitTransactions(self.mux, txset, self.chain, self.coinbase)
+
+				// Keep the pending header in sync with the pending state
+				self.current.header.Root = self.current.state.IntermediateRoot(self.config.IsEIP158(self.current.header.Number))
 				self.currentMu.Unlock()
 			}
 		}
@@ -274,21 +304,17 @@ func (self *worker) wait() {
 				}
 				go self.mux.Post(core.NewMinedBlockEvent{Block: block})
 			} else {
-				work.state.CommitTo(self.chainDb, self.config.IsEIP158(block.Number()))
+				if err := self.chain.CommitState(block, work.state); err != nil {
+					log.Error("Failed writing block state", "err", err)
+					continue
+				}
 				stat, err := self.chain.WriteBlock(block)
 				if err != nil {
 					log.Error("Failed writing block to chain", "err", err)
 					continue
 				}
-				// update block hash since it is now available and not when the receipt/log of individual transactions were created
-				for _, r := range work.receipts {
-					for _, l := range r.Logs {
-						l.BlockHash = block.Hash()
-					}
-				}
-				for _, log := range work.state.Logs() {
-					log.BlockHash = block.Hash()
-				}
+				// update the derived fields since the block hash is now available and not when the receipt/log of individual transactions were created
+				types.Receipts(work.receipts).DeriveFields(types.MakeSigner(self.config, block.Number()), block.Hash(), block.NumberU64(), block.Transactions())
 
 				// check if canon block and write transactions
 				if stat == core.CanonStatTy {
@@ -443,7 +469,20 @@ func (self *worker) commitNewWork() {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
-	txs := types.NewTransactionsByPriceAndNonce(pending)
+	// Forget the arrival times of transactions no longer pending
+	self.arrivalMu.Lock()
+	arrivals := make(map[common.Hash]time.Time)
+	for _, txs := range pending {
+		for _, tx := range txs {
+			if seen, ok := self.arrivals[tx.Hash()]; ok {
+				arrivals[tx.Hash()] = seen
+			}
+		}
+	}
+	self.arrivals = arrivals
+	self.arrivalMu.Unlock()
+
+	txs := self.ordering(work.signer, pending, self.arrival)
 	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
 
 	self.eth.TxPool().RemoveBatch(work.failedTxs)
@@ -498,7 +537,7 @@ func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
 	return nil
 }
 
-func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
+func (env *Work) commitTransactions(mux *event.TypeMux, txs TxOrdering, bc *core.BlockChain, coinbase common.Address) {
 	gp := new(core.GasPool).AddGas(env.header.GasLimit)
 
 	var coalescedLogs []*types.Log
@@ -0,0 +1,94 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// testBackend implements Backend on top of a chain and transaction pool.
+type testBackend struct {
+	db     ethdb.Database
+	chain  *core.BlockChain
+	pool   *core.TxPool
+	accman *accounts.Manager
+}
+
+func (b *testBackend) AccountManager() *accounts.Manager { return b.accman }
+func (b *testBackend) BlockChain() *core.BlockChain      { return b.chain }
+func (b *testBackend) TxPool() *core.TxPool              { return b.pool }
+func (b *testBackend) ChainDb() ethdb.Database           { return b.db }
+
+// Tests that transactions arriving while not mining are reflected consistently
+// in both the pending block and the pending state.
+func TestPendingStateConsistency(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		db, _  = ethdb.NewMemDatabase()
+		mux    = new(event.TypeMux)
+		gspec  = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}
+		engine = ethash.NewFaker()
+	)
+	gspec.MustCommit(db)
+	chain, _ := core.NewBlockChain(db, gspec.Config, engine, mux, vm.Config{})
+	defer chain.Stop()
+
+	pool := core.NewTxPool(core.DefaultTxPoolConfig, gspec.Config, mux, chain.State, chain.GasLimit)
+	defer pool.Stop()
+
+	w := newWorker(gspec.Config, engine, addr, &testBackend{db, chain, pool, accounts.NewManager()}, mux)
+	defer mux.Stop()
+
+	parent := w.pendingBlock().Root()
+
+	tx, _ := types.SignTx(types.NewTransaction(0, addr, big.NewInt(1000), big.NewInt(21000), big.NewInt(1), nil), types.NewEIP155Signer(gspec.Config.ChainId), key)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Wait for the worker to apply the transaction to the pending block
+	for start := time.Now(); len(w.pendingBlock().Transactions()) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("transaction not applied to pending block")
+		}
+	}
+	block, statedb := w.pending()
+	if nonce := statedb.GetNonce(addr); nonce != 1 {
+		t.Errorf("pending nonce mismatch: have %d, want 1", nonce)
+	}
+	if block.GasUsed().Cmp(big.NewInt(21000)) != 0 {
+		t.Errorf("pending gas used mismatch: have %v, want 21000", block.GasUsed())
+	}
+	if block.Root() == parent {
+		t.Errorf("pending block root not updated")
+	}
+	if root := statedb.IntermediateRoot(gspec.Config.IsEIP158(block.Number())); block.Root() != root {
+		t.Errorf("pending block root mismatch: have %x, want %x", block.Root(), root)
+	}
+}
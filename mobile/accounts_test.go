@@ -0,0 +1,103 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package netk
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Tests that accounts can be created, unlocked, used for signing and moved
+// between key stores through the mobile wrappers.
+func TestKeyStoreLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netk-keystore-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := NewKeyStore(dir+"/first", LightScryptN, LightScryptP)
+
+	// Create an account and make sure it's tracked by the key store
+	account, err := ks.NewAccount("Creation password")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if !ks.HasAddress(account.GetAddress()) {
+		t.Fatalf("created account not found in key store")
+	}
+	if size := ks.GetAccounts().Size(); size != 1 {
+		t.Fatalf("account count mismatch: have %d, want %d", size, 1)
+	}
+	// Signing must fail while locked and succeed once unlocked
+	tx := NewTransaction(1, new(Address), NewBigInt(0), NewBigInt(0), NewBigInt(0), nil)
+	chain := NewBigInt(1)
+
+	if _, err := ks.SignTx(account, tx, chain); err == nil {
+		t.Fatalf("signed transaction with locked account")
+	}
+	if err := ks.Unlock(account, "Wrong password"); err == nil {
+		t.Fatalf("unlocked account with wrong password")
+	}
+	if err := ks.Unlock(account, "Creation password"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	signed, err := ks.SignTx(account, tx, chain)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	from, err := signed.GetFrom(chain)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from.GetHex() != account.GetAddress().GetHex() {
+		t.Fatalf("sender mismatch: have %s, want %s", from.GetHex(), account.GetAddress().GetHex())
+	}
+	if err := ks.Lock(account.GetAddress()); err != nil {
+		t.Fatalf("failed to lock account: %v", err)
+	}
+	// A timed unlock must expire on its own
+	if err := ks.TimedUnlock(account, "Creation password", int64(50*time.Millisecond)); err != nil {
+		t.Fatalf("failed to timed unlock account: %v", err)
+	}
+	if _, err := ks.SignTx(account, tx, chain); err != nil {
+		t.Fatalf("failed to sign with timed unlocked account: %v", err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if _, err := ks.SignTx(account, tx, chain); err == nil {
+		t.Fatalf("signed transaction after timed unlock expired")
+	}
+	// Export the key and import it into a fresh key store
+	keyJSON, err := ks.ExportKey(account, "Creation password", "Export password")
+	if err != nil {
+		t.Fatalf("failed to export account: %v", err)
+	}
+	other := NewKeyStore(dir+"/second", LightScryptN, LightScryptP)
+
+	imported, err := other.ImportKey(keyJSON, "Export password", "Import password")
+	if err != nil {
+		t.Fatalf("failed to import account: %v", err)
+	}
+	if imported.GetAddress().GetHex() != account.GetAddress().GetHex() {
+		t.Fatalf("imported address mismatch: have %s, want %s", imported.GetAddress().GetHex(), account.GetAddress().GetHex())
+	}
+	if _, err := other.SignTxPassphrase(imported, "Import password", tx, chain); err != nil {
+		t.Fatalf("failed to sign with imported account: %v", err)
+	}
+}
@@ -21,7 +21,11 @@ package netk
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"unicode"
 
+	"github.com/networkchain/networkchain/p2p/discover"
 	"github.com/networkchain/networkchain/p2p/discv5"
 )
 
@@ -61,6 +65,60 @@ func NewEnode(rawurl string) (enode *Enode, _ error) {
 	return &Enode{node}, nil
 }
 
+// ValidateEnode checks whether a node designator describes a complete node which
+// can be connected to, returning the reason if not.
+func ValidateEnode(rawurl string) error {
+	enode, err := NewEnode(rawurl)
+	if err != nil {
+		return err
+	}
+	return enode.validateComplete()
+}
+
+// GetID returns the hex encoded public key of the node.
+func (e *Enode) GetID() string { return e.node.ID.String() }
+
+// GetIP returns the IP address of the node, or an empty string if incomplete.
+func (e *Enode) GetIP() string {
+	if e.node.IP == nil {
+		return ""
+	}
+	return e.node.IP.String()
+}
+
+// GetListenerPort returns the TCP listening port of the node.
+func (e *Enode) GetListenerPort() int { return int(e.node.TCP) }
+
+// GetDiscoveryPort returns the UDP discovery port of the node.
+func (e *Enode) GetDiscoveryPort() int { return int(e.node.UDP) }
+
+// IsIncomplete returns whether the enode only contains the node ID, without the
+// network endpoint to connect to.
+func (e *Enode) IsIncomplete() bool { return e.node.Incomplete() }
+
+// String returns the node designator in URL form.
+func (e *Enode) String() string { return e.node.String() }
+
+// validateComplete checks whether the enode can be dialed.
+func (e *Enode) validateComplete() error {
+	if e.node.Incomplete() {
+		return errors.New("incomplete enode, missing IP address")
+	}
+	if e.node.TCP == 0 {
+		return errors.New("missing TCP port")
+	}
+	if e.node.IP.IsMulticast() || e.node.IP.IsUnspecified() {
+		return errors.New("invalid IP (multicast/unspecified)")
+	}
+	_, err := e.node.ID.Pubkey()
+	return err
+}
+
+// discoverNode converts the enode into the node type used for dialing peers.
+func (e *Enode) discoverNode() *discover.Node {
+	return discover.NewNode(discover.NodeID(e.node.ID), e.node.IP, e.node.UDP, e.node.TCP)
+}
+
 // Enodes represents a slice of accounts.
 type Enodes struct{ nodes []*discv5.Node }
 
@@ -76,6 +134,20 @@ func NewEnodesEmpty() *Enodes {
 	return NewEnodes(0)
 }
 
+// ParseEnodes parses a list of node designators separated by commas or white
+// space, as commonly found in configuration files.
+func ParseEnodes(list string) (*Enodes, error) {
+	enodes := NewEnodesEmpty()
+	for _, rawurl := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		enode, err := NewEnode(rawurl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enode %q: %v", rawurl, err)
+		}
+		enodes.Append(enode)
+	}
+	return enodes, nil
+}
+
 // Size returns the number of enodes in the slice.
 func (e *Enodes) Size() int {
 	return len(e.nodes)
@@ -102,3 +174,26 @@ func (e *Enodes) Set(index int, enode *Enode) error {
 func (e *Enodes) Append(enode *Enode) {
 	e.nodes = append(e.nodes, enode.node)
 }
+
+// Remove deletes the enode at the given index from the slice.
+func (e *Enodes) Remove(index int) error {
+	if index < 0 || index >= len(e.nodes) {
+		return errors.New("index out of bounds")
+	}
+	e.nodes = append(e.nodes[:index], e.nodes[index+1:]...)
+	return nil
+}
+
+// discoverNodes converts the enodes into the node type used for dialing peers.
+func (e *Enodes) discoverNodes() []*discover.Node {
+	if e == nil {
+		return nil
+	}
+	nodes := make([]*discover.Node, 0, len(e.nodes))
+	for _, node := range e.nodes {
+		if node != nil {
+			nodes = append(nodes, (&Enode{node}).discoverNode())
+		}
+	}
+	return nodes
+}
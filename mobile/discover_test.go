@@ -0,0 +1,54 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package netk
+
+import "testing"
+
+const (
+	testEnodeID  = "a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c"
+	testEnodeURL = "enode://" + testEnodeID + "@52.16.188.185:30303"
+)
+
+// Tests that enode lists are parsed and that only dialable enodes are accepted
+// as valid.
+func TestEnodeParsing(t *testing.T) {
+	enodes, err := ParseEnodes(testEnodeURL + ",\n enode://" + testEnodeID + "@10.3.58.6:30303?discport=30301 ")
+	if err != nil {
+		t.Fatalf("failed to parse enodes: %v", err)
+	}
+	if enodes.Size() != 2 {
+		t.Fatalf("enode count mismatch: have %d, want %d", enodes.Size(), 2)
+	}
+	enode, _ := enodes.Get(1)
+	if enode.GetID() != testEnodeID || enode.GetIP() != "10.3.58.6" || enode.GetListenerPort() != 30303 || enode.GetDiscoveryPort() != 30301 {
+		t.Errorf("enode fields mismatch: %v", enode)
+	}
+	if err := enodes.Remove(0); err != nil || enodes.Size() != 1 {
+		t.Errorf("failed to remove enode: %v", err)
+	}
+	if _, err := ParseEnodes(testEnodeURL + ",enode://invalid"); err == nil {
+		t.Errorf("invalid enode list accepted")
+	}
+	if err := ValidateEnode(testEnodeURL); err != nil {
+		t.Errorf("complete enode rejected: %v", err)
+	}
+	for _, rawurl := range []string{"enode://" + testEnodeID, "enode://" + testEnodeID + "@0.0.0.0:30303", "enode://1234@52.16.188.185:30303"} {
+		if err := ValidateEnode(rawurl); err == nil {
+			t.Errorf("invalid enode %q accepted", rawurl)
+		}
+	}
+}
@@ -84,6 +84,54 @@ func (p *SyncProgress) GetHighestBlock() int64  { return int64(p.progress.Highes
 func (p *SyncProgress) GetPulledStates() int64  { return int64(p.progress.PulledStates) }
 func (p *SyncProgress) GetKnownStates() int64   { return int64(p.progress.KnownStates) }
 
+// Sync stages reported by SyncProgress.
+const (
+	SyncStageChain = "chain" // Downloading the headers (light sync) or blocks of the chain
+	SyncStageState = "state" // Downloading the state trie of the pivot block (fast sync)
+	SyncStageDone  = "done"  // Synchronised with the network
+)
+
+// GetStage returns the stage the synchronisation is in, one of SyncStageChain,
+// SyncStageState or SyncStageDone.
+func (p *SyncProgress) GetStage() string {
+	switch {
+	case p.progress.CurrentBlock < p.progress.HighestBlock:
+		return SyncStageChain
+	case p.progress.PulledStates < p.progress.KnownStates:
+		return SyncStageState
+	default:
+		return SyncStageDone
+	}
+}
+
+// GetSyncedHeaders returns the number of headers (or blocks) downloaded since the
+// synchronisation started.
+func (p *SyncProgress) GetSyncedHeaders() int64 {
+	if p.progress.CurrentBlock < p.progress.StartingBlock {
+		return 0
+	}
+	return int64(p.progress.CurrentBlock - p.progress.StartingBlock)
+}
+
+// GetRemainingHeaders returns the number of headers (or blocks) still to download
+// to reach the highest known block.
+func (p *SyncProgress) GetRemainingHeaders() int64 {
+	if p.progress.HighestBlock < p.progress.CurrentBlock {
+		return 0
+	}
+	return int64(p.progress.HighestBlock - p.progress.CurrentBlock)
+}
+
+// GetPercentage returns the completion of the chain synchronisation in percent,
+// measured from the block it started at.
+func (p *SyncProgress) GetPercentage() float64 {
+	total := p.GetSyncedHeaders() + p.GetRemainingHeaders()
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(p.GetSyncedHeaders()) / float64(total)
+}
+
 // Topics is a set of topic lists to filter events with.
 type Topics struct{ topics [][]common.Hash }
 
@@ -20,9 +20,12 @@
 package netk
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/eth"
@@ -45,6 +48,22 @@ type NodeConfig struct {
 	// Bootstrap nodes used to establish connectivity with the rest of the network.
 	BootstrapNodes *Enodes
 
+	// StaticNodes is the list of peers the node always maintains a connection to,
+	// useful for pinning the node to private infrastructure.
+	StaticNodes *Enodes
+
+	// ListenPort is the TCP port to listen on for incoming peer connections. If
+	// it is set to zero, a random port is picked.
+	ListenPort int
+
+	// DiscoveryEnabled specifies whether the node should look for new peers via
+	// the discovery v5 protocol. If disabled, only the static peers are dialed.
+	//
+	// A false value is not replaced by the default when creating the node, so a
+	// literal &NodeConfig{} runs without discovery. Start from NewNodeConfig (or
+	// pass a nil config) to have it enabled.
+	DiscoveryEnabled bool
+
 	// MaxPeers is the maximum number of peers that can be connected. If this is
 	// set to zero, then only the configured static and trusted peers can connect.
 	MaxPeers int
@@ -67,9 +86,26 @@ type NodeConfig struct {
 	// NetworkChainNetStats is a netstats connection string to use to report various
 	// chain, transaction and node stats to a monitoring server.
 	//
-	// It has the form "nodename:secret@host:port"
+	// It has the form "nodename:secret@host:port". The host may be prefixed with
+	// ws:// or wss:// to force a plain or TLS connection, otherwise TLS is tried
+	// first, falling back to a plain connection.
 	NetworkChainNetStats string
 
+	// NetworkChainNetStatsCertPin is the hex encoded SHA256 fingerprint of the TLS
+	// certificate of the netstats server. If set, the server certificate is only
+	// accepted if it matches (instead of being verified against the certificate
+	// authorities), and the connection is never downgraded to plain text.
+	NetworkChainNetStatsCertPin string
+
+	// NetworkChainHeaderRetention is the number of recent headers to keep on disk,
+	// older ones being pruned and retrieved on demand. Zero keeps all headers.
+	NetworkChainHeaderRetention int64
+
+	// NetworkChainDataDirLimit is a soft cap in MB on the size of the node's data
+	// directory. If exceeded, the header retention is progressively tightened to
+	// curb further growth. Zero disables the limit.
+	NetworkChainDataDirLimit int64
+
 	// WhisperEnabled specifies whether the node should run the Whisper protocol.
 	WhisperEnabled bool
 }
@@ -79,6 +115,7 @@ type NodeConfig struct {
 var defaultNodeConfig = &NodeConfig{
 	BootstrapNodes:        FoundationBootnodes(),
 	MaxPeers:              25,
+	DiscoveryEnabled:      true,
 	NetworkChainEnabled:       true,
 	NetworkChainNetworkID:     1,
 	NetworkChainDatabaseCache: 16,
@@ -92,7 +129,10 @@ func NewNodeConfig() *NodeConfig {
 
 // Node represents a Netk NetworkChain node instance.
 type Node struct {
-	node *node.Node
+	node      *node.Node
+	limit     int64         // Soft cap on the data directory size in bytes (0 = unlimited)
+	retention uint64        // Header retention set by the user, restored once the data directory shrinks
+	quit      chan struct{} // Quit channel to stop the storage monitor
 }
 
 // NewNode creates and configures a new Netk node.
@@ -107,6 +147,9 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 	if config.BootstrapNodes == nil || config.BootstrapNodes.Size() == 0 {
 		config.BootstrapNodes = defaultNodeConfig.BootstrapNodes
 	}
+	if config.ListenPort < 0 || config.ListenPort > 65535 {
+		return nil, fmt.Errorf("invalid listen port: %d", config.ListenPort)
+	}
 	// Create the empty networking stack
 	nodeConf := &node.Config{
 		Name:        clientIdentifier,
@@ -115,10 +158,11 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 		KeyStoreDir: filepath.Join(datadir, "keystore"), // Mobile should never use internal keystores!
 		P2P: p2p.Config{
 			NoDiscovery:      true,
-			DiscoveryV5:      true,
+			DiscoveryV5:      config.DiscoveryEnabled,
 			DiscoveryV5Addr:  ":0",
 			BootstrapNodesV5: config.BootstrapNodes.nodes,
-			ListenAddr:       ":0",
+			StaticNodes:      config.StaticNodes.discoverNodes(),
+			ListenAddr:       fmt.Sprintf(":%d", config.ListenPort),
 			NAT:              nat.Any(),
 			MaxPeers:         config.MaxPeers,
 		},
@@ -150,18 +194,45 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 		ethConf.SyncMode = downloader.LightSync
 		ethConf.NetworkId = uint64(config.NetworkChainNetworkID)
 		ethConf.DatabaseCache = config.NetworkChainDatabaseCache
+		ethConf.LightHeaderRetention = uint64(config.NetworkChainHeaderRetention)
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, &ethConf)
+			lightNode, err := les.New(ctx, &ethConf)
+			if err != nil {
+				return nil, err
+			}
+			lightNode.Supervise(ctx.Go)
+			return lightNode, nil
 		}); err != nil {
 			return nil, fmt.Errorf("networkchain init: %v", err)
 		}
 		// If netstats reporting is requested, do it
 		if config.NetworkChainNetStats != "" {
+			var pin []byte
+			if config.NetworkChainNetStatsCertPin != "" {
+				var err error
+				if pin, err = hex.DecodeString(strings.Replace(config.NetworkChainNetStatsCertPin, ":", "", -1)); err != nil {
+					return nil, fmt.Errorf("invalid netstats certificate pin: %v", err)
+				}
+			}
 			if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+				// Report whichever networkchain service is running
+				var ethServ *eth.NetworkChain
+				ctx.Service(&ethServ)
+
 				var lesServ *les.LightNetworkChain
 				ctx.Service(&lesServ)
 
-				return ethstats.New(config.NetworkChainNetStats, nil, lesServ)
+				stats, err := ethstats.New(config.NetworkChainNetStats, ethServ, lesServ)
+				if err != nil {
+					return nil, err
+				}
+				stats.Supervise(ctx.Go)
+				if pin != nil {
+					if err := stats.PinCertificate(pin); err != nil {
+						return nil, err
+					}
+				}
+				return stats, nil
 			}); err != nil {
 				return nil, fmt.Errorf("netstats init: %v", err)
 			}
@@ -169,23 +240,43 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 	}
 	// Register the Whisper protocol if requested
 	if config.WhisperEnabled {
-		if err := rawStack.Register(func(*node.ServiceContext) (node.Service, error) {
-			return whisper.New(&whisper.DefaultConfig), nil
+		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			shh := whisper.New(&whisper.DefaultConfig)
+			shh.Supervise(ctx.Go)
+			return shh, nil
 		}); err != nil {
 			return nil, fmt.Errorf("whisper init: %v", err)
 		}
 	}
-	return &Node{rawStack}, nil
+	stack = &Node{
+		node:  rawStack,
+		limit: config.NetworkChainDataDirLimit * 1024 * 1024,
+	}
+	if config.NetworkChainHeaderRetention > 0 {
+		stack.retention = uint64(config.NetworkChainHeaderRetention)
+	}
+	return stack, nil
 }
 
 // Start creates a live P2P node and starts running it.
 func (n *Node) Start() error {
-	return n.node.Start()
+	if err := n.node.Start(); err != nil {
+		return err
+	}
+	if n.limit > 0 {
+		n.quit = make(chan struct{})
+		go n.monitorStorage(n.quit)
+	}
+	return nil
 }
 
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
+	if n.quit != nil {
+		close(n.quit)
+		n.quit = nil
+	}
 	return n.node.Stop()
 }
 
@@ -203,6 +294,31 @@ func (n *Node) GetNodeInfo() *NodeInfo {
 	return &NodeInfo{n.node.Server().NodeInfo()}
 }
 
+// AddPeer connects to the given node and maintains the connection until the node
+// is stopped or the peer removed, reconnecting if it drops.
+func (n *Node) AddPeer(enode *Enode) error {
+	if err := enode.validateComplete(); err != nil {
+		return err
+	}
+	server := n.node.Server()
+	if server == nil {
+		return errors.New("node not started")
+	}
+	server.AddPeer(enode.discoverNode())
+	return nil
+}
+
+// RemovePeer disconnects from the given node, if connected, and stops maintaining
+// the connection to it.
+func (n *Node) RemovePeer(enode *Enode) error {
+	server := n.node.Server()
+	if server == nil {
+		return errors.New("node not started")
+	}
+	server.RemovePeer(enode.discoverNode())
+	return nil
+}
+
 // GetPeersInfo returns an array of metadata objects describing connected peers.
 func (n *Node) GetPeersInfo() *PeerInfos {
 	return &PeerInfos{n.node.Server().PeersInfo()}
@@ -0,0 +1,181 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the wrappers to inspect and manage the on-disk storage of the node.
+
+package netk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkchain/networkchain/eth"
+	"github.com/networkchain/networkchain/les"
+	"github.com/networkchain/networkchain/log"
+)
+
+const (
+	// storageCheckInterval is the time between two data directory size checks.
+	storageCheckInterval = time.Minute
+
+	// minHeaderRetention is the lowest header retention the storage monitor will
+	// tighten to when the data directory limit is exceeded.
+	minHeaderRetention = 2048
+
+	// storageRelaxRatio is the fraction of the data directory limit (in percent)
+	// below which the storage monitor loosens a tightened header retention again.
+	storageRelaxRatio = 75
+)
+
+// errNodeRunning is returned if an operation requires the node to be stopped.
+var errNodeRunning = errors.New("node is running")
+
+// GetDataDirSize returns the total size in bytes of the node's data directory.
+func (n *Node) GetDataDirSize() (int64, error) {
+	return dirSize(n.node.InstanceDir())
+}
+
+// GetDatabaseSize returns the size in bytes of the node's chain database.
+func (n *Node) GetDatabaseSize() (int64, error) {
+	return dirSize(n.node.ResolvePath(les.DatabaseName))
+}
+
+// PruneHeaders sets the number of recent headers the node keeps on disk, older
+// ones being pruned progressively as new headers are imported. Zero disables
+// pruning. The node must be running.
+func (n *Node) PruneHeaders(retention int64) error {
+	var lesServ *les.LightNetworkChain
+	if err := n.node.Service(&lesServ); err != nil {
+		return err
+	}
+	if retention < 0 {
+		retention = 0
+	}
+	atomic.StoreUint64(&n.retention, uint64(retention))
+	lesServ.BlockChain().SetHeaderRetention(uint64(retention))
+	return nil
+}
+
+// PurgeCaches deletes the regenerable data (e.g. ethash verification caches)
+// from the node's data directory. The node must be stopped.
+func (n *Node) PurgeCaches() error {
+	if n.node.Server() != nil {
+		return errNodeRunning
+	}
+	return os.RemoveAll(n.node.ResolvePath(eth.DefaultConfig.EthashCacheDir))
+}
+
+// monitorStorage periodically checks the size of the data directory. If it exceeds
+// the configured limit, the header retention of the light chain is halved (down
+// to a minimum) to curb further growth. Once the size drops well below the limit,
+// the retention is doubled back towards the one configured by the user.
+func (n *Node) monitorStorage(quit chan struct{}) {
+	ticker := time.NewTicker(storageCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			size, err := n.GetDataDirSize()
+			if err != nil {
+				log.Warn("Failed to measure data directory", "err", err)
+				continue
+			}
+			var lesServ *les.LightNetworkChain
+			if err := n.node.Service(&lesServ); err != nil {
+				continue
+			}
+			chain := lesServ.BlockChain()
+
+			current := chain.HeaderRetention()
+			retention := adjustRetention(size, n.limit, current, atomic.LoadUint64(&n.retention), chain.CurrentHeader().Number.Uint64())
+			switch {
+			case retention == current:
+			case retention != 0 && (current == 0 || retention < current):
+				log.Warn("Data directory limit exceeded, tightening header retention", "size", size, "limit", n.limit, "retention", retention)
+				chain.SetHeaderRetention(retention)
+			default:
+				log.Info("Data directory below limit, loosening header retention", "size", size, "limit", n.limit, "retention", retention)
+				chain.SetHeaderRetention(retention)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// adjustRetention calculates the header retention of the light chain for the
+// given data directory size and limit, starting from the current retention and
+// never exceeding the one configured by the user (zero meaning unlimited).
+func adjustRetention(size, limit int64, current, configured, head uint64) uint64 {
+	// Halve the retention if the data directory is over the limit
+	if size > limit {
+		retention := current
+		if retention == 0 {
+			retention = head
+		}
+		if retention /= 2; retention < minHeaderRetention {
+			retention = minHeaderRetention
+		}
+		if configured != 0 && retention > configured {
+			retention = configured
+		}
+		if current != 0 && retention > current {
+			retention = current
+		}
+		return retention
+	}
+	// Double a tightened retention back if well below the limit
+	if current == configured || size*100 >= limit*storageRelaxRatio {
+		return current
+	}
+	if current == 0 {
+		return configured
+	}
+	retention := current * 2
+	if configured == 0 {
+		if retention >= head {
+			return 0
+		}
+		return retention
+	}
+	if retention > configured {
+		retention = configured
+	}
+	return retention
+}
+
+// dirSize returns the total size of all the files within a directory tree. A
+// missing directory is considered empty.
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package netk
+
+import "testing"
+
+// Tests that the header retention is tightened while the data directory exceeds
+// its limit, and loosened back towards the configured one once it shrinks.
+func TestAdjustRetention(t *testing.T) {
+	const limit = 1000
+
+	tests := []struct {
+		size       int64
+		current    uint64
+		configured uint64
+		head       uint64
+		retention  uint64
+	}{
+		// Over the limit, the retention is halved down to the minimum
+		{1200, 0, 0, 100000, 50000},
+		{1200, 50000, 0, 100000, 25000},
+		{1200, 40000, 100000, 100000, 20000},
+		{1200, 3000, 0, 100000, minHeaderRetention},
+		{1200, minHeaderRetention, 0, 100000, minHeaderRetention},
+		{1200, 1000, 1000, 100000, 1000}, // never loosened when over the limit
+		{1200, 0, 30000, 100000, 30000},  // never above the configured retention
+
+		// Around the limit, the retention is left alone
+		{1000, 25000, 0, 100000, 25000},
+		{800, 25000, 0, 100000, 25000},
+
+		// Well below the limit, the retention is doubled up to the configured one
+		{700, 25000, 0, 100000, 50000},
+		{700, 50000, 0, 100000, 0},
+		{700, 20000, 30000, 100000, 30000},
+		{700, 5000, 30000, 100000, 10000},
+		{700, 30000, 30000, 100000, 30000},
+		{700, 0, 0, 100000, 0},
+	}
+	for i, tt := range tests {
+		if retention := adjustRetention(tt.size, limit, tt.current, tt.configured, tt.head); retention != tt.retention {
+			t.Errorf("test %d: retention mismatch: have %d, want %d", i, retention, tt.retention)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the sync progress notifications of the embedded node.
+
+package netk
+
+import (
+	"errors"
+	"time"
+
+	"github.com/networkchain/networkchain/eth/downloader"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/les"
+)
+
+// syncProgressInterval is the interval of the progress reports while syncing.
+const syncProgressInterval = time.Second
+
+// SyncProgressHandler is a callback to invoke on sync progress and failures.
+type SyncProgressHandler interface {
+	OnSyncProgress(progress *SyncProgress)
+	OnError(failure string)
+}
+
+// SubscribeSyncProgress reports the synchronisation progress of the started node
+// to the handler: whenever a sync cycle starts, periodically while it runs, and
+// once more when it finishes. Failed sync cycles are reported as errors, after
+// which the node keeps retrying with other peers.
+func (n *Node) SubscribeSyncProgress(handler SyncProgressHandler) (sub *Subscription, _ error) {
+	var lesServ *les.LightNetworkChain
+	if err := n.node.Service(&lesServ); err != nil {
+		return nil, errors.New("networkchain service not running")
+	}
+	rawSub := event.NewSubscription(func(quit <-chan struct{}) error {
+		events := lesServ.EventMux().Subscribe(downloader.StartEvent{}, downloader.DoneEvent{}, downloader.FailedEvent{})
+		defer events.Unsubscribe()
+
+		ticker := time.NewTicker(syncProgressInterval)
+		defer ticker.Stop()
+
+		report := func() {
+			handler.OnSyncProgress(&SyncProgress{lesServ.Downloader().Progress()})
+		}
+		syncing := lesServ.Downloader().Synchronising()
+		for {
+			select {
+			case ev, ok := <-events.Chan():
+				if !ok {
+					return nil
+				}
+				switch ev := ev.Data.(type) {
+				case downloader.StartEvent:
+					syncing = true
+				case downloader.DoneEvent:
+					syncing = false
+				case downloader.FailedEvent:
+					syncing = false
+					handler.OnError(ev.Err.Error())
+					continue
+				}
+				report()
+
+			case <-ticker.C:
+				if syncing {
+					report()
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	})
+	return &Subscription{rawSub}, nil
+}
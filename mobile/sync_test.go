@@ -0,0 +1,54 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package netk
+
+import (
+	"testing"
+
+	networkchain "github.com/networkchain/networkchain"
+)
+
+// Tests that the sync progress reports the stage and completion of the sync.
+func TestSyncProgress(t *testing.T) {
+	tests := []struct {
+		progress   networkchain.SyncProgress
+		stage      string
+		percentage float64
+		synced     int64
+		remaining  int64
+	}{
+		{networkchain.SyncProgress{}, SyncStageDone, 100, 0, 0},
+		{networkchain.SyncProgress{StartingBlock: 100, CurrentBlock: 150, HighestBlock: 300}, SyncStageChain, 25, 50, 150},
+		{networkchain.SyncProgress{StartingBlock: 0, CurrentBlock: 300, HighestBlock: 300, PulledStates: 10, KnownStates: 20}, SyncStageState, 100, 300, 0},
+		{networkchain.SyncProgress{StartingBlock: 0, CurrentBlock: 300, HighestBlock: 300, PulledStates: 20, KnownStates: 20}, SyncStageDone, 100, 300, 0},
+	}
+	for i, tt := range tests {
+		progress := &SyncProgress{tt.progress}
+		if stage := progress.GetStage(); stage != tt.stage {
+			t.Errorf("test %d: stage mismatch: have %s, want %s", i, stage, tt.stage)
+		}
+		if percentage := progress.GetPercentage(); percentage != tt.percentage {
+			t.Errorf("test %d: percentage mismatch: have %v, want %v", i, percentage, tt.percentage)
+		}
+		if synced := progress.GetSyncedHeaders(); synced != tt.synced {
+			t.Errorf("test %d: synced headers mismatch: have %d, want %d", i, synced, tt.synced)
+		}
+		if remaining := progress.GetRemainingHeaders(); remaining != tt.remaining {
+			t.Errorf("test %d: remaining headers mismatch: have %d, want %d", i, remaining, tt.remaining)
+		}
+	}
+}
@@ -43,7 +43,16 @@ import (
 	"github.com/networkchain/networkchain/trie"
 )
 
-const defaultTraceTimeout = 5 * time.Second
+const (
+	// defaultTraceTimeout is the amount of time a single transaction can execute
+	// by default before being forcefully aborted.
+	defaultTraceTimeout = 5 * time.Second
+
+	// defaultTraceReexec is the number of blocks the tracer is willing to go back
+	// and re-execute to produce missing historical state necessary to run a specific
+	// trace.
+	defaultTraceReexec = uint64(128)
+)
 
 // PublicNetworkChainAPI provides an API to access NetworkChain full node-related
 // information.
@@ -80,7 +89,7 @@ type PublicMinerAPI struct {
 
 // NewPublicMinerAPI create a new PublicMinerAPI instance.
 func NewPublicMinerAPI(e *NetworkChain) *PublicMinerAPI {
-	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine())
+	agent := miner.NewRemoteAgent(e.BlockChain(), e.Engine(), e.minerNotify)
 	e.Miner().Register(agent)
 
 	return &PublicMinerAPI{e, agent}
@@ -319,12 +328,7 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		_, stateDb := api.eth.miner.Pending()
 		return stateDb.RawDump(), nil
 	}
-	var block *types.Block
-	if blockNr == rpc.LatestBlockNumber {
-		block = api.eth.blockchain.CurrentBlock()
-	} else {
-		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
-	}
+	block, _ := api.eth.ApiBackend.BlockByNumber(context.Background(), blockNr)
 	if block == nil {
 		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
 	}
@@ -361,6 +365,7 @@ type TraceArgs struct {
 	*vm.LogConfig
 	Tracer  *string
 	Timeout *string
+	Reexec  *uint64 // Number of blocks to re-execute if the historical state is missing
 }
 
 // TraceBlock processes the given block'api RLP but does not import the block in to
@@ -392,18 +397,8 @@ func (api *PrivateDebugAPI) TraceBlockFromFile(file string, config *vm.LogConfig
 
 // TraceBlockByNumber processes the block by canonical block number.
 func (api *PrivateDebugAPI) TraceBlockByNumber(blockNr rpc.BlockNumber, config *vm.LogConfig) BlockTraceResult {
-	// Fetch the block that we aim to reprocess
-	var block *types.Block
-	switch blockNr {
-	case rpc.PendingBlockNumber:
-		// Pending block is only known by the miner
-		block = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		block = api.eth.blockchain.CurrentBlock()
-	default:
-		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
-	}
-
+	// Fetch the block that we aim to reprocess, the pending one known by the miner
+	block, _ := api.eth.ApiBackend.BlockByNumber(context.Background(), blockNr)
 	if block == nil {
 		return BlockTraceResult{Error: fmt.Sprintf("block #%d not found", blockNr)}
 	}
@@ -450,7 +445,11 @@ func (api *PrivateDebugAPI) traceBlock(block *types.Block, logConfig *vm.LogConf
 	if err := api.eth.engine.VerifyHeader(blockchain, block.Header(), true); err != nil {
 		return false, structLogger.StructLogs(), err
 	}
-	statedb, err := blockchain.StateAt(blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1).Root())
+	parent := blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return false, structLogger.StructLogs(), fmt.Errorf("block parent %x not found", block.ParentHash())
+	}
+	statedb, err := api.computeStateDB(parent, defaultTraceReexec)
 	if err != nil {
 		return false, structLogger.StructLogs(), err
 	}
@@ -500,43 +499,70 @@ func (t *timeoutError) Error() string {
 	return "Execution time exceeded"
 }
 
-// TraceTransaction returns the structured logs created during the execution of EVM
-// and returns them as a JSON object.
-func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
-	var tracer vm.Tracer
-	if config != nil && config.Tracer != nil {
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			var err error
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-
+// newTracer creates the tracer requested by the trace configuration. The returned
+// function must be called to release the resources of the tracer once done.
+func newTracer(ctx context.Context, config *TraceArgs) (vm.Tracer, func(), error) {
+	if config == nil {
+		return vm.NewStructLogger(nil), func() {}, nil
+	}
+	if config.Tracer == nil {
+		return vm.NewStructLogger(config.LogConfig), func() {}, nil
+	}
+	timeout := defaultTraceTimeout
+	if config.Timeout != nil {
 		var err error
-		if tracer, err = ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
-			return nil, err
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, nil, err
 		}
+	}
+	tracer, err := ethapi.NewJavascriptTracer(*config.Tracer)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Handle timeouts and RPC cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		tracer.Stop(&timeoutError{})
+	}()
+	return tracer, cancel, nil
+}
+
+// traceResult assembles the RPC result of a traced execution.
+func traceResult(tracer vm.Tracer, ret []byte, gas *big.Int) (interface{}, error) {
+	switch tracer := tracer.(type) {
+	case *vm.StructLogger:
+		return &ethapi.ExecutionResult{
+			Gas:         gas,
+			ReturnValue: ret,
+			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
+		}, nil
+	case *ethapi.JavascriptTracer:
+		return tracer.GetResult()
+	default:
+		panic(fmt.Sprintf("bad tracer type %T", tracer))
+	}
+}
 
-		// Handle timeouts and RPC cancellations
-		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
-			<-deadlineCtx.Done()
-			tracer.(*ethapi.JavascriptTracer).Stop(&timeoutError{})
-		}()
-		defer cancel()
-	} else if config == nil {
-		tracer = vm.NewStructLogger(nil)
-	} else {
-		tracer = vm.NewStructLogger(config.LogConfig)
+// TraceTransaction returns the structured logs created during the execution of EVM
+// and returns them as a JSON object.
+func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
+	tracer, release, err := newTracer(ctx, config)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	// Retrieve the tx from the chain and the containing block
 	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
 	if tx == nil {
 		return nil, fmt.Errorf("transaction %x not found", txHash)
 	}
-	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex), reexec)
 	if err != nil {
 		return nil, err
 	}
@@ -547,22 +573,104 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
-	switch tracer := tracer.(type) {
-	case *vm.StructLogger:
-		return &ethapi.ExecutionResult{
-			Gas:         gas,
-			ReturnValue: fmt.Sprintf("%x", ret),
-			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
-	case *ethapi.JavascriptTracer:
-		return tracer.GetResult()
-	default:
-		panic(fmt.Sprintf("bad tracer type %T", tracer))
+	return traceResult(tracer, ret, gas)
+}
+
+// TraceCallArgs holds the parameters of tracing a call, extending the trace
+// configuration with optional state overrides applied before the call.
+type TraceCallArgs struct {
+	TraceArgs
+	StateOverrides *ethapi.StateOverride
+}
+
+// TraceCall executes the given call on top of the state of the given block with
+// tracing enabled and returns the trace, without an on-chain transaction being
+// needed. Gas and gas price default as with eth_call, and the sender is credited
+// the balance needed to pay for them.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNr rpc.BlockNumber, config *TraceCallArgs) (interface{}, error) {
+	statedb, header, err := api.eth.ApiBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
+		}
+		return nil, err
+	}
+	var traceConfig *TraceArgs
+	if config != nil {
+		if config.StateOverrides != nil {
+			if err := config.StateOverrides.Apply(statedb); err != nil {
+				return nil, err
+			}
+		}
+		traceConfig = &config.TraceArgs
+	}
+	tracer, release, err := newTracer(ctx, traceConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Run the call with tracing enabled
+	msg := args.ToMessage(args.From)
+	vmenv, _, err := api.eth.ApiBackend.GetEVM(ctx, msg, statedb, header, vm.Config{Debug: true, Tracer: tracer})
+	if err != nil {
+		return nil, err
+	}
+	ret, gas, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	return traceResult(tracer, ret, gas)
+}
+
+// computeStateDB retrieves the state database associated with a certain block.
+// If no state is locally available for the given block, a number of blocks are
+// attempted to be reexecuted to generate the desired state, allowing historical
+// traces on nodes not running in archive mode.
+func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*state.StateDB, error) {
+	// If we have the state fully available, use that
+	blockchain := api.eth.BlockChain()
+	statedb, err := blockchain.StateAt(block.Root())
+	if err == nil {
+		return statedb, nil
 	}
+	// Otherwise try to reexec blocks until we find a state or reach our limit
+	var blocks []*types.Block
+	for i := uint64(0); i < reexec; i++ {
+		blocks = append(blocks, block)
+		if block = blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1); block == nil {
+			break
+		}
+		if statedb, err = blockchain.StateAt(block.Root()); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		switch err.(type) {
+		case *trie.MissingNodeError:
+			return nil, fmt.Errorf("required historical state unavailable (reexec=%d)", reexec)
+		default:
+			return nil, err
+		}
+	}
+	// State was available at historical point, regenerate
+	log.Info("Regenerating historical state", "block", blocks[len(blocks)-1].NumberU64(), "count", len(blocks))
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		if _, _, _, err := blockchain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+			return nil, fmt.Errorf("failed to regenerate state of block #%d: %v", block.NumberU64(), err)
+		}
+		if root := statedb.IntermediateRoot(api.config.IsEIP158(block.Number())); root != block.Root() {
+			return nil, fmt.Errorf("regenerated state root mismatch of block #%d: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+	}
+	return statedb, nil
 }
 
-// computeTxEnv returns the execution environment of a certain transaction.
-func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
+// computeTxEnv returns the execution environment of a certain transaction,
+// regenerating the parent state by reexecuting at most reexec blocks if missing.
+func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, reexec uint64) (core.Message, vm.Context, *state.StateDB, error) {
 	// Create the parent state.
 	block := api.eth.BlockChain().GetBlockByHash(blockHash)
 	if block == nil {
@@ -572,7 +680,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 	if parent == nil {
 		return nil, vm.Context{}, nil, fmt.Errorf("block parent %x not found", block.ParentHash())
 	}
-	statedb, err := api.eth.BlockChain().StateAt(parent.Root())
+	statedb, err := api.computeStateDB(parent, reexec)
 	if err != nil {
 		return nil, vm.Context{}, nil, err
 	}
@@ -605,12 +713,48 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return db.Get(hash.Bytes())
 }
 
+// PurgeCaches drops all the headers, blocks and state tries cached in memory by
+// the blockchain, releasing the memory to be reallocated on demand.
+func (api *PrivateDebugAPI) PurgeCaches() {
+	api.eth.BlockChain().PurgeCaches()
+}
+
 // GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
 // and returns them as a JSON list of block-hashes
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockArgs, error) {
 	return api.eth.BlockChain().BadBlocks()
 }
 
+// AccessStats returns the transaction conflict statistics recorded while importing
+// the given block. Statistics are only gathered if state access tracking is
+// enabled, and are retained for the most recently processed blocks only.
+func (api *PrivateDebugAPI) AccessStats(ctx context.Context, blockNr rpc.BlockNumber) (*core.AccessStats, error) {
+	block, _ := api.eth.ApiBackend.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	stats := api.eth.blockchain.AccessStats(block.Hash())
+	if stats == nil {
+		return nil, fmt.Errorf("no access statistics for block #%d", block.NumberU64())
+	}
+	return stats, nil
+}
+
+// ExecutionWitness returns the execution witness recorded while importing the
+// given block: the parent state trie nodes and contract codes it touched. It is
+// only available for recent blocks if witness recording is enabled.
+func (api *PrivateDebugAPI) ExecutionWitness(ctx context.Context, blockNr rpc.BlockNumber) (*core.Witness, error) {
+	block, _ := api.eth.ApiBackend.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	witness := api.eth.blockchain.Witness(block.Hash())
+	if witness == nil {
+		return nil, fmt.Errorf("no execution witness for block #%d", block.NumberU64())
+	}
+	return witness, nil
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -626,7 +770,7 @@ type storageEntry struct {
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex)
+	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex, defaultTraceReexec)
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
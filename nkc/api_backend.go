@@ -21,6 +21,7 @@ import (
 	"math/big"
 
 	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/accounts/policy"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/math"
 	"github.com/networkchain/networkchain/core"
@@ -31,14 +32,19 @@ import (
 	"github.com/networkchain/networkchain/eth/gasprice"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
 	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rpc"
 )
 
 // EthApiBackend implements ethapi.Backend for full nodes
 type EthApiBackend struct {
-	eth *NetworkChain
-	gpo *gasprice.Oracle
+	eth    *NetworkChain
+	gpo    *gasprice.Oracle
+	policy *policy.Policy // Restrictions on the signing RPC endpoints
+
+	safeDepth     uint64 // Confirmations resolving the "safe" block tag
+	finalityDepth uint64 // Confirmations resolving the "finalized" block tag
 }
 
 func (b *EthApiBackend) ChainConfig() *params.ChainConfig {
@@ -61,6 +67,7 @@ func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 		return block.Header(), nil
 	}
 	// Otherwise resolve and return the block
+	blockNr = ethapi.ResolveFinality(blockNr, b.eth.blockchain.CurrentBlock().NumberU64(), b.safeDepth, b.finalityDepth)
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
@@ -74,6 +81,7 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 		return block, nil
 	}
 	// Otherwise resolve and return the block
+	blockNr = ethapi.ResolveFinality(blockNr, b.eth.blockchain.CurrentBlock().NumberU64(), b.safeDepth, b.finalityDepth)
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
@@ -95,12 +103,21 @@ func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return stateDb, header, err
 }
 
+func (b *EthApiBackend) StateAndHeaderByHash(ctx context.Context, blockHash common.Hash) (*state.StateDB, *types.Header, error) {
+	header := b.eth.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, nil, nil
+	}
+	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	return stateDb, header, err
+}
+
 func (b *EthApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.eth.blockchain.GetBlockByHash(blockHash), nil
 }
 
 func (b *EthApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
-	return core.GetBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash)), nil
+	return core.GetDerivedBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash), b.eth.chainConfig), nil
 }
 
 func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
@@ -119,6 +136,10 @@ func (b *EthApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.eth.txPool.AddPrivate(signedTx)
+}
+
 func (b *EthApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.Remove(txHash)
 }
@@ -135,6 +156,17 @@ func (b *EthApiBackend) GetPoolTransactions() (types.Transactions, error) {
 	return txs, nil
 }
 
+func (b *EthApiBackend) GetPoolTransactionsFrom(addrs []common.Address) (types.Transactions, error) {
+	pending := b.eth.txPool.PendingFrom(addrs)
+
+	var txs types.Transactions
+	for _, addr := range addrs {
+		txs = append(txs, pending[addr]...)
+		delete(pending, addr)
+	}
+	return txs, nil
+}
+
 func (b *EthApiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
 	return b.eth.txPool.Get(hash)
 }
@@ -174,3 +206,7 @@ func (b *EthApiBackend) EventMux() *event.TypeMux {
 func (b *EthApiBackend) AccountManager() *accounts.Manager {
 	return b.eth.AccountManager()
 }
+
+func (b *EthApiBackend) SigningPolicy() *policy.Policy {
+	return b.policy
+}
@@ -17,13 +17,24 @@
 package eth
 
 import (
+	"context"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/core/vm"
 	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -86,3 +97,169 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that transactions can be traced on a node lacking the historical state,
+// regenerating it by reexecuting blocks from the nearest available state.
+func TestTraceTransactionReexec(t *testing.T) {
+	var (
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		genDb, _ = ethdb.NewMemDatabase()
+		genesis  = gspec.MustCommit(genDb)
+		signer   = types.HomesteadSigner{}
+	)
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, genDb, 4, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{0x01}, big.NewInt(1000), new(big.Int).SetUint64(params.TxGas), nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	// Import the chain without state, as a fast synced node would
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer blockchain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := blockchain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := blockchain.InsertReceiptChain(blocks, receipts); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	api := NewPrivateDebugAPI(gspec.Config, &NetworkChain{blockchain: blockchain, chainDb: db})
+
+	// Trace the last transaction, regenerating the state from genesis
+	txHash := blocks[3].Transactions()[0].Hash()
+	result, err := api.TraceTransaction(context.Background(), txHash, nil)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if res := result.(*ethapi.ExecutionResult); res.Gas.Uint64() != params.TxGas {
+		t.Fatalf("traced gas mismatch: have %v, want %v", res.Gas, params.TxGas)
+	}
+	// Ensure tracing fails if not allowed to reexecute far enough
+	reexec := uint64(1)
+	if _, err := api.TraceTransaction(context.Background(), txHash, &TraceArgs{Reexec: &reexec}); err == nil || !strings.Contains(err.Error(), "historical state unavailable") {
+		t.Fatalf("error mismatch: have %v, want historical state unavailable", err)
+	}
+}
+
+// Tests that the safe and finalized block tags resolve to the blocks with the
+// configured number of confirmations.
+func TestFinalityTags(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 10, nil)
+	if n, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	backend := &EthApiBackend{eth: &NetworkChain{blockchain: blockchain, chainDb: db}, safeDepth: 3, finalityDepth: 12}
+
+	tests := []struct {
+		blockNr rpc.BlockNumber
+		number  uint64
+	}{
+		{rpc.LatestBlockNumber, 10},
+		{rpc.SafeBlockNumber, 8},
+		{rpc.FinalizedBlockNumber, 0}, // chain shorter than the finality depth
+		{rpc.BlockNumber(5), 5},
+	}
+	for i, tt := range tests {
+		header, err := backend.HeaderByNumber(context.Background(), tt.blockNr)
+		if err != nil || header == nil {
+			t.Fatalf("test %d: failed to retrieve header: %v", i, err)
+		}
+		if header.Number.Uint64() != tt.number {
+			t.Errorf("test %d: header number mismatch: have %d, want %d", i, header.Number, tt.number)
+		}
+		block, err := backend.BlockByNumber(context.Background(), tt.blockNr)
+		if err != nil || block == nil {
+			t.Fatalf("test %d: failed to retrieve block: %v", i, err)
+		}
+		if block.Hash() != header.Hash() {
+			t.Errorf("test %d: block mismatch: have %x, want %x", i, block.Hash(), header.Hash())
+		}
+	}
+}
+
+// Tests that calls are traced on top of the state of the requested block, both
+// with the default struct logger and with a custom tracer.
+func TestTraceCall(t *testing.T) {
+	// Deploy a counter incrementing and returning its first storage slot:
+	//   PUSH1 0 SLOAD PUSH1 1 ADD DUP1 PUSH1 0 SSTORE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	var (
+		counter = common.Address{0xcc}
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank: {Balance: big.NewInt(1000000000)},
+				counter:  {Code: common.FromHex("0x6000546001018060005560005260206000f3"), Balance: new(big.Int)},
+			},
+		}
+		db, _   = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	// Increment the counter once in each of the blocks
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 3, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), counter, new(big.Int), big.NewInt(100000), nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer blockchain.Stop()
+
+	if n, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	eth := &NetworkChain{chainConfig: gspec.Config, blockchain: blockchain, chainDb: db}
+	eth.ApiBackend = &EthApiBackend{eth: eth}
+	api := NewPrivateDebugAPI(gspec.Config, eth)
+
+	tracer := "{count: 0, step: function() { this.count++ }, result: function() { return this.count }}"
+	tests := []struct {
+		blockNr rpc.BlockNumber
+		config  *TraceCallArgs
+		value   uint64 // Counter value returned with the struct logger
+		steps   int    // Opcodes executed by the call
+	}{
+		{rpc.BlockNumber(0), nil, 1, 12},
+		{rpc.BlockNumber(1), nil, 2, 12},
+		{rpc.LatestBlockNumber, nil, 4, 12},
+		{rpc.BlockNumber(2), &TraceCallArgs{TraceArgs: TraceArgs{Tracer: &tracer}}, 0, 12},
+	}
+	for i, tt := range tests {
+		result, err := api.TraceCall(context.Background(), ethapi.CallArgs{To: &counter}, tt.blockNr, tt.config)
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		switch res := result.(type) {
+		case *ethapi.ExecutionResult:
+			if value := new(big.Int).SetBytes(res.ReturnValue); value.Uint64() != tt.value {
+				t.Errorf("test %d: return value mismatch: have %v, want %d", i, value, tt.value)
+			}
+			if len(res.StructLogs) != tt.steps {
+				t.Errorf("test %d: struct log count mismatch: have %d, want %d", i, len(res.StructLogs), tt.steps)
+			}
+		default:
+			if count, ok := res.(float64); !ok || int(count) != tt.steps {
+				t.Errorf("test %d: custom trace result mismatch: have %v, want %d", i, res, tt.steps)
+			}
+		}
+	}
+	// Ensure missing blocks are reported instead of traced
+	if _, err := api.TraceCall(context.Background(), ethapi.CallArgs{To: &counter}, rpc.BlockNumber(10), nil); err == nil {
+		t.Errorf("traced call on missing block")
+	}
+}
@@ -26,11 +26,13 @@ import (
 	"sync/atomic"
 
 	"github.com/networkchain/networkchain/accounts"
+	"github.com/networkchain/networkchain/accounts/policy"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/consensus"
 	"github.com/networkchain/networkchain/consensus/clique"
 	"github.com/networkchain/networkchain/consensus/ethash"
+	"github.com/networkchain/networkchain/consensus/remote"
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/core/vm"
@@ -53,6 +55,7 @@ type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
+	PauseIndexing(paused bool)
 }
 
 // NetworkChain implements the NetworkChain full node service.
@@ -66,6 +69,7 @@ type NetworkChain struct {
 	blockchain      *core.BlockChain
 	protocolManager *ProtocolManager
 	lesServer       LesServer
+	rescuer         *txRescuer
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
@@ -75,11 +79,13 @@ type NetworkChain struct {
 
 	ApiBackend *EthApiBackend
 
-	miner     *miner.Miner
-	gasPrice  *big.Int
-	etherbase common.Address
+	miner       *miner.Miner
+	minerNotify []string // Remote miner URLs to push new work packages to
+	gasPrice    *big.Int
+	etherbase   common.Address
 
 	networkId     uint64
+	lightPeers    int // Peer slots reserved for LES clients (0 = not serving)
 	netRPCService *ethapi.PublicNetAPI
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
@@ -89,6 +95,40 @@ func (s *NetworkChain) AddLesServer(ls LesServer) {
 	s.lesServer = ls
 }
 
+// ethPeerLimit returns the number of eth peers allowed within the global peer
+// limit. If we are running a light server, the number of eth peers is limited so
+// that we reserve some space for incoming LES connections (temporary solution
+// until the new peer connectivity API is finished).
+func ethPeerLimit(maxPeers, lightPeers int) int {
+	if lightPeers == 0 {
+		return maxPeers
+	}
+	halfPeers := maxPeers / 2
+	maxPeers -= lightPeers
+	if maxPeers < halfPeers {
+		maxPeers = halfPeers
+	}
+	return maxPeers
+}
+
+// SetMaxPeers adjusts the eth peer limit to a changed global peer limit.
+func (s *NetworkChain) SetMaxPeers(maxPeers int) {
+	s.protocolManager.SetMaxPeers(ethPeerLimit(maxPeers, s.lightPeers))
+}
+
+// PauseSync suspends or resumes the synchronisation of the chain with its peers.
+func (s *NetworkChain) PauseSync(paused bool) {
+	s.protocolManager.PauseSync(paused)
+}
+
+// PauseIndexing suspends or resumes the generation of the CHTs and bloom tries
+// served to light clients, if running a light server.
+func (s *NetworkChain) PauseIndexing(paused bool) {
+	if s.lesServer != nil {
+		s.lesServer.PauseIndexing(paused)
+	}
+}
+
 // New creates a new NetworkChain object (including the
 // initialisation of the common NetworkChain object)
 func New(ctx *node.ServiceContext, config *Config) (*NetworkChain, error) {
@@ -129,18 +169,42 @@ func New(ctx *node.ServiceContext, config *Config) (*NetworkChain, error) {
 	log.Info("Initialising NetworkChain protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
 	if !config.SkipBcVersionCheck {
-		bcVersion := core.GetBlockChainVersion(chainDb)
-		if bcVersion != core.BlockChainVersion && bcVersion != 0 {
-			return nil, fmt.Errorf("Blockchain DB version mismatch (%d / %d). Run netk upgradedb.\n", bcVersion, core.BlockChainVersion)
+		if err := checkBlockChainVersion(chainDb); err != nil {
+			return nil, err
 		}
-		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
 
-	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+	// Size the trie cache and the garbage collector before any state is accessed
+	if config.TrieCache > 0 {
+		balancer := newCacheBalancer(CacheBudget{
+			Database: config.DatabaseCache,
+			Trie:     config.TrieCache,
+			GC:       config.GCHeadroom,
+		})
+		balancer.apply()
+		go eth.balanceCaches(balancer)
+	}
+
+	vmConfig := vm.Config{
+		EnablePreimageRecording: config.EnablePreimageRecording,
+		TrackStateAccess:        config.TrackStateAccess,
+		ParallelExecution:       config.ParallelExecution,
+		RecordWitness:           config.RecordWitness,
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.engine, eth.eventMux, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	if config.StateGC {
+		eth.blockchain.EnableStateGC(core.DefaultStateGCLimit)
+	}
+	eth.blockchain.SetLogIndexing(config.LogIndex)
+	if config.StateSnapshot {
+		if err := eth.blockchain.EnableSnapshots(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -148,28 +212,46 @@ func New(ctx *node.ServiceContext, config *Config) (*NetworkChain, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 
+	if config.TxPool.Journal != "" {
+		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
+	}
 	newPool := core.NewTxPool(config.TxPool, eth.chainConfig, eth.EventMux(), eth.blockchain.State, eth.blockchain.GasLimit)
 	eth.txPool = newPool
 
-	maxPeers := config.MaxPeers
 	if config.LightServ > 0 {
-		// if we are running a light server, limit the number of ETH peers so that we reserve some space for incoming LES connections
-		// temporary solution until the new peer connectivity API is finished
-		halfPeers := maxPeers / 2
-		maxPeers -= config.LightPeers
-		if maxPeers < halfPeers {
-			maxPeers = halfPeers
-		}
+		eth.lightPeers = config.LightPeers
 	}
-
-	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, maxPeers, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
+	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, ethPeerLimit(config.MaxPeers, eth.lightPeers), eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if config.StaticSync {
+		eth.protocolManager.setSyncPeers(ctx.StaticNodes())
+	}
+	if config.SyncAnchor != (common.Hash{}) {
+		log.Info("Anchored block synchronisation to trusted block", "hash", config.SyncAnchor)
+		eth.protocolManager.downloader.SetAnchor(config.SyncAnchor)
+	}
 
+	if config.TxRescue.Enabled {
+		if eth.rescuer, err = newTxRescuer(config.TxRescue, eth.txPool, eth.protocolManager.BroadcastTx, eth.signTx); err != nil {
+			return nil, err
+		}
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
+	eth.minerNotify = config.MinerNotify
+	if config.MinerTxOrder != "" {
+		ordering, ok := miner.TxOrderings[config.MinerTxOrder]
+		if !ok {
+			return nil, fmt.Errorf("unknown miner transaction ordering %q", config.MinerTxOrder)
+		}
+		eth.miner.SetTxOrdering(ordering)
+	}
 
-	eth.ApiBackend = &EthApiBackend{eth, nil}
+	eth.ApiBackend = &EthApiBackend{eth: eth, safeDepth: config.SafeDepth, finalityDepth: config.FinalityDepth}
+	if eth.ApiBackend.policy, err = CreateSigningPolicy(ctx, config); err != nil {
+		return nil, err
+	}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.GasPrice
@@ -208,8 +290,29 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 	return db, nil
 }
 
+// CreateSigningPolicy loads the rules restricting the accounts the RPC endpoints
+// may sign with, or returns nil if no policy file was configured.
+func CreateSigningPolicy(ctx *node.ServiceContext, config *Config) (*policy.Policy, error) {
+	if config.SigningPolicy == "" {
+		return nil, nil
+	}
+	return policy.Load(config.SigningPolicy, ctx.ResolvePath("signingpolicy.journal"))
+}
+
 // CreateConsensusEngine creates the required type of consensus engine instance for an NetworkChain service
 func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig *params.ChainConfig, db ethdb.Database) consensus.Engine {
+	engine := createBaseEngine(ctx, config, chainConfig, db)
+
+	// If an external sealer is requested, delegate verification and sealing to it
+	if config.RemoteSealer != "" {
+		log.Warn("Delegating consensus to remote sealer", "endpoint", config.RemoteSealer)
+		return remote.New(engine, config.RemoteSealer)
+	}
+	return engine
+}
+
+// createBaseEngine creates the consensus engine specified by the chain config.
+func createBaseEngine(ctx *node.ServiceContext, config *Config, chainConfig *params.ChainConfig, db ethdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
 	if chainConfig.Clique != nil {
 		return clique.New(chainConfig.Clique, db)
@@ -344,6 +447,23 @@ func (s *NetworkChain) StartMining(local bool) error {
 	return nil
 }
 
+// signTx signs a transaction with the wallet holding the given account, as long
+// as it is unlocked. It is used to sign re-priced replacements of stuck local
+// transactions.
+func (s *NetworkChain) signTx(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	account := accounts.Account{Address: from}
+
+	wallet, err := s.accountManager.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var chainID *big.Int
+	if config := s.chainConfig; config.IsEIP155(s.blockchain.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+	return wallet.SignTx(account, tx, chainID)
+}
+
 func (s *NetworkChain) StopMining()         { s.miner.Stop() }
 func (s *NetworkChain) IsMining() bool      { return s.miner.Mining() }
 func (s *NetworkChain) Miner() *miner.Miner { return s.miner }
@@ -378,6 +498,9 @@ func (s *NetworkChain) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.rescuer != nil {
+		s.rescuer.Start()
+	}
 	return nil
 }
 
@@ -388,6 +511,9 @@ func (s *NetworkChain) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.blockchain.Stop()
+	if s.rescuer != nil {
+		s.rescuer.Stop()
+	}
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
@@ -81,3 +81,33 @@ func TestMipmapUpgrade(t *testing.T) {
 		t.Error("setting-mipmap-version not written to database")
 	}
 }
+
+func TestBlockChainVersionUpgrade(t *testing.T) {
+	tests := []struct {
+		stored  int
+		wantErr bool
+	}{
+		{0, false}, // fresh database
+		{core.SeparateTdBlockChainVersion, false}, // upgraded in place
+		{core.BlockChainVersion, false},
+		{core.SeparateTdBlockChainVersion - 1, true},
+		{core.BlockChainVersion + 1, true},
+	}
+	for i, tt := range tests {
+		db, _ := ethdb.NewMemDatabase()
+		if tt.stored != 0 {
+			core.WriteBlockChainVersion(db, tt.stored)
+		}
+		err := checkBlockChainVersion(db)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %t", i, err, tt.wantErr)
+		}
+		want := core.BlockChainVersion
+		if tt.wantErr {
+			want = tt.stored
+		}
+		if version := core.GetBlockChainVersion(db); version != want {
+			t.Errorf("test %d: stored version mismatch: have %d, want %d", i, version, want)
+		}
+	}
+}
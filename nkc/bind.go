@@ -66,7 +66,7 @@ func (b *ContractBackend) PendingCodeAt(ctx context.Context, contract common.Add
 // call with the specified data as the input. The pending flag requests execution
 // against the pending block, not the stable head of the chain.
 func (b *ContractBackend) CallContract(ctx context.Context, msg networkchain.CallMsg, blockNum *big.Int) ([]byte, error) {
-	out, err := b.bcapi.Call(ctx, toCallArgs(msg), toBlockNumber(blockNum))
+	out, err := b.bcapi.Call(ctx, toCallArgs(msg), toBlockNumber(blockNum), nil)
 	return out, err
 }
 
@@ -74,7 +74,7 @@ func (b *ContractBackend) CallContract(ctx context.Context, msg networkchain.Cal
 // call with the specified data as the input. The pending flag requests execution
 // against the pending block, not the stable head of the chain.
 func (b *ContractBackend) PendingCallContract(ctx context.Context, msg networkchain.CallMsg) ([]byte, error) {
-	out, err := b.bcapi.Call(ctx, toCallArgs(msg), rpc.PendingBlockNumber)
+	out, err := b.bcapi.Call(ctx, toCallArgs(msg), rpc.PendingBlockNumber, nil)
 	return out, err
 }
 
@@ -0,0 +1,180 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math"
+	"runtime/debug"
+	"time"
+
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/trie"
+)
+
+const (
+	databaseCacheShare = 50 // Percentage of the cache allowance given to the database
+	trieCacheShare     = 25 // Percentage of the cache allowance given to in-memory trie nodes
+
+	trieCacheGenSize = 2 // Rough megabytes of trie nodes retained per cache generation
+
+	minGCPercent = 20  // Lowest garbage collection target ever configured
+	maxGCPercent = 100 // Highest garbage collection target ever configured (Go default)
+
+	cacheBalanceInterval = time.Minute // Interval between cache rebalancing rounds
+)
+
+// CacheBudget is the split of a single memory allowance across the caches of a
+// node. All values are in megabytes.
+type CacheBudget struct {
+	Database int // Database block cache and write buffers
+	Trie     int // State trie nodes kept in memory across blocks
+	GC       int // Headroom for garbage awaiting collection
+}
+
+// SplitCache divides the total cache allowance across the database, the trie
+// cache and the garbage collector. The database takes half, the trie cache a
+// quarter and the rest is left as garbage collection headroom.
+func SplitCache(total int) CacheBudget {
+	database := total * databaseCacheShare / 100
+	tries := total * trieCacheShare / 100
+	return CacheBudget{
+		Database: database,
+		Trie:     tries,
+		GC:       total - database - tries,
+	}
+}
+
+// trieCacheGens converts a trie cache allowance into the number of trie node
+// generations to retain in memory.
+func trieCacheGens(megabytes int) uint16 {
+	gens := megabytes / trieCacheGenSize
+	if gens < 1 {
+		gens = 1
+	}
+	if gens > math.MaxUint16 {
+		gens = math.MaxUint16
+	}
+	return uint16(gens)
+}
+
+// gcPercent calculates the garbage collection target that lets the heap grow by
+// roughly the headroom on top of the memory retained by the caches.
+func gcPercent(headroom, retained int) int {
+	if retained <= 0 {
+		return maxGCPercent
+	}
+	percent := 100 * headroom / retained
+	if percent < minGCPercent {
+		percent = minGCPercent
+	}
+	if percent > maxGCPercent {
+		percent = maxGCPercent
+	}
+	return percent
+}
+
+// cacheBalancer shifts memory between the trie cache and the garbage collection
+// headroom based on how well the trie cache is doing. If trie nodes evicted from
+// memory are frequently loaded back from disk, the trie cache grows; if evicted
+// nodes are rarely needed again, memory is handed back to the collector.
+type cacheBalancer struct {
+	database int // Database allowance, fixed once the database is opened
+	trie     int // Current trie cache allowance
+	gc       int // Current garbage collection headroom
+
+	minTrie int // Lowest trie allowance the balancer may shrink to
+	maxTrie int // Highest trie allowance the balancer may grow to
+	step    int // Amount of memory moved in a single rebalancing round
+
+	misses  int64 // Trie cache misses at the last rebalancing round
+	unloads int64 // Trie cache unloads at the last rebalancing round
+}
+
+// newCacheBalancer creates a balancer for the given budget. The trie cache may
+// shrink to half its allowance and grow by at most half the GC headroom.
+func newCacheBalancer(budget CacheBudget) *cacheBalancer {
+	step := (budget.Trie + budget.GC) / 8
+	if step < trieCacheGenSize {
+		step = trieCacheGenSize
+	}
+	return &cacheBalancer{
+		database: budget.Database,
+		trie:     budget.Trie,
+		gc:       budget.GC,
+		minTrie:  budget.Trie / 2,
+		maxTrie:  budget.Trie + budget.GC/2,
+		step:     step,
+		misses:   trie.CacheMisses(),
+		unloads:  trie.CacheUnloads(),
+	}
+}
+
+// apply configures the trie cache and the garbage collector according to the
+// current allocation.
+func (b *cacheBalancer) apply() {
+	state.SetMaxTrieCacheGen(trieCacheGens(b.trie))
+	debug.SetGCPercent(gcPercent(b.gc, b.database+b.trie))
+}
+
+// rebalance inspects the trie cache counters accumulated since the last round
+// and moves memory between the trie cache and the GC headroom if warranted. It
+// returns whether the allocation changed.
+func (b *cacheBalancer) rebalance(misses, unloads int64) bool {
+	dmisses, dunloads := misses-b.misses, unloads-b.unloads
+	b.misses, b.unloads = misses, unloads
+
+	switch {
+	case dmisses > dunloads && b.trie < b.maxTrie:
+		// Evicted nodes keep getting reloaded, borrow from the GC headroom
+		step := b.step
+		if b.trie+step > b.maxTrie {
+			step = b.maxTrie - b.trie
+		}
+		b.trie, b.gc = b.trie+step, b.gc-step
+		return true
+
+	case 4*dmisses < dunloads && b.trie > b.minTrie:
+		// Evicted nodes are rarely needed again, return memory to the collector
+		step := b.step
+		if b.trie-step < b.minTrie {
+			step = b.trie - b.minTrie
+		}
+		b.trie, b.gc = b.trie-step, b.gc+step
+		return true
+	}
+	return false
+}
+
+// balanceCaches periodically shifts memory between the trie cache and the GC
+// headroom until the service stops.
+func (s *NetworkChain) balanceCaches(balancer *cacheBalancer) {
+	ticker := time.NewTicker(cacheBalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if balancer.rebalance(trie.CacheMisses(), trie.CacheUnloads()) {
+				balancer.apply()
+				log.Debug("Rebalanced caches", "trie", balancer.trie, "gc", balancer.gc)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
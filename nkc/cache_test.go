@@ -0,0 +1,70 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import "testing"
+
+// Tests that the cache allowance is split without losing any memory.
+func TestSplitCache(t *testing.T) {
+	tests := []struct {
+		total  int
+		budget CacheBudget
+	}{
+		{128, CacheBudget{Database: 64, Trie: 32, GC: 32}},
+		{1024, CacheBudget{Database: 512, Trie: 256, GC: 256}},
+		{99, CacheBudget{Database: 49, Trie: 24, GC: 26}},
+	}
+	for _, tt := range tests {
+		if budget := SplitCache(tt.total); budget != tt.budget {
+			t.Errorf("split of %d mismatch: have %+v, want %+v", tt.total, budget, tt.budget)
+		}
+	}
+}
+
+// Tests that the balancer moves memory between the trie cache and the GC
+// headroom according to the trie cache counters, within its bounds.
+func TestCacheBalancer(t *testing.T) {
+	b := newCacheBalancer(CacheBudget{Database: 512, Trie: 256, GC: 256})
+	b.misses, b.unloads = 0, 0
+
+	// Frequent reloads should grow the trie cache up to half the GC headroom
+	var misses, unloads int64
+	for i := 0; i < 10; i++ {
+		misses, unloads = misses+100, unloads+10
+		b.rebalance(misses, unloads)
+	}
+	if b.trie != 384 || b.gc != 128 {
+		t.Fatalf("grown allocation mismatch: have trie %d gc %d, want trie 384 gc 128", b.trie, b.gc)
+	}
+	// Balanced counters should leave the allocation alone
+	misses, unloads = misses+100, unloads+100
+	if b.rebalance(misses, unloads) {
+		t.Fatalf("allocation changed with balanced counters")
+	}
+	// Rare reloads should shrink the trie cache down to half its budget
+	for i := 0; i < 10; i++ {
+		misses, unloads = misses+10, unloads+100
+		b.rebalance(misses, unloads)
+	}
+	if b.trie != 128 || b.gc != 384 {
+		t.Fatalf("shrunk allocation mismatch: have trie %d gc %d, want trie 128 gc 384", b.trie, b.gc)
+	}
+	// Ensure the garbage collection target follows the headroom
+	if percent := gcPercent(b.gc, b.database+b.trie); percent != 60 {
+		t.Fatalf("gc percent mismatch: have %d, want 60", percent)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainaccess implements the retrieval of chain data requested by remote
+// peers, shared by the full and the light protocol handlers.
+package chainaccess
+
+import (
+	"errors"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+const (
+	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned blocks, headers or receipts
+	estHeaderRlpSize  = 500             // Approximate size of an RLP encoded block header
+)
+
+// ErrSkipOverflow is returned if a header query skips beyond the range of block
+// numbers, which is only done by malicious peers.
+var ErrSkipOverflow = errors.New("header query skip overflow")
+
+// HeaderReader is the header access needed to serve header queries, implemented
+// by both the full and the light chain.
+type HeaderReader interface {
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetHeaderByHash(hash common.Hash) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetBlockHashesFromHash(hash common.Hash, max uint64) []common.Hash
+}
+
+// Backend is the chain data access needed to serve header, body and receipt
+// queries, implemented by the full chain.
+type Backend interface {
+	HeaderReader
+
+	GetBodyRLP(hash common.Hash) rlp.RawValue
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// HeaderQuery is a request for a run of headers, starting at an origin block
+// given by hash or number and advancing by skip+1 blocks in either direction.
+type HeaderQuery struct {
+	Hash    common.Hash // Block hash from which to retrieve headers (excludes Number)
+	Number  uint64      // Block number from which to retrieve headers (excludes Hash)
+	Amount  uint64      // Maximum number of headers to retrieve
+	Skip    uint64      // Blocks to skip between consecutive headers
+	Reverse bool        // Query direction (false = rising towards latest, true = falling towards genesis)
+}
+
+// Headers gathers the headers satisfying a query, stopping at the first unknown
+// one or when max headers or the soft response size limit are reached. Queries
+// skipping past the range of block numbers are cut short with ErrSkipOverflow,
+// returning the headers gathered until then.
+func Headers(chain HeaderReader, query HeaderQuery, max int) ([]*types.Header, error) {
+	hashMode := query.Hash != (common.Hash{})
+
+	var (
+		bytes   common.StorageSize
+		headers []*types.Header
+		unknown bool
+	)
+	for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < max {
+		// Retrieve the next header satisfying the query
+		var origin *types.Header
+		if hashMode {
+			origin = chain.GetHeaderByHash(query.Hash)
+		} else {
+			origin = chain.GetHeaderByNumber(query.Number)
+		}
+		if origin == nil {
+			break
+		}
+		number := origin.Number.Uint64()
+		headers = append(headers, origin)
+		bytes += estHeaderRlpSize
+
+		// Advance to the next header of the query
+		switch {
+		case hashMode && query.Reverse:
+			// Hash based traversal towards the genesis block
+			for i := 0; i < int(query.Skip)+1; i++ {
+				if header := chain.GetHeader(query.Hash, number); header != nil {
+					query.Hash = header.ParentHash
+					number--
+				} else {
+					unknown = true
+					break
+				}
+			}
+		case hashMode && !query.Reverse:
+			// Hash based traversal towards the leaf block
+			var (
+				current = origin.Number.Uint64()
+				next    = current + query.Skip + 1
+			)
+			if next <= current {
+				return headers, ErrSkipOverflow
+			}
+			if header := chain.GetHeaderByNumber(next); header != nil {
+				if chain.GetBlockHashesFromHash(header.Hash(), query.Skip+1)[query.Skip] == query.Hash {
+					query.Hash = header.Hash()
+				} else {
+					unknown = true
+				}
+			} else {
+				unknown = true
+			}
+		case query.Reverse:
+			// Number based traversal towards the genesis block
+			if query.Number >= query.Skip+1 {
+				query.Number -= (query.Skip + 1)
+			} else {
+				unknown = true
+			}
+
+		case !query.Reverse:
+			// Number based traversal towards the leaf block
+			query.Number += (query.Skip + 1)
+		}
+	}
+	return headers, nil
+}
+
+// BodiesRLP retrieves the RLP encoded bodies of the given blocks, skipping the
+// unknown ones, until the soft response size limit is reached.
+func BodiesRLP(chain Backend, hashes []common.Hash) []rlp.RawValue {
+	var (
+		bytes  int
+		bodies []rlp.RawValue
+	)
+	for _, hash := range hashes {
+		if bytes >= softResponseLimit {
+			break
+		}
+		if data := chain.GetBodyRLP(hash); len(data) != 0 {
+			bodies = append(bodies, data)
+			bytes += len(data)
+		}
+	}
+	return bodies
+}
+
+// ReceiptsRLP retrieves the RLP encoded receipts of the given blocks, skipping the
+// unknown ones, until the soft response size limit is reached.
+func ReceiptsRLP(chain Backend, hashes []common.Hash) []rlp.RawValue {
+	var (
+		bytes    int
+		receipts []rlp.RawValue
+	)
+	for _, hash := range hashes {
+		if bytes >= softResponseLimit {
+			break
+		}
+		// Retrieve the requested block's receipts, skipping if unknown to us
+		results := chain.GetReceiptsByHash(hash)
+		if results == nil {
+			if header := chain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
+				continue
+			}
+		}
+		// If known, encode and queue for response packet
+		if encoded, err := rlp.EncodeToBytes(results); err != nil {
+			log.Error("Failed to encode receipt", "err", err)
+		} else {
+			receipts = append(receipts, encoded)
+			bytes += len(encoded)
+		}
+	}
+	return receipts
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package chainaccess
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+)
+
+// testChain is a linear chain of headers used to serve header queries.
+type testChain []*types.Header
+
+func newTestChain(n int) testChain {
+	chain := make(testChain, n)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i))}
+		if i > 0 {
+			chain[i].ParentHash = chain[i-1].Hash()
+		}
+	}
+	return chain
+}
+
+func (c testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c)) {
+		return nil
+	}
+	return c[number]
+}
+
+func (c testChain) GetBlockHashesFromHash(hash common.Hash, max uint64) []common.Hash {
+	var hashes []common.Hash
+	for header := c.GetHeaderByHash(hash); header != nil && uint64(len(hashes)) < max; {
+		hashes = append(hashes, header.ParentHash)
+		header = c.GetHeaderByHash(header.ParentHash)
+	}
+	return hashes
+}
+
+// Tests that header queries are traversed correctly by hash and by number in
+// both directions.
+func TestHeaders(t *testing.T) {
+	chain := newTestChain(16)
+
+	tests := []struct {
+		query  HeaderQuery
+		max    int
+		expect []uint64
+		err    error
+	}{
+		{HeaderQuery{Number: 2, Amount: 3, Skip: 1}, 16, []uint64{2, 4, 6}, nil},
+		{HeaderQuery{Number: 6, Amount: 5, Skip: 2, Reverse: true}, 16, []uint64{6, 3, 0}, nil},
+		{HeaderQuery{Hash: chain[2].Hash(), Amount: 3, Skip: 1}, 16, []uint64{2, 4, 6}, nil},
+		{HeaderQuery{Hash: chain[6].Hash(), Amount: 5, Skip: 2, Reverse: true}, 16, []uint64{6, 3, 0}, nil},
+		{HeaderQuery{Number: 12, Amount: 8}, 16, []uint64{12, 13, 14, 15}, nil},
+		{HeaderQuery{Number: 0, Amount: 8}, 2, []uint64{0, 1}, nil},
+		{HeaderQuery{Hash: common.Hash{0x01}, Amount: 1}, 16, nil, nil},
+		{HeaderQuery{Hash: chain[3].Hash(), Amount: 2, Skip: math.MaxUint64}, 16, []uint64{3}, ErrSkipOverflow},
+	}
+	for i, tt := range tests {
+		headers, err := Headers(chain, tt.query, tt.max)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if len(headers) != len(tt.expect) {
+			t.Errorf("test %d: header count mismatch: have %d, want %d", i, len(headers), len(tt.expect))
+			continue
+		}
+		for j, header := range headers {
+			if header.Number.Uint64() != tt.expect[j] {
+				t.Errorf("test %d: header %d mismatch: have #%d, want #%d", i, j, header.Number, tt.expect[j])
+			}
+		}
+	}
+}
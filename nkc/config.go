@@ -28,6 +28,7 @@ import (
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/eth/gasprice"
+	"github.com/networkchain/networkchain/p2p/netutil"
 	"github.com/networkchain/networkchain/params"
 )
 
@@ -43,8 +44,11 @@ var DefaultConfig = Config{
 	LightPeers:           20,
 	DatabaseCache:        128,
 	GasPrice:             big.NewInt(18 * params.Shannon),
+	SafeDepth:            6,
+	FinalityDepth:        12,
 
-	TxPool: core.DefaultTxPoolConfig,
+	TxPool:   core.DefaultTxPoolConfig,
+	TxRescue: DefaultRescueConfig,
 	GPO: gasprice.Config{
 		Blocks:     10,
 		Percentile: 50,
@@ -52,6 +56,8 @@ var DefaultConfig = Config{
 }
 
 func init() {
+	DefaultConfig.TxPool.Journal = "transactions.rlp"
+
 	home := os.Getenv("HOME")
 	if home == "" {
 		if user, err := user.Current(); err == nil {
@@ -76,20 +82,38 @@ type Config struct {
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
 
+	// Static sync options, for environments without peer discovery
+	StaticSync bool        `toml:",omitempty"` // Synchronise blocks exclusively from static nodes
+	SyncAnchor common.Hash `toml:",omitempty"` // Trusted block hash any synced chain must contain
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 	MaxPeers   int `toml:"-"`          // Maximum number of global peers
 
+	// Number of recent headers a light client keeps in its database (0 = keep all)
+	LightHeaderRetention uint64 `toml:",omitempty"`
+
+	// Light client server selection options, favouring nearby servers
+	LightProbeLatency bool             `toml:",omitempty"` // Measure the round trip time of discovered servers
+	LightPreferNets   *netutil.Netlist `toml:",omitempty"` // Networks whose servers are preferred
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	TrieCache          int  // Megabytes of trie nodes kept in memory (0 = fixed trie cache generations)
+	GCHeadroom         int  // Megabytes of garbage collection headroom the trie cache may borrow from
+	LogIndex           bool // Maintain the contract event log index
+	StateSnapshot      bool // Maintain a flat state snapshot for trie-less reads
+	StateGC            bool // Garbage collect old states in memory instead of archiving them all
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
 	MinerThreads int            `toml:",omitempty"`
 	ExtraData    []byte         `toml:",omitempty"`
+	MinerTxOrder string         `toml:",omitempty"` // Transaction ordering strategy (price, pricenonce, fifo)
+	MinerNotify  []string       `toml:",omitempty"` // HTTP URLs to push new work packages to
 	GasPrice     *big.Int
 
 	// Ethash options
@@ -100,15 +124,39 @@ type Config struct {
 	EthashDatasetsInMem  int
 	EthashDatasetsOnDisk int
 
+	// External consensus engine endpoint (IPC, HTTP or WebSocket) to delegate
+	// header verification and sealing to
+	RemoteSealer string `toml:",omitempty"`
+
+	// File restricting what the RPC endpoints may sign with each account
+	SigningPolicy string `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Stuck transaction rescue options
+	TxRescue RescueConfig
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Records the state accessed by each transaction to measure conflicts
+	TrackStateAccess bool
+
+	// Speculatively executes the transactions of imported blocks in parallel
+	ParallelExecution bool
+
+	// Records the execution witnesses of imported blocks for stateless verification
+	RecordWitness bool
+
+	// Number of confirmations (including the block itself) the "safe" and
+	// "finalized" RPC block tags resolve to
+	SafeDepth     uint64
+	FinalityDepth uint64
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowFake   bool   `toml:"-"`
@@ -294,3 +294,19 @@ func addMipmapBloomBins(db ethdb.Database) (err error) {
 	log.Info("Bloom-bin upgrade completed", "elapsed", common.PrettyDuration(time.Since(tstart)))
 	return nil
 }
+
+// checkBlockChainVersion ensures the chain database is compatible with this
+// release, stamping it with the current version. Databases of the previous
+// version, storing headers and total difficulties separately, are upgraded in
+// place, after which they can't be opened by older releases anymore.
+func checkBlockChainVersion(db ethdb.Database) error {
+	switch version := core.GetBlockChainVersion(db); version {
+	case 0, core.BlockChainVersion:
+	case core.SeparateTdBlockChainVersion:
+		log.Warn("Upgrading chain database to compact headers, older releases won't be able to open it", "version", version, "upgraded", core.BlockChainVersion)
+	default:
+		return fmt.Errorf("Blockchain DB version mismatch (%d / %d). Run netk upgradedb.\n", version, core.BlockChainVersion)
+	}
+	core.WriteBlockChainVersion(db, core.BlockChainVersion)
+	return nil
+}
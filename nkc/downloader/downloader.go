@@ -92,6 +92,7 @@ var (
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
+	errAnchorMismatch          = errors.New("remote chain does not contain the trusted anchor block")
 )
 
 type Downloader struct {
@@ -102,6 +103,8 @@ type Downloader struct {
 	peers   *peerSet // Set of active peers from which download can proceed
 	stateDB ethdb.Database
 
+	anchor common.Hash // Trusted block hash remote chains must contain (zero = any chain)
+
 	fsPivotLock  *types.Header // Pivot header on critical section entry (cannot change between retries)
 	fsPivotFails uint32        // Number of subsequent fast sync failures in the critical section
 
@@ -233,6 +236,13 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetAnchor configures a trusted block hash that any remote chain must contain
+// to be synchronised with. Peers failing the check are dropped. It must be set
+// before the downloader is put to use.
+func (d *Downloader) SetAnchor(hash common.Hash) {
+	d.anchor = hash
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -322,7 +332,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 
 	case errTimeout, errBadPeer, errStallingPeer,
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
-		errInvalidAncestor, errInvalidChain:
+		errInvalidAncestor, errInvalidChain, errAnchorMismatch:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
 		d.dropPeer(id)
 
@@ -425,6 +435,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
+	if err := d.checkAnchor(p, latest); err != nil {
+		return err
+	}
 	origin, err := d.findAncestor(p, height)
 	if err != nil {
 		return err
@@ -593,6 +606,73 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// checkAnchor verifies that the canonical chain of the remote peer contains the
+// trusted anchor block, if one was configured. The anchor header is retrieved by
+// hash to learn its number, and then by number to ensure it's not a side block.
+func (d *Downloader) checkAnchor(p *peerConnection, head *types.Header) error {
+	if d.anchor == (common.Hash{}) {
+		return nil
+	}
+	p.log.Debug("Verifying trusted anchor block", "hash", d.anchor)
+
+	header, err := d.fetchHeader(p, func() error { return p.peer.RequestHeadersByHash(d.anchor, 1, 0, false) })
+	if err != nil {
+		return err
+	}
+	if header == nil || header.Hash() != d.anchor || header.Number.Cmp(head.Number) > 0 {
+		return errAnchorMismatch
+	}
+	number := header.Number.Uint64()
+	if header, err = d.fetchHeader(p, func() error { return p.peer.RequestHeadersByNumber(number, 1, 0, false) }); err != nil {
+		return err
+	}
+	if header == nil || header.Hash() != d.anchor {
+		return errAnchorMismatch
+	}
+	p.log.Debug("Trusted anchor block verified", "number", number, "hash", d.anchor)
+	return nil
+}
+
+// fetchHeader issues a single header request to the remote peer and waits for
+// the reply, returning nil if the peer doesn't have the requested header.
+func (d *Downloader) fetchHeader(p *peerConnection, request func() error) (*types.Header, error) {
+	go request()
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			switch len(headers) {
+			case 0:
+				return nil, nil
+			case 1:
+				return headers[0], nil
+			default:
+				p.log.Debug("Multiple headers for single request", "headers", len(headers))
+				return nil, errBadPeer
+			}
+
+		case <-timeout:
+			p.log.Debug("Waiting for single header timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -899,7 +979,7 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.Header) (
 		}
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchHeaders(req.From, MaxHeaderFetch) }
 		capacity = func(p *peerConnection) int { return p.HeaderCapacity(d.requestRTT()) }
-		setIdle  = func(p *peerConnection, accepted int) { p.SetHeadersIdle(accepted) }
+		setIdle  = func(p *peerConnection, accepted int, size common.StorageSize) { p.SetHeadersIdle(accepted, size) }
 	)
 	err := d.fetchParts(errCancelHeaderFetch, d.headerCh, deliver, d.queue.headerContCh, expire,
 		d.queue.PendingHeaders, d.queue.InFlightHeaders, throttle, reserve,
@@ -925,7 +1005,7 @@ func (d *Downloader) fetchBodies(from uint64) error {
 		expire   = func() map[string]int { return d.queue.ExpireBodies(d.requestTTL()) }
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchBodies(req) }
 		capacity = func(p *peerConnection) int { return p.BlockCapacity(d.requestRTT()) }
-		setIdle  = func(p *peerConnection, accepted int) { p.SetBodiesIdle(accepted) }
+		setIdle  = func(p *peerConnection, accepted int, size common.StorageSize) { p.SetBodiesIdle(accepted, size) }
 	)
 	err := d.fetchParts(errCancelBodyFetch, d.bodyCh, deliver, d.bodyWakeCh, expire,
 		d.queue.PendingBlocks, d.queue.InFlightBlocks, d.queue.ShouldThrottleBlocks, d.queue.ReserveBodies,
@@ -949,7 +1029,7 @@ func (d *Downloader) fetchReceipts(from uint64) error {
 		expire   = func() map[string]int { return d.queue.ExpireReceipts(d.requestTTL()) }
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchReceipts(req) }
 		capacity = func(p *peerConnection) int { return p.ReceiptCapacity(d.requestRTT()) }
-		setIdle  = func(p *peerConnection, accepted int) { p.SetReceiptsIdle(accepted) }
+		setIdle  = func(p *peerConnection, accepted int, size common.StorageSize) { p.SetReceiptsIdle(accepted, size) }
 	)
 	err := d.fetchParts(errCancelReceiptFetch, d.receiptCh, deliver, d.receiptWakeCh, expire,
 		d.queue.PendingReceipts, d.queue.InFlightReceipts, d.queue.ShouldThrottleReceipts, d.queue.ReserveReceipts,
@@ -982,12 +1062,12 @@ func (d *Downloader) fetchReceipts(from uint64) error {
 //  - cancel:      task callback to abort an in-flight download request and allow rescheduling it (in case of lost peer)
 //  - capacity:    network callback to retrieve the estimated type-specific bandwidth capacity of a peer (traffic shaping)
 //  - idle:        network callback to retrieve the currently (type specific) idle peers that can be assigned tasks
-//  - setIdle:     network callback to set a peer back to idle and update its estimated capacity (traffic shaping)
+//  - setIdle:     network callback to set a peer back to idle and update its estimated capacity and item size (traffic shaping)
 //  - kind:        textual label of the type being downloaded to display in log mesages
 func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliver func(dataPack) (int, error), wakeCh chan bool,
 	expire func() map[string]int, pending func() int, inFlight func() bool, throttle func() bool, reserve func(*peerConnection, int) (*fetchRequest, bool, error),
 	fetchHook func([]*types.Header), fetch func(*peerConnection, *fetchRequest) error, cancel func(*fetchRequest), capacity func(*peerConnection) int,
-	idle func() ([]*peerConnection, int), setIdle func(*peerConnection, int), kind string) error {
+	idle func() ([]*peerConnection, int), setIdle func(*peerConnection, int, common.StorageSize), kind string) error {
 
 	// Create a ticker to detect expired retrieval tasks
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -1015,7 +1095,7 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
 				if err != errStaleDelivery {
-					setIdle(peer, accepted)
+					setIdle(peer, accepted, deliveredSize(packet, accepted))
 				}
 				// Issue a log to the user to see what's going on
 				switch {
@@ -1068,7 +1148,7 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 					// how response times reacts, to it always requests one more than the minimum (i.e. min 2).
 					if fails > 2 {
 						peer.log.Trace("Data delivery timed out", "type", kind)
-						setIdle(peer, 0)
+						setIdle(peer, 0, 0)
 					} else {
 						peer.log.Debug("Stalling delivery, dropping", "type", kind)
 						d.dropPeer(pid)
@@ -1136,6 +1216,16 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 	}
 }
 
+// deliveredSize estimates the byte size of the accepted part of a delivery, used
+// to track the average item size of a peer for request sizing.
+func deliveredSize(packet dataPack, accepted int) common.StorageSize {
+	items := packet.Items()
+	if items == 0 || accepted == 0 {
+		return 0
+	}
+	return packet.Size() * common.StorageSize(accepted) / common.StorageSize(items)
+}
+
 // processHeaders takes batches of retrieved headers from an input channel and
 // keeps processing and scheduling them into the header chain and downloader's
 // queue until the stream ends or a failure occurs.
@@ -1803,3 +1803,74 @@ func testFastCriticalRestarts(t *testing.T, protocol int, progress bool) {
 	// completed using a single mode of operation, whereas fast-then-slow can result
 	// in arbitrary intermediate state that's not cleanly verifiable.
 }
+
+// Tests that the request capacity of a peer follows its measured throughput, but
+// is capped by the response byte budget and the protocol limits.
+func TestThroughputCapacity(t *testing.T) {
+	// An unmeasured peer should be allowed a minimal request
+	var tput throughput
+	if have := tput.capacity(time.Second, MaxBlockFetch); have != 2 {
+		t.Errorf("unmeasured capacity mismatch: have %d, want %d", have, 2)
+	}
+	// A fast peer with small items should be limited by the protocol cap
+	tput = throughput{items: 10000, size: 100}
+	if have := tput.capacity(time.Second, MaxBlockFetch); have != MaxBlockFetch {
+		t.Errorf("protocol capped capacity mismatch: have %d, want %d", have, MaxBlockFetch)
+	}
+	// A fast peer with large items should be limited by the byte budget
+	tput = throughput{items: 10000, size: maxRequestBytes / 16}
+	if have := tput.capacity(time.Second, MaxBlockFetch); have != 16 {
+		t.Errorf("byte capped capacity mismatch: have %d, want %d", have, 16)
+	}
+	// Item sizes beyond the byte budget should still allow a single item
+	tput = throughput{items: 10000, size: 4 * maxRequestBytes}
+	if have := tput.capacity(time.Second, MaxBlockFetch); have != 1 {
+		t.Errorf("oversized capacity mismatch: have %d, want %d", have, 1)
+	}
+	// The first sized measurement should set the item size directly
+	tput = throughput{}
+	tput.update(10, 1000, time.Second)
+	if tput.size != 100 {
+		t.Errorf("item size mismatch: have %v, want %v", tput.size, 100)
+	}
+	if tput.items != measurementImpact*10 {
+		t.Errorf("item rate mismatch: have %v, want %v", tput.items, measurementImpact*10)
+	}
+}
+
+// Tests that a configured trusted anchor block is enforced: peers containing it
+// in their canonical chain are synchronised with, others are rejected.
+func TestSyncAnchor62(t *testing.T)      { testSyncAnchor(t, 62, FullSync) }
+func TestSyncAnchor63Full(t *testing.T)  { testSyncAnchor(t, 63, FullSync) }
+func TestSyncAnchor63Fast(t *testing.T)  { testSyncAnchor(t, 63, FastSync) }
+func TestSyncAnchor64Full(t *testing.T)  { testSyncAnchor(t, 64, FullSync) }
+func TestSyncAnchor64Fast(t *testing.T)  { testSyncAnchor(t, 64, FastSync) }
+func TestSyncAnchor64Light(t *testing.T) { testSyncAnchor(t, 64, LightSync) }
+
+func testSyncAnchor(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Create two forks, anchoring the sync to a block only present in the first
+	common, fork := MaxHashFetch, 2*MaxHashFetch
+	hashesA, hashesB, headersA, headersB, blocksA, blocksB, receiptsA, receiptsB := tester.makeChainFork(common+fork, fork, tester.genesis, nil, true)
+
+	tester.downloader.SetAnchor(hashesA[fork/2])
+
+	tester.newPeer("fork B", protocol, hashesB, headersB, blocksB, receiptsB)
+	tester.newPeer("fork A", protocol, hashesA, headersA, blocksA, receiptsA)
+
+	// Synchronising with the fork missing the anchor should fail
+	if err := tester.sync("fork B", nil, mode); err != errAnchorMismatch {
+		t.Fatalf("sync failure mismatch: have %v, want %v", err, errAnchorMismatch)
+	}
+	assertOwnChain(t, tester, 1)
+
+	// Synchronising with the anchored fork should succeed
+	if err := tester.sync("fork A", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, common+fork+1)
+}
@@ -20,6 +20,7 @@
 package downloader
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -32,11 +33,17 @@ import (
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/event"
 	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/tracing"
 )
 
 const (
 	maxLackingHashes  = 4096 // Maximum number of entries allowed on the list or lacking items
 	measurementImpact = 0.1  // The impact a single measurement has on a peer's final throughput value.
+
+	// maxRequestBytes is the response size budget of a single request. Serving
+	// peers truncate their replies around this limit, so asking for more items
+	// than fit only yields partial deliveries.
+	maxRequestBytes = 2 * 1024 * 1024
 )
 
 var (
@@ -54,10 +61,10 @@ type peerConnection struct {
 	receiptIdle int32 // Current receipt activity state of the peer (idle = 0, active = 1)
 	stateIdle   int32 // Current node data activity state of the peer (idle = 0, active = 1)
 
-	headerThroughput  float64 // Number of headers measured to be retrievable per second
-	blockThroughput   float64 // Number of blocks (bodies) measured to be retrievable per second
-	receiptThroughput float64 // Number of receipts measured to be retrievable per second
-	stateThroughput   float64 // Number of node data pieces measured to be retrievable per second
+	headerThroughput  throughput // Measured header retrieval rate
+	blockThroughput   throughput // Measured block (body) retrieval rate
+	receiptThroughput throughput // Measured receipt retrieval rate
+	stateThroughput   throughput // Measured node data retrieval rate
 
 	rtt time.Duration // Request round trip time to track responsiveness (QoS)
 
@@ -75,6 +82,38 @@ type peerConnection struct {
 	lock    sync.RWMutex
 }
 
+// throughput is the measured retrieval rate of a single data type from a peer.
+type throughput struct {
+	items float64 // Number of items measured to be retrievable per second
+	size  float64 // Average size of a retrieved item in bytes (0 = not measured yet)
+}
+
+// update folds a new delivery measurement into the throughput estimate.
+func (t *throughput) update(delivered int, size common.StorageSize, elapsed time.Duration) {
+	measured := float64(delivered) / (float64(elapsed) / float64(time.Second))
+	t.items = (1-measurementImpact)*t.items + measurementImpact*measured
+
+	if size > 0 {
+		itemSize := float64(size) / float64(delivered)
+		if t.size == 0 {
+			t.size = itemSize
+		} else {
+			t.size = (1-measurementImpact)*t.size + measurementImpact*itemSize
+		}
+	}
+}
+
+// capacity returns the number of items to request from the peer, so that the
+// response is expected to arrive within the target round trip time and to fit
+// into the request byte budget, without exceeding the protocol limit.
+func (t *throughput) capacity(targetRTT time.Duration, limit int) int {
+	items := 1 + math.Max(1, t.items*float64(targetRTT)/float64(time.Second))
+	if t.size > 0 {
+		items = math.Min(items, math.Max(1, maxRequestBytes/t.size))
+	}
+	return int(math.Min(items, float64(limit)))
+}
+
 // LightPeer encapsulates the methods required to synchronise with a remote light peer.
 type LightPeer interface {
 	Head() (common.Hash, *big.Int)
@@ -135,10 +174,10 @@ func (p *peerConnection) Reset() {
 	atomic.StoreInt32(&p.receiptIdle, 0)
 	atomic.StoreInt32(&p.stateIdle, 0)
 
-	p.headerThroughput = 0
-	p.blockThroughput = 0
-	p.receiptThroughput = 0
-	p.stateThroughput = 0
+	p.headerThroughput = throughput{}
+	p.blockThroughput = throughput{}
+	p.receiptThroughput = throughput{}
+	p.stateThroughput = throughput{}
 
 	p.lacking = make(map[common.Hash]struct{})
 }
@@ -225,99 +264,102 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
-func (p *peerConnection) SetHeadersIdle(delivered int) {
-	p.setIdle(p.headerStarted, delivered, &p.headerThroughput, &p.headerIdle)
+func (p *peerConnection) SetHeadersIdle(delivered int, size common.StorageSize) {
+	p.setIdle("headers", p.headerStarted, delivered, size, &p.headerThroughput, &p.headerIdle)
 }
 
 // SetBlocksIdle sets the peer to idle, allowing it to execute new block retrieval
 // requests. Its estimated block retrieval throughput is updated with that measured
 // just now.
-func (p *peerConnection) SetBlocksIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+func (p *peerConnection) SetBlocksIdle(delivered int, size common.StorageSize) {
+	p.setIdle("blocks", p.blockStarted, delivered, size, &p.blockThroughput, &p.blockIdle)
 }
 
 // SetBodiesIdle sets the peer to idle, allowing it to execute block body retrieval
 // requests. Its estimated body retrieval throughput is updated with that measured
 // just now.
-func (p *peerConnection) SetBodiesIdle(delivered int) {
-	p.setIdle(p.blockStarted, delivered, &p.blockThroughput, &p.blockIdle)
+func (p *peerConnection) SetBodiesIdle(delivered int, size common.StorageSize) {
+	p.setIdle("bodies", p.blockStarted, delivered, size, &p.blockThroughput, &p.blockIdle)
 }
 
 // SetReceiptsIdle sets the peer to idle, allowing it to execute new receipt
 // retrieval requests. Its estimated receipt retrieval throughput is updated
 // with that measured just now.
-func (p *peerConnection) SetReceiptsIdle(delivered int) {
-	p.setIdle(p.receiptStarted, delivered, &p.receiptThroughput, &p.receiptIdle)
+func (p *peerConnection) SetReceiptsIdle(delivered int, size common.StorageSize) {
+	p.setIdle("receipts", p.receiptStarted, delivered, size, &p.receiptThroughput, &p.receiptIdle)
 }
 
 // SetNodeDataIdle sets the peer to idle, allowing it to execute new state trie
 // data retrieval requests. Its estimated state retrieval throughput is updated
 // with that measured just now.
-func (p *peerConnection) SetNodeDataIdle(delivered int) {
-	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateIdle)
+func (p *peerConnection) SetNodeDataIdle(delivered int, size common.StorageSize) {
+	p.setIdle("nodedata", p.stateStarted, delivered, size, &p.stateThroughput, &p.stateIdle)
 }
 
 // setIdle sets the peer to idle, allowing it to execute new retrieval requests.
-// Its estimated retrieval throughput is updated with that measured just now.
-func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *float64, idle *int32) {
+// Its estimated retrieval throughput is updated with that measured just now, the
+// size being the total byte size of the delivered items. The round trip of the
+// request is traced under the given kind of the retrieved items.
+func (p *peerConnection) setIdle(kind string, started time.Time, delivered int, size common.StorageSize, tput *throughput, idle *int32) {
 	// Irrelevant of the scaling, make sure the peer ends up idle
 	defer atomic.StoreInt32(idle, 0)
 
+	tracing.Record(context.Background(), "eth.fetch."+kind, started, nil, "peer", p.id, "delivered", delivered, "size", size)
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
 	// If nothing was delivered (hard timeout / unavailable data), reduce throughput to minimum
 	if delivered == 0 {
-		*throughput = 0
+		tput.items = 0
 		return
 	}
 	// Otherwise update the throughput with a new measurement
 	elapsed := time.Since(started) + 1 // +1 (ns) to ensure non-zero divisor
-	measured := float64(delivered) / (float64(elapsed) / float64(time.Second))
+	tput.update(delivered, size, elapsed)
 
-	*throughput = (1-measurementImpact)*(*throughput) + measurementImpact*measured
 	p.rtt = time.Duration((1-measurementImpact)*float64(p.rtt) + measurementImpact*float64(elapsed))
 
 	p.log.Trace("Peer throughput measurements updated",
-		"hps", p.headerThroughput, "bps", p.blockThroughput,
-		"rps", p.receiptThroughput, "sps", p.stateThroughput,
+		"hps", p.headerThroughput.items, "bps", p.blockThroughput.items,
+		"rps", p.receiptThroughput.items, "sps", p.stateThroughput.items,
 		"miss", len(p.lacking), "rtt", p.rtt)
 }
 
 // HeaderCapacity retrieves the peers header download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and the size of its headers.
 func (p *peerConnection) HeaderCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.headerThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxHeaderFetch)))
+	return p.headerThroughput.capacity(targetRTT, MaxHeaderFetch)
 }
 
 // BlockCapacity retrieves the peers block download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and the size of its block bodies.
 func (p *peerConnection) BlockCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.blockThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxBlockFetch)))
+	return p.blockThroughput.capacity(targetRTT, MaxBlockFetch)
 }
 
 // ReceiptCapacity retrieves the peers receipt download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and the size of its receipts.
 func (p *peerConnection) ReceiptCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.receiptThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxReceiptFetch)))
+	return p.receiptThroughput.capacity(targetRTT, MaxReceiptFetch)
 }
 
 // NodeDataCapacity retrieves the peers state download allowance based on its
-// previously discovered throughput.
+// previously discovered throughput and the size of its state entries.
 func (p *peerConnection) NodeDataCapacity(targetRTT time.Duration) int {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	return int(math.Min(1+math.Max(1, p.stateThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxStateFetch)))
+	return p.stateThroughput.capacity(targetRTT, MaxStateFetch)
 }
 
 // MarkLacking appends a new entity to the set of items (blocks, receipts, states)
@@ -393,20 +435,19 @@ func (ps *peerSet) Register(p *peerConnection) error {
 		return errAlreadyRegistered
 	}
 	if len(ps.peers) > 0 {
-		p.headerThroughput, p.blockThroughput, p.receiptThroughput, p.stateThroughput = 0, 0, 0, 0
-
+		var headers, blocks, receipts, states []throughput
 		for _, peer := range ps.peers {
 			peer.lock.RLock()
-			p.headerThroughput += peer.headerThroughput
-			p.blockThroughput += peer.blockThroughput
-			p.receiptThroughput += peer.receiptThroughput
-			p.stateThroughput += peer.stateThroughput
+			headers = append(headers, peer.headerThroughput)
+			blocks = append(blocks, peer.blockThroughput)
+			receipts = append(receipts, peer.receiptThroughput)
+			states = append(states, peer.stateThroughput)
 			peer.lock.RUnlock()
 		}
-		p.headerThroughput /= float64(len(ps.peers))
-		p.blockThroughput /= float64(len(ps.peers))
-		p.receiptThroughput /= float64(len(ps.peers))
-		p.stateThroughput /= float64(len(ps.peers))
+		p.headerThroughput = averageThroughput(headers)
+		p.blockThroughput = averageThroughput(blocks)
+		p.receiptThroughput = averageThroughput(receipts)
+		p.stateThroughput = averageThroughput(states)
 	}
 	ps.peers[p.id] = p
 	ps.lock.Unlock()
@@ -415,6 +456,27 @@ func (ps *peerSet) Register(p *peerConnection) error {
 	return nil
 }
 
+// averageThroughput calculates the average retrieval rate of a set of peers and
+// the average item size among the peers that already measured it.
+func averageThroughput(tputs []throughput) throughput {
+	var (
+		avg   throughput
+		sized int
+	)
+	for _, t := range tputs {
+		avg.items += t.items
+		if t.size > 0 {
+			avg.size += t.size
+			sized++
+		}
+	}
+	avg.items /= float64(len(tputs))
+	if sized > 0 {
+		avg.size /= float64(sized)
+	}
+	return avg
+}
+
 // Unregister removes a remote peer from the active set, disabling any further
 // actions to/from that particular entity.
 func (ps *peerSet) Unregister(id string) error {
@@ -465,7 +527,7 @@ func (ps *peerSet) HeaderIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.headerThroughput
+		return p.headerThroughput.items
 	}
 	return ps.idlePeers(62, 64, idle, throughput)
 }
@@ -479,7 +541,7 @@ func (ps *peerSet) BodyIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.blockThroughput
+		return p.blockThroughput.items
 	}
 	return ps.idlePeers(62, 64, idle, throughput)
 }
@@ -493,7 +555,7 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.receiptThroughput
+		return p.receiptThroughput.items
 	}
 	return ps.idlePeers(63, 64, idle, throughput)
 }
@@ -507,7 +569,7 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) float64 {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
-		return p.stateThroughput
+		return p.stateThroughput.items
 	}
 	return ps.idlePeers(63, 64, idle, throughput)
 }
@@ -97,7 +97,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 		// available for the next sync.
 		for _, req := range active {
 			req.timer.Stop()
-			req.peer.SetNodeDataIdle(len(req.items))
+			req.peer.SetNodeDataIdle(len(req.items), 0)
 		}
 	}()
 	// Run the state sync.
@@ -280,7 +280,7 @@ func (s *stateSync) loop() error {
 			}
 			// The the delivery contains requested data, mark the node idle (otherwise it's a timed out delivery)
 			if !stale {
-				req.peer.SetNodeDataIdle(len(req.response))
+				req.peer.SetNodeDataIdle(len(req.response), responseSize(req.response))
 			}
 		}
 	}
@@ -447,3 +447,12 @@ func (s *stateSync) updateStats(processed, written, duplicate, unexpected int, d
 
 	log.Info("Imported new state entries", "count", processed, "flushed", written, "elapsed", common.PrettyDuration(duration), "processed", s.d.syncStatsState.processed, "pending", s.d.syncStatsState.pending, "retry", len(s.tasks), "duplicate", s.d.syncStatsState.duplicate, "unexpected", s.d.syncStatsState.unexpected)
 }
+
+// responseSize returns the total byte size of a node data response.
+func responseSize(response [][]byte) common.StorageSize {
+	var size common.StorageSize
+	for _, blob := range response {
+		size += common.StorageSize(len(blob))
+	}
+	return size
+}
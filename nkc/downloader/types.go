@@ -19,6 +19,7 @@ package downloader
 import (
 	"fmt"
 
+	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/core/types"
 )
 
@@ -29,6 +30,7 @@ type peerDropFn func(id string)
 type dataPack interface {
 	PeerId() string
 	Items() int
+	Size() common.StorageSize
 	Stats() string
 }
 
@@ -40,7 +42,14 @@ type headerPack struct {
 
 func (p *headerPack) PeerId() string { return p.peerId }
 func (p *headerPack) Items() int     { return len(p.headers) }
-func (p *headerPack) Stats() string  { return fmt.Sprintf("%d", len(p.headers)) }
+func (p *headerPack) Size() common.StorageSize {
+	var size common.StorageSize
+	for _, header := range p.headers {
+		size += header.Size()
+	}
+	return size
+}
+func (p *headerPack) Stats() string { return fmt.Sprintf("%d", len(p.headers)) }
 
 // bodyPack is a batch of block bodies returned by a peer.
 type bodyPack struct {
@@ -56,6 +65,20 @@ func (p *bodyPack) Items() int {
 	}
 	return len(p.uncles)
 }
+func (p *bodyPack) Size() common.StorageSize {
+	var size common.StorageSize
+	for _, txs := range p.transactions {
+		for _, tx := range txs {
+			size += tx.Size()
+		}
+	}
+	for _, uncles := range p.uncles {
+		for _, uncle := range uncles {
+			size += uncle.Size()
+		}
+	}
+	return size
+}
 func (p *bodyPack) Stats() string { return fmt.Sprintf("%d:%d", len(p.transactions), len(p.uncles)) }
 
 // receiptPack is a batch of receipts returned by a peer.
@@ -66,7 +89,16 @@ type receiptPack struct {
 
 func (p *receiptPack) PeerId() string { return p.peerId }
 func (p *receiptPack) Items() int     { return len(p.receipts) }
-func (p *receiptPack) Stats() string  { return fmt.Sprintf("%d", len(p.receipts)) }
+func (p *receiptPack) Size() common.StorageSize {
+	var size common.StorageSize
+	for _, receipts := range p.receipts {
+		for _, receipt := range receipts {
+			size += receipt.Size()
+		}
+	}
+	return size
+}
+func (p *receiptPack) Stats() string { return fmt.Sprintf("%d", len(p.receipts)) }
 
 // statePack is a batch of states returned by a peer.
 type statePack struct {
@@ -76,4 +108,11 @@ type statePack struct {
 
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
-func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+func (p *statePack) Size() common.StorageSize {
+	var size common.StorageSize
+	for _, state := range p.states {
+		size += common.StorageSize(len(state))
+	}
+	return size
+}
+func (p *statePack) Stats() string { return fmt.Sprintf("%d", len(p.states)) }
@@ -0,0 +1,264 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eventsink implements a service streaming the chain events (new heads,
+// logs and pending transactions) into an external message broker, so that data
+// pipelines may consume them without a bespoke RPC subscriber per node.
+package eventsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/rlp"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+const (
+	queueSize      = 4096            // Maximum number of messages waiting to be published
+	redialInterval = 5 * time.Second // Minimum time between two broker connection attempts
+)
+
+// Config contains the settings of the event sink.
+type Config struct {
+	URL        string // Message broker to publish to, the scheme selecting the broker type (e.g. nats://host:4222)
+	Encoding   string // Serialization format of the messages: "json" or "rlp"
+	HeadsTopic string // Topic to publish the new chain heads to ("" = not published)
+	LogsTopic  string // Topic to publish the new and removed logs to ("" = not published)
+	TxsTopic   string // Topic to publish the new pending transactions to ("" = not published)
+}
+
+// DefaultConfig contains the default settings of the event sink (disabled).
+var DefaultConfig = Config{
+	Encoding:   "json",
+	HeadsTopic: "netk.heads",
+	LogsTopic:  "netk.logs",
+	TxsTopic:   "netk.transactions",
+}
+
+// message is a serialized event waiting to be published.
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// rlpLog is the RLP serialization of a log, including the fields derived from
+// its block and the removal flag set on chain reorganisations.
+type rlpLog struct {
+	Log     *types.LogForStorage
+	Removed bool
+}
+
+// Service publishes the chain events of a NetworkChain service to a message
+// broker. Events are published at most once: if the broker is unreachable or
+// too slow to keep up, they are dropped.
+type Service struct {
+	config Config
+	mux    *event.TypeMux
+	host   string // Broker address for logging, without any credentials
+
+	queue   chan *message
+	dropped uint64 // Number of messages dropped since the last report (atomic)
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an event sink publishing the events posted on the given mux.
+func New(config Config, mux *event.TypeMux) (*Service, error) {
+	if config.Encoding != "json" && config.Encoding != "rlp" {
+		return nil, fmt.Errorf("unsupported event encoding %q", config.Encoding)
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lookupSink(u.Scheme); err != nil {
+		return nil, err
+	}
+	return &Service{
+		config: config,
+		mux:    mux,
+		host:   u.Scheme + "://" + u.Host,
+		queue:  make(chan *message, queueSize),
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the event sink (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// event sink (nil as it doesn't provide any user callable APIs).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting to publish the chain events.
+func (s *Service) Start(server *p2p.Server) error {
+	var events []interface{}
+	if s.config.HeadsTopic != "" || s.config.LogsTopic != "" {
+		events = append(events, core.ChainEvent{})
+	}
+	if s.config.LogsTopic != "" {
+		events = append(events, core.RemovedLogsEvent{})
+	}
+	if s.config.TxsTopic != "" {
+		events = append(events, core.TxPreEvent{})
+	}
+	s.wg.Add(2)
+	go s.loop(s.mux.Subscribe(events...))
+	go s.publishLoop()
+
+	log.Info("Event sink started", "broker", s.host, "encoding", s.config.Encoding)
+	return nil
+}
+
+// Stop implements node.Service, terminating the publishing of the chain events.
+// Messages still waiting to be published are lost.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	log.Info("Event sink stopped")
+	return nil
+}
+
+// loop serializes the chain events and queues them for publishing.
+func (s *Service) loop(sub *event.TypeMuxSubscription) {
+	defer s.wg.Done()
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev, ok := <-sub.Chan():
+			if !ok {
+				return
+			}
+			switch ev := ev.Data.(type) {
+			case core.ChainEvent:
+				if s.config.HeadsTopic != "" {
+					s.enqueue(s.config.HeadsTopic, ev.Block.Header())
+				}
+				if s.config.LogsTopic != "" {
+					s.enqueueLogs(ev.Logs)
+				}
+			case core.RemovedLogsEvent:
+				s.enqueueLogs(ev.Logs)
+
+			case core.TxPreEvent:
+				// Private transactions must not leave the node
+				if !ev.Private {
+					s.enqueue(s.config.TxsTopic, ev.Tx)
+				}
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// enqueueLogs queues the logs for publishing, one message per log.
+func (s *Service) enqueueLogs(logs []*types.Log) {
+	for _, l := range logs {
+		if s.config.Encoding == "rlp" {
+			s.enqueue(s.config.LogsTopic, &rlpLog{(*types.LogForStorage)(l), l.Removed})
+		} else {
+			s.enqueue(s.config.LogsTopic, l)
+		}
+	}
+}
+
+// enqueue serializes an event and queues it for publishing to the given topic,
+// dropping it if the queue is full.
+func (s *Service) enqueue(topic string, v interface{}) {
+	var (
+		payload []byte
+		err     error
+	)
+	if s.config.Encoding == "rlp" {
+		payload, err = rlp.EncodeToBytes(v)
+	} else {
+		payload, err = json.Marshal(v)
+	}
+	if err != nil {
+		log.Error("Failed to encode chain event", "topic", topic, "err", err)
+		return
+	}
+	select {
+	case s.queue <- &message{topic, payload}:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// publishLoop publishes the queued messages to the message broker, connecting
+// and reconnecting to it as needed.
+func (s *Service) publishLoop() {
+	defer s.wg.Done()
+
+	var (
+		sink     Sink
+		lastDial time.Time
+	)
+	defer func() {
+		if sink != nil {
+			sink.Close()
+		}
+	}()
+	for {
+		select {
+		case msg := <-s.queue:
+			// Make sure there's a broker connection, dropping messages while down
+			if sink == nil {
+				if time.Since(lastDial) < redialInterval {
+					atomic.AddUint64(&s.dropped, 1)
+					continue
+				}
+				lastDial = time.Now()
+
+				var err error
+				if sink, err = dialSink(s.config.URL); err != nil {
+					log.Warn("Failed to connect to message broker", "broker", s.host, "err", err)
+					atomic.AddUint64(&s.dropped, 1)
+					continue
+				}
+				log.Info("Connected to message broker", "broker", s.host)
+			}
+			if err := sink.Publish(msg.topic, msg.payload); err != nil {
+				log.Warn("Failed to publish chain event", "broker", s.host, "topic", msg.topic, "err", err)
+				atomic.AddUint64(&s.dropped, 1)
+
+				sink.Close()
+				sink = nil
+				continue
+			}
+			if dropped := atomic.SwapUint64(&s.dropped, 0); dropped > 0 {
+				log.Warn("Dropped chain events before publishing", "broker", s.host, "count", dropped)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
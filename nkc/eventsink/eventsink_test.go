@@ -0,0 +1,162 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eventsink
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// testSink is a message broker recording the published messages.
+type testSink chan *message
+
+func (s testSink) Publish(topic string, payload []byte) error {
+	s <- &message{topic, payload}
+	return nil
+}
+
+func (s testSink) Close() error { return nil }
+
+// newTestService creates an event sink publishing into a test broker.
+func newTestService(t *testing.T, encoding string) (*Service, *event.TypeMux, testSink) {
+	sink := make(testSink, 16)
+	RegisterSink("test", func(u *url.URL) (Sink, error) { return sink, nil })
+
+	config := DefaultConfig
+	config.URL, config.Encoding = "test://broker", encoding
+
+	mux := new(event.TypeMux)
+	service, err := New(config, mux)
+	if err != nil {
+		t.Fatalf("failed to create event sink: %v", err)
+	}
+	service.Start(nil)
+	return service, mux, sink
+}
+
+// expectMessage retrieves the next published message, checking its topic.
+func expectMessage(t *testing.T, sink testSink, topic string) []byte {
+	select {
+	case msg := <-sink:
+		if msg.topic != topic {
+			t.Fatalf("topic mismatch: have %s, want %s", msg.topic, topic)
+		}
+		return msg.payload
+	case <-time.After(time.Second):
+		t.Fatalf("message timeout on %s", topic)
+	}
+	return nil
+}
+
+// Tests that chain events are published to their topics as JSON.
+func TestPublishJSON(t *testing.T) {
+	service, mux, sink := newTestService(t, "json")
+	defer service.Stop()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)})
+	logs := []*types.Log{{Address: common.Address{0x01}, Topics: []common.Hash{{0x01}}, Data: []byte{}, BlockNumber: 1}}
+
+	key, _ := crypto.GenerateKey()
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x02}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+
+	mux.Post(core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+	mux.Post(core.RemovedLogsEvent{Logs: []*types.Log{{Address: common.Address{0x03}, Topics: []common.Hash{}, Data: []byte{}, Removed: true}}})
+	mux.Post(core.TxPreEvent{Tx: tx})
+
+	var header types.Header
+	if err := json.Unmarshal(expectMessage(t, sink, DefaultConfig.HeadsTopic), &header); err != nil || header.Hash() != block.Hash() {
+		t.Errorf("head mismatch: %v", err)
+	}
+	var l types.Log
+	if err := json.Unmarshal(expectMessage(t, sink, DefaultConfig.LogsTopic), &l); err != nil || l.Address != logs[0].Address {
+		t.Errorf("log mismatch: %v, %+v", err, l)
+	}
+	if err := json.Unmarshal(expectMessage(t, sink, DefaultConfig.LogsTopic), &l); err != nil || !l.Removed {
+		t.Errorf("removed log mismatch: %v, %+v", err, l)
+	}
+	var ptx types.Transaction
+	if err := json.Unmarshal(expectMessage(t, sink, DefaultConfig.TxsTopic), &ptx); err != nil || ptx.Hash() != tx.Hash() {
+		t.Errorf("transaction mismatch: %v", err)
+	}
+}
+
+// Tests that chain events are published as RLP if requested.
+func TestPublishRLP(t *testing.T) {
+	service, mux, sink := newTestService(t, "rlp")
+	defer service.Stop()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)})
+	logs := []*types.Log{{Address: common.Address{0x01}, BlockNumber: 1, TxHash: common.Hash{0x02}}}
+
+	mux.Post(core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+
+	var header types.Header
+	if err := rlp.DecodeBytes(expectMessage(t, sink, DefaultConfig.HeadsTopic), &header); err != nil || header.Hash() != block.Hash() {
+		t.Errorf("head mismatch: %v", err)
+	}
+	var l struct {
+		Log     types.LogForStorage
+		Removed bool
+	}
+	if err := rlp.DecodeBytes(expectMessage(t, sink, DefaultConfig.LogsTopic), &l); err != nil {
+		t.Fatalf("failed to decode log: %v", err)
+	}
+	if l.Log.Address != logs[0].Address || l.Log.TxHash != logs[0].TxHash || l.Removed {
+		t.Errorf("log mismatch: %+v", l)
+	}
+}
+
+// Tests that private transactions are not published.
+func TestSkipPrivateTransactions(t *testing.T) {
+	service, mux, sink := newTestService(t, "json")
+	defer service.Stop()
+
+	key, _ := crypto.GenerateKey()
+	private, _ := types.SignTx(types.NewTransaction(0, common.Address{0x02}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	public, _ := types.SignTx(types.NewTransaction(1, common.Address{0x02}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+
+	mux.Post(core.TxPreEvent{Tx: private, Private: true})
+	mux.Post(core.TxPreEvent{Tx: public})
+
+	var tx types.Transaction
+	if err := json.Unmarshal(expectMessage(t, sink, DefaultConfig.TxsTopic), &tx); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if tx.Hash() != public.Hash() {
+		t.Errorf("transaction mismatch: have %x, want %x", tx.Hash(), public.Hash())
+	}
+}
+
+// Tests that unknown brokers and encodings are rejected.
+func TestInvalidConfig(t *testing.T) {
+	if _, err := New(Config{URL: "carrier-pigeon://coop", Encoding: "json"}, new(event.TypeMux)); err == nil {
+		t.Errorf("unknown broker accepted")
+	}
+	if _, err := New(Config{URL: "nats://localhost:4222", Encoding: "xml"}, new(event.TypeMux)); err == nil {
+		t.Errorf("unknown encoding accepted")
+	}
+}
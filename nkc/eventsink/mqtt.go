@@ -0,0 +1,233 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eventsink
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types used by the sink.
+const (
+	mqttConnect  = 0x10
+	mqttConnack  = 0x20
+	mqttPublish  = 0x30
+	mqttPingreq  = 0xc0
+	mqttPingresp = 0xd0
+	mqttDisconn  = 0xe0
+)
+
+// mqttKeepAlive is the keepalive interval negotiated with the broker.
+const mqttKeepAlive = 60 * time.Second
+
+// mqttSink publishes messages to an MQTT 3.1.1 broker with QoS 0 (at most once).
+type mqttSink struct {
+	conn net.Conn
+	lock sync.Mutex // Serializes the writes of the publisher and the keepalive pings
+	err  error      // Error terminating the connection, failing subsequent publishes
+	quit chan struct{}
+}
+
+// dialMQTT connects to an MQTT broker at mqtt://[user:pass@]host:port[/clientid].
+func dialMQTT(u *url.URL) (Sink, error) {
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	// Assemble the connection request with a clean session
+	clientID := "netk"
+	if len(u.Path) > 1 {
+		clientID = u.Path[1:]
+	}
+	var (
+		flags   byte = 0x02
+		payload      = mqttString(nil, clientID)
+	)
+	if u.User != nil {
+		flags |= 0x80
+		payload = mqttString(payload, u.User.Username())
+		if pass, ok := u.User.Password(); ok {
+			flags |= 0x40
+			payload = mqttString(payload, pass)
+		}
+	}
+	keepalive := uint16(mqttKeepAlive / time.Second)
+
+	body := mqttString(nil, "MQTT")
+	body = append(body, 0x04, flags, byte(keepalive>>8), byte(keepalive))
+	body = append(body, payload...)
+
+	if _, err := conn.Write(mqttPacket(mqttConnect, body)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Wait for the broker to acknowledge the connection
+	reader := bufio.NewReader(conn)
+	typ, ack, err := readMQTTPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if typ != mqttConnack || len(ack) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected mqtt packet %#x", typ)
+	}
+	if ack[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt connection refused: code %d", ack[1])
+	}
+	conn.SetDeadline(time.Time{})
+
+	sink := &mqttSink{conn: conn, quit: make(chan struct{})}
+	go sink.readLoop(reader)
+	go sink.pingLoop()
+	return sink, nil
+}
+
+// mqttString appends a length prefixed UTF-8 string to the buffer.
+func mqttString(buf []byte, s string) []byte {
+	buf = append(buf, byte(len(s)>>8), byte(len(s)))
+	return append(buf, s...)
+}
+
+// mqttPacket assembles a control packet from its type and flags and the body,
+// prefixed with the variable length encoded remaining length.
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	for size := len(body); ; {
+		digit := byte(size % 128)
+		if size /= 128; size > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if size == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// readMQTTPacket reads a control packet, returning its type and body.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size, shift uint
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size |= uint(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("malformed mqtt packet length")
+		}
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
+
+// readLoop consumes the packets sent by the broker (ping responses) until the
+// connection breaks.
+func (s *mqttSink) readLoop(reader *bufio.Reader) {
+	for {
+		typ, _, err := readMQTTPacket(reader)
+		if err == nil && typ != mqttPingresp {
+			err = fmt.Errorf("unexpected mqtt packet %#x", typ)
+		}
+		if err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+// pingLoop keeps the connection alive while no messages are published.
+func (s *mqttSink) pingLoop() {
+	ticker := time.NewTicker(mqttKeepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.lock.Lock()
+			_, err := s.conn.Write(mqttPacket(mqttPingreq, nil))
+			s.lock.Unlock()
+			if err != nil {
+				s.fail(err)
+				return
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// fail records the error terminating the connection.
+func (s *mqttSink) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+	s.conn.Close()
+}
+
+// Publish implements Sink, sending a message to the given topic.
+func (s *mqttSink) Publish(topic string, payload []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	body := make([]byte, 0, len(topic)+len(payload)+2)
+	body = mqttString(body, topic)
+	body = append(body, payload...)
+
+	_, err := s.conn.Write(mqttPacket(mqttPublish, body))
+	return err
+}
+
+// Close implements Sink, disconnecting from the broker.
+func (s *mqttSink) Close() error {
+	close(s.quit)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil {
+		s.conn.Write(mqttPacket(mqttDisconn, nil))
+	}
+	return s.conn.Close()
+}
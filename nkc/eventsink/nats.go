@@ -0,0 +1,143 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout is the maximum time to wait for a broker connection to be set up.
+const dialTimeout = 10 * time.Second
+
+// natsSink publishes messages to a NATS server using its plain text protocol.
+type natsSink struct {
+	conn net.Conn
+	lock sync.Mutex // Serializes the writes of the publisher and the pong replies
+	err  error      // Error terminating the read loop, failing subsequent publishes
+}
+
+// dialNATS connects to a NATS server at nats://[user:pass@]host:port.
+func dialNATS(u *url.URL) (Sink, error) {
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	// The server greets with its INFO, answer with the connection options
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected nats greeting: %q", strings.TrimSpace(line))
+	}
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "netk"}
+	if u.User != nil {
+		opts["user"] = u.User.Username()
+		if pass, ok := u.User.Password(); ok {
+			opts["pass"] = pass
+		}
+	}
+	blob, _ := json.Marshal(opts)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", blob); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Wait for the ping to be answered, catching any authorization errors
+	if line, err = reader.ReadString('\n'); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if line = strings.TrimSpace(line); line != "PONG" {
+		conn.Close()
+		return nil, fmt.Errorf("nats connection refused: %s", line)
+	}
+	conn.SetDeadline(time.Time{})
+
+	sink := &natsSink{conn: conn}
+	go sink.readLoop(reader)
+	return sink, nil
+}
+
+// readLoop answers the keepalive pings of the server and records any protocol
+// errors it reports.
+func (s *natsSink) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			s.lock.Lock()
+			_, err = s.conn.Write([]byte("PONG\r\n"))
+			s.lock.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			err = errors.New("nats error: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		if err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+// fail records the error terminating the connection.
+func (s *natsSink) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+	s.conn.Close()
+}
+
+// Publish implements Sink, sending a message to the given subject.
+func (s *natsSink) Publish(topic string, payload []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	msg := make([]byte, 0, len(payload)+len(topic)+32)
+	msg = append(msg, fmt.Sprintf("PUB %s %d\r\n", topic, len(payload))...)
+	msg = append(msg, payload...)
+	msg = append(msg, '\r', '\n')
+
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+// Close implements Sink, terminating the connection to the server.
+func (s *natsSink) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eventsink
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Sink is a connection to a message broker the chain events are published to.
+type Sink interface {
+	// Publish sends a message to the given topic of the broker.
+	Publish(topic string, payload []byte) error
+
+	// Close terminates the connection to the broker.
+	Close() error
+}
+
+// SinkFactory connects to the message broker at the given URL.
+type SinkFactory func(u *url.URL) (Sink, error)
+
+var (
+	sinks     = make(map[string]SinkFactory)
+	sinksLock sync.RWMutex
+)
+
+func init() {
+	RegisterSink("nats", dialNATS)
+	RegisterSink("mqtt", dialMQTT)
+}
+
+// RegisterSink makes a message broker available under the given URL scheme,
+// allowing additional brokers (e.g. Kafka) to be plugged in without this package
+// depending on their client libraries. Registering a scheme twice replaces the
+// earlier factory.
+func RegisterSink(scheme string, factory SinkFactory) {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+
+	sinks[scheme] = factory
+}
+
+// dialSink connects to the message broker at the given URL, selecting the sink
+// implementation by the URL scheme.
+func dialSink(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := lookupSink(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	return factory(u)
+}
+
+// lookupSink retrieves the factory of the message broker registered under the
+// given URL scheme.
+func lookupSink(scheme string) (SinkFactory, error) {
+	sinksLock.RLock()
+	defer sinksLock.RUnlock()
+
+	factory, ok := sinks[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported message broker %q", scheme)
+	}
+	return factory, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eventsink
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Tests that messages are published to a NATS server, answering its pings.
+func TestNATSSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		for i := 0; i < 5; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- strings.TrimSpace(line)
+			switch i {
+			case 1: // PING after CONNECT
+				conn.Write([]byte("PONG\r\n"))
+			case 3: // payload after PUB
+				conn.Write([]byte("PING\r\n"))
+			}
+		}
+	}()
+	sink, err := dialNATS(&url.URL{Scheme: "nats", Host: listener.Addr().String(), User: url.UserPassword("user", "pass")})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish("netk.heads", []byte("hello")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	want := []string{`CONNECT {"name":"netk","pass":"pass","pedantic":false,"user":"user","verbose":false}`, "PING", "PUB netk.heads 5", "hello", "PONG"}
+	for i, line := range want {
+		if have := <-lines; have != line {
+			t.Errorf("line %d mismatch: have %q, want %q", i, have, line)
+		}
+	}
+}
+
+// Tests that messages are published to an MQTT broker after the handshake.
+func TestMQTTSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	packets := make(chan []byte, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Read the raw connect packet and acknowledge it
+		connect := make([]byte, 2+31)
+		if _, err := io.ReadFull(conn, connect); err != nil {
+			return
+		}
+		packets <- connect
+		conn.Write([]byte{mqttConnack, 0x02, 0x00, 0x00})
+
+		publish := make([]byte, 2+2+10+5)
+		if _, err := io.ReadFull(conn, publish); err != nil {
+			return
+		}
+		packets <- publish
+	}()
+	sink, err := dialMQTT(&url.URL{Scheme: "mqtt", Host: listener.Addr().String(), User: url.UserPassword("user", "pass"), Path: "/client1"})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish("netk.heads", []byte("hello")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	connect := append([]byte{mqttConnect, 31, 0, 4, 'M', 'Q', 'T', 'T', 4, 0xc2, 0, 60, 0, 7}, "client1"...)
+	connect = append(append(connect, 0, 4), "user"...)
+	connect = append(append(connect, 0, 4), "pass"...)
+	if have := <-packets; !bytes.Equal(have, connect) {
+		t.Errorf("connect packet mismatch: have %x, want %x", have, connect)
+	}
+	publish := append(append([]byte{mqttPublish, 17, 0, 10}, "netk.heads"...), "hello"...)
+	if have := <-packets; !bytes.Equal(have, publish) {
+		t.Errorf("publish packet mismatch: have %x, want %x", have, publish)
+	}
+}
+
+// Tests the variable length encoding of the MQTT packet sizes.
+func TestMQTTPacketLength(t *testing.T) {
+	tests := []struct {
+		size   int
+		header []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{321, []byte{0xc1, 0x02}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+	}
+	for _, tt := range tests {
+		packet := mqttPacket(mqttPublish, make([]byte, tt.size))
+		if !bytes.Equal(packet[1:1+len(tt.header)], tt.header) || len(packet) != 1+len(tt.header)+tt.size {
+			t.Errorf("size %d: length encoding mismatch: have %x, want %x", tt.size, packet[1:1+len(tt.header)], tt.header)
+			continue
+		}
+		typ, body, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(packet)))
+		if err != nil || typ != mqttPublish || len(body) != tt.size {
+			t.Errorf("size %d: decoding mismatch: type %#x, size %d, err %v", tt.size, typ, len(body), err)
+		}
+	}
+}
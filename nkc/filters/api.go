@@ -27,16 +27,35 @@ import (
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
+	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/rpc"
 )
 
 var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
+
+	headRetrievalTimeout = 10 * time.Second // maximum time to retrieve the block of a new head for a newHeads subscription
 )
 
+// maxTxStatusHashes is the maximum number of transactions a single status
+// subscription may track.
+const maxTxStatusHashes = 1024
+
+var (
+	errTxStatusLightMode = errors.New("transaction status subscriptions are not supported in light mode")
+	errTxStatusEmpty     = errors.New("no transaction hashes given")
+	errTxStatusTooMany   = fmt.Errorf("too many transaction hashes, at most %d allowed", maxTxStatusHashes)
+	errInvalidCursor     = errors.New("cursor ahead of filter changes")
+)
+
+// maxPollTimeout is the longest time a long-polling request waits for changes.
+const maxPollTimeout = 30 * time.Second
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -45,9 +64,29 @@ type filter struct {
 	hashes   []common.Hash
 	crit     FilterCriteria
 	logs     []*types.Log
+	offset   uint64        // number of changes already delivered and discarded
+	wake     chan struct{} // closed when changes arrive or the filter is removed
 	s        *Subscription // associated subscription in event system
 }
 
+// signal wakes up the long-polling requests waiting on the filter.
+func (f *filter) signal() {
+	if f.wake != nil {
+		close(f.wake)
+		f.wake = nil
+	}
+}
+
+// touch extends the lifetime of the filter by resetting its deadline.
+func (f *filter) touch() {
+	if !f.deadline.Stop() {
+		// timer expired but filter is not yet removed in timeout loop
+		// receive timer value and reset timer
+		<-f.deadline.C
+	}
+	f.deadline.Reset(deadline)
+}
+
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the NetworkChain protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
@@ -88,6 +127,7 @@ func (api *PublicFilterAPI) timeoutLoop() {
 			select {
 			case <-f.deadline.C:
 				f.s.Unsubscribe()
+				f.signal()
 				delete(api.filters, id)
 			default:
 				continue
@@ -121,10 +161,14 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
 					f.hashes = append(f.hashes, ph)
+					f.signal()
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
 				api.filtersMu.Lock()
+				if f, found := api.filters[pendingTxSub.ID]; found {
+					f.signal()
+				}
 				delete(api.filters, pendingTxSub.ID)
 				api.filtersMu.Unlock()
 				return
@@ -137,7 +181,8 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// If fullTx is set, the notifications contain the full transactions instead of their hashes.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -146,13 +191,22 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan common.Hash)
-		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
-
+		var (
+			txHashes     = make(chan common.Hash)
+			txs          = make(chan *types.Transaction)
+			pendingTxSub *Subscription
+		)
+		if fullTx != nil && *fullTx {
+			pendingTxSub = api.events.SubscribePendingTxs(txs)
+		} else {
+			pendingTxSub = api.events.SubscribePendingTxEvents(txHashes)
+		}
 		for {
 			select {
 			case h := <-txHashes:
 				notifier.Notify(rpcSub.ID, h)
+			case tx := <-txs:
+				notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
 				return
@@ -187,10 +241,14 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 				api.filtersMu.Lock()
 				if f, found := api.filters[headerSub.ID]; found {
 					f.hashes = append(f.hashes, h.Hash())
+					f.signal()
 				}
 				api.filtersMu.Unlock()
 			case <-headerSub.Err():
 				api.filtersMu.Lock()
+				if f, found := api.filters[headerSub.ID]; found {
+					f.signal()
+				}
 				delete(api.filters, headerSub.ID)
 				api.filtersMu.Unlock()
 				return
@@ -201,23 +259,56 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 	return headerSub.ID
 }
 
+// HeadsCriteria are the optional parameters of a newHeads subscription, selecting
+// the transaction details included with each new head.
+type HeadsCriteria struct {
+	IncludeTransactions bool `json:"includeTransactions"` // Include the hashes of the block's transactions
+	FullTransactions    bool `json:"fullTransactions"`    // Include the full transactions instead of their hashes
+}
+
 // NewHeads send a notification each time a new (header) block is appended to the chain.
-func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+// If transactions are requested, the notification contains the block in the format
+// of eth_getBlockByHash instead (without the total difficulty).
+func (api *PublicFilterAPI) NewHeads(ctx context.Context, crit *HeadsCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
+	var inclTx, fullTx bool
+	if crit != nil {
+		inclTx, fullTx = crit.IncludeTransactions || crit.FullTransactions, crit.FullTransactions
+	}
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
 		headers := make(chan *types.Header)
 		headersSub := api.events.SubscribeNewHeads(headers)
 
+		// Abort any block retrieval in flight (e.g. over ODR) once the subscription ends
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+			case <-notifier.Closed():
+			case <-ctx.Done():
+			}
+			cancel()
+		}()
+
 		for {
 			select {
 			case h := <-headers:
-				notifier.Notify(rpcSub.ID, h)
+				if !inclTx {
+					notifier.Notify(rpcSub.ID, h)
+					break
+				}
+				head, err := api.headWithTransactions(ctx, h, fullTx)
+				if err != nil {
+					log.Debug("Failed to assemble new head notification", "number", h.Number, "hash", h.Hash(), "err", err)
+					break
+				}
+				notifier.Notify(rpcSub.ID, head)
 			case <-rpcSub.Err():
 				headersSub.Unsubscribe()
 				return
@@ -231,6 +322,108 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// headWithTransactions retrieves the block of a new head and converts it into the
+// RPC output including its transaction hashes or, if fullTx is set, the full
+// transactions. The retrieval is aborted after headRetrievalTimeout or once the
+// context is cancelled.
+func (api *PublicFilterAPI) headWithTransactions(ctx context.Context, header *types.Header, fullTx bool) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, headRetrievalTimeout)
+	defer cancel()
+
+	block, err := api.backend.GetBlock(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	return ethapi.RPCMarshalBlock(block, true, fullTx)
+}
+
+// Lifecycle states reported by transaction status subscriptions.
+const (
+	TxStatusPending  = "pending"  // Transaction entered the pending state of the pool
+	TxStatusMined    = "mined"    // Transaction was included in a canonical block
+	TxStatusDropped  = "dropped"  // Transaction was removed from the pool without being included
+	TxStatusReplaced = "replaced" // Transaction was superseded by another with the same nonce
+)
+
+// TxStatus is a lifecycle change notification of a tracked transaction.
+type TxStatus struct {
+	Hash        common.Hash     `json:"hash"`
+	Status      string          `json:"status"`
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	Index       *hexutil.Uint64 `json:"transactionIndex,omitempty"`
+	ReplacedBy  *common.Hash    `json:"replacedBy,omitempty"`
+}
+
+// newMinedStatus creates a notification for a transaction included in a block.
+func newMinedStatus(hash common.Hash, blockHash common.Hash, number uint64, index uint64) *TxStatus {
+	return &TxStatus{
+		Hash:        hash,
+		Status:      TxStatusMined,
+		BlockHash:   &blockHash,
+		BlockNumber: (*hexutil.Uint64)(&number),
+		Index:       (*hexutil.Uint64)(&index),
+	}
+}
+
+// newDropStatus creates a notification for a transaction leaving the pool, as
+// replaced if a superseding transaction is known.
+func newDropStatus(hash common.Hash, replacement *types.Transaction) *TxStatus {
+	if replacement == nil {
+		return &TxStatus{Hash: hash, Status: TxStatusDropped}
+	}
+	replacedBy := replacement.Hash()
+	return &TxStatus{Hash: hash, Status: TxStatusReplaced, ReplacedBy: &replacedBy}
+}
+
+// TransactionStatus creates a subscription that notifies about the lifecycle of
+// the given transactions: when each becomes pending, gets mined (including the
+// block it was mined in), or is dropped from or replaced in the pool. Already
+// mined transactions are reported right after subscribing.
+func (api *PublicFilterAPI) TransactionStatus(ctx context.Context, hashes []common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	switch {
+	case !api.useMipMap:
+		return &rpc.Subscription{}, errTxStatusLightMode
+	case len(hashes) == 0:
+		return &rpc.Subscription{}, errTxStatusEmpty
+	case len(hashes) > maxTxStatusHashes:
+		return &rpc.Subscription{}, errTxStatusTooMany
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		statuses := make(chan *TxStatus)
+		statusSub := api.events.SubscribeTxStatus(hashes, statuses)
+
+		for _, hash := range hashes {
+			if tx, blockHash, number, index := core.GetTransaction(api.chainDb, hash); tx != nil {
+				notifier.Notify(rpcSub.ID, newMinedStatus(hash, blockHash, number, index))
+			}
+		}
+		for {
+			select {
+			case status := <-statuses:
+				notifier.Notify(rpcSub.ID, status)
+			case <-rpcSub.Err():
+				statusSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				statusSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -308,10 +501,14 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 				api.filtersMu.Lock()
 				if f, found := api.filters[logsSub.ID]; found {
 					f.logs = append(f.logs, l...)
+					f.signal()
 				}
 				api.filtersMu.Unlock()
 			case <-logsSub.Err():
 				api.filtersMu.Lock()
+				if f, found := api.filters[logsSub.ID]; found {
+					f.signal()
+				}
 				delete(api.filters, logsSub.ID)
 				api.filtersMu.Unlock()
 				return
@@ -350,6 +547,7 @@ func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Lock()
 	f, found := api.filters[id]
 	if found {
+		f.signal()
 		delete(api.filters, id)
 	}
 	api.filtersMu.Unlock()
@@ -406,21 +604,16 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 	defer api.filtersMu.Unlock()
 
 	if f, found := api.filters[id]; found {
-		if !f.deadline.Stop() {
-			// timer expired but filter is not yet removed in timeout loop
-			// receive timer value and reset timer
-			<-f.deadline.C
-		}
-		f.deadline.Reset(deadline)
+		f.touch()
 
 		switch f.typ {
 		case PendingTransactionsSubscription, BlocksSubscription:
 			hashes := f.hashes
-			f.hashes = nil
+			f.hashes, f.offset = nil, f.offset+uint64(len(hashes))
 			return returnHashes(hashes), nil
 		case LogsSubscription:
 			logs := f.logs
-			f.logs = nil
+			f.logs, f.offset = nil, f.offset+uint64(len(logs))
 			return returnLogs(logs), nil
 		}
 	}
@@ -428,6 +621,86 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 	return []interface{}{}, fmt.Errorf("filter not found")
 }
 
+// FilterPoll is the result of a long-polling request, containing the changes of
+// a filter past the requested cursor and the cursor to continue polling from.
+type FilterPoll struct {
+	Cursor  hexutil.Uint64 `json:"cursor"`
+	Changes interface{}    `json:"changes"`
+}
+
+// PollFilterChanges is a long-polling alternative to GetFilterChanges for clients
+// unable to use subscriptions, e.g. over HTTP. It works with any filter created by
+// eth_newFilter, eth_newBlockFilter or eth_newPendingTransactionFilter, returning
+// the changes past the given cursor. If there are none yet, it waits for up to
+// timeout milliseconds (capped at 30 seconds) for new ones to arrive.
+//
+// Unlike GetFilterChanges, changes are only discarded once they are acknowledged
+// by a request passing the cursor returned along with them, so events are not lost
+// if a response fails to reach the client. Polling starts with cursor 0.
+func (api *PublicFilterAPI) PollFilterChanges(ctx context.Context, id rpc.ID, cursor hexutil.Uint64, timeout *hexutil.Uint64) (*FilterPoll, error) {
+	wait := maxPollTimeout
+	if timeout != nil && time.Duration(*timeout)*time.Millisecond < wait {
+		wait = time.Duration(*timeout) * time.Millisecond
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		api.filtersMu.Lock()
+		f, found := api.filters[id]
+		if !found {
+			api.filtersMu.Unlock()
+			return nil, fmt.Errorf("filter not found")
+		}
+		f.touch()
+
+		// Discard the acknowledged changes and return any remaining ones
+		poll, pending, err := f.poll(uint64(cursor))
+		if err != nil || pending > 0 {
+			api.filtersMu.Unlock()
+			return poll, err
+		}
+		// No changes yet, wait for some to arrive
+		if f.wake == nil {
+			f.wake = make(chan struct{})
+		}
+		wake := f.wake
+		api.filtersMu.Unlock()
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			return poll, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// poll discards the changes of the filter preceding cursor and returns the ones
+// past it, along with their number.
+func (f *filter) poll(cursor uint64) (*FilterPoll, int, error) {
+	switch f.typ {
+	case PendingTransactionsSubscription, BlocksSubscription:
+		if cursor > f.offset+uint64(len(f.hashes)) {
+			return nil, 0, errInvalidCursor
+		}
+		if cursor > f.offset {
+			f.hashes, f.offset = f.hashes[cursor-f.offset:], cursor
+		}
+		return &FilterPoll{Cursor: hexutil.Uint64(f.offset + uint64(len(f.hashes))), Changes: returnHashes(f.hashes)}, len(f.hashes), nil
+	case LogsSubscription:
+		if cursor > f.offset+uint64(len(f.logs)) {
+			return nil, 0, errInvalidCursor
+		}
+		if cursor > f.offset {
+			f.logs, f.offset = f.logs[cursor-f.offset:], cursor
+		}
+		return &FilterPoll{Cursor: hexutil.Uint64(f.offset + uint64(len(f.logs))), Changes: returnLogs(f.logs)}, len(f.logs), nil
+	}
+	return nil, 0, fmt.Errorf("filter type %d cannot be polled", f.typ)
+}
+
 // returnHashes is a helper that will return an empty hash array case the given hash array is nil,
 // otherwise the given hashes array is returned.
 func returnHashes(hashes []common.Hash) []common.Hash {
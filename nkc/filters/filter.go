@@ -20,6 +20,7 @@ import (
 	"context"
 	"math"
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/networkchain/networkchain/common"
@@ -35,6 +36,7 @@ type Backend interface {
 	EventMux() *event.TypeMux
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -96,6 +98,16 @@ func (f *Filter) FindOnce(ctx context.Context) ([]*types.Log, error) {
 	}
 	headBlockNumber := head.Number.Uint64()
 
+	// Resolve the safe and finalized block tags through the backend
+	for _, number := range []*int64{&f.begin, &f.end} {
+		if *number == rpc.SafeBlockNumber.Int64() || *number == rpc.FinalizedBlockNumber.Int64() {
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(*number))
+			if header == nil || err != nil {
+				return nil, err
+			}
+			*number = header.Number.Int64()
+		}
+	}
 	var beginBlockNo uint64 = uint64(f.begin)
 	if f.begin == -1 {
 		beginBlockNo = headBlockNumber
@@ -105,18 +117,80 @@ func (f *Filter) FindOnce(ctx context.Context) ([]*types.Log, error) {
 		endBlockNo = headBlockNumber
 	}
 
+	// If the filter selects events of specific contracts, resolve the blocks
+	// covered by the log index directly from it
+	if tail, ok := core.GetLogIndexTail(f.db); ok && f.indexable() && endBlockNo >= tail {
+		if beginBlockNo < tail {
+			logs, blockNumber, err := f.find(ctx, beginBlockNo, tail-1)
+			if len(logs) > 0 || err != nil {
+				f.begin = int64(blockNumber + 1)
+				return logs, err
+			}
+			beginBlockNo = tail
+		}
+		logs, blockNumber, err := f.indexFind(ctx, beginBlockNo, endBlockNo)
+		f.begin = int64(blockNumber + 1)
+		return logs, err
+	}
+	logs, blockNumber, err := f.find(ctx, beginBlockNo, endBlockNo)
+	f.begin = int64(blockNumber + 1)
+	return logs, err
+}
+
+// find returns the logs of the first block within the given range containing
+// matching entries, along with the number of the last block searched.
+func (f *Filter) find(ctx context.Context, start, end uint64) ([]*types.Log, uint64, error) {
 	// if no addresses are present we can't make use of fast search which
 	// uses the mipmap bloom filters to check for fast inclusion and uses
 	// higher range probability in order to ensure at least a false positive
 	if !f.useMipMap || len(f.addresses) == 0 {
-		logs, blockNumber, err := f.getLogs(ctx, beginBlockNo, endBlockNo)
-		f.begin = int64(blockNumber + 1)
-		return logs, err
+		return f.getLogs(ctx, start, end)
 	}
+	logs, blockNumber := f.mipFind(start, end, 0)
+	return logs, blockNumber, nil
+}
 
-	logs, blockNumber := f.mipFind(beginBlockNo, endBlockNo, 0)
-	f.begin = int64(blockNumber + 1)
-	return logs, nil
+// indexable returns whether the log index can be used to resolve the filter,
+// requiring both the contract addresses and the event signatures to be set.
+func (f *Filter) indexable() bool {
+	if !f.useMipMap || len(f.addresses) == 0 || len(f.topics) == 0 || len(f.topics[0]) == 0 {
+		return false
+	}
+	for _, topic := range f.topics[0] {
+		if topic == (common.Hash{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexFind looks up the blocks containing events of the filtered contracts
+// in the log index, returning the logs of the first one with matching entries.
+func (f *Filter) indexFind(ctx context.Context, start, end uint64) ([]*types.Log, uint64, error) {
+	for section := start / core.LogIndexSectionSize; section <= end/core.LogIndexSectionSize; section++ {
+		var numbers []uint64
+		for _, addr := range f.addresses {
+			for _, topic := range f.topics[0] {
+				numbers = append(numbers, core.GetLogIndex(f.db, addr, topic, section)...)
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+		for i, number := range numbers {
+			if number < start || number > end || (i > 0 && numbers[i-1] == number) {
+				continue
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return nil, end, err
+			}
+			logs, err := f.blockLogs(ctx, header)
+			if len(logs) > 0 || err != nil {
+				return logs, number, err
+			}
+		}
+	}
+	return nil, end, nil
 }
 
 // Run filters logs with the current parameters set
@@ -177,16 +251,10 @@ func (f *Filter) getLogs(ctx context.Context, start, end uint64) (logs []*types.
 		// Use bloom filtering to see if this block is interesting given the
 		// current parameters
 		if f.bloomFilter(header.Bloom) {
-			// Get the logs of the block
-			receipts, err := f.backend.GetReceipts(ctx, header.Hash())
+			logs, err = f.blockLogs(ctx, header)
 			if err != nil {
 				return nil, end, err
 			}
-			var unfiltered []*types.Log
-			for _, receipt := range receipts {
-				unfiltered = append(unfiltered, ([]*types.Log)(receipt.Logs)...)
-			}
-			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 			if len(logs) > 0 {
 				return logs, uint64(blockNumber), nil
 			}
@@ -196,6 +264,19 @@ func (f *Filter) getLogs(ctx context.Context, start, end uint64) (logs []*types.
 	return logs, end, nil
 }
 
+// blockLogs retrieves the logs of the given block matching the filter criteria.
+func (f *Filter) blockLogs(ctx context.Context, header *types.Header) ([]*types.Log, error) {
+	receipts, err := f.backend.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []*types.Log
+	for _, receipt := range receipts {
+		unfiltered = append(unfiltered, ([]*types.Log)(receipt.Logs)...)
+	}
+	return filterLogs(unfiltered, nil, nil, f.addresses, f.topics), nil
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
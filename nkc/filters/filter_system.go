@@ -50,6 +50,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// TransactionStatusSubscription queries lifecycle changes of a set of
+	// transactions entering, leaving or getting included from the pool
+	TransactionStatusSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -65,7 +68,10 @@ type subscription struct {
 	logsCrit  FilterCriteria
 	logs      chan []*types.Log
 	hashes    chan common.Hash
+	txs       chan *types.Transaction // full pending transactions, replacing hashes if set
 	headers   chan *types.Header
+	txHashes  map[common.Hash]struct{}
+	txStatus  chan *TxStatus
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -129,7 +135,9 @@ func (sub *Subscription) Unsubscribe() {
 				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
+			case <-sub.f.txs:
 			case <-sub.f.headers:
+			case <-sub.f.txStatus:
 			}
 		}
 
@@ -274,6 +282,45 @@ func (es *EventSystem) SubscribePendingTxEvents(hashes chan common.Hash) *Subscr
 	return es.subscribe(sub)
 }
 
+// SubscribePendingTxs creates a subscription that writes the full transactions
+// entering the transaction pool.
+func (es *EventSystem) SubscribePendingTxs(txs chan *types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		txs:       txs,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+
+	return es.subscribe(sub)
+}
+
+// SubscribeTxStatus creates a subscription that writes the lifecycle changes of
+// the given transactions: entering the pool, getting mined, dropped or replaced.
+func (es *EventSystem) SubscribeTxStatus(hashes []common.Hash, status chan *TxStatus) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       TransactionStatusSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		headers:   make(chan *types.Header),
+		txHashes:  make(map[common.Hash]struct{}, len(hashes)),
+		txStatus:  status,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	for _, hash := range hashes {
+		sub.txHashes[hash] = struct{}{}
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 // broadcast event to filters that match criteria.
@@ -310,17 +357,51 @@ func (es *EventSystem) broadcast(filters filterIndex, ev *event.TypeMuxEvent) {
 			}
 		}
 	case core.TxPreEvent:
+		// Private transactions are not announced to pending transaction filters,
+		// only to the status subscriptions of clients already knowing their hash
 		for _, f := range filters[PendingTransactionsSubscription] {
-			if ev.Time.After(f.created) {
+			if e.Private || !ev.Time.After(f.created) {
+				continue
+			}
+			if f.txs != nil {
+				f.txs <- e.Tx
+			} else {
 				f.hashes <- e.Tx.Hash()
 			}
 		}
+		for _, f := range filters[TransactionStatusSubscription] {
+			if _, ok := f.txHashes[e.Tx.Hash()]; ok && ev.Time.After(f.created) {
+				f.txStatus <- &TxStatus{Hash: e.Tx.Hash(), Status: TxStatusPending}
+			}
+		}
+	case core.TxDropEvent:
+		hash := e.Tx.Hash()
+		for _, f := range filters[TransactionStatusSubscription] {
+			if _, ok := f.txHashes[hash]; !ok || !ev.Time.After(f.created) {
+				continue
+			}
+			// Transactions included in a block are also dropped from the pool, but
+			// those are reported through the chain events instead
+			if tx, _, _, _ := core.GetTransaction(es.backend.ChainDb(), hash); tx != nil {
+				continue
+			}
+			f.txStatus <- newDropStatus(hash, e.Replacement)
+		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
 			if ev.Time.After(f.created) {
 				f.headers <- e.Block.Header()
 			}
 		}
+		for _, f := range filters[TransactionStatusSubscription] {
+			if ev.Time.After(f.created) {
+				for i, tx := range e.Block.Transactions() {
+					if _, ok := f.txHashes[tx.Hash()]; ok {
+						f.txStatus <- newMinedStatus(tx.Hash(), e.Block.Hash(), e.Block.NumberU64(), uint64(i))
+					}
+				}
+			}
+		}
 		if es.lightMode && len(filters[LogsSubscription]) > 0 {
 			es.lightFilterNewHead(e.Block.Header(), func(header *types.Header, remove bool) {
 				for _, f := range filters[LogsSubscription] {
@@ -396,7 +477,7 @@ func (es *EventSystem) lightFilterLogs(header *types.Header, addresses []common.
 func (es *EventSystem) eventLoop() {
 	var (
 		index = make(filterIndex)
-		sub   = es.mux.Subscribe(core.PendingLogsEvent{}, core.RemovedLogsEvent{}, []*types.Log{}, core.TxPreEvent{}, core.ChainEvent{})
+		sub   = es.mux.Subscribe(core.PendingLogsEvent{}, core.RemovedLogsEvent{}, []*types.Log{}, core.TxPreEvent{}, core.TxDropEvent{}, core.ChainEvent{})
 	)
 
 	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
@@ -24,10 +24,13 @@ import (
 	"time"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/internal/ethapi"
 	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rpc"
 )
@@ -63,6 +66,11 @@ func (b *testBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (t
 	return core.GetBlockReceipts(b.db, blockHash, num), nil
 }
 
+func (b *testBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	num := core.GetBlockNumber(b.db, blockHash)
+	return core.GetBlock(b.db, blockHash, num), nil
+}
+
 // TestBlockSubscription tests if a block subscription returns block hashes for posted chain events.
 // It creates multiple subscriptions:
 // - one at the start and should receive all posted chain events and a second (blockHashes)
@@ -120,6 +128,146 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestHeadWithTransactions tests that new head notifications can be extended with
+// the transaction hashes or the full transactions of the head block.
+func TestHeadWithTransactions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = new(event.TypeMux)
+		db, _    = ethdb.NewMemDatabase()
+		backend  = &testBackend{mux, db}
+		api      = NewPublicFilterAPI(backend, false)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		genesis  = core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+		chain, _ = core.GenerateChain(params.TestChainConfig, genesis, db, 1, func(i int, gen *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0x01}, big.NewInt(1), big.NewInt(21000), nil, nil), types.HomesteadSigner{}, key)
+			gen.AddTx(tx)
+		})
+	)
+	block := chain[0]
+	core.WriteBlock(db, block)
+	tx := block.Transactions()[0]
+
+	head, err := api.headWithTransactions(context.Background(), block.Header(), false)
+	if err != nil {
+		t.Fatalf("failed to assemble head with transaction hashes: %v", err)
+	}
+	if head["hash"] != block.Hash() {
+		t.Errorf("head hash mismatch: have %v, want %x", head["hash"], block.Hash())
+	}
+	if txs := head["transactions"].([]interface{}); len(txs) != 1 || txs[0] != tx.Hash() {
+		t.Errorf("transaction hashes mismatch: have %v, want [%x]", txs, tx.Hash())
+	}
+	head, err = api.headWithTransactions(context.Background(), block.Header(), true)
+	if err != nil {
+		t.Fatalf("failed to assemble head with full transactions: %v", err)
+	}
+	if txs := head["transactions"].([]interface{}); len(txs) != 1 || txs[0].(*ethapi.RPCTransaction).Hash != tx.Hash() {
+		t.Errorf("full transactions mismatch: have %v, want [%x]", txs, tx.Hash())
+	}
+	if _, err := api.headWithTransactions(context.Background(), &types.Header{Number: big.NewInt(2)}, false); err == nil {
+		t.Errorf("assembled head for unknown block")
+	}
+}
+
+// stallingBackend is a filter backend whose block retrievals never complete, like
+// an ODR retrieval without any peer to serve it, until their context is done.
+type stallingBackend struct {
+	*testBackend
+	stalled chan struct{} // Notified when a retrieval starts stalling (optional)
+	aborted chan error    // Notified when a retrieval is aborted (optional)
+}
+
+func (b *stallingBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	if b.stalled != nil {
+		b.stalled <- struct{}{}
+	}
+	<-ctx.Done()
+	if b.aborted != nil {
+		b.aborted <- ctx.Err()
+	}
+	return nil, ctx.Err()
+}
+
+// TestHeadWithTransactionsAbort tests that the block retrieval of a new head is
+// aborted on timeout or when the subscription ends.
+func TestHeadWithTransactionsAbort(t *testing.T) {
+	defer func(timeout time.Duration) { headRetrievalTimeout = timeout }(headRetrievalTimeout)
+	headRetrievalTimeout = 100 * time.Millisecond
+
+	var (
+		mux     = new(event.TypeMux)
+		db, _   = ethdb.NewMemDatabase()
+		backend = &stallingBackend{testBackend: &testBackend{mux, db}}
+		api     = NewPublicFilterAPI(backend, false)
+		header  = &types.Header{Number: big.NewInt(1)}
+	)
+	if _, err := api.headWithTransactions(context.Background(), header, false); err != context.DeadlineExceeded {
+		t.Errorf("timed out retrieval error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	headRetrievalTimeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := api.headWithTransactions(ctx, header, false)
+		errc <- err
+	}()
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("cancelled retrieval error mismatch: have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("retrieval not aborted on cancellation")
+	}
+}
+
+// TestNewHeadsUnsubscribeAbort tests that ending a newHeads subscription including
+// transactions aborts the block retrieval in flight.
+func TestNewHeadsUnsubscribeAbort(t *testing.T) {
+	var (
+		mux     = new(event.TypeMux)
+		db, _   = ethdb.NewMemDatabase()
+		backend = &stallingBackend{testBackend: &testBackend{mux, db}, stalled: make(chan struct{}), aborted: make(chan error)}
+		api     = NewPublicFilterAPI(backend, false)
+		genesis = new(core.Genesis).MustCommit(db)
+	)
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register filter API: %v", err)
+	}
+	defer server.Stop()
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	heads := make(chan map[string]interface{})
+	sub, err := client.EthSubscribe(context.Background(), heads, "newHeads", &HeadsCriteria{IncludeTransactions: true})
+	if err != nil {
+		t.Fatalf("failed to subscribe to new heads: %v", err)
+	}
+	// Wait for the retrieval of a new head to stall, then unsubscribe
+	time.Sleep(100 * time.Millisecond) // Wait for the event system to install the subscription
+	mux.Post(core.ChainEvent{Hash: genesis.Hash(), Block: genesis})
+	select {
+	case <-backend.stalled:
+	case <-time.After(time.Second):
+		t.Fatalf("block of new head not retrieved")
+	}
+	sub.Unsubscribe()
+	select {
+	case err := <-backend.aborted:
+		if err != context.Canceled {
+			t.Errorf("aborted retrieval error mismatch: have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("block retrieval not aborted on unsubscribe")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -144,6 +292,8 @@ func TestPendingTxFilter(t *testing.T) {
 	fid0 := api.NewPendingTransactionFilter()
 
 	time.Sleep(1 * time.Second)
+	// Private transactions must not be reported
+	mux.Post(core.TxPreEvent{Tx: types.NewTransaction(5, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil), Private: true})
 	for _, tx := range transactions {
 		ev := core.TxPreEvent{Tx: tx}
 		mux.Post(ev)
@@ -171,6 +321,82 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPollFilterChanges tests that filter changes can be long-polled, with changes
+// only being discarded once acknowledged through the returned cursor.
+func TestPollFilterChanges(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux     = new(event.TypeMux)
+		db, _   = ethdb.NewMemDatabase()
+		backend = &testBackend{mux, db}
+		api     = NewPublicFilterAPI(backend, false)
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil),
+			types.NewTransaction(2, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil),
+		}
+		short = hexutil.Uint64(10)
+		long  = hexutil.Uint64(5000)
+	)
+	fid := api.NewPendingTransactionFilter()
+
+	// Polling without any changes should time out with an empty result
+	poll, err := api.PollFilterChanges(context.Background(), fid, 0, &short)
+	if err != nil {
+		t.Fatalf("failed to poll empty filter: %v", err)
+	}
+	if hashes := poll.Changes.([]common.Hash); len(hashes) != 0 || poll.Cursor != 0 {
+		t.Fatalf("empty poll mismatch: have %d changes at cursor %d, want 0 at 0", len(hashes), poll.Cursor)
+	}
+	// Post the transactions while polling and wait for all of them to arrive
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		for _, tx := range transactions {
+			mux.Post(core.TxPreEvent{Tx: tx})
+		}
+	}()
+	for {
+		if poll, err = api.PollFilterChanges(context.Background(), fid, 0, &long); err != nil {
+			t.Fatalf("failed to poll filter: %v", err)
+		}
+		if len(poll.Changes.([]common.Hash)) >= len(transactions) {
+			break
+		}
+	}
+	// Unacknowledged changes must be returned again, acknowledged ones not
+	if poll, err = api.PollFilterChanges(context.Background(), fid, 0, &short); err != nil {
+		t.Fatalf("failed to repoll filter: %v", err)
+	}
+	hashes := poll.Changes.([]common.Hash)
+	if len(hashes) != len(transactions) || poll.Cursor != hexutil.Uint64(len(transactions)) {
+		t.Fatalf("repoll mismatch: have %d changes at cursor %d, want %d at %d", len(hashes), poll.Cursor, len(transactions), len(transactions))
+	}
+	for i := range hashes {
+		if hashes[i] != transactions[i].Hash() {
+			t.Errorf("hashes[%d] invalid, want %x, got %x", i, transactions[i].Hash(), hashes[i])
+		}
+	}
+	if poll, err = api.PollFilterChanges(context.Background(), fid, poll.Cursor, &short); err != nil {
+		t.Fatalf("failed to poll acknowledged filter: %v", err)
+	}
+	if hashes := poll.Changes.([]common.Hash); len(hashes) != 0 || poll.Cursor != hexutil.Uint64(len(transactions)) {
+		t.Fatalf("acknowledged poll mismatch: have %d changes at cursor %d, want 0 at %d", len(hashes), poll.Cursor, len(transactions))
+	}
+	if _, err := api.PollFilterChanges(context.Background(), fid, poll.Cursor+1, &short); err != errInvalidCursor {
+		t.Fatalf("future cursor error mismatch: have %v, want %v", err, errInvalidCursor)
+	}
+	// Uninstalling the filter must abort pending polls
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		api.UninstallFilter(fid)
+	}()
+	if _, err := api.PollFilterChanges(context.Background(), fid, poll.Cursor, &long); err == nil {
+		t.Fatalf("poll succeeded on uninstalled filter")
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -461,3 +687,57 @@ func TestPendingLogsSubscription(t *testing.T) {
 		}
 	}
 }
+
+// TestTxStatusSubscription tests that transaction status subscriptions report the
+// lifecycle events of the tracked transactions only, skipping the pool drops of
+// already mined ones.
+func TestTxStatusSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux     = new(event.TypeMux)
+		db, _   = ethdb.NewMemDatabase()
+		backend = &testBackend{mux, db}
+		es      = NewEventSystem(mux, backend, false)
+
+		to  = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		txs = []*types.Transaction{
+			types.NewTransaction(0, to, new(big.Int), new(big.Int), new(big.Int), nil),
+			types.NewTransaction(1, to, new(big.Int), new(big.Int), new(big.Int), nil),
+			types.NewTransaction(2, to, new(big.Int), new(big.Int), new(big.Int), nil),
+			types.NewTransaction(1, to, new(big.Int), big.NewInt(1), new(big.Int), nil),
+		}
+		block = types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs[:1], nil, nil)
+	)
+	if err := core.WriteTransactions(db, block); err != nil {
+		t.Fatalf("failed to write transactions: %v", err)
+	}
+	statuses := make(chan *TxStatus)
+	sub := es.SubscribeTxStatus([]common.Hash{txs[0].Hash(), txs[1].Hash(), txs[2].Hash()}, statuses)
+	defer sub.Unsubscribe()
+
+	go func() {
+		mux.Post(core.TxPreEvent{Tx: txs[3]})
+		mux.Post(core.TxPreEvent{Tx: txs[0]})
+		mux.Post(core.TxDropEvent{Tx: txs[1], Replacement: txs[3]})
+		mux.Post(core.ChainEvent{Block: block, Hash: block.Hash()})
+		mux.Post(core.TxDropEvent{Tx: txs[0]})
+		mux.Post(core.TxDropEvent{Tx: txs[2]})
+	}()
+	want := []*TxStatus{
+		{Hash: txs[0].Hash(), Status: TxStatusPending},
+		newDropStatus(txs[1].Hash(), txs[3]),
+		newMinedStatus(txs[0].Hash(), block.Hash(), 1, 0),
+		{Hash: txs[2].Hash(), Status: TxStatusDropped},
+	}
+	for i, status := range want {
+		select {
+		case have := <-statuses:
+			if !reflect.DeepEqual(have, status) {
+				t.Errorf("status %d mismatch: have %+v, want %+v", i, have, status)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("status %d: timeout", i)
+		}
+	}
+}
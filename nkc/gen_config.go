@@ -10,6 +10,7 @@ import (
 	"github.com/networkchain/networkchain/core"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/eth/gasprice"
+	"github.com/networkchain/networkchain/p2p/netutil"
 )
 
 func (c Config) MarshalTOML() (interface{}, error) {
@@ -17,15 +18,27 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		MaxPeers                int  `toml:"-"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		StaticSync              bool             `toml:",omitempty"`
+		SyncAnchor              common.Hash      `toml:",omitempty"`
+		LightServ               int              `toml:",omitempty"`
+		LightPeers              int              `toml:",omitempty"`
+		LightHeaderRetention    uint64           `toml:",omitempty"`
+		LightProbeLatency       bool             `toml:",omitempty"`
+		LightPreferNets         *netutil.Netlist `toml:",omitempty"`
+		MaxPeers                int              `toml:"-"`
+		SkipBcVersionCheck      bool             `toml:"-"`
+		DatabaseHandles         int              `toml:"-"`
 		DatabaseCache           int
+		TrieCache               int
+		GCHeadroom              int
+		LogIndex                bool
+		StateSnapshot           bool
+		StateGC                 bool
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
+		MinerTxOrder            string         `toml:",omitempty"`
+		MinerNotify             []string       `toml:",omitempty"`
 		GasPrice                *big.Int
 		EthashCacheDir          string
 		EthashCachesInMem       int
@@ -33,9 +46,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EthashDatasetDir        string
 		EthashDatasetsInMem     int
 		EthashDatasetsOnDisk    int
+		RemoteSealer            string `toml:",omitempty"`
+		SigningPolicy           string `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
+		TxRescue                RescueConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		TrackStateAccess        bool
+		ParallelExecution       bool
+		RecordWitness           bool
+		SafeDepth               uint64
+		FinalityDepth           uint64
 		DocRoot                 string `toml:"-"`
 		PowFake                 bool   `toml:"-"`
 		PowTest                 bool   `toml:"-"`
@@ -45,15 +66,27 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.StaticSync = c.StaticSync
+	enc.SyncAnchor = c.SyncAnchor
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightHeaderRetention = c.LightHeaderRetention
+	enc.LightProbeLatency = c.LightProbeLatency
+	enc.LightPreferNets = c.LightPreferNets
 	enc.MaxPeers = c.MaxPeers
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TrieCache = c.TrieCache
+	enc.GCHeadroom = c.GCHeadroom
+	enc.LogIndex = c.LogIndex
+	enc.StateSnapshot = c.StateSnapshot
+	enc.StateGC = c.StateGC
 	enc.Etherbase = c.Etherbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
+	enc.MinerTxOrder = c.MinerTxOrder
+	enc.MinerNotify = c.MinerNotify
 	enc.GasPrice = c.GasPrice
 	enc.EthashCacheDir = c.EthashCacheDir
 	enc.EthashCachesInMem = c.EthashCachesInMem
@@ -61,9 +94,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EthashDatasetDir = c.EthashDatasetDir
 	enc.EthashDatasetsInMem = c.EthashDatasetsInMem
 	enc.EthashDatasetsOnDisk = c.EthashDatasetsOnDisk
+	enc.RemoteSealer = c.RemoteSealer
+	enc.SigningPolicy = c.SigningPolicy
 	enc.TxPool = c.TxPool
+	enc.TxRescue = c.TxRescue
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.TrackStateAccess = c.TrackStateAccess
+	enc.ParallelExecution = c.ParallelExecution
+	enc.RecordWitness = c.RecordWitness
+	enc.SafeDepth = c.SafeDepth
+	enc.FinalityDepth = c.FinalityDepth
 	enc.DocRoot = c.DocRoot
 	enc.PowFake = c.PowFake
 	enc.PowTest = c.PowTest
@@ -76,15 +117,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		MaxPeers                *int  `toml:"-"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		StaticSync              *bool            `toml:",omitempty"`
+		SyncAnchor              *common.Hash     `toml:",omitempty"`
+		LightServ               *int             `toml:",omitempty"`
+		LightPeers              *int             `toml:",omitempty"`
+		LightHeaderRetention    *uint64          `toml:",omitempty"`
+		LightProbeLatency       *bool            `toml:",omitempty"`
+		LightPreferNets         *netutil.Netlist `toml:",omitempty"`
+		MaxPeers                *int             `toml:"-"`
+		SkipBcVersionCheck      *bool            `toml:"-"`
+		DatabaseHandles         *int             `toml:"-"`
 		DatabaseCache           *int
+		TrieCache               *int
+		GCHeadroom              *int
+		LogIndex                *bool
+		StateSnapshot           *bool
+		StateGC                 *bool
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes   `toml:",omitempty"`
+		MinerTxOrder            *string         `toml:",omitempty"`
+		MinerNotify             []string        `toml:",omitempty"`
 		GasPrice                *big.Int
 		EthashCacheDir          *string
 		EthashCachesInMem       *int
@@ -92,9 +145,17 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EthashDatasetDir        *string
 		EthashDatasetsInMem     *int
 		EthashDatasetsOnDisk    *int
+		RemoteSealer            *string `toml:",omitempty"`
+		SigningPolicy           *string `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
+		TxRescue                *RescueConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		TrackStateAccess        *bool
+		ParallelExecution       *bool
+		RecordWitness           *bool
+		SafeDepth               *uint64
+		FinalityDepth           *uint64
 		DocRoot                 *string `toml:"-"`
 		PowFake                 *bool   `toml:"-"`
 		PowTest                 *bool   `toml:"-"`
@@ -113,12 +174,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.StaticSync != nil {
+		c.StaticSync = *dec.StaticSync
+	}
+	if dec.SyncAnchor != nil {
+		c.SyncAnchor = *dec.SyncAnchor
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightHeaderRetention != nil {
+		c.LightHeaderRetention = *dec.LightHeaderRetention
+	}
+	if dec.LightProbeLatency != nil {
+		c.LightProbeLatency = *dec.LightProbeLatency
+	}
+	if dec.LightPreferNets != nil {
+		c.LightPreferNets = dec.LightPreferNets
+	}
 	if dec.MaxPeers != nil {
 		c.MaxPeers = *dec.MaxPeers
 	}
@@ -131,6 +207,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TrieCache != nil {
+		c.TrieCache = *dec.TrieCache
+	}
+	if dec.GCHeadroom != nil {
+		c.GCHeadroom = *dec.GCHeadroom
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.StateSnapshot != nil {
+		c.StateSnapshot = *dec.StateSnapshot
+	}
+	if dec.StateGC != nil {
+		c.StateGC = *dec.StateGC
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -140,6 +231,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.ExtraData != nil {
 		c.ExtraData = dec.ExtraData
 	}
+	if dec.MinerTxOrder != nil {
+		c.MinerTxOrder = *dec.MinerTxOrder
+	}
+	if dec.MinerNotify != nil {
+		c.MinerNotify = dec.MinerNotify
+	}
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
@@ -161,15 +258,39 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EthashDatasetsOnDisk != nil {
 		c.EthashDatasetsOnDisk = *dec.EthashDatasetsOnDisk
 	}
+	if dec.RemoteSealer != nil {
+		c.RemoteSealer = *dec.RemoteSealer
+	}
+	if dec.SigningPolicy != nil {
+		c.SigningPolicy = *dec.SigningPolicy
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.TxRescue != nil {
+		c.TxRescue = *dec.TxRescue
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.TrackStateAccess != nil {
+		c.TrackStateAccess = *dec.TrackStateAccess
+	}
+	if dec.ParallelExecution != nil {
+		c.ParallelExecution = *dec.ParallelExecution
+	}
+	if dec.RecordWitness != nil {
+		c.RecordWitness = *dec.RecordWitness
+	}
+	if dec.SafeDepth != nil {
+		c.SafeDepth = *dec.SafeDepth
+	}
+	if dec.FinalityDepth != nil {
+		c.FinalityDepth = *dec.FinalityDepth
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -26,11 +26,14 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/golang-lru"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/consensus"
 	"github.com/networkchain/networkchain/consensus/misc"
 	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/forkid"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/eth/chainaccess"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/eth/fetcher"
 	"github.com/networkchain/networkchain/ethdb"
@@ -43,8 +46,9 @@ import (
 )
 
 const (
-	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned blocks, headers or node data.
-	estHeaderRlpSize  = 500             // Approximate size of an RLP encoded block header
+	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned node data.
+
+	maxBroadcastBlocks = 1024 // Maximum block hashes to remember as already broadcast (prevent DOS)
 )
 
 var (
@@ -59,22 +63,48 @@ func errResp(code errCode, format string, v ...interface{}) error {
 	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
 }
 
+// decodeHashes decodes the hash list of a retrieval message, up to the given
+// maximum. Surplus hashes are ignored without being decoded.
+func decodeHashes(msg p2p.Msg, max int) ([]common.Hash, error) {
+	stream := msg.Stream()
+	if _, err := stream.List(); err != nil {
+		return nil, err
+	}
+	var hashes []common.Hash
+	for len(hashes) < max {
+		var hash common.Hash
+		if err := stream.Decode(&hash); err == rlp.EOL {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
 type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	noSync    uint32 // Flag whether chain synchronisation is suspended (e.g. under memory pressure)
 
 	txpool      txPool
 	blockchain  *core.BlockChain
 	chaindb     ethdb.Database
 	chainconfig *params.ChainConfig
-	maxPeers    int
+	forkFilter  forkid.Filter                // Fork ID filter, constant across the lifetime of the node
+	syncPeers   map[discover.NodeID]struct{} // Peers to exclusively sync blocks from (nil = any peer)
+	maxPeers    int32                        // Maximum number of eth peers, adjustable at runtime
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
 
+	propagated *lru.Cache // Block hashes already pushed in full to a subset of peers, oldest evicted first
+	announced  *lru.Cache // Block hashes already announced to the remaining peers, oldest evicted first
+
 	SubProtocols []p2p.Protocol
 
 	eventMux      *event.TypeMux
@@ -103,13 +133,17 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chaindb:     chaindb,
 		chainconfig: config,
-		maxPeers:    maxPeers,
+		forkFilter:  forkid.NewFilter(config, blockchain.Genesis().Hash(), func() uint64 { return blockchain.CurrentHeader().Number.Uint64() }),
+		maxPeers:    int32(maxPeers),
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
+	manager.propagated, _ = lru.New(maxBroadcastBlocks)
+	manager.announced, _ = lru.New(maxBroadcastBlocks)
+
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
@@ -200,7 +234,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 func (pm *ProtocolManager) Start() {
 	// broadcast transactions
-	pm.txSub = pm.eventMux.Subscribe(core.TxPreEvent{})
+	pm.txSub = pm.eventMux.Subscribe(core.TxPreEvent{}, core.TxRebroadcastEvent{})
 	go pm.txBroadcastLoop()
 	// broadcast mined blocks
 	pm.minedBlockSub = pm.eventMux.Subscribe(core.NewMinedBlockEvent{})
@@ -236,6 +270,24 @@ func (pm *ProtocolManager) Stop() {
 	log.Info("NetworkChain protocol stopped")
 }
 
+// SetMaxPeers adjusts the maximum number of eth peers. A lowered limit only
+// applies to new connections, existing peers are not disconnected.
+func (pm *ProtocolManager) SetMaxPeers(maxPeers int) {
+	atomic.StoreInt32(&pm.maxPeers, int32(maxPeers))
+}
+
+// PauseSync suspends or resumes chain synchronisation, aborting any one running
+// when suspending. Blocks propagated by peers are still imported meanwhile.
+func (pm *ProtocolManager) PauseSync(paused bool) {
+	if !paused {
+		atomic.StoreUint32(&pm.noSync, 0)
+		return
+	}
+	if atomic.SwapUint32(&pm.noSync, 1) == 0 {
+		pm.downloader.Cancel()
+	}
+}
+
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 	return newPeer(pv, p, newMeteredMsgWriter(rw))
 }
@@ -243,14 +295,17 @@ func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *p
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	if pm.peers.Len() >= pm.maxPeers {
+	if pm.peers.Len() >= int(atomic.LoadInt32(&pm.maxPeers)) {
 		return p2p.DiscTooManyPeers
 	}
 	p.Log().Debug("NetworkChain peer connected", "name", p.Name())
 
 	// Execute the NetworkChain handshake
-	td, head, genesis := pm.blockchain.Status()
-	if err := p.Handshake(pm.networkId, td, head, genesis); err != nil {
+	var (
+		td, head, genesis = pm.blockchain.Status()
+		forkID            = forkid.NewID(pm.chainconfig, genesis, pm.blockchain.CurrentHeader().Number.Uint64())
+	)
+	if err := p.Handshake(pm.networkId, td, head, genesis, forkID, pm.forkFilter); err != nil {
 		p.Log().Debug("NetworkChain handshake failed", "err", err)
 		return err
 	}
@@ -326,75 +381,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&query); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
-		hashMode := query.Origin.Hash != (common.Hash{})
-
 		// Gather headers until the fetch or network limits is reached
-		var (
-			bytes   common.StorageSize
-			headers []*types.Header
-			unknown bool
-		)
-		for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
-			// Retrieve the next header satisfying the query
-			var origin *types.Header
-			if hashMode {
-				origin = pm.blockchain.GetHeaderByHash(query.Origin.Hash)
-			} else {
-				origin = pm.blockchain.GetHeaderByNumber(query.Origin.Number)
-			}
-			if origin == nil {
-				break
-			}
-			number := origin.Number.Uint64()
-			headers = append(headers, origin)
-			bytes += estHeaderRlpSize
-
-			// Advance to the next header of the query
-			switch {
-			case query.Origin.Hash != (common.Hash{}) && query.Reverse:
-				// Hash based traversal towards the genesis block
-				for i := 0; i < int(query.Skip)+1; i++ {
-					if header := pm.blockchain.GetHeader(query.Origin.Hash, number); header != nil {
-						query.Origin.Hash = header.ParentHash
-						number--
-					} else {
-						unknown = true
-						break
-					}
-				}
-			case query.Origin.Hash != (common.Hash{}) && !query.Reverse:
-				// Hash based traversal towards the leaf block
-				var (
-					current = origin.Number.Uint64()
-					next    = current + query.Skip + 1
-				)
-				if next <= current {
-					infos, _ := json.MarshalIndent(p.Peer.Info(), "", "  ")
-					p.Log().Warn("GetBlockHeaders skip overflow attack", "current", current, "skip", query.Skip, "next", next, "attacker", infos)
-					unknown = true
-				} else {
-					if header := pm.blockchain.GetHeaderByNumber(next); header != nil {
-						if pm.blockchain.GetBlockHashesFromHash(header.Hash(), query.Skip+1)[query.Skip] == query.Origin.Hash {
-							query.Origin.Hash = header.Hash()
-						} else {
-							unknown = true
-						}
-					} else {
-						unknown = true
-					}
-				}
-			case query.Reverse:
-				// Number based traversal towards the genesis block
-				if query.Origin.Number >= query.Skip+1 {
-					query.Origin.Number -= (query.Skip + 1)
-				} else {
-					unknown = true
-				}
-
-			case !query.Reverse:
-				// Number based traversal towards the leaf block
-				query.Origin.Number += (query.Skip + 1)
-			}
+		headers, err := chainaccess.Headers(pm.blockchain, query.headerQuery(), downloader.MaxHeaderFetch)
+		if err == chainaccess.ErrSkipOverflow {
+			infos, _ := json.MarshalIndent(p.Peer.Info(), "", "  ")
+			p.Log().Warn("GetBlockHeaders skip overflow attack", "skip", query.Skip, "attacker", infos)
 		}
 		return p.SendBlockHeaders(headers)
 
@@ -453,44 +444,30 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 	case msg.Code == GetBlockBodiesMsg:
 		// Decode the retrieval message
-		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
-		if _, err := msgStream.List(); err != nil {
-			return err
+		hashes, err := decodeHashes(msg, downloader.MaxBlockFetch)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Gather blocks until the fetch or network limits is reached
-		var (
-			hash   common.Hash
-			bytes  int
-			bodies []rlp.RawValue
-		)
-		for bytes < softResponseLimit && len(bodies) < downloader.MaxBlockFetch {
-			// Retrieve the hash of the next block
-			if err := msgStream.Decode(&hash); err == rlp.EOL {
-				break
-			} else if err != nil {
-				return errResp(ErrDecode, "msg %v: %v", msg, err)
-			}
-			// Retrieve the requested block body, stopping if enough was found
-			if data := pm.blockchain.GetBodyRLP(hash); len(data) != 0 {
-				bodies = append(bodies, data)
-				bytes += len(data)
-			}
-		}
-		return p.SendBlockBodiesRLP(bodies)
+		return p.SendBlockBodiesRLP(chainaccess.BodiesRLP(pm.blockchain, hashes))
 
 	case msg.Code == BlockBodiesMsg:
-		// A batch of block bodies arrived to one of our previous requests
-		var request blockBodiesData
-		if err := msg.Decode(&request); err != nil {
+		// A batch of block bodies arrived to one of our previous requests. Decode
+		// them one by one, rejecting more bodies than we would ever request.
+		it, err := rlp.NewListIterator(msg.Stream(), downloader.MaxBlockFetch)
+		if err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Deliver them all to the downloader for queuing
-		trasactions := make([][]*types.Transaction, len(request))
-		uncles := make([][]*types.Header, len(request))
-
-		for i, body := range request {
-			trasactions[i] = body.Transactions
-			uncles[i] = body.Uncles
+		var (
+			trasactions [][]*types.Transaction
+			uncles      [][]*types.Header
+		)
+		for body := new(blockBody); it.Next(body); body = new(blockBody) {
+			trasactions = append(trasactions, body.Transactions)
+			uncles = append(uncles, body.Uncles)
+		}
+		if err := it.Err(); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Filter out any explicitly requested bodies, deliver the rest to the downloader
 		filter := len(trasactions) > 0 || len(uncles) > 0
@@ -506,7 +483,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 	case p.version >= eth63 && msg.Code == GetNodeDataMsg:
 		// Decode the retrieval message
-		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		msgStream := msg.Stream()
 		if _, err := msgStream.List(); err != nil {
 			return err
 		}
@@ -524,7 +501,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested state entry, stopping if enough was found
-			if entry, err := pm.chaindb.Get(hash.Bytes()); err == nil {
+			if entry, err := pm.blockchain.StateDatabase().Get(hash.Bytes()); err == nil {
 				data = append(data, entry)
 				bytes += len(entry)
 			}
@@ -533,8 +510,19 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 	case p.version >= eth63 && msg.Code == NodeDataMsg:
 		// A batch of node state data arrived to one of our previous requests
-		var data [][]byte
-		if err := msg.Decode(&data); err != nil {
+		it, err := rlp.NewListIterator(msg.Stream(), downloader.MaxStateFetch)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		var (
+			data  [][]byte
+			entry []byte
+		)
+		for it.Next(&entry) {
+			data = append(data, entry)
+			entry = nil
+		}
+		if err := it.Err(); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
@@ -544,44 +532,28 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
-		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
-		if _, err := msgStream.List(); err != nil {
-			return err
-		}
-		// Gather state data until the fetch or network limits is reached
-		var (
-			hash     common.Hash
-			bytes    int
-			receipts []rlp.RawValue
-		)
-		for bytes < softResponseLimit && len(receipts) < downloader.MaxReceiptFetch {
-			// Retrieve the hash of the next block
-			if err := msgStream.Decode(&hash); err == rlp.EOL {
-				break
-			} else if err != nil {
-				return errResp(ErrDecode, "msg %v: %v", msg, err)
-			}
-			// Retrieve the requested block's receipts, skipping if unknown to us
-			results := core.GetBlockReceipts(pm.chaindb, hash, core.GetBlockNumber(pm.chaindb, hash))
-			if results == nil {
-				if header := pm.blockchain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
-					continue
-				}
-			}
-			// If known, encode and queue for response packet
-			if encoded, err := rlp.EncodeToBytes(results); err != nil {
-				log.Error("Failed to encode receipt", "err", err)
-			} else {
-				receipts = append(receipts, encoded)
-				bytes += len(encoded)
-			}
+		hashes, err := decodeHashes(msg, downloader.MaxReceiptFetch)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		return p.SendReceiptsRLP(receipts)
+		// Gather receipts until the fetch or network limits is reached
+		return p.SendReceiptsRLP(chainaccess.ReceiptsRLP(pm.blockchain, hashes))
 
 	case p.version >= eth63 && msg.Code == ReceiptsMsg:
 		// A batch of receipts arrived to one of our previous requests
-		var receipts [][]*types.Receipt
-		if err := msg.Decode(&receipts); err != nil {
+		it, err := rlp.NewListIterator(msg.Stream(), downloader.MaxReceiptFetch)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		var (
+			receipts [][]*types.Receipt
+			block    []*types.Receipt
+		)
+		for it.Next(&block) {
+			receipts = append(receipts, block)
+			block = nil
+		}
+		if err := it.Err(); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
@@ -605,6 +577,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				unknown = append(unknown, block)
 			}
 		}
+		propHashDupInMeter.Mark(int64(len(announces) - len(unknown)))
+		if !pm.syncAllowed(p) {
+			break
+		}
 		for _, block := range unknown {
 			pm.fetcher.Notify(p.id, block.Hash, block.Number, time.Now(), p.RequestOneHeader, p.RequestBodies)
 		}
@@ -618,9 +594,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		request.Block.ReceivedAt = msg.ReceivedAt
 		request.Block.ReceivedFrom = p
 
-		// Mark the peer as owning the block and schedule it for import
+		// Mark the peer as owning the block and schedule it for import (unless
+		// we already have it, in which case the push was a duplicate)
 		p.MarkBlock(request.Block.Hash())
-		pm.fetcher.Enqueue(p.id, request.Block)
+		if pm.blockchain.HasBlock(request.Block.Hash()) {
+			propBlockDupInMeter.Mark(1)
+		} else if pm.syncAllowed(p) {
+			pm.fetcher.Enqueue(p.id, request.Block)
+		}
 
 		// Assuming the block is importable by the peer, but possibly not yet done so,
 		// calculate the head hash and TD that the peer truly must have.
@@ -668,9 +649,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
+//
+// Full blocks are pushed to the square root of the peers not yet knowing about
+// the block, whilst the rest are only notified of its hash, leaving it up to
+// them to fetch it if needed. Each block is propagated and announced at most
+// once, any further requests being suppressed as duplicates.
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
-	peers := pm.peers.PeersWithoutBlock(hash)
 
 	// If propagation is requested, send to a subset of the peer
 	if propagate {
@@ -682,22 +667,43 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
+		if !pm.markBroadcast(pm.propagated, hash) {
+			propBlockDupOutMeter.Mark(1)
+			return
+		}
 		// Send the block to a subset of our peers
+		peers := pm.peers.PeersWithoutBlock(hash)
 		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
 		for _, peer := range transfer {
 			peer.SendNewBlock(block, td)
 		}
+		propBlockPushMeter.Mark(int64(len(transfer)))
 		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
+		return
 	}
 	// Otherwise if the block is indeed in out own chain, announce it
 	if pm.blockchain.HasBlock(hash) {
+		if !pm.markBroadcast(pm.announced, hash) {
+			propHashDupOutMeter.Mark(1)
+			return
+		}
+		peers := pm.peers.PeersWithoutBlock(hash)
 		for _, peer := range peers {
 			peer.SendNewBlockHashes([]common.Hash{hash}, []uint64{block.NumberU64()})
 		}
+		propBlockAnnounceMeter.Mark(int64(len(peers)))
 		log.Trace("Announced block", "hash", hash, "recipients", len(peers), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 	}
 }
 
+// markBroadcast records a block hash in the given broadcast set, returning
+// whether it was newly added (i.e. not yet broadcast). Membership checks don't
+// refresh entries, so the set evicts the oldest hashes once full.
+func (pm *ProtocolManager) markBroadcast(broadcast *lru.Cache, hash common.Hash) bool {
+	known, _ := broadcast.ContainsOrAdd(hash, nil)
+	return !known
+}
+
 // BroadcastTx will propagate a transaction to all peers which are not known to
 // already have the given transaction.
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
@@ -725,8 +731,16 @@ func (self *ProtocolManager) minedBroadcastLoop() {
 func (self *ProtocolManager) txBroadcastLoop() {
 	// automatically stops if unsubscribe
 	for obj := range self.txSub.Chan() {
-		event := obj.Data.(core.TxPreEvent)
-		self.BroadcastTx(event.Tx.Hash(), event.Tx)
+		switch ev := obj.Data.(type) {
+		case core.TxPreEvent:
+			if !ev.Private {
+				self.BroadcastTx(ev.Tx.Hash(), ev.Tx)
+			}
+		case core.TxRebroadcastEvent:
+			for _, tx := range ev.Txs {
+				self.BroadcastTx(tx.Hash(), tx)
+			}
+		}
 	}
 }
 
@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 	"math/rand"
@@ -522,3 +523,100 @@ func testDAOChallenge(t *testing.T, localForked, remoteForked bool, timeout bool
 		}
 	}
 }
+
+// Tests that blocks are pushed in full to the square root of the peers and only
+// announced to the rest, and that repeated broadcasts are suppressed.
+func TestBroadcastBlock1Peer(t *testing.T)   { testBroadcastBlock(t, 1, 1) }
+func TestBroadcastBlock4Peers(t *testing.T)  { testBroadcastBlock(t, 4, 2) }
+func TestBroadcastBlock9Peers(t *testing.T)  { testBroadcastBlock(t, 9, 3) }
+func TestBroadcastBlock12Peers(t *testing.T) { testBroadcastBlock(t, 12, 3) }
+
+func testBroadcastBlock(t *testing.T, totalPeers, broadcastExpected int) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 1, nil, nil)
+	defer pm.Stop()
+
+	// Connect all the peers and start tracking the messages they receive
+	codes := make(chan uint64, 4*totalPeers)
+	for i := 0; i < totalPeers; i++ {
+		peer, _ := newTestPeer(fmt.Sprintf("peer %d", i), eth63, pm, true)
+		defer peer.close()
+
+		go func() {
+			for {
+				msg, err := peer.app.ReadMsg()
+				if err != nil {
+					return
+				}
+				msg.Discard()
+				codes <- msg.Code
+			}
+		}()
+	}
+	// Wait until all the peers are registered with the protocol manager
+	for i := 0; pm.peers.Len() < totalPeers; i++ {
+		if i > 100 {
+			t.Fatalf("peers not registered: have %d, want %d", pm.peers.Len(), totalPeers)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Broadcast the head block twice, duplicates should be dropped
+	block := pm.blockchain.CurrentBlock()
+	for i := 0; i < 2; i++ {
+		pm.BroadcastBlock(block, true)
+		pm.BroadcastBlock(block, false)
+	}
+	var blocks, hashes int
+	timeout := time.After(time.Second)
+	for blocks+hashes < totalPeers {
+		select {
+		case code := <-codes:
+			switch code {
+			case NewBlockMsg:
+				blocks++
+			case NewBlockHashesMsg:
+				hashes++
+			}
+		case <-timeout:
+			t.Fatalf("broadcast timed out: blocks %d, hashes %d, want %d in total", blocks, hashes, totalPeers)
+		}
+	}
+	// Make sure no duplicate notifications trickle in
+	select {
+	case code := <-codes:
+		if code == NewBlockMsg || code == NewBlockHashesMsg {
+			t.Fatalf("duplicate broadcast received: code %d", code)
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	if blocks != broadcastExpected {
+		t.Errorf("full block propagation mismatch: have %d, want %d", blocks, broadcastExpected)
+	}
+	if hashes != totalPeers-broadcastExpected {
+		t.Errorf("block announcement mismatch: have %d, want %d", hashes, totalPeers-broadcastExpected)
+	}
+}
+
+// Tests that the broadcast sets evict the oldest hashes once full, even if those
+// were checked again in the meantime.
+func TestBroadcastSetEvictsOldest(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	for i := 0; i < maxBroadcastBlocks; i++ {
+		if !pm.markBroadcast(pm.announced, common.BigToHash(big.NewInt(int64(i)))) {
+			t.Fatalf("hash %d reported as already broadcast", i)
+		}
+	}
+	// Re-checking the oldest hash must neither re-add nor refresh it
+	oldest := common.BigToHash(big.NewInt(0))
+	if pm.markBroadcast(pm.announced, oldest) {
+		t.Fatalf("duplicate hash reported as new")
+	}
+	pm.markBroadcast(pm.announced, common.BigToHash(big.NewInt(maxBroadcastBlocks)))
+	if pm.announced.Contains(oldest) {
+		t.Errorf("oldest hash not evicted")
+	}
+	if !pm.announced.Contains(common.BigToHash(big.NewInt(1))) {
+		t.Errorf("second oldest hash evicted")
+	}
+}
@@ -26,10 +26,12 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/consensus/ethash"
 	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/forkid"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/core/vm"
 	"github.com/networkchain/networkchain/crypto"
@@ -39,6 +41,7 @@ import (
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
 	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rlp"
 )
 
 var (
@@ -124,6 +127,11 @@ func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return batches, nil
 }
 
+// IsPrivate reports that no transaction of the test pool is private.
+func (p *testTxPool) IsPrivate(hash common.Hash) bool {
+	return false
+}
+
 // newTestTransaction create a new dummy transaction.
 func newTestTransaction(from *ecdsa.PrivateKey, nonce uint64, datasize int) *types.Transaction {
 	tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(0), big.NewInt(100000), big.NewInt(0), make([]byte, datasize))
@@ -163,20 +171,33 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 	// Execute any implicitly requested handshakes and return
 	if shake {
 		td, head, genesis := pm.blockchain.Status()
-		tp.handshake(nil, td, head, genesis)
+		forkID := forkid.NewID(pm.chainconfig, genesis, pm.blockchain.CurrentHeader().Number.Uint64())
+		tp.handshake(nil, td, head, genesis, forkID)
 	}
 	return tp, errc
 }
 
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
-func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash) {
-	msg := &statusData{
-		ProtocolVersion: uint32(p.version),
-		NetworkId:       DefaultConfig.NetworkId,
-		TD:              td,
-		CurrentBlock:    head,
-		GenesisBlock:    genesis,
+func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID) {
+	var msg interface{}
+	if p.version >= eth64 {
+		msg = &statusData64{
+			ProtocolVersion: uint32(p.version),
+			NetworkId:       DefaultConfig.NetworkId,
+			TD:              td,
+			CurrentBlock:    head,
+			GenesisBlock:    genesis,
+			ForkID:          forkID,
+		}
+	} else {
+		msg = &statusData{
+			ProtocolVersion: uint32(p.version),
+			NetworkId:       DefaultConfig.NetworkId,
+			TD:              td,
+			CurrentBlock:    head,
+			GenesisBlock:    genesis,
+		}
 	}
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
@@ -191,3 +212,143 @@ func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesi
 func (p *testPeer) close() {
 	p.app.Close()
 }
+
+// tamperFn inspects a message relayed by a malicious peer, returning the message
+// to forward in its stead, or nil to swallow it. Replies can be injected back to
+// the sender through the reply writer. Messages flowing from the node under test
+// towards the honest remote node have fromNode set.
+type tamperFn func(msg p2p.Msg, fromNode bool, reply p2p.MsgWriter) (*p2p.Msg, error)
+
+// maliciousPeer is a simulated misbehaving peer. It sits between the protocol
+// manager under test and an honest one serving a real chain, relaying messages
+// between them while tampering with them to mount an attack.
+type maliciousPeer struct {
+	id     string         // Identifier of the peer at the protocol manager under test
+	local  *p2p.MsgPipeRW // Relay end of the connection to the node under test
+	remote *p2p.MsgPipeRW // Relay end of the connection to the honest node
+	tamper tamperFn       // Misbehaviour to inject into the relayed messages
+}
+
+// newMaliciousPeer connects the protocol manager under test to the honest source
+// through a relay applying the given tampering to the exchanged messages.
+func newMaliciousPeer(pm *ProtocolManager, source *ProtocolManager, version int, tamper tamperFn) (*maliciousPeer, <-chan error) {
+	local, localNet := p2p.MsgPipe()
+	remote, remoteNet := p2p.MsgPipe()
+
+	var id discover.NodeID
+	rand.Read(id[:])
+
+	peer := pm.newPeer(version, p2p.NewPeer(id, "malicious", nil), localNet)
+	mp := &maliciousPeer{id: peer.id, local: local, remote: remote, tamper: tamper}
+
+	errc := make(chan error, 1)
+	go func() { errc <- pm.handle(peer) }()
+	go source.handle(source.newPeer(version, p2p.NewPeer(discover.NodeID{}, "victim", nil), remoteNet))
+
+	go mp.relay(local, remote, true)
+	go mp.relay(remote, local, false)
+
+	return mp, errc
+}
+
+// relay forwards messages from one side of the connection to the other, passing
+// them through the tampering function.
+func (mp *maliciousPeer) relay(from, to *p2p.MsgPipeRW, fromNode bool) {
+	defer to.Close()
+
+	for {
+		msg, err := from.ReadMsg()
+		if err != nil {
+			return
+		}
+		fwd := &msg
+		if mp.tamper != nil {
+			if fwd, err = mp.tamper(msg, fromNode, from); err != nil {
+				return
+			}
+		}
+		if fwd == nil {
+			msg.Discard()
+			continue
+		}
+		if err := to.WriteMsg(*fwd); err != nil {
+			return
+		}
+	}
+}
+
+// waitDropped waits until the protocol manager under test drops the malicious
+// peer, returning false if it is still connected once no synchronisation which
+// could drop it is running anymore.
+func (mp *maliciousPeer) waitDropped(pm *ProtocolManager) bool {
+	for pm.peers.Peer(mp.id) != nil {
+		if !pm.downloader.Synchronising() {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// close terminates the connections of the malicious peer.
+func (mp *maliciousPeer) close() {
+	mp.local.Close()
+	mp.remote.Close()
+}
+
+// tamperMsg replaces the payload of a message with the encoding of the given value.
+func tamperMsg(code uint64, val interface{}) (*p2p.Msg, error) {
+	size, r, err := rlp.EncodeToReader(val)
+	if err != nil {
+		return nil, err
+	}
+	return &p2p.Msg{Code: code, Size: uint32(size), Payload: r}, nil
+}
+
+// staleHeaders returns a misbehaviour serving headers from depth blocks before
+// the ones requested by number.
+func staleHeaders(depth uint64) tamperFn {
+	return func(msg p2p.Msg, fromNode bool, reply p2p.MsgWriter) (*p2p.Msg, error) {
+		if !fromNode || msg.Code != GetBlockHeadersMsg {
+			return &msg, nil
+		}
+		var query getBlockHeadersData
+		if err := msg.Decode(&query); err != nil {
+			return nil, err
+		}
+		if query.Origin.Hash == (common.Hash{}) {
+			if query.Origin.Number > depth {
+				query.Origin.Number -= depth
+			} else {
+				query.Origin.Number = 0
+			}
+		}
+		return tamperMsg(GetBlockHeadersMsg, &query)
+	}
+}
+
+// withholdBodies is a misbehaviour answering all block body requests with empty
+// replies, while serving all other data honestly.
+func withholdBodies(msg p2p.Msg, fromNode bool, reply p2p.MsgWriter) (*p2p.Msg, error) {
+	if !fromNode || msg.Code != GetBlockBodiesMsg {
+		return &msg, nil
+	}
+	msg.Discard()
+	return nil, p2p.Send(reply, BlockBodiesMsg, []rlp.RawValue{})
+}
+
+// fakeTD returns a misbehaviour announcing the given multiple of the real total
+// difficulty of the honest chain.
+func fakeTD(factor int64) tamperFn {
+	return func(msg p2p.Msg, fromNode bool, reply p2p.MsgWriter) (*p2p.Msg, error) {
+		if fromNode || msg.Code != StatusMsg {
+			return &msg, nil
+		}
+		var status statusData
+		if err := msg.Decode(&status); err != nil {
+			return nil, err
+		}
+		status.TD = new(big.Int).Mul(status.TD, big.NewInt(factor))
+		return tamperMsg(StatusMsg, &status)
+	}
+}
@@ -34,6 +34,12 @@ var (
 	propBlockInTrafficMeter   = metrics.NewMeter("eth/prop/blocks/in/traffic")
 	propBlockOutPacketsMeter  = metrics.NewMeter("eth/prop/blocks/out/packets")
 	propBlockOutTrafficMeter  = metrics.NewMeter("eth/prop/blocks/out/traffic")
+	propBlockPushMeter        = metrics.NewMeter("eth/prop/blocks/out/push")
+	propBlockAnnounceMeter    = metrics.NewMeter("eth/prop/blocks/out/announce")
+	propBlockDupInMeter       = metrics.NewMeter("eth/prop/blocks/in/dups")
+	propBlockDupOutMeter      = metrics.NewMeter("eth/prop/blocks/out/dups")
+	propHashDupInMeter        = metrics.NewMeter("eth/prop/hashes/in/dups")
+	propHashDupOutMeter       = metrics.NewMeter("eth/prop/hashes/out/dups")
 	reqHeaderInPacketsMeter   = metrics.NewMeter("eth/req/headers/in/packets")
 	reqHeaderInTrafficMeter   = metrics.NewMeter("eth/req/headers/in/traffic")
 	reqHeaderOutPacketsMeter  = metrics.NewMeter("eth/req/headers/out/packets")
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/forkid"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/rlp"
@@ -229,13 +230,28 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks. From eth/64 onwards the
+// fork identifiers are exchanged as well and the remote one is validated with
+// the local fork filter.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
-
+	var (
+		status   statusData   // safe to read after two values have been received from errc
+		status64 statusData64 // safe to read after two values have been received from errc
+	)
 	go func() {
+		if p.version >= eth64 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData64{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				ForkID:          forkID,
+			})
+			return
+		}
 		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
@@ -245,6 +261,10 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		})
 	}()
 	go func() {
+		if p.version >= eth64 {
+			errc <- p.readStatus64(network, &status64, genesis, forkFilter)
+			return
+		}
 		errc <- p.readStatus(network, &status, genesis)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
@@ -259,24 +279,33 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	if p.version >= eth64 {
+		p.td, p.head = status64.TD, status64.CurrentBlock
+	} else {
+		p.td, p.head = status.TD, status.CurrentBlock
+	}
 	return nil
 }
 
 func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
-	msg, err := p.rw.ReadMsg()
-	if err != nil {
+	if err := p.readStatusMsg(status); err != nil {
 		return err
 	}
-	if msg.Code != StatusMsg {
-		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, StatusMsg)
+	if status.GenesisBlock != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
 	}
-	if msg.Size > ProtocolMaxMsgSize {
-		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	if status.NetworkId != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
 	}
-	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
-		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	if int(status.ProtocolVersion) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	}
+	return nil
+}
+
+func (p *peer) readStatus64(network uint64, status *statusData64, genesis common.Hash, forkFilter forkid.Filter) (err error) {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
 	}
 	if status.GenesisBlock != genesis {
 		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
@@ -287,6 +316,31 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if int(status.ProtocolVersion) != p.version {
 		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
 	}
+	// Reject peers on an incompatible fork schedule before wasting a slot on them
+	if err := forkFilter(status.ForkID); err != nil {
+		p.Log().Info("Rejected peer on incompatible fork schedule", "forkhash", fmt.Sprintf("%x", status.ForkID.Hash), "forknext", status.ForkID.Next, "err", err)
+		return errResp(ErrForkIDRejected, "%x/%d: %v", status.ForkID.Hash, status.ForkID.Next, err)
+	}
+	return nil
+}
+
+// readStatusMsg reads the first message from the remote peer and decodes it as
+// a status message into the given packet.
+func (p *peer) readStatusMsg(status interface{}) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != StatusMsg {
+		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, StatusMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	// Decode the handshake and make sure everything matches
+	if err := msg.Decode(status); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
 	return nil
 }
 
@@ -389,6 +443,12 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
+	return ps.BestPeerOf(func(*peer) bool { return true })
+}
+
+// BestPeerOf retrieves the peer with the currently highest total difficulty among
+// the known peers accepted by the given filter.
+func (ps *peerSet) BestPeerOf(accept func(*peer) bool) *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
@@ -397,6 +457,9 @@ func (ps *peerSet) BestPeer() *peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
+		if !accept(p) {
+			continue
+		}
 		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p, td
 		}
@@ -22,7 +22,9 @@ import (
 	"math/big"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/forkid"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/eth/chainaccess"
 	"github.com/networkchain/networkchain/rlp"
 )
 
@@ -30,16 +32,17 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{17, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -74,6 +77,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrForkIDRejected
 )
 
 func (e errCode) String() string {
@@ -91,6 +95,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrForkIDRejected:          "Fork ID rejected",
 }
 
 type txPool interface {
@@ -100,9 +105,13 @@ type txPool interface {
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
+
+	// IsPrivate should report whether a transaction must not be announced to
+	// the network.
+	IsPrivate(hash common.Hash) bool
 }
 
-// statusData is the network packet for the status message.
+// statusData is the network packet for the status message of eth/62 and eth/63.
 type statusData struct {
 	ProtocolVersion uint32
 	NetworkId       uint64
@@ -111,6 +120,17 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData64 is the network packet for the status message of eth/64, which
+// extends the handshake with the fork identifier of the chain.
+type statusData64 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	ForkID          forkid.ID
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -125,6 +145,17 @@ type getBlockHeadersData struct {
 	Reverse bool         // Query direction (false = rising towards latest, true = falling towards genesis)
 }
 
+// headerQuery converts the query into the form served from the chain.
+func (q *getBlockHeadersData) headerQuery() chainaccess.HeaderQuery {
+	return chainaccess.HeaderQuery{
+		Hash:    q.Origin.Hash,
+		Number:  q.Origin.Number,
+		Amount:  q.Amount,
+		Skip:    q.Skip,
+		Reverse: q.Reverse,
+	}
+}
+
 // hashOrNumber is a combined field for specifying an origin block.
 type hashOrNumber struct {
 	Hash   common.Hash // Block hash from which to retrieve headers (excludes Number)
@@ -0,0 +1,156 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package eth
+
+import (
+	"bytes"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/eth/downloader"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test the decoding of eth protocol
+// messages the same way the protocol handler does. The first byte of the input
+// is the message code, the rest is the message payload.
+func Fuzz(data []byte) int {
+	if len(data) == 0 || len(data) > ProtocolMaxMsgSize {
+		return -1
+	}
+	msg := p2p.Msg{
+		Code:    uint64(data[0]),
+		Size:    uint32(len(data) - 1),
+		Payload: bytes.NewReader(data[1:]),
+	}
+	if err := fuzzMsg(msg); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// fuzzMsg decodes a single protocol message, checking the invariants the handler
+// relies upon.
+func fuzzMsg(msg p2p.Msg) error {
+	switch msg.Code {
+	case StatusMsg:
+		var status statusData64
+		if err := msg.Decode(&status); err != nil {
+			return err
+		}
+		if status.TD == nil {
+			panic("nil total difficulty")
+		}
+
+	case NewBlockHashesMsg:
+		var announces newBlockHashesData
+		return msg.Decode(&announces)
+
+	case TxMsg:
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return err
+		}
+		for _, tx := range txs {
+			if tx == nil {
+				panic("nil transaction")
+			}
+			tx.Hash()
+		}
+
+	case GetBlockHeadersMsg:
+		var query getBlockHeadersData
+		if err := msg.Decode(&query); err != nil {
+			return err
+		}
+		// A query decoded by number must survive reencoding unchanged
+		if query.Origin.Hash == (common.Hash{}) {
+			enc, err := rlp.EncodeToBytes(&query.Origin)
+			if err != nil {
+				panic(err)
+			}
+			var origin hashOrNumber
+			if err := rlp.DecodeBytes(enc, &origin); err != nil || origin != query.Origin {
+				panic("origin reencoding mismatch")
+			}
+		}
+
+	case BlockHeadersMsg:
+		var headers []*types.Header
+		if err := msg.Decode(&headers); err != nil {
+			return err
+		}
+		for _, header := range headers {
+			header.Hash()
+		}
+
+	case GetBlockBodiesMsg, GetNodeDataMsg, GetReceiptsMsg:
+		stream := msg.Stream()
+		if _, err := stream.List(); err != nil {
+			return err
+		}
+		var hash common.Hash
+		for {
+			if err := stream.Decode(&hash); err == rlp.EOL {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+
+	case BlockBodiesMsg:
+		it, err := rlp.NewListIterator(msg.Stream(), downloader.MaxBlockFetch)
+		if err != nil {
+			return err
+		}
+		for body := new(blockBody); it.Next(body); body = new(blockBody) {
+			types.DeriveSha(types.Transactions(body.Transactions))
+			types.CalcUncleHash(body.Uncles)
+		}
+		return it.Err()
+
+	case NodeDataMsg:
+		var data [][]byte
+		return msg.Decode(&data)
+
+	case ReceiptsMsg:
+		it, err := rlp.NewListIterator(msg.Stream(), downloader.MaxReceiptFetch)
+		if err != nil {
+			return err
+		}
+		var block []*types.Receipt
+		for it.Next(&block) {
+			types.DeriveSha(types.Receipts(block))
+			block = nil
+		}
+		return it.Err()
+
+	case NewBlockMsg:
+		var request newBlockData
+		if err := msg.Decode(&request); err != nil {
+			return err
+		}
+		if request.Block == nil || request.TD == nil {
+			panic("nil block or total difficulty")
+		}
+		request.Block.Hash()
+	}
+	return nil
+}
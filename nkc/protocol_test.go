@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core/forkid"
 	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/crypto"
 	"github.com/networkchain/networkchain/eth/downloader"
@@ -88,6 +89,83 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that eth/64 handshake failures, including fork ID mismatches, are
+// detected and reported correctly.
+func TestStatusMsgErrors64(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	var (
+		td, currentBlock, genesis = pm.blockchain.Status()
+		forkID                    = forkid.NewID(pm.chainconfig, genesis, pm.blockchain.CurrentHeader().Number.Uint64())
+	)
+	tests := []struct {
+		code      uint64
+		data      interface{}
+		wantError error
+	}{
+		{
+			code: TxMsg, data: []interface{}{},
+			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
+		},
+		{
+			code: StatusMsg, data: statusData{eth64, DefaultConfig.NetworkId, td, currentBlock, genesis},
+			wantError: errResp(ErrDecode, "msg msg #0 (71 bytes): invalid message: (code 0) (size 71) rlp: too few elements for eth.statusData64"),
+		},
+		{
+			code: StatusMsg, data: statusData64{10, DefaultConfig.NetworkId, td, currentBlock, genesis, forkID},
+			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", eth64),
+		},
+		{
+			code: StatusMsg, data: statusData64{eth64, 999, td, currentBlock, genesis, forkID},
+			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 1)"),
+		},
+		{
+			code: StatusMsg, data: statusData64{eth64, DefaultConfig.NetworkId, td, currentBlock, common.Hash{3}, forkID},
+			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis[:8]),
+		},
+		{
+			code: StatusMsg, data: statusData64{eth64, DefaultConfig.NetworkId, td, currentBlock, genesis, forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}},
+			wantError: errResp(ErrForkIDRejected, "deadbeef/0: %v", forkid.ErrLocalIncompatibleOrStale),
+		},
+	}
+	for i, test := range tests {
+		p, errc := newTestPeer("peer", eth64, pm, false)
+		// The send call might hang until reset because
+		// the protocol might not read the payload.
+		go p2p.Send(p.app, test.code, test.data)
+
+		select {
+		case err := <-errc:
+			if err == nil {
+				t.Errorf("test %d: protocol returned nil error, want %q", i, test.wantError)
+			} else if err.Error() != test.wantError.Error() {
+				t.Errorf("test %d: wrong error: got %q, want %q", i, err, test.wantError)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("protocol did not shut down within 2 seconds")
+		}
+		p.close()
+	}
+}
+
+// Tests that eth/64 peers on the same chain complete the handshake.
+func TestHandshake64(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer p.close()
+
+	// Wait for the peer to get registered, or fail if the handshake was rejected
+	for i := 0; i < 100 && pm.peers.Peer(p.id) == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pm.peers.Peer(p.id) == nil {
+		t.Fatalf("eth/64 peer not registered after handshake")
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
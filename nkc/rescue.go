@@ -0,0 +1,195 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/log"
+)
+
+// RescueConfig are the configuration parameters of the stuck transaction rescuer.
+type RescueConfig struct {
+	Enabled   bool          // Whether stuck local transactions should be rescued
+	Window    time.Duration // Time a local transaction may stay pending before being rescued
+	PriceBump uint64        // Gas price bump percentage for stuck transactions (0 = rebroadcast only, otherwise at least the pool's)
+	PriceCap  *big.Int      `toml:",omitempty"` // Maximum gas price a stuck transaction may be bumped to
+}
+
+// DefaultRescueConfig contains the default configurations for the stuck
+// transaction rescuer.
+var DefaultRescueConfig = RescueConfig{
+	Window: 5 * time.Minute,
+}
+
+// txRescuer monitors the pending local transactions of the pool and re-announces
+// the ones not mined within the configured window to all the peers not yet known
+// to have them, optionally bumping their gas price (up to a cap) if the account
+// owning them can sign replacements.
+type txRescuer struct {
+	config RescueConfig
+	pool   *core.TxPool
+
+	broadcast func(hash common.Hash, tx *types.Transaction)                                // Announces a transaction to the network
+	sign      func(from common.Address, tx *types.Transaction) (*types.Transaction, error) // Signs a replacement transaction
+
+	seen map[common.Hash]time.Time // Time each tracked transaction was first seen pending
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newTxRescuer creates a stuck transaction rescuer on top of a transaction pool.
+// The re-pricing bump must satisfy the pool's replacement rules, otherwise all
+// the replacements would be rejected.
+func newTxRescuer(config RescueConfig, pool *core.TxPool, broadcast func(common.Hash, *types.Transaction), sign func(common.Address, *types.Transaction) (*types.Transaction, error)) (*txRescuer, error) {
+	if config.Window < time.Second {
+		log.Warn("Sanitizing invalid rescue window", "provided", config.Window, "updated", DefaultRescueConfig.Window)
+		config.Window = DefaultRescueConfig.Window
+	}
+	if bump := pool.PriceBump(); config.PriceBump != 0 && config.PriceBump < bump {
+		return nil, fmt.Errorf("rescue price bump %d%% below the transaction pool's replacement bump %d%%", config.PriceBump, bump)
+	}
+	return &txRescuer{
+		config:    config,
+		pool:      pool,
+		broadcast: broadcast,
+		sign:      sign,
+		seen:      make(map[common.Hash]time.Time),
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+// Start launches the background rescue loop.
+func (r *txRescuer) Start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Stop terminates the background rescue loop.
+func (r *txRescuer) Stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// loop periodically checks the pending local transactions, rescuing any that
+// seem to be stuck.
+func (r *txRescuer) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.Window / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			r.rescue(now)
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// rescue runs a single pass over the pending local transactions, re-announcing
+// (and potentially re-pricing) those pending for longer than the window.
+func (r *txRescuer) rescue(now time.Time) {
+	pending, err := r.pool.Pending()
+	if err != nil {
+		log.Warn("Failed to retrieve pending transactions", "err", err)
+		return
+	}
+	seen := make(map[common.Hash]time.Time)
+	for _, addr := range r.pool.Locals() {
+		for _, tx := range pending[addr] {
+			hash := tx.Hash()
+
+			first, ok := r.seen[hash]
+			if !ok {
+				first = now
+			}
+			if now.Sub(first) < r.config.Window {
+				seen[hash] = first
+				continue
+			}
+			// Transaction stuck for too long, try to re-price it if enabled
+			private := r.pool.IsPrivate(hash)
+			if replacement := r.reprice(addr, tx); replacement != nil {
+				add := r.pool.AddLocal
+				if private {
+					add = r.pool.AddPrivate
+				}
+				err := add(replacement)
+				if err == nil {
+					log.Info("Re-priced stuck transaction", "from", addr, "nonce", tx.Nonce(), "old", hash, "new", replacement.Hash(), "price", replacement.GasPrice())
+					seen[replacement.Hash()] = now
+					continue
+				}
+				log.Warn("Failed to replace stuck transaction", "hash", hash, "err", err)
+			}
+			// Private transactions wait for the local miner, never announce them
+			if private {
+				seen[hash] = now
+				continue
+			}
+			log.Debug("Rebroadcasting stuck transaction", "hash", hash, "from", addr, "nonce", tx.Nonce())
+			r.broadcast(hash, tx)
+			seen[hash] = now
+		}
+	}
+	r.seen = seen
+}
+
+// reprice creates a replacement for a stuck transaction with a bumped gas price,
+// or nil if re-pricing is disabled, the cap leaves no room for a bump the pool
+// accepts or the transaction can't be signed.
+func (r *txRescuer) reprice(from common.Address, tx *types.Transaction) *types.Transaction {
+	if r.config.PriceBump == 0 || r.sign == nil {
+		return nil
+	}
+	price := new(big.Int).Mul(tx.GasPrice(), big.NewInt(100+int64(r.config.PriceBump)))
+	price.Div(price, big.NewInt(100))
+	if price.Cmp(tx.GasPrice()) <= 0 {
+		price.Add(tx.GasPrice(), common.Big1)
+	}
+	if r.config.PriceCap != nil && price.Cmp(r.config.PriceCap) > 0 {
+		// Capping must still leave the replacement above the pool's threshold
+		threshold := new(big.Int).Mul(tx.GasPrice(), big.NewInt(100+int64(r.pool.PriceBump())))
+		threshold.Div(threshold, big.NewInt(100))
+		if threshold.Cmp(r.config.PriceCap) > 0 || tx.GasPrice().Cmp(r.config.PriceCap) >= 0 {
+			return nil
+		}
+		price.Set(r.config.PriceCap)
+	}
+	var replacement *types.Transaction
+	if to := tx.To(); to == nil {
+		replacement = types.NewContractCreation(tx.Nonce(), tx.Value(), tx.Gas(), price, tx.Data())
+	} else {
+		replacement = types.NewTransaction(tx.Nonce(), *to, tx.Value(), tx.Gas(), price, tx.Data())
+	}
+	signed, err := r.sign(from, replacement)
+	if err != nil {
+		log.Debug("Failed to sign stuck transaction replacement", "hash", tx.Hash(), "err", err)
+		return nil
+	}
+	return signed
+}
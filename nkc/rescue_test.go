@@ -0,0 +1,137 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/state"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// newTestRescuer creates a transaction pool with a funded local account and a
+// stuck transaction rescuer on top, tracking all the rebroadcast transactions.
+func newTestRescuer(config RescueConfig) (*txRescuer, *core.TxPool, *[]common.Hash) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.AddBalance(testBank, big.NewInt(1000000000000))
+
+	pool := core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+
+	broadcasts := new([]common.Hash)
+	broadcast := func(hash common.Hash, tx *types.Transaction) {
+		*broadcasts = append(*broadcasts, hash)
+	}
+	sign := func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		return types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+	}
+	rescuer, err := newTxRescuer(config, pool, broadcast, sign)
+	if err != nil {
+		panic(err)
+	}
+	return rescuer, pool, broadcasts
+}
+
+// Tests that stuck local transactions are only rebroadcast after the rescue
+// window passes, and that remote ones are left alone.
+func TestRescueRebroadcast(t *testing.T) {
+	rescuer, pool, broadcasts := newTestRescuer(RescueConfig{Enabled: true, Window: time.Minute})
+	defer pool.Stop()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	start := time.Now()
+	rescuer.rescue(start)
+	rescuer.rescue(start.Add(time.Minute / 2))
+	if len(*broadcasts) != 0 {
+		t.Fatalf("premature rebroadcast: have %d, want %d", len(*broadcasts), 0)
+	}
+	rescuer.rescue(start.Add(time.Minute))
+	if len(*broadcasts) != 1 || (*broadcasts)[0] != tx.Hash() {
+		t.Fatalf("stuck transaction not rebroadcast: have %x, want %x", *broadcasts, tx.Hash())
+	}
+	// Ensure the rebroadcast restarts the rescue window
+	rescuer.rescue(start.Add(time.Minute + time.Second))
+	if len(*broadcasts) != 1 {
+		t.Fatalf("rebroadcast window not reset: have %d broadcasts, want %d", len(*broadcasts), 1)
+	}
+}
+
+// Tests that stuck local transactions get re-priced if enabled, but never
+// above the configured price cap.
+func TestRescueReprice(t *testing.T) {
+	rescuer, pool, broadcasts := newTestRescuer(RescueConfig{Enabled: true, Window: time.Minute, PriceBump: 50, PriceCap: big.NewInt(120)})
+	defer pool.Stop()
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), big.NewInt(100000), big.NewInt(100), nil)
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	start := time.Now()
+	rescuer.rescue(start)
+	rescuer.rescue(start.Add(time.Minute))
+
+	// The transaction should have been replaced with a capped price
+	pending, _ := pool.Pending()
+	if txs := pending[testBank]; len(txs) != 1 || txs[0].GasPrice().Cmp(big.NewInt(120)) != 0 {
+		t.Fatalf("stuck transaction not re-priced to cap: %v", txs)
+	}
+	if len(*broadcasts) != 0 {
+		t.Fatalf("re-priced transaction explicitly rebroadcast: have %d, want %d", len(*broadcasts), 0)
+	}
+	// Once at the cap, the transaction should only be rebroadcast
+	rescuer.rescue(start.Add(2 * time.Minute))
+	if len(*broadcasts) != 1 {
+		t.Fatalf("capped transaction not rebroadcast: have %d, want %d", len(*broadcasts), 1)
+	}
+}
+
+// Tests that a re-pricing bump the transaction pool would reject is refused up
+// front instead of producing unacceptable replacements.
+func TestRescuePriceBumpValidation(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	pool := core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	defer pool.Stop()
+
+	tests := []struct {
+		bump uint64
+		fail bool
+	}{
+		{0, false},
+		{core.DefaultTxPoolConfig.PriceBump - 1, true},
+		{core.DefaultTxPoolConfig.PriceBump, false},
+		{core.DefaultTxPoolConfig.PriceBump + 1, false},
+	}
+	for i, tt := range tests {
+		_, err := newTxRescuer(RescueConfig{Enabled: true, Window: time.Minute, PriceBump: tt.bump}, pool, nil, nil)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: bump %d%%: failure mismatch: have %v, want %v", i, tt.bump, err, tt.fail)
+		}
+	}
+}
@@ -47,7 +47,11 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	var txs types.Transactions
 	pending, _ := pm.txpool.Pending()
 	for _, batch := range pending {
-		txs = append(txs, batch...)
+		for _, tx := range batch {
+			if !pm.txpool.IsPrivate(tx.Hash()) {
+				txs = append(txs, tx)
+			}
+		}
 	}
 	if len(txs) == 0 {
 		return
@@ -146,11 +150,11 @@ func (pm *ProtocolManager) syncer() {
 			if pm.peers.Len() < minDesiredPeerCount {
 				break
 			}
-			go pm.synchronise(pm.peers.BestPeer())
+			go pm.synchronise(pm.peers.BestPeerOf(pm.syncAllowed))
 
 		case <-forceSync:
 			// Force a sync even if not enough peers are present
-			go pm.synchronise(pm.peers.BestPeer())
+			go pm.synchronise(pm.peers.BestPeerOf(pm.syncAllowed))
 
 		case <-pm.noMorePeers:
 			return
@@ -158,10 +162,33 @@ func (pm *ProtocolManager) syncer() {
 	}
 }
 
+// setSyncPeers restricts block synchronisation to the given set of nodes, which
+// is meant for static peering in environments without discovery. It must be
+// called before the protocol manager starts accepting peers.
+func (pm *ProtocolManager) setSyncPeers(nodes []*discover.Node) {
+	if len(nodes) == 0 {
+		log.Warn("Static sync enabled without any static nodes")
+	}
+	pm.syncPeers = make(map[discover.NodeID]struct{}, len(nodes))
+	for _, node := range nodes {
+		pm.syncPeers[node.ID] = struct{}{}
+	}
+	log.Info("Restricted block synchronisation to static nodes", "nodes", len(nodes))
+}
+
+// syncAllowed reports whether blocks may be synchronised from the given peer.
+func (pm *ProtocolManager) syncAllowed(p *peer) bool {
+	if pm.syncPeers == nil {
+		return true
+	}
+	_, ok := pm.syncPeers[p.ID()]
+	return ok
+}
+
 // synchronise tries to sync up our local block chain with a remote peer.
 func (pm *ProtocolManager) synchronise(peer *peer) {
-	// Short circuit if no peers are available
-	if peer == nil {
+	// Short circuit if no peers are available or syncing is suspended
+	if peer == nil || !pm.syncAllowed(peer) || atomic.LoadUint32(&pm.noSync) == 1 {
 		return
 	}
 	// Make sure the peer's TD is higher than our own
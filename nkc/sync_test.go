@@ -17,10 +17,14 @@
 package eth
 
 import (
+	"math/big"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
 	"github.com/networkchain/networkchain/eth/downloader"
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
@@ -53,3 +57,121 @@ func TestFastSyncDisabling(t *testing.T) {
 		t.Fatalf("fast sync not disabled after successful synchronisation")
 	}
 }
+
+// Tests that in static sync mode blocks are only synchronised from the configured
+// static nodes, ignoring any other connected peers.
+func TestStaticSync(t *testing.T) {
+	var (
+		staticID = discover.NodeID{0x01}
+		otherID  = discover.NodeID{0x02}
+	)
+	pmEmpty := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pmEmpty.setSyncPeers([]*discover.Node{{ID: staticID}})
+
+	// Connect a full peer that is not a static node and ensure it's not synced from
+	pmOther := newTestProtocolManagerMust(t, downloader.FullSync, 1024, nil, nil)
+	io1, io2 := p2p.MsgPipe()
+
+	go pmOther.handle(pmOther.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io2))
+	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(otherID, "other", nil), io1))
+
+	time.Sleep(250 * time.Millisecond)
+	if peer := pmEmpty.peers.BestPeerOf(pmEmpty.syncAllowed); peer != nil {
+		t.Fatalf("non-static peer selected for sync: %v", peer.id)
+	}
+	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
+	if head := pmEmpty.blockchain.CurrentBlock().NumberU64(); head != 0 {
+		t.Fatalf("synchronised from non-static peer: head %d", head)
+	}
+	// Connect a full static peer and ensure it's synced from
+	pmStatic := newTestProtocolManagerMust(t, downloader.FullSync, 1024, nil, nil)
+	io3, io4 := p2p.MsgPipe()
+
+	go pmStatic.handle(pmStatic.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io4))
+	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(staticID, "static", nil), io3))
+
+	time.Sleep(250 * time.Millisecond)
+	pmEmpty.synchronise(pmEmpty.peers.BestPeerOf(pmEmpty.syncAllowed))
+	if head := pmEmpty.blockchain.CurrentBlock().NumberU64(); head != 1024 {
+		t.Fatalf("static peer sync mismatch: head %d, want %d", head, 1024)
+	}
+}
+
+// Tests that no blocks are synchronised while syncing is paused, and that it
+// resumes afterwards.
+func TestPauseSync(t *testing.T) {
+	pmEmpty := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pmFull := newTestProtocolManagerMust(t, downloader.FullSync, 1024, nil, nil)
+	io1, io2 := p2p.MsgPipe()
+
+	go pmFull.handle(pmFull.newPeer(63, p2p.NewPeer(discover.NodeID{}, "empty", nil), io2))
+	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(discover.NodeID{}, "full", nil), io1))
+
+	time.Sleep(250 * time.Millisecond)
+	pmEmpty.PauseSync(true)
+	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
+	if head := pmEmpty.blockchain.CurrentBlock().NumberU64(); head != 0 {
+		t.Fatalf("synchronised while paused: head %d", head)
+	}
+	pmEmpty.PauseSync(false)
+	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
+	if head := pmEmpty.blockchain.CurrentBlock().NumberU64(); head != 1024 {
+		t.Fatalf("resumed sync mismatch: head %d, want %d", head, 1024)
+	}
+}
+
+// testMaliciousPeerDrop connects a protocol manager with a chain of the given
+// length to a malicious peer relaying a source chain, and checks that the peer is
+// dropped by the synchronisation attempted with it. The optional generator is
+// used to populate the blocks of the source chain.
+func testMaliciousPeerDrop(t *testing.T, local, remote int, generator func(int, *core.BlockGen), tamper tamperFn) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, local, nil, nil)
+	source := newTestProtocolManagerMust(t, downloader.FullSync, remote, generator, nil)
+	defer pm.Stop()
+	defer source.Stop()
+
+	mp, errc := newMaliciousPeer(pm, source, 63, tamper)
+	defer mp.close()
+
+	// Wait for the handshake to complete and sync with the peer
+	var peer *peer
+	for peer == nil {
+		select {
+		case err := <-errc:
+			t.Fatalf("malicious peer rejected during handshake: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		peer = pm.peers.Peer(mp.id)
+	}
+	pm.synchronise(peer)
+
+	if !mp.waitDropped(pm) {
+		t.Fatalf("malicious peer not dropped by synchronisation")
+	}
+	if head := pm.blockchain.CurrentBlock().NumberU64(); head > uint64(remote) {
+		t.Errorf("chain head beyond the honest chain: have %d, limit %d", head, remote)
+	}
+}
+
+// Tests that peers announcing a total difficulty higher than what their chain
+// can deliver are dropped.
+func TestMaliciousPeerFakeTD(t *testing.T) {
+	testMaliciousPeerDrop(t, 1024, 1024, nil, fakeTD(2))
+}
+
+// Tests that peers serving headers from stale sections of the chain instead of
+// the requested ones are dropped.
+func TestMaliciousPeerStaleHeaders(t *testing.T) {
+	testMaliciousPeerDrop(t, 256, 1024, nil, staleHeaders(32))
+}
+
+// Tests that peers serving headers but withholding the block bodies are dropped.
+func TestMaliciousPeerWithheldBodies(t *testing.T) {
+	// Fill every block with a transaction, otherwise no bodies are requested
+	signer := types.HomesteadSigner{}
+	generator := func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{0x01}, big.NewInt(1), bigTxGas, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	}
+	testMaliciousPeerDrop(t, 0, 1024, generator, withholdBodies)
+}
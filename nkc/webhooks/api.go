@@ -0,0 +1,50 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package webhooks
+
+import "github.com/networkchain/networkchain/common"
+
+// PrivateWebhooksAPI provides an API to manage the registered web-hooks.
+type PrivateWebhooksAPI struct {
+	service *Service
+}
+
+// NewPrivateWebhooksAPI creates a new API to manage the web-hooks of a service.
+func NewPrivateWebhooksAPI(service *Service) *PrivateWebhooksAPI {
+	return &PrivateWebhooksAPI{service}
+}
+
+// Register adds a callback URL to be notified of the mined transactions and
+// emitted logs of the given addresses, returning the identifier of the hook. If
+// a secret is given, notifications carry its HMAC-SHA256 signature of the body.
+func (api *PrivateWebhooksAPI) Register(url string, addresses []common.Address, secret *string) (string, error) {
+	var key string
+	if secret != nil {
+		key = *secret
+	}
+	return api.service.Register(url, addresses, key)
+}
+
+// Unregister removes a hook, returning whether it existed.
+func (api *PrivateWebhooksAPI) Unregister(id string) (bool, error) {
+	return api.service.Unregister(id)
+}
+
+// List returns the registered hooks, omitting their secrets.
+func (api *PrivateWebhooksAPI) List() []Hook {
+	return api.service.Hooks()
+}
@@ -0,0 +1,500 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package webhooks implements a notification service that POSTs the activity of
+// registered addresses (mined transactions and emitted logs) to HTTP callbacks,
+// turning the node into a self-hosted wallet notification backend.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+const (
+	maxHookAddresses = 1024 // Maximum number of addresses a single hook may watch
+	hookQueueSize    = 256  // Maximum number of notifications queued for delivery per hook
+
+	// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of the
+	// notification body, keyed with the secret of the hook.
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+var (
+	errNoAddresses    = errors.New("no addresses to watch")
+	errTooManyAddress = fmt.Errorf("too many addresses, at most %d allowed", maxHookAddresses)
+)
+
+// Config contains the settings of the web-hooks service.
+type Config struct {
+	Enabled    bool          // Whether to run the web-hooks service
+	Store      string        // File persisting the registered hooks, relative to the data directory ("" = memory only)
+	Retries    int           // Number of redelivery attempts of a failed notification
+	RetryDelay time.Duration // Delay before the first redelivery, doubled for every subsequent one
+	Timeout    time.Duration // Timeout of a single delivery attempt
+}
+
+// DefaultConfig contains the default settings of the web-hooks service (disabled).
+var DefaultConfig = Config{
+	Store:      "webhooks.json",
+	Retries:    5,
+	RetryDelay: time.Second,
+	Timeout:    10 * time.Second,
+}
+
+// Hook is a callback registered for the activity of a set of addresses.
+type Hook struct {
+	ID        string           `json:"id"`
+	URL       string           `json:"url"`
+	Addresses []common.Address `json:"addresses"`
+	Secret    string           `json:"secret,omitempty"` // Key signing the notifications ("" = unsigned)
+}
+
+// Notification is the JSON body POSTed to a hook, containing the activity of the
+// watched addresses in a single mined block.
+type Notification struct {
+	Hook         string         `json:"hook"`
+	BlockHash    common.Hash    `json:"blockHash"`
+	BlockNumber  hexutil.Uint64 `json:"blockNumber"`
+	Transactions []*Transaction `json:"transactions,omitempty"`
+	Logs         []*types.Log   `json:"logs,omitempty"`
+}
+
+// Transaction is a mined transaction sent from or to a watched address.
+type Transaction struct {
+	Hash  common.Hash     `json:"hash"`
+	Index hexutil.Uint    `json:"transactionIndex"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+}
+
+// Signature returns the value of the signature header of a notification body,
+// which receivers may recompute to authenticate the notifications.
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// hook is a registered callback along with its delivery queue.
+type hook struct {
+	Hook
+
+	addrs  map[common.Address]struct{} // Watched addresses
+	topics map[common.Hash]struct{}    // Watched addresses as log topics (e.g. token transfers)
+
+	queue chan []byte   // Notifications waiting for delivery
+	quit  chan struct{} // Closed when the hook is unregistered
+}
+
+// newHook creates the delivery state of a registered callback.
+func newHook(def Hook) *hook {
+	h := &hook{
+		Hook:   def,
+		addrs:  make(map[common.Address]struct{}, len(def.Addresses)),
+		topics: make(map[common.Hash]struct{}, len(def.Addresses)),
+		queue:  make(chan []byte, hookQueueSize),
+		quit:   make(chan struct{}),
+	}
+	for _, addr := range def.Addresses {
+		h.addrs[addr] = struct{}{}
+		h.topics[common.BytesToHash(addr[:])] = struct{}{}
+	}
+	return h
+}
+
+// matchLog checks whether a log was emitted by a watched address or refers to
+// one in any of its indexed arguments.
+func (h *hook) matchLog(l *types.Log) bool {
+	if _, ok := h.addrs[l.Address]; ok {
+		return true
+	}
+	for i := 1; i < len(l.Topics); i++ {
+		if _, ok := h.topics[l.Topics[i]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Service notifies the registered hooks of the activity of their addresses in
+// newly mined blocks, retrying failed deliveries with exponential backoff.
+type Service struct {
+	config      Config
+	mux         *event.TypeMux
+	chainConfig *params.ChainConfig
+	client      *http.Client
+
+	hooks   map[string]*hook
+	running bool // Whether the delivery routines of new hooks should be started
+	lock    sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a web-hooks service watching the chain events of the given mux,
+// loading the hooks registered earlier from the configured store.
+func New(config Config, mux *event.TypeMux, chainConfig *params.ChainConfig) (*Service, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+	s := &Service{
+		config:      config,
+		mux:         mux,
+		chainConfig: chainConfig,
+		client:      &http.Client{Timeout: config.Timeout},
+		hooks:       make(map[string]*hook),
+		quit:        make(chan struct{}),
+	}
+	if config.Store != "" {
+		blob, err := ioutil.ReadFile(config.Store)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var defs []Hook
+			if err := json.Unmarshal(blob, &defs); err != nil {
+				return nil, fmt.Errorf("invalid web-hooks store %s: %v", config.Store, err)
+			}
+			for _, def := range defs {
+				s.hooks[def.ID] = newHook(def)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the web-hooks service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints to manage the
+// registered hooks.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "webhooks",
+			Version:   "1.0",
+			Service:   NewPrivateWebhooksAPI(s),
+		},
+	}
+}
+
+// Start implements node.Service, starting the chain event processing and the
+// delivery routines of the registered hooks.
+func (s *Service) Start(server *p2p.Server) error {
+	s.lock.Lock()
+	s.running = true
+	for _, h := range s.hooks {
+		s.wg.Add(1)
+		go s.deliver(h)
+	}
+	hooks := len(s.hooks)
+	s.lock.Unlock()
+
+	s.wg.Add(1)
+	go s.loop(s.mux.Subscribe(core.ChainEvent{}))
+
+	log.Info("Web-hooks service started", "hooks", hooks)
+	return nil
+}
+
+// Stop implements node.Service, terminating the event processing and deliveries.
+// Notifications still queued for delivery are lost.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	log.Info("Web-hooks service stopped")
+	return nil
+}
+
+// Register adds a new hook POSTing the activity of the given addresses to the
+// callback URL, signing the notifications with the secret if one is given.
+func (s *Service) Register(callback string, addresses []common.Address, secret string) (string, error) {
+	u, err := url.Parse(callback)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid callback url %q, must be http(s)", callback)
+	}
+	switch {
+	case len(addresses) == 0:
+		return "", errNoAddresses
+	case len(addresses) > maxHookAddresses:
+		return "", errTooManyAddress
+	}
+	h := newHook(Hook{
+		ID:        string(rpc.NewID()),
+		URL:       callback,
+		Addresses: append([]common.Address(nil), addresses...),
+		Secret:    secret,
+	})
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.hooks[h.ID] = h
+	if err := s.save(); err != nil {
+		delete(s.hooks, h.ID)
+		return "", err
+	}
+	if s.running {
+		s.wg.Add(1)
+		go s.deliver(h)
+	}
+	log.Info("Registered web-hook", "id", h.ID, "url", h.URL, "addresses", len(h.Addresses))
+	return h.ID, nil
+}
+
+// Unregister removes a hook, dropping any of its undelivered notifications. It
+// returns whether the hook existed.
+func (s *Service) Unregister(id string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	h, ok := s.hooks[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.hooks, id)
+	close(h.quit)
+
+	log.Info("Unregistered web-hook", "id", id, "url", h.URL)
+	return true, s.save()
+}
+
+// Hooks returns the registered hooks sorted by identifier, omitting the secrets.
+func (s *Service) Hooks() []Hook {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hooks := s.definitions()
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks
+}
+
+// definitions returns the registered hooks sorted by identifier.
+//
+// Note, this method assumes the service lock is held!
+func (s *Service) definitions() []Hook {
+	defs := make([]Hook, 0, len(s.hooks))
+	for _, h := range s.hooks {
+		defs = append(defs, h.Hook)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs
+}
+
+// save persists the registered hooks into the configured store.
+//
+// Note, this method assumes the service lock is held!
+func (s *Service) save() error {
+	if s.config.Store == "" {
+		return nil
+	}
+	blob, err := json.MarshalIndent(s.definitions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	// The temporary file is created readable by the owner only, keeping the
+	// secrets private
+	f, err := ioutil.TempFile(filepath.Dir(s.config.Store), "."+filepath.Base(s.config.Store)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(blob); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), s.config.Store)
+}
+
+// loop processes the chain events until termination.
+func (s *Service) loop(sub *event.TypeMuxSubscription) {
+	defer s.wg.Done()
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev, ok := <-sub.Chan():
+			if !ok {
+				return
+			}
+			s.process(ev.Data.(core.ChainEvent))
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// process matches the transactions and logs of a newly mined block against the
+// registered hooks, queueing a notification for every hook with any activity.
+func (s *Service) process(ev core.ChainEvent) {
+	s.lock.Lock()
+	hooks := make([]*hook, 0, len(s.hooks))
+	for _, h := range s.hooks {
+		hooks = append(hooks, h)
+	}
+	s.lock.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+	var (
+		signer = types.MakeSigner(s.chainConfig, ev.Block.Number())
+		txs    = make([]*Transaction, len(ev.Block.Transactions()))
+	)
+	for i, tx := range ev.Block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		txs[i] = &Transaction{
+			Hash:  tx.Hash(),
+			Index: hexutil.Uint(i),
+			From:  from,
+			To:    tx.To(),
+			Value: (*hexutil.Big)(tx.Value()),
+		}
+	}
+	for _, h := range hooks {
+		notification := &Notification{
+			Hook:        h.ID,
+			BlockHash:   ev.Block.Hash(),
+			BlockNumber: hexutil.Uint64(ev.Block.NumberU64()),
+		}
+		for _, tx := range txs {
+			_, from := h.addrs[tx.From]
+
+			var to bool
+			if tx.To != nil {
+				_, to = h.addrs[*tx.To]
+			}
+			if from || to {
+				notification.Transactions = append(notification.Transactions, tx)
+			}
+		}
+		for _, l := range ev.Logs {
+			if h.matchLog(l) {
+				notification.Logs = append(notification.Logs, l)
+			}
+		}
+		if len(notification.Transactions) == 0 && len(notification.Logs) == 0 {
+			continue
+		}
+		body, err := json.Marshal(notification)
+		if err != nil {
+			log.Error("Failed to encode web-hook notification", "id", h.ID, "err", err)
+			continue
+		}
+		select {
+		case h.queue <- body:
+		default:
+			log.Warn("Web-hook delivery queue full, dropping notification", "id", h.ID, "block", ev.Block.NumberU64())
+		}
+	}
+}
+
+// deliver POSTs the queued notifications of a hook in order until the hook is
+// unregistered or the service terminated.
+func (s *Service) deliver(h *hook) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case body := <-h.queue:
+			s.post(h, body)
+		case <-h.quit:
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// post delivers a single notification, retrying with exponential backoff until
+// it is accepted or the retry allowance is exhausted.
+func (s *Service) post(h *hook, body []byte) {
+	delay := s.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := s.send(h, body)
+		if err == nil {
+			return
+		}
+		if attempt >= s.config.Retries {
+			log.Warn("Dropping undeliverable web-hook notification", "id", h.ID, "url", h.URL, "attempts", attempt+1, "err", err)
+			return
+		}
+		log.Debug("Web-hook delivery failed, retrying", "id", h.ID, "url", h.URL, "delay", delay, "err", err)
+		select {
+		case <-time.After(delay):
+		case <-h.quit:
+			return
+		case <-s.quit:
+			return
+		}
+		delay *= 2
+	}
+}
+
+// send makes a single delivery attempt of a notification.
+func (s *Service) send(h *hook, body []byte) error {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Signature(h.Secret, body))
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	return nil
+}
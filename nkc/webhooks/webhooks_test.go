@@ -0,0 +1,183 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+)
+
+// delivery is a notification received by the test callback server.
+type delivery struct {
+	body      []byte
+	signature string
+}
+
+// newTestServer creates a callback server rejecting the first fails deliveries
+// and reporting all received ones.
+func newTestServer(fails int) (*httptest.Server, chan delivery) {
+	deliveries := make(chan delivery, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		deliveries <- delivery{body, r.Header.Get(SignatureHeader)}
+		if fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	return server, deliveries
+}
+
+// Tests that the activity of watched addresses is delivered to the registered
+// hooks, signed and retried until accepted.
+func TestNotifications(t *testing.T) {
+	server, deliveries := newTestServer(1)
+	defer server.Close()
+
+	mux := new(event.TypeMux)
+	defer mux.Stop()
+
+	service, err := New(Config{Retries: 2, RetryDelay: 10 * time.Millisecond}, mux, params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	service.Start(nil)
+	defer service.Stop()
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	id, err := service.Register(server.URL, []common.Address{addr}, "secret")
+	if err != nil {
+		t.Fatalf("failed to register hook: %v", err)
+	}
+	// Mine a block with an own transaction, an unrelated one and a token transfer
+	signer := types.MakeSigner(params.TestChainConfig, big.NewInt(1))
+	own, _ := types.SignTx(types.NewTransaction(0, common.Address{0xaa}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+	otherKey, _ := crypto.GenerateKey()
+	other, _ := types.SignTx(types.NewTransaction(0, common.Address{0xbb}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, otherKey)
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{own, other}, nil, nil)
+	logs := []*types.Log{
+		{Address: common.Address{0xcc}, Topics: []common.Hash{{0x01}, {0x02}, common.BytesToHash(addr[:])}},
+		{Address: common.Address{0xcc}, Topics: []common.Hash{common.BytesToHash(addr[:])}},
+	}
+	mux.Post(core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+
+	var last delivery
+	for i := 0; i < 2; i++ {
+		select {
+		case last = <-deliveries:
+		case <-time.After(time.Second):
+			t.Fatalf("delivery %d: timeout", i)
+		}
+	}
+	if want := Signature("secret", last.body); last.signature != want {
+		t.Errorf("signature mismatch: have %s, want %s", last.signature, want)
+	}
+	var notification Notification
+	if err := json.Unmarshal(last.body, &notification); err != nil {
+		t.Fatalf("failed to decode notification: %v", err)
+	}
+	if notification.Hook != id || notification.BlockHash != block.Hash() || uint64(notification.BlockNumber) != 1 {
+		t.Errorf("notification header mismatch: %+v", notification)
+	}
+	if len(notification.Transactions) != 1 || notification.Transactions[0].Hash != own.Hash() || notification.Transactions[0].From != addr {
+		t.Errorf("notified transactions mismatch: %+v", notification.Transactions)
+	}
+	if len(notification.Logs) != 1 || len(notification.Logs[0].Topics) != 3 {
+		t.Errorf("notified logs mismatch: %+v", notification.Logs)
+	}
+	select {
+	case extra := <-deliveries:
+		t.Errorf("unexpected delivery: %s", extra.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that registered hooks are persisted across restarts and listed without
+// their secrets.
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{Store: filepath.Join(dir, "webhooks.json")}
+	service, err := New(config, new(event.TypeMux), params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if _, err := service.Register("ftp://example.org", []common.Address{{0x01}}, ""); err == nil {
+		t.Errorf("non-http callback accepted")
+	}
+	if _, err := service.Register("http://example.org", nil, ""); err != errNoAddresses {
+		t.Errorf("empty address set error mismatch: have %v, want %v", err, errNoAddresses)
+	}
+	first, _ := service.Register("http://example.org/first", []common.Address{{0x01}}, "secret")
+	second, _ := service.Register("https://example.org/second", []common.Address{{0x02}, {0x03}}, "")
+
+	if ok, err := service.Unregister(first); !ok || err != nil {
+		t.Fatalf("failed to unregister hook: %v, %v", ok, err)
+	}
+	third, _ := service.Register("http://example.org/third", []common.Address{{0x04}}, "secret")
+
+	// Reload the hooks and check the remaining ones
+	service, err = New(config, new(event.TypeMux), params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("failed to reload service: %v", err)
+	}
+	hooks := service.Hooks()
+	if len(hooks) != 2 {
+		t.Fatalf("hook count mismatch: have %d, want 2", len(hooks))
+	}
+	for _, hook := range hooks {
+		switch hook.ID {
+		case second:
+			if hook.URL != "https://example.org/second" || len(hook.Addresses) != 2 {
+				t.Errorf("second hook mismatch: %+v", hook)
+			}
+		case third:
+			if hook.URL != "http://example.org/third" || len(hook.Addresses) != 1 {
+				t.Errorf("third hook mismatch: %+v", hook)
+			}
+			if hook.Secret != "" {
+				t.Errorf("secret listed")
+			}
+			if service.hooks[third].Secret != "secret" {
+				t.Errorf("secret not persisted")
+			}
+		default:
+			t.Errorf("unexpected hook: %+v", hook)
+		}
+	}
+}
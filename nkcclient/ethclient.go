@@ -18,15 +18,18 @@
 package ethclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/networkchain/networkchain"
 	"github.com/networkchain/networkchain/common"
 	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/params"
 	"github.com/networkchain/networkchain/rlp"
 	"github.com/networkchain/networkchain/rpc"
 )
@@ -174,6 +177,25 @@ func (ec *Client) TransactionByHash(ctx context.Context, hash common.Hash) (tx *
 	return tx, block.BlockNumber == nil, nil
 }
 
+// TransactionConfirmations returns the number of confirmations of a transaction,
+// counting the block including it. Pending transactions and transactions included
+// in non-canonical blocks have no confirmations.
+func (ec *Client) TransactionConfirmations(ctx context.Context, hash common.Hash) (uint64, error) {
+	var tx *struct {
+		Confirmations *hexutil.Uint64 `json:"confirmations"`
+	}
+	err := ec.c.CallContext(ctx, &tx, "eth_getTransactionByHash", hash)
+	if err != nil {
+		return 0, err
+	} else if tx == nil {
+		return 0, networkchain.NotFound
+	}
+	if tx.Confirmations == nil {
+		return 0, nil
+	}
+	return uint64(*tx.Confirmations), nil
+}
+
 // TransactionCount returns the total number of transactions in the given block.
 func (ec *Client) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
 	var num hexutil.Uint
@@ -210,10 +232,91 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// ChainConfig retrieves the chain configuration the remote node is running with,
+// containing the fork schedule needed to interpret its transactions.
+func (ec *Client) ChainConfig(ctx context.Context) (*params.ChainConfig, error) {
+	var config *params.ChainConfig
+	if err := ec.c.CallContext(ctx, &config, "admin_chainConfig"); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, networkchain.NotFound
+	}
+	return config, nil
+}
+
+// SignerAt returns the transaction signer the remote node's chain uses at the
+// given block number, honouring its Homestead and EIP-155 fork blocks. If the
+// number is nil, the signer of the latest block is returned.
+func (ec *Client) SignerAt(ctx context.Context, number *big.Int) (types.Signer, error) {
+	config, err := ec.ChainConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if number == nil {
+		head, err := ec.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		number = head.Number
+	}
+	return types.MakeSigner(config, number), nil
+}
+
+// TransactionSender returns the sender address of the given transaction. The
+// transaction must be known to the remote node and included in the blockchain
+// at the given block and index, the signer of that block being used to recover
+// the sender. The recovered address is cross checked against the one reported
+// by the node.
+func (ec *Client) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
+	header, err := ec.HeaderByHash(ctx, block)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signer, err := ec.SignerAt(ctx, header.Number)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var meta *struct {
+		Hash common.Hash
+		From common.Address
+	}
+	if err = ec.c.CallContext(ctx, &meta, "eth_getTransactionByBlockHashAndIndex", block, hexutil.Uint64(index)); err != nil {
+		return common.Address{}, err
+	}
+	if meta == nil {
+		return common.Address{}, networkchain.NotFound
+	}
+	if meta.Hash != tx.Hash() {
+		return common.Address{}, fmt.Errorf("wrong inclusion block/index: have %x, want %x", meta.Hash, tx.Hash())
+	}
+	if meta.From != sender {
+		return common.Address{}, fmt.Errorf("sender mismatch: recovered %x, node reported %x", sender, meta.From)
+	}
+	return sender, nil
+}
+
+// toBlockNumArg converts a block number into its RPC representation. Besides nil
+// for the latest block, the negative rpc.PendingBlockNumber, rpc.SafeBlockNumber
+// and rpc.FinalizedBlockNumber values select the respective block tags.
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
 	}
+	if number.Sign() < 0 && number.IsInt64() {
+		switch rpc.BlockNumber(number.Int64()) {
+		case rpc.PendingBlockNumber:
+			return "pending"
+		case rpc.SafeBlockNumber:
+			return "safe"
+		case rpc.FinalizedBlockNumber:
+			return "finalized"
+		}
+	}
 	return hexutil.EncodeBig(number)
 }
 
@@ -282,6 +385,76 @@ func (ec *Client) CodeAt(ctx context.Context, account common.Address, blockNumbe
 	return result, err
 }
 
+// CodeAtHash returns the contract code of the given account in the state of the
+// block with the given hash. Unlike CodeAt, the block doesn't need to be part of
+// the canonical chain.
+func (ec *Client) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.c.CallContext(ctx, &result, "eth_getCodeByBlockHash", account, blockHash)
+	return result, err
+}
+
+// StorageEntry is a single contract storage slot returned by StorageRangeAt.
+type StorageEntry struct {
+	Key   *common.Hash `json:"key"`   // Preimage of the slot key, nil if unknown to the node
+	Value common.Hash  `json:"value"` // Raw value of the slot as stored in the storage trie
+}
+
+// StorageRange is a page of contract storage returned by StorageRangeAt, keyed
+// by the hash of the slot keys.
+type StorageRange struct {
+	Storage map[common.Hash]StorageEntry `json:"storage"`
+	NextKey *common.Hash                 `json:"nextKey"` // Hashed key of the next page, nil if this was the last one
+}
+
+// StorageRangeAt returns at most maxResult storage slots of the given account,
+// in the state right before the transaction at txIndex in the given block got
+// executed, starting at the slot with the hashed key start (or the first slot if
+// nil). The call is served by the debug API of the node.
+func (ec *Client) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, account common.Address, start []byte, maxResult int) (*StorageRange, error) {
+	var result StorageRange
+	err := ec.c.CallContext(ctx, &result, "debug_storageRangeAt", blockHash, txIndex, account, hexutil.Bytes(start), maxResult)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// IterateStorageAt pages through the entire storage of the given account at the
+// given block and transaction index using StorageRangeAt, invoking fn for every
+// slot in the order of the hashed keys. Iteration stops at the first error
+// returned by fn, which is passed back to the caller.
+func (ec *Client) IterateStorageAt(ctx context.Context, blockHash common.Hash, txIndex int, account common.Address, pageSize int, fn func(hash common.Hash, entry StorageEntry) error) error {
+	var start []byte
+	for {
+		page, err := ec.StorageRangeAt(ctx, blockHash, txIndex, account, start, pageSize)
+		if err != nil {
+			return err
+		}
+		hashes := make([]common.Hash, 0, len(page.Storage))
+		for hash := range page.Storage {
+			hashes = append(hashes, hash)
+		}
+		sort.Sort(hashesByValue(hashes))
+		for _, hash := range hashes {
+			if err := fn(hash, page.Storage[hash]); err != nil {
+				return err
+			}
+		}
+		if page.NextKey == nil {
+			return nil
+		}
+		start = page.NextKey.Bytes()
+	}
+}
+
+// hashesByValue implements sort.Interface to order hashes bytewise.
+type hashesByValue []common.Hash
+
+func (h hashesByValue) Len() int           { return len(h) }
+func (h hashesByValue) Less(i, j int) bool { return bytes.Compare(h[i][:], h[j][:]) < 0 }
+func (h hashesByValue) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
 // NonceAt returns the account nonce of the given account.
 // The block number can be nil, in which case the nonce is taken from the latest known block.
 func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
@@ -355,7 +528,11 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	return uint(num), err
 }
 
-// TODO: SubscribePendingTransactions (needs server side)
+// SubscribePendingTransactions subscribes to notifications about the transactions
+// entering the transaction pool of the node.
+func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (networkchain.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", true)
+}
 
 // Contract Calling
 
@@ -16,7 +16,25 @@
 
 package ethclient
 
-import "github.com/networkchain/networkchain"
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain"
+	"github.com/networkchain/networkchain/common"
+	"github.com/networkchain/networkchain/common/hexutil"
+	"github.com/networkchain/networkchain/core"
+	"github.com/networkchain/networkchain/core/types"
+	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/eth/filters"
+	"github.com/networkchain/networkchain/ethdb"
+	"github.com/networkchain/networkchain/event"
+	"github.com/networkchain/networkchain/params"
+	"github.com/networkchain/networkchain/rpc"
+)
 
 // Verify that Client implements the networkchain interfaces.
 var (
@@ -29,6 +47,173 @@ var (
 	_ = networkchain.GasPricer(&Client{})
 	_ = networkchain.LogFilterer(&Client{})
 	_ = networkchain.PendingStateReader(&Client{})
-	// _ = networkchain.PendingStateEventer(&Client{})
+	_ = networkchain.PendingStateEventer(&Client{})
 	_ = networkchain.PendingContractCaller(&Client{})
 )
+
+// FakeAdminAPI serves a fixed chain configuration over the admin namespace.
+type FakeAdminAPI struct {
+	config *params.ChainConfig
+}
+
+func (api *FakeAdminAPI) ChainConfig() *params.ChainConfig {
+	return api.config
+}
+
+// Tests that the signer is selected based on the fork schedule of the remote
+// node's chain configuration.
+func TestSignerAt(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainId:        big.NewInt(7),
+		HomesteadBlock: big.NewInt(10),
+		EIP155Block:    big.NewInt(20),
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("admin", &FakeAdminAPI{config}); err != nil {
+		t.Fatalf("failed to register admin API: %v", err)
+	}
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	tests := []struct {
+		number uint64
+		signer types.Signer
+	}{
+		{0, types.FrontierSigner{}},
+		{10, types.HomesteadSigner{}},
+		{19, types.HomesteadSigner{}},
+		{20, types.NewEIP155Signer(big.NewInt(7))},
+	}
+	for i, tt := range tests {
+		signer, err := client.SignerAt(context.Background(), new(big.Int).SetUint64(tt.number))
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve signer: %v", i, err)
+		}
+		if !signer.Equal(tt.signer) {
+			t.Errorf("test %d: signer mismatch at block %d: have %T, want %T", i, tt.number, signer, tt.signer)
+		}
+	}
+}
+
+// FakeDebugAPI serves the storage of a single contract over the debug namespace,
+// paging through the slots ordered by their hashed keys.
+type FakeDebugAPI struct {
+	slots []common.Hash // Sorted hashed keys of the storage slots
+}
+
+func (api *FakeDebugAPI) StorageRangeAt(blockHash common.Hash, txIndex int, account common.Address, start hexutil.Bytes, maxResult int) StorageRange {
+	result := StorageRange{Storage: make(map[common.Hash]StorageEntry)}
+	for _, slot := range api.slots {
+		if bytes.Compare(slot[:], start) < 0 {
+			continue
+		}
+		if len(result.Storage) == maxResult {
+			next := slot
+			result.NextKey = &next
+			break
+		}
+		result.Storage[slot] = StorageEntry{Value: slot}
+	}
+	return result
+}
+
+// Tests that contract storage is iterated across multiple pages in order.
+func TestIterateStorageAt(t *testing.T) {
+	api := new(FakeDebugAPI)
+	for i := 1; i <= 7; i++ {
+		api.slots = append(api.slots, common.BigToHash(big.NewInt(int64(i*1000))))
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	var seen []common.Hash
+	err := client.IterateStorageAt(context.Background(), common.Hash{}, 0, common.Address{}, 3, func(hash common.Hash, entry StorageEntry) error {
+		if entry.Value != hash {
+			t.Errorf("slot %x: value mismatch: have %x", hash, entry.Value)
+		}
+		seen = append(seen, hash)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to iterate storage: %v", err)
+	}
+	if len(seen) != len(api.slots) {
+		t.Fatalf("slot count mismatch: have %d, want %d", len(seen), len(api.slots))
+	}
+	for i := range seen {
+		if seen[i] != api.slots[i] {
+			t.Errorf("slot %d: hash mismatch: have %x, want %x", i, seen[i], api.slots[i])
+		}
+	}
+}
+
+// filterBackend is a minimal filter backend only providing an event mux.
+type filterBackend struct {
+	mux *event.TypeMux
+	db  ethdb.Database
+}
+
+func (b *filterBackend) ChainDb() ethdb.Database  { return b.db }
+func (b *filterBackend) EventMux() *event.TypeMux { return b.mux }
+func (b *filterBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	return nil, nil
+}
+func (b *filterBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return nil, nil
+}
+func (b *filterBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	return nil, nil
+}
+
+// Tests that full pending transactions are delivered through a subscription.
+func TestSubscribePendingTransactions(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	backend := &filterBackend{mux: new(event.TypeMux), db: db}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", filters.NewPublicFilterAPI(backend, false)); err != nil {
+		t.Fatalf("failed to register filter API: %v", err)
+	}
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	txs := make(chan *types.Transaction)
+	sub, err := client.SubscribePendingTransactions(context.Background(), txs)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	// The subscription is installed asynchronously, post until delivered
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			backend.mux.Post(core.TxPreEvent{Tx: tx})
+		case have := <-txs:
+			if have.Hash() != tx.Hash() {
+				t.Fatalf("transaction mismatch: have %x, want %x", have.Hash(), tx.Hash())
+			}
+			return
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-timeout:
+			t.Fatalf("transaction not delivered")
+		}
+	}
+}
@@ -17,38 +17,65 @@
 package ethdb
 
 import (
+	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/metrics"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/cache"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	gometrics "github.com/rcrowley/go-metrics"
 )
 
 var OpenFileLimit = 64
 
+// rangeDeleteBatchSize is the amount of data accumulated in a batch by range
+// deletions before flushing it to disk, bounding their memory use.
+const rangeDeleteBatchSize = 100 * 1024
+
+// cappedBlockCache is the capacity the block cache of a database is shrunk to
+// while capped under memory pressure.
+const cappedBlockCache = 1 * opt.MiB
+
 type LDBDatabase struct {
-	fn string      // filename for reporting
-	db *leveldb.DB // LevelDB instance
+	written uint64 // Data written by the user, for write amplification (atomic, 64-bit aligned)
+
+	fn       string      // filename for reporting
+	db       *leveldb.DB // LevelDB instance
+	snapshot string      // Path of the private copy of a locked database, removed on close
+
+	blockCache *blockCacher // Block cache of the database, resizable at runtime
 
 	getTimer       gometrics.Timer // Timer for measuring the database get request counts and latencies
 	putTimer       gometrics.Timer // Timer for measuring the database put request counts and latencies
 	delTimer       gometrics.Timer // Timer for measuring the database delete request counts and latencies
 	missMeter      gometrics.Meter // Meter for measuring the missed database get requests
 	readMeter      gometrics.Meter // Meter for measuring the database get request data usage
+	iterMeter      gometrics.Meter // Meter for measuring the number of entries iterated over
+	iterReadMeter  gometrics.Meter // Meter for measuring the data read by iterators
+	rangeDelMeter  gometrics.Meter // Meter for measuring the number of entries deleted by range
 	writeMeter     gometrics.Meter // Meter for measuring the database put request data usage
 	compTimeMeter  gometrics.Meter // Meter for measuring the total time spent in database compaction
 	compReadMeter  gometrics.Meter // Meter for measuring the data read during compaction
 	compWriteMeter gometrics.Meter // Meter for measuring the data written during compaction
 
+	backlogGauge   gometrics.Gauge        // Gauge for tracking the data pending compaction
+	level0Gauge    gometrics.Gauge        // Gauge for tracking the number of level 0 tables
+	openFilesGauge gometrics.Gauge        // Gauge for tracking the number of opened table files
+	readAmpGauge   gometrics.Gauge        // Gauge for tracking the read amplification
+	writeAmpGauge  gometrics.GaugeFloat64 // Gauge for tracking the write amplification
+
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
 
@@ -69,13 +96,16 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 	logger.Info("Allocated cache and file handles", "cache", cache, "handles", handles)
 
 	// Open the db and recover any potential corruptions
+	blockCache := &blockCacher{capacity: cache / 2 * opt.MiB}
 	db, err := leveldb.OpenFile(file, &opt.Options{
 		OpenFilesCacheCapacity: handles,
-		BlockCacheCapacity:     cache / 2 * opt.MiB,
+		BlockCacher:            blockCache,
+		BlockCacheCapacity:     blockCache.capacity,
 		WriteBuffer:            cache / 4 * opt.MiB, // Two of these are used internally
 		Filter:                 filter.NewBloomFilter(10),
 	})
 	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		blockCache.cacher = nil
 		db, err = leveldb.RecoverFile(file, nil)
 	}
 	// (Re)check for errors and abort if opening of the db failed
@@ -83,17 +113,45 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 		return nil, err
 	}
 	return &LDBDatabase{
-		fn:  file,
-		db:  db,
-		log: logger,
+		fn:         file,
+		db:         db,
+		blockCache: blockCache,
+		log:        logger,
 	}, nil
 }
 
+// blockCacher creates the block cache of a database, retaining it so that its
+// capacity can be adjusted while the database is open.
+type blockCacher struct {
+	capacity int          // Configured capacity of the block cache
+	cacher   cache.Cacher // Block cache created by the database, nil if not yet opened
+}
+
+// New implements opt.Cacher, creating an LRU block cache.
+func (c *blockCacher) New(capacity int) cache.Cacher {
+	c.cacher = cache.NewLRU(capacity)
+	return c.cacher
+}
+
 // Path returns the path to the database directory.
 func (db *LDBDatabase) Path() string {
 	return db.fn
 }
 
+// CapBlockCache shrinks the block cache of the database to cappedBlockCache (or
+// restores its configured capacity), e.g. to relieve memory pressure. Shrinking
+// evicts the least recently used blocks beyond the capped capacity.
+func (db *LDBDatabase) CapBlockCache(capped bool) {
+	if db.blockCache == nil || db.blockCache.cacher == nil {
+		return
+	}
+	capacity := db.blockCache.capacity
+	if capped && capacity > cappedBlockCache {
+		capacity = cappedBlockCache
+	}
+	db.blockCache.cacher.SetCapacity(capacity)
+}
+
 // Put puts the given key / value to the queue
 func (db *LDBDatabase) Put(key []byte, value []byte) error {
 	// Measure the database put latency, if requested
@@ -106,6 +164,7 @@ func (db *LDBDatabase) Put(key []byte, value []byte) error {
 	if db.writeMeter != nil {
 		db.writeMeter.Mark(int64(len(value)))
 	}
+	atomic.AddUint64(&db.written, uint64(len(key)+len(value)))
 	return db.db.Put(key, value, nil)
 }
 
@@ -142,7 +201,72 @@ func (db *LDBDatabase) Delete(key []byte) error {
 }
 
 func (db *LDBDatabase) NewIterator() iterator.Iterator {
-	return db.db.NewIterator(nil, nil)
+	return db.meterIterator(db.db.NewIterator(nil, nil))
+}
+
+// NewIteratorWithPrefix returns an iterator over the entries whose keys start with
+// the given prefix.
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return db.meterIterator(db.db.NewIterator(util.BytesPrefix(prefix), nil))
+}
+
+// Range returns an iterator over the entries with keys in [start, limit).
+func (db *LDBDatabase) Range(start, limit []byte) iterator.Iterator {
+	return db.meterIterator(db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil))
+}
+
+// DeleteRange deletes all the entries with keys in [start, limit). The entries are
+// removed in multiple batches, so a failure might leave the range partially deleted.
+func (db *LDBDatabase) DeleteRange(start, limit []byte) (int, error) {
+	it := db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+	defer it.Release()
+
+	var (
+		batch   = new(leveldb.Batch)
+		size    int
+		deleted int
+	)
+	for it.Next() {
+		batch.Delete(it.Key())
+		size += len(it.Key())
+		deleted++
+
+		if size >= rangeDeleteBatchSize {
+			if err := db.db.Write(batch, nil); err != nil {
+				return deleted - batch.Len(), err
+			}
+			batch.Reset()
+			size = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		return deleted - batch.Len(), err
+	}
+	if err := db.db.Write(batch, nil); err != nil {
+		return deleted - batch.Len(), err
+	}
+	if db.rangeDelMeter != nil {
+		db.rangeDelMeter.Mark(int64(deleted))
+	}
+	return deleted, nil
+}
+
+// NewSnapshot creates a point-in-time view of the database.
+func (db *LDBDatabase) NewSnapshot() (Snapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &ldbSnapshot{db: db, snap: snap}, nil
+}
+
+// meterIterator wraps an iterator to report the iterated entries to the metrics
+// system, if enabled.
+func (db *LDBDatabase) meterIterator(it iterator.Iterator) iterator.Iterator {
+	if db.iterMeter == nil {
+		return it
+	}
+	return &meteredIterator{Iterator: it, entries: db.iterMeter, data: db.iterReadMeter}
 }
 
 func (db *LDBDatabase) Close() {
@@ -163,6 +287,11 @@ func (db *LDBDatabase) Close() {
 	} else {
 		db.log.Error("Failed to close database", "err", err)
 	}
+	if db.snapshot != "" {
+		if err := os.RemoveAll(db.snapshot); err != nil {
+			db.log.Error("Failed to remove database snapshot", "snapshot", db.snapshot, "err", err)
+		}
+	}
 }
 
 func (db *LDBDatabase) LDB() *leveldb.DB {
@@ -181,10 +310,18 @@ func (db *LDBDatabase) Meter(prefix string) {
 	db.delTimer = metrics.NewTimer(prefix + "user/dels")
 	db.missMeter = metrics.NewMeter(prefix + "user/misses")
 	db.readMeter = metrics.NewMeter(prefix + "user/reads")
+	db.iterMeter = metrics.NewMeter(prefix + "user/iterations")
+	db.iterReadMeter = metrics.NewMeter(prefix + "user/iterreads")
+	db.rangeDelMeter = metrics.NewMeter(prefix + "user/rangedels")
 	db.writeMeter = metrics.NewMeter(prefix + "user/writes")
 	db.compTimeMeter = metrics.NewMeter(prefix + "compact/time")
 	db.compReadMeter = metrics.NewMeter(prefix + "compact/input")
 	db.compWriteMeter = metrics.NewMeter(prefix + "compact/output")
+	db.backlogGauge = metrics.NewGauge(prefix + "compact/backlog")
+	db.level0Gauge = metrics.NewGauge(prefix + "compact/level0")
+	db.openFilesGauge = metrics.NewGauge(prefix + "files/open")
+	db.readAmpGauge = metrics.NewGauge(prefix + "amplification/read")
+	db.writeAmpGauge = metrics.NewGaugeFloat64(prefix + "amplification/write")
 
 	// Create a quit channel for the periodic collector and run it
 	db.quitLock.Lock()
@@ -214,39 +351,19 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 	// Iterate ad infinitum and collect the stats
 	for i := 1; ; i++ {
 		// Retrieve the database stats
-		stats, err := db.db.GetProperty("leveldb.stats")
+		stats, err := db.Stats()
 		if err != nil {
 			db.log.Error("Failed to read database stats", "err", err)
 			return
 		}
-		// Find the compaction table, skip the header
-		lines := strings.Split(stats, "\n")
-		for len(lines) > 0 && strings.TrimSpace(lines[0]) != "Compactions" {
-			lines = lines[1:]
-		}
-		if len(lines) <= 3 {
-			db.log.Error("Compaction table not found")
-			return
-		}
-		lines = lines[3:]
-
 		// Iterate over all the table rows, and accumulate the entries
 		for j := 0; j < len(counters[i%2]); j++ {
 			counters[i%2][j] = 0
 		}
-		for _, line := range lines {
-			parts := strings.Split(line, "|")
-			if len(parts) != 6 {
-				break
-			}
-			for idx, counter := range parts[3:] {
-				value, err := strconv.ParseFloat(strings.TrimSpace(counter), 64)
-				if err != nil {
-					db.log.Error("Compaction entry parsing failed", "err", err)
-					return
-				}
-				counters[i%2][idx] += value
-			}
+		for _, level := range stats.Levels {
+			counters[i%2][0] += level.Time
+			counters[i%2][1] += level.Read
+			counters[i%2][2] += level.Write
 		}
 		// Update all the requested meters
 		if db.compTimeMeter != nil {
@@ -258,6 +375,21 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 		if db.compWriteMeter != nil {
 			db.compWriteMeter.Mark(int64((counters[i%2][2] - counters[(i-1)%2][2]) * 1024 * 1024))
 		}
+		if db.backlogGauge != nil {
+			db.backlogGauge.Update(int64(stats.Backlog * 1024 * 1024))
+		}
+		if db.level0Gauge != nil && len(stats.Levels) > 0 && stats.Levels[0].Level == 0 {
+			db.level0Gauge.Update(int64(stats.Levels[0].Tables))
+		}
+		if db.openFilesGauge != nil {
+			db.openFilesGauge.Update(int64(stats.OpenTables))
+		}
+		if db.readAmpGauge != nil {
+			db.readAmpGauge.Update(int64(stats.ReadAmp))
+		}
+		if db.writeAmpGauge != nil {
+			db.writeAmpGauge.Update(stats.WriteAmp)
+		}
 		// Sleep a bit, then repeat the stats collection
 		select {
 		case errc := <-db.quitChan:
@@ -271,24 +403,204 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 	}
 }
 
+// LevelStats contains the table and compaction statistics of a single level of
+// the database.
+type LevelStats struct {
+	Level  int     `json:"level"`
+	Tables int     `json:"tables"`
+	Size   float64 `json:"size"`  // Total size of the tables in megabytes
+	Time   float64 `json:"time"`  // Time spent compacting into the level in seconds
+	Read   float64 `json:"read"`  // Data read by compactions in megabytes
+	Write  float64 `json:"write"` // Data written by compactions in megabytes
+	Score  float64 `json:"score"` // Compaction score, the level is due for compaction above 1
+}
+
+// Stats is a digest of the internal state of the storage engine, allowing disk
+// bound stalls to be told apart from network bound ones.
+type Stats struct {
+	Levels     []LevelStats `json:"levels"`
+	Backlog    float64      `json:"compactionBacklog"`  // Data above the level size targets, in megabytes
+	OpenTables int          `json:"openTables"`         // Number of table files held open
+	Snapshots  int          `json:"snapshots"`          // Number of live database snapshots
+	Iterators  int          `json:"iterators"`          // Number of live database iterators
+	ReadAmp    int          `json:"readAmplification"`  // Tables consulted by a worst case lookup
+	WriteAmp   float64      `json:"writeAmplification"` // Data written to disk per byte of user writes
+}
+
+// Stats retrieves the internal counters of leveldb and derives the compaction
+// backlog and the read and write amplification from them.
+func (db *LDBDatabase) Stats() (*Stats, error) {
+	table, err := db.db.GetProperty("leveldb.stats")
+	if err != nil {
+		return nil, err
+	}
+	levels, err := parseCompactionStats(table)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{Levels: levels}
+
+	var compacted float64
+	for i, level := range stats.Levels {
+		// Score the levels the same way leveldb picks its compaction targets
+		if level.Level == 0 {
+			stats.Levels[i].Score = float64(level.Tables) / float64(opt.DefaultCompactionL0Trigger)
+			if stats.Levels[i].Score >= 1 {
+				stats.Backlog += level.Size
+			}
+			stats.ReadAmp += level.Tables
+		} else {
+			target := float64((*opt.Options)(nil).GetCompactionTotalSize(level.Level)) / opt.MiB
+			stats.Levels[i].Score = level.Size / target
+			if level.Size > target {
+				stats.Backlog += level.Size - target
+			}
+			if level.Tables > 0 {
+				stats.ReadAmp++
+			}
+		}
+		compacted += level.Write
+	}
+	if written := atomic.LoadUint64(&db.written); written > 0 {
+		stats.WriteAmp = (float64(written) + compacted*opt.MiB) / float64(written)
+	}
+	for prop, field := range map[string]*int{
+		"leveldb.openedtables": &stats.OpenTables,
+		"leveldb.alivesnaps":   &stats.Snapshots,
+		"leveldb.aliveiters":   &stats.Iterators,
+	} {
+		value, err := db.db.GetProperty(prop)
+		if err != nil {
+			return nil, err
+		}
+		if *field, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %v", prop, value, err)
+		}
+	}
+	return stats, nil
+}
+
+// parseCompactionStats extracts the per level statistics from the compaction
+// table of the leveldb.stats property.
+func parseCompactionStats(stats string) ([]LevelStats, error) {
+	// Find the compaction table, skip the header
+	lines := strings.Split(stats, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) != "Compactions" {
+		lines = lines[1:]
+	}
+	if len(lines) <= 3 {
+		return nil, errors.New("compaction table not found")
+	}
+	lines = lines[3:]
+
+	// Iterate over all the table rows and parse the entries
+	var levels []LevelStats
+	for _, line := range lines {
+		parts := strings.Split(line, "|")
+		if len(parts) != 6 {
+			break
+		}
+		var (
+			level LevelStats
+			err   error
+		)
+		if level.Level, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+			return nil, fmt.Errorf("compaction entry parsing failed: %v", err)
+		}
+		if level.Tables, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return nil, fmt.Errorf("compaction entry parsing failed: %v", err)
+		}
+		for idx, field := range []*float64{&level.Size, &level.Time, &level.Read, &level.Write} {
+			if *field, err = strconv.ParseFloat(strings.TrimSpace(parts[2+idx]), 64); err != nil {
+				return nil, fmt.Errorf("compaction entry parsing failed: %v", err)
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// meteredIterator is an iterator reporting the entries it visits and the amount of
+// data read through them.
+type meteredIterator struct {
+	iterator.Iterator
+	entries gometrics.Meter
+	data    gometrics.Meter
+}
+
+// mark reports the current entry of the iterator if it's positioned at one.
+func (it *meteredIterator) mark(ok bool) bool {
+	if ok {
+		it.entries.Mark(1)
+		it.data.Mark(int64(len(it.Key()) + len(it.Value())))
+	}
+	return ok
+}
+
+func (it *meteredIterator) First() bool          { return it.mark(it.Iterator.First()) }
+func (it *meteredIterator) Last() bool           { return it.mark(it.Iterator.Last()) }
+func (it *meteredIterator) Seek(key []byte) bool { return it.mark(it.Iterator.Seek(key)) }
+func (it *meteredIterator) Next() bool           { return it.mark(it.Iterator.Next()) }
+func (it *meteredIterator) Prev() bool           { return it.mark(it.Iterator.Prev()) }
+
+// ldbSnapshot is a point-in-time view of a LevelDB database.
+type ldbSnapshot struct {
+	db   *LDBDatabase
+	snap *leveldb.Snapshot
+}
+
+// Get retrieves the value of the given key as it was when taking the snapshot.
+func (s *ldbSnapshot) Get(key []byte) ([]byte, error) {
+	dat, err := s.snap.Get(key, nil)
+	if err != nil {
+		if s.db.missMeter != nil {
+			s.db.missMeter.Mark(1)
+		}
+		return nil, err
+	}
+	if s.db.readMeter != nil {
+		s.db.readMeter.Mark(int64(len(dat)))
+	}
+	return dat, nil
+}
+
+// NewIteratorWithPrefix returns an iterator over the snapshot entries whose keys
+// start with the given prefix.
+func (s *ldbSnapshot) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return s.db.meterIterator(s.snap.NewIterator(util.BytesPrefix(prefix), nil))
+}
+
+// Range returns an iterator over the snapshot entries with keys in [start, limit).
+func (s *ldbSnapshot) Range(start, limit []byte) iterator.Iterator {
+	return s.db.meterIterator(s.snap.NewIterator(&util.Range{Start: start, Limit: limit}, nil))
+}
+
+// Release releases the snapshot.
+func (s *ldbSnapshot) Release() {
+	s.snap.Release()
+}
+
 // TODO: remove this stuff and expose leveldb directly
 
 func (db *LDBDatabase) NewBatch() Batch {
-	return &ldbBatch{db: db.db, b: new(leveldb.Batch)}
+	return &ldbBatch{db: db, b: new(leveldb.Batch)}
 }
 
 type ldbBatch struct {
-	db *leveldb.DB
-	b  *leveldb.Batch
+	db   *LDBDatabase
+	b    *leveldb.Batch
+	size int
 }
 
 func (b *ldbBatch) Put(key, value []byte) error {
 	b.b.Put(key, value)
+	b.size += len(key) + len(value)
 	return nil
 }
 
 func (b *ldbBatch) Write() error {
-	return b.db.Write(b.b, nil)
+	atomic.AddUint64(&b.db.written, uint64(b.size))
+	return b.db.db.Write(b.b, nil)
 }
 
 type table struct {
@@ -17,10 +17,15 @@
 package ethdb
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 func newDb() *LDBDatabase {
@@ -32,3 +37,214 @@ func newDb() *LDBDatabase {
 
 	return db
 }
+
+func TestLDBStats(t *testing.T) {
+	db := newDb()
+	defer db.Close()
+
+	batch := db.NewBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Put([]byte(fmt.Sprintf("key-%04d", i)), make([]byte, 1024))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		t.Fatalf("failed to compact database: %v", err)
+	}
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("failed to retrieve stats: %v", err)
+	}
+	var tables int
+	for _, level := range stats.Levels {
+		tables += level.Tables
+	}
+	if tables == 0 {
+		t.Errorf("no tables reported after compaction: %+v", stats.Levels)
+	}
+	if stats.ReadAmp == 0 {
+		t.Errorf("read amplification not reported")
+	}
+	if stats.WriteAmp < 1 {
+		t.Errorf("write amplification too low: have %f, want >= 1", stats.WriteAmp)
+	}
+}
+
+// Tests that prefix iterators only return the matching entries, in key order.
+func TestIteratorWithPrefix(t *testing.T) {
+	ldb := newDb()
+	defer ldb.Close()
+	mdb, _ := NewMemDatabase()
+
+	for _, db := range []Database{ldb, mdb} {
+		for _, key := range []string{"b-3", "a-1", "b-1", "c-1", "b-2", "b"} {
+			db.Put([]byte(key), []byte("v"+key))
+		}
+		var keys []string
+		it := db.(Iteratee).NewIteratorWithPrefix([]byte("b-"))
+		for it.Next() {
+			if string(it.Value()) != "v"+string(it.Key()) {
+				t.Errorf("%T: value mismatch for %q: have %q", db, it.Key(), it.Value())
+			}
+			keys = append(keys, string(it.Key()))
+		}
+		it.Release()
+
+		if want := []string{"b-1", "b-2", "b-3"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("%T: iterated keys mismatch: have %v, want %v", db, keys, want)
+		}
+	}
+}
+
+func TestParseCompactionStats(t *testing.T) {
+	table := "Compactions\n" +
+		" Level |   Tables   |    Size(MB)   |    Time(sec)  |    Read(MB)   |   Write(MB)\n" +
+		"-------+------------+---------------+---------------+---------------+---------------\n" +
+		"   0   |          2 |       4.00000 |       1.27969 |       0.00000 |      12.31098\n" +
+		"   2   |        523 |    1000.37159 |       7.26059 |      66.86342 |      66.77884\n"
+
+	levels, err := parseCompactionStats(table)
+	if err != nil {
+		t.Fatalf("failed to parse stats: %v", err)
+	}
+	want := []LevelStats{
+		{Level: 0, Tables: 2, Size: 4, Time: 1.27969, Read: 0, Write: 12.31098},
+		{Level: 2, Tables: 523, Size: 1000.37159, Time: 7.26059, Read: 66.86342, Write: 66.77884},
+	}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("level stats mismatch:\nhave %+v\nwant %+v", levels, want)
+	}
+	if _, err := parseCompactionStats("garbage"); err == nil {
+		t.Errorf("missing compaction table not detected")
+	}
+}
+
+// Tests that range iterators and deletions only touch the entries within the
+// requested bounds.
+func TestRangeAndDeleteRange(t *testing.T) {
+	ldb := newDb()
+	defer ldb.Close()
+	mdb, _ := NewMemDatabase()
+
+	for _, db := range []Database{ldb, mdb} {
+		for i := 0; i < 100; i++ {
+			db.Put([]byte(fmt.Sprintf("key-%02d", i)), []byte{byte(i)})
+		}
+		ranger := db.(Ranger)
+
+		var keys int
+		it := ranger.Range([]byte("key-10"), []byte("key-20"))
+		for it.Next() {
+			if want := fmt.Sprintf("key-%02d", 10+keys); string(it.Key()) != want {
+				t.Errorf("%T: iterated key mismatch: have %q, want %q", db, it.Key(), want)
+			}
+			keys++
+		}
+		it.Release()
+		if keys != 10 {
+			t.Errorf("%T: iterated key count mismatch: have %d, want %d", db, keys, 10)
+		}
+		// Delete a bounded and an unbounded range, and check the remaining keys
+		if deleted, err := ranger.DeleteRange([]byte("key-10"), []byte("key-20")); err != nil || deleted != 10 {
+			t.Fatalf("%T: bounded range deletion mismatch: have %d (%v), want %d", db, deleted, err, 10)
+		}
+		if deleted, err := ranger.DeleteRange([]byte("key-90"), nil); err != nil || deleted != 10 {
+			t.Fatalf("%T: unbounded range deletion mismatch: have %d (%v), want %d", db, deleted, err, 10)
+		}
+		for i := 0; i < 100; i++ {
+			_, err := db.Get([]byte(fmt.Sprintf("key-%02d", i)))
+			if deleted := (i >= 10 && i < 20) || i >= 90; deleted != (err != nil) {
+				t.Errorf("%T: key %d presence mismatch: deleted %v, err %v", db, i, deleted, err)
+			}
+		}
+	}
+}
+
+// Tests that snapshots are not affected by modifications done after taking them.
+func TestSnapshot(t *testing.T) {
+	ldb := newDb()
+	defer ldb.Close()
+	mdb, _ := NewMemDatabase()
+
+	for _, db := range []Database{ldb, mdb} {
+		db.Put([]byte("a"), []byte("old"))
+		db.Put([]byte("b"), []byte("old"))
+
+		snap, err := db.(Snapshotter).NewSnapshot()
+		if err != nil {
+			t.Fatalf("%T: failed to create snapshot: %v", db, err)
+		}
+		db.Put([]byte("a"), []byte("new"))
+		db.Delete([]byte("b"))
+		db.Put([]byte("c"), []byte("new"))
+
+		if value, err := snap.Get([]byte("a")); err != nil || string(value) != "old" {
+			t.Errorf("%T: snapshot value mismatch: have %q (%v), want %q", db, value, err, "old")
+		}
+		var keys []string
+		it := snap.Range(nil, nil)
+		for it.Next() {
+			keys = append(keys, string(it.Key()))
+		}
+		it.Release()
+		snap.Release()
+
+		if want := []string{"a", "b"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("%T: snapshot keys mismatch: have %v, want %v", db, keys, want)
+		}
+	}
+}
+
+// Tests that the block cache of a database can be shrunk and restored while the
+// database is open.
+func TestCapBlockCache(t *testing.T) {
+	db := newDb()
+	defer db.Close()
+
+	// Fill the block cache beyond the capped capacity
+	batch := db.NewBatch()
+	for i := 0; i < 1000; i++ {
+		batch.Put([]byte(fmt.Sprintf("key-%04d", i)), make([]byte, 4096))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		t.Fatalf("failed to compact database: %v", err)
+	}
+	cached := func() int {
+		prop, err := db.LDB().GetProperty("leveldb.cachedblock")
+		if err != nil {
+			t.Fatalf("failed to retrieve cached block size: %v", err)
+		}
+		size, _ := strconv.Atoi(prop)
+		return size
+	}
+	read := func() {
+		for i := 0; i < 1000; i++ {
+			if _, err := db.Get([]byte(fmt.Sprintf("key-%04d", i))); err != nil {
+				t.Fatalf("failed to read key %d: %v", i, err)
+			}
+		}
+	}
+	read()
+	if size := cached(); size <= cappedBlockCache {
+		t.Fatalf("block cache not filled: have %d, want more than %d", size, cappedBlockCache)
+	}
+	// Cap the cache and ensure it's shrunk and stays small while reading
+	db.CapBlockCache(true)
+	if size := cached(); size > cappedBlockCache {
+		t.Errorf("block cache not shrunk: have %d, want at most %d", size, cappedBlockCache)
+	}
+	read()
+	if size := cached(); size > cappedBlockCache {
+		t.Errorf("capped block cache exceeded: have %d, want at most %d", size, cappedBlockCache)
+	}
+	// Lift the cap and ensure the cache refills
+	db.CapBlockCache(false)
+	read()
+	if size := cached(); size <= cappedBlockCache {
+		t.Errorf("block cache not refilled: have %d, want more than %d", size, cappedBlockCache)
+	}
+}
@@ -16,6 +16,8 @@
 
 package ethdb
 
+import "github.com/syndtr/goleveldb/leveldb/iterator"
+
 type Database interface {
 	Put(key []byte, value []byte) error
 	Get(key []byte) ([]byte, error)
@@ -24,6 +26,49 @@ type Database interface {
 	NewBatch() Batch
 }
 
+// Iteratee is implemented by databases able to iterate over their entries in key
+// order.
+type Iteratee interface {
+	// NewIteratorWithPrefix returns an iterator over the entries whose keys start
+	// with the given prefix.
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Ranger is implemented by databases able to iterate over and delete ranges of
+// their entries without loading them into memory.
+type Ranger interface {
+	// Range returns an iterator over the entries with keys in [start, limit). A
+	// nil start or limit leaves the range unbounded on that side.
+	Range(start, limit []byte) iterator.Iterator
+
+	// DeleteRange deletes all the entries with keys in [start, limit), returning
+	// the number of entries removed. A nil start or limit leaves the range
+	// unbounded on that side.
+	DeleteRange(start, limit []byte) (int, error)
+}
+
+// Snapshotter is implemented by databases able to provide consistent views of
+// their content at a point in time.
+type Snapshotter interface {
+	// NewSnapshot creates a snapshot of the current state of the database.
+	NewSnapshot() (Snapshot, error)
+}
+
+// Snapshot is a read only view of a database at a point in time, unaffected by
+// later modifications. It must be released after use.
+type Snapshot interface {
+	Iteratee
+
+	// Get retrieves the value of the given key as it was when taking the snapshot.
+	Get(key []byte) ([]byte, error)
+
+	// Range returns an iterator over the snapshot entries with keys in [start, limit).
+	Range(start, limit []byte) iterator.Iterator
+
+	// Release releases the resources associated with the snapshot.
+	Release()
+}
+
 type Batch interface {
 	Put(key, value []byte) error
 	Write() error
@@ -17,10 +17,14 @@
 package ethdb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/networkchain/networkchain/common"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 /*
@@ -82,6 +86,71 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+// NewIteratorWithPrefix returns an iterator over a snapshot of the entries whose
+// keys start with the given prefix.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var entries memEntries
+	for key, value := range db.db {
+		if strings.HasPrefix(key, string(prefix)) {
+			entries = append(entries, kv{[]byte(key), common.CopyBytes(value)})
+		}
+	}
+	sort.Sort(entries)
+	return iterator.NewArrayIterator(entries)
+}
+
+// Range returns an iterator over a snapshot of the entries with keys in
+// [start, limit).
+func (db *MemDatabase) Range(start, limit []byte) iterator.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return iterator.NewArrayIterator(collectRange(db.db, start, limit))
+}
+
+// DeleteRange deletes all the entries with keys in [start, limit).
+func (db *MemDatabase) DeleteRange(start, limit []byte) (int, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	entries := collectRange(db.db, start, limit)
+	for _, entry := range entries {
+		delete(db.db, string(entry.k))
+	}
+	return len(entries), nil
+}
+
+// NewSnapshot creates a copy of the current content of the database.
+func (db *MemDatabase) NewSnapshot() (Snapshot, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	snap := &memSnapshot{db: MemDatabase{db: make(map[string][]byte, len(db.db))}}
+	for key, value := range db.db {
+		snap.db.db[key] = value
+	}
+	return snap, nil
+}
+
+// collectRange gathers the entries with keys in [start, limit) in key order.
+func collectRange(db map[string][]byte, start, limit []byte) memEntries {
+	var entries memEntries
+	for key, value := range db {
+		if start != nil && key < string(start) {
+			continue
+		}
+		if limit != nil && key >= string(limit) {
+			continue
+		}
+		entries = append(entries, kv{[]byte(key), common.CopyBytes(value)})
+	}
+	sort.Sort(entries)
+	return entries
+}
+
 func (db *MemDatabase) Close() {}
 
 func (db *MemDatabase) NewBatch() Batch {
@@ -90,6 +159,19 @@ func (db *MemDatabase) NewBatch() Batch {
 
 type kv struct{ k, v []byte }
 
+// memEntries is a key ordered list of database entries, usable as the backing
+// array of an iterator.
+type memEntries []kv
+
+func (e memEntries) Len() int                        { return len(e) }
+func (e memEntries) Less(i, j int) bool              { return bytes.Compare(e[i].k, e[j].k) < 0 }
+func (e memEntries) Swap(i, j int)                   { e[i], e[j] = e[j], e[i] }
+func (e memEntries) Index(i int) (key, value []byte) { return e[i].k, e[i].v }
+
+func (e memEntries) Search(key []byte) int {
+	return sort.Search(len(e), func(i int) bool { return bytes.Compare(e[i].k, key) >= 0 })
+}
+
 type memBatch struct {
 	db     *MemDatabase
 	writes []kv
@@ -116,3 +198,18 @@ func (b *memBatch) Write() error {
 	}
 	return nil
 }
+
+// memSnapshot is a point-in-time copy of a memory database.
+type memSnapshot struct {
+	db MemDatabase
+}
+
+func (s *memSnapshot) Get(key []byte) ([]byte, error) { return s.db.Get(key) }
+
+func (s *memSnapshot) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	return s.db.NewIteratorWithPrefix(prefix)
+}
+
+func (s *memSnapshot) Range(start, limit []byte) iterator.Iterator { return s.db.Range(start, limit) }
+
+func (s *memSnapshot) Release() {}
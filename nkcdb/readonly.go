@@ -0,0 +1,177 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// snapshotRetries is the number of times a snapshot of a database in use by
+// another process is attempted before giving up. Background compactions of the
+// owning process may delete files while they are being linked, so a few tries
+// might be needed on a busy database.
+const snapshotRetries = 5
+
+// NewLDBDatabaseReadOnly opens an existing LevelDB database without modifying it
+// in any way. If the database is locked by another process (e.g. a running node),
+// a point-in-time snapshot of it is taken and opened instead, providing a stable
+// view of the data without interfering with the owner. The snapshot is removed
+// when the database is closed.
+func NewLDBDatabaseReadOnly(file string, cache int, handles int) (*LDBDatabase, error) {
+	logger := log.New("database", file)
+
+	// Ensure we have some minimal caching and file guarantees
+	if cache < 16 {
+		cache = 16
+	}
+	if handles < 16 {
+		handles = 16
+	}
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+	options := &opt.Options{
+		OpenFilesCacheCapacity: handles,
+		BlockCacheCapacity:     cache / 2 * opt.MiB,
+		Filter:                 filter.NewBloomFilter(10),
+		ReadOnly:               true,
+	}
+	// Try to open the database directly, snapshotting if someone else holds it
+	db, err := leveldb.OpenFile(file, options)
+	if err == nil {
+		logger.Info("Opened database read-only")
+		return &LDBDatabase{fn: file, db: db, log: logger}, nil
+	}
+	logger.Debug("Database unavailable, snapshotting", "err", err)
+
+	for i := 0; i < snapshotRetries; i++ {
+		var snapshot string
+		if snapshot, err = snapshotLDB(file); err != nil {
+			logger.Debug("Failed to snapshot database", "attempt", i+1, "err", err)
+			continue
+		}
+		if db, err = leveldb.OpenFile(snapshot, options); err != nil {
+			logger.Debug("Failed to open database snapshot", "attempt", i+1, "err", err)
+			os.RemoveAll(snapshot)
+			continue
+		}
+		logger.Info("Opened database snapshot read-only", "snapshot", snapshot)
+		return &LDBDatabase{fn: file, db: db, snapshot: snapshot, log: logger}, nil
+	}
+	return nil, fmt.Errorf("failed to snapshot database in use: %v", err)
+}
+
+// snapshotLDB creates a consistent copy of a LevelDB database that might be
+// concurrently modified by another process, returning the path to the copy.
+//
+// Table files are immutable once written, so they are hard linked into the copy
+// (falling back to real copies if linking is not possible). The small mutable
+// files are copied: first the write-ahead journals, then the manifest describing
+// the live tables. As the owner only drops a journal after recording its flush in
+// the manifest, every entry is covered by one of the two. Tables deleted by a
+// compaction in between result in an error, and the snapshot should be retried.
+func snapshotLDB(file string) (string, error) {
+	// Place the snapshot next to the database so that tables can be linked,
+	// resorting to the system temp directory if that's not writable
+	snapshot, err := ioutil.TempDir(filepath.Dir(file), filepath.Base(file)+"-snapshot-")
+	if err != nil {
+		if snapshot, err = ioutil.TempDir("", "ldb-snapshot-"); err != nil {
+			return "", err
+		}
+	}
+	if err := snapshotFiles(file, snapshot); err != nil {
+		os.RemoveAll(snapshot)
+		return "", err
+	}
+	return snapshot, nil
+}
+
+// snapshotFiles copies the files of a LevelDB database from src into dst in the
+// order documented on snapshotLDB.
+func snapshotFiles(src, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	// Copy the journals first so no flushed out data can go missing
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".log") && entry.Name() != "LOG" {
+			if err := copyFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	// Copy the manifest currently in use, along with the pointer to it
+	current, err := ioutil.ReadFile(filepath.Join(src, "CURRENT"))
+	if err != nil {
+		return err
+	}
+	manifest := strings.TrimSpace(string(current))
+	if !strings.HasPrefix(manifest, "MANIFEST-") {
+		return fmt.Errorf("invalid CURRENT file content %q", current)
+	}
+	if err := copyFile(filepath.Join(src, manifest), filepath.Join(dst, manifest)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dst, "CURRENT"), current, 0644); err != nil {
+		return err
+	}
+	// Link all the tables, including the ones the manifest is not aware of yet
+	entries, err = ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, ".ldb") || strings.HasSuffix(name, ".sst") {
+			if err := os.Link(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+				if err := copyFile(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// copyFile copies the content of the file src into a newly created file dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
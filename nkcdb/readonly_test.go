@@ -0,0 +1,126 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/networkchain/networkchain/common"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Tests that a database locked by a live instance can be opened read-only from
+// a snapshot, isolated from subsequent modifications.
+func TestReadOnlySnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ldbreadonly-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "chaindata")
+	db, err := NewLDBDatabase(file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	// Write some data, flushing part of it into tables and keeping the rest in the journal
+	for i := 0; i < 100; i++ {
+		db.Put([]byte(fmt.Sprintf("flushed-%03d", i)), bytes.Repeat([]byte{byte(i)}, 1024))
+	}
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		t.Fatalf("failed to compact database: %v", err)
+	}
+	db.Put([]byte("journaled"), []byte("value"))
+
+	// Open the locked database read-only and check the contents
+	rodb, err := NewLDBDatabaseReadOnly(file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database read-only: %v", err)
+	}
+	if rodb.snapshot == "" {
+		t.Fatalf("locked database opened without snapshotting")
+	}
+	db.Put([]byte("later"), []byte("value"))
+
+	for i := 0; i < 100; i++ {
+		if val, err := rodb.Get([]byte(fmt.Sprintf("flushed-%03d", i))); err != nil || !bytes.Equal(val, bytes.Repeat([]byte{byte(i)}, 1024)) {
+			t.Errorf("flushed item %d mismatch: %x, %v", i, val, err)
+		}
+	}
+	if val, err := rodb.Get([]byte("journaled")); err != nil || string(val) != "value" {
+		t.Errorf("journaled item mismatch: %q, %v", val, err)
+	}
+	if _, err := rodb.Get([]byte("later")); err == nil {
+		t.Errorf("item written after snapshot visible")
+	}
+	if err := rodb.Put([]byte("key"), []byte("value")); err == nil {
+		t.Errorf("read-only database accepted write")
+	}
+	snapshot := rodb.snapshot
+	rodb.Close()
+	if _, err := os.Stat(snapshot); !os.IsNotExist(err) {
+		t.Errorf("snapshot not removed on close: %v", err)
+	}
+	// The original database must remain usable by its owner
+	if val, err := db.Get([]byte("later")); err != nil || string(val) != "value" {
+		t.Errorf("owner item mismatch: %q, %v", val, err)
+	}
+}
+
+// Tests that an unlocked database is opened in place and a missing one is not
+// created.
+func TestReadOnlyOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ldbreadonly-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := NewLDBDatabaseReadOnly(filepath.Join(dir, "missing"), 0, 0); err == nil {
+		t.Fatalf("missing database opened")
+	}
+	if common.FileExist(filepath.Join(dir, "missing")) {
+		t.Errorf("missing database created")
+	}
+	file := filepath.Join(dir, "chaindata")
+	db, err := NewLDBDatabase(file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	db.Close()
+
+	rodb, err := NewLDBDatabaseReadOnly(file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database read-only: %v", err)
+	}
+	defer rodb.Close()
+
+	if rodb.snapshot != "" {
+		t.Errorf("unlocked database snapshotted")
+	}
+	if val, err := rodb.Get([]byte("key")); err != nil || string(val) != "value" {
+		t.Errorf("item mismatch: %q, %v", val, err)
+	}
+}
@@ -18,16 +18,24 @@
 package ethstats
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"net"
+	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/networkchain/networkchain/common"
@@ -59,6 +67,23 @@ type Service struct {
 	les    *les.LightNetworkChain // Light NetworkChain service if monitoring a light node
 	engine consensus.Engine   // Consensus engine to retrieve variadic block fields
 
+	reporters []*reporter // Reporters pushing the stats to the monitoring servers
+	pin       []byte      // SHA256 fingerprint of the pinned server certificate (nil = CA verification)
+
+	file     string       // Local file to write the stats snapshots into
+	socket   string       // Local unix socket to serve the stats snapshots on
+	listener net.Listener // Listener serving the stats snapshots on the unix socket
+
+	snapshot   []byte       // Latest JSON encoded stats snapshot
+	snapshotMu sync.RWMutex // Protects the latest stats snapshot
+
+	spawn func(name string, routine func()) // Runs the long lived reporting routines
+}
+
+// reporter pushes the local chain statistics up to a single monitoring server.
+type reporter struct {
+	*Service
+
 	node string // Name of the node to display on the monitoring page
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
@@ -67,31 +92,96 @@ type Service struct {
 	histCh chan []uint64 // History request block numbers are fed into this channel
 }
 
-// New returns a monitoring service ready for stats reporting.
+// New returns a monitoring service ready for stats reporting. The url may list
+// multiple comma separated monitoring servers, all of which are reported to.
 func New(url string, ethServ *eth.NetworkChain, lesServ *les.LightNetworkChain) (*Service, error) {
-	// Parse the netstats connection url
-	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
-	parts := re.FindStringSubmatch(url)
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
-	}
-	// Assemble and return the stats service
+	// Assemble the stats service
 	var engine consensus.Engine
-	if ethServ != nil {
+	switch {
+	case ethServ != nil:
 		engine = ethServ.Engine()
-	} else {
+	case lesServ != nil:
 		engine = lesServ.Engine()
+	default:
+		return nil, errors.New("no networkchain service to report")
 	}
-	return &Service{
+	service := &Service{
 		eth:    ethServ,
 		les:    lesServ,
 		engine: engine,
-		node:   parts[1],
-		pass:   parts[3],
-		host:   parts[4],
-		pongCh: make(chan struct{}),
-		histCh: make(chan []uint64, 1),
-	}, nil
+		spawn:  func(name string, routine func()) { go routine() },
+	}
+	// Parse the netstats connection urls and create a reporter for each
+	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
+	for _, url := range strings.Split(url, ",") {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		parts := re.FindStringSubmatch(url)
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
+		}
+		service.reporters = append(service.reporters, &reporter{
+			Service: service,
+			node:    parts[1],
+			pass:    parts[3],
+			host:    parts[4],
+			pongCh:  make(chan struct{}),
+			histCh:  make(chan []uint64, 1),
+		})
+	}
+	return service, nil
+}
+
+// ExportFile requests the stats snapshots to be written as JSON into a local
+// file too, replacing it atomically on every update. It must be called before
+// the service is started.
+func (s *Service) ExportFile(path string) {
+	s.file = path
+}
+
+// ExportSocket requests the latest stats snapshot to be served as JSON to any
+// client connecting to a local unix socket. It must be called before the service
+// is started.
+func (s *Service) ExportSocket(path string) {
+	s.socket = path
+}
+
+// Supervise requests the long lived reporting routines to be run through the
+// given spawner (e.g. node.ServiceContext.Go), restarting the service should they
+// crash. It must be called before the service is started.
+func (s *Service) Supervise(spawn func(name string, routine func())) {
+	s.spawn = spawn
+}
+
+// PinCertificate restricts the stats reporting to servers presenting a TLS
+// certificate with the given SHA256 fingerprint, skipping the usual certificate
+// authority verification (e.g. to report to internal servers with self signed
+// certificates). Unless explicitly requested otherwise in the url, connections
+// are only attempted over TLS. It must be called before the service is started.
+func (s *Service) PinCertificate(fingerprint []byte) error {
+	if len(fingerprint) != sha256.Size {
+		return fmt.Errorf("invalid certificate fingerprint length: have %d, want %d", len(fingerprint), sha256.Size)
+	}
+	s.pin = common.CopyBytes(fingerprint)
+	return nil
+}
+
+// tlsConfig returns the TLS configuration verifying that the server presents
+// the pinned certificate.
+func (s *Service) tlsConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true, // Replaced by the certificate pinning below
+		VerifyPeerCertificate: func(certs [][]byte, _ [][]*x509.Certificate) error {
+			if len(certs) == 0 {
+				return errors.New("no server certificate")
+			}
+			if fingerprint := sha256.Sum256(certs[0]); !bytes.Equal(fingerprint[:], s.pin) {
+				return fmt.Errorf("server certificate fingerprint mismatch: %x", fingerprint)
+			}
+			return nil
+		},
+	}
 }
 
 // Protocols implements node.Service, returning the P2P network protocols used
@@ -105,21 +195,38 @@ func (s *Service) APIs() []rpc.API { return nil }
 // Start implements node.Service, starting up the monitoring and reporting daemon.
 func (s *Service) Start(server *p2p.Server) error {
 	s.server = server
-	go s.loop()
-
-	log.Info("Stats daemon started")
+	for _, r := range s.reporters {
+		s.spawn("ethstats/"+r.host, r.loop)
+	}
+	if s.socket != "" {
+		os.Remove(s.socket)
+		listener, err := net.Listen("unix", s.socket)
+		if err != nil {
+			return err
+		}
+		s.listener = listener
+		go s.serveSnapshots(listener)
+	}
+	if s.file != "" || s.socket != "" {
+		s.spawn("ethstats/export", s.exportLoop)
+	}
+	log.Info("Stats daemon started", "servers", len(s.reporters), "file", s.file, "socket", s.socket)
 	return nil
 }
 
 // Stop implements node.Service, terminating the monitoring and reporting daemon.
 func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		os.Remove(s.socket)
+	}
 	log.Info("Stats daemon stopped")
 	return nil
 }
 
 // loop keeps trying to connect to the netstats server, reporting chain events
 // until termination.
-func (s *Service) loop() {
+func (s *reporter) loop() {
 	// Subscribe to chain events to execute updates on
 	var emux *event.TypeMux
 	if s.eth != nil {
@@ -181,6 +288,9 @@ func (s *Service) loop() {
 
 		if !strings.Contains(path, "://") { // url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
 			urls = []string{"wss://" + path, "ws://" + path}
+			if s.pin != nil {
+				urls = urls[:1] // Never downgrade a pinned connection to plain text
+			}
 		}
 		// Establish a websocket connection to the server on any supported URL
 		var (
@@ -193,6 +303,9 @@ func (s *Service) loop() {
 				continue
 			}
 			conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
+			if s.pin != nil {
+				conf.TlsConfig = s.tlsConfig()
+			}
 			if conn, err = websocket.DialConfig(conf); err == nil {
 				break
 			}
@@ -256,7 +369,7 @@ func (s *Service) loop() {
 // from the network socket. If any of them match an active request, it forwards
 // it, if they themselves are requests it initiates a reply, and lastly it drops
 // unknown packets.
-func (s *Service) readLoop(conn *websocket.Conn) {
+func (s *reporter) readLoop(conn *websocket.Conn) {
 	// If the read loop exists, close the connection
 	defer conn.Close()
 
@@ -347,7 +460,7 @@ type authMsg struct {
 }
 
 // login tries to authorize the client at the remote server.
-func (s *Service) login(conn *websocket.Conn) error {
+func (s *reporter) login(conn *websocket.Conn) error {
 	// Construct and send the login authentication
 	infos := s.server.NodeInfo()
 
@@ -392,7 +505,7 @@ func (s *Service) login(conn *websocket.Conn) error {
 // report collects all possible data to report and send it to the stats server.
 // This should only be used on reconnects or rarely to avoid overloading the
 // server. Use the individual methods for reporting subscribed events.
-func (s *Service) report(conn *websocket.Conn) error {
+func (s *reporter) report(conn *websocket.Conn) error {
 	if err := s.reportLatency(conn); err != nil {
 		return err
 	}
@@ -410,7 +523,7 @@ func (s *Service) report(conn *websocket.Conn) error {
 
 // reportLatency sends a ping request to the server, measures the RTT time and
 // finally sends a latency update.
-func (s *Service) reportLatency(conn *websocket.Conn) error {
+func (s *reporter) reportLatency(conn *websocket.Conn) error {
 	// Send the current time to the ethstats server
 	start := time.Now()
 
@@ -479,7 +592,7 @@ func (s uncleStats) MarshalJSON() ([]byte, error) {
 }
 
 // reportBlock retrieves the current chain head and repors it to the stats server.
-func (s *Service) reportBlock(conn *websocket.Conn, block *types.Block) error {
+func (s *reporter) reportBlock(conn *websocket.Conn, block *types.Block) error {
 	// Gather the block details from the header or block chain
 	details := s.assembleBlockStats(block)
 
@@ -551,7 +664,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 
 // reportHistory retrieves the most recent batch of blocks and reports it to the
 // stats server.
-func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
+func (s *reporter) reportHistory(conn *websocket.Conn, list []uint64) error {
 	// Figure out the indexes that need reporting
 	indexes := make([]uint64, 0, historyUpdateRange)
 	if len(list) > 0 {
@@ -616,22 +729,14 @@ type pendStats struct {
 
 // reportPending retrieves the current number of pending transactions and reports
 // it to the stats server.
-func (s *Service) reportPending(conn *websocket.Conn) error {
-	// Retrieve the pending count from the local blockchain
-	var pending int
-	if s.eth != nil {
-		pending, _ = s.eth.TxPool().Stats()
-	} else {
-		pending = s.les.TxPool().Stats()
-	}
+func (s *reporter) reportPending(conn *websocket.Conn) error {
 	// Assemble the transaction stats and send it to the server
-	log.Trace("Sending pending transactions to ethstats", "count", pending)
+	details := s.assemblePendingStats()
+	log.Trace("Sending pending transactions to ethstats", "count", details.Pending)
 
 	stats := map[string]interface{}{
-		"id": s.node,
-		"stats": &pendStats{
-			Pending: pending,
-		},
+		"id":    s.node,
+		"stats": details,
 	}
 	report := map[string][]interface{}{
 		"emit": {"pending", stats},
@@ -639,6 +744,19 @@ func (s *Service) reportPending(conn *websocket.Conn) error {
 	return websocket.JSON.Send(conn, report)
 }
 
+// assemblePendingStats retrieves the current number of pending transactions.
+func (s *Service) assemblePendingStats() *pendStats {
+	var pending int
+	if s.eth != nil {
+		pending, _ = s.eth.TxPool().Stats()
+	} else {
+		pending = s.les.TxPool().Stats()
+	}
+	return &pendStats{
+		Pending: pending,
+	}
+}
+
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
 	Active   bool `json:"active"`
@@ -650,9 +768,25 @@ type nodeStats struct {
 	Uptime   int  `json:"uptime"`
 }
 
-// reportPending retrieves various stats about the node at the networking and
+// reportStats retrieves various stats about the node at the networking and
 // mining layer and reports it to the stats server.
-func (s *Service) reportStats(conn *websocket.Conn) error {
+func (s *reporter) reportStats(conn *websocket.Conn) error {
+	// Assemble the node stats and send it to the server
+	log.Trace("Sending node details to ethstats")
+
+	stats := map[string]interface{}{
+		"id":    s.node,
+		"stats": s.assembleNodeStats(),
+	}
+	report := map[string][]interface{}{
+		"emit": {"stats", stats},
+	}
+	return websocket.JSON.Send(conn, report)
+}
+
+// assembleNodeStats retrieves various stats about the node at the networking
+// and mining layer.
+func (s *Service) assembleNodeStats() *nodeStats {
 	// Gather the syncing and mining infos from the local miner instance
 	var (
 		mining   bool
@@ -673,23 +807,108 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		sync := s.les.Downloader().Progress()
 		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
 	}
-	// Assemble the node stats and send it to the server
-	log.Trace("Sending node details to ethstats")
+	return &nodeStats{
+		Active:   true,
+		Mining:   mining,
+		Hashrate: hashrate,
+		Peers:    s.server.PeerCount(),
+		GasPrice: gasprice,
+		Syncing:  syncing,
+		Uptime:   100,
+	}
+}
 
-	stats := map[string]interface{}{
-		"id": s.node,
-		"stats": &nodeStats{
-			Active:   true,
-			Mining:   mining,
-			Hashrate: hashrate,
-			Peers:    s.server.PeerCount(),
-			GasPrice: gasprice,
-			Syncing:  syncing,
-			Uptime:   100,
-		},
+// localStats is the stats snapshot exported locally to a file or unix socket.
+type localStats struct {
+	Block   *blockStats `json:"block"`
+	Pending *pendStats  `json:"pending"`
+	Stats   *nodeStats  `json:"stats"`
+	Updated time.Time   `json:"updated"`
+}
+
+// exportLoop refreshes the local stats snapshot on every new chain head and
+// periodically, until the node is stopped.
+func (s *Service) exportLoop() {
+	var emux *event.TypeMux
+	if s.eth != nil {
+		emux = s.eth.EventMux()
+	} else {
+		emux = s.les.EventMux()
 	}
-	report := map[string][]interface{}{
-		"emit": {"stats", stats},
+	headSub := emux.Subscribe(core.ChainHeadEvent{})
+	defer headSub.Unsubscribe()
+
+	refresh := time.NewTicker(15 * time.Second)
+	defer refresh.Stop()
+
+	s.export(nil)
+	for {
+		select {
+		case head, ok := <-headSub.Chan():
+			if !ok { // node stopped
+				return
+			}
+			s.export(head.Data.(core.ChainHeadEvent).Block)
+		case <-refresh.C:
+			s.export(nil)
+		}
 	}
-	return websocket.JSON.Send(conn, report)
+}
+
+// export assembles a stats snapshot around the given block (or the current head
+// if nil) and publishes it to the local file and unix socket.
+func (s *Service) export(block *types.Block) {
+	blob, err := json.Marshal(&localStats{
+		Block:   s.assembleBlockStats(block),
+		Pending: s.assemblePendingStats(),
+		Stats:   s.assembleNodeStats(),
+		Updated: time.Now(),
+	})
+	if err != nil {
+		log.Warn("Failed to encode stats snapshot", "err", err)
+		return
+	}
+	s.snapshotMu.Lock()
+	s.snapshot = blob
+	s.snapshotMu.Unlock()
+
+	if s.file != "" {
+		if err := writeFileAtomic(s.file, blob); err != nil {
+			log.Warn("Failed to write stats snapshot", "file", s.file, "err", err)
+		}
+	}
+}
+
+// serveSnapshots accepts connections on the unix socket, sending the latest stats
+// snapshot to each before closing the connection.
+func (s *Service) serveSnapshots(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return // listener closed
+		}
+		s.snapshotMu.RLock()
+		blob := s.snapshot
+		s.snapshotMu.RUnlock()
+
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		conn.Write(blob)
+		conn.Close()
+	}
+}
+
+// writeFileAtomic writes the data into a temporary file next to the target and
+// renames it over the target, so readers never see partial snapshots.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), path)
 }
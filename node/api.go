@@ -23,6 +23,7 @@ import (
 
 	"github.com/networkchain/networkchain/common/hexutil"
 	"github.com/networkchain/networkchain/crypto"
+	"github.com/networkchain/networkchain/internal/version"
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
 	"github.com/rcrowley/go-metrics"
@@ -203,14 +204,27 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// NodeInfo represents a short summary of the information known about the host
+// node, extended with the crash statistics of its services and the version
+// control metadata of the running binary.
+type NodeInfo struct {
+	*p2p.NodeInfo
+	Services map[string]SupervisedInfo `json:"services"`
+	Build    version.Info              `json:"build"`
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
-func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
+func (api *PublicAdminAPI) NodeInfo() (*NodeInfo, error) {
 	server := api.node.Server()
 	if server == nil {
 		return nil, ErrNodeStopped
 	}
-	return server.NodeInfo(), nil
+	return &NodeInfo{
+		NodeInfo: server.NodeInfo(),
+		Services: api.node.Supervised(),
+		Build:    version.Get(),
+	}, nil
 }
 
 // Datadir retrieves the current data directory the node is using.
@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -33,16 +34,22 @@ import (
 	"github.com/networkchain/networkchain/log"
 	"github.com/networkchain/networkchain/p2p"
 	"github.com/networkchain/networkchain/p2p/discover"
+	"github.com/pborman/uuid"
 )
 
 var (
 	datadirPrivateKey      = "nodekey"            // Path within the datadir to the node's private key
+	datadirEncryptedKey    = "nodekey.json"       // Path within the datadir to the node's encrypted private key
 	datadirDefaultKeyStore = "keystore"           // Path within the datadir to the keystore
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
 )
 
+// machineIDPaths are the files checked in order for the identity of the host
+// machine, which machine bound node keys are encrypted with.
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
 // Config represents a small collection of configuration values to fine tune the
 // P2P network layer of a protocol stack. These values can be further extended by
 // all registered services.
@@ -69,6 +76,22 @@ type Config struct {
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
+	// NodeKeyPassphrase, if set, is used to encrypt the node's private key at rest
+	// within the data directory. Any previously persisted plaintext key is migrated
+	// over to the encrypted format and removed.
+	//
+	// Only the node key is covered, being the sole secret of the data directory
+	// outside of the key store: light servers identify with the node key too, and
+	// account keys are encrypted by the key store on their own.
+	NodeKeyPassphrase string `toml:"-"`
+
+	// NodeKeyMachineBound encrypts the node key at rest with a passphrase derived
+	// from the identity of the host machine if no passphrase is configured, so a
+	// copied data directory doesn't carry a usable node key to another machine.
+	// It doesn't protect against anyone able to read the machine identity, and is
+	// only supported on hosts providing one (systemd or D-Bus machine id).
+	NodeKeyMachineBound bool `toml:",omitempty"`
+
 	// KeyStoreDir is the file system folder that contains private keys. The directory can
 	// be specified as a relative path, in which case it is resolved relative to the
 	// current directory.
@@ -275,11 +298,12 @@ func (c *Config) instanceDir() string {
 
 // NodeKey retrieves the currently configured private key of the node, checking
 // first any manually set key, falling back to the one found in the configured
-// data folder. If no key can be found, a new one is generated.
-func (c *Config) NodeKey() *ecdsa.PrivateKey {
+// data folder. If no key can be found, a new one is generated. An encrypted key
+// found without a configured passphrase results in ErrNodeKeyLocked.
+func (c *Config) NodeKey() (*ecdsa.PrivateKey, error) {
 	// Use any specifically configured key.
 	if c.P2P.PrivateKey != nil {
-		return c.P2P.PrivateKey
+		return c.P2P.PrivateKey, nil
 	}
 	// Generate ephemeral key if no datadir is being used.
 	if c.DataDir == "" {
@@ -287,12 +311,26 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 		if err != nil {
 			log.Crit(fmt.Sprintf("Failed to generate ephemeral node key: %v", err))
 		}
-		return key
+		return key, nil
+	}
+	// Load or create the encrypted key if a passphrase was configured
+	if c.NodeKeyPassphrase != "" {
+		return c.encryptedNodeKey(c.NodeKeyPassphrase)
+	}
+	if c.NodeKeyMachineBound {
+		passphrase, err := machinePassphrase()
+		if err != nil {
+			return nil, err
+		}
+		return c.encryptedNodeKey(passphrase)
+	}
+	// Refuse to replace an encrypted identity with a new plaintext one
+	if _, err := os.Stat(c.resolvePath(datadirEncryptedKey)); err == nil {
+		return nil, ErrNodeKeyLocked
 	}
-
 	keyfile := c.resolvePath(datadirPrivateKey)
 	if key, err := crypto.LoadECDSA(keyfile); err == nil {
-		return key
+		return key, nil
 	}
 	// No persistent key found, generate and store a new one.
 	key, err := crypto.GenerateKey()
@@ -302,13 +340,78 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	instanceDir := filepath.Join(c.DataDir, c.name())
 	if err := os.MkdirAll(instanceDir, 0700); err != nil {
 		log.Error(fmt.Sprintf("Failed to persist node key: %v", err))
-		return key
+		return key, nil
 	}
 	keyfile = filepath.Join(instanceDir, datadirPrivateKey)
 	if err := crypto.SaveECDSA(keyfile, key); err != nil {
 		log.Error(fmt.Sprintf("Failed to persist node key: %v", err))
 	}
-	return key
+	return key, nil
+}
+
+// encryptedNodeKey retrieves the node key from its passphrase encrypted form in
+// the data directory. If no encrypted key exists yet, any plaintext key is taken
+// over (and removed), or a new key is generated otherwise.
+func (c *Config) encryptedNodeKey(passphrase string) (*ecdsa.PrivateKey, error) {
+	keyfile := c.resolvePath(datadirEncryptedKey)
+	if keyjson, err := ioutil.ReadFile(keyfile); err == nil {
+		key, err := keystore.DecryptKey(keyjson, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt node key: %v", err)
+		}
+		return key.PrivateKey, nil
+	}
+	// No encrypted key found, migrate any plaintext one or generate a new one
+	plainfile := c.resolvePath(datadirPrivateKey)
+	key, err := crypto.LoadECDSA(plainfile)
+	if err != nil {
+		if key, err = crypto.GenerateKey(); err != nil {
+			log.Crit(fmt.Sprintf("Failed to generate node key: %v", err))
+		}
+		plainfile = ""
+	}
+	instanceDir := filepath.Join(c.DataDir, c.name())
+	if err := os.MkdirAll(instanceDir, 0700); err != nil {
+		log.Error(fmt.Sprintf("Failed to persist node key: %v", err))
+		return key, nil
+	}
+	scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+	if c.UseLightweightKDF {
+		scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	}
+	keyjson, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.NewRandom(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, passphrase, scryptN, scryptP)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to encrypt node key: %v", err))
+		return key, nil
+	}
+	if err := ioutil.WriteFile(filepath.Join(instanceDir, datadirEncryptedKey), keyjson, 0600); err != nil {
+		log.Error(fmt.Sprintf("Failed to persist node key: %v", err))
+		return key, nil
+	}
+	if plainfile != "" {
+		if err := os.Remove(plainfile); err != nil {
+			log.Error(fmt.Sprintf("Failed to remove plaintext node key: %v", err))
+		} else {
+			log.Info("Encrypted plaintext node key", "path", plainfile)
+		}
+	}
+	return key, nil
+}
+
+// machinePassphrase derives the passphrase of a machine bound node key from the
+// identity of the host machine.
+func machinePassphrase() (string, error) {
+	for _, path := range machineIDPaths {
+		if id, err := ioutil.ReadFile(path); err == nil && len(strings.TrimSpace(string(id))) > 0 {
+			hash := crypto.Keccak256([]byte("networkchain node key"), []byte(strings.TrimSpace(string(id))))
+			return common.Bytes2Hex(hash), nil
+		}
+	}
+	return "", errors.New("machine identity unavailable, node key can't be machine bound")
 }
 
 // StaticNodes returns a list of node enode URLs configured as static nodes.
@@ -398,6 +501,11 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		} else {
 			backends = append(backends, ledgerhub)
 		}
+		if trezorhub, err := usbwallet.NewTrezorHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Trezor hub, disabling: %v", err))
+		} else {
+			backends = append(backends, trezorhub)
+		}
 	}
 	return accounts.NewManager(backends...), ephemeral, nil
 }
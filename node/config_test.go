@@ -18,6 +18,7 @@ package node
 
 import (
 	"bytes"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -148,3 +149,98 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that node keys can be encrypted at rest, migrating plaintext keys over.
+func TestNodeKeyEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	plainfile := filepath.Join(dir, "unit-test", datadirPrivateKey)
+	keyfile := filepath.Join(dir, "unit-test", datadirEncryptedKey)
+
+	// Create a plaintext key and ensure it's migrated to the encrypted format
+	config := &Config{Name: "unit-test", DataDir: dir}
+	key, err := config.NodeKey()
+	if err != nil {
+		t.Fatalf("failed to create plaintext node key: %v", err)
+	}
+	config = &Config{Name: "unit-test", DataDir: dir, NodeKeyPassphrase: "foobar", UseLightweightKDF: true}
+	if migrated, _ := config.NodeKey(); !bytes.Equal(crypto.FromECDSA(migrated), crypto.FromECDSA(key)) {
+		t.Fatalf("migrated node key mismatch: have %x, want %x", crypto.FromECDSA(migrated), crypto.FromECDSA(key))
+	}
+	if _, err := os.Stat(plainfile); err == nil {
+		t.Fatalf("plaintext node key not removed")
+	}
+	blob, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		t.Fatalf("encrypted node key not persisted: %v", err)
+	}
+	if bytes.Contains(blob, []byte(hex.EncodeToString(crypto.FromECDSA(key)))) {
+		t.Fatalf("node key persisted in plaintext")
+	}
+	// Ensure the encrypted key is loaded on subsequent runs
+	config = &Config{Name: "unit-test", DataDir: dir, NodeKeyPassphrase: "foobar", UseLightweightKDF: true}
+	if loaded, _ := config.NodeKey(); !bytes.Equal(crypto.FromECDSA(loaded), crypto.FromECDSA(key)) {
+		t.Fatalf("loaded node key mismatch: have %x, want %x", crypto.FromECDSA(loaded), crypto.FromECDSA(key))
+	}
+	// Ensure a wrong or missing passphrase is refused instead of replacing the key
+	config = &Config{Name: "unit-test", DataDir: dir, NodeKeyPassphrase: "barfoo", UseLightweightKDF: true}
+	if _, err := config.NodeKey(); err == nil {
+		t.Fatalf("node key decrypted with wrong passphrase")
+	}
+	config = &Config{Name: "unit-test", DataDir: dir}
+	if _, err := config.NodeKey(); err != ErrNodeKeyLocked {
+		t.Fatalf("encrypted node key error mismatch: have %v, want %v", err, ErrNodeKeyLocked)
+	}
+	if _, err := os.Stat(plainfile); err == nil {
+		t.Fatalf("plaintext node key generated next to the encrypted one")
+	}
+}
+
+// Tests that node keys can be bound to the identity of the host machine, being
+// unusable on any other.
+func TestNodeKeyMachineBound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	machineID := filepath.Join(dir, "machine-id")
+	if err := ioutil.WriteFile(machineID, []byte("0123456789abcdef\n"), 0644); err != nil {
+		t.Fatalf("failed to write machine id: %v", err)
+	}
+	defer func(paths []string) { machineIDPaths = paths }(machineIDPaths)
+	machineIDPaths = []string{filepath.Join(dir, "missing"), machineID}
+
+	// Create a machine bound key and ensure it's persisted encrypted only
+	config := &Config{Name: "unit-test", DataDir: dir, NodeKeyMachineBound: true, UseLightweightKDF: true}
+	key, err := config.NodeKey()
+	if err != nil {
+		t.Fatalf("failed to create machine bound node key: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", datadirPrivateKey)); err == nil {
+		t.Fatalf("machine bound node key persisted in plaintext")
+	}
+	if loaded, err := config.NodeKey(); err != nil || !bytes.Equal(crypto.FromECDSA(loaded), crypto.FromECDSA(key)) {
+		t.Fatalf("loaded node key mismatch: have %x, want %x (err %v)", crypto.FromECDSA(loaded), crypto.FromECDSA(key), err)
+	}
+	// Ensure the key can't be loaded on another machine, nor without the binding
+	if err := ioutil.WriteFile(machineID, []byte("fedcba9876543210\n"), 0644); err != nil {
+		t.Fatalf("failed to update machine id: %v", err)
+	}
+	if _, err := config.NodeKey(); err == nil {
+		t.Fatalf("node key decrypted on another machine")
+	}
+	if _, err := (&Config{Name: "unit-test", DataDir: dir}).NodeKey(); err != ErrNodeKeyLocked {
+		t.Fatalf("machine bound node key error mismatch: have %v, want %v", err, ErrNodeKeyLocked)
+	}
+	// Ensure hosts without a machine identity are refused
+	machineIDPaths = []string{filepath.Join(dir, "missing")}
+	if _, err := config.NodeKey(); err == nil {
+		t.Fatalf("node key bound to a missing machine identity")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// namespaceRegexp is the format custom RPC namespaces must adhere to.
+var namespaceRegexp = regexp.MustCompile("^[a-z][a-z0-9]*$")
+
+// RegisterAPIs injects custom RPC services provided by the embedding code into the
+// node's API endpoints. The namespaces must be lowercase identifiers not already
+// served by the node or any of its services, so that built in methods cannot be
+// shadowed. Similar to the built in APIs, custom ones are only exposed through
+// the HTTP and WebSocket endpoints if whitelisted, or if no whitelist is set and
+// they are marked public.
+//
+// If the node is not running, the APIs are surfaced when it starts. Otherwise the
+// RPC endpoints are reopened to include them. Custom APIs are retained across
+// restarts of the node.
+func (n *Node) RegisterAPIs(apis []rpc.API) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// Validate the new APIs before registering any of them
+	if err := checkNamespaces(apis, append(append(n.apis(), n.rpcAPIs...), n.customAPIs...)); err != nil {
+		return err
+	}
+	for _, api := range apis {
+		if err := rpc.NewServer().RegisterName(api.Namespace, api.Service); err != nil {
+			return err
+		}
+	}
+	n.customAPIs = append(n.customAPIs, apis...)
+
+	// If the node is running, reopen the endpoints with the new APIs
+	if n.server == nil {
+		return nil
+	}
+	if err := n.reopenRPC(append(append([]rpc.API{}, n.rpcAPIs...), apis...)); err != nil {
+		return err
+	}
+	for _, api := range apis {
+		log.Info("Registered custom RPC API", "namespace", api.Namespace, "public", api.Public)
+	}
+	return nil
+}
+
+// checkNamespaces verifies that the namespaces of the custom APIs are well formed
+// and not used by any of the existing APIs. A batch of custom APIs may however
+// share a namespace, e.g. to split public and private methods.
+func checkNamespaces(custom []rpc.API, existing []rpc.API) error {
+	taken := map[string]bool{rpc.MetadataApi: true}
+	for _, api := range existing {
+		taken[api.Namespace] = true
+	}
+	for _, api := range custom {
+		if !namespaceRegexp.MatchString(api.Namespace) {
+			return fmt.Errorf("invalid RPC namespace %q", api.Namespace)
+		}
+		if taken[api.Namespace] {
+			return ErrNamespaceTaken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// CustomAPI is a trivial RPC service registered by embedding code.
+type CustomAPI struct{ name string }
+
+func (api *CustomAPI) Name() string { return api.name }
+
+// EmptyAPI is an RPC service without any methods to expose.
+type EmptyAPI struct{}
+
+// Tests that custom APIs can be registered both before and after the node is
+// started, and that they cannot shadow existing namespaces.
+func TestNodeRegisterAPIs(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.RegisterAPIs([]rpc.API{{Namespace: "first", Service: &CustomAPI{"first"}}}); err != nil {
+		t.Fatalf("failed to register API on stopped node: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	if err := stack.RegisterAPIs([]rpc.API{{Namespace: "second", Service: &CustomAPI{"second"}}}); err != nil {
+		t.Fatalf("failed to register API on running node: %v", err)
+	}
+	// Ensure invalid and clashing namespaces are rejected
+	for _, namespace := range []string{"", "Third", "third_api", "admin", "rpc", "first"} {
+		if err := stack.RegisterAPIs([]rpc.API{{Namespace: namespace, Service: &CustomAPI{namespace}}}); err == nil {
+			t.Errorf("namespace %q: registration succeeded", namespace)
+		}
+	}
+	if err := stack.RegisterAPIs([]rpc.API{{Namespace: "nomethods", Service: new(EmptyAPI)}}); err == nil {
+		t.Errorf("registered service without suitable methods")
+	}
+	// Check that both APIs are served, also after a restart
+	for i := 0; i < 2; i++ {
+		client, err := stack.Attach()
+		if err != nil {
+			t.Fatalf("iter %d: failed to attach to node: %v", i, err)
+		}
+		for _, namespace := range []string{"first", "second"} {
+			var name string
+			if err := client.Call(&name, namespace+"_name"); err != nil {
+				t.Errorf("iter %d: failed to call %s API: %v", i, namespace, err)
+			} else if name != namespace {
+				t.Errorf("iter %d: %s API name mismatch: have %s, want %s", i, namespace, name, namespace)
+			}
+		}
+		client.Close()
+
+		if err := stack.Restart(); err != nil {
+			t.Fatalf("iter %d: failed to restart protocol stack: %v", i, err)
+		}
+	}
+}
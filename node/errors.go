@@ -37,9 +37,13 @@ func (e *DuplicateServiceError) Error() string {
 type StopError struct {
 	Server   error
 	Services map[reflect.Type]error
+	Networks map[string]error
 }
 
 // Error generates a textual representation of the stop error.
 func (e *StopError) Error() string {
+	if len(e.Networks) > 0 {
+		return fmt.Sprintf("server: %v, services: %v, networks: %v", e.Server, e.Services, e.Networks)
+	}
 	return fmt.Sprintf("server: %v, services: %v", e.Server, e.Services)
 }
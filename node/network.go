@@ -0,0 +1,135 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// networkSeparator separates the name of a hosted network from the namespaces of
+// its APIs, e.g. "testnet.eth_blockNumber".
+const networkSeparator = "."
+
+// network is an independent chain hosted by a node within the same process.
+type network struct {
+	name  string
+	stack *Node
+}
+
+// AddNetwork creates a nested protocol stack hosting the services of an additional,
+// independent network (e.g. a testnet light client next to a mainnet full node)
+// within the same process. Services are registered on the returned stack, which
+// is started and stopped along with its host.
+//
+// The network runs its own P2P server, so conf must listen on a different port,
+// and keeps its data in its own data directory. The account manager and the RPC
+// endpoints of the host are shared, the APIs of the network being exposed under
+// namespaces prefixed with its name, e.g. "testnet.eth_blockNumber". Any RPC
+// endpoints configured in conf are ignored.
+func (n *Node) AddNetwork(name string, conf *Config) (*Node, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.server != nil {
+		return nil, ErrNodeRunning
+	}
+	if !validNetworkName(name) {
+		return nil, fmt.Errorf("invalid network name %q, must be alphanumeric", name)
+	}
+	conf, err := copyConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	conf.IPCPath, conf.HTTPHost, conf.WSHost = "", "", ""
+
+	for _, net := range n.networks {
+		if net.name == name {
+			return nil, fmt.Errorf("duplicate network %q", name)
+		}
+		if dir := conf.instanceDir(); dir != "" && dir == net.stack.config.instanceDir() {
+			return nil, ErrDatadirUsed
+		}
+	}
+	if dir := conf.instanceDir(); dir != "" && dir == n.config.instanceDir() {
+		return nil, ErrDatadirUsed
+	}
+	stack := newNode(conf, n.accman, "")
+	n.networks = append(n.networks, &network{name: name, stack: stack})
+	return stack, nil
+}
+
+// validNetworkName checks that a network name can be used as an RPC namespace
+// prefix without clashing with the separators of the method names.
+func validNetworkName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// startNetworks starts all the networks hosted by the node, stopping the already
+// started ones upon failure.
+func (n *Node) startNetworks() error {
+	for i, net := range n.networks {
+		if err := net.stack.Start(); err != nil {
+			for _, started := range n.networks[:i] {
+				started.stack.Stop()
+			}
+			return fmt.Errorf("network %s: %v", net.name, err)
+		}
+		log.Info("Started hosted network", "name", net.name, "instance", net.stack.InstanceDir())
+	}
+	return nil
+}
+
+// stopNetworks terminates all the networks hosted by the node, returning the
+// failures keyed by network name.
+func (n *Node) stopNetworks() map[string]error {
+	var failures map[string]error
+	for _, net := range n.networks {
+		if err := net.stack.Stop(); err != nil && err != ErrNodeStopped {
+			if failures == nil {
+				failures = make(map[string]error)
+			}
+			failures[net.name] = err
+		}
+	}
+	return failures
+}
+
+// networkAPIs returns the RPC descriptors of the running hosted networks, with
+// their namespaces prefixed by the network names.
+func (n *Node) networkAPIs() []rpc.API {
+	var apis []rpc.API
+	for _, net := range n.networks {
+		net.stack.lock.RLock()
+		for _, api := range net.stack.rpcAPIs {
+			api.Namespace = net.name + networkSeparator + api.Namespace
+			apis = append(apis, api)
+		}
+		net.stack.lock.RUnlock()
+	}
+	return apis
+}
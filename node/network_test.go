@@ -0,0 +1,102 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/rpc"
+)
+
+// Tests that hosted networks are started and stopped along with the host, and
+// that their APIs are exposed by the host under prefixed namespaces.
+func TestNetworkLifeCycle(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	calls := make(chan string, 1)
+	register := func(stack *Node, result string) {
+		constructor := func(*ServiceContext) (Service, error) {
+			api := &OneMethodApi{fun: func() { calls <- result }}
+			return &InstrumentedService{apis: []rpc.API{{Namespace: "single", Version: "1", Service: api, Public: true}}}, nil
+		}
+		if err := stack.Register(constructor); err != nil {
+			t.Fatalf("failed to register service: %v", err)
+		}
+	}
+	register(stack, "host")
+
+	for _, name := range []string{"", "test_net", "test.net"} {
+		if _, err := stack.AddNetwork(name, testNodeConfig()); err == nil {
+			t.Errorf("invalid network name %q accepted", name)
+		}
+	}
+	testnet, err := stack.AddNetwork("testnet", testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to add network: %v", err)
+	}
+	if _, err := stack.AddNetwork("testnet", testNodeConfig()); err == nil {
+		t.Fatalf("duplicate network accepted")
+	}
+	register(testnet, "testnet")
+
+	// Start the host and ensure the hosted network is running too
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	if testnet.Server() == nil {
+		t.Fatalf("hosted network not started")
+	}
+	if _, err := stack.AddNetwork("other", testNodeConfig()); err != ErrNodeRunning {
+		t.Fatalf("network addition failure mismatch: have %v, want %v", err, ErrNodeRunning)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to connect to the inproc API server: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		Method string
+		Result string
+	}{
+		{"single_theOneMethod", "host"},
+		{"testnet.single_theOneMethod", "testnet"},
+	}
+	for i, test := range tests {
+		if err := client.Call(nil, test.Method); err != nil {
+			t.Errorf("test %d: API request failed: %v", i, err)
+		}
+		select {
+		case result := <-calls:
+			if result != test.Result {
+				t.Errorf("test %d: result mismatch: have %s, want %s", i, result, test.Result)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("test %d: rpc execution timeout", i)
+		}
+	}
+	// Stop the host and ensure the hosted network is terminated too
+	if err := stack.Stop(); err != nil {
+		t.Fatalf("failed to stop protocol stack: %v", err)
+	}
+	if testnet.Server() != nil {
+		t.Fatalf("hosted network not stopped")
+	}
+}
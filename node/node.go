@@ -42,6 +42,8 @@ var (
 	ErrNodeStopped    = errors.New("node not started")
 	ErrNodeRunning    = errors.New("node already running")
 	ErrServiceUnknown = errors.New("unknown service")
+	ErrNamespaceTaken = errors.New("RPC namespace already in use")
+	ErrNodeKeyLocked  = errors.New("node key encrypted, passphrase required")
 
 	datadirInUseErrnos = map[uint]bool{11: true, 32: true, 35: true}
 )
@@ -60,8 +62,11 @@ type Node struct {
 
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
+	supervisor   *supervisor              // Supervisor restarting crashed services
+	networks     []*network               // Independent chains hosted in the same process
 
 	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
+	customAPIs    []rpc.API   // APIs registered by the embedding code, surfaced on every start
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
 	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
@@ -83,8 +88,39 @@ type Node struct {
 
 // New creates a new P2P node, ready for protocol registration.
 func New(conf *Config) (*Node, error) {
-	// Copy config and resolve the datadir so future changes to the current
-	// working directory don't affect the node.
+	conf, err := copyConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	// Ensure that the AccountManager method works before the node has started.
+	// We rely on this in cmd/netk.
+	am, ephemeralKeystore, err := makeAccountManager(conf)
+	if err != nil {
+		return nil, err
+	}
+	// Note: any interaction with Config that would create/touch files
+	// in the data directory or instance directory is delayed until Start.
+	return newNode(conf, am, ephemeralKeystore), nil
+}
+
+// newNode assembles a node around an already validated configuration.
+func newNode(conf *Config, am *accounts.Manager, ephemeralKeystore string) *Node {
+	return &Node{
+		accman:            am,
+		ephemeralKeystore: ephemeralKeystore,
+		config:            conf,
+		serviceFuncs:      []ServiceConstructor{},
+		ipcEndpoint:       conf.IPCEndpoint(),
+		httpEndpoint:      conf.HTTPEndpoint(),
+		wsEndpoint:        conf.WSEndpoint(),
+		eventmux:          new(event.TypeMux),
+	}
+}
+
+// copyConfig validates a node configuration and returns a copy of it with the
+// datadir resolved, so future changes to the current working directory don't
+// affect the node.
+func copyConfig(conf *Config) (*Config, error) {
 	confCopy := *conf
 	conf = &confCopy
 	if conf.DataDir != "" {
@@ -105,24 +141,7 @@ func New(conf *Config) (*Node, error) {
 	if strings.HasSuffix(conf.Name, ".ipc") {
 		return nil, errors.New(`Config.Name cannot end in ".ipc"`)
 	}
-	// Ensure that the AccountManager method works before the node has started.
-	// We rely on this in cmd/netk.
-	am, ephemeralKeystore, err := makeAccountManager(conf)
-	if err != nil {
-		return nil, err
-	}
-	// Note: any interaction with Config that would create/touch files
-	// in the data directory or instance directory is delayed until Start.
-	return &Node{
-		accman:            am,
-		ephemeralKeystore: ephemeralKeystore,
-		config:            conf,
-		serviceFuncs:      []ServiceConstructor{},
-		ipcEndpoint:       conf.IPCEndpoint(),
-		httpEndpoint:      conf.HTTPEndpoint(),
-		wsEndpoint:        conf.WSEndpoint(),
-		eventmux:          new(event.TypeMux),
-	}, nil
+	return conf, nil
 }
 
 // Register injects a new service into the node's stack. The service created by
@@ -154,7 +173,11 @@ func (n *Node) Start() error {
 	// Initialize the p2p server. This creates the node key and
 	// discovery databases.
 	n.serverConfig = n.config.P2P
-	n.serverConfig.PrivateKey = n.config.NodeKey()
+	key, err := n.config.NodeKey()
+	if err != nil {
+		return err
+	}
+	n.serverConfig.PrivateKey = key
 	n.serverConfig.Name = n.config.NodeName()
 	if n.serverConfig.StaticNodes == nil {
 		n.serverConfig.StaticNodes = n.config.StaticNodes()
@@ -170,19 +193,11 @@ func (n *Node) Start() error {
 
 	// Otherwise copy and specialize the P2P configuration
 	services := make(map[reflect.Type]Service)
+	supervisor := newSupervisor(n.restartServices)
 	for _, constructor := range n.serviceFuncs {
-		// Create a new context for the particular service
-		ctx := &ServiceContext{
-			config:         n.config,
-			services:       make(map[reflect.Type]Service),
-			EventMux:       n.eventmux,
-			AccountManager: n.accman,
-		}
-		for kind, s := range services { // copy needed for threaded access
-			ctx.services[kind] = s
-		}
 		// Construct and save the service
-		service, err := constructor(ctx)
+		entry := supervisor.track()
+		service, err := n.constructService(constructor, supervisor, entry, services)
 		if err != nil {
 			return err
 		}
@@ -191,10 +206,11 @@ func (n *Node) Start() error {
 			return &DuplicateServiceError{Kind: kind}
 		}
 		services[kind] = service
+		supervisor.attach(entry, service)
 	}
 	// Gather the protocols and start the freshly assembled P2P server
-	for _, service := range services {
-		running.Protocols = append(running.Protocols, service.Protocols()...)
+	for _, entry := range supervisor.services {
+		running.Protocols = append(running.Protocols, supervisor.protocols(entry)...)
 	}
 	if err := running.Start(); err != nil {
 		if errno, ok := err.(syscall.Errno); ok && datadirInUseErrnos[uint(errno)] {
@@ -207,6 +223,7 @@ func (n *Node) Start() error {
 	for kind, service := range services {
 		// Start the next service, stopping all previous upon failure
 		if err := service.Start(running); err != nil {
+			supervisor.stop()
 			for _, kind := range started {
 				services[kind].Stop()
 			}
@@ -217,8 +234,19 @@ func (n *Node) Start() error {
 		// Mark the service started for potential cleanup
 		started = append(started, kind)
 	}
+	// Start the additional networks hosted by this node
+	if err := n.startNetworks(); err != nil {
+		supervisor.stop()
+		for _, service := range services {
+			service.Stop()
+		}
+		running.Stop()
+		return err
+	}
 	// Lastly start the configured RPC interfaces
 	if err := n.startRPC(services); err != nil {
+		n.stopNetworks()
+		supervisor.stop()
 		for _, service := range services {
 			service.Stop()
 		}
@@ -227,12 +255,155 @@ func (n *Node) Start() error {
 	}
 	// Finish initializing the startup
 	n.services = services
+	n.supervisor = supervisor
 	n.server = running
 	n.stop = make(chan struct{})
 
 	return nil
 }
 
+// constructService creates a fresh instance of a service through its constructor,
+// with a context exposing the services constructed before it.
+func (n *Node) constructService(constructor ServiceConstructor, supervisor *supervisor, entry *supervised, services map[reflect.Type]Service) (Service, error) {
+	// Create a new context for the particular service
+	ctx := &ServiceContext{
+		config:         n.config,
+		services:       make(map[reflect.Type]Service),
+		supervisor:     supervisor,
+		supervised:     entry,
+		generation:     supervisor.construct(entry),
+		EventMux:       n.eventmux,
+		AccountManager: n.accman,
+	}
+	for kind, s := range services { // copy needed for threaded access
+		ctx.services[kind] = s
+	}
+	return constructor(ctx)
+}
+
+// restartServices replaces a crashed service by a fresh instance, together with
+// all services registered after it, as they may hold references to the crashed
+// one. The replaced instances are stopped in reverse order of construction and
+// the fresh ones started on the running P2P server and RPC endpoints.
+//
+// If the crashed generation was already replaced in the meantime (e.g. by the
+// restart of a service it depends on), the restart is skipped.
+func (n *Node) restartServices(supervisor *supervisor, entry *supervised, gen int) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// Bail out if the node was stopped or the crashed instance replaced meanwhile
+	if n.supervisor != supervisor {
+		return nil
+	}
+	if service, current := supervisor.current(entry); service != nil && current != gen {
+		return nil
+	}
+	entries := supervisor.following(entry)
+
+	// Stop the instances being replaced, dependents first
+	for i := len(entries) - 1; i >= 0; i-- {
+		service, _ := supervisor.current(entries[i])
+		if service == nil {
+			continue // Already stopped by a failed restart
+		}
+		if err := service.Stop(); err != nil {
+			log.Warn("Failed to stop crashed service", "service", entries[i].name, "err", err)
+		}
+		delete(n.services, reflect.TypeOf(service))
+		supervisor.stopped(entries[i])
+	}
+	// Construct the fresh instances, which must speak the same protocols. Any
+	// instance constructed already holds resources (e.g. opened databases), so a
+	// failed restart must stop all of them for the next attempt to succeed.
+	services := make(map[reflect.Type]Service)
+	for kind, service := range n.services {
+		services[kind] = service
+	}
+	fresh := make([]Service, 0, len(entries))
+	for _, entry := range entries {
+		service, err := n.constructService(n.serviceFuncs[entry.index], supervisor, entry, services)
+		if err != nil {
+			stopServices(fresh)
+			return err
+		}
+		fresh = append(fresh, service)
+		if !supervisor.compatible(entry, service) {
+			stopServices(fresh)
+			return fmt.Errorf("service %T changed its protocols", service)
+		}
+		services[reflect.TypeOf(service)] = service
+	}
+	// Start the fresh instances, stopping all of them upon failure
+	for i, service := range fresh {
+		supervisor.attach(entries[i], service)
+		if err := service.Start(n.server); err != nil {
+			for j := i; j >= 0; j-- {
+				delete(n.services, reflect.TypeOf(fresh[j]))
+				supervisor.stopped(entries[j])
+			}
+			stopServices(fresh)
+			return err
+		}
+		n.services[reflect.TypeOf(service)] = service
+	}
+	return n.refreshAPIs()
+}
+
+// stopServices stops the instances of a failed restart in reverse order of
+// construction, including those never started.
+func stopServices(services []Service) {
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := services[i].Stop(); err != nil {
+			log.Warn("Failed to stop service of aborted restart", "service", reflect.TypeOf(services[i]), "err", err)
+		}
+	}
+}
+
+// refreshAPIs reopens the running RPC endpoints with the APIs of the currently
+// running services, replacing the ones of stopped instances. Clients attached to
+// the previous in-process handler are disconnected instead of being left to call
+// into the stopped instances.
+func (n *Node) refreshAPIs() error {
+	n.stopInProc()
+
+	apis := n.apis()
+	for _, service := range n.services {
+		apis = append(apis, service.APIs()...)
+	}
+	apis = append(apis, n.networkAPIs()...)
+	apis = append(apis, n.customAPIs...)
+
+	return n.reopenRPC(apis)
+}
+
+// reopenRPC restarts the running RPC endpoints to serve the given set of APIs.
+func (n *Node) reopenRPC(apis []rpc.API) error {
+	if err := n.startInProc(apis); err != nil {
+		return err
+	}
+	if n.ipcListener != nil {
+		n.stopIPC()
+		if err := n.startIPC(apis); err != nil {
+			return err
+		}
+	}
+	if n.httpHandler != nil {
+		n.stopHTTP()
+		if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+			return err
+		}
+	}
+	if n.wsHandler != nil {
+		n.stopWS()
+		if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins); err != nil {
+			return err
+		}
+	}
+	n.rpcAPIs = apis
+	return nil
+}
+
 func (n *Node) openDataDir() error {
 	if n.config.DataDir == "" {
 		return nil // ephemeral
@@ -262,6 +433,11 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+	apis = append(apis, n.networkAPIs()...)
+	if err := checkNamespaces(n.customAPIs, apis); err != nil {
+		return err
+	}
+	apis = append(apis, n.customAPIs...)
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -336,9 +512,9 @@ func (n *Node) startIPC(apis []rpc.API) error {
 		for {
 			conn, err := listener.Accept()
 			if err != nil {
-				// Terminate if the listener was closed
+				// Terminate if the listener was closed or replaced
 				n.lock.RLock()
-				closed := n.ipcListener == nil
+				closed := n.ipcListener != listener
 				n.lock.RUnlock()
 				if closed {
 					return
@@ -495,8 +671,13 @@ func (n *Node) Stop() error {
 	n.stopHTTP()
 	n.stopIPC()
 	n.rpcAPIs = nil
+
+	// Abort any pending restarts before tearing down the services
+	n.supervisor.stop()
+
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
+		Networks: n.stopNetworks(),
 	}
 	for kind, service := range n.services {
 		if err := service.Stop(); err != nil {
@@ -505,6 +686,7 @@ func (n *Node) Stop() error {
 	}
 	n.server.Stop()
 	n.services = nil
+	n.supervisor = nil
 	n.server = nil
 
 	// Release instance directory lock.
@@ -522,7 +704,7 @@ func (n *Node) Stop() error {
 		keystoreErr = os.RemoveAll(n.ephemeralKeystore)
 	}
 
-	if len(failure.Services) > 0 {
+	if len(failure.Services) > 0 || len(failure.Networks) > 0 {
 		return failure
 	}
 	if keystoreErr != nil {
@@ -578,6 +760,18 @@ func (n *Node) Server() *p2p.Server {
 	return n.server
 }
 
+// Supervised retrieves the crash statistics of the services run under the
+// supervision of the node, or nil if the node is not running.
+func (n *Node) Supervised() map[string]SupervisedInfo {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.supervisor == nil {
+		return nil
+	}
+	return n.supervisor.stats()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (n *Node) Service(service interface{}) error {
 	n.lock.RLock()
@@ -596,6 +790,15 @@ func (n *Node) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Config returns a copy of the configuration of the protocol stack, including
+// the settings reloaded since it was created.
+func (n *Node) Config() Config {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return *n.config
+}
+
 // DataDir retrieves the current datadir used by the protocol stack.
 // Deprecated: No files should be stored in this directory, use InstanceDir instead.
 func (n *Node) DataDir() string {
@@ -643,6 +846,16 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
 }
 
+// OpenDatabaseReadOnly opens an existing database with the given name from within
+// the node's instance directory without modifying it. If the database is in use by
+// another process, a private snapshot of it is opened instead.
+func (n *Node) OpenDatabaseReadOnly(name string, cache, handles int) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return nil, errors.New("ephemeral node has no database to open")
+	}
+	return ethdb.NewLDBDatabaseReadOnly(n.config.resolvePath(name), cache, handles)
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.resolvePath(x)
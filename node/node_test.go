@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -573,3 +574,208 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a service crashing in a supervised routine is stopped and replaced
+// by a fresh instance, together with the services registered after it, and that
+// the RPC APIs are switched over to the fresh instances.
+func TestServiceSupervision(t *testing.T) {
+	defer func(backoff time.Duration) { supervisorMinBackoff = backoff }(supervisorMinBackoff)
+	supervisorMinBackoff = time.Millisecond
+
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	// Register a service with a routine crashing a few times before settling, and
+	// a dependent service registered after it
+	var (
+		built int
+		count int
+		runs  = make(chan int, 3)
+		stops = make(chan string, 4)
+		calls = make(chan int, 1)
+	)
+	crasher := func(ctx *ServiceContext) (Service, error) {
+		built++
+		instance := built
+		return &InstrumentedService{
+			apis: []rpc.API{{
+				Namespace: "crasher",
+				Version:   "1.0",
+				Service:   &OneMethodApi{fun: func() { calls <- instance }},
+				Public:    true,
+			}},
+			startHook: func(*p2p.Server) {
+				ctx.Go("crasher", func() {
+					count++
+					runs <- count
+					switch count {
+					case 1:
+						panic("") // Empty panics are crashes too
+					case 2:
+						panic("crash")
+					}
+				})
+			},
+			stopHook: func() { stops <- "crasher" },
+		}, nil
+	}
+	dependent := func(ctx *ServiceContext) (Service, error) {
+		return &InstrumentedService{stopHook: func() { stops <- "dependent" }}, nil
+	}
+	if err := stack.Register(InstrumentedServiceMakerA(crasher)); err != nil {
+		t.Fatalf("failed to register crashing service: %v", err)
+	}
+	if err := stack.Register(InstrumentedServiceMakerB(dependent)); err != nil {
+		t.Fatalf("failed to register dependent service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case run := <-runs:
+			if run != i {
+				t.Fatalf("run mismatch: have %d, want %d", run, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("service not restarted after crash %d", i-1)
+		}
+	}
+	// Both services must have been stopped in reverse order before each restart
+	for i := 0; i < 4; i++ {
+		want := []string{"dependent", "crasher"}[i%2]
+		select {
+		case stop := <-stops:
+			if stop != want {
+				t.Fatalf("stop %d mismatch: have %s, want %s", i, stop, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("stop %d missing", i)
+		}
+	}
+	if built != 3 {
+		t.Errorf("constructor run count mismatch: have %d, want %d", built, 3)
+	}
+	// The restart statistics are updated after the fresh instance is started
+	var info SupervisedInfo
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		nodeInfo, err := (&PublicAdminAPI{node: stack}).NodeInfo()
+		if err != nil {
+			t.Fatalf("failed to retrieve node infos: %v", err)
+		}
+		if info = nodeInfo.Services["node.InstrumentedServiceA"]; info.Restarts == 2 {
+			break
+		}
+	}
+	if info.Restarts != 2 {
+		t.Errorf("restart count mismatch: have %d, want %d", info.Restarts, 2)
+	}
+	if info.LastPanic != "crasher: crash" {
+		t.Errorf("last panic mismatch: have %q, want %q", info.LastPanic, "crasher: crash")
+	}
+	// The APIs of the fresh instance must be served
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach to the stack: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "crasher_theOneMethod"); err != nil {
+		t.Fatalf("failed to call the fresh instance: %v", err)
+	}
+	select {
+	case instance := <-calls:
+		if instance != 3 {
+			t.Errorf("serving instance mismatch: have %d, want %d", instance, 3)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("API call not served")
+	}
+}
+
+// Tests that the fresh instances of a failed restart are all stopped, so that
+// resources they hold (e.g. database locks) don't make every retry fail.
+func TestServiceSupervisionFailedRestart(t *testing.T) {
+	defer func(backoff time.Duration) { supervisorMinBackoff = backoff }(supervisorMinBackoff)
+	supervisorMinBackoff = time.Millisecond
+
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	// Register a service holding an exclusive lock until stopped, crashing once,
+	// and a dependent service failing its construction during the first restart
+	var (
+		lock    sync.Mutex
+		locked  bool
+		crashes int
+		built   int
+		starts  = make(chan struct{}, 3)
+	)
+	locker := func(ctx *ServiceContext) (Service, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if locked {
+			return nil, errors.New("resource locked")
+		}
+		locked = true
+		return &InstrumentedService{
+			startHook: func(*p2p.Server) {
+				starts <- struct{}{}
+				ctx.Go("locker", func() {
+					lock.Lock()
+					crashes++
+					crash := crashes == 1
+					lock.Unlock()
+
+					if crash {
+						panic("crash")
+					}
+				})
+			},
+			stopHook: func() {
+				lock.Lock()
+				locked = false
+				lock.Unlock()
+			},
+		}, nil
+	}
+	dependent := func(ctx *ServiceContext) (Service, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if built++; built == 2 {
+			return nil, errors.New("construction failed")
+		}
+		return new(InstrumentedService), nil
+	}
+	if err := stack.Register(InstrumentedServiceMakerA(locker)); err != nil {
+		t.Fatalf("failed to register locking service: %v", err)
+	}
+	if err := stack.Register(InstrumentedServiceMakerB(dependent)); err != nil {
+		t.Fatalf("failed to register dependent service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	// The locking service must come back after the failed restart attempt
+	for i := 0; i < 2; i++ {
+		select {
+		case <-starts:
+		case <-time.After(time.Second):
+			t.Fatalf("service not started after %d failed restarts", i)
+		}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+
+	if built != 3 {
+		t.Errorf("dependent construction count mismatch: have %d, want %d", built, 3)
+	}
+}
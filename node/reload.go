@@ -0,0 +1,140 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/networkchain/networkchain/log"
+)
+
+// PeerLimiter is implemented by services enforcing their own peer limits derived
+// from the one of the P2P server, which must follow it when reloaded.
+type PeerLimiter interface {
+	SetMaxPeers(maxPeers int)
+}
+
+// reloadable lists the configuration settings Reload applies to a running node.
+var reloadable = map[string]bool{
+	"P2P.MaxPeers": true,
+	"HTTPCors":     true,
+	"HTTPModules":  true,
+	"WSOrigins":    true,
+	"WSModules":    true,
+}
+
+// Reload applies the dynamically adjustable settings of an updated configuration
+// to the running node: the peer limit of the P2P server and the allowed modules
+// and origins of the HTTP and WebSocket RPC endpoints, which are reopened with
+// the new settings. The names of any other changed settings, which require a
+// restart to take effect, are returned.
+func (n *Node) Reload(conf *Config) ([]string, error) {
+	conf, err := copyConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	var changed, skipped []string
+	diffConfig(reflect.ValueOf(*n.config), reflect.ValueOf(*conf), "", &changed)
+	for _, setting := range changed {
+		if !reloadable[setting] {
+			skipped = append(skipped, setting)
+		}
+	}
+	// Apply the new peer limits
+	if conf.P2P.MaxPeers != n.config.P2P.MaxPeers {
+		n.server.SetMaxPeers(conf.P2P.MaxPeers)
+		for _, service := range n.services {
+			if limiter, ok := service.(PeerLimiter); ok {
+				limiter.SetMaxPeers(conf.P2P.MaxPeers)
+			}
+		}
+		log.Info("Reloaded peer limit", "old", n.config.P2P.MaxPeers, "new", conf.P2P.MaxPeers)
+		n.config.P2P.MaxPeers = conf.P2P.MaxPeers
+	}
+	// Reopen the RPC endpoints whose exposed APIs changed
+	if !reflect.DeepEqual(conf.HTTPModules, n.config.HTTPModules) || !reflect.DeepEqual(conf.HTTPCors, n.config.HTTPCors) {
+		n.config.HTTPModules, n.config.HTTPCors = conf.HTTPModules, conf.HTTPCors
+		if n.httpHandler != nil {
+			n.stopHTTP()
+			if err := n.startHTTP(n.httpEndpoint, n.rpcAPIs, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+				return skipped, err
+			}
+		}
+		log.Info("Reloaded HTTP RPC settings", "modules", n.config.HTTPModules, "cors", n.config.HTTPCors)
+	}
+	if !reflect.DeepEqual(conf.WSModules, n.config.WSModules) || !reflect.DeepEqual(conf.WSOrigins, n.config.WSOrigins) {
+		n.config.WSModules, n.config.WSOrigins = conf.WSModules, conf.WSOrigins
+		if n.wsHandler != nil {
+			n.stopWS()
+			if err := n.startWS(n.wsEndpoint, n.rpcAPIs, n.config.WSModules, n.config.WSOrigins); err != nil {
+				return skipped, err
+			}
+		}
+		log.Info("Reloaded WebSocket RPC settings", "modules", n.config.WSModules, "origins", n.config.WSOrigins)
+	}
+	return skipped, nil
+}
+
+// diffConfig collects the names of the fields differing between two configuration
+// structs, descending into nested structs. Fields not loaded from the config file
+// are ignored.
+func diffConfig(old, new reflect.Value, prefix string, changed *[]string) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if field.PkgPath != "" || field.Tag.Get("toml") == "-" {
+			continue
+		}
+		name := prefix + field.Name
+		if field.Type.Kind() == reflect.Struct {
+			diffConfig(old.Field(i), new.Field(i), name+".", changed)
+			continue
+		}
+		if !equalSetting(old.Field(i), new.Field(i)) {
+			*changed = append(*changed, name)
+		}
+	}
+}
+
+// equalSetting reports whether two values of a configuration field are the same.
+// Nodes carry runtime state and are compared by their URLs instead. NAT mappers
+// resolve their mechanism lazily, so only their types can be compared.
+func equalSetting(old, new reflect.Value) bool {
+	switch old.Kind() {
+	case reflect.Interface:
+		if old.IsNil() || new.IsNil() {
+			return old.IsNil() == new.IsNil()
+		}
+		if old.Elem().Type() != new.Elem().Type() {
+			return false
+		}
+		if old.Elem().Kind() == reflect.Ptr {
+			return true
+		}
+	case reflect.Slice:
+		if old.Type().Elem().Kind() == reflect.Ptr {
+			return fmt.Sprint(old.Interface()) == fmt.Sprint(new.Interface())
+		}
+	}
+	return reflect.DeepEqual(old.Interface(), new.Interface())
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/networkchain/networkchain/p2p/discover"
+)
+
+// limiterService is a service following the peer limit of the node.
+type limiterService struct {
+	NoopService
+	maxPeers int
+}
+
+func (s *limiterService) SetMaxPeers(maxPeers int) { s.maxPeers = maxPeers }
+
+// Tests that reloading a running node applies the adjustable settings and reports
+// the changed ones requiring a restart.
+func TestNodeReload(t *testing.T) {
+	config := testNodeConfig()
+	config.P2P.MaxPeers = 10
+	config.P2P.BootstrapNodes = []*discover.Node{discover.MustParseNode("enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:30303")}
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPModules = []string{"net"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	service := new(limiterService)
+	if err := stack.Register(func(*ServiceContext) (Service, error) { return service, nil }); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if _, err := stack.Reload(config); err != ErrNodeStopped {
+		t.Fatalf("reload failure mismatch: have %v, want %v", err, ErrNodeStopped)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	// Reload an equivalent config and ensure nothing is reported
+	reloaded := *config
+	reloaded.P2P.BootstrapNodes = []*discover.Node{discover.MustParseNode(config.P2P.BootstrapNodes[0].String())}
+	skipped, err := stack.Reload(&reloaded)
+	if err != nil {
+		t.Fatalf("failed to reload unchanged config: %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Fatalf("unchanged config reported changes: %v", skipped)
+	}
+	// Reload adjustable and fixed settings, checking which ones were applied
+	reloaded.P2P.MaxPeers = 20
+	reloaded.HTTPModules = []string{"net", "web3"}
+	reloaded.P2P.ListenAddr = ":30304"
+	reloaded.DataDir = "/tmp/reloaded"
+
+	if skipped, err = stack.Reload(&reloaded); err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if want := []string{"DataDir", "P2P.ListenAddr"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped settings mismatch: have %v, want %v", skipped, want)
+	}
+	if stack.Server().MaxPeers != 20 {
+		t.Errorf("server peer limit mismatch: have %d, want %d", stack.Server().MaxPeers, 20)
+	}
+	if service.maxPeers != 20 {
+		t.Errorf("service peer limit mismatch: have %d, want %d", service.maxPeers, 20)
+	}
+	if stack.httpHandler == nil {
+		t.Fatalf("HTTP endpoint not reopened")
+	}
+	if modules := stack.config.HTTPModules; !reflect.DeepEqual(modules, reloaded.HTTPModules) {
+		t.Errorf("HTTP modules mismatch: have %v, want %v", modules, reloaded.HTTPModules)
+	}
+	if stack.config.DataDir != config.DataDir {
+		t.Errorf("fixed setting applied: have %q, want %q", stack.config.DataDir, config.DataDir)
+	}
+}
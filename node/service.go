@@ -23,6 +23,7 @@ import (
 	"github.com/networkchain/networkchain/ethdb"
 	"github.com/networkchain/networkchain/event"
 	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/p2p/discover"
 	"github.com/networkchain/networkchain/rpc"
 )
 
@@ -32,6 +33,9 @@ import (
 type ServiceContext struct {
 	config         *Config
 	services       map[reflect.Type]Service // Index of the already constructed services
+	supervisor     *supervisor              // Supervisor restarting crashed services
+	supervised     *supervised              // Supervisor record of the service being constructed
+	generation     int                      // Generation of the service being constructed
 	EventMux       *event.TypeMux           // Event multiplexer used for decoupled notifications
 	AccountManager *accounts.Manager        // Account manager created by the node.
 }
@@ -57,6 +61,15 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.resolvePath(path)
 }
 
+// StaticNodes returns the list of nodes the protocol stack keeps persistent
+// connections with, either configured explicitly or loaded from the data directory.
+func (ctx *ServiceContext) StaticNodes() []*discover.Node {
+	if ctx.config.P2P.StaticNodes != nil {
+		return ctx.config.P2P.StaticNodes
+	}
+	return ctx.config.StaticNodes()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
@@ -67,6 +80,24 @@ func (ctx *ServiceContext) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Go runs a long lived routine of the service under the supervision of the node.
+// Should the routine panic, the service is restarted with an exponential backoff
+// instead of taking down the entire process: it is stopped and replaced by a fresh
+// instance from its constructor, together with the services registered after it.
+// From then on panics in the handlers of the service's protocols are recovered in
+// the same way. The restarts are reported by admin_nodeInfo.
+//
+// The crashed routine is not restarted on its own, the fresh instance of the
+// service starts its routines anew. Services without a node (i.e. constructed
+// manually) simply run the routine in a new goroutine.
+func (ctx *ServiceContext) Go(name string, routine func()) {
+	if ctx.supervisor == nil {
+		go routine()
+		return
+	}
+	ctx.supervisor.Go(ctx.supervised, ctx.generation, name, routine)
+}
+
 // ServiceConstructor is the function signature of the constructors needed to be
 // registered for service instantiation.
 type ServiceConstructor func(ctx *ServiceContext) (Service, error)
@@ -0,0 +1,358 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/p2p/discover"
+)
+
+var (
+	// supervisorMinBackoff is the delay before restarting a crashed service for
+	// the first time, doubled on every consecutive crash.
+	supervisorMinBackoff = time.Second
+
+	// supervisorMaxBackoff is the maximum delay before restarting a crashed service.
+	supervisorMaxBackoff = time.Minute
+
+	// supervisorStableRun is the time after which a running service is deemed to
+	// have recovered, resetting its restart backoff.
+	supervisorStableRun = time.Minute
+)
+
+var (
+	errServiceCrashed    = errors.New("service crashed")
+	errServiceRestarting = errors.New("service restarting")
+)
+
+// SupervisedInfo contains the crash statistics of a supervised service.
+type SupervisedInfo struct {
+	Restarts    int       `json:"restarts"`    // Number of times the service was restarted after a panic
+	LastPanic   string    `json:"lastPanic"`   // Description of the last panic of the service
+	LastRestart time.Time `json:"lastRestart"` // Time of the last restart of the service
+}
+
+// supervised is the supervisor's record of a service registered into the node,
+// surviving the restarts of the service.
+type supervised struct {
+	index    int            // Position of the service's constructor in the node
+	name     string         // Name of the service reported by admin_nodeInfo
+	service  Service        // Currently running instance (nil = stopped)
+	protos   []p2p.Protocol // Protocols of the running instance (nil = stopped)
+	layout   []p2p.Protocol // Protocols launched on the P2P server for the service
+	gen      int            // Generation of the instance, bumped on every construction
+	watched  bool           // Whether the instance runs supervised routines
+	started  time.Time      // Time the current instance was started
+	backoff  time.Duration  // Delay before the next restart of the service
+	crashed  int            // Last generation a restart was scheduled for
+	restarts SupervisedInfo // Crash statistics of the service
+}
+
+// supervisor restarts services crashing in one of their supervised routines or
+// protocol handlers, with exponential backoff, instead of letting the panic take
+// down the entire process.
+//
+// Services opt in by running their long lived routines through ServiceContext.Go,
+// which also covers the handlers of their protocols. A crashed service is stopped
+// and replaced by a fresh instance from its constructor, together with the services
+// registered after it, which may hold references to the crashed instance. The
+// netstats, whisper and les services are supervised.
+type supervisor struct {
+	services []*supervised // Records of the node's services, in constructor order
+	lock     sync.RWMutex  // Protects the service records
+
+	restart func(*supervisor, *supervised, int) error // Callback replacing a crashed service and its dependents
+	quit    chan struct{}                             // Quit channel to abort any pending restarts
+}
+
+// newSupervisor creates a supervisor for the services of a node, replacing crashed
+// ones through the given callback.
+func newSupervisor(restart func(*supervisor, *supervised, int) error) *supervisor {
+	return &supervisor{
+		restart: restart,
+		quit:    make(chan struct{}),
+	}
+}
+
+// track creates the record of the next service registered into the node.
+func (s *supervisor) track() *supervised {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := &supervised{
+		index:   len(s.services),
+		backoff: supervisorMinBackoff,
+	}
+	s.services = append(s.services, entry)
+	return entry
+}
+
+// following returns the record of the given service and all records after it.
+func (s *supervisor) following(entry *supervised) []*supervised {
+	return s.services[entry.index:]
+}
+
+// current returns the running instance of a service (nil if stopped) and the
+// generation of its last constructed one.
+func (s *supervisor) current(entry *supervised) (Service, int) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return entry.service, entry.gen
+}
+
+// construct bumps the generation of a service about to be constructed, returning
+// the new one. Crashes reported for older generations are ignored.
+func (s *supervisor) construct(entry *supervised) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry.gen++
+	entry.watched = false
+	return entry.gen
+}
+
+// attach records a fresh instance of a service, about to be started.
+func (s *supervisor) attach(entry *supervised, service Service) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry.name = strings.TrimPrefix(reflect.TypeOf(service).String(), "*")
+	entry.service = service
+	entry.protos = service.Protocols()
+	entry.started = time.Now()
+}
+
+// stopped records that the running instance of a service was stopped.
+func (s *supervisor) stopped(entry *supervised) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry.service = nil
+	entry.protos = nil
+}
+
+// Go runs a routine of the given generation of a service in a new goroutine. If
+// the routine panics, the panic is logged and the service restarted.
+func (s *supervisor) Go(entry *supervised, gen int, name string, routine func()) {
+	s.lock.Lock()
+	if entry.gen == gen {
+		entry.watched = true
+	}
+	s.lock.Unlock()
+
+	go func() {
+		if crashed, crash := s.run(routine); crashed {
+			s.crashed(entry, gen, fmt.Sprintf("%s: %s", name, crash))
+		}
+	}()
+}
+
+// run executes the routine, returning whether it crashed and the description of
+// the panic. Any panic counts as a crash, even one with an empty or nil value.
+func (s *supervisor) run(routine func()) (crashed bool, crash string) {
+	crashed = true
+	defer func() {
+		if crashed {
+			crash = fmt.Sprint(recover())
+			log.Debug("Supervised routine stack trace", "stack", string(debug.Stack()))
+		}
+	}()
+	routine()
+	return false, ""
+}
+
+// protocols creates the protocols to launch on the P2P server for a service.
+// They dispatch to the protocols of whichever instance of the service runs at
+// the time, so restarts don't have to touch the server.
+func (s *supervisor) protocols(entry *supervised) []p2p.Protocol {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stubs := make([]p2p.Protocol, len(entry.protos))
+	for i, proto := range entry.protos {
+		i, stub := i, proto
+
+		stub.Run = func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return s.runProtocol(entry, i, peer, rw)
+		}
+		if proto.NodeInfo != nil {
+			stub.NodeInfo = func() interface{} {
+				if current := s.protocol(entry, i); current != nil && current.NodeInfo != nil {
+					return current.NodeInfo()
+				}
+				return nil
+			}
+		}
+		if proto.PeerInfo != nil {
+			stub.PeerInfo = func(id discover.NodeID) interface{} {
+				if current := s.protocol(entry, i); current != nil && current.PeerInfo != nil {
+					return current.PeerInfo(id)
+				}
+				return nil
+			}
+		}
+		stubs[i] = stub
+	}
+	entry.layout = stubs
+	return stubs
+}
+
+// compatible reports whether a fresh instance of a service speaks the protocols
+// launched on the P2P server for the service.
+func (s *supervisor) compatible(entry *supervised, service Service) bool {
+	protos := service.Protocols()
+	if len(protos) != len(entry.layout) {
+		return false
+	}
+	for i, proto := range protos {
+		stub := entry.layout[i]
+		if proto.Name != stub.Name || proto.Version != stub.Version || proto.Length != stub.Length {
+			return false
+		}
+	}
+	return true
+}
+
+// protocol retrieves a protocol of the running instance of a service, or nil if
+// the service is stopped.
+func (s *supervisor) protocol(entry *supervised, index int) *p2p.Protocol {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if index >= len(entry.protos) {
+		return nil
+	}
+	return &entry.protos[index]
+}
+
+// runProtocol runs a protocol of the running instance of a service on a peer. If
+// the service is supervised and the protocol handler panics, the peer is dropped
+// and the service restarted. Otherwise the panic is left to take down the process.
+func (s *supervisor) runProtocol(entry *supervised, index int, peer *p2p.Peer, rw p2p.MsgReadWriter) (err error) {
+	s.lock.RLock()
+	gen, protos := entry.gen, entry.protos
+	s.lock.RUnlock()
+
+	if index >= len(protos) {
+		return errServiceRestarting
+	}
+	proto := protos[index]
+
+	crashed := true
+	defer func() {
+		if !crashed {
+			return
+		}
+		r := recover()
+
+		s.lock.RLock()
+		watched := entry.watched && entry.gen == gen
+		s.lock.RUnlock()
+		if !watched {
+			panic(r)
+		}
+		log.Debug("Supervised protocol stack trace", "stack", string(debug.Stack()))
+
+		s.crashed(entry, gen, fmt.Sprintf("%s/%d: %v", proto.Name, proto.Version, r))
+		err = errServiceCrashed
+	}()
+	err = proto.Run(peer, rw)
+	crashed = false
+	return err
+}
+
+// crashed schedules the restart of a service after a crash of the given generation.
+// Crashes of replaced instances and further crashes of an instance already being
+// replaced are ignored.
+func (s *supervisor) crashed(entry *supervised, gen int, crash string) {
+	s.lock.Lock()
+	if entry.gen != gen || entry.crashed == gen {
+		s.lock.Unlock()
+		return
+	}
+	entry.crashed = gen
+	if time.Since(entry.started) > supervisorStableRun {
+		entry.backoff = supervisorMinBackoff
+	}
+	backoff := entry.backoff
+	if entry.backoff *= 2; entry.backoff > supervisorMaxBackoff {
+		entry.backoff = supervisorMaxBackoff
+	}
+	s.lock.Unlock()
+
+	log.Error("Supervised service crashed", "service", entry.name, "restart", backoff, "err", crash)
+	go func() {
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-s.quit:
+				return
+			}
+			err := s.restart(s, entry, gen)
+			if err == nil {
+				break
+			}
+			if backoff *= 2; backoff > supervisorMaxBackoff {
+				backoff = supervisorMaxBackoff
+			}
+			log.Error("Failed to restart crashed service", "service", entry.name, "retry", backoff, "err", err)
+		}
+		// The node might have been stopped while waiting for the restart
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		s.lock.Lock()
+		entry.restarts.Restarts++
+		entry.restarts.LastPanic = crash
+		entry.restarts.LastRestart = time.Now()
+		s.lock.Unlock()
+
+		log.Info("Restarted crashed service", "service", entry.name)
+	}()
+}
+
+// stats returns a copy of the crash statistics of all services.
+func (s *supervisor) stats() map[string]SupervisedInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stats := make(map[string]SupervisedInfo, len(s.services))
+	for _, entry := range s.services {
+		if entry.name != "" {
+			stats[entry.name] = entry.restarts
+		}
+	}
+	return stats
+}
+
+// stop aborts all pending restarts. Running services are not affected, their
+// termination is the responsibility of the node.
+func (s *supervisor) stop() {
+	close(s.quit)
+}
@@ -319,7 +319,7 @@ func (t *dialTask) resolve(srv *Server) bool {
 // dial performs the actual connection attempt.
 func (t *dialTask) dial(srv *Server, dest *discover.Node) bool {
 	addr := &net.TCPAddr{IP: dest.IP, Port: int(dest.TCP)}
-	fd, err := srv.Dialer.Dial("tcp", addr.String())
+	fd, err := srv.dialConn(addr.String())
 	if err != nil {
 		log.Trace("Dial error", "task", t, "err", err)
 		return false
@@ -30,6 +30,11 @@ import (
 	"github.com/networkchain/networkchain/rlp"
 )
 
+// msgNestingLimit is the maximum depth of nested RLP lists accepted in a message
+// payload. None of the wire protocols need more than a handful of levels, so
+// anything deeper is rejected early instead of being decoded.
+const msgNestingLimit = 16
+
 // Msg defines the structure of a p2p message.
 //
 // Note that a Msg can only be sent once since the Payload reader is
@@ -49,13 +54,21 @@ type Msg struct {
 //
 // For the decoding rules, please see package rlp.
 func (msg Msg) Decode(val interface{}) error {
-	s := rlp.NewStream(msg.Payload, uint64(msg.Size))
+	s := msg.Stream()
 	if err := s.Decode(val); err != nil {
 		return newPeerError(errInvalidMsg, "(code %x) (size %d) %v", msg.Code, msg.Size, err)
 	}
 	return nil
 }
 
+// Stream returns an RLP stream over the message payload for incremental decoding.
+// The stream rejects lists nested deeper than any message may contain.
+func (msg Msg) Stream() *rlp.Stream {
+	s := rlp.NewStream(msg.Payload, uint64(msg.Size))
+	s.SetNestingLimit(msgNestingLimit)
+	return s
+}
+
 func (msg Msg) String() string {
 	return fmt.Sprintf("msg #%v (%v bytes)", msg.Code, msg.Size)
 }
@@ -25,6 +25,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/networkchain/networkchain/rlp"
 )
 
 func ExampleMsgPipe() {
@@ -49,6 +51,26 @@ func ExampleMsgPipe() {
 	// msg: 5, 0101
 }
 
+// Tests that message streams reject payloads nested deeper than any message may
+// contain, the same way Decode does.
+func TestMsgStreamNestingLimit(t *testing.T) {
+	nested := func(depth int) Msg {
+		var v interface{} = []interface{}{}
+		for i := 1; i < depth; i++ {
+			v = []interface{}{v}
+		}
+		enc, _ := rlp.EncodeToBytes(v)
+		return Msg{Size: uint32(len(enc)), Payload: bytes.NewReader(enc)}
+	}
+	var v interface{}
+	if err := nested(msgNestingLimit).Stream().Decode(&v); err != nil {
+		t.Errorf("nesting within limit rejected: %v", err)
+	}
+	if err := nested(msgNestingLimit + 1).Stream().Decode(&v); err != rlp.ErrNestingTooDeep {
+		t.Errorf("too deep nesting error mismatch: have %v, want %v", err, rlp.ErrNestingTooDeep)
+	}
+}
+
 func TestMsgPipeUnblockWrite(t *testing.T) {
 loop:
 	for i := 0; i < 100; i++ {
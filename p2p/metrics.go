@@ -19,9 +19,12 @@
 package p2p
 
 import (
+	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/networkchain/networkchain/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 var (
@@ -69,3 +72,110 @@ func (c *meteredConn) Write(b []byte) (n int, err error) {
 	egressTrafficMeter.Mark(int64(n))
 	return
 }
+
+// MsgTraffic is the number of packets and payload bytes transferred with a peer
+// for a single message code.
+type MsgTraffic struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// ProtoTraffic is the traffic of a sub-protocol with a peer, broken down by the
+// message codes of the protocol. Codes without any traffic are omitted.
+type ProtoTraffic struct {
+	Ingress map[uint64]MsgTraffic `json:"ingress"`
+	Egress  map[uint64]MsgTraffic `json:"egress"`
+}
+
+// msgCounter accumulates the traffic of a single message code. Its fields are
+// accessed atomically.
+type msgCounter struct {
+	packets uint64
+	bytes   uint64
+}
+
+// msgMeters are the global meters of a single message code, shared by all the
+// peers running the same protocol version.
+type msgMeters struct {
+	packets gometrics.Meter
+	traffic gometrics.Meter
+}
+
+// protoMeter meters the traffic of a sub-protocol with a single peer for every
+// message code, both locally for peer introspection and globally through the
+// metrics system if it is enabled.
+type protoMeter struct {
+	ingress, egress             []msgCounter
+	ingressMeters, egressMeters []msgMeters
+}
+
+// newProtoMeter creates the per message code meters for a sub-protocol.
+func newProtoMeter(proto Protocol) *protoMeter {
+	m := &protoMeter{
+		ingress:       make([]msgCounter, proto.Length),
+		egress:        make([]msgCounter, proto.Length),
+		ingressMeters: make([]msgMeters, proto.Length),
+		egressMeters:  make([]msgMeters, proto.Length),
+	}
+	for code := uint64(0); code < proto.Length; code++ {
+		prefix := fmt.Sprintf("p2p/msg/%s/%d/%d", proto.Name, proto.Version, code)
+		m.ingressMeters[code] = msgMeters{
+			packets: metrics.NewMeter(prefix + "/in/packets"),
+			traffic: metrics.NewMeter(prefix + "/in/traffic"),
+		}
+		m.egressMeters[code] = msgMeters{
+			packets: metrics.NewMeter(prefix + "/out/packets"),
+			traffic: metrics.NewMeter(prefix + "/out/traffic"),
+		}
+	}
+	return m
+}
+
+// markIngress accounts for a message received from the peer. The code is the
+// protocol relative message code.
+func (m *protoMeter) markIngress(code uint64, size uint32) {
+	if code >= uint64(len(m.ingress)) {
+		return
+	}
+	atomic.AddUint64(&m.ingress[code].packets, 1)
+	atomic.AddUint64(&m.ingress[code].bytes, uint64(size))
+
+	m.ingressMeters[code].packets.Mark(1)
+	m.ingressMeters[code].traffic.Mark(int64(size))
+}
+
+// markEgress accounts for a message sent to the peer. The code is the protocol
+// relative message code.
+func (m *protoMeter) markEgress(code uint64, size uint32) {
+	if code >= uint64(len(m.egress)) {
+		return
+	}
+	atomic.AddUint64(&m.egress[code].packets, 1)
+	atomic.AddUint64(&m.egress[code].bytes, uint64(size))
+
+	m.egressMeters[code].packets.Mark(1)
+	m.egressMeters[code].traffic.Mark(int64(size))
+}
+
+// traffic returns a snapshot of the traffic accumulated with the peer so far.
+func (m *protoMeter) traffic() *ProtoTraffic {
+	return &ProtoTraffic{
+		Ingress: snapshotCounters(m.ingress),
+		Egress:  snapshotCounters(m.egress),
+	}
+}
+
+// snapshotCounters collects the non-empty message counters into a map keyed by
+// message code.
+func snapshotCounters(counters []msgCounter) map[uint64]MsgTraffic {
+	snapshot := make(map[uint64]MsgTraffic)
+	for code := range counters {
+		if packets := atomic.LoadUint64(&counters[code].packets); packets > 0 {
+			snapshot[uint64(code)] = MsgTraffic{
+				Packets: packets,
+				Bytes:   atomic.LoadUint64(&counters[code].bytes),
+			}
+		}
+	}
+	return snapshot
+}
@@ -279,7 +279,7 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw, meter: newProtoMeter(proto)}
 				offset += proto.Length
 
 				continue outer
@@ -330,16 +330,21 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+	meter  *protoMeter // per message code traffic meters
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code, size := msg.Code, msg.Size
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil {
+			rw.meter.markEgress(code, size)
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -355,6 +360,7 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		rw.meter.markIngress(msg.Code, msg.Size)
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
@@ -372,7 +378,8 @@ type PeerInfo struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols map[string]interface{}   `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   map[string]*ProtoTraffic `json:"traffic"`   // Per message code traffic of the sub-protocols
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -388,6 +395,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Traffic:   make(map[string]*ProtoTraffic),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
@@ -403,6 +411,7 @@ func (p *Peer) Info() *PeerInfo {
 			}
 		}
 		info.Protocols[proto.Name] = protoInfo
+		info.Traffic[proto.Name] = proto.meter.traffic()
 	}
 	return info
 }
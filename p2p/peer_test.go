@@ -98,6 +98,49 @@ func TestPeerProtoReadMsg(t *testing.T) {
 	}
 }
 
+// Tests that the traffic of sub-protocols is metered per message code and is
+// reported in the peer infos.
+func TestPeerTraffic(t *testing.T) {
+	done := make(chan *PeerInfo)
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			for i := 0; i < 3; i++ {
+				if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+					t.Error(err)
+				}
+			}
+			if err := SendItems(rw, 4, "foo"); err != nil {
+				t.Error(err)
+			}
+			done <- peer.Info()
+			return nil
+		},
+	}
+	closer, rw, _, _ := testPeer([]Protocol{proto})
+	defer closer()
+
+	for i := 0; i < 3; i++ {
+		Send(rw, baseProtocolLength+2, []uint{1})
+	}
+	if err := ExpectMsg(rw, baseProtocolLength+4, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case info := <-done:
+		want := &ProtoTraffic{
+			Ingress: map[uint64]MsgTraffic{2: {Packets: 3, Bytes: 6}},
+			Egress:  map[uint64]MsgTraffic{4: {Packets: 1, Bytes: 5}},
+		}
+		if have := info.Traffic["a"]; !reflect.DeepEqual(have, want) {
+			t.Errorf("traffic mismatch: have %+v, want %+v", have, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("protocol did not report traffic")
+	}
+}
+
 func TestPeerProtoEncodeMsg(t *testing.T) {
 	proto := Protocol{
 		Name:   "a",
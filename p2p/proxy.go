@@ -0,0 +1,206 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone     = 0x00 // No authentication required
+	socks5AuthPassword = 0x02 // Username/password authentication (RFC 1929)
+	socks5AuthNoAccept = 0xff // No acceptable authentication methods
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+)
+
+var (
+	errProxyScheme   = errors.New("unsupported proxy scheme, only socks5 is supported")
+	errProxyVersion  = errors.New("proxy replied with unexpected SOCKS version")
+	errProxyAuth     = errors.New("proxy requires unsupported authentication")
+	errProxyAuthFail = errors.New("proxy rejected the credentials")
+)
+
+// socks5Replies are the textual representations of the SOCKS5 reply codes.
+var socks5Replies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// dialConn opens an outbound TCP connection to a peer, routing it through the
+// configured proxy if any.
+func (srv *Server) dialConn(addr string) (net.Conn, error) {
+	if srv.proxy != nil {
+		return dialSOCKS5(srv.Dialer, srv.proxy, addr)
+	}
+	return srv.Dialer.Dial("tcp", addr)
+}
+
+// parseProxy validates a proxy URL of the form socks5://[user:pass@]host:port.
+func parseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" {
+		return nil, errProxyScheme
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// dialSOCKS5 establishes a TCP connection to addr through the given SOCKS5 proxy,
+// authenticating with the credentials contained in the proxy URL, if any.
+func dialSOCKS5(dialer *net.Dialer, proxy *url.URL, addr string) (net.Conn, error) {
+	conn, err := dialer.Dial("tcp", proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	if dialer.Timeout != 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+	if err := socks5Handshake(conn, proxy.User, addr); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", proxy.Host, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// socks5Handshake negotiates the authentication method with a SOCKS5 proxy and
+// requests a connection to the destination address.
+func socks5Handshake(conn net.Conn, user *url.Userinfo, addr string) error {
+	host, portstr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portstr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portstr)
+	}
+	// Negotiate the authentication method
+	methods := []byte{socks5AuthNone}
+	if user != nil {
+		methods = append(methods, socks5AuthPassword)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errProxyVersion
+	}
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if user == nil {
+			return errProxyAuth
+		}
+		pass, _ := user.Password()
+		if len(user.Username()) > 255 || len(pass) > 255 {
+			return errors.New("proxy credentials too long")
+		}
+		req := []byte{0x01, byte(len(user.Username()))}
+		req = append(req, user.Username()...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errProxyAuthFail
+		}
+	default:
+		return errProxyAuth
+	}
+	// Request the connection to the destination
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AddrIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	// Read the reply, discarding the address bound by the proxy
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return errProxyVersion
+	}
+	if header[1] != 0x00 {
+		if msg, ok := socks5Replies[header[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("unknown proxy reply %d", header[1])
+	}
+	var size int
+	switch header[3] {
+	case socks5AddrIPv4:
+		size = net.IPv4len
+	case socks5AddrIPv6:
+		size = net.IPv6len
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		size = int(length[0])
+	default:
+		return fmt.Errorf("unknown proxy address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, size+2))
+	return err
+}
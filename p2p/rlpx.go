@@ -583,12 +583,18 @@ func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
 func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	ptype, _ := rlp.EncodeToBytes(msg.Code)
 
-	// write header
-	headbuf := make([]byte, 32)
 	fsize := uint32(len(ptype)) + msg.Size
 	if fsize > maxUint24 {
 		return errors.New("message size overflows uint24")
 	}
+	return rw.writeFrame(io.MultiReader(bytes.NewReader(ptype), msg.Payload), fsize)
+}
+
+// writeFrame encrypts and authenticates the given frame content of fsize bytes,
+// writing it to the connection.
+func (rw *rlpxFrameRW) writeFrame(content io.Reader, fsize uint32) error {
+	// write header
+	headbuf := make([]byte, 32)
 	putInt24(fsize, headbuf) // TODO: check overflow
 	copy(headbuf[3:], zeroHeader)
 	rw.enc.XORKeyStream(headbuf[:16], headbuf[:16]) // first half is now encrypted
@@ -602,10 +608,7 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	// write encrypted frame, updating the egress MAC hash with
 	// the data written to conn.
 	tee := cipher.StreamWriter{S: rw.enc, W: io.MultiWriter(rw.conn, rw.egressMAC)}
-	if _, err := tee.Write(ptype); err != nil {
-		return err
-	}
-	if _, err := io.Copy(tee, msg.Payload); err != nil {
+	if _, err := io.Copy(tee, content); err != nil {
 		return err
 	}
 	if padding := fsize % 16; padding > 0 {
@@ -0,0 +1,108 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package p2p
+
+import (
+	"bytes"
+
+	"github.com/networkchain/networkchain/crypto/sha3"
+	"github.com/networkchain/networkchain/rlp"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test the parsing of RLPx frames and
+// of the encryption and protocol handshake messages.
+//
+// The first byte of the input selects the fuzzed stage:
+//	0: raw bytes read off the wire by the frame reader
+//	1: frame content sealed with valid MACs, parsed as a protocol handshake
+//	2: decrypted encryption handshake messages
+func Fuzz(data []byte) int {
+	if len(data) == 0 {
+		return -1
+	}
+	switch data[0] % 3 {
+	case 0:
+		return fuzzFrame(data[1:])
+	case 1:
+		return fuzzFrameContent(data[1:])
+	default:
+		return fuzzHandshake(data[1:])
+	}
+}
+
+// fuzzSecrets returns the fixed session secrets shared by both ends of a fuzzed
+// frame stream.
+func fuzzSecrets() secrets {
+	return secrets{
+		AES:        make([]byte, 16),
+		MAC:        make([]byte, 16),
+		EgressMAC:  sha3.NewKeccak256(),
+		IngressMAC: sha3.NewKeccak256(),
+	}
+}
+
+// fuzzFrame feeds arbitrary bytes into the frame reader.
+func fuzzFrame(data []byte) int {
+	rw := newRLPXFrameRW(bytes.NewBuffer(data), fuzzSecrets())
+	score := 0
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return score
+		}
+		msg.Discard()
+		score = 1
+	}
+}
+
+// fuzzFrameContent seals the input as the content of a frame, getting past the
+// MAC checks, and processes the read message as a protocol handshake.
+func fuzzFrameContent(data []byte) int {
+	if uint64(len(data)) > uint64(maxUint24) {
+		return -1
+	}
+	buf := new(bytes.Buffer)
+	if err := newRLPXFrameRW(buf, fuzzSecrets()).writeFrame(bytes.NewReader(data), uint32(len(data))); err != nil {
+		panic(err)
+	}
+	rw := newRLPXFrameRW(buf, fuzzSecrets())
+	if _, err := readProtocolHandshake(rw, &protoHandshake{}); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// fuzzHandshake decodes the input as decrypted pre-EIP-8 and EIP-8 encryption
+// handshake messages.
+func fuzzHandshake(data []byte) int {
+	if len(data) == authMsgLen {
+		new(authMsgV4).decodePlain(data)
+	}
+	if len(data) == authRespLen {
+		new(authRespV4).decodePlain(data)
+	}
+	score := 0
+	if err := rlp.NewStream(bytes.NewReader(data), 0).Decode(new(authMsgV4)); err == nil {
+		score = 1
+	}
+	if err := rlp.NewStream(bytes.NewReader(data), 0).Decode(new(authRespV4)); err == nil {
+		score = 1
+	}
+	return score
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"sync"
 	"time"
 
@@ -132,6 +133,12 @@ type Config struct {
 	// is used to dial outbound peer connections.
 	Dialer *net.Dialer `toml:"-"`
 
+	// If Proxy is set to a SOCKS5 proxy URL (socks5://[user:pass@]host:port),
+	// outbound peer connections are dialed through the proxy. Discovery runs
+	// over UDP and is not proxied, so it should be disabled if the node's
+	// address must not be revealed.
+	Proxy string `toml:",omitempty"`
+
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 }
@@ -151,6 +158,7 @@ type Server struct {
 
 	ntab         discoverTable
 	listener     net.Listener
+	proxy        *url.URL
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 	DiscV5       *discv5.Network
@@ -273,6 +281,16 @@ func (srv *Server) PeerCount() int {
 	return count
 }
 
+// SetMaxPeers adjusts the maximum number of peers of a running server. A lowered
+// limit only applies to new connections, existing peers are not disconnected.
+func (srv *Server) SetMaxPeers(n int) {
+	select {
+	case srv.peerOp <- func(map[discover.NodeID]*Peer) { srv.MaxPeers = n }:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+}
+
 // AddPeer connects to the given node and maintains the connection until the
 // server is shut down. If the connection fails for any reason, the server will
 // attempt to reconnect the peer.
@@ -360,6 +378,16 @@ func (srv *Server) Start() (err error) {
 	if srv.Dialer == nil {
 		srv.Dialer = &net.Dialer{Timeout: defaultDialTimeout}
 	}
+	if srv.Proxy != "" {
+		proxy, err := parseProxy(srv.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %q: %v", srv.Proxy, err)
+		}
+		srv.proxy = proxy
+		if !srv.NoDiscovery || srv.DiscoveryV5 {
+			log.Warn("Peer discovery is not routed through the proxy", "proxy", proxy.Host)
+		}
+	}
 	srv.quit = make(chan struct{})
 	srv.addpeer = make(chan *conn)
 	srv.delpeer = make(chan peerDrop)
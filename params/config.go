@@ -86,8 +86,8 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(math.MaxInt64) /*disabled*/, new(EthashConfig), nil}
-	TestChainConfig    = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(math.MaxInt64) /*disabled*/, nil, new(EthashConfig), nil}
+	TestChainConfig    = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
 	TestRules          = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -112,6 +112,10 @@ type ChainConfig struct {
 
 	MetropolisBlock *big.Int `json:"metropolisBlock,omitempty"` // Metropolis switch block (nil = no fork, 0 = alraedy on homestead)
 
+	// GasRepricings schedules changes to the gas prices on top of the ones of the
+	// standard forks, applied in order once their blocks are reached.
+	GasRepricings []*GasRepricing `json:"gasRepricings,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -186,21 +190,29 @@ func (c *ChainConfig) IsMetropolis(num *big.Int) bool {
 	return isForked(c.MetropolisBlock, num)
 }
 
-// GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
+// GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice),
+// with the gas repricings scheduled up to the given block applied.
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
 func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 	if num == nil {
 		return GasTableHomestead
 	}
+	var gt GasTable
 	switch {
 	case c.IsEIP158(num):
-		return GasTableEIP158
+		gt = GasTableEIP158
 	case c.IsEIP150(num):
-		return GasTableHomesteadGasRepriceFork
+		gt = GasTableHomesteadGasRepriceFork
 	default:
-		return GasTableHomestead
+		gt = GasTableHomestead
+	}
+	for _, repricing := range c.GasRepricings {
+		if isForked(repricing.Block, num) {
+			repricing.apply(&gt)
+		}
 	}
+	return gt
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
@@ -246,9 +258,30 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.MetropolisBlock, newcfg.MetropolisBlock, head) {
 		return newCompatError("Metropolis fork block", c.MetropolisBlock, newcfg.MetropolisBlock)
 	}
+	for i := 0; i < len(c.GasRepricings) || i < len(newcfg.GasRepricings); i++ {
+		var stored, updated *GasRepricing
+		if i < len(c.GasRepricings) {
+			stored = c.GasRepricings[i]
+		}
+		if i < len(newcfg.GasRepricings) {
+			updated = newcfg.GasRepricings[i]
+		}
+		if isRepricingIncompatible(stored, updated, head) {
+			return newCompatError(fmt.Sprintf("gas repricing #%d block", i), stored.block(), updated.block())
+		}
+	}
 	return nil
 }
 
+// isRepricingIncompatible returns true if a gas repricing cannot be changed to
+// another one because head is already past either of them.
+func isRepricingIncompatible(r1, r2 *GasRepricing, head *big.Int) bool {
+	if !isForked(r1.block(), head) && !isForked(r2.block(), head) {
+		return false
+	}
+	return !r1.equal(r2)
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -17,6 +17,7 @@
 package params
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -70,6 +71,40 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(800)}}},
+			new:     &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(20), SLoad: gasPtr(800)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(800)}}},
+			new:     &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(800)}, {Block: big.NewInt(30), SLoad: gasPtr(900)}}},
+			head:    20,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(800)}}},
+			new:    &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(900)}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "gas repricing #0 block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{GasRepricings: []*GasRepricing{{Block: big.NewInt(10), SLoad: gasPtr(800)}}},
+			new:    &ChainConfig{},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "gas repricing #0 block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +114,47 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func gasPtr(gas uint64) *uint64 { return &gas }
+
+// Tests that gas repricings scheduled via the chain config JSON are applied on
+// top of the standard gas tables once their blocks are reached.
+func TestGasRepricing(t *testing.T) {
+	var config ChainConfig
+	blob := `{
+		"eip150Block": 5,
+		"gasRepricings": [
+			{"block": 10, "sstoreSet": 25000, "refundQuotient": 5},
+			{"block": 20, "sstoreSet": 30000, "sload": 800}
+		]
+	}`
+	if err := json.Unmarshal([]byte(blob), &config); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	tests := []struct {
+		number uint64
+		want   GasTable
+	}{
+		{0, GasTableHomestead},
+		{5, GasTableHomesteadGasRepriceFork},
+		{10, func() GasTable {
+			gt := GasTableHomesteadGasRepriceFork
+			gt.SstoreSet, gt.RefundQuotient = 25000, 5
+			return gt
+		}()},
+		{20, func() GasTable {
+			gt := GasTableHomesteadGasRepriceFork
+			gt.SstoreSet, gt.RefundQuotient, gt.SLoad = 30000, 5, 800
+			return gt
+		}()},
+	}
+	for _, tt := range tests {
+		if have := config.GasTable(new(big.Int).SetUint64(tt.number)); have != tt.want {
+			t.Errorf("block %d: gas table mismatch:\nhave %+v\nwant %+v", tt.number, have, tt.want)
+		}
+	}
+	// The standard gas tables must not be modified by the repricings
+	if GasTableHomesteadGasRepriceFork.SstoreSet != SstoreSetGas {
+		t.Errorf("standard gas table modified: sstore set gas %d", GasTableHomesteadGasRepriceFork.SstoreSet)
+	}
+}
@@ -16,6 +16,8 @@
 
 package params
 
+import "math/big"
+
 type GasTable struct {
 	ExtcodeSize uint64
 	ExtcodeCopy uint64
@@ -32,6 +34,17 @@ type GasTable struct {
 	// to call. May be left nil. Nil means
 	// not charged.
 	CreateBySuicide uint64
+
+	SstoreSet    uint64 // Once per SSTORE operation if the zeroness changes from zero
+	SstoreReset  uint64 // Once per SSTORE operation if the zeroness doesn't change
+	SstoreClear  uint64 // Once per SSTORE operation if the zeroness changes to zero
+	SstoreRefund uint64 // Refunded when the zeroness of a storage slot changes to zero
+
+	SuicideRefund uint64 // Refunded following a suicide operation
+
+	// RefundQuotient caps the refunds of a transaction to the
+	// used gas divided by the quotient. Zero disables refunds.
+	RefundQuotient uint64
 }
 
 var (
@@ -45,6 +58,13 @@ var (
 		Calls:       40,
 		Suicide:     0,
 		ExpByte:     10,
+
+		SstoreSet:      SstoreSetGas,
+		SstoreReset:    SstoreResetGas,
+		SstoreClear:    SstoreClearGas,
+		SstoreRefund:   SstoreRefundGas,
+		SuicideRefund:  SuicideRefundGas,
+		RefundQuotient: RefundQuotient,
 	}
 
 	// GasTableHomestead contain the gas re-prices for
@@ -61,6 +81,13 @@ var (
 		ExpByte:     10,
 
 		CreateBySuicide: 25000,
+
+		SstoreSet:      SstoreSetGas,
+		SstoreReset:    SstoreResetGas,
+		SstoreClear:    SstoreClearGas,
+		SstoreRefund:   SstoreRefundGas,
+		SuicideRefund:  SuicideRefundGas,
+		RefundQuotient: RefundQuotient,
 	}
 
 	GasTableEIP158 = GasTable{
@@ -73,5 +100,96 @@ var (
 		ExpByte:     50,
 
 		CreateBySuicide: 25000,
+
+		SstoreSet:      SstoreSetGas,
+		SstoreReset:    SstoreResetGas,
+		SstoreClear:    SstoreClearGas,
+		SstoreRefund:   SstoreRefundGas,
+		SuicideRefund:  SuicideRefundGas,
+		RefundQuotient: RefundQuotient,
 	}
 )
+
+// GasRepricing schedules changes to the gas table from a given block on. Prices
+// left unset retain their value from the gas table otherwise in effect.
+type GasRepricing struct {
+	Block *big.Int `json:"block"` // Block number of the repricing (mandatory)
+
+	ExtcodeSize     *uint64 `json:"extcodeSize,omitempty"`
+	ExtcodeCopy     *uint64 `json:"extcodeCopy,omitempty"`
+	Balance         *uint64 `json:"balance,omitempty"`
+	SLoad           *uint64 `json:"sload,omitempty"`
+	Calls           *uint64 `json:"calls,omitempty"`
+	Suicide         *uint64 `json:"suicide,omitempty"`
+	ExpByte         *uint64 `json:"expByte,omitempty"`
+	CreateBySuicide *uint64 `json:"createBySuicide,omitempty"`
+	SstoreSet       *uint64 `json:"sstoreSet,omitempty"`
+	SstoreReset     *uint64 `json:"sstoreReset,omitempty"`
+	SstoreClear     *uint64 `json:"sstoreClear,omitempty"`
+	SstoreRefund    *uint64 `json:"sstoreRefund,omitempty"`
+	SuicideRefund   *uint64 `json:"suicideRefund,omitempty"`
+	RefundQuotient  *uint64 `json:"refundQuotient,omitempty"`
+}
+
+// block returns the block number of the repricing, nil meaning never.
+func (r *GasRepricing) block() *big.Int {
+	if r == nil {
+		return nil
+	}
+	return r.Block
+}
+
+// gasPrice pairs a price override of a repricing with the gas table field it
+// applies to.
+type gasPrice struct {
+	override *uint64
+	field    *uint64
+}
+
+// prices lists the price overrides of the repricing along with the fields of the
+// given gas table they apply to.
+func (r *GasRepricing) prices(gt *GasTable) []gasPrice {
+	return []gasPrice{
+		{r.ExtcodeSize, &gt.ExtcodeSize},
+		{r.ExtcodeCopy, &gt.ExtcodeCopy},
+		{r.Balance, &gt.Balance},
+		{r.SLoad, &gt.SLoad},
+		{r.Calls, &gt.Calls},
+		{r.Suicide, &gt.Suicide},
+		{r.ExpByte, &gt.ExpByte},
+		{r.CreateBySuicide, &gt.CreateBySuicide},
+		{r.SstoreSet, &gt.SstoreSet},
+		{r.SstoreReset, &gt.SstoreReset},
+		{r.SstoreClear, &gt.SstoreClear},
+		{r.SstoreRefund, &gt.SstoreRefund},
+		{r.SuicideRefund, &gt.SuicideRefund},
+		{r.RefundQuotient, &gt.RefundQuotient},
+	}
+}
+
+// apply overrides the prices of the gas table set by the repricing.
+func (r *GasRepricing) apply(gt *GasTable) {
+	for _, price := range r.prices(gt) {
+		if price.override != nil {
+			*price.field = *price.override
+		}
+	}
+}
+
+// equal returns whether two repricings schedule the same prices at the same block.
+func (r *GasRepricing) equal(other *GasRepricing) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	if !configNumEqual(r.Block, other.Block) {
+		return false
+	}
+	prices, others := r.prices(new(GasTable)), other.prices(new(GasTable))
+	for i := range prices {
+		a, b := prices[i].override, others[i].override
+		if (a == nil) != (b == nil) || (a != nil && *a != *b) {
+			return false
+		}
+	}
+	return true
+}
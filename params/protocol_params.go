@@ -61,6 +61,8 @@ const (
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
 	MaxCodeSize = 24576
+
+	RefundQuotient uint64 = 2 // Maximum refund quotient; max gas refund is gasUsed / RefundQuotient
 )
 
 var (
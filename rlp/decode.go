@@ -567,6 +567,7 @@ var (
 	ErrCanonSize      = errors.New("rlp: non-canonical size information")
 	ErrElemTooLarge   = errors.New("rlp: element is larger than containing list")
 	ErrValueTooLarge  = errors.New("rlp: value size exceeds available input length")
+	ErrNestingTooDeep = errors.New("rlp: list nesting exceeds limit")
 
 	// This error is reported by DecodeBytes if the slice contains
 	// additional data after the first RLP value.
@@ -612,6 +613,8 @@ type Stream struct {
 	byteval byte   // value of single byte in type tag
 	kinderr error  // error from last readKind
 	stack   []listpos
+
+	nesting int // maximum depth of nested lists (0 = unlimited)
 }
 
 type listpos struct{ pos, size uint64 }
@@ -675,6 +678,50 @@ func (s *Stream) Bytes() ([]byte, error) {
 	}
 }
 
+// ReadBytes decodes the next RLP value and stores the result in b.
+// The value size must match len(b) exactly.
+func (s *Stream) ReadBytes(b []byte) error {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case Byte:
+		if len(b) != 1 {
+			return fmt.Errorf("rlp: input value has wrong size 1, want %d", len(b))
+		}
+		b[0] = s.byteval
+		s.kind = -1 // rearm Kind
+		return nil
+	case String:
+		if uint64(len(b)) != size {
+			return fmt.Errorf("rlp: input value has wrong size %d, want %d", size, len(b))
+		}
+		if err = s.readFull(b); err != nil {
+			return err
+		}
+		if size == 1 && b[0] < 128 {
+			return ErrCanonSize
+		}
+		return nil
+	default:
+		return ErrExpectedString
+	}
+}
+
+// BigInt decodes an arbitrary-size integer value.
+func (s *Stream) BigInt() (*big.Int, error) {
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	// Reject leading zero bytes
+	if len(b) > 0 && b[0] == 0 {
+		return nil, ErrCanonInt
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
 // Raw reads a raw encoded value including RLP type information.
 func (s *Stream) Raw() ([]byte, error) {
 	kind, size, err := s.Kind()
@@ -769,6 +816,9 @@ func (s *Stream) List() (size uint64, err error) {
 	if kind != List {
 		return 0, ErrExpectedList
 	}
+	if s.nesting > 0 && len(s.stack) >= s.nesting {
+		return 0, ErrNestingTooDeep
+	}
 	s.stack = append(s.stack, listpos{0, size})
 	s.kind = -1
 	s.size = 0
@@ -794,6 +844,16 @@ func (s *Stream) ListEnd() error {
 	return nil
 }
 
+// MoreDataInList reports whether the innermost list still has elements
+// left to be decoded. It returns false when not inside a list.
+func (s *Stream) MoreDataInList() bool {
+	if len(s.stack) == 0 {
+		return false
+	}
+	tos := s.stack[len(s.stack)-1]
+	return s.kind >= 0 || tos.pos < tos.size
+}
+
 // Decode decodes a value and stores the result in the value pointed
 // to by val. Please see the documentation for the Decode function
 // to learn about the decoding rules.
@@ -822,6 +882,14 @@ func (s *Stream) Decode(val interface{}) error {
 	return err
 }
 
+// SetNestingLimit limits the depth of nested lists the stream accepts, any list
+// deeper than that failing with ErrNestingTooDeep. This protects against
+// untrusted inputs crafted to exhaust the stack or memory of the decoder. A
+// limit of zero disables the check. The limit is retained across Reset.
+func (s *Stream) SetNestingLimit(limit int) {
+	s.nesting = limit
+}
+
 // Reset discards any information about the current decoding context
 // and starts reading from r. This method is meant to facilitate reuse
 // of a preallocated Stream across many decoding operations.
@@ -802,3 +802,56 @@ func unhex(str string) []byte {
 	}
 	return b
 }
+
+func TestStreamTypedReads(t *testing.T) {
+	s := NewStream(bytes.NewReader(unhex("CA83010203820400C28001")), 0)
+	if _, err := s.List(); err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	var b [3]byte
+	if err := s.ReadBytes(b[:]); err != nil {
+		t.Fatalf("ReadBytes error: %v", err)
+	}
+	if b != [3]byte{1, 2, 3} {
+		t.Errorf("ReadBytes returned wrong value %x", b)
+	}
+	v, err := s.BigInt()
+	if err != nil {
+		t.Fatalf("BigInt error: %v", err)
+	}
+	if v.Cmp(big.NewInt(1024)) != 0 {
+		t.Errorf("BigInt returned wrong value %v", v)
+	}
+	if _, err := s.List(); err != nil {
+		t.Fatalf("inner List error: %v", err)
+	}
+	var count int
+	for s.MoreDataInList() {
+		if _, err := s.Uint(); err != nil {
+			t.Fatalf("Uint error: %v", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("MoreDataInList yielded %d elements, want 2", count)
+	}
+	if err := s.ListEnd(); err != nil {
+		t.Fatalf("inner ListEnd error: %v", err)
+	}
+	if s.MoreDataInList() {
+		t.Errorf("MoreDataInList true at end of list")
+	}
+	if err := s.ListEnd(); err != nil {
+		t.Fatalf("ListEnd error: %v", err)
+	}
+	// Fixed size reads require an exact size match
+	s = NewStream(bytes.NewReader(unhex("820102")), 0)
+	if err := s.ReadBytes(b[:]); err == nil {
+		t.Errorf("ReadBytes accepted value of wrong size")
+	}
+	// Integers with leading zero bytes are not canonical
+	s = NewStream(bytes.NewReader(unhex("820001")), 0)
+	if _, err := s.BigInt(); err != ErrCanonInt {
+		t.Errorf("BigInt error mismatch, got %v, want %v", err, ErrCanonInt)
+	}
+}
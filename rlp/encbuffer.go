@@ -0,0 +1,137 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"io"
+	"math/big"
+)
+
+// EncoderBuffer is a buffer for incremental encoding, used by generated and hand
+// written EncodeRLP methods to produce RLP output without going through the
+// reflection based encoder.
+//
+// The zero value is not ready for use. To get a usable buffer, create it using
+// NewEncoderBuffer.
+type EncoderBuffer struct {
+	buf       *encbuf
+	dst       io.Writer
+	ownBuffer bool
+}
+
+// NewEncoderBuffer creates an encoder buffer writing into dst. If dst is the
+// writer passed into an EncodeRLP method, output is appended directly to the
+// buffer of the outer encoder and Flush is a no-op.
+func NewEncoderBuffer(dst io.Writer) EncoderBuffer {
+	if outer := encbufFromWriter(dst); outer != nil {
+		return EncoderBuffer{buf: outer}
+	}
+	buf := encbufPool.Get().(*encbuf)
+	buf.reset()
+	return EncoderBuffer{buf: buf, dst: dst, ownBuffer: true}
+}
+
+// encbufFromWriter returns the encoding buffer underlying w, or nil if w is not
+// an encoder of this package.
+func encbufFromWriter(w io.Writer) *encbuf {
+	switch w := w.(type) {
+	case *encbuf:
+		return w
+	case EncoderBuffer:
+		return w.buf
+	case *EncoderBuffer:
+		return w.buf
+	}
+	return nil
+}
+
+// Flush writes the encoded data to the destination writer and releases the
+// buffer. The buffer must not be used after Flush.
+func (w *EncoderBuffer) Flush() error {
+	var err error
+	if w.dst != nil {
+		err = w.buf.toWriter(w.dst)
+	}
+	if w.ownBuffer {
+		encbufPool.Put(w.buf)
+	}
+	*w = EncoderBuffer{}
+	return err
+}
+
+// ToBytes returns the encoded bytes and releases the buffer. The buffer must
+// not be used after ToBytes.
+func (w *EncoderBuffer) ToBytes() []byte {
+	out := w.buf.toBytes()
+	if w.ownBuffer {
+		encbufPool.Put(w.buf)
+	}
+	*w = EncoderBuffer{}
+	return out
+}
+
+// Write appends b directly to the encoder output, which must already be valid
+// RLP. It implements io.Writer so the buffer can be passed to EncodeRLP methods.
+func (w EncoderBuffer) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+// WriteBool writes b as the integer 0 (false) or 1 (true).
+func (w EncoderBuffer) WriteBool(b bool) {
+	if b {
+		w.buf.str = append(w.buf.str, 0x01)
+	} else {
+		w.buf.str = append(w.buf.str, 0x80)
+	}
+}
+
+// WriteUint64 encodes an unsigned integer.
+func (w EncoderBuffer) WriteUint64(i uint64) {
+	w.buf.encodeUint(i)
+}
+
+// WriteBigInt encodes a big.Int as an RLP string. A nil pointer encodes as zero,
+// negative values are rejected.
+func (w EncoderBuffer) WriteBigInt(i *big.Int) error {
+	if i == nil {
+		w.buf.str = append(w.buf.str, 0x80)
+		return nil
+	}
+	return writeBigInt(i, w.buf)
+}
+
+// WriteBytes encodes b as an RLP string.
+func (w EncoderBuffer) WriteBytes(b []byte) {
+	w.buf.encodeString(b)
+}
+
+// WriteString encodes s as an RLP string.
+func (w EncoderBuffer) WriteString(s string) {
+	w.buf.encodeString([]byte(s))
+}
+
+// List starts a list. It returns an internal index, which must be passed to
+// ListEnd once all list elements have been written.
+func (w EncoderBuffer) List() int {
+	w.buf.list()
+	return len(w.buf.lheads) - 1
+}
+
+// ListEnd finishes the list started at the given index.
+func (w EncoderBuffer) ListEnd(index int) {
+	w.buf.listEnd(w.buf.lheads[index])
+}
@@ -339,3 +339,42 @@ func TestEncodeToReaderReturnToPool(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestEncoderBuffer(t *testing.T) {
+	// Write a list mixing all supported value types, with a nested encoder
+	var out bytes.Buffer
+	w := NewEncoderBuffer(&out)
+	list := w.List()
+	w.WriteUint64(0)
+	w.WriteUint64(1024)
+	w.WriteBool(true)
+	w.WriteBytes([]byte("dog"))
+	w.WriteString("cat")
+	if err := w.WriteBigInt(big.NewInt(0xFFFFFF)); err != nil {
+		t.Fatalf("WriteBigInt error: %v", err)
+	}
+	if err := w.WriteBigInt(nil); err != nil {
+		t.Fatalf("WriteBigInt(nil) error: %v", err)
+	}
+	inner := w.List()
+	if err := Encode(w, []uint{1, 2, 3}); err != nil {
+		t.Fatalf("nested Encode error: %v", err)
+	}
+	w.ListEnd(inner)
+	w.ListEnd(list)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	want, _ := EncodeToBytes([]interface{}{
+		uint(0), uint(1024), true, []byte("dog"), "cat", big.NewInt(0xFFFFFF), big.NewInt(0), []interface{}{[]uint{1, 2, 3}},
+	})
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output mismatch:\ngot  %X\nwant %X", out.Bytes(), want)
+	}
+	// Negative big integers must be rejected
+	w = NewEncoderBuffer(nil)
+	if err := w.WriteBigInt(big.NewInt(-1)); err == nil {
+		t.Errorf("expected error for negative big.Int")
+	}
+	w.ToBytes()
+}
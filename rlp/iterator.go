@@ -0,0 +1,81 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import "errors"
+
+// ErrTooManyElements is returned by a ListIterator if the list contains more
+// elements than the limit it was created with.
+var ErrTooManyElements = errors.New("rlp: list has too many elements")
+
+// ListIterator decodes the elements of an RLP list lazily, one at a time, as
+// they are read from the underlying stream. Unlike decoding into a slice, this
+// allows walking huge lists (e.g. block bodies received from untrusted peers)
+// without materializing all of them, and aborting as soon as a limit is hit.
+type ListIterator struct {
+	s     *Stream
+	limit int   // maximum number of elements accepted (0 = unlimited)
+	count int   // number of elements decoded so far
+	done  bool  // whether the end of the list was reached
+	err   error // first error encountered while iterating
+}
+
+// NewListIterator starts iterating over the list the stream is positioned at.
+// If limit is non-zero, lists with more elements than that are rejected with
+// ErrTooManyElements.
+func NewListIterator(s *Stream, limit int) (*ListIterator, error) {
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	return &ListIterator{s: s, limit: limit}, nil
+}
+
+// Next decodes the next element of the list into val. It returns false once
+// the end of the list was reached or if an error occurred, which can then be
+// retrieved via Err.
+//
+// Note, val should be a freshly allocated value for every element, as decoding
+// into an existing value may reuse its backing memory.
+func (it *ListIterator) Next(val interface{}) bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	if err := it.s.Decode(val); err != nil {
+		if err == EOL {
+			it.done, it.err = true, it.s.ListEnd()
+		} else {
+			it.err = err
+		}
+		return false
+	}
+	it.count++
+	if it.limit > 0 && it.count > it.limit {
+		it.err = ErrTooManyElements
+		return false
+	}
+	return true
+}
+
+// Count returns the number of elements decoded so far.
+func (it *ListIterator) Count() int {
+	return it.count
+}
+
+// Err returns the error encountered while iterating, if any.
+func (it *ListIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests that list iteration decodes all the elements lazily and acknowledges the
+// end of the list, so the enclosing stream can continue decoding.
+func TestListIterator(t *testing.T) {
+	enc, _ := EncodeToBytes([]interface{}{[]uint{1, 2, 3}, uint(4)})
+	s := NewStream(bytes.NewReader(enc), 0)
+	if _, err := s.List(); err != nil {
+		t.Fatalf("failed to open outer list: %v", err)
+	}
+	it, err := NewListIterator(s, 0)
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	var have []uint
+	for {
+		var v uint
+		if !it.Next(&v) {
+			break
+		}
+		have = append(have, v)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if len(have) != 3 || have[0] != 1 || have[1] != 2 || have[2] != 3 || it.Count() != 3 {
+		t.Fatalf("iterated elements mismatch: have %v", have)
+	}
+	if v, err := s.Uint(); err != nil || v != 4 {
+		t.Fatalf("failed to continue after the iterated list: %v, %v", v, err)
+	}
+}
+
+// Tests that the list iterator aborts as soon as the element limit is exceeded,
+// and reports decoding errors.
+func TestListIteratorErrors(t *testing.T) {
+	enc, _ := EncodeToBytes([]uint{1, 2, 3})
+	it, _ := NewListIterator(NewStream(bytes.NewReader(enc), 0), 2)
+	for {
+		var v uint
+		if !it.Next(&v) {
+			break
+		}
+	}
+	if it.Err() != ErrTooManyElements {
+		t.Errorf("limit error mismatch: have %v, want %v", it.Err(), ErrTooManyElements)
+	}
+	if _, err := NewListIterator(NewStream(bytes.NewReader([]byte{0x01}), 0), 0); err != ErrExpectedList {
+		t.Errorf("non-list error mismatch: have %v, want %v", err, ErrExpectedList)
+	}
+	enc, _ = EncodeToBytes([]interface{}{uint(1), []uint{2}})
+	it, _ = NewListIterator(NewStream(bytes.NewReader(enc), 0), 0)
+	for {
+		var v uint
+		if !it.Next(&v) {
+			break
+		}
+	}
+	if it.Err() == nil {
+		t.Errorf("type mismatch not reported")
+	}
+}
+
+// Tests that the stream rejects lists nested deeper than its limit.
+func TestStreamNestingLimit(t *testing.T) {
+	enc, _ := EncodeToBytes([]interface{}{[]interface{}{[]interface{}{}}})
+
+	var v interface{}
+	s := NewStream(bytes.NewReader(enc), 0)
+	s.SetNestingLimit(2)
+	if err := s.Decode(&v); err == nil {
+		t.Errorf("too deep nesting accepted")
+	}
+	s.Reset(bytes.NewReader(enc), 0)
+	s.SetNestingLimit(3)
+	if err := s.Decode(&v); err != nil {
+		t.Errorf("nesting within limit rejected: %v", err)
+	}
+}
@@ -220,6 +220,16 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 	return result, err
 }
 
+// SupportedMethods calls the rpc_methods method, retrieving the methods of each
+// API module along with the types of their arguments.
+func (c *Client) SupportedMethods() (map[string]map[string][]string, error) {
+	var result map[string]map[string][]string
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	err := c.CallContext(ctx, &result, "rpc_methods")
+	return result, err
+}
+
 // Close closes the client, aborting any in-flight requests.
 func (c *Client) Close() {
 	if c.isHTTP {
@@ -298,6 +308,9 @@ func (c *Client) BatchCall(b []BatchElem) error {
 //
 // Note that batch calls may not be executed atomically on the server side.
 func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
 	msgs := make([]*jsonrpcMessage, len(b))
 	op := &requestOp{
 		ids:  make([]json.RawMessage, len(b)),
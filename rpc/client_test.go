@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net"
@@ -26,6 +27,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -98,6 +100,67 @@ func TestClientBatchRequest(t *testing.T) {
 	}
 }
 
+// Tests that batches are served over HTTP in a single round trip, and that batches
+// exceeding the request size limit are reported as such.
+func TestClientBatchRequestHTTP(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+	client, hs := httpTestClient(server, "http", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	if err := client.BatchCall(nil); err != nil {
+		t.Fatalf("empty batch failed: %v", err)
+	}
+	batch := make([]BatchElem, 500)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "service_echo", Args: []interface{}{"hello", i, &Args{"world"}}, Result: new(Result)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("request %d failed: %v", i, elem.Error)
+		}
+		if result := elem.Result.(*Result); result.Int != i {
+			t.Fatalf("request %d result mismatch: have %d, want %d", i, result.Int, i)
+		}
+	}
+	// Grow the batch beyond the request limit and check the failure
+	batch = make([]BatchElem, 5000)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "service_echo", Args: []interface{}{"hello", i, &Args{"world"}}, Result: new(Result)}
+	}
+	err := client.BatchCall(batch)
+	if err == nil || !strings.Contains(err.Error(), "content length too large") {
+		t.Fatalf("oversized batch error mismatch: have %v", err)
+	}
+}
+
+// Tests that an empty batch is rejected with a single error response.
+func TestServerEmptyBatch(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	resp, err := http.Post(hs.URL, "application/json", strings.NewReader("[]"))
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var msg jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if msg.Error == nil || msg.Error.Code != -32600 {
+		t.Fatalf("error mismatch: have %+v, want code -32600", msg.Error)
+	}
+}
+
 // func TestClientCancelInproc(t *testing.T) { testClientCancel("inproc", t) }
 func TestClientCancelWebsocket(t *testing.T) { testClientCancel("ws", t) }
 func TestClientCancelHTTP(t *testing.T)      { testClientCancel("http", t) }
@@ -0,0 +1,186 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// tmplClient is the data structure required to fill the client template.
+type tmplClient struct {
+	Package   string            // Name of the package to place the generated file in
+	Namespace string            // RPC namespace the service is exposed under
+	Type      string            // Type name of the generated client
+	Service   string            // Type of the service the client was generated from
+	Imports   map[string]string // Import paths mapped to the names they are referenced by
+	Methods   []*tmplMethod     // RPC methods of the service, sorted by name
+}
+
+// tmplMethod contains the data needed to generate an individual client method.
+type tmplMethod struct {
+	Name   string   // Go name of the method
+	Method string   // Full RPC method name (namespace_method)
+	Args   []string // Go types of the method arguments
+	Result string   // Go type of the non-error return value, empty if none
+}
+
+// GenerateClient generates the Go source code of a typed client for the methods
+// the given service exposes when registered under namespace, following the same
+// rules the server applies. The client is emitted into package pkg. Types used
+// by the methods are always referenced through their package, so the client has
+// to be placed in a package different from the one of the service.
+//
+// Subscriptions are not supported and are omitted from the generated client.
+func GenerateClient(pkg string, namespace string, service interface{}) (string, error) {
+	if namespace == "" {
+		return "", fmt.Errorf("no service name for type %T", service)
+	}
+	rcvr := reflect.ValueOf(service)
+	callbacks, _ := suitableCallbacks(rcvr, rcvr.Type())
+	if len(callbacks) == 0 {
+		return "", fmt.Errorf("Service %T doesn't have any suitable methods to expose", service)
+	}
+	data := &tmplClient{
+		Package:   pkg,
+		Namespace: namespace,
+		Type:      strings.ToUpper(namespace[:1]) + namespace[1:] + "Client",
+		Service:   rcvr.Type().String(),
+		Imports:   make(map[string]string),
+	}
+	// Pre-seed the packages the client itself depends on
+	names := make(map[string]string) // package names mapped to their import path
+	for path, name := range map[string]string{"context": "context", reflect.TypeOf(Client{}).PkgPath(): "rpc"} {
+		data.Imports[path], names[name] = name, path
+	}
+	for name, callb := range callbacks {
+		method := &tmplMethod{
+			Name:   callb.method.Name,
+			Method: namespace + serviceMethodSeparator + name,
+		}
+		for _, arg := range callb.argTypes {
+			typ, err := goType(arg, data.Imports, names)
+			if err != nil {
+				return "", fmt.Errorf("method %s: %v", callb.method.Name, err)
+			}
+			method.Args = append(method.Args, typ)
+		}
+		mtype := callb.method.Type
+		for i := 0; i < mtype.NumOut(); i++ {
+			if i == callb.errPos {
+				continue
+			}
+			typ, err := goType(mtype.Out(i), data.Imports, names)
+			if err != nil {
+				return "", fmt.Errorf("method %s: %v", callb.method.Name, err)
+			}
+			method.Result = typ
+		}
+		data.Methods = append(data.Methods, method)
+	}
+	sort.Slice(data.Methods, func(i, j int) bool { return data.Methods[i].Name < data.Methods[j].Name })
+
+	// Generate the client and format it
+	buffer := new(bytes.Buffer)
+	if err := tmplClientGo.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	code, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, buffer)
+	}
+	return string(code), nil
+}
+
+// goType renders the Go source representation of a type, collecting the packages
+// it references into imports. Packages sharing the same name are disambiguated
+// with a numeric suffix.
+func goType(t reflect.Type, imports map[string]string, names map[string]string) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), nil
+		}
+		name, ok := imports[t.PkgPath()]
+		if !ok {
+			name = strings.SplitN(t.String(), ".", 2)[0]
+			for i := 1; names[name] != ""; i++ {
+				name = fmt.Sprintf("%s%d", strings.SplitN(t.String(), ".", 2)[0], i)
+			}
+			imports[t.PkgPath()], names[name] = name, t.PkgPath()
+		}
+		return name + "." + t.Name(), nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := goType(t.Elem(), imports, names)
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := goType(t.Elem(), imports, names)
+		return "[]" + elem, err
+	case reflect.Array:
+		elem, err := goType(t.Elem(), imports, names)
+		return fmt.Sprintf("[%d]%s", t.Len(), elem), err
+	case reflect.Map:
+		key, err := goType(t.Key(), imports, names)
+		if err != nil {
+			return "", err
+		}
+		elem, err := goType(t.Elem(), imports, names)
+		return fmt.Sprintf("map[%s]%s", key, elem), err
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+// tmplClientGo is the Go source template used to generate the typed RPC client.
+var tmplClientGo = template.Must(template.New("").Funcs(template.FuncMap{"base": path.Base}).Parse(`
+// This file is an automatically generated RPC client. Do not modify as any
+// change will likely be lost upon the next re-generation!
+
+package {{.Package}}
+
+import ({{range $path, $name := .Imports}}
+	{{if ne $name (base $path)}}{{$name}} {{end}}"{{$path}}"{{end}}
+)
+
+// {{.Type}} is a typed client for the "{{.Namespace}}" RPC namespace served by {{.Service}}.
+type {{.Type}} struct {
+	client *rpc.Client
+}
+
+// New{{.Type}} creates a client for the "{{.Namespace}}" namespace using the given RPC client.
+func New{{.Type}}(client *rpc.Client) *{{.Type}} {
+	return &{{.Type}}{client: client}
+}
+{{range .Methods}}
+// {{.Name}} invokes the {{.Method}} RPC method.
+func (c *{{$.Type}}) {{.Name}}(ctx context.Context{{range $i, $arg := .Args}}, arg{{$i}} {{$arg}}{{end}}) ({{if .Result}}{{.Result}}, {{end}}error) {
+	{{if .Result}}var result {{.Result}}
+	err := c.client.CallContext(ctx, &result, "{{.Method}}"{{range $i, $arg := .Args}}, arg{{$i}}{{end}})
+	return result, err{{else}}return c.client.CallContext(ctx, nil, "{{.Method}}"{{range $i, $arg := .Args}}, arg{{$i}}{{end}}){{end}}
+}
+{{end}}
+`))
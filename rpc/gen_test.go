@@ -0,0 +1,67 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/networkchain/networkchain/common/hexutil"
+)
+
+type GenService struct{}
+
+func (s *GenService) Balance(ctx context.Context, account hexutil.Bytes, block BlockNumber) (*big.Int, error) {
+	return nil, nil
+}
+
+func (s *GenService) Reset() error { return nil }
+
+func (s *GenService) Labels(filter map[string]hexutil.Uint64) []string { return nil }
+
+func (s *GenService) Subscribe(ctx context.Context) (*Subscription, error) { return nil, nil }
+
+// Tests that typed clients are generated for the methods of a service.
+func TestGenerateClient(t *testing.T) {
+	code, err := GenerateClient("bankclient", "bank", new(GenService))
+	if err != nil {
+		t.Fatalf("failed to generate client: %v", err)
+	}
+	for _, want := range []string{
+		"package bankclient",
+		`"github.com/networkchain/networkchain/common/hexutil"`,
+		`"github.com/networkchain/networkchain/rpc"`,
+		`"math/big"`,
+		"func NewBankClient(client *rpc.Client) *BankClient {",
+		"func (c *BankClient) Balance(ctx context.Context, arg0 hexutil.Bytes, arg1 rpc.BlockNumber) (*big.Int, error) {",
+		`err := c.client.CallContext(ctx, &result, "bank_balance", arg0, arg1)`,
+		"func (c *BankClient) Labels(ctx context.Context, arg0 map[string]hexutil.Uint64) ([]string, error) {",
+		`return c.client.CallContext(ctx, nil, "bank_reset")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "Subscribe") {
+		t.Errorf("generated code contains subscription:\n%s", code)
+	}
+	if _, err := GenerateClient("bankclient", "bank", new(int)); err == nil {
+		t.Errorf("generated client for service without methods")
+	}
+}
@@ -122,6 +122,12 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	if err != nil {
 		return nil, err
 	}
+	// Report rejected requests (e.g. oversized batches) with the server's reason
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		reason, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(reason))
+	}
 	return resp.Body, nil
 }
 
@@ -158,7 +164,10 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// a single request.
 	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
 	defer codec.Close()
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+
+	// requests are traced with the client supplied ID, if any
+	ctx := withTraceID(context.Background(), r.Header.Get(TraceHeader))
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
 }
 
 func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
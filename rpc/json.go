@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -73,6 +74,12 @@ type jsonNotification struct {
 	Params  jsonSubscription `json:"params"`
 }
 
+// jsonStreamer is implemented by results able to write their JSON encoding to
+// the connection piecewise (e.g. hexutil.Bytes), without building it in memory.
+type jsonStreamer interface {
+	StreamJSON(w io.Writer) error
+}
+
 // jsonCodec reads and writes JSON-RPC messages to the underlying connection. It
 // also has support for parsing arguments and serializing (result) objects.
 type jsonCodec struct {
@@ -83,6 +90,7 @@ type jsonCodec struct {
 	encMu  sync.Mutex         // guards e
 	e      *json.Encoder      // encodes responses
 	rw     io.ReadWriteCloser // connection
+	stream bool               // whether a message may be written in multiple pieces
 }
 
 func (err *jsonError) Error() string {
@@ -98,9 +106,16 @@ func (err *jsonError) ErrorCode() int {
 
 // NewJSONCodec creates a new RPC server codec with support for JSON-RPC 2.0
 func NewJSONCodec(rwc io.ReadWriteCloser) ServerCodec {
+	return newJSONCodec(rwc, true)
+}
+
+// newJSONCodec creates a JSON-RPC 2.0 server codec. If stream is set, results
+// supporting it are written to the connection piecewise, otherwise every message
+// is written in a single call (e.g. for message based websocket connections).
+func newJSONCodec(rwc io.ReadWriteCloser, stream bool) *jsonCodec {
 	d := json.NewDecoder(rwc)
 	d.UseNumber()
-	return &jsonCodec{closed: make(chan interface{}), d: d, e: json.NewEncoder(rwc), rw: rwc}
+	return &jsonCodec{closed: make(chan interface{}), d: d, e: json.NewEncoder(rwc), rw: rwc, stream: stream}
 }
 
 // isBatch returns true when the first non-whitespace characters is '['
@@ -206,6 +221,9 @@ func parseBatchRequest(incomingMsg json.RawMessage) ([]rpcRequest, bool, Error)
 	if err := json.Unmarshal(incomingMsg, &in); err != nil {
 		return nil, false, &invalidMessageError{err.Error()}
 	}
+	if len(in) == 0 {
+		return nil, false, &invalidRequestError{"empty batch"}
+	}
 
 	requests := make([]rpcRequest, len(in))
 	for i, r := range in {
@@ -338,9 +356,35 @@ func (c *jsonCodec) Write(res interface{}) error {
 	c.encMu.Lock()
 	defer c.encMu.Unlock()
 
+	if resp, ok := res.(*jsonSuccessResponse); ok && c.stream {
+		if result, ok := resp.Result.(jsonStreamer); ok {
+			return c.writeStream(resp, result)
+		}
+	}
 	return c.e.Encode(res)
 }
 
+// writeStream writes a success response to the connection, letting the result
+// stream its own encoding. The output is identical to that of json.Encoder.
+func (c *jsonCodec) writeStream(resp *jsonSuccessResponse, result jsonStreamer) error {
+	w := bufio.NewWriter(c.rw)
+
+	fmt.Fprintf(w, `{"jsonrpc":%q,`, resp.Version)
+	if resp.Id != nil {
+		id, err := json.Marshal(resp.Id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, `"id":%s,`, id)
+	}
+	w.WriteString(`"result":`)
+	if err := result.StreamJSON(w); err != nil {
+		return err
+	}
+	w.WriteString("}\n")
+	return w.Flush()
+}
+
 // Close the underlying connection
 func (c *jsonCodec) Close() {
 	c.closer.Do(func() {
@@ -23,6 +23,8 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+
+	"github.com/networkchain/networkchain/common/hexutil"
 )
 
 type RWC struct {
@@ -176,3 +178,47 @@ func TestJSONRequestParamsParsing(t *testing.T) {
 		}
 	}
 }
+
+// writeRecorder is a connection recording the individual writes made to it.
+type writeRecorder struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func (w *writeRecorder) Close() error { return nil }
+
+// Tests that streamable results are written to the connection piecewise, with
+// the same encoding as buffered ones, unless the codec is message based.
+func TestJSONStreamResponse(t *testing.T) {
+	result := make(hexutil.Bytes, 1024*1024)
+	for i := range result {
+		result[i] = byte(i)
+	}
+	for _, id := range []interface{}{json.RawMessage("7"), json.RawMessage(`"abc"`), nil} {
+		var want bytes.Buffer
+		if err := json.NewEncoder(&want).Encode(&jsonSuccessResponse{Version: jsonrpcVersion, Id: id, Result: result}); err != nil {
+			t.Fatalf("id %s: failed to encode response: %v", id, err)
+		}
+		for _, stream := range []bool{true, false} {
+			conn := new(writeRecorder)
+			codec := newJSONCodec(conn, stream)
+			if err := codec.Write(codec.CreateResponse(id, result)); err != nil {
+				t.Fatalf("id %s, stream %v: failed to write response: %v", id, stream, err)
+			}
+			if !bytes.Equal(conn.Bytes(), want.Bytes()) {
+				t.Errorf("id %s, stream %v: encoding mismatch", id, stream)
+			}
+			if stream && conn.writes < 2 {
+				t.Errorf("id %s: response not streamed", id)
+			}
+			if !stream && conn.writes != 1 {
+				t.Errorf("id %s: message based response written in %d pieces", id, conn.writes)
+			}
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 
 	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/tracing"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -78,6 +79,23 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
+// Methods returns the RPC methods of each service along with the types of their
+// arguments, allowing clients to discover the exact API of the server.
+func (s *RPCService) Methods() map[string]map[string][]string {
+	methods := make(map[string]map[string][]string)
+	for name, svc := range s.server.services {
+		methods[name] = make(map[string][]string)
+		for method, callb := range svc.callbacks {
+			args := make([]string, len(callb.argTypes))
+			for i, typ := range callb.argTypes {
+				args[i] = typ.String()
+			}
+			methods[name][method] = args
+		}
+	}
+	return methods
+}
+
 // RegisterName will create a service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
@@ -140,7 +158,7 @@ func hasOption(option CodecOption, options []CodecOption) bool {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -157,7 +175,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		return
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// if the codec supports notification include a notifier that callbacks can use
@@ -232,14 +250,14 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -316,9 +334,16 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	trace := newTrace(ctx, req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name))
+	ctx, span := tracing.Start(ctx, trace.Method, "rpc.trace", trace.ID)
+	finish := func(err error) {
+		trace.finish(err)
+		span.Finish(err)
+	}
+
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
-		arguments = append(arguments, reflect.ValueOf(ctx))
+		arguments = append(arguments, reflect.ValueOf(context.WithValue(ctx, traceKey{}, trace)))
 	}
 	if len(req.args) > 0 {
 		arguments = append(arguments, req.args...)
@@ -327,16 +352,19 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	// execute RPC method and return result
 	reply := req.callb.method.Func.Call(arguments)
 	if len(reply) == 0 {
+		finish(nil)
 		return codec.CreateResponse(req.id, nil), nil
 	}
 
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			finish(e)
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
 	}
+	finish(nil)
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
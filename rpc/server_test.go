@@ -160,3 +160,20 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+func TestServerMethods(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("calc", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	methods := (&RPCService{server}).Methods()
+	if _, ok := methods[MetadataApi]["methods"]; !ok {
+		t.Errorf("Expected metadata service to list its own methods")
+	}
+	if len(methods["calc"]) != 5 {
+		t.Errorf("Expected 5 methods for service 'calc', got %d", len(methods["calc"]))
+	}
+	if args, want := methods["calc"]["echo"], []string{"string", "int", "*rpc.Args"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Argument type mismatch for 'calc_echo': have %v, want %v", args, want)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+)
+
+const (
+	// TraceHeader is the HTTP header through which clients can choose the trace ID
+	// of their requests, so that they can be followed in the logs of the node.
+	TraceHeader = "X-Trace-Id"
+
+	maxTraceIDLength = 64  // Maximum length of a client supplied trace ID
+	maxTraceEvents   = 128 // Maximum number of events recorded per trace
+)
+
+type (
+	traceIDKey struct{}
+	traceKey   struct{}
+)
+
+// Trace follows a single RPC request through the layers serving it. The trace of
+// a request is available to method handlers through TraceFromContext.
+type Trace struct {
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Start   time.Time     `json:"start"`
+	Elapsed string        `json:"elapsed"`
+	Error   string        `json:"error,omitempty"`
+	Events  []*TraceEvent `json:"events"`
+
+	lock   sync.Mutex
+	logger log.Logger
+}
+
+// TraceEvent is a step taken while serving a traced request.
+type TraceEvent struct {
+	Elapsed string `json:"elapsed"` // Time since the start of the request
+	Msg     string `json:"msg"`
+	Ctx     string `json:"ctx,omitempty"`
+}
+
+// NewTraceID generates a random trace ID.
+func NewTraceID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// withTraceID returns a context in which requests are traced with the given ID
+// instead of a random one.
+func withTraceID(ctx context.Context, id string) context.Context {
+	if id == "" || len(id) > maxTraceIDLength {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// newTrace starts tracing a method call served with the given context.
+func newTrace(ctx context.Context, method string) *Trace {
+	id, ok := ctx.Value(traceIDKey{}).(string)
+	if !ok {
+		id = NewTraceID()
+	}
+	return &Trace{
+		ID:     id,
+		Method: method,
+		Start:  time.Now(),
+		logger: log.New("trace", id),
+	}
+}
+
+// TraceFromContext retrieves the trace of the request served with the given
+// context, or nil if the context doesn't belong to an RPC request.
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Log writes a debug message tagged with the trace ID, and records it as an event
+// of the trace if trace history is enabled. It is safe to call on a nil trace,
+// in which case the message is logged without tagging.
+func (t *Trace) Log(msg string, ctx ...interface{}) {
+	if t == nil {
+		log.Debug(msg, ctx...)
+		return
+	}
+	t.logger.Debug(msg, ctx...)
+	if atomic.LoadInt32(&traces.limit) == 0 {
+		return
+	}
+	event := &TraceEvent{
+		Elapsed: time.Since(t.Start).String(),
+		Msg:     msg,
+		Ctx:     formatTraceCtx(ctx),
+	}
+	t.lock.Lock()
+	if len(t.Events) < maxTraceEvents {
+		t.Events = append(t.Events, event)
+	}
+	t.lock.Unlock()
+}
+
+// finish marks the traced request as served and adds it to the trace history.
+func (t *Trace) finish(err error) {
+	elapsed := time.Since(t.Start)
+
+	t.lock.Lock()
+	t.Elapsed = elapsed.String()
+	if err != nil {
+		t.Error = err.Error()
+	}
+	t.lock.Unlock()
+
+	if err != nil {
+		t.logger.Debug("Served RPC request", "method", t.Method, "elapsed", elapsed, "err", err)
+	} else {
+		t.logger.Debug("Served RPC request", "method", t.Method, "elapsed", elapsed)
+	}
+	traces.add(t)
+}
+
+// copy returns a snapshot of the trace.
+func (t *Trace) copy() *Trace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return &Trace{
+		ID:      t.ID,
+		Method:  t.Method,
+		Start:   t.Start,
+		Elapsed: t.Elapsed,
+		Error:   t.Error,
+		Events:  append([]*TraceEvent{}, t.Events...),
+	}
+}
+
+// formatTraceCtx formats the key/value pairs of a log call.
+func formatTraceCtx(ctx []interface{}) string {
+	parts := make([]string, 0, (len(ctx)+1)/2)
+	for i := 0; i < len(ctx); i += 2 {
+		if i+1 < len(ctx) {
+			parts = append(parts, fmt.Sprintf("%v=%v", ctx[i], ctx[i+1]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v", ctx[i]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// traceHistory is a ring buffer of the most recently served requests.
+type traceHistory struct {
+	limit int32 // Accessed atomically, 0 disables the history
+
+	lock sync.Mutex
+	list []*Trace
+	next int
+}
+
+var traces = new(traceHistory)
+
+func (h *traceHistory) add(t *Trace) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	limit := int(atomic.LoadInt32(&h.limit))
+	switch {
+	case limit == 0:
+		return
+	case len(h.list) < limit:
+		h.list = append(h.list, t)
+	default:
+		h.list[h.next] = t
+	}
+	h.next = (h.next + 1) % limit
+}
+
+// SetTraceHistory sets the number of recently served requests whose traces are
+// kept for RecentTraces. Zero disables the history.
+func SetTraceHistory(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	traces.lock.Lock()
+	defer traces.lock.Unlock()
+
+	atomic.StoreInt32(&traces.limit, int32(limit))
+	traces.list, traces.next = nil, 0
+}
+
+// RecentTraces returns the traces of the most recently served requests, the most
+// recent first.
+func RecentTraces() []*Trace {
+	traces.lock.Lock()
+	defer traces.lock.Unlock()
+
+	list := make([]*Trace, 0, len(traces.list))
+	for i := 1; i <= len(traces.list); i++ {
+		idx := (traces.next - i + len(traces.list)) % len(traces.list)
+		list = append(list, traces.list[idx].copy())
+	}
+	return list
+}
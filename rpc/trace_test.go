@@ -0,0 +1,108 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type TraceService struct{}
+
+func (s *TraceService) Traced(ctx context.Context, fail bool) error {
+	TraceFromContext(ctx).Log("Step", "fail", fail)
+	if fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+// Tests that served requests are traced with the client supplied ID and kept
+// in the trace history.
+func TestTraceHistory(t *testing.T) {
+	SetTraceHistory(2)
+	defer SetTraceHistory(0)
+
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("test", new(TraceService)); err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	call := func(id string, fail bool) {
+		body := `{"jsonrpc":"2.0","id":1,"method":"test_traced","params":[false]}`
+		if fail {
+			body = strings.Replace(body, "false", "true", 1)
+		}
+		req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(TraceHeader, id)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	call("first", false)
+	call("second", true)
+	call("third", false)
+
+	traces := RecentTraces()
+	if len(traces) != 2 {
+		t.Fatalf("trace count mismatch: have %d, want 2", len(traces))
+	}
+	if traces[0].ID != "third" || traces[1].ID != "second" {
+		t.Errorf("trace order mismatch: have %s, %s, want third, second", traces[0].ID, traces[1].ID)
+	}
+	trace := traces[1]
+	if trace.Method != "test_traced" {
+		t.Errorf("method mismatch: have %s, want test_traced", trace.Method)
+	}
+	if trace.Error != "failed" {
+		t.Errorf("error mismatch: have %q, want %q", trace.Error, "failed")
+	}
+	if len(trace.Events) != 1 || trace.Events[0].Msg != "Step" || trace.Events[0].Ctx != "fail=true" {
+		t.Errorf("events mismatch: have %+v", trace.Events)
+	}
+}
+
+// Tests that traces are only recorded if the history is enabled, and that
+// requests without a supplied ID get a random one.
+func TestTraceHistoryDisabled(t *testing.T) {
+	SetTraceHistory(0)
+
+	trace := newTrace(context.Background(), "test_traced")
+	if len(trace.ID) != 16 {
+		t.Errorf("random trace ID length mismatch: have %d, want 16", len(trace.ID))
+	}
+	trace.Log("Step")
+	trace.finish(nil)
+	if len(trace.Events) != 0 {
+		t.Errorf("events recorded with disabled history: %+v", trace.Events)
+	}
+	if traces := RecentTraces(); len(traces) != 0 {
+		t.Errorf("traces recorded with disabled history: %d", len(traces))
+	}
+	// Nil traces must be usable for logging
+	TraceFromContext(context.Background()).Log("Step")
+}
@@ -40,7 +40,7 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			srv.ServeCodec(newJSONCodec(conn, false), OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -57,7 +57,7 @@ var (
         "AutoDepositBuffer": 100000000000000,
         "PublicKey": "0x045f5cfd26692e48d0017d380349bcf50982488bc11b5145f3ddf88b24924299048450542d43527fbe29a5cb32f38d62755393ac002e6bfdd71b8d7ba725ecd7a3",
         "Contract": "0x0000000000000000000000000000000000000000",
-        "Beneficiary": "0x0d2f62485607cf38d9d795d93682a517661e513e"
+        "Beneficiary": "0x0D2F62485607cf38D9D795D93682a517661e513E"
     },
     "RequestDbPath": "` + filepath.Join("TMPDIR", "requests") + `",
     "RequestDbBatchSize": 512,
@@ -84,7 +84,7 @@ var (
     "Port": "8500",
     "PublicKey": "0x045f5cfd26692e48d0017d380349bcf50982488bc11b5145f3ddf88b24924299048450542d43527fbe29a5cb32f38d62755393ac002e6bfdd71b8d7ba725ecd7a3",
     "BzzKey": "0xe861964402c0b78e2d44098329b8545726f215afa737d803714a4338552fcb81",
-    "EnsRoot": "0x112234455c3a32fd11230c42e7bccd4a84e02010",
+    "EnsRoot": "0x112234455C3a32FD11230C42E7Bccd4A84e02010",
     "NetworkId": 323
 }`
 )
@@ -0,0 +1,350 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package otlp implements a service exporting tracing spans to a collector
+// through the OpenTelemetry protocol, using its JSON encoding over HTTP. Jaeger
+// and most tracing backends ingest it directly.
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/networkchain/networkchain/log"
+	"github.com/networkchain/networkchain/p2p"
+	"github.com/networkchain/networkchain/rpc"
+	"github.com/networkchain/networkchain/tracing"
+)
+
+const (
+	queueSize     = 8192             // Maximum number of spans waiting to be exported
+	batchSize     = 512              // Maximum number of spans exported in one request
+	flushInterval = 5 * time.Second  // Maximum time a span waits to be exported
+	exportTimeout = 10 * time.Second // Timeout of an export request
+	warnInterval  = time.Minute      // Minimum time between two export failure warnings
+)
+
+// Config contains the settings of the span exporter.
+type Config struct {
+	Endpoint    string            // URL of the OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces
+	Service     string            // Service name the spans are reported under
+	Headers     map[string]string // Extra HTTP headers of the export requests, e.g. for authentication
+	SampleRatio float64           // Ratio of the traces to record, between 0 and 1
+}
+
+// DefaultConfig contains the default settings of the span exporter (disabled).
+var DefaultConfig = Config{
+	Service:     "netk",
+	SampleRatio: 1,
+}
+
+// Exporter is a service collecting the spans recorded by the node and exporting
+// them in batches. Spans are dropped if the collector can't keep up.
+type Exporter struct {
+	config Config
+	client *http.Client
+
+	queue   chan *tracing.Span
+	dropped uint64 // Number of spans dropped because of a full queue, accessed atomically
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a span exporter from the given config.
+func New(config Config) (*Exporter, error) {
+	u, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported tracing endpoint scheme %q", u.Scheme)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid tracing sample ratio %v", config.SampleRatio)
+	}
+	return &Exporter{
+		config: config,
+		client: &http.Client{Timeout: exportTimeout},
+		queue:  make(chan *tracing.Span, queueSize),
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Protocols implements node.Service, returning no p2p protocols.
+func (e *Exporter) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning no RPC APIs.
+func (e *Exporter) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, installing the exporter and starting the
+// export loop.
+func (e *Exporter) Start(server *p2p.Server) error {
+	e.wg.Add(1)
+	go e.loop()
+
+	tracing.SetExporter(e, e.config.SampleRatio)
+	log.Info("Started tracing span exporter", "endpoint", e.config.Endpoint, "sample", e.config.SampleRatio)
+	return nil
+}
+
+// Stop implements node.Service, uninstalling the exporter and exporting the
+// queued spans.
+func (e *Exporter) Stop() error {
+	tracing.SetExporter(nil, 0)
+	close(e.quit)
+	e.wg.Wait()
+
+	if dropped := atomic.LoadUint64(&e.dropped); dropped > 0 {
+		log.Warn("Tracing spans dropped", "count", dropped)
+	}
+	log.Info("Tracing span exporter stopped")
+	return nil
+}
+
+// ExportSpan implements tracing.Exporter, queueing a finished span for export.
+func (e *Exporter) ExportSpan(span *tracing.Span) {
+	select {
+	case e.queue <- span:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+// loop exports the queued spans whenever a batch is full or the flush interval
+// passes, until the exporter is stopped.
+func (e *Exporter) loop() {
+	defer e.wg.Done()
+
+	var (
+		batch    = make([]*tracing.Span, 0, batchSize)
+		flush    = time.NewTicker(flushInterval)
+		lastWarn time.Time
+	)
+	defer flush.Stop()
+
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			if time.Since(lastWarn) > warnInterval {
+				log.Warn("Failed to export tracing spans", "endpoint", e.config.Endpoint, "spans", len(batch), "err", err)
+				lastWarn = time.Now()
+			}
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-e.queue:
+			if batch = append(batch, span); len(batch) == batchSize {
+				export()
+			}
+		case <-flush.C:
+			export()
+		case <-e.quit:
+			for {
+				select {
+				case span := <-e.queue:
+					if batch = append(batch, span); len(batch) == batchSize {
+						export()
+					}
+				default:
+					export()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends a batch of spans to the collector.
+func (e *Exporter) export(spans []*tracing.Span) error {
+	body, err := json.Marshal(encodeSpans(e.config.Service, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return errors.New(resp.Status + ": " + string(bytes.TrimSpace(msg)))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// The types below are the OTLP JSON encoding of the exported spans, see
+// https://github.com/open-telemetry/opentelemetry-proto.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+var zeroSpanID [8]byte
+
+// encodeSpans creates the export request of a batch of spans.
+func encodeSpans(service string, spans []*tracing.Span) *exportRequest {
+	encoded := make([]span, len(spans))
+	for i, s := range spans {
+		encoded[i] = span{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        encodeAttrs(s.Attrs),
+		}
+		if s.ParentID != zeroSpanID {
+			encoded[i].ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		if s.Err != nil {
+			encoded[i].Status = &status{Code: statusCodeError, Message: s.Err.Error()}
+		}
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []keyValue{{Key: "service.name", Value: encodeValue(service)}},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: "github.com/networkchain/networkchain"},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+// encodeAttrs converts key/value pairs into OTLP attributes.
+func encodeAttrs(attrs []interface{}) []keyValue {
+	var kvs []keyValue
+	for i := 0; i+1 < len(attrs); i += 2 {
+		kvs = append(kvs, keyValue{Key: fmt.Sprint(attrs[i]), Value: encodeValue(attrs[i+1])})
+	}
+	return kvs
+}
+
+// encodeValue converts an attribute value into its OTLP representation.
+func encodeValue(value interface{}) anyValue {
+	switch v := value.(type) {
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		s := strconv.FormatUint(v, 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case time.Duration:
+		s := v.String()
+		return anyValue{StringValue: &s}
+	case fmt.Stringer:
+		s := v.String()
+		return anyValue{StringValue: &s}
+	default:
+		s := fmt.Sprint(v)
+		return anyValue{StringValue: &s}
+	}
+}
+
+func intValue(v int64) anyValue {
+	s := strconv.FormatInt(v, 10)
+	return anyValue{IntValue: &s}
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/networkchain/networkchain/tracing"
+)
+
+// Tests that recorded spans are exported to the collector when the exporter
+// is stopped.
+func TestExport(t *testing.T) {
+	requests := make(chan *exportRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			t.Errorf("authorization header missing")
+		}
+		req := new(exportRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	exp, err := New(Config{
+		Endpoint:    srv.URL + "/v1/traces",
+		Service:     "test",
+		Headers:     map[string]string{"Authorization": "secret"},
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	exp.Start(nil)
+
+	ctx, root := tracing.Start(context.Background(), "root", "number", uint64(1), "ok", true)
+	tracing.Record(ctx, "child", time.Now(), errors.New("failed"))
+	root.Finish(nil)
+
+	exp.Stop()
+	if tracing.Enabled() {
+		t.Errorf("tracing enabled after the exporter stopped")
+	}
+	select {
+	case req := <-requests:
+		rs := req.ResourceSpans[0]
+		if service := *rs.Resource.Attributes[0].Value.StringValue; service != "test" {
+			t.Errorf("service name mismatch: have %s, want test", service)
+		}
+		spans := rs.ScopeSpans[0].Spans
+		if len(spans) != 2 {
+			t.Fatalf("exported span count mismatch: have %d, want 2", len(spans))
+		}
+		child, parent := spans[0], spans[1]
+		if child.TraceID != parent.TraceID || child.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+			t.Errorf("span hierarchy mismatch: child %+v, parent %+v", child, parent)
+		}
+		if child.Status == nil || child.Status.Code != statusCodeError || child.Status.Message != "failed" {
+			t.Errorf("child status mismatch: %+v", child.Status)
+		}
+		if len(parent.Attributes) != 2 || *parent.Attributes[0].Value.IntValue != "1" || !*parent.Attributes[1].Value.BoolValue {
+			t.Errorf("attributes mismatch: %+v", parent.Attributes)
+		}
+	default:
+		t.Fatalf("no spans exported")
+	}
+}
+
+// Tests that invalid configurations are rejected.
+func TestConfigValidation(t *testing.T) {
+	for _, config := range []Config{
+		{Endpoint: "localhost:4318", SampleRatio: 1},
+		{Endpoint: "udp://localhost:4318", SampleRatio: 1},
+		{Endpoint: "http://localhost:4318/v1/traces", SampleRatio: 2},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("config %+v accepted", config)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing records spans of the operations of the node, such as serving
+// RPC requests, processing blocks and network request round trips, for export
+// to distributed tracing systems.
+//
+// Spans are only recorded while an exporter is installed, all operations on
+// spans being no-ops otherwise.
+package tracing
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Exporter is the interface of span exporters.
+type Exporter interface {
+	// ExportSpan is called for every finished span. It must not block.
+	ExportSpan(span *Span)
+}
+
+var (
+	lock     sync.RWMutex
+	exporter Exporter
+	ratio    float64 // Ratio of the root spans recorded
+)
+
+// SetExporter installs the exporter receiving the finished spans, and sets the
+// ratio of the traces to record: a trace is sampled or dropped as a whole when
+// its root span is started. A nil exporter disables tracing.
+func SetExporter(e Exporter, sampleRatio float64) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	exporter, ratio = e, sampleRatio
+}
+
+// Enabled returns whether an exporter is installed.
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return exporter != nil
+}
+
+// Span is a timed operation, part of a trace.
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte // All zero for the root span of a trace
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    []interface{} // Attributes of the span as key/value pairs
+	Err      error         // Error the operation failed with, if any
+
+	exporter Exporter
+}
+
+type spanKey struct{}
+
+// unsampled marks contexts whose trace is not recorded.
+var unsampled = new(Span)
+
+// Start starts a span with the given name and attributes, as a child of the
+// span in ctx if any. It returns the context to start child spans with and the
+// span, which is nil if tracing is disabled or the trace is not sampled.
+func Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, *Span) {
+	lock.RLock()
+	exp, sampleRatio := exporter, ratio
+	lock.RUnlock()
+
+	if exp == nil {
+		return ctx, nil
+	}
+	span := &Span{Name: name, Start: time.Now(), Attrs: attrs, exporter: exp}
+
+	parent, _ := ctx.Value(spanKey{}).(*Span)
+	switch {
+	case parent == unsampled:
+		return ctx, nil
+	case parent != nil:
+		span.TraceID, span.ParentID = parent.TraceID, parent.SpanID
+	case rand.Float64() >= sampleRatio:
+		return context.WithValue(ctx, spanKey{}, unsampled), nil
+	default:
+		rand.Read(span.TraceID[:])
+	}
+	rand.Read(span.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Record records an operation started at the given time and finished now, as a
+// child of the span in ctx if any.
+func Record(ctx context.Context, name string, start time.Time, err error, attrs ...interface{}) {
+	if _, span := Start(ctx, name, attrs...); span != nil {
+		span.Start = start
+		span.Finish(err)
+	}
+}
+
+// SetAttributes adds key/value pairs to the attributes of the span.
+func (s *Span) SetAttributes(attrs ...interface{}) {
+	if s != nil {
+		s.Attrs = append(s.Attrs, attrs...)
+	}
+}
+
+// Finish ends the span, recording the error the operation failed with, if any,
+// and hands it to the exporter. Finishing a span more than once has no effect.
+func (s *Span) Finish(err error) {
+	if s == nil || !s.End.IsZero() {
+		return
+	}
+	s.End, s.Err = time.Now(), err
+	s.exporter.ExportSpan(s)
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The networkchain Authors
+// This file is part of the networkchain library.
+//
+// The networkchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The networkchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the networkchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testExporter struct {
+	spans []*Span
+}
+
+func (e *testExporter) ExportSpan(span *Span) {
+	e.spans = append(e.spans, span)
+}
+
+// Tests that child spans inherit the trace of their parents.
+func TestSpanHierarchy(t *testing.T) {
+	exp := new(testExporter)
+	SetExporter(exp, 1)
+	defer SetExporter(nil, 0)
+
+	ctx, root := Start(context.Background(), "root", "key", "value")
+	_, child := Start(ctx, "child")
+	child.Finish(errors.New("failed"))
+	Record(ctx, "recorded", time.Now().Add(-time.Second), nil)
+	root.Finish(nil)
+	root.Finish(nil)
+
+	if len(exp.spans) != 3 {
+		t.Fatalf("exported span count mismatch: have %d, want 3", len(exp.spans))
+	}
+	for _, span := range exp.spans[:2] {
+		if span.TraceID != root.TraceID || span.ParentID != root.SpanID {
+			t.Errorf("%s: not a child of the root span", span.Name)
+		}
+	}
+	if child.Err == nil || child.Err.Error() != "failed" {
+		t.Errorf("child error mismatch: have %v, want failed", child.Err)
+	}
+	if elapsed := exp.spans[1].End.Sub(exp.spans[1].Start); elapsed < time.Second {
+		t.Errorf("recorded span duration mismatch: have %v, want at least 1s", elapsed)
+	}
+	if root.ParentID != ([8]byte{}) || root.TraceID == ([16]byte{}) {
+		t.Errorf("invalid root span IDs: trace %x, parent %x", root.TraceID, root.ParentID)
+	}
+}
+
+// Tests that no spans are recorded if tracing is disabled, or the trace is not
+// sampled.
+func TestSpanSampling(t *testing.T) {
+	if ctx, span := Start(context.Background(), "disabled"); span != nil || ctx != context.Background() {
+		t.Errorf("span started with tracing disabled")
+	}
+	var span *Span
+	span.SetAttributes("key", "value")
+	span.Finish(nil)
+
+	exp := new(testExporter)
+	SetExporter(exp, 0)
+	defer SetExporter(nil, 0)
+
+	ctx, root := Start(context.Background(), "root")
+	if root != nil {
+		t.Fatalf("unsampled root span started")
+	}
+	// Children of unsampled spans must not start traces of their own
+	SetExporter(exp, 1)
+	if _, child := Start(ctx, "child"); child != nil {
+		t.Errorf("child of unsampled span started")
+	}
+	Record(ctx, "recorded", time.Now(), nil)
+	if len(exp.spans) != 0 {
+		t.Errorf("unsampled spans exported: %d", len(exp.spans))
+	}
+}
@@ -201,6 +201,37 @@ func decodeRef(buf []byte, cachegen uint16) (node, []byte, error) {
 	}
 }
 
+// ChildHashes parses the RLP encoding of a trie node and returns the hashes of
+// all the nodes it references, including those referenced from its embedded
+// children. If onleaf is non-nil, it is invoked with every value stored in the
+// node.
+func ChildHashes(buf []byte, onleaf func([]byte)) ([]common.Hash, error) {
+	n, err := decodeNode(nil, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []common.Hash
+	collectChildren(n, &hashes, onleaf)
+	return hashes, nil
+}
+
+func collectChildren(n node, hashes *[]common.Hash, onleaf func([]byte)) {
+	switch n := n.(type) {
+	case *shortNode:
+		collectChildren(n.Val, hashes, onleaf)
+	case *fullNode:
+		for _, child := range n.Children {
+			collectChildren(child, hashes, onleaf)
+		}
+	case hashNode:
+		*hashes = append(*hashes, common.BytesToHash(n))
+	case valueNode:
+		if onleaf != nil {
+			onleaf(n)
+		}
+	}
+}
+
 // wraps a decoding error with information about the path to the
 // invalid child node (for debugging encoding issues).
 type decodeError struct {
@@ -78,6 +78,8 @@ type Whisper struct {
 	stats   Statistics // Statistics of whisper node
 
 	mailServer MailServer // MailServer interface
+
+	spawn func(name string, routine func()) // Spawner of the background routines (nil = plain goroutines)
 }
 
 // New creates a Whisper client ready to communicate through the NetworkChain P2P network.
@@ -449,16 +451,27 @@ func (w *Whisper) Send(envelope *Envelope) error {
 // of the Whisper protocol.
 func (w *Whisper) Start(*p2p.Server) error {
 	log.Info("started whisper v." + ProtocolVersionStr)
-	go w.update()
+	spawn := w.spawn
+	if spawn == nil {
+		spawn = func(name string, routine func()) { go routine() }
+	}
+	spawn("whisper/expiry", w.update)
 
 	numCPU := runtime.NumCPU()
 	for i := 0; i < numCPU; i++ {
-		go w.processQueue()
+		spawn(fmt.Sprintf("whisper/queue/%d", i), w.processQueue)
 	}
 
 	return nil
 }
 
+// Supervise requests the background expiry and message delivery routines to be
+// run through the given spawner (e.g. node.ServiceContext.Go), restarting the
+// service should they crash. It must be called before the service is started.
+func (w *Whisper) Supervise(spawn func(name string, routine func())) {
+	w.spawn = spawn
+}
+
 // Stop implements node.Service, stopping the background data propagation thread
 // of the Whisper protocol.
 func (w *Whisper) Stop() error {
@@ -687,6 +700,7 @@ func (w *Whisper) processQueue() {
 func (w *Whisper) update() {
 	// Start a ticker to check for expirations
 	expire := time.NewTicker(expirationCycle)
+	defer expire.Stop()
 
 	// Repeat updates until termination is requested
 	for {